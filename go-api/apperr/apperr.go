@@ -0,0 +1,105 @@
+// Package apperr — категории ошибок, по которым обработчики выбирают ответ
+// пользователю и уровень лога. Категория проверяется через errors.Is:
+//
+//	errors.Is(err, apperr.ErrValidation)
+package apperr
+
+import (
+    "errors"
+    "log/slog"
+)
+
+var (
+    // ErrValidation — пользователь прислал некорректные данные; ответ объясняет, что не так
+    ErrValidation = errors.New("некорректный запрос")
+    // ErrNotFound — запрошенной записи нет или она недоступна пользователю
+    ErrNotFound = errors.New("не найдено")
+    // ErrUpstream — внешний сервис (OpenAI и т.п.) не ответил или ответил ошибкой
+    ErrUpstream = errors.New("внешний сервис недоступен")
+)
+
+// Error — ошибка с категорией и текстом для пользователя
+type Error struct {
+    // Kind — категория: ErrValidation, ErrNotFound, ErrUpstream или nil
+    Kind error
+    // Message — что ответить пользователю; пусто — ответ по категории
+    Message string
+    // Err — исходная ошибка, может быть nil
+    Err error
+}
+
+func (e *Error) Error() string {
+    var head string
+    switch {
+    case e.Message != "":
+        head = e.Message
+    case e.Kind != nil:
+        head = e.Kind.Error()
+    }
+    switch {
+    case e.Err == nil:
+        return head
+    case head == "":
+        return e.Err.Error()
+    }
+    return head + ": " + e.Err.Error()
+}
+
+// Unwrap отдаёт и категорию, и исходную ошибку — errors.Is и errors.As видят обе
+func (e *Error) Unwrap() []error {
+    var errs []error
+    if e.Kind != nil {
+        errs = append(errs, e.Kind)
+    }
+    if e.Err != nil {
+        errs = append(errs, e.Err)
+    }
+    return errs
+}
+
+// Validation — ошибка ввода с объяснением для пользователя
+func Validation(message string) error {
+    return &Error{Kind: ErrValidation, Message: message}
+}
+
+// NotFound — запись не найдена; message — что ответить пользователю
+func NotFound(message string) error {
+    return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// Upstream помечает ошибку внешнего сервиса
+func Upstream(err error) error {
+    if err == nil || errors.Is(err, ErrUpstream) {
+        return err
+    }
+    return &Error{Kind: ErrUpstream, Err: err}
+}
+
+// WithMessage добавляет к ошибке текст для пользователя, сохраняя её категорию
+func WithMessage(err error, message string) error {
+    if err == nil {
+        return nil
+    }
+    return &Error{Message: message, Err: err}
+}
+
+// UserMessage — ближайший текст для пользователя из цепочки ошибок или пустая строка
+func UserMessage(err error) string {
+    var e *Error
+    for errors.As(err, &e) {
+        if e.Message != "" {
+            return e.Message
+        }
+        err = e.Err
+    }
+    return ""
+}
+
+// Level — уровень лога для ошибки: ошибки пользователя — Info,
+// всё остальное (внешние сервисы, сбои хранилищ) — Error
+func Level(err error) slog.Level {
+    if errors.Is(err, ErrValidation) || errors.Is(err, ErrNotFound) {
+        return slog.LevelInfo
+    }
+    return slog.LevelError
+}
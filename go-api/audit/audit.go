@@ -0,0 +1,149 @@
+// Package audit пишет выборку запросов к модели в журнал llm_audit. Личные
+// данные скрываются до записи, а пишет в базу один фоновый воркер через
+// очередь ограниченной длины: медленная база не копит горутины, а лишние
+// записи при переполнении отбрасываются.
+package audit
+
+import (
+    "context"
+    "math/rand"
+    "strings"
+    "sync/atomic"
+    "time"
+
+    "ai_seller/filter"
+    "ai_seller/logging"
+    "ai_seller/openai"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// QueueSize — сколько записей ждёт воркера; при переполнении новые отбрасываются
+const QueueSize = 256
+
+// drainTimeout — сколько при остановке дописываются записи из очереди
+const drainTimeout = 5 * time.Second
+
+// Sink — журнал запросов к модели; реализуется *storage.LLMAuditStore
+type Sink interface {
+    Record(ctx context.Context, e storage.LLMAuditEntry) error
+}
+
+// Recorder — хук клиента модели, отбирающий запросы в журнал
+type Recorder struct {
+    sink     Sink
+    redactor *filter.Filter
+    sample   float64
+    // random — случайное число из [0, 1) для выборки
+    random  func() float64
+    queue   chan storage.LLMAuditEntry
+    dropped atomic.Int64
+}
+
+// New — журнал с долей sample (0..1) запросов и очередью на queueSize записей;
+// скрываются все готовые шаблоны filter: телефоны, почта, номера карт,
+// паспортов и СНИЛС, адреса
+func New(sink Sink, sample float64, queueSize int) *Recorder {
+    // Готовые шаблоны компилируются всегда, ошибки здесь быть не может
+    redactor, _ := filter.New(filter.Rules{Redact: filter.Builtin()})
+    return &Recorder{
+        sink:     sink,
+        redactor: redactor,
+        sample:   sample,
+        random:   rand.Float64,
+        queue:    make(chan storage.LLMAuditEntry, queueSize),
+    }
+}
+
+// Exchange — openai.ExchangeFunc: отобранный обмен с моделью без личных
+// данных ставится в очередь на запись. Не блокируется.
+func (r *Recorder) Exchange(ctx context.Context, ex openai.Exchange) {
+    if r.sample <= 0 || r.random() >= r.sample {
+        return
+    }
+    entry := storage.LLMAuditEntry{
+        ChatID:           reqctx.ChatIDFromContext(ctx),
+        TraceID:          reqctx.TraceIDFromContext(ctx),
+        Model:            ex.Model,
+        Response:         r.redact(ex.Response),
+        PromptTokens:     ex.Usage.PromptTokens,
+        CompletionTokens: ex.Usage.CompletionTokens,
+        Latency:          ex.Latency,
+    }
+    if ex.Err != nil {
+        entry.Error = r.redact(ex.Err.Error())
+    }
+    for _, m := range ex.Messages {
+        entry.Messages = append(entry.Messages, storage.AuditMessage{Role: string(m.Role), Content: r.redact(messageText(m))})
+    }
+    select {
+    case r.queue <- entry:
+    default:
+        if r.dropped.Add(1) == 1 {
+            logging.FromContext(ctx).Warn("очередь журнала запросов к модели переполнена, записи отбрасываются")
+        }
+    }
+}
+
+// Dropped — сколько записей отброшено из-за переполненной очереди
+func (r *Recorder) Dropped() int64 {
+    return r.dropped.Load()
+}
+
+// Run пишет записи из очереди до отмены ctx, затем не дольше drainTimeout
+// дописывает оставшиеся
+func (r *Recorder) Run(ctx context.Context) {
+    for {
+        select {
+        case e := <-r.queue:
+            r.write(ctx, e)
+        case <-ctx.Done():
+            r.drain(context.WithoutCancel(ctx))
+            return
+        }
+    }
+}
+
+// drain дописывает записи, уже стоящие в очереди
+func (r *Recorder) drain(ctx context.Context) {
+    ctx, cancel := context.WithTimeout(ctx, drainTimeout)
+    defer cancel()
+    for {
+        select {
+        case e := <-r.queue:
+            r.write(ctx, e)
+        default:
+            return
+        }
+        if ctx.Err() != nil {
+            return
+        }
+    }
+}
+
+func (r *Recorder) write(ctx context.Context, e storage.LLMAuditEntry) {
+    if err := r.sink.Record(ctx, e); err != nil {
+        logging.Logger().Error("ошибка записи аудита запроса к модели", "err", err)
+    }
+}
+
+func (r *Recorder) redact(text string) string {
+    text, _ = r.redactor.Apply(text)
+    return text
+}
+
+// messageText — текст сообщения для журнала; изображения не сохраняются
+func messageText(m openai.Message) string {
+    if len(m.Parts) == 0 {
+        return m.Content
+    }
+    texts := make([]string, 0, len(m.Parts))
+    for _, p := range m.Parts {
+        if p.Text != "" {
+            texts = append(texts, p.Text)
+        } else {
+            texts = append(texts, "[изображение]")
+        }
+    }
+    return strings.Join(texts, "\n")
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/openai"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// sink — журнал в памяти; max — наибольшее число одновременных записей
+type sink struct {
+    mu      sync.Mutex
+    entries []storage.LLMAuditEntry
+    active  int
+    max     int
+}
+
+func (s *sink) Record(ctx context.Context, e storage.LLMAuditEntry) error {
+    s.mu.Lock()
+    s.active++
+    if s.active > s.max {
+        s.max = s.active
+    }
+    s.entries = append(s.entries, e)
+    s.mu.Unlock()
+
+    // Пауза даёт второй записи шанс начаться, если воркеров больше одного
+    time.Sleep(time.Millisecond)
+    s.mu.Lock()
+    s.active--
+    s.mu.Unlock()
+    return nil
+}
+
+func (s *sink) written() []storage.LLMAuditEntry {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]storage.LLMAuditEntry(nil), s.entries...)
+}
+
+// flush дописывает очередь и возвращает записанное
+func flush(r *Recorder, s *sink) []storage.LLMAuditEntry {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    r.Run(ctx)
+    return s.written()
+}
+
+func TestExchangeRedactsPersonalData(t *testing.T) {
+    s := &sink{}
+    r := New(s, 1, QueueSize)
+    ctx := reqctx.WithTraceID(reqctx.WithChatID(context.Background(), 42), "upd-1")
+    r.Exchange(ctx, openai.Exchange{
+        Model: "gpt-test",
+        Messages: []openai.Message{
+            {Role: "system", Content: "Ты продавец чая."},
+            {Role: "user", Content: "Мой телефон +7 (912) 345-67-89, почта anna@example.ru"},
+            {Role: "user", Content: "Карта 4111 1111 1111 1111, паспорт 45 06 123456, СНИЛС 112-233-445 95"},
+            {Role: "user", Parts: []openai.ContentPart{openai.TextPart("Доставьте на ул. Ленина, д. 5, кв. 12"), openai.ImagePart([]byte("png"), "image/png")}},
+        },
+        Response: "Записал: ул. Ленина, д. 5, кв. 12, телефон +79123456789",
+        Err:      errors.New("ошибка для anna@example.ru"),
+    })
+
+    entries := flush(r, s)
+    if len(entries) != 1 {
+        t.Fatalf("записано %d, ожидалась 1 запись", len(entries))
+    }
+    e := entries[0]
+    if e.ChatID != 42 || e.TraceID != "upd-1" || e.Model != "gpt-test" {
+        t.Fatalf("запись: %+v", e)
+    }
+    texts := []string{e.Response, e.Error}
+    for _, m := range e.Messages {
+        texts = append(texts, m.Content)
+    }
+    for _, secret := range []string{"345-67-89", "anna@example.ru", "4111", "123456", "445 95", "Ленина", "кв. 12", "79123456789"} {
+        for _, text := range texts {
+            if strings.Contains(text, secret) {
+                t.Errorf("в журнале осталось %q: %q", secret, text)
+            }
+        }
+    }
+    if e.Messages[0].Content != "Ты продавец чая." {
+        t.Errorf("текст без личных данных изменён: %q", e.Messages[0].Content)
+    }
+    if !strings.Contains(e.Messages[3].Content, "[изображение]") {
+        t.Errorf("изображение не заменено пометкой: %q", e.Messages[3].Content)
+    }
+}
+
+func TestExchangeSamples(t *testing.T) {
+    for _, tc := range []struct {
+        name   string
+        sample float64
+        rolls  []float64
+        want   int
+    }{
+        {"всё", 1, []float64{0, 0.5, 0.99}, 3},
+        {"ничего", 0, []float64{0, 0.5, 0.99}, 0},
+        {"половина", 0.5, []float64{0.1, 0.5, 0.49, 0.9}, 2},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            s := &sink{}
+            r := New(s, tc.sample, QueueSize)
+            rolls := tc.rolls
+            r.random = func() float64 {
+                v := rolls[0]
+                rolls = rolls[1:]
+                return v
+            }
+            for range tc.rolls {
+                r.Exchange(context.Background(), openai.Exchange{Model: "gpt-test"})
+            }
+            if got := len(flush(r, s)); got != tc.want {
+                t.Fatalf("записано %d, ожидалось %d", got, tc.want)
+            }
+        })
+    }
+}
+
+func TestExchangeDropsWhenQueueIsFull(t *testing.T) {
+    s := &sink{}
+    r := New(s, 1, 2)
+    for range 5 {
+        r.Exchange(context.Background(), openai.Exchange{Model: "gpt-test"})
+    }
+    if got := r.Dropped(); got != 3 {
+        t.Fatalf("отброшено %d, ожидалось 3", got)
+    }
+    if got := len(flush(r, s)); got != 2 {
+        t.Fatalf("записано %d, ожидалось 2", got)
+    }
+}
+
+func TestRunWritesOneAtATime(t *testing.T) {
+    s := &sink{}
+    r := New(s, 1, QueueSize)
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        r.Run(ctx)
+        close(done)
+    }()
+
+    var wg sync.WaitGroup
+    for range 50 {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            r.Exchange(context.Background(), openai.Exchange{Model: "gpt-test"})
+        }()
+    }
+    wg.Wait()
+    cancel()
+    <-done
+
+    if got := len(s.written()); got != 50 {
+        t.Fatalf("записано %d, ожидалось 50", got)
+    }
+    if s.max != 1 {
+        t.Fatalf("одновременных записей %d, ожидалась одна", s.max)
+    }
+}
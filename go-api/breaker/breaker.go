@@ -0,0 +1,167 @@
+// Package breaker — предохранитель (circuit breaker) для вызовов внешней
+// зависимости: после серии сбоев вызовы какое-то время отклоняются сразу,
+// не нагружая упавший сервис, затем пробный вызов проверяет, ожил ли он.
+package breaker
+
+import (
+    "errors"
+    "sync"
+    "time"
+
+    "ai_seller/metrics"
+)
+
+// ErrOpen — предохранитель разомкнут, вызов не выполнялся
+var ErrOpen = errors.New("зависимость недоступна, вызов отклонён предохранителем")
+
+// State — состояние предохранителя
+type State int
+
+const (
+    // Closed — вызовы проходят, сбои подряд считаются
+    Closed State = iota
+    // HalfOpen — пауза истекла, проходит один пробный вызов
+    HalfOpen
+    // Open — вызовы отклоняются до конца паузы
+    Open
+)
+
+func (s State) String() string {
+    switch s {
+    case HalfOpen:
+        return "half-open"
+    case Open:
+        return "open"
+    }
+    return "closed"
+}
+
+// Breaker — предохранитель одной зависимости. Размыкается после threshold
+// сбоев подряд на cooldown; затем пропускает один пробный вызов: успех
+// замыкает предохранитель, сбой размыкает снова.
+type Breaker struct {
+    name      string
+    threshold int
+    cooldown  time.Duration
+    // IsFailure решает, считать ли ошибку сбоем зависимости; nil — любая ошибка.
+    // Ошибки вроде «не найдено» означают, что зависимость ответила.
+    IsFailure func(error) bool
+
+    mu       sync.Mutex
+    state    State
+    failures int
+    openedAt time.Time
+    probing  bool
+    // generation растёт при каждой смене состояния; итог вызова, пропущенного
+    // в другом поколении, уже ничего не решает и не учитывается
+    generation uint64
+}
+
+// New — предохранитель зависимости name (для метрик и логов)
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+    b := &Breaker{name: name, threshold: threshold, cooldown: cooldown}
+    metrics.BreakerState.WithLabelValues(name).Set(float64(Closed))
+    return b
+}
+
+// Name — имя зависимости
+func (b *Breaker) Name() string {
+    return b.name
+}
+
+// State — текущее состояние; Open с истёкшей паузой показывается как HalfOpen
+func (b *Breaker) State() State {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.state == Open && time.Since(b.openedAt) >= b.cooldown {
+        return HalfOpen
+    }
+    return b.state
+}
+
+// Do выполняет fn, если предохранитель пропускает вызов, иначе сразу
+// возвращает ErrOpen. Итог fn учитывается в счётчике сбоев.
+func (b *Breaker) Do(fn func() error) error {
+    gen, ok := b.allow()
+    if !ok {
+        return ErrOpen
+    }
+    err := fn()
+    b.record(gen, err)
+    return err
+}
+
+// Call — Do для функций, возвращающих значение
+func Call[T any](b *Breaker, fn func() (T, error)) (T, error) {
+    var out T
+    err := b.Do(func() error {
+        var err error
+        out, err = fn()
+        return err
+    })
+    return out, err
+}
+
+// allow решает, пропустить ли вызов, и возвращает поколение, в котором он пропущен
+func (b *Breaker) allow() (uint64, bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    switch b.state {
+    case Closed:
+        return b.generation, true
+    case Open:
+        if time.Since(b.openedAt) < b.cooldown {
+            return 0, false
+        }
+        b.setState(HalfOpen)
+    }
+    // Полуоткрыт: один пробный вызов, остальные ждут его итога снаружи
+    if b.probing {
+        return 0, false
+    }
+    b.probing = true
+    return b.generation, true
+}
+
+// record учитывает итог вызова из поколения gen. Запоздалый итог вызова,
+// пропущенного до смены состояния, отбрасывается: иначе медленный запрос,
+// начатый до размыкания, решил бы судьбу пробного вызова.
+func (b *Breaker) record(gen uint64, err error) {
+    failed := err != nil && (b.IsFailure == nil || b.IsFailure(err))
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if gen != b.generation {
+        return
+    }
+    if b.state == HalfOpen {
+        b.probing = false
+        if failed {
+            b.trip()
+        } else {
+            b.failures = 0
+            b.setState(Closed)
+        }
+        return
+    }
+
+    if !failed {
+        b.failures = 0
+        return
+    }
+    b.failures++
+    if b.state == Closed && b.failures >= b.threshold {
+        b.trip()
+    }
+}
+
+func (b *Breaker) trip() {
+    b.openedAt = time.Now()
+    b.setState(Open)
+}
+
+func (b *Breaker) setState(s State) {
+    b.state = s
+    b.generation++
+    metrics.BreakerState.WithLabelValues(b.name).Set(float64(s))
+}
@@ -0,0 +1,142 @@
+package breaker
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+var errDown = errors.New("connection refused")
+
+func fail() error { return errDown }
+func ok() error   { return nil }
+
+func TestBreakerOpensAfterThresholdAndProbes(t *testing.T) {
+    b := New("test-threshold", 3, 20*time.Millisecond)
+    for range 2 {
+        _ = b.Do(fail)
+    }
+    // Успех сбрасывает счётчик: сбои должны идти подряд
+    _ = b.Do(ok)
+    for range 2 {
+        _ = b.Do(fail)
+    }
+    if b.State() != Closed {
+        t.Fatalf("после сбоев не подряд состояние %s, нужно closed", b.State())
+    }
+    _ = b.Do(fail)
+    if b.State() != Open {
+        t.Fatalf("после трёх сбоев подряд состояние %s, нужно open", b.State())
+    }
+    if err := b.Do(ok); !errors.Is(err, ErrOpen) {
+        t.Fatalf("разомкнутый предохранитель пропустил вызов: %v", err)
+    }
+
+    time.Sleep(25 * time.Millisecond)
+    if b.State() != HalfOpen {
+        t.Fatalf("после паузы состояние %s, нужно half-open", b.State())
+    }
+    if err := b.Do(ok); err != nil {
+        t.Fatalf("пробный вызов: %v", err)
+    }
+    if b.State() != Closed {
+        t.Fatalf("после удачной пробы состояние %s, нужно closed", b.State())
+    }
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+    b := New("test-probe", 1, 20*time.Millisecond)
+    _ = b.Do(fail)
+    time.Sleep(25 * time.Millisecond)
+    _ = b.Do(fail)
+    if b.State() != Open {
+        t.Fatalf("после неудачной пробы состояние %s, нужно open", b.State())
+    }
+}
+
+// Пока идёт проба, остальные вызовы отклоняются
+func TestBreakerSingleProbe(t *testing.T) {
+    b := New("test-single", 1, 10*time.Millisecond)
+    _ = b.Do(fail)
+    time.Sleep(15 * time.Millisecond)
+
+    probing := make(chan struct{})
+    release := make(chan struct{})
+    done := make(chan error)
+    go func() {
+        done <- b.Do(func() error {
+            close(probing)
+            <-release
+            return nil
+        })
+    }()
+    <-probing
+    if err := b.Do(ok); !errors.Is(err, ErrOpen) {
+        t.Fatalf("второй вызов во время пробы: %v, нужен ErrOpen", err)
+    }
+    close(release)
+    if err := <-done; err != nil {
+        t.Fatal(err)
+    }
+    if b.State() != Closed {
+        t.Fatalf("после пробы состояние %s", b.State())
+    }
+}
+
+// Медленный вызов, пропущенный до размыкания, своим запоздалым итогом не
+// решает судьбу пробы и не замыкает предохранитель
+func TestBreakerIgnoresLateResults(t *testing.T) {
+    b := New("test-late", 1, 10*time.Millisecond)
+
+    slow := make(chan struct{})
+    release := make(chan struct{})
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        _ = b.Do(func() error {
+            close(slow)
+            <-release
+            return nil
+        })
+    }()
+    <-slow
+    _ = b.Do(fail)
+    time.Sleep(15 * time.Millisecond)
+
+    probing := make(chan struct{})
+    probeRelease := make(chan struct{})
+    probeDone := make(chan struct{})
+    go func() {
+        defer close(probeDone)
+        _ = b.Do(func() error {
+            close(probing)
+            <-probeRelease
+            return errDown
+        })
+    }()
+    <-probing
+
+    // Запоздалый успех не должен закрыть предохранитель вместо пробы
+    close(release)
+    <-done
+    if b.State() != HalfOpen {
+        t.Fatalf("запоздалый успех сменил состояние на %s", b.State())
+    }
+    close(probeRelease)
+    <-probeDone
+    if b.State() != Open {
+        t.Fatalf("после неудачной пробы состояние %s, нужно open", b.State())
+    }
+}
+
+func TestBreakerIsFailure(t *testing.T) {
+    b := New("test-is-failure", 1, time.Minute)
+    errNotFound := errors.New("не найдено")
+    b.IsFailure = func(err error) bool { return !errors.Is(err, errNotFound) }
+    for range 3 {
+        _ = b.Do(func() error { return errNotFound })
+    }
+    if b.State() != Closed {
+        t.Fatalf("ошибки, не считающиеся сбоем, разомкнули предохранитель: %s", b.State())
+    }
+}
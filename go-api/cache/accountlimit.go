@@ -0,0 +1,100 @@
+package cache
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "ai_seller/metrics"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// accountWindow — окно лимитов аккаунта OpenAI: RPM и TPM считаются по минутам
+const accountWindow = time.Minute
+
+// ErrAccountLimit — лимит аккаунта OpenAI на текущую минуту исчерпан и не
+// освободился за время ожидания
+var ErrAccountLimit = errors.New("исчерпан минутный лимит аккаунта OpenAI")
+
+// reserveScript учитывает запрос в окне, только если он укладывается в оба
+// лимита (0 — лимита нет). Проверка и увеличение — один скрипт, иначе
+// экземпляры сервиса между GET и INCR вместе перешагнули бы лимит.
+// Возвращает {1 или 0, запросов в окне, токенов в окне}.
+var reserveScript = redis.NewScript(`
+local requests = tonumber(redis.call("GET", KEYS[1]) or "0")
+local tokens = tonumber(redis.call("GET", KEYS[2]) or "0")
+local rpm, tpm, cost = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+if (rpm > 0 and requests + 1 > rpm) or (tpm > 0 and tokens > 0 and tokens + cost > tpm) then
+    return {0, requests, tokens}
+end
+requests = redis.call("INCR", KEYS[1])
+tokens = redis.call("INCRBY", KEYS[2], cost)
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+redis.call("PEXPIRE", KEYS[2], ARGV[4])
+return {1, requests, tokens}`)
+
+// AccountLimiter — общий для всех экземпляров сервиса лимит запросов и
+// токенов в минуту на аккаунт OpenAI. Окна фиксированные, как у RateLimiter;
+// токены — оценка до запроса, фактический расход считает UsageCounter.
+type AccountLimiter struct {
+    rdb *redis.Client
+    rpm int64
+    tpm int64
+    // maxWait — сколько запрос может ждать следующей минуты, прежде чем
+    // получить ErrAccountLimit
+    maxWait time.Duration
+}
+
+// NewAccountLimiter — фабрика лимитера на rpm запросов и tpm токенов в минуту
+// (0 — без этого лимита); maxWait — предел ожидания освобождения окна
+func NewAccountLimiter(rdb *redis.Client, rpm, tpm int64, maxWait time.Duration) *AccountLimiter {
+    return &AccountLimiter{rdb: rdb, rpm: rpm, tpm: tpm, maxWait: maxWait}
+}
+
+// Wait учитывает запрос примерно на tokens токенов. Если в текущей минуте
+// места нет, ждёт следующую — но не дольше maxWait и дедлайна ctx, иначе
+// возвращает ErrAccountLimit. Запрос больше всего TPM пропускается в пустое
+// окно, чтобы не ждать вечно.
+func (l *AccountLimiter) Wait(ctx context.Context, tokens int64) error {
+    giveUp := time.Now().Add(l.maxWait)
+    for {
+        now := time.Now()
+        ok, err := l.reserve(ctx, now, tokens)
+        if err != nil {
+            return err
+        }
+        if ok {
+            return nil
+        }
+
+        next := now.Truncate(accountWindow).Add(accountWindow)
+        deadline, hasDeadline := ctx.Deadline()
+        if next.After(giveUp) || (hasDeadline && next.After(deadline)) {
+            metrics.OpenAIAccountThrottledTotal.Inc()
+            return ErrAccountLimit
+        }
+        select {
+        case <-time.After(time.Until(next)):
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+// reserve — одна попытка учесть запрос в окне момента now
+func (l *AccountLimiter) reserve(ctx context.Context, now time.Time, tokens int64) (bool, error) {
+    bucket := now.Unix() / int64(accountWindow/time.Second)
+    keys := []string{
+        fmt.Sprintf("openai:rpm:%d", bucket),
+        fmt.Sprintf("openai:tpm:%d", bucket),
+    }
+    res, err := reserveScript.Run(ctx, l.rdb, keys, l.rpm, l.tpm, tokens, (2 * accountWindow).Milliseconds()).Int64Slice()
+    if err != nil {
+        return false, fmt.Errorf("ошибка проверки лимита аккаунта OpenAI в Redis: %w", err)
+    }
+    metrics.OpenAIAccountRequests.Set(float64(res[1]))
+    metrics.OpenAIAccountTokens.Set(float64(res[2]))
+    return res[0] == 1, nil
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+    "context"
+    "errors"
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/redistest"
+)
+
+// handleReserve подменяет reserveScript теми же командами на Go: redistest
+// не выполняет Lua, но скрипт, как и в Redis, идёт целиком под блокировкой сервера
+func handleReserve(s *redistest.Server) {
+    s.HandleScript(reserveScript.Hash(), func(call func(args ...string) any, keys, args []string) any {
+        requests, tokens := scriptInt(call("GET", keys[0])), scriptInt(call("GET", keys[1]))
+        rpm, tpm, cost := scriptInt(args[0]), scriptInt(args[1]), scriptInt(args[2])
+        if (rpm > 0 && requests+1 > rpm) || (tpm > 0 && tokens > 0 && tokens+cost > tpm) {
+            return []any{int64(0), requests, tokens}
+        }
+        requests = scriptInt(call("INCR", keys[0]))
+        tokens = scriptInt(call("INCRBY", keys[1], args[2]))
+        call("PEXPIRE", keys[0], args[3])
+        call("PEXPIRE", keys[1], args[3])
+        return []any{int64(1), requests, tokens}
+    })
+}
+
+// scriptInt — число из ответа команды или аргумента скрипта; nil — 0, как tonumber(... or "0")
+func scriptInt(v any) int64 {
+    switch v := v.(type) {
+    case int64:
+        return v
+    case []byte:
+        n, _ := strconv.ParseInt(string(v), 10, 64)
+        return n
+    case string:
+        n, _ := strconv.ParseInt(v, 10, 64)
+        return n
+    }
+    return 0
+}
+
+// Одновременные запросы всех экземпляров укладываются в RPM: ровно rpm из
+// них проходят, остальные без ожидания получают ErrAccountLimit
+func TestAccountLimiterConcurrentWait(t *testing.T) {
+    // Окно фиксированное: запросы, разошедшиеся по двум минутам, прошли бы оба раза
+    if left := time.Until(time.Now().Truncate(accountWindow).Add(accountWindow)); left < 2*time.Second {
+        time.Sleep(left + 10*time.Millisecond)
+    }
+    srv, rdb := redistest.NewClient(t)
+    handleReserve(srv)
+    const rpm, callers = 5, 20
+    limiter := NewAccountLimiter(rdb, rpm, 0, 0)
+
+    var (
+        wg       sync.WaitGroup
+        mu       sync.Mutex
+        admitted int
+        limited  int
+    )
+    for range callers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            err := limiter.Wait(context.Background(), 100)
+            mu.Lock()
+            defer mu.Unlock()
+            switch {
+            case err == nil:
+                admitted++
+            case errors.Is(err, ErrAccountLimit):
+                limited++
+            default:
+                t.Errorf("Wait: %v", err)
+            }
+        }()
+    }
+    wg.Wait()
+    if admitted != rpm || limited != callers-rpm {
+        t.Fatalf("прошло %d, отклонено %d; ожидалось %d и %d", admitted, limited, rpm, callers-rpm)
+    }
+}
+
+// Запрос, не уместившийся в TPM, ждёт следующей минуты не дольше дедлайна
+// ctx; запрос, который в остаток укладывается, проходит сразу
+func TestAccountLimiterTokens(t *testing.T) {
+    if left := time.Until(time.Now().Truncate(accountWindow).Add(accountWindow)); left < 2*time.Second {
+        time.Sleep(left + 10*time.Millisecond)
+    }
+    srv, rdb := redistest.NewClient(t)
+    handleReserve(srv)
+    ctx := context.Background()
+
+    limiter := NewAccountLimiter(rdb, 0, 1000, time.Hour)
+    if err := limiter.Wait(ctx, 800); err != nil {
+        t.Fatalf("первый запрос: %v", err)
+    }
+    // До следующей минуты дальше дедлайна ctx — ждать её нет смысла
+    short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+    defer cancel()
+    if err := limiter.Wait(short, 300); !errors.Is(err, ErrAccountLimit) {
+        t.Fatalf("сверх TPM: получено %v, ожидалось ErrAccountLimit", err)
+    }
+    if err := NewAccountLimiter(rdb, 0, 1000, time.Hour).Wait(ctx, 200); err != nil {
+        t.Fatalf("в пределах TPM: %v", err)
+    }
+}
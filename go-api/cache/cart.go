@@ -0,0 +1,168 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// cartTTL — корзина живёт сутки с последнего изменения
+const cartTTL = 24 * time.Hour
+
+// cartActivityKey — sorted set chat_id → время последнего изменения корзины
+// (unix). По нему ищутся брошенные корзины; напомненные из него убираются.
+const cartActivityKey = "carts:activity"
+
+// CartItem — позиция корзины
+type CartItem struct {
+    ProductID int64
+    Qty       int
+}
+
+// Cart — корзина чата
+type Cart struct {
+    ChatID int64
+    Items  []CartItem
+}
+
+// Empty — в корзине нет позиций
+func (c Cart) Empty() bool {
+    return len(c.Items) == 0
+}
+
+// CartStore — корзины в Redis: хэш cart:<chat_id> вида product_id → количество
+type CartStore struct {
+    rdb *redis.Client
+}
+
+// NewCartStore — фабрика хранилища корзин
+func NewCartStore(rdb *redis.Client) *CartStore {
+    return &CartStore{rdb: rdb}
+}
+
+func cartKey(chatID int64) string {
+    return fmt.Sprintf("cart:%d", chatID)
+}
+
+// AddItem добавляет qty единиц товара (к уже имеющимся) и продлевает жизнь корзины
+func (s *CartStore) AddItem(ctx context.Context, chatID, productID int64, qty int) error {
+    if qty <= 0 {
+        return fmt.Errorf("количество должно быть положительным, получено %d", qty)
+    }
+
+    key := cartKey(chatID)
+    _, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        pipe.HIncrBy(ctx, key, strconv.FormatInt(productID, 10), int64(qty))
+        pipe.Expire(ctx, key, cartTTL)
+        touchCart(ctx, pipe, chatID)
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("ошибка добавления в корзину: %w", err)
+    }
+    return nil
+}
+
+// RemoveItem убирает товар из корзины целиком
+func (s *CartStore) RemoveItem(ctx context.Context, chatID, productID int64) error {
+    key := cartKey(chatID)
+    _, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        pipe.HDel(ctx, key, strconv.FormatInt(productID, 10))
+        pipe.Expire(ctx, key, cartTTL)
+        touchCart(ctx, pipe, chatID)
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("ошибка удаления из корзины: %w", err)
+    }
+    return nil
+}
+
+// GetCart возвращает корзину чата; позиции упорядочены по id товара
+func (s *CartStore) GetCart(ctx context.Context, chatID int64) (Cart, error) {
+    raw, err := s.rdb.HGetAll(ctx, cartKey(chatID)).Result()
+    if err != nil {
+        return Cart{}, fmt.Errorf("ошибка чтения корзины: %w", err)
+    }
+
+    cart := Cart{ChatID: chatID}
+    for field, val := range raw {
+        productID, err := strconv.ParseInt(field, 10, 64)
+        if err != nil {
+            return Cart{}, fmt.Errorf("повреждённая позиция корзины %q: %w", field, err)
+        }
+        qty, err := strconv.Atoi(val)
+        if err != nil {
+            return Cart{}, fmt.Errorf("повреждённое количество в корзине %q: %w", val, err)
+        }
+        if qty > 0 {
+            cart.Items = append(cart.Items, CartItem{ProductID: productID, Qty: qty})
+        }
+    }
+
+    sort.Slice(cart.Items, func(i, j int) bool {
+        return cart.Items[i].ProductID < cart.Items[j].ProductID
+    })
+    return cart, nil
+}
+
+// ClearCart удаляет корзину чата
+func (s *CartStore) ClearCart(ctx context.Context, chatID int64) error {
+    _, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        pipe.Del(ctx, cartKey(chatID))
+        pipe.ZRem(ctx, cartActivityKey, chatID)
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("ошибка очистки корзины: %w", err)
+    }
+    return nil
+}
+
+// touchCart отмечает изменение корзины: после него о корзине можно напомнить снова
+func touchCart(ctx context.Context, pipe redis.Pipeliner, chatID int64) {
+    pipe.ZAdd(ctx, cartActivityKey, redis.Z{Score: float64(time.Now().Unix()), Member: chatID})
+}
+
+// IdleCarts возвращает чаты, чьи корзины не менялись дольше idle и ещё
+// не истекли. Записи истёкших корзин попутно удаляются.
+func (s *CartStore) IdleCarts(ctx context.Context, idle time.Duration) ([]int64, error) {
+    now := time.Now()
+    expired := strconv.FormatInt(now.Add(-cartTTL).Unix(), 10)
+    if err := s.rdb.ZRemRangeByScore(ctx, cartActivityKey, "-inf", "("+expired).Err(); err != nil {
+        return nil, fmt.Errorf("ошибка очистки активности корзин: %w", err)
+    }
+
+    members, err := s.rdb.ZRangeByScore(ctx, cartActivityKey, &redis.ZRangeBy{
+        Min: expired,
+        Max: strconv.FormatInt(now.Add(-idle).Unix(), 10),
+    }).Result()
+    if err != nil {
+        return nil, fmt.Errorf("ошибка поиска брошенных корзин: %w", err)
+    }
+
+    chatIDs := make([]int64, 0, len(members))
+    for _, m := range members {
+        id, err := strconv.ParseInt(m, 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("повреждённый id чата в активности корзин %q: %w", m, err)
+        }
+        chatIDs = append(chatIDs, id)
+    }
+    return chatIDs, nil
+}
+
+// ClaimReminder отмечает, что о корзине чата напомнили. true — отметка
+// поставлена этим вызовом; false — напоминание уже отправлено (в том числе
+// другим экземпляром сервиса) и повторять его не нужно.
+func (s *CartStore) ClaimReminder(ctx context.Context, chatID int64) (bool, error) {
+    n, err := s.rdb.ZRem(ctx, cartActivityKey, chatID).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки напоминания о корзине: %w", err)
+    }
+    return n > 0, nil
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// chatLockRetry — как часто пробовать занять блокировку, пока её держит другой
+const chatLockRetry = 50 * time.Millisecond
+
+// ErrChatLocked — блокировка чата не освободилась до отмены контекста
+var ErrChatLocked = errors.New("чат занят обработкой другого апдейта")
+
+// unlockScript снимает блокировку, только если она всё ещё своя: после
+// истечения TTL ключ мог занять другой обработчик
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// UnlockScriptHash — SHA1 скрипта снятия блокировки: по нему тесты
+// подменяют скрипт в redistest (redistest.DelIfEquals)
+func UnlockScriptHash() string {
+    return unlockScript.Hash()
+}
+
+// ChatLocker — блокировки чатов в Redis: апдейты одного чата обрабатываются
+// по очереди даже на нескольких обработчиках и экземплярах сервиса
+type ChatLocker struct {
+    rdb *redis.Client
+    // ttl — страховка на случай падения держателя; должна превышать время
+    // обработки апдейта, иначе блокировка истечёт посреди обработки
+    ttl time.Duration
+}
+
+// NewChatLocker — фабрика блокировок чатов с временем жизни ttl
+func NewChatLocker(rdb *redis.Client, ttl time.Duration) *ChatLocker {
+    return &ChatLocker{rdb: rdb, ttl: ttl}
+}
+
+// Lock ждёт освобождения чата и занимает его (SET NX PX). Возвращает функцию
+// снятия блокировки; ErrChatLocked — если ctx истёк раньше, ошибку Redis —
+// если блокировку проверить не удалось.
+func (l *ChatLocker) Lock(ctx context.Context, chatID int64) (func(), error) {
+    key := fmt.Sprintf("lock:chat:%d", chatID)
+    var buf [16]byte
+    rand.Read(buf[:])
+    token := hex.EncodeToString(buf[:])
+
+    for {
+        ok, err := l.rdb.SetNX(ctx, key, token, l.ttl).Result()
+        if err != nil && ctx.Err() == nil {
+            return nil, fmt.Errorf("ошибка блокировки чата в Redis: %w", err)
+        }
+        if ok {
+            return func() {
+                // Контекст обработки к этому моменту мог истечь — снимаем отдельно
+                ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+                defer cancel()
+                unlockScript.Run(ctx, l.rdb, []string{key}, token)
+            }, nil
+        }
+
+        timer := time.NewTimer(chatLockRetry)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return nil, fmt.Errorf("%w: %w", ErrChatLocked, ctx.Err())
+        case <-timer.C:
+        }
+    }
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "ai_seller/redistest"
+)
+
+// lockedChat — блокировки чатов на сервере, где скрипт снятия подменён
+func lockedChat(t *testing.T, ttl time.Duration) (*redistest.Server, *ChatLocker) {
+    t.Helper()
+    srv, rdb := redistest.NewClient(t)
+    srv.HandleScript(UnlockScriptHash(), redistest.DelIfEquals)
+    return srv, NewChatLocker(rdb, ttl)
+}
+
+// Второй Lock того же чата ждёт, пока первый не снимет блокировку
+func TestChatLockerWaitsForRelease(t *testing.T) {
+    _, locks := lockedChat(t, time.Minute)
+    ctx := context.Background()
+    unlock, err := locks.Lock(ctx, 42)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+    defer cancel()
+    if _, err := locks.Lock(short, 42); !errors.Is(err, ErrChatLocked) {
+        t.Fatalf("занятый чат: получено %v, ожидалось ErrChatLocked", err)
+    }
+    if _, err := locks.Lock(ctx, 43); err != nil {
+        t.Fatalf("другой чат: %v", err)
+    }
+
+    unlock()
+    again, cancel := context.WithTimeout(ctx, time.Second)
+    defer cancel()
+    if _, err := locks.Lock(again, 42); err != nil {
+        t.Fatalf("после снятия: %v", err)
+    }
+}
+
+// Блокировка, истёкшая у первого держателя и занятая вторым, не снимается
+// запоздалым unlock первого
+func TestChatLockerKeepsForeignLock(t *testing.T) {
+    srv, locks := lockedChat(t, time.Second)
+    ctx := context.Background()
+    stale, err := locks.Lock(ctx, 42)
+    if err != nil {
+        t.Fatal(err)
+    }
+    srv.FastForward(2 * time.Second)
+    current, err := locks.Lock(ctx, 42)
+    if err != nil {
+        t.Fatalf("истёкшая блокировка не освободилась: %v", err)
+    }
+
+    stale()
+    short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+    defer cancel()
+    if _, err := locks.Lock(short, 42); !errors.Is(err, ErrChatLocked) {
+        t.Fatalf("после чужого unlock: получено %v, блокировка должна остаться", err)
+    }
+
+    current()
+    if keys := srv.Keys(); len(keys) != 0 {
+        t.Fatalf("после своего unlock остались ключи %v", keys)
+    }
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// updateTTL — сколько помнить обработанный update_id; Telegram повторяет
+// доставку в течение нескольких минут, дольше хранить незачем
+const updateTTL = 10 * time.Minute
+
+// UpdateDeduper отсекает повторные доставки апдейтов Telegram по update_id
+type UpdateDeduper struct {
+    rdb *redis.Client
+}
+
+// NewUpdateDeduper — фабрика дедупликатора апдейтов
+func NewUpdateDeduper(rdb *redis.Client) *UpdateDeduper {
+    return &UpdateDeduper{rdb: rdb}
+}
+
+// FirstSeen атомарно отмечает апдейт и сообщает, пришёл ли он впервые
+func (d *UpdateDeduper) FirstSeen(ctx context.Context, updateID int64) (bool, error) {
+    ok, err := d.rdb.SetNX(ctx, fmt.Sprintf("update:%d", updateID), 1, updateTTL).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка дедупликации апдейта в Redis: %w", err)
+    }
+    return ok, nil
+}
+
+// ClaimRetry отмечает, что запись журнала необработанных апдейтов
+// повторяется, и сообщает, не повторяют ли её уже: два /retryfailed подряд
+// не должны обработать один апдейт дважды. Отметку снимает ReleaseRetry;
+// если процесс упал, она истекает через updateTTL.
+func (d *UpdateDeduper) ClaimRetry(ctx context.Context, failedID int64) (bool, error) {
+    ok, err := d.rdb.SetNX(ctx, retryKey(failedID), 1, updateTTL).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки повтора апдейта в Redis: %w", err)
+    }
+    return ok, nil
+}
+
+// ReleaseRetry снимает отметку ClaimRetry после повтора
+func (d *UpdateDeduper) ReleaseRetry(ctx context.Context, failedID int64) error {
+    if err := d.rdb.Del(ctx, retryKey(failedID)).Err(); err != nil {
+        return fmt.Errorf("ошибка снятия отметки повтора апдейта в Redis: %w", err)
+    }
+    return nil
+}
+
+func retryKey(failedID int64) string {
+    return fmt.Sprintf("update:retry:%d", failedID)
+}
+
+// Forget снимает отметку апдейта: он не обработан и придёт повторно
+func (d *UpdateDeduper) Forget(ctx context.Context, updateID int64) error {
+    if err := d.rdb.Del(ctx, fmt.Sprintf("update:%d", updateID)).Err(); err != nil {
+        return fmt.Errorf("ошибка снятия отметки апдейта в Redis: %w", err)
+    }
+    return nil
+}
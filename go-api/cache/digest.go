@@ -0,0 +1,35 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// digestMarkTTL — отметка об отправленной сводке живёт дольше недельного периода
+const digestMarkTTL = 8 * 24 * time.Hour
+
+// DigestMarks — отметки об отправленных сводках заказов: одна сводка за
+// период, даже если экземпляров бота несколько или бот перезапустился
+type DigestMarks struct {
+    rdb *redis.Client
+}
+
+// NewDigestMarks — фабрика отметок о сводках
+func NewDigestMarks(rdb *redis.Client) *DigestMarks {
+    return &DigestMarks{rdb: rdb}
+}
+
+// Claim отмечает сводку за период, закончившийся в end. true — сводку
+// ещё никто не отправлял и отправить её должен вызывающий.
+func (d *DigestMarks) Claim(ctx context.Context, end time.Time) (bool, error) {
+    key := "digest:sent:" + strconv.FormatInt(end.Unix(), 10)
+    ok, err := d.rdb.SetNX(ctx, key, 1, digestMarkTTL).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки сводки заказов: %w", err)
+    }
+    return ok, nil
+}
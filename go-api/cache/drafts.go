@@ -0,0 +1,32 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// DraftOffers — отметки о предложении вернуть черновик заказа в корзину:
+// покупатель видит его один раз на черновик, даже если экземпляров бота несколько
+type DraftOffers struct {
+    rdb *redis.Client
+}
+
+// NewDraftOffers — фабрика отметок о предложенных черновиках
+func NewDraftOffers(rdb *redis.Client) *DraftOffers {
+    return &DraftOffers{rdb: rdb}
+}
+
+// Claim отмечает черновик orderID как предложенный на ttl — столько, сколько
+// черновик вообще предлагается. true — его ещё не предлагали.
+func (d *DraftOffers) Claim(ctx context.Context, orderID int64, ttl time.Duration) (bool, error) {
+    key := "draft:offered:" + strconv.FormatInt(orderID, 10)
+    ok, err := d.rdb.SetNX(ctx, key, 1, max(ttl, time.Second)).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки предложенного черновика: %w", err)
+    }
+    return ok, nil
+}
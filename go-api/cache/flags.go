@@ -0,0 +1,73 @@
+package cache
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// flagOverridesKey — hash «имя флага → true/false»
+const flagOverridesKey = "flags:overrides"
+
+// FlagOverrides — переключения флагов функций, заданные админом на лету.
+// Хранятся в Redis, поэтому общие для всех экземпляров и переживают перезапуск.
+type FlagOverrides struct {
+    rdb *redis.Client
+}
+
+// NewFlagOverrides — фабрика хранилища переключений флагов
+func NewFlagOverrides(rdb *redis.Client) *FlagOverrides {
+    return &FlagOverrides{rdb: rdb}
+}
+
+// Overrides возвращает все переключения; флагов без переключения в ответе нет
+func (f *FlagOverrides) Overrides(ctx context.Context) (map[string]bool, error) {
+    raw, err := f.rdb.HGetAll(ctx, flagOverridesKey).Result()
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения переключений флагов: %w", err)
+    }
+    out := make(map[string]bool, len(raw))
+    for name, v := range raw {
+        on, err := strconv.ParseBool(v)
+        if err != nil {
+            continue
+        }
+        out[name] = on
+    }
+    return out, nil
+}
+
+// Override возвращает переключение флага; ok=false — флаг не переключали
+func (f *FlagOverrides) Override(ctx context.Context, name string) (on, ok bool, err error) {
+    v, err := f.rdb.HGet(ctx, flagOverridesKey, name).Result()
+    if errors.Is(err, redis.Nil) {
+        return false, false, nil
+    }
+    if err != nil {
+        return false, false, fmt.Errorf("ошибка чтения переключения флага %s: %w", name, err)
+    }
+    on, err = strconv.ParseBool(v)
+    if err != nil {
+        return false, false, nil
+    }
+    return on, true, nil
+}
+
+// Set переключает флаг поверх значения из окружения
+func (f *FlagOverrides) Set(ctx context.Context, name string, on bool) error {
+    if err := f.rdb.HSet(ctx, flagOverridesKey, name, strconv.FormatBool(on)).Err(); err != nil {
+        return fmt.Errorf("ошибка переключения флага %s: %w", name, err)
+    }
+    return nil
+}
+
+// Reset снимает переключение: флаг снова берётся из окружения
+func (f *FlagOverrides) Reset(ctx context.Context, name string) error {
+    if err := f.rdb.HDel(ctx, flagOverridesKey, name).Err(); err != nil {
+        return fmt.Errorf("ошибка сброса флага %s: %w", name, err)
+    }
+    return nil
+}
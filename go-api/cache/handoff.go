@@ -0,0 +1,54 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// Handoffs — чаты, переданные живому оператору: пока отметка стоит, бот
+// не отвечает покупателю сам. Отметка снимается /resolve или истекает через
+// ttl, чтобы забытый админом чат не остался без ответов навсегда.
+type Handoffs struct {
+    rdb *redis.Client
+    ttl time.Duration
+}
+
+// NewHandoffs — фабрика отметок о передаче оператору на срок ttl
+func NewHandoffs(rdb *redis.Client, ttl time.Duration) *Handoffs {
+    return &Handoffs{rdb: rdb, ttl: ttl}
+}
+
+func handoffKey(chatID int64) string {
+    return fmt.Sprintf("handoff:%d", chatID)
+}
+
+// Start передаёт чат оператору с причиной reason. false — чат уже передан
+// и админов повторно звать не нужно.
+func (h *Handoffs) Start(ctx context.Context, chatID int64, reason string) (bool, error) {
+    ok, err := h.rdb.SetNX(ctx, handoffKey(chatID), reason, h.ttl).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка передачи чата оператору: %w", err)
+    }
+    return ok, nil
+}
+
+// Active — передан ли чат оператору
+func (h *Handoffs) Active(ctx context.Context, chatID int64) (bool, error) {
+    n, err := h.rdb.Exists(ctx, handoffKey(chatID)).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка чтения передачи чата оператору: %w", err)
+    }
+    return n > 0, nil
+}
+
+// Resolve возвращает чат боту. false — чат и не был передан.
+func (h *Handoffs) Resolve(ctx context.Context, chatID int64) (bool, error) {
+    n, err := h.rdb.Del(ctx, handoffKey(chatID)).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка возврата чата боту: %w", err)
+    }
+    return n > 0, nil
+}
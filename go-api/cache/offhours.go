@@ -0,0 +1,40 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// offHoursNoteTTL — срок отметки, если время открытия неизвестно (в
+// расписании нет рабочих интервалов): фраза повторится раз в сутки
+const offHoursNoteTTL = 24 * time.Hour
+
+// OffHoursNotes — отметки о фразе про нерабочее время: чат получает её один
+// раз за нерабочий период, даже если экземпляров бота несколько
+type OffHoursNotes struct {
+    rdb *redis.Client
+}
+
+// NewOffHoursNotes — фабрика отметок о фразе про нерабочее время
+func NewOffHoursNotes(rdb *redis.Client) *OffHoursNotes {
+    return &OffHoursNotes{rdb: rdb}
+}
+
+// Claim отмечает фразу для чата до открытия магазина в opens (нулевое —
+// неизвестно когда). true — в этот нерабочий период чат её ещё не получал.
+func (n *OffHoursNotes) Claim(ctx context.Context, chatID int64, now, opens time.Time) (bool, error) {
+    ttl := offHoursNoteTTL
+    if !opens.IsZero() {
+        ttl = opens.Sub(now)
+    }
+    key := "offhours:noted:" + strconv.FormatInt(chatID, 10) + ":" + strconv.FormatInt(opens.Unix(), 10)
+    ok, err := n.rdb.SetNX(ctx, key, 1, max(ttl, time.Second)).Result()
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки фразы о нерабочем времени: %w", err)
+    }
+    return ok, nil
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// RateLimiter — ограничение числа сообщений от одного чата в минуту.
+// Счётчик ведётся по фиксированным минутным окнам: ключ содержит номер окна,
+// поэтому с началом новой минуты лимит сбрасывается без гонок.
+type RateLimiter struct {
+    rdb    *redis.Client
+    limit  int64
+    window time.Duration
+}
+
+// NewRateLimiter — фабрика лимитера на perMinute сообщений в минуту
+func NewRateLimiter(rdb *redis.Client, perMinute int) *RateLimiter {
+    return &RateLimiter{rdb: rdb, limit: int64(perMinute), window: time.Minute}
+}
+
+// Allow учитывает сообщение чата и сообщает, укладывается ли оно в лимит
+func (l *RateLimiter) Allow(ctx context.Context, chatID int64) (bool, error) {
+    bucket := time.Now().Unix() / int64(l.window/time.Second)
+    key := fmt.Sprintf("ratelimit:%d:%d", chatID, bucket)
+
+    var incr *redis.IntCmd
+    _, err := l.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        incr = pipe.Incr(ctx, key)
+        pipe.Expire(ctx, key, l.window)
+        return nil
+    })
+    if err != nil {
+        return false, fmt.Errorf("ошибка проверки лимита в Redis: %w", err)
+    }
+    return incr.Val() <= l.limit, nil
+}
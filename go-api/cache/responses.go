@@ -0,0 +1,56 @@
+package cache
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// ResponseCache — готовые ответы модели на одинаковые промпты
+type ResponseCache struct {
+    rdb *redis.Client
+    ttl time.Duration
+}
+
+// NewResponseCache — фабрика кэша ответов; ttl — сколько ответ остаётся актуальным
+func NewResponseCache(rdb *redis.Client, ttl time.Duration) *ResponseCache {
+    return &ResponseCache{rdb: rdb, ttl: ttl}
+}
+
+// ResponseKey — ключ кэша по модели и частям промпта. Части нормализуются
+// (регистр, лишние пробелы), чтобы «Есть доставка?» и «есть  доставка?» совпали.
+func ResponseKey(model string, parts ...string) string {
+    h := sha256.New()
+    h.Write([]byte(model))
+    for _, p := range parts {
+        h.Write([]byte{0})
+        h.Write([]byte(strings.Join(strings.Fields(strings.ToLower(p)), " ")))
+    }
+    return "response:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Get возвращает сохранённый ответ; ok=false — промах
+func (c *ResponseCache) Get(ctx context.Context, key string) (answer string, ok bool, err error) {
+    answer, err = c.rdb.Get(ctx, key).Result()
+    if errors.Is(err, redis.Nil) {
+        return "", false, nil
+    }
+    if err != nil {
+        return "", false, fmt.Errorf("ошибка чтения кэша ответов из Redis: %w", err)
+    }
+    return answer, true, nil
+}
+
+// Set сохраняет ответ на ttl
+func (c *ResponseCache) Set(ctx context.Context, key, answer string) error {
+    if err := c.rdb.Set(ctx, key, answer, c.ttl).Err(); err != nil {
+        return fmt.Errorf("ошибка записи кэша ответов в Redis: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// Message — реплика диалога в кратковременном контексте
+type Message struct {
+    Role    string `json:"role"`
+    Content string `json:"content"`
+    // Incomplete — ответ модели оборвался на середине потока
+    Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// SessionCache — кратковременный контекст диалога в Redis.
+// Хранит последние maxTurns реплик чата и забывает их после ttl бездействия.
+type SessionCache struct {
+    rdb      *redis.Client
+    maxTurns int
+    ttl      time.Duration
+}
+
+// NewSessionCache — фабрика кэша сессий
+func NewSessionCache(rdb *redis.Client, maxTurns int, ttl time.Duration) *SessionCache {
+    return &SessionCache{rdb: rdb, maxTurns: maxTurns, ttl: ttl}
+}
+
+func sessionKey(chatID int64) string {
+    return fmt.Sprintf("session:%d", chatID)
+}
+
+// AppendTurn добавляет реплику в контекст чата и продлевает TTL
+func (c *SessionCache) AppendTurn(ctx context.Context, chatID int64, role, text string) error {
+    return c.append(ctx, chatID, Message{Role: role, Content: text})
+}
+
+// AppendIncompleteTurn добавляет реплику, помеченную незавершённой
+func (c *SessionCache) AppendIncompleteTurn(ctx context.Context, chatID int64, role, text string) error {
+    return c.append(ctx, chatID, Message{Role: role, Content: text, Incomplete: true})
+}
+
+func (c *SessionCache) append(ctx context.Context, chatID int64, m Message) error {
+    payload, err := json.Marshal(m)
+    if err != nil {
+        return fmt.Errorf("ошибка сериализации реплики: %w", err)
+    }
+
+    key := sessionKey(chatID)
+    _, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        pipe.RPush(ctx, key, payload)
+        pipe.LTrim(ctx, key, int64(-c.maxTurns), -1)
+        pipe.Expire(ctx, key, c.ttl)
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("ошибка записи контекста в Redis: %w", err)
+    }
+    return nil
+}
+
+// RecentTurns возвращает сохранённые реплики чата от старых к новым
+func (c *SessionCache) RecentTurns(ctx context.Context, chatID int64) ([]Message, error) {
+    raw, err := c.rdb.LRange(ctx, sessionKey(chatID), 0, -1).Result()
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения контекста из Redis: %w", err)
+    }
+
+    turns := make([]Message, 0, len(raw))
+    for _, item := range raw {
+        var m Message
+        if err := json.Unmarshal([]byte(item), &m); err != nil {
+            return nil, fmt.Errorf("повреждённая реплика в контексте чата %d: %w", chatID, err)
+        }
+        turns = append(turns, m)
+    }
+    return turns, nil
+}
+
+// Clear удаляет контекст чата
+func (c *SessionCache) Clear(ctx context.Context, chatID int64) error {
+    if err := c.rdb.Del(ctx, sessionKey(chatID)).Err(); err != nil {
+        return fmt.Errorf("ошибка очистки контекста в Redis: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// shoppingKey — sorted set chat_id → время, когда покупатель последний раз
+// смотрел товары или менял корзину (unix)
+const shoppingKey = "shopping:activity"
+
+// ShoppingActivity — когда покупатели последний раз смотрели каталог или
+// меняли корзину. Нужна подсказкам замолчавшим покупателям: писать стоит
+// тем, кто выбирал товары, а не просто задавал вопрос. Отметки старше
+// retention удаляются при записи.
+type ShoppingActivity struct {
+    rdb       *redis.Client
+    retention time.Duration
+}
+
+// NewShoppingActivity — фабрика отметок о покупательской активности
+func NewShoppingActivity(rdb *redis.Client, retention time.Duration) *ShoppingActivity {
+    return &ShoppingActivity{rdb: rdb, retention: retention}
+}
+
+// Touch отмечает активность чата в момент now
+func (s *ShoppingActivity) Touch(ctx context.Context, chatID int64, now time.Time) error {
+    _, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        pipe.ZAdd(ctx, shoppingKey, redis.Z{Score: float64(now.Unix()), Member: chatID})
+        pipe.ZRemRangeByScore(ctx, shoppingKey, "-inf", "("+strconv.FormatInt(now.Add(-s.retention).Unix(), 10))
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("ошибка отметки активности покупателя: %w", err)
+    }
+    return nil
+}
+
+// Since — чаты, активные начиная с from
+func (s *ShoppingActivity) Since(ctx context.Context, from time.Time) ([]int64, error) {
+    members, err := s.rdb.ZRangeByScore(ctx, shoppingKey, &redis.ZRangeBy{
+        Min: strconv.FormatInt(from.Unix(), 10),
+        Max: "+inf",
+    }).Result()
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения активности покупателей: %w", err)
+    }
+    ids := make([]int64, 0, len(members))
+    for _, m := range members {
+        id, err := strconv.ParseInt(m, 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("повреждённая отметка активности %q: %w", m, err)
+        }
+        ids = append(ids, id)
+    }
+    return ids, nil
+}
+
+// Forget удаляет отметку чата (удаление данных по запросу покупателя)
+func (s *ShoppingActivity) Forget(ctx context.Context, chatID int64) error {
+    if err := s.rdb.ZRem(ctx, shoppingKey, chatID).Err(); err != nil {
+        return fmt.Errorf("ошибка удаления активности покупателя: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// usageTTL — счётчики месяца храним с запасом, чтобы их можно было посмотреть после его окончания
+const usageTTL = 62 * 24 * time.Hour
+
+// TokenUsage — накопленный расход токенов
+type TokenUsage struct {
+    Prompt     int64
+    Completion int64
+    Total      int64
+}
+
+// UsageCounter — счётчики токенов OpenAI в Redis по месяцам: общий и по чатам
+type UsageCounter struct {
+    rdb *redis.Client
+}
+
+// NewUsageCounter — фабрика счётчиков токенов
+func NewUsageCounter(rdb *redis.Client) *UsageCounter {
+    return &UsageCounter{rdb: rdb}
+}
+
+func usageKey(month string) string {
+    return "usage:" + month
+}
+
+func chatUsageKey(month string, chatID int64) string {
+    return fmt.Sprintf("usage:%s:chat:%d", month, chatID)
+}
+
+// currentMonth — ключ месяца в UTC, например 2026-10
+func currentMonth() string {
+    return time.Now().UTC().Format("2006-01")
+}
+
+// Record добавляет расход токенов к общему счётчику месяца и к счётчику чата
+// (если chatID не 0)
+func (u *UsageCounter) Record(ctx context.Context, chatID int64, usage TokenUsage) error {
+    month := currentMonth()
+    keys := []string{usageKey(month)}
+    if chatID != 0 {
+        keys = append(keys, chatUsageKey(month, chatID))
+    }
+
+    _, err := u.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+        for _, key := range keys {
+            pipe.HIncrBy(ctx, key, "prompt", usage.Prompt)
+            pipe.HIncrBy(ctx, key, "completion", usage.Completion)
+            pipe.HIncrBy(ctx, key, "total", usage.Total)
+            pipe.Expire(ctx, key, usageTTL)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("ошибка учёта токенов: %w", err)
+    }
+    return nil
+}
+
+// MonthTotal — общий расход токенов за текущий месяц
+func (u *UsageCounter) MonthTotal(ctx context.Context) (TokenUsage, error) {
+    return u.read(ctx, usageKey(currentMonth()))
+}
+
+// ChatMonthTotal — расход токенов чата за текущий месяц
+func (u *UsageCounter) ChatMonthTotal(ctx context.Context, chatID int64) (TokenUsage, error) {
+    return u.read(ctx, chatUsageKey(currentMonth(), chatID))
+}
+
+func (u *UsageCounter) read(ctx context.Context, key string) (TokenUsage, error) {
+    raw, err := u.rdb.HGetAll(ctx, key).Result()
+    if err != nil {
+        return TokenUsage{}, fmt.Errorf("ошибка чтения счётчиков токенов: %w", err)
+    }
+
+    parse := func(field string) int64 {
+        v, _ := strconv.ParseInt(raw[field], 10, 64)
+        return v
+    }
+    return TokenUsage{Prompt: parse("prompt"), Completion: parse("completion"), Total: parse("total")}, nil
+}
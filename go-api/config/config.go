@@ -1,58 +1,1179 @@
 package config
 
 import (
-    "log"
+    "cmp"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "net/netip"
+    "net/url"
     "os"
+    "regexp"
+    "strconv"
+    "strings"
     "sync"
+    "time"
+    // База часовых поясов в бинарнике: в минимальных образах её нет
+    _ "time/tzdata"
 
-    "github.com/joho/godotenv"
+    "ai_seller/money"
 )
 
 // Config — структура для хранения конфигурации приложения
 type Config struct {
-    Env         string
-    Port        string
-    PostgresDSN string
-    RedisAddr   string
-    OpenAIKey   string
+    Env  string
+    Port string
+    // StorageDriver — база хранилищ: postgres (PostgresDSN) или sqlite
+    // (файл SQLitePath)
+    StorageDriver string
+    PostgresDSN   string
+    SQLitePath    string
+    RedisAddr     string
+    // DebugHTTP — писать в лог тела HTTP-запросов и ответов и вне development.
+    // В production тела не пишутся и с ним: в них личные данные покупателей
+    DebugHTTP bool
+    // TLSCertFile и TLSKeyFile — сертификат и ключ в PEM: если заданы оба,
+    // сервер сам принимает HTTPS (без обратного прокси)
+    TLSCertFile string
+    TLSKeyFile  string
+    // SeedFile — SQL с начальными данными, применяется один раз при bootstrap
+    // (пусто — без начальных данных)
+    SeedFile string
+
+    DBMaxOpenConns    int
+    DBMaxIdleConns    int
+    DBConnMaxLifetime time.Duration
+    // Пределы на один вызов PostgreSQL, Redis и OpenAI. Дедлайн апдейта
+    // (UpdateTimeout) остаётся в силе: если он ближе, действует он.
+    DBTimeout     time.Duration
+    RedisTimeout  time.Duration
+    OpenAITimeout time.Duration
+    // OpenAIAdaptiveTimeout — подстраивать предел запроса к модели под её
+    // задержки: OpenAITimeoutMultiplier × p95 последних ответов, в границах
+    // от OpenAITimeoutMin до OpenAITimeout. Множитель строго больше 1: иначе
+    // попытки, оборванные по пределу, учитываются ровно с ним, и при росте
+    // задержек предел не растёт.
+    OpenAIAdaptiveTimeout   bool
+    OpenAITimeoutMultiplier float64
+    OpenAITimeoutMin        time.Duration
+    // DBBreakerThreshold — после стольких сбоев PostgreSQL подряд запросы
+    // к нему отклоняются сразу на DBBreakerCooldown
+    DBBreakerThreshold int
+    DBBreakerCooldown  time.Duration
+    // LLMProvider — поставщик модели: openai или ollama
+    LLMProvider string
+    // OpenAIKey — ключ API; для ollama необязателен
+    OpenAIKey string
+    // OpenAIBaseURL — адрес API: OpenAI, Azure OpenAI или совместимый прокси
+    OpenAIBaseURL string
+    // OpenAIProvider — openai или azure (другой заголовок ключа и api-version)
+    OpenAIProvider string
+    // OpenAIAPIVersion — api-version для Azure OpenAI
+    OpenAIAPIVersion string
+
+    OpenAIMaxAttempts int
+    // OpenAIMaxConcurrency — предел одновременных запросов к OpenAI (0 — без ограничения)
+    OpenAIMaxConcurrency int
+    // OpenAIAccountRPM и OpenAIAccountTPM — лимиты аккаунта OpenAI в минуту
+    // на все экземпляры сервиса вместе (0 — не ограничивать)
+    OpenAIAccountRPM int64
+    OpenAIAccountTPM int64
+    // OpenAIAccountWait — сколько запрос ждёт места в минутном лимите аккаунта
+    OpenAIAccountWait time.Duration
+    // LLMAudit — писать запросы к модели и ответы в журнал llm_audit
+    // (личные данные скрываются); выключено по умолчанию
+    LLMAudit bool
+    // LLMAuditSample — доля запросов, попадающих в журнал, 0..1
+    LLMAuditSample float64
+    // LLMAuditRetention — сколько хранить записи журнала llm_audit
+    LLMAuditRetention time.Duration
+
+    OpenAIModel       string
+    OpenAITemperature float64
+    // OpenAIMaxTokens — предел длины ответа (OPENAI_MAX_TOKENS). 0 — без
+    // предела: max_tokens в запрос не передаётся, длину выбирает модель
+    OpenAIMaxTokens int
+    // BriefMaxTokens — предел длины ответа в кратком режиме (/brief)
+    BriefMaxTokens int
+
+    // OllamaBaseURL — OpenAI-совместимый адрес Ollama (LLM_PROVIDER=ollama)
+    OllamaBaseURL string
+    // OllamaModel — локальная модель для текста
+    OllamaModel string
+    // OllamaVisionModel — локальная мультимодальная модель для фото (llava
+    // и т. п.); пусто — фото с Ollama не разбираются
+    OllamaVisionModel string
+    // OllamaTools — модель умеет tool calling (llama3.1, qwen2.5 и т. п.)
+    OllamaTools bool
+
+    // MonthlyTokenBudget — лимит токенов OpenAI на календарный месяц (0 — без лимита)
+    MonthlyTokenBudget int64
+
+    // Features — включённые функции (фото, голос, стриминг и т.д.), см. features.go
+    Features FeatureFlags
+
+    VisionModel string
+
+    // VoiceMaxDuration — голосовые длиннее не распознаются
+    VoiceMaxDuration time.Duration
+    // TranscriptionModel — модель распознавания речи OpenAI
+    TranscriptionModel string
+
+    // SystemPrompt — персона продавца, передаётся модели первым сообщением
+    SystemPrompt string
+    // PromptVariants — варианты системного промпта для A/B-теста; пусто — все
+    // чаты получают SystemPrompt
+    PromptVariants []PromptVariant
+    // WelcomeMessage — ответ на первый /start
+    WelcomeMessage string
+    // WelcomeCategories — кнопки категорий под приветствием (пусто — без кнопок)
+    WelcomeCategories []string
+    // MenuButtons — постоянное меню под полем ввода (пусто — без меню)
+    MenuButtons []MenuButton
+    // WelcomeBackMessage — ответ на повторный /start
+    WelcomeBackMessage string
+    // CampaignCodes — коды рекламных кампаний в ссылках t.me/<бот>?start=<код>;
+    // переход по такой ссылке записывается как источник покупателя
+    CampaignCodes []string
+    // FallbackMessage — ответ, когда OpenAI недоступен после всех повторов
+    FallbackMessage string
+    // UnknownToolReply — ответ, когда модель раз за разом вызывает
+    // несуществующие инструменты
+    UnknownToolReply string
+    // UnknownToolRetries — сколько таких вызовов прощается модели за ответ;
+    // 0 — первый же прекращает ответ
+    UnknownToolRetries int
+
+    // EmbeddingModel — модель эмбеддингов OpenAI для семантического поиска
+    EmbeddingModel string
+    // EmbeddingBatch — сколько товаров индексировать одним запросом эмбеддингов
+    EmbeddingBatch int
+
+    // ResponseCacheTTL — сколько хранится ответ в кэше
+    ResponseCacheTTL time.Duration
+
+    // FAQFile — JSON с типовыми вопросами и ответами (пусто — FAQ выключен)
+    FAQFile string
+    // OutputFilterFile — JSON с правилами фильтра ответов модели (пусто — фильтр выключен)
+    OutputFilterFile string
+    // ModerationWordsFile — JSON в формате фильтра ответов, из которого для
+    // модерации входящих берётся список blocked (пусто — без словаря)
+    ModerationWordsFile string
+    // ModerationThreshold — оценка модерации (0..1), с которой сообщение отклоняется
+    ModerationThreshold float64
+    // FAQThreshold — минимальная похожесть вопроса (0..1) для ответа из FAQ
+    FAQThreshold float64
+    // SearchSimilarity — минимальная триграммная похожесть (0..1) для /search с опечатками
+    SearchSimilarity float64
+    // CatalogImportStrict — /importcatalog отменяет загрузку при первой же ошибке в строках
+    CatalogImportStrict bool
+
+    // DefaultCurrency — валюта магазина: в ней хранятся цены без явной валюты
+    // и через неё пересчитываются остальные
+    DefaultCurrency string
+    // CurrencyRates — курсы из CURRENCY_RATES: сколько единиц валюты за единицу
+    // DefaultCurrency; пустая таблица — цены показываются как есть
+    CurrencyRates *money.Rates
+    // CurrencyRatesURL — JSON-источник курсов к DefaultCurrency (пусто — только CURRENCY_RATES)
+    CurrencyRatesURL string
+    // CurrencyRatesRefresh — как часто обновлять курсы из CurrencyRatesURL
+    CurrencyRatesRefresh time.Duration
+
+    TelegramToken string
+    // BotUsername — username бота без @: по упоминанию бот понимает, что к
+    // нему обращаются в группе. Обычно username приходит из getMe при
+    // старте, переменная нужна, если Bot API тогда не ответил.
+    BotUsername   string
+    WebhookSecret string
+    // WebhookMaxBodyBytes — предел размера тела запроса к /telegram
+    WebhookMaxBodyBytes int64
+    // TelegramMode — как получать апдейты: webhook или polling (getUpdates)
+    TelegramMode string
+    // WebhookURL — публичный https-адрес /telegram; если задан, вебхук регистрируется при старте
+    WebhookURL string
+    // TelegramParseMode — разметка ответов: MarkdownV2, HTML или пусто (простой
+    // текст). Ответы пишутся обычным Markdown, клиент переводит их в этот режим.
+    TelegramParseMode string
+    // ThreadGroupReplies — в группах отвечать цитатой сообщения покупателя,
+    // чтобы было видно, кому и на что бот отвечает
+    ThreadGroupReplies bool
+    // TelegramSendAttempts — сколько раз пробовать отправить сообщение при
+    // сетевых сбоях, 5xx и 429
+    TelegramSendAttempts int
+    // TelegramSendMaxWait — дольше этого отправка не ждёт retry_after при 429
+    // и сдаётся
+    TelegramSendMaxWait time.Duration
+
+    // AllowedOrigins — origin-ы, которым разрешены кросс-доменные запросы ("*" — всем)
+    AllowedOrigins []string
+    // TrustedProxies — сети прокси, чьим X-Forwarded-For и X-Real-IP верим
+    TrustedProxies []netip.Prefix
+
+    // AdminChatIDs — чаты, которым доступны админские команды
+    AdminChatIDs map[int64]bool
+    // AllowedChatIDs — если не пуст, бот отвечает только этим чатам (и админам)
+    AllowedChatIDs map[int64]bool
+    // BlockedChatIDs — чаты, апдейты которых молча игнорируются
+    BlockedChatIDs map[int64]bool
+    // ClosedAccessMessage — ответ чату, которого нет в AllowedChatIDs
+    ClosedAccessMessage string
+    // MaintenanceMessage — ответ на сообщения в режиме обслуживания (флаг maintenance)
+    MaintenanceMessage string
+
+    SessionMaxTurns int
+    SessionTTL      time.Duration
+    // SummaryEvery — после стольких новых реплик за окном истории сводка
+    // обновляется (при включённом флаге summaries)
+    SummaryEvery int
+
+    // TypingDelayPerChar и TypingDelayMax — пауза перед ответом при флаге
+    // typing_delay: столько на символ ответа, но не дольше TypingDelayMax
+    TypingDelayPerChar time.Duration
+    TypingDelayMax     time.Duration
+
+    // ContextTokenBudget — примерный предел токенов на промпт, историю диалога
+    // и новое сообщение вместе
+    ContextTokenBudget int
+
+    RateLimitPerMinute int
+    // RateLimitFailOpen — пропускать сообщения, когда лимитер (Redis) недоступен;
+    // false — отвечать как при превышении лимита
+    RateLimitFailOpen bool
+
+    // UpdateTimeout — предел времени на обработку одного апдейта
+    UpdateTimeout time.Duration
+    // UpdateWorkers — сколько апдейтов вебхука обрабатывается одновременно
+    UpdateWorkers int
+    // ChatLockWait — сколько обработчик очереди ждёт, пока чат занят другим
+    // апдейтом; затем апдейт возвращается в конец очереди
+    ChatLockWait time.Duration
+    // UpdateQueueSize — сколько апдейтов ждёт свободного обработчика в очереди
+    // inprocess (делится поровну между обработчиками — у каждого свои чаты);
+    // лишние отбрасываются
+    UpdateQueueSize int
+    // UpdateTransport — где ждут обработки апдейты: inprocess (в памяти) или
+    // redis (Redis Streams, общий для всех реплик бота)
+    UpdateTransport string
+    // UpdateStream — ключ потока Redis с апдейтами
+    UpdateStream string
+    // UpdateGroup — группа потребителей потока; у всех реплик одна
+    UpdateGroup string
+    // UpdateStreamRetention — сколько хранятся записи потока; старые обрезаются
+    UpdateStreamRetention time.Duration
+    // UpdateClaimAfter — через сколько апдейт упавшей реплики забирает другая
+    UpdateClaimAfter time.Duration
+    // UpdateMaxDeliveries — сколько раз апдейт выдаётся обработчикам, прежде
+    // чем уйти в поток UpdateDeadLetter
+    UpdateMaxDeliveries int64
+    // UpdateDeadLetter — поток Redis для апдейтов, которые не удалось обработать
+    UpdateDeadLetter string
+    // FailedUpdateRetention — сколько хранить апдейты, обработка которых упала
+    FailedUpdateRetention time.Duration
+
+    // PaymentProvider — формат уведомлений об оплате: stripe или hmac;
+    // пусто — приём оплаты выключен
+    PaymentProvider string
+    // PaymentWebhookSecret — общий секрет подписи уведомлений об оплате
+    PaymentWebhookSecret string
+
+    // HandoffKeywords — фразы покупателя (в нижнем регистре), по которым чат
+    // передаётся оператору без запроса к модели. Фразы ищутся целыми словами;
+    // по умолчанию это просьбы позвать человека, а не само слово «оператор»:
+    // оно встречается и в обычных вопросах («оператор связи»).
+    HandoffKeywords []string
+    // HandoffTTL — через сколько переданный оператору чат сам возвращается боту
+    HandoffTTL time.Duration
+
+    // CartReminderAfter — сколько корзина должна простоять без изменений до
+    // напоминания; корзина живёт сутки, поэтому больше суток смысла нет
+    CartReminderAfter time.Duration
+
+    // DraftRestoreWindow — сколько черновик заказа, брошенный посреди
+    // оформления, предлагается вернуть в корзину; более старые не предлагаются
+    DraftRestoreWindow time.Duration
+
+    // ReengageAfter — сколько покупатель должен молчать, прежде чем бот
+    // напишет ему подсказку (FlagReengagement)
+    ReengageAfter time.Duration
+    // ReengageLookback — насколько давним может быть последнее сообщение
+    // покупателя: тем, кто замолчал раньше, подсказка уже не к месту
+    ReengageLookback time.Duration
+    // ReengageCooldown — не больше одной подсказки покупателю за этот срок
+    ReengageCooldown time.Duration
+    // ReengageBatch — сколько подсказок составлять и отправлять за один проход
+    ReengageBatch int
+    // ReengageQuietHours — когда подсказки не отправляются (формат
+    // BUSINESS_HOURS); nil — в любое время
+    ReengageQuietHours *BusinessHours
+
+    // DigestSchedule — сводка заказов админам: daily, weekly или пусто (выключена)
+    DigestSchedule string
+    // DigestAt — время отправки сводки от полуночи в DigestLocation
+    DigestAt time.Duration
+    // DigestLocation — часовой пояс, по которому считаются сутки сводки
+    DigestLocation *time.Location
+    // DigestCSV — прикладывать к сводке CSV со списком заказов
+    DigestCSV bool
+
+    // BusinessHours — часы работы магазина; nil — бот работает без нерабочего времени
+    BusinessHours *BusinessHours
+    // OffHoursMode — что делать вне часов работы: note — модель отвечает, а
+    // перед ответом идёт OffHoursMessage; ack — модель не вызывается,
+    // покупатель получает только OffHoursMessage
+    OffHoursMode string
+    // OffHoursMessage — фраза о нерабочем времени
+    OffHoursMessage string
+    // OffHoursSearch — в режиме ack искать текст сообщения в каталоге, чтобы
+    // покупатель мог сам посмотреть товары до начала рабочего дня
+    OffHoursSearch bool
+
+    // MemberVerifyTimeout — сколько новый участник группы с проверкой
+    // (/verifymembers) может не нажимать кнопку, прежде чем его удалят
+    MemberVerifyTimeout time.Duration
+
+    // ReplyStages — этапы обработки ответа модели перед отправкой, по порядку
+    // (ReplyStageFilter, ReplyStagePause); отправка всегда идёт последней
+    ReplyStages []string
+
+    // lookup — источник переменных, из которого собрана конфигурация; из него
+    // же ReadSystemPrompt перечитывает промпт
+    lookup func(key string) (string, bool)
 }
 
 var (
-    cfg  *Config
-    once sync.Once
+    cfg    *Config
+    cfgErr error
+    once   sync.Once
 )
 
-// LoadConfig загружает конфигурацию только один раз (singleton)
-func LoadConfig() *Config {
-    once.Do(func() {
-        _ = godotenv.Load("../.env") // Загружаем .env файл, если он есть
+// telegramTokenRe — формат токена бота: <id бота>:<секрет>.
+// В секрете помимо букв и цифр встречаются "_" и "-".
+var telegramTokenRe = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
 
-        cfg = &Config{
-            Env:         getEnv("APP_ENV", "development"),
-            Port:        getEnv("PORT", "8080"),
-            PostgresDSN: mustHave("POSTGRES_DSN"),
-            RedisAddr:   mustHave("REDIS_ADDR"),
-            OpenAIKey:   mustHave("OPENAI_KEY"),
+// LoadConfig загружает конфигурацию процесса только один раз (singleton):
+// .env-файлы и окружение через NewConfig. Ошибка тоже кэшируется: все
+// вызовы, в том числе одновременные с первым, получают один и тот же
+// результат, а окружение повторно не читается.
+func LoadConfig() (*Config, error) {
+    once.Do(func() {
+        // Паника при разборе оставила бы once выполненным, а остальные
+        // вызовы получили бы (nil, nil) — вместо этого она становится ошибкой
+        defer func() {
+            if r := recover(); r != nil {
+                cfg, cfgErr = nil, fmt.Errorf("ошибка загрузки конфигурации: %v", r)
+            }
+        }()
+        if err := loadDotEnv(); err != nil {
+            cfgErr = err
+            return
         }
+        cfg, cfgErr = NewConfig()
     })
-    return cfg
+    return cfg, cfgErr
+}
+
+// MigrationConfig — настройки bootstrap базы (--migrate-only): init-контейнеру
+// не нужны ни токены, ни Redis, ни ключи OpenAI
+type MigrationConfig struct {
+    Env string
+    // StorageDriver, PostgresDSN и SQLitePath — см. Config
+    StorageDriver string
+    PostgresDSN   string
+    SQLitePath    string
+    // SeedFile — SQL с начальными данными (см. Config.SeedFile)
+    SeedFile string
+}
+
+// LoadMigrationConfig загружает настройки bootstrap из .env-файлов и
+// окружения; остальные переменные не читаются и не проверяются
+func LoadMigrationConfig() (*MigrationConfig, error) {
+    if err := loadDotEnv(); err != nil {
+        return nil, err
+    }
+    return NewMigrationConfig()
+}
+
+// NewMigrationConfig собирает настройки bootstrap, как NewConfig — полную конфигурацию
+func NewMigrationConfig(opts ...Option) (*MigrationConfig, error) {
+    l := &envLoader{lookup: os.LookupEnv}
+    for _, opt := range opts {
+        opt(l)
+    }
+    c := &MigrationConfig{
+        Env:      l.getEnv("APP_ENV", defaultEnv),
+        SeedFile: l.getEnv("SEED_FILE", ""),
+    }
+    c.StorageDriver, c.PostgresDSN, c.SQLitePath = l.database()
+    if err := l.err(); err != nil {
+        return nil, err
+    }
+    return c, nil
+}
+
+// Option — источник переменных для NewConfig и NewMigrationConfig
+type Option func(*envLoader)
+
+// WithEnv — читать переменные только из env, без окружения процесса
+func WithEnv(env map[string]string) Option {
+    return func(l *envLoader) {
+        l.lookup = func(key string) (string, bool) {
+            val, ok := env[key]
+            return val, ok
+        }
+    }
+}
+
+// WithLookup — читать переменные через lookup (например, с префиксом арендатора)
+func WithLookup(lookup func(key string) (string, bool)) Option {
+    return func(l *envLoader) { l.lookup = lookup }
+}
+
+// NewConfig собирает новую конфигурацию, не трогая singleton LoadConfig:
+// так в одном процессе живут несколько по-разному настроенных экземпляров.
+// Без опций переменные читаются из окружения процесса; .env-файлы
+// загружает только LoadConfig. Все проблемы собираются в одну ошибку.
+func NewConfig(opts ...Option) (*Config, error) {
+    l := &envLoader{lookup: os.LookupEnv}
+    for _, opt := range opts {
+        opt(l)
+    }
+    return l.load()
+}
+
+// ParseConfig собирает конфигурацию из явного набора переменных env;
+// окружение процесса не читается
+func ParseConfig(env map[string]string) (*Config, error) {
+    return NewConfig(WithEnv(env))
+}
+
+// load — читает конфигурацию из переменных загрузчика
+func (l *envLoader) load() (*Config, error) {
+    c := &Config{
+        Env:         l.getEnv("APP_ENV", defaultEnv),
+        DebugHTTP:   l.boolean("DEBUG_HTTP", false),
+        Port:        l.getEnv("PORT", "8080"),
+        RedisAddr:   l.require("REDIS_ADDR"),
+        TLSCertFile: l.getEnv("TLS_CERT_FILE", ""),
+        TLSKeyFile:  l.getEnv("TLS_KEY_FILE", ""),
+
+        SeedFile: l.getEnv("SEED_FILE", ""),
+
+        DBMaxOpenConns:    l.positiveInt("DB_MAX_OPEN_CONNS", 10),
+        DBMaxIdleConns:    l.positiveInt("DB_MAX_IDLE_CONNS", 5),
+        DBConnMaxLifetime: l.duration("DB_CONN_MAX_LIFETIME", time.Hour),
+
+        DBTimeout:     l.duration("DB_TIMEOUT", 2*time.Second),
+        RedisTimeout:  l.duration("REDIS_TIMEOUT", time.Second),
+        OpenAITimeout: l.duration("OPENAI_TIMEOUT", 30*time.Second),
+
+        OpenAIAdaptiveTimeout:   l.boolean("OPENAI_ADAPTIVE_TIMEOUT", false),
+        OpenAITimeoutMultiplier: l.floatInRange("OPENAI_TIMEOUT_MULTIPLIER", 3, 1, 20),
+        OpenAITimeoutMin:        l.duration("OPENAI_TIMEOUT_MIN", 10*time.Second),
+
+        DBBreakerThreshold: l.positiveInt("DB_BREAKER_THRESHOLD", 5),
+        DBBreakerCooldown:  l.duration("DB_BREAKER_COOLDOWN", 30*time.Second),
+        LLMProvider:        l.oneOf("LLM_PROVIDER", "openai", "openai", "ollama"),
+        OpenAIKey:          l.getEnv("OPENAI_KEY", ""),
+        OpenAIBaseURL:      l.baseURL("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+        OpenAIProvider:     l.oneOf("OPENAI_PROVIDER", "openai", "openai", "azure"),
+        OpenAIAPIVersion:   l.getEnv("OPENAI_API_VERSION", ""),
+
+        OpenAIMaxAttempts:    l.positiveInt("OPENAI_MAX_ATTEMPTS", 3),
+        OpenAIMaxConcurrency: l.positiveInt("OPENAI_MAX_CONCURRENCY", 10),
+        OpenAIAccountRPM:     l.nonNegativeInt64("OPENAI_ACCOUNT_RPM", 0),
+        OpenAIAccountTPM:     l.nonNegativeInt64("OPENAI_ACCOUNT_TPM", 0),
+        OpenAIAccountWait:    l.duration("OPENAI_ACCOUNT_WAIT", 5*time.Second),
+
+        LLMAudit:          l.boolean("LLM_AUDIT", false),
+        LLMAuditSample:    l.floatInRange("LLM_AUDIT_SAMPLE", 1, 0, 1),
+        LLMAuditRetention: l.duration("LLM_AUDIT_RETENTION", 30*24*time.Hour),
+
+        OpenAIModel:       l.getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+        OpenAITemperature: l.floatInRange("OPENAI_TEMPERATURE", 0.7, 0, 2),
+        OpenAIMaxTokens:   l.nonNegativeInt("OPENAI_MAX_TOKENS", 0),
+        BriefMaxTokens:    l.positiveInt("BRIEF_MAX_TOKENS", 150),
+
+        OllamaBaseURL:     l.baseURL("OLLAMA_BASE_URL", "http://localhost:11434/v1"),
+        OllamaModel:       l.getEnv("OLLAMA_MODEL", "llama3.1"),
+        OllamaVisionModel: l.getEnv("OLLAMA_VISION_MODEL", ""),
+        OllamaTools:       l.boolean("OLLAMA_TOOLS", false),
+
+        MonthlyTokenBudget: l.nonNegativeInt64("MONTHLY_TOKEN_BUDGET", 0),
+
+        Features: l.featureFlags(),
+
+        VisionModel: l.getEnv("OPENAI_VISION_MODEL", "gpt-4o-mini"),
+
+        VoiceMaxDuration:   l.duration("VOICE_MAX_DURATION", time.Minute),
+        TranscriptionModel: l.getEnv("OPENAI_TRANSCRIPTION_MODEL", "whisper-1"),
+
+        SystemPrompt:       l.systemPrompt(),
+        PromptVariants:     l.promptVariants("PROMPT_VARIANTS"),
+        WelcomeMessage:     cmp.Or(l.getEnv("WELCOME_MESSAGE", ""), "Здравствуйте! Я AI-продавец. Расскажите, что вы ищете, и я помогу подобрать товар."),
+        WelcomeCategories:  l.categories("WELCOME_CATEGORIES"),
+        MenuButtons:        l.menuButtons("MENU_BUTTONS", "Каталог=catalog,Корзина=cart,Помощь=help"),
+        WelcomeBackMessage: cmp.Or(l.getEnv("WELCOME_BACK_MESSAGE", ""), "Рад снова видеть! Чем могу помочь?"),
+        FallbackMessage:    cmp.Or(l.getEnv("FALLBACK_MESSAGE", ""), "Извините, сейчас не могу ответить, попробуйте позже"),
+
+        CampaignCodes: l.startCodes("CAMPAIGN_CODES"),
+
+        UnknownToolReply:   cmp.Or(l.getEnv("UNKNOWN_TOOL_REPLY", ""), "Не получилось выполнить запрос. Попробуйте сформулировать его иначе."),
+        UnknownToolRetries: l.nonNegativeInt("UNKNOWN_TOOL_RETRIES", 1),
+
+        EmbeddingModel: l.getEnv("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small"),
+        EmbeddingBatch: l.positiveInt("EMBEDDING_BATCH", 100),
+
+        ResponseCacheTTL: l.duration("RESPONSE_CACHE_TTL", time.Hour),
+
+        FAQFile:          l.getEnv("FAQ_FILE", ""),
+        OutputFilterFile: l.getEnv("OUTPUT_FILTER_FILE", ""),
+        FAQThreshold:     l.floatInRange("FAQ_THRESHOLD", 0.6, 0, 1),
+        SearchSimilarity: l.floatInRange("SEARCH_SIMILARITY_THRESHOLD", 0.3, 0, 1),
+
+        ModerationWordsFile: l.getEnv("MODERATION_WORDS_FILE", ""),
+        ModerationThreshold: l.floatInRange("MODERATION_THRESHOLD", 0.5, 0, 1),
+
+        CatalogImportStrict: l.boolean("CATALOG_IMPORT_STRICT", false),
+
+        DefaultCurrency:      l.currency("DEFAULT_CURRENCY", "RUB"),
+        CurrencyRatesURL:     l.getEnv("CURRENCY_RATES_URL", ""),
+        CurrencyRatesRefresh: l.duration("CURRENCY_RATES_REFRESH", 6*time.Hour),
+
+        TelegramToken:        l.telegramToken("TELEGRAM_TOKEN"),
+        BotUsername:          strings.TrimPrefix(l.getEnv("TELEGRAM_BOT_USERNAME", ""), "@"),
+        WebhookSecret:        l.getEnv("TELEGRAM_WEBHOOK_SECRET", ""),
+        WebhookMaxBodyBytes:  l.positiveInt64("WEBHOOK_MAX_BODY_BYTES", 1<<20),
+        TelegramMode:         l.oneOf("TELEGRAM_MODE", "webhook", "webhook", "polling"),
+        WebhookURL:           l.webhookURL("WEBHOOK_URL"),
+        TelegramParseMode:    l.oneOf("TELEGRAM_PARSE_MODE", "MarkdownV2", "MarkdownV2", "HTML", ""),
+        ThreadGroupReplies:   l.boolean("GROUP_REPLY_THREADING", true),
+        TelegramSendAttempts: l.positiveInt("TELEGRAM_SEND_ATTEMPTS", 3),
+        TelegramSendMaxWait:  l.duration("TELEGRAM_SEND_MAX_WAIT", 30*time.Second),
+
+        AdminChatIDs:        l.chatIDSet("ADMIN_CHAT_IDS"),
+        AllowedChatIDs:      l.chatIDSet("ALLOWED_CHAT_IDS"),
+        BlockedChatIDs:      l.chatIDSet("BLOCKED_CHAT_IDS"),
+        ClosedAccessMessage: cmp.Or(l.getEnv("CLOSED_ACCESS_MESSAGE", ""), "Извините, бот пока в закрытом доступе."),
+        MaintenanceMessage:  cmp.Or(l.getEnv("MAINTENANCE_MESSAGE", ""), "Идут технические работы, скоро вернусь. Пожалуйста, напишите чуть позже."),
+
+        AllowedOrigins: l.stringList("ALLOWED_ORIGINS"),
+        TrustedProxies: l.prefixList("TRUSTED_PROXIES"),
+
+        SessionMaxTurns: l.positiveInt("SESSION_MAX_TURNS", 20),
+        SessionTTL:      l.duration("SESSION_TTL", 30*time.Minute),
+        SummaryEvery:    l.positiveInt("SUMMARY_EVERY", 10),
+
+        TypingDelayPerChar: l.duration("TYPING_DELAY_PER_CHAR", 15*time.Millisecond),
+        TypingDelayMax:     l.duration("TYPING_DELAY_MAX", 3*time.Second),
+
+        // CONTEXT_TOKEN_BUDGET — прежнее имя, оставлено для совместимости
+        ContextTokenBudget: l.positiveInt("OPENAI_CONTEXT_BUDGET", l.positiveInt("CONTEXT_TOKEN_BUDGET", 3000)),
+
+        RateLimitPerMinute: l.positiveInt("RATE_LIMIT_PER_MINUTE", 20),
+        RateLimitFailOpen:  l.boolean("RATE_LIMIT_FAIL_OPEN", true),
+
+        UpdateTimeout:   l.duration("UPDATE_TIMEOUT", 30*time.Second),
+        UpdateWorkers:   l.positiveInt("UPDATE_WORKERS", 8),
+        ChatLockWait:    l.duration("CHAT_LOCK_WAIT", 2*time.Second),
+        UpdateQueueSize: l.positiveInt("UPDATE_QUEUE_SIZE", 100),
+
+        UpdateTransport:       l.oneOf("UPDATE_TRANSPORT", "inprocess", "inprocess", "redis"),
+        UpdateStream:          l.getEnv("UPDATE_STREAM", "updates"),
+        UpdateGroup:           l.getEnv("UPDATE_GROUP", "bot"),
+        UpdateStreamRetention: l.duration("UPDATE_STREAM_RETENTION", 24*time.Hour),
+        UpdateClaimAfter:      l.duration("UPDATE_CLAIM_AFTER", time.Minute),
+        UpdateMaxDeliveries:   l.positiveInt64("UPDATE_MAX_DELIVERIES", 5),
+        UpdateDeadLetter:      l.getEnv("UPDATE_DEAD_LETTER_STREAM", "updates:dead"),
+
+        FailedUpdateRetention: l.duration("FAILED_UPDATE_RETENTION", 7*24*time.Hour),
+
+        PaymentProvider:      l.oneOf("PAYMENT_PROVIDER", "", "", "stripe", "hmac"),
+        PaymentWebhookSecret: l.getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+
+        HandoffKeywords: l.keywordList("HANDOFF_KEYWORDS", "позовите оператора,позови оператора,нужен оператор,соедините с оператором,переключите на оператора,живой человек,живого человека,позовите человека,human agent,talk to a human,real person"),
+        HandoffTTL:      l.duration("HANDOFF_TTL", 24*time.Hour),
+
+        CartReminderAfter: l.duration("CART_REMINDER_AFTER", 3*time.Hour),
+
+        DraftRestoreWindow: l.duration("DRAFT_RESTORE_WINDOW", 24*time.Hour),
+
+        ReengageAfter:      l.duration("REENGAGE_AFTER", 24*time.Hour),
+        ReengageLookback:   l.duration("REENGAGE_LOOKBACK", 7*24*time.Hour),
+        ReengageCooldown:   l.duration("REENGAGE_COOLDOWN", 14*24*time.Hour),
+        ReengageBatch:      l.positiveInt("REENGAGE_BATCH", 20),
+        ReengageQuietHours: l.businessHours("REENGAGE_QUIET_HOURS", "BUSINESS_HOURS_TZ", "mon-sun 21:00-10:00"),
+
+        DigestSchedule: l.oneOf("ORDER_DIGEST", "", "", "daily", "weekly"),
+        DigestAt:       l.clock("ORDER_DIGEST_AT", 9*time.Hour),
+        DigestLocation: l.location("ORDER_DIGEST_TZ", "Europe/Moscow"),
+        DigestCSV:      l.boolean("ORDER_DIGEST_CSV", false),
+
+        BusinessHours:   l.businessHours("BUSINESS_HOURS", "BUSINESS_HOURS_TZ", ""),
+        OffHoursMode:    l.oneOf("OFF_HOURS_MODE", "note", "note", "ack"),
+        OffHoursMessage: cmp.Or(l.getEnv("OFF_HOURS_MESSAGE", ""), "Сейчас нерабочее время — менеджеры ответят в начале следующего рабочего дня."),
+        OffHoursSearch:  l.boolean("OFF_HOURS_SEARCH", true),
+
+        MemberVerifyTimeout: l.duration("MEMBER_VERIFY_TIMEOUT", 2*time.Minute),
+
+        ReplyStages: l.replyStages("REPLY_STAGES", ReplyStageFilter+","+ReplyStagePause),
+    }
+
+    c.StorageDriver, c.PostgresDSN, c.SQLitePath = l.database()
+
+    switch {
+    case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+        l.fail("TLS_CERT_FILE и TLS_KEY_FILE задаются вместе")
+    case c.TLSCertFile != "":
+        // Битый или несовпадающий ключ лучше увидеть при старте, а не на первом запросе
+        if _, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile); err != nil {
+            l.fail("не удалось загрузить TLS-сертификат: %v", err)
+        }
+    }
+
+    if c.LLMProvider == "openai" && c.OpenAIKey == "" {
+        l.fail("обязательная переменная окружения OPENAI_KEY не установлена")
+    }
+
+    if c.StorageDriver == "sqlite" && c.Features.IsEnabled(FlagSemanticSearch) {
+        l.fail("семантический поиск требует STORAGE_DRIVER=postgres: ему нужно расширение pgvector")
+    }
+
+    if c.LLMProvider == "ollama" && c.Features.IsEnabled(FlagSemanticSearch) {
+        l.fail("семантический поиск требует LLM_PROVIDER=openai: у Ollama нет эмбеддингов нужной размерности")
+    }
+
+    if c.OpenAIProvider == "azure" && c.OpenAIAPIVersion == "" {
+        l.fail("для OPENAI_PROVIDER=azure нужна переменная OPENAI_API_VERSION")
+    }
+
+    if c.DigestSchedule != "" && len(c.AdminChatIDs) == 0 {
+        l.fail("для ORDER_DIGEST нужна переменная ADMIN_CHAT_IDS")
+    }
+
+    if c.Features.IsEnabled(FlagHandoff) && len(c.AdminChatIDs) == 0 {
+        l.fail("для HANDOFF_ENABLED нужна переменная ADMIN_CHAT_IDS")
+    }
+
+    if c.OpenAIAdaptiveTimeout && c.OpenAITimeoutMin > c.OpenAITimeout {
+        l.fail("OPENAI_TIMEOUT_MIN не может быть больше OPENAI_TIMEOUT")
+    }
+    if c.OpenAITimeoutMultiplier <= 1 {
+        l.fail("OPENAI_TIMEOUT_MULTIPLIER должна быть больше 1, получено %g", c.OpenAITimeoutMultiplier)
+    }
+
+    if c.Features.IsEnabled(FlagReengagement) && c.ReengageAfter >= c.ReengageLookback {
+        l.fail("REENGAGE_AFTER должна быть меньше REENGAGE_LOOKBACK")
+    }
+
+    if c.PaymentProvider != "" && c.PaymentWebhookSecret == "" {
+        l.fail("для PAYMENT_PROVIDER нужна переменная PAYMENT_WEBHOOK_SECRET")
+    }
+
+    // Больше строк в одном запросе /embeddings OpenAI не принимает
+    if c.EmbeddingBatch > 2048 {
+        l.fail("EMBEDDING_BATCH должен быть не больше 2048")
+    }
+
+    // Курсы задаются к валюте магазина, поэтому читаются после неё
+    c.CurrencyRates = l.currencyRates("CURRENCY_RATES", c.DefaultCurrency)
+
+    if c.ChatLockWait >= c.UpdateTimeout {
+        l.fail("CHAT_LOCK_WAIT должен быть меньше UPDATE_TIMEOUT")
+    }
+
+    // Иначе апдейт заберёт другая реплика, пока первая ещё его обрабатывает
+    if c.UpdateTransport == "redis" && c.UpdateClaimAfter <= c.UpdateTimeout {
+        l.fail("UPDATE_CLAIM_AFTER должен быть больше UPDATE_TIMEOUT")
+    }
+    // Иначе поток обрежет апдейт раньше, чем кончатся его попытки
+    if c.UpdateTransport == "redis" && c.UpdateStreamRetention <= c.UpdateClaimAfter*time.Duration(c.UpdateMaxDeliveries) {
+        l.fail("UPDATE_STREAM_RETENTION должен быть больше UPDATE_CLAIM_AFTER × UPDATE_MAX_DELIVERIES")
+    }
+
+    if err := l.err(); err != nil {
+        return nil, err
+    }
+    c.lookup = l.lookup
+    return c, nil
+}
+
+// defaultSystemPrompt — персона по умолчанию, если промпт не задан
+const defaultSystemPrompt = `Ты — вежливый и внимательный менеджер по продажам интернет-магазина трикотажа.
+Отвечай кратко и по делу, на «вы». Помогай подобрать товар, уточняй размер, цвет и количество.
+Не выдумывай товары, цены и наличие — если не знаешь, так и скажи и предложи помощь.`
+
+// IsAdmin — входит ли чат в список администраторов
+func (c *Config) IsAdmin(chatID int64) bool {
+    return c.AdminChatIDs[chatID]
+}
+
+// ChatAccess — допуск чата к боту
+type ChatAccess int
+
+const (
+    // AccessAllowed — чат обслуживается
+    AccessAllowed ChatAccess = iota
+    // AccessBlocked — чат в BlockedChatIDs, апдейты игнорируются молча
+    AccessBlocked
+    // AccessClosed — список допущенных задан, а чата в нём нет
+    AccessClosed
+)
+
+// Access — допуск чата: админы проходят всегда, затем проверяется
+// список заблокированных, затем список допущенных. Пустые списки не ограничивают.
+func (c *Config) Access(chatID int64) ChatAccess {
+    switch {
+    case c.IsAdmin(chatID):
+        return AccessAllowed
+    case c.BlockedChatIDs[chatID]:
+        return AccessBlocked
+    case len(c.AllowedChatIDs) > 0 && !c.AllowedChatIDs[chatID]:
+        return AccessClosed
+    }
+    return AccessAllowed
 }
 
 // getEnv — возвращает значение или дефолт
-func getEnv(key string, defaultVal string) string {
-    if val, ok := os.LookupEnv(key); ok {
+func (l *envLoader) getEnv(key string, defaultVal string) string {
+    if val, ok := l.lookupEnv(key); ok {
         return val
     }
     return defaultVal
 }
 
-// mustHave — проверяет наличие обязательной переменной
-func mustHave(key string) string {
-    if val, ok := os.LookupEnv(key); ok && val != "" {
+// stringList — читает список через запятую без пустых элементов и пробелов вокруг
+func (l *envLoader) stringList(key string) []string {
+    var list []string
+    for _, part := range strings.Split(l.getEnv(key, ""), ",") {
+        if part = strings.TrimSpace(part); part != "" {
+            list = append(list, part)
+        }
+    }
+    return list
+}
+
+// keywordList — список фраз через запятую в нижнем регистре; если переменная
+// не задана — defaultVal, пустое значение выключает список
+func (l *envLoader) keywordList(key, defaultVal string) []string {
+    var list []string
+    for _, part := range strings.Split(l.getEnv(key, defaultVal), ",") {
+        if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+            list = append(list, part)
+        }
+    }
+    return list
+}
+
+// envLoader — читает переменные через lookup и накапливает ошибки, чтобы
+// сообщить обо всех сразу
+type envLoader struct {
+    // lookup — источник переменных; nil — окружение процесса
+    lookup func(key string) (string, bool)
+    errs   []error
+}
+
+// lookupEnv — значение переменной и задана ли она
+func (l *envLoader) lookupEnv(key string) (string, bool) {
+    if l.lookup == nil {
+        return os.LookupEnv(key)
+    }
+    return l.lookup(key)
+}
+
+// err — объединённая ошибка по всем переменным или nil
+func (l *envLoader) err() error {
+    return errors.Join(l.errs...)
+}
+
+// fail — регистрирует проблему с переменной окружения
+func (l *envLoader) fail(format string, args ...interface{}) {
+    l.errs = append(l.errs, fmt.Errorf(format, args...))
+}
+
+// require — проверяет наличие обязательной переменной
+func (l *envLoader) require(key string) string {
+    if val, ok := l.lookupEnv(key); ok && val != "" {
         return val
     }
-    log.Fatalf("❌ Обязательная переменная окружения %s не установлена", 
-key)
+    l.fail("обязательная переменная окружения %s не установлена", key)
     return ""
 }
 
+// database читает драйвер хранилища (STORAGE_DRIVER) и адрес его базы:
+// POSTGRES_DSN обязателен только для postgres, файл SQLite по умолчанию —
+// ai_seller.db в рабочем каталоге
+func (l *envLoader) database() (driver, dsn, path string) {
+    driver = l.oneOf("STORAGE_DRIVER", "postgres", "postgres", "sqlite")
+    if driver == "sqlite" {
+        return driver, "", l.getEnv("SQLITE_PATH", "ai_seller.db")
+    }
+    return driver, l.require("POSTGRES_DSN"), ""
+}
+
+// telegramToken — проверяет наличие и формат токена бота
+func (l *envLoader) telegramToken(key string) string {
+    token := l.require(key)
+    if token != "" && !telegramTokenRe.MatchString(token) {
+        l.fail("переменная %s содержит некорректный токен бота: ожидается формат <цифры>:<символы>", key)
+    }
+    return token
+}
+
+// baseURL — абсолютный http(s)-адрес API; завершающий "/" отбрасывается
+func (l *envLoader) baseURL(key, defaultVal string) string {
+    raw := strings.TrimRight(l.getEnv(key, defaultVal), "/")
+    u, err := url.Parse(raw)
+    if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+        l.fail("переменная %s должна быть адресом вида https://host/path, получено %q", key, raw)
+        return defaultVal
+    }
+    return raw
+}
+
+// webhookURL — адрес вебхука: Telegram принимает только https
+func (l *envLoader) webhookURL(key string) string {
+    raw := l.getEnv(key, "")
+    if raw != "" && !strings.HasPrefix(raw, "https://") {
+        l.fail("переменная %s должна начинаться с https://, получено %q", key, raw)
+    }
+    return raw
+}
+
+// positiveInt — читает целое число больше нуля или возвращает дефолт
+func (l *envLoader) positiveInt(key string, defaultVal int) int {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := strconv.Atoi(raw)
+    if err != nil || val <= 0 {
+        l.fail("переменная %s должна быть положительным целым числом, получено %q", key, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// positiveInt64 — читает целое число больше нуля или возвращает дефолт
+func (l *envLoader) positiveInt64(key string, defaultVal int64) int64 {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := strconv.ParseInt(raw, 10, 64)
+    if err != nil || val <= 0 {
+        l.fail("переменная %s должна быть положительным целым числом, получено %q", key, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// nonNegativeInt — читает целое число не меньше нуля или возвращает дефолт
+func (l *envLoader) nonNegativeInt(key string, defaultVal int) int {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := strconv.Atoi(raw)
+    if err != nil || val < 0 {
+        l.fail("переменная %s должна быть неотрицательным целым числом, получено %q", key, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// nonNegativeInt64 — читает целое число не меньше нуля или возвращает дефолт
+func (l *envLoader) nonNegativeInt64(key string, defaultVal int64) int64 {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := strconv.ParseInt(raw, 10, 64)
+    if err != nil || val < 0 {
+        l.fail("переменная %s должна быть неотрицательным целым числом, получено %q", key, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// floatInRange — читает дробное число из отрезка [min, max] или возвращает дефолт
+func (l *envLoader) floatInRange(key string, defaultVal, min, max float64) float64 {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := strconv.ParseFloat(raw, 64)
+    if err != nil || val < min || val > max {
+        l.fail("переменная %s должна быть числом от %g до %g, получено %q", key, min, max, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// oneOf — читает значение из списка допустимых или возвращает дефолт.
+// Явно заданная пустая строка допустима, только если "" есть в списке.
+func (l *envLoader) oneOf(key, defaultVal string, allowed ...string) string {
+    raw, ok := l.lookupEnv(key)
+    if !ok {
+        return defaultVal
+    }
+    for _, v := range allowed {
+        if raw == v {
+            return raw
+        }
+    }
+    l.fail("переменная %s должна быть одним из %q, получено %q", key, allowed, raw)
+    return defaultVal
+}
+
+// currency — код валюты ISO 4217 из трёх латинских букв, приводится к верхнему регистру
+func (l *envLoader) currency(key, defaultVal string) string {
+    raw := strings.ToUpper(strings.TrimSpace(l.getEnv(key, defaultVal)))
+    if len(raw) != 3 || strings.Trim(raw, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+        l.fail("переменная %s должна быть кодом валюты из трёх букв, получено %q", key, raw)
+        return defaultVal
+    }
+    return raw
+}
+
+// currencyRates — курсы вида «USD=0.011,EUR=0.0102» к валюте base
+func (l *envLoader) currencyRates(key, base string) *money.Rates {
+    rates, err := money.ParseRates(base, l.getEnv(key, ""))
+    if err != nil {
+        l.fail("переменная %s: %v", key, err)
+        return &money.Rates{Base: base}
+    }
+    return rates
+}
+
+// chatIDSet — читает список id чатов через запятую; пробелы вокруг допускаются
+func (l *envLoader) chatIDSet(key string) map[int64]bool {
+    set := make(map[int64]bool)
+    for _, part := range strings.Split(l.getEnv(key, ""), ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+        id, err := strconv.ParseInt(part, 10, 64)
+        if err != nil {
+            l.fail("переменная %s содержит нечисловой id чата %q", key, part)
+            continue
+        }
+        set[id] = true
+    }
+    return set
+}
+
+// maxCategoryBytes — предел длины категории: она уходит в callback_data
+// кнопки ("cat:<категория>"), а Telegram ограничивает его 64 байтами
+const maxCategoryBytes = 60
+
+// categories — список категорий через запятую для кнопок приветствия
+func (l *envLoader) categories(key string) []string {
+    list := l.stringList(key)
+    for _, c := range list {
+        if len(c) > maxCategoryBytes {
+            l.fail("категория %q в %s длиннее %d байт", c, key, maxCategoryBytes)
+        }
+    }
+    return list
+}
+
+// Этапы обработки ответа модели для REPLY_STAGES
+const (
+    // ReplyStageFilter — фильтр ответов (OUTPUT_FILTER_FILE)
+    ReplyStageFilter = "filter"
+    // ReplyStagePause — пауза «набора текста» (флаг typing_delay)
+    ReplyStagePause = "pause"
+)
+
+// replyStages — список этапов ответа через запятую. Не заданная переменная
+// даёт defaultVal, пустая — ответ уходит без обработки.
+func (l *envLoader) replyStages(key, defaultVal string) []string {
+    raw, ok := l.lookupEnv(key)
+    if !ok {
+        raw = defaultVal
+    }
+
+    var stages []string
+    seen := make(map[string]bool)
+    for _, part := range strings.Split(raw, ",") {
+        name := strings.ToLower(strings.TrimSpace(part))
+        switch {
+        case name == "":
+            continue
+        case name != ReplyStageFilter && name != ReplyStagePause:
+            l.fail("неизвестный этап %q в %s, допустимы %q и %q", name, key, ReplyStageFilter, ReplyStagePause)
+            continue
+        case seen[name]:
+            l.fail("этап %q указан в %s дважды", name, key)
+            continue
+        }
+        seen[name] = true
+        stages = append(stages, name)
+    }
+    return stages
+}
+
+// startCodeRe — допустимый параметр /start: до 64 символов из латиницы,
+// цифр, "_" и "-"
+var startCodeRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// startCodes — список кодов для ссылок t.me/<бот>?start=<код>
+func (l *envLoader) startCodes(key string) []string {
+    list := l.stringList(key)
+    for _, c := range list {
+        if !startCodeRe.MatchString(c) {
+            l.fail("код %q в %s: допустимы до 64 символов A-Z, a-z, 0-9, _ и -", c, key)
+        }
+    }
+    return list
+}
+
+// MenuButton — кнопка меню: нажатие отправляет Label, бот выполняет Command
+type MenuButton struct {
+    Label string
+    // Command — имя команды без "/"
+    Command string
+}
+
+// menuButtons — читает кнопки меню вида "Каталог=catalog,Корзина=cart".
+// Не заданная переменная даёт меню по умолчанию, пустая — выключает меню.
+func (l *envLoader) menuButtons(key, defaultVal string) []MenuButton {
+    raw, ok := l.lookupEnv(key)
+    if !ok {
+        raw = defaultVal
+    }
+
+    var buttons []MenuButton
+    for _, part := range strings.Split(raw, ",") {
+        if part = strings.TrimSpace(part); part == "" {
+            continue
+        }
+        label, cmd, found := strings.Cut(part, "=")
+        label, cmd = strings.TrimSpace(label), strings.TrimPrefix(strings.TrimSpace(cmd), "/")
+        if !found || label == "" || cmd == "" {
+            l.fail("кнопка %q в %s должна иметь вид Текст=команда", part, key)
+            continue
+        }
+        buttons = append(buttons, MenuButton{Label: label, Command: strings.ToLower(cmd)})
+    }
+    return buttons
+}
+
+// prefixList — читает список сетей (10.0.0.0/8) или адресов через запятую
+func (l *envLoader) prefixList(key string) []netip.Prefix {
+    var prefixes []netip.Prefix
+    for _, part := range l.stringList(key) {
+        if p, err := netip.ParsePrefix(part); err == nil {
+            prefixes = append(prefixes, p.Masked())
+            continue
+        }
+        addr, err := netip.ParseAddr(part)
+        if err != nil {
+            l.fail("переменная %s содержит некорректный адрес или сеть %q", key, part)
+            continue
+        }
+        prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+    }
+    return prefixes
+}
+
+// clock — читает время суток ЧЧ:ММ и возвращает его как смещение от полуночи
+func (l *envLoader) clock(key string, defaultVal time.Duration) time.Duration {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    t, err := time.Parse("15:04", raw)
+    if err != nil {
+        l.fail("переменная %s должна быть временем ЧЧ:ММ, получено %q", key, raw)
+        return defaultVal
+    }
+    return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// location — читает часовой пояс IANA (например, Europe/Moscow)
+func (l *envLoader) location(key, defaultVal string) *time.Location {
+    name := l.getEnv(key, defaultVal)
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        l.fail("переменная %s: неизвестный часовой пояс %q", key, name)
+        return time.UTC
+    }
+    return loc
+}
+
+// businessHours — читает часы работы (см. ParseBusinessHours) в часовом
+// поясе из tzKey; без переменной берётся defaultVal, пустая — часы не заданы
+func (l *envLoader) businessHours(key, tzKey, defaultVal string) *BusinessHours {
+    raw := strings.TrimSpace(l.getEnv(key, defaultVal))
+    if raw == "" {
+        return nil
+    }
+    h, err := ParseBusinessHours(raw, l.location(tzKey, "Europe/Moscow"))
+    if err != nil {
+        l.fail("переменная %s: %v", key, err)
+        return nil
+    }
+    return h
+}
+
+// boolean — читает флаг (true/false, 1/0) или возвращает дефолт
+func (l *envLoader) boolean(key string, defaultVal bool) bool {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := strconv.ParseBool(raw)
+    if err != nil {
+        l.fail("переменная %s должна быть true или false, получено %q", key, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// duration — читает длительность в формате time.ParseDuration (например, 30m)
+func (l *envLoader) duration(key string, defaultVal time.Duration) time.Duration {
+    raw, ok := l.lookupEnv(key)
+    if !ok || raw == "" {
+        return defaultVal
+    }
+    val, err := time.ParseDuration(raw)
+    if err != nil || val <= 0 {
+        l.fail("переменная %s должна быть положительной длительностью (например, 30m), получено %q", key, raw)
+        return defaultVal
+    }
+    return val
+}
+
+// ReadSystemPrompt заново читает системный промпт из SYSTEM_PROMPT_FILE или
+// SYSTEM_PROMPT — для перезагрузки по SIGHUP без перезапуска сервиса.
+// Переменные берутся из того же источника, что и при сборке конфигурации:
+// у экземпляра с WithEnv или WithLookup — из них, а не из окружения процесса.
+// Пустой или нечитаемый файл — ошибка, а не промпт по умолчанию.
+func (c *Config) ReadSystemPrompt() (string, error) {
+    l := envLoader{lookup: c.lookup}
+    prompt := l.systemPrompt()
+    if err := l.err(); err != nil {
+        return "", err
+    }
+    return prompt, nil
+}
+
+// systemPrompt — промпт из файла SYSTEM_PROMPT_FILE, переменной SYSTEM_PROMPT
+// или встроенный по умолчанию (в порядке приоритета)
+func (l *envLoader) systemPrompt() string {
+    if path := l.getEnv("SYSTEM_PROMPT_FILE", ""); path != "" {
+        data, err := os.ReadFile(path)
+        if err != nil {
+            l.fail("не удалось прочитать SYSTEM_PROMPT_FILE: %v", err)
+            return ""
+        }
+        if prompt := strings.TrimSpace(string(data)); prompt != "" {
+            return prompt
+        }
+        l.fail("файл SYSTEM_PROMPT_FILE %s пуст", path)
+        return ""
+    }
+    return l.getEnv("SYSTEM_PROMPT", defaultSystemPrompt)
+}
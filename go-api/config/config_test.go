@@ -0,0 +1,340 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// testEnv — минимальное рабочее окружение с заменой и добавлением переменных
+// из overrides
+func testEnv(overrides map[string]string) map[string]string {
+    env := map[string]string{
+        "POSTGRES_DSN":   "postgres://localhost/test",
+        "REDIS_ADDR":     "localhost:6379",
+        "TELEGRAM_TOKEN": "123456:ABCdefGhIJKlmnOPQRstuVWXyz0123456789",
+        "OPENAI_KEY":     "sk-test",
+        "WEBHOOK_URL":    "https://example.com/webhook",
+    }
+    for k, v := range overrides {
+        env[k] = v
+    }
+    return env
+}
+
+// mustConfig — конфигурация из testEnv(overrides); ошибка валит тест
+func mustConfig(t *testing.T, overrides map[string]string) *Config {
+    t.Helper()
+    cfg, err := NewConfig(WithEnv(testEnv(overrides)))
+    if err != nil {
+        t.Fatalf("NewConfig: %v", err)
+    }
+    return cfg
+}
+
+// configError — ошибка NewConfig для testEnv(overrides); её отсутствие валит тест
+func configError(t *testing.T, overrides map[string]string) string {
+    t.Helper()
+    _, err := NewConfig(WithEnv(testEnv(overrides)))
+    if err == nil {
+        t.Fatalf("NewConfig(%v) без ошибки", overrides)
+    }
+    return err.Error()
+}
+
+func TestOpenAIMaxTokens(t *testing.T) {
+    for _, tc := range []struct {
+        raw  string
+        want int
+    }{
+        {"", 0},
+        {"0", 0},
+        {"512", 512},
+    } {
+        cfg := mustConfig(t, map[string]string{"OPENAI_MAX_TOKENS": tc.raw})
+        if cfg.OpenAIMaxTokens != tc.want {
+            t.Errorf("OPENAI_MAX_TOKENS=%q: получено %d, ожидалось %d", tc.raw, cfg.OpenAIMaxTokens, tc.want)
+        }
+    }
+
+    if msg := configError(t, map[string]string{"OPENAI_MAX_TOKENS": "-1"}); !strings.Contains(msg, "OPENAI_MAX_TOKENS") {
+        t.Errorf("ошибка не называет переменную: %s", msg)
+    }
+}
+
+func TestChatLockWaitBelowUpdateTimeout(t *testing.T) {
+    if cfg := mustConfig(t, nil); cfg.ChatLockWait != 2*time.Second {
+        t.Fatalf("CHAT_LOCK_WAIT по умолчанию = %s, ожидалось 2s", cfg.ChatLockWait)
+    }
+    msg := configError(t, map[string]string{"CHAT_LOCK_WAIT": "30s", "UPDATE_TIMEOUT": "30s"})
+    if !strings.Contains(msg, "CHAT_LOCK_WAIT") {
+        t.Fatalf("ошибка конфигурации %q не называет CHAT_LOCK_WAIT", msg)
+    }
+}
+
+// Без APP_ENV окружение — development; DEBUG_HTTP выключен, пока не задан явно
+func TestDefaultEnv(t *testing.T) {
+    cfg := mustConfig(t, nil)
+    if cfg.Env != "development" || cfg.DebugHTTP {
+        t.Fatalf("по умолчанию Env=%q DebugHTTP=%v, нужно development и false", cfg.Env, cfg.DebugHTTP)
+    }
+    if cfg := mustConfig(t, map[string]string{"APP_ENV": "production", "DEBUG_HTTP": "true"}); cfg.Env != "production" || !cfg.DebugHTTP {
+        t.Fatalf("Env=%q DebugHTTP=%v, нужно production и true", cfg.Env, cfg.DebugHTTP)
+    }
+}
+
+// Несколько конфигураций в одном процессе не делят источник переменных:
+// перечитывание промпта у каждой идёт из её собственного
+func TestIndependentConfigsReloadOwnPrompt(t *testing.T) {
+    t.Setenv("SYSTEM_PROMPT", "промпт из окружения процесса")
+    dir := t.TempDir()
+    file := filepath.Join(dir, "prompt.txt")
+    if err := os.WriteFile(file, []byte("промпт из файла"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+
+    fromFile := mustConfig(t, map[string]string{"SYSTEM_PROMPT_FILE": file, "DB_TIMEOUT": "1s"})
+    fromEnv := mustConfig(t, map[string]string{"SYSTEM_PROMPT": "промпт магазина А", "DB_TIMEOUT": "5s"})
+    tenant := testEnv(map[string]string{"SYSTEM_PROMPT": "общий промпт"})
+    tenant["SHOP_B_SYSTEM_PROMPT"] = "промпт магазина Б"
+    prefixed, err := NewConfig(WithLookup(func(key string) (string, bool) {
+        if v, ok := tenant["SHOP_B_"+key]; ok {
+            return v, true
+        }
+        v, ok := tenant[key]
+        return v, ok
+    }))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if err := os.WriteFile(file, []byte("новый промпт из файла"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    for _, tc := range []struct {
+        name string
+        cfg  *Config
+        want string
+    }{
+        {"файл", fromFile, "новый промпт из файла"},
+        {"WithEnv", fromEnv, "промпт магазина А"},
+        {"WithLookup", prefixed, "промпт магазина Б"},
+    } {
+        got, err := tc.cfg.ReadSystemPrompt()
+        if err != nil {
+            t.Fatalf("%s: %v", tc.name, err)
+        }
+        if got != tc.want {
+            t.Errorf("%s: перечитан промпт %q, нужно %q", tc.name, got, tc.want)
+        }
+    }
+    if fromFile.DBTimeout != time.Second || fromEnv.DBTimeout != 5*time.Second {
+        t.Errorf("DB_TIMEOUT смешался: %s и %s", fromFile.DBTimeout, fromEnv.DBTimeout)
+    }
+
+    // Сломанный файл — ошибка только у той конфигурации, что его читает
+    if err := os.WriteFile(file, []byte("  \n"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := fromFile.ReadSystemPrompt(); err == nil {
+        t.Error("пустой SYSTEM_PROMPT_FILE перечитан без ошибки")
+    }
+    if _, err := fromEnv.ReadSystemPrompt(); err != nil {
+        t.Errorf("чужой пустой файл сломал перечитывание: %v", err)
+    }
+}
+
+// resetLoadConfig сбрасывает singleton LoadConfig для теста и после него
+func resetLoadConfig(t *testing.T) {
+    t.Helper()
+    reset := func() { cfg, cfgErr, once = nil, nil, sync.Once{} }
+    reset()
+    t.Cleanup(reset)
+}
+
+// Одновременные вызовы LoadConfig без обязательной переменной получают одну
+// и ту же ошибку, а поздние вызовы окружение заново не читают
+func TestLoadConfigConcurrentFailure(t *testing.T) {
+    resetLoadConfig(t)
+    t.Chdir(t.TempDir())
+    t.Setenv("APP_ENV", "test")
+    for k, v := range testEnv(nil) {
+        t.Setenv(k, v)
+    }
+    t.Setenv("POSTGRES_DSN", "")
+
+    const callers = 64
+    var (
+        wg      sync.WaitGroup
+        start   = make(chan struct{})
+        configs = make([]*Config, callers)
+        errs    = make([]error, callers)
+    )
+    for i := range callers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            configs[i], errs[i] = LoadConfig()
+        }()
+    }
+    close(start)
+    wg.Wait()
+
+    first := errs[0]
+    if first == nil || !strings.Contains(first.Error(), "POSTGRES_DSN") {
+        t.Fatalf("ошибка LoadConfig = %v, нужна ошибка про POSTGRES_DSN", first)
+    }
+    for i := range callers {
+        if configs[i] != nil || errs[i] != first {
+            t.Fatalf("вызов %d получил (%v, %v), а первый — (nil, %v)", i, configs[i], errs[i], first)
+        }
+    }
+
+    t.Setenv("POSTGRES_DSN", "postgres://localhost/test")
+    if c, err := LoadConfig(); c != nil || err != first {
+        t.Fatalf("после исправления окружения LoadConfig перечитал его: (%v, %v)", c, err)
+    }
+}
+
+// С множителем 1 оборванные по пределу попытки не дают ему расти
+func TestOpenAITimeoutMultiplierAboveOne(t *testing.T) {
+    if msg := configError(t, map[string]string{"OPENAI_TIMEOUT_MULTIPLIER": "1"}); !strings.Contains(msg, "OPENAI_TIMEOUT_MULTIPLIER") {
+        t.Fatalf("ошибка конфигурации %q не называет OPENAI_TIMEOUT_MULTIPLIER", msg)
+    }
+    if cfg := mustConfig(t, map[string]string{"OPENAI_TIMEOUT_MULTIPLIER": "1.5"}); cfg.OpenAITimeoutMultiplier != 1.5 {
+        t.Fatalf("OPENAI_TIMEOUT_MULTIPLIER = %g, нужно 1.5", cfg.OpenAITimeoutMultiplier)
+    }
+}
+
+func TestUnknownToolRetries(t *testing.T) {
+    for raw, want := range map[string]int{"": 1, "0": 0, "3": 3} {
+        if cfg := mustConfig(t, map[string]string{"UNKNOWN_TOOL_RETRIES": raw}); cfg.UnknownToolRetries != want {
+            t.Errorf("UNKNOWN_TOOL_RETRIES=%q: получено %d, ожидалось %d", raw, cfg.UnknownToolRetries, want)
+        }
+    }
+    if msg := configError(t, map[string]string{"UNKNOWN_TOOL_RETRIES": "-1"}); !strings.Contains(msg, "UNKNOWN_TOOL_RETRIES") {
+        t.Errorf("ошибка не называет переменную: %s", msg)
+    }
+}
+
+// Для --migrate-only достаточно базы: токены и Redis не требуются
+func TestMigrationConfig(t *testing.T) {
+    cfg, err := NewMigrationConfig(WithEnv(map[string]string{"POSTGRES_DSN": "postgres://localhost/test", "SEED_FILE": "seed.sql"}))
+    if err != nil {
+        t.Fatalf("NewMigrationConfig: %v", err)
+    }
+    if cfg.PostgresDSN != "postgres://localhost/test" || cfg.SeedFile != "seed.sql" {
+        t.Fatalf("конфигурация %+v", cfg)
+    }
+    if _, err := NewMigrationConfig(WithEnv(map[string]string{})); err == nil || !strings.Contains(err.Error(), "POSTGRES_DSN") {
+        t.Fatalf("без POSTGRES_DSN: %v", err)
+    }
+}
+
+// Для SQLite нужен только файл базы: POSTGRES_DSN не требуется, а
+// семантический поиск без pgvector не включается
+func TestStorageDriver(t *testing.T) {
+    if cfg := mustConfig(t, nil); cfg.StorageDriver != "postgres" {
+        t.Fatalf("по умолчанию STORAGE_DRIVER=%q, нужно postgres", cfg.StorageDriver)
+    }
+    env := testEnv(map[string]string{"STORAGE_DRIVER": "sqlite"})
+    delete(env, "POSTGRES_DSN")
+    cfg, err := NewConfig(WithEnv(env))
+    if err != nil {
+        t.Fatalf("NewConfig: %v", err)
+    }
+    if cfg.SQLitePath != "ai_seller.db" || cfg.PostgresDSN != "" {
+        t.Fatalf("sqlite: файл %q, DSN %q", cfg.SQLitePath, cfg.PostgresDSN)
+    }
+    mig, err := NewMigrationConfig(WithEnv(map[string]string{"STORAGE_DRIVER": "sqlite", "SQLITE_PATH": "/data/shop.db"}))
+    if err != nil || mig.SQLitePath != "/data/shop.db" {
+        t.Fatalf("NewMigrationConfig: %+v, %v", mig, err)
+    }
+
+    for _, tc := range []struct {
+        name, want string
+        env        map[string]string
+    }{
+        {"неизвестный драйвер", "STORAGE_DRIVER", map[string]string{"STORAGE_DRIVER": "mysql"}},
+        {"семантический поиск", "pgvector", map[string]string{"STORAGE_DRIVER": "sqlite", "SEMANTIC_SEARCH": "true"}},
+    } {
+        if msg := configError(t, tc.env); !strings.Contains(msg, tc.want) {
+            t.Errorf("%s: ошибка %q не называет %s", tc.name, msg, tc.want)
+        }
+    }
+}
+
+func TestOpenAIBaseURL(t *testing.T) {
+    if cfg := mustConfig(t, nil); cfg.OpenAIBaseURL != "https://api.openai.com/v1" || cfg.OpenAIProvider != "openai" {
+        t.Fatalf("по умолчанию %q (%s), нужно https://api.openai.com/v1 (openai)", cfg.OpenAIBaseURL, cfg.OpenAIProvider)
+    }
+    if cfg := mustConfig(t, map[string]string{"OPENAI_BASE_URL": "http://litellm:4000/v1/"}); cfg.OpenAIBaseURL != "http://litellm:4000/v1" {
+        t.Errorf("завершающий / не срезан: %q", cfg.OpenAIBaseURL)
+    }
+    for _, raw := range []string{"ftp://example.com/v1", "not a url", "https://"} {
+        if msg := configError(t, map[string]string{"OPENAI_BASE_URL": raw}); !strings.Contains(msg, "OPENAI_BASE_URL") {
+            t.Errorf("OPENAI_BASE_URL=%q: ошибка не называет переменную: %s", raw, msg)
+        }
+    }
+
+    azure := map[string]string{"OPENAI_PROVIDER": "azure", "OPENAI_BASE_URL": "https://shop.openai.azure.com/openai/deployments/gpt-4o"}
+    if msg := configError(t, azure); !strings.Contains(msg, "OPENAI_API_VERSION") {
+        t.Errorf("Azure без версии API: %s", msg)
+    }
+    azure["OPENAI_API_VERSION"] = "2024-06-01"
+    if cfg := mustConfig(t, azure); cfg.OpenAIProvider != "azure" || cfg.OpenAIAPIVersion != "2024-06-01" {
+        t.Errorf("Azure: %s %q", cfg.OpenAIProvider, cfg.OpenAIAPIVersion)
+    }
+    if msg := configError(t, map[string]string{"OPENAI_PROVIDER": "anthropic"}); !strings.Contains(msg, "OPENAI_PROVIDER") {
+        t.Errorf("неизвестный провайдер: %s", msg)
+    }
+}
+
+func TestFeatureFlags(t *testing.T) {
+    defaults := mustConfig(t, nil).Features
+    for _, f := range Flags() {
+        if defaults.IsEnabled(f.Name) != f.Default {
+            t.Errorf("флаг %s по умолчанию %v, нужно %v", f.Name, defaults.IsEnabled(f.Name), f.Default)
+        }
+    }
+    if defaults.IsEnabled("no_such_flag") {
+        t.Error("неизвестный флаг включён")
+    }
+
+    cfg := mustConfig(t, map[string]string{"VISION_ENABLED": "true", "STREAMING_ENABLED": "1", "CART_REMINDERS": "false", "VOICE_ENABLED": ""})
+    for name, want := range map[string]bool{FlagVision: true, FlagStreaming: true, FlagCartReminders: false, FlagVoice: false} {
+        if cfg.Features.IsEnabled(name) != want {
+            t.Errorf("флаг %s = %v, нужно %v", name, cfg.Features.IsEnabled(name), want)
+        }
+    }
+
+    if msg := configError(t, map[string]string{"VISION_ENABLED": "да"}); !strings.Contains(msg, "VISION_ENABLED") {
+        t.Errorf("ошибка не называет переменную: %s", msg)
+    }
+}
+
+// Непарные или непригодные TLS_CERT_FILE и TLS_KEY_FILE валят старт
+func TestTLSFiles(t *testing.T) {
+    dir := t.TempDir()
+    garbage := filepath.Join(dir, "garbage.pem")
+    if err := os.WriteFile(garbage, []byte("не сертификат"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    for name, env := range map[string]map[string]string{
+        "только сертификат": {"TLS_CERT_FILE": garbage},
+        "только ключ":       {"TLS_KEY_FILE": garbage},
+        "не PEM":            {"TLS_CERT_FILE": garbage, "TLS_KEY_FILE": garbage},
+        "нет файла":         {"TLS_CERT_FILE": filepath.Join(dir, "missing.pem"), "TLS_KEY_FILE": garbage},
+    } {
+        if msg := configError(t, env); !strings.Contains(msg, "TLS") {
+            t.Errorf("%s: ошибка %q не про TLS", name, msg)
+        }
+    }
+    if cfg := mustConfig(t, nil); cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+        t.Error("TLS включён по умолчанию")
+    }
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "github.com/joho/godotenv"
+)
+
+// defaultEnv — APP_ENV, если он не задан
+const defaultEnv = "development"
+
+// loadDotEnv загружает .env, а поверх него .env.<APP_ENV> (например, .env.production).
+// Файлы ищутся от текущей директории вверх до корня репозитория, поэтому
+// бинарник одинаково находит их из go-api/, из корня и в Docker.
+// Правила приоритета и ошибок — как у loadDotEnvFiles.
+func loadDotEnv() error {
+    dir := findEnvDir()
+    basePath := filepath.Join(dir, ".env")
+
+    env, ok := os.LookupEnv("APP_ENV")
+    if !ok {
+        // Ошибку разбора вернёт loadDotEnvFiles, но APP_ENV из .env с ошибкой
+        // выбирает наложение всё равно: иначе одна опечатка в .env подменила
+        // бы .env.production на .env.<по умолчанию>
+        base, err := readEnvFile(basePath)
+        if err != nil {
+            env = scanAppEnv(basePath)
+        } else {
+            env = base["APP_ENV"]
+        }
+    }
+    if env == "" {
+        env = defaultEnv
+    }
+
+    return loadDotEnvFiles(basePath, filepath.Join(dir, ".env."+env))
+}
+
+// loadDotEnvFiles загружает файлы переменных по порядку: значение из более
+// позднего файла перекрывает более раннее, а переменные, уже заданные в
+// окружении процесса, не перезаписываются. Отсутствующие файлы пропускаются.
+// Файлы, которые не удалось разобрать, не мешают загрузить остальные, а их
+// ошибки возвращаются вместе через errors.Join.
+func loadDotEnvFiles(paths ...string) error {
+    var (
+        merged = make(map[string]string)
+        errs   []error
+    )
+    for _, path := range paths {
+        vals, err := readEnvFile(path)
+        if err != nil {
+            errs = append(errs, err)
+            continue
+        }
+        for k, v := range vals {
+            merged[k] = v
+        }
+    }
+
+    for k, v := range merged {
+        if _, exists := os.LookupEnv(k); exists {
+            continue
+        }
+        if err := os.Setenv(k, v); err != nil {
+            errs = append(errs, fmt.Errorf("ошибка установки переменной %s: %w", k, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// findEnvDir ищет ближайшую вверх директорию с .env или корень git-репозитория.
+// Если ничего не нашлось — используется текущая директория.
+func findEnvDir() string {
+    cwd, err := os.Getwd()
+    if err != nil {
+        return "."
+    }
+
+    for dir := cwd; ; dir = filepath.Dir(dir) {
+        for _, marker := range []string{".env", ".git"} {
+            if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+                return dir
+            }
+        }
+        if parent := filepath.Dir(dir); parent == dir {
+            return cwd
+        }
+    }
+}
+
+// readEnvFile читает файл переменных; отсутствие файла — не ошибка
+func readEnvFile(path string) (map[string]string, error) {
+    vals, err := godotenv.Read(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("ошибка разбора %s: %w", path, err)
+    }
+    return vals, nil
+}
+
+// appEnvLineRe — строка .env, задающая APP_ENV: APP_ENV=x, export APP_ENV="x"
+var appEnvLineRe = regexp.MustCompile(`^\s*(?:export\s+)?APP_ENV\s*[=:]\s*(.*)$`)
+
+// scanAppEnv — APP_ENV из файла, который godotenv разобрать не смог: файл
+// читается построчно, последнее присваивание побеждает, как у godotenv
+func scanAppEnv(path string) string {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return ""
+    }
+    env := ""
+    for _, line := range strings.Split(string(data), "\n") {
+        m := appEnvLineRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+        if m == nil {
+            continue
+        }
+        val := strings.TrimSpace(m[1])
+        if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+            val = val[1 : len(val)-1]
+        } else if i := strings.Index(val, " #"); i >= 0 {
+            val = strings.TrimSpace(val[:i])
+        }
+        env = val
+    }
+    return env
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// writeEnvFiles создаёт в новой текущей директории файлы name → содержимое
+func writeEnvFiles(t *testing.T, files map[string]string) string {
+    t.Helper()
+    dir := t.TempDir()
+    for name, body := range files {
+        if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+            t.Fatal(err)
+        }
+    }
+    t.Chdir(dir)
+    return dir
+}
+
+// unsetEnv убирает переменные из окружения на время теста: loadDotEnvFiles
+// пишет в окружение процесса, t.Setenv вернёт прежние значения после теста
+func unsetEnv(t *testing.T, keys ...string) {
+    t.Helper()
+    for _, k := range keys {
+        t.Setenv(k, "")
+        os.Unsetenv(k)
+    }
+}
+
+// Окружение процесса важнее файлов, наложение .env.<APP_ENV> — важнее .env
+func TestDotEnvPrecedence(t *testing.T) {
+    unsetEnv(t, "APP_ENV", "DOTENV_BASE", "DOTENV_OVERLAY", "DOTENV_PROCESS")
+    t.Setenv("DOTENV_PROCESS", "из процесса")
+    writeEnvFiles(t, map[string]string{
+        ".env":         "APP_ENV=staging\nDOTENV_BASE=из .env\nDOTENV_OVERLAY=из .env\nDOTENV_PROCESS=из .env\n",
+        ".env.staging": "DOTENV_OVERLAY=из .env.staging\nDOTENV_PROCESS=из .env.staging\n",
+    })
+
+    if err := loadDotEnv(); err != nil {
+        t.Fatal(err)
+    }
+    for key, want := range map[string]string{
+        "APP_ENV":        "staging",
+        "DOTENV_BASE":    "из .env",
+        "DOTENV_OVERLAY": "из .env.staging",
+        "DOTENV_PROCESS": "из процесса",
+    } {
+        if got := os.Getenv(key); got != want {
+            t.Errorf("%s = %q, нужно %q", key, got, want)
+        }
+    }
+}
+
+// Без APP_ENV наложение выбирается по умолчанию — .env.development
+func TestDotEnvDefaultOverlay(t *testing.T) {
+    unsetEnv(t, "APP_ENV", "DOTENV_OVERLAY")
+    writeEnvFiles(t, map[string]string{
+        ".env.production":  "DOTENV_OVERLAY=production\n",
+        ".env.development": "DOTENV_OVERLAY=development\n",
+    })
+    if err := loadDotEnv(); err != nil {
+        t.Fatal(err)
+    }
+    if got := os.Getenv("DOTENV_OVERLAY"); got != "development" {
+        t.Fatalf("DOTENV_OVERLAY = %q, нужно из .env.development", got)
+    }
+}
+
+// Отсутствующих файлов нет — и ошибки нет
+func TestDotEnvMissingFiles(t *testing.T) {
+    unsetEnv(t, "APP_ENV")
+    writeEnvFiles(t, nil)
+    if err := loadDotEnv(); err != nil {
+        t.Fatalf("без .env-файлов: %v", err)
+    }
+}
+
+// Файл с ошибкой не мешает загрузить остальные, ошибка называет файл,
+// а APP_ENV из сломанного .env всё равно выбирает наложение
+func TestDotEnvParseErrorKeepsOverlay(t *testing.T) {
+    unsetEnv(t, "APP_ENV", "DOTENV_OVERLAY")
+    dir := writeEnvFiles(t, map[string]string{
+        ".env":         "export APP_ENV=\"staging\"\nSECRET='не закрыта\n",
+        ".env.staging": "DOTENV_OVERLAY=staging\n",
+    })
+
+    err := loadDotEnv()
+    if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, ".env")) {
+        t.Fatalf("ошибка = %v, нужна ошибка разбора .env", err)
+    }
+    if got := os.Getenv("DOTENV_OVERLAY"); got != "staging" {
+        t.Fatalf("DOTENV_OVERLAY = %q: наложение по APP_ENV из сломанного .env не загружено", got)
+    }
+}
+
+func TestScanAppEnv(t *testing.T) {
+    for _, tc := range []struct{ body, want string }{
+        {"APP_ENV=staging\n", "staging"},
+        {"export APP_ENV='test'\n", "test"},
+        {"APP_ENV = production # боевой\n", "production"},
+        {"# APP_ENV=staging\nOTHER=1\n", ""},
+        {"APP_ENV=a\r\nAPP_ENV=b\r\n", "b"},
+    } {
+        path := filepath.Join(t.TempDir(), ".env")
+        if err := os.WriteFile(path, []byte(tc.body), 0o600); err != nil {
+            t.Fatal(err)
+        }
+        if got := scanAppEnv(path); got != tc.want {
+            t.Errorf("scanAppEnv(%q) = %q, нужно %q", tc.body, got, tc.want)
+        }
+    }
+}
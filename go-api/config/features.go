@@ -0,0 +1,92 @@
+package config
+
+// Имена флагов для FeatureFlags.IsEnabled и /flags
+const (
+    FlagVision         = "vision"
+    FlagVoice          = "voice"
+    FlagStreaming      = "streaming"
+    FlagSemanticSearch = "semantic_search"
+    FlagResponseCache  = "response_cache"
+    FlagCartReminders  = "cart_reminders"
+    FlagReengagement   = "reengagement"
+    FlagSummaries      = "summaries"
+    FlagTypingDelay    = "typing_delay"
+    FlagHandoff        = "handoff"
+    FlagModeration     = "moderation"
+    FlagMaintenance    = "maintenance"
+)
+
+// FlagInfo — описание флага функции
+type FlagInfo struct {
+    Name string
+    // Env — переменная окружения со значением флага
+    Env     string
+    Default bool
+    // Runtime — флаг проверяется на каждом сообщении и его можно переключить
+    // без перезапуска; остальные читаются один раз при старте
+    Runtime     bool
+    Description string
+}
+
+// knownFlags — все флаги функций в порядке вывода /flags. Имена переменных
+// окружения сохранены прежними, чтобы не ломать существующие .env.
+var knownFlags = []FlagInfo{
+    {Name: FlagVision, Env: "VISION_ENABLED", Runtime: true,
+        Description: "разбирать фото покупателей vision-моделью (дороже обычных запросов)"},
+    {Name: FlagVoice, Env: "VOICE_ENABLED", Runtime: true,
+        Description: "распознавать голосовые сообщения (платно за минуту аудио)"},
+    {Name: FlagStreaming, Env: "STREAMING_ENABLED", Runtime: true,
+        Description: "показывать ответ по мере генерации; модель отвечает без инструментов"},
+    {Name: FlagSemanticSearch, Env: "SEMANTIC_SEARCH",
+        Description: "искать товары по эмбеддингам (нужно расширение pgvector)"},
+    {Name: FlagResponseCache, Env: "RESPONSE_CACHE",
+        Description: "переиспользовать ответы модели на одинаковые первые вопросы"},
+    {Name: FlagCartReminders, Env: "CART_REMINDERS", Default: true,
+        Description: "напоминать о брошенных корзинах"},
+    {Name: FlagReengagement, Env: "REENGAGEMENT_ENABLED",
+        Description: "писать замолчавшим покупателям подсказку от модели; сообщения без запроса — включайте осознанно"},
+    {Name: FlagSummaries, Env: "CONTEXT_SUMMARIES",
+        Description: "сворачивать старые реплики в сводку вместо того, чтобы забывать их"},
+    {Name: FlagTypingDelay, Env: "TYPING_DELAY", Runtime: true,
+        Description: "отвечать с паузой, как будто ответ набирает человек"},
+    {Name: FlagHandoff, Env: "HANDOFF_ENABLED",
+        Description: "передавать разговор живому оператору по просьбе покупателя или решению модели"},
+    {Name: FlagModeration, Env: "MODERATION_ENABLED", Runtime: true,
+        Description: "проверять входящие сообщения модерацией OpenAI (или словарём) до запроса к модели"},
+    {Name: FlagMaintenance, Env: "MAINTENANCE", Runtime: true,
+        Description: "режим обслуживания: всем, кроме админов, отвечать MAINTENANCE_MESSAGE без модели и базы"},
+}
+
+// Flags — описания всех флагов функций
+func Flags() []FlagInfo {
+    return knownFlags
+}
+
+// LookupFlag — описание флага по имени
+func LookupFlag(name string) (FlagInfo, bool) {
+    for _, f := range knownFlags {
+        if f.Name == name {
+            return f, true
+        }
+    }
+    return FlagInfo{}, false
+}
+
+// FeatureFlags — включённые функции по переменным окружения
+type FeatureFlags struct {
+    enabled map[string]bool
+}
+
+// IsEnabled — включена ли функция; неизвестный флаг выключен
+func (f FeatureFlags) IsEnabled(name string) bool {
+    return f.enabled[name]
+}
+
+// featureFlags — читает все флаги из окружения
+func (l *envLoader) featureFlags() FeatureFlags {
+    f := FeatureFlags{enabled: make(map[string]bool, len(knownFlags))}
+    for _, flag := range knownFlags {
+        f.enabled[flag.Name] = l.boolean(flag.Env, flag.Default)
+    }
+    return f
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// weekdays — сокращения дней недели в BUSINESS_HOURS
+var weekdays = map[string]time.Weekday{
+    "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+    "fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// hoursRange — рабочий интервал дня: смещения от полуночи; end меньше start —
+// интервал переходит через полночь и заканчивается на следующий день
+type hoursRange struct {
+    start, end time.Duration
+}
+
+// BusinessHours — часы работы магазина по дням недели в часовом поясе Location
+type BusinessHours struct {
+    Location *time.Location
+    days     [7][]hoursRange
+}
+
+// ParseBusinessHours разбирает часы работы вида
+// "mon-fri 09:00-18:00; sat 10:00-16:00; fri 20:00-02:00".
+// Диапазон дней может переходить через воскресенье (sat-mon), конец
+// 24:00 — до полуночи, конец раньше начала — работа после полуночи
+// следующего дня.
+func ParseBusinessHours(spec string, loc *time.Location) (*BusinessHours, error) {
+    h := &BusinessHours{Location: loc}
+    for _, part := range strings.Split(spec, ";") {
+        if part = strings.TrimSpace(part); part == "" {
+            continue
+        }
+        days, clock, ok := strings.Cut(part, " ")
+        if !ok {
+            return nil, fmt.Errorf("интервал %q должен иметь вид \"mon-fri 09:00-18:00\"", part)
+        }
+        from, to, err := parseWeekdays(days)
+        if err != nil {
+            return nil, err
+        }
+        r, err := parseHoursRange(strings.TrimSpace(clock))
+        if err != nil {
+            return nil, err
+        }
+        for d := from; ; d = (d + 1) % 7 {
+            h.days[d] = append(h.days[d], r)
+            if d == to {
+                break
+            }
+        }
+    }
+    return h, nil
+}
+
+// parseWeekdays — день (mon) или диапазон дней (mon-fri)
+func parseWeekdays(s string) (from, to time.Weekday, err error) {
+    first, last, isRange := strings.Cut(strings.ToLower(s), "-")
+    from, ok := weekdays[first]
+    if !ok {
+        return 0, 0, fmt.Errorf("неизвестный день недели %q, ожидается mon, tue, ..., sun", first)
+    }
+    if !isRange {
+        return from, from, nil
+    }
+    to, ok = weekdays[last]
+    if !ok {
+        return 0, 0, fmt.Errorf("неизвестный день недели %q, ожидается mon, tue, ..., sun", last)
+    }
+    return from, to, nil
+}
+
+// parseHoursRange — интервал ЧЧ:ММ-ЧЧ:ММ
+func parseHoursRange(s string) (hoursRange, error) {
+    start, end, ok := strings.Cut(s, "-")
+    if !ok {
+        return hoursRange{}, fmt.Errorf("время %q должно иметь вид ЧЧ:ММ-ЧЧ:ММ", s)
+    }
+    var r hoursRange
+    var err error
+    if r.start, err = parseClock(start); err != nil {
+        return hoursRange{}, err
+    }
+    if r.end, err = parseClock(end); err != nil {
+        return hoursRange{}, err
+    }
+    if r.start == r.end {
+        return hoursRange{}, fmt.Errorf("интервал %q пустой: начало совпадает с концом", s)
+    }
+    if r.start == 24*time.Hour {
+        return hoursRange{}, fmt.Errorf("интервал %q не может начинаться в 24:00", s)
+    }
+    return r, nil
+}
+
+// parseClock — время ЧЧ:ММ от 00:00 до 24:00 как смещение от полуночи
+func parseClock(s string) (time.Duration, error) {
+    s = strings.TrimSpace(s)
+    if s == "24:00" {
+        return 24 * time.Hour, nil
+    }
+    t, err := time.Parse("15:04", s)
+    if err != nil {
+        return 0, fmt.Errorf("время %q должно иметь вид ЧЧ:ММ", s)
+    }
+    return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Open — открыт ли магазин в момент t. Интервал, начатый накануне и
+// перешедший через полночь, тоже считается.
+func (h *BusinessHours) Open(t time.Time) bool {
+    local := t.In(h.Location)
+    since := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+        time.Duration(local.Second())*time.Second
+    today := local.Weekday()
+    for _, r := range h.days[today] {
+        if since >= r.start && (since < r.end || r.end < r.start) {
+            return true
+        }
+    }
+    for _, r := range h.days[(today+6)%7] {
+        if r.end < r.start && since < r.end {
+            return true
+        }
+    }
+    return false
+}
+
+// OffHours — нерабочее ли время в момент t; без BUSINESS_HOURS — никогда
+func (c *Config) OffHours(t time.Time) bool {
+    return c.BusinessHours != nil && !c.BusinessHours.Open(t)
+}
+
+// NextOpen — ближайшее после t начало рабочего интервала; для t в
+// нерабочее время это открытие магазина. Нулевое время — в расписании нет
+// ни одного интервала.
+func (h *BusinessHours) NextOpen(t time.Time) time.Time {
+    local := t.In(h.Location)
+    var next time.Time
+    for i := 0; i <= 7; i++ {
+        day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, h.Location)
+        for _, r := range h.days[day.Weekday()] {
+            start := day.Add(r.start)
+            if start.After(t) && (next.IsZero() || start.Before(next)) {
+                next = start
+            }
+        }
+        if !next.IsZero() {
+            return next
+        }
+    }
+    return next
+}
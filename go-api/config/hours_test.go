@@ -0,0 +1,54 @@
+package config
+
+import (
+    "testing"
+    "time"
+)
+
+func TestBusinessHours(t *testing.T) {
+    msk, err := time.LoadLocation("Europe/Moscow")
+    if err != nil {
+        t.Skip("нет базы часовых поясов:", err)
+    }
+    h, err := ParseBusinessHours("mon-fri 09:00-18:00; sat 10:00-16:00; fri 20:00-02:00", msk)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // 12 октября 2026 — понедельник
+    at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, msk) }
+
+    cases := []struct {
+        name string
+        now  time.Time
+        open bool
+        next time.Time
+    }{
+        {"понедельник до открытия", at(12, 8, 0), false, at(12, 9, 0)},
+        {"понедельник днём", at(12, 12, 0), true, at(13, 9, 0)},
+        {"закрытие не входит в часы", at(12, 18, 0), false, at(13, 9, 0)},
+        {"пятница перед ночной сменой", at(16, 19, 0), false, at(16, 20, 0)},
+        {"ночь на субботу", at(17, 1, 0), true, at(17, 10, 0)},
+        {"конец ночной смены", at(17, 2, 0), false, at(17, 10, 0)},
+        {"суббота вечером", at(17, 17, 0), false, at(19, 9, 0)},
+        {"воскресенье", at(18, 12, 0), false, at(19, 9, 0)},
+        {"в другом часовом поясе", at(12, 8, 0).UTC(), false, at(12, 9, 0)},
+    }
+    for _, tc := range cases {
+        if got := h.Open(tc.now); got != tc.open {
+            t.Errorf("%s: Open(%s) = %v, нужно %v", tc.name, tc.now, got, tc.open)
+        }
+        if got := h.NextOpen(tc.now); !got.Equal(tc.next) {
+            t.Errorf("%s: NextOpen(%s) = %s, нужно %s", tc.name, tc.now, got, tc.next)
+        }
+    }
+}
+
+func TestNextOpenWithoutHours(t *testing.T) {
+    h, err := ParseBusinessHours("", time.UTC)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := h.NextOpen(time.Now()); !got.IsZero() {
+        t.Fatalf("NextOpen без интервалов = %s, нужно нулевое время", got)
+    }
+}
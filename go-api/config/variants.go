@@ -0,0 +1,91 @@
+package config
+
+import (
+    "hash/fnv"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// PromptVariant — вариант системного промпта в A/B-тесте
+type PromptVariant struct {
+    Name string
+    // Weight — доля чатов с этим вариантом относительно суммы весов
+    Weight int
+    // Prompt — текст промпта; пусто — базовый SYSTEM_PROMPT (контрольная группа)
+    Prompt string
+}
+
+// variantNameRe — допустимое имя варианта: оно же часть имени переменной с файлом промпта
+var variantNameRe = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// PickPromptVariant выбирает вариант для чата по хэшу его id: один и тот же
+// чат при тех же вариантах всегда получает один и тот же, а чаты в целом
+// делятся пропорционально весам. Пусто — вариантов нет.
+func PickPromptVariant(variants []PromptVariant, chatID int64) string {
+    total := 0
+    for _, v := range variants {
+        total += v.Weight
+    }
+    if total <= 0 {
+        return ""
+    }
+
+    h := fnv.New32a()
+    h.Write([]byte(strconv.FormatInt(chatID, 10)))
+    n := int(h.Sum32() % uint32(total))
+    for _, v := range variants {
+        if n < v.Weight {
+            return v.Name
+        }
+        n -= v.Weight
+    }
+    return variants[len(variants)-1].Name
+}
+
+// promptVariants — читает варианты вида "control=50,sales=50". Промпт
+// варианта — из файла PROMPT_VARIANT_<ИМЯ>_FILE; вариант без файла
+// использует базовый SYSTEM_PROMPT. Пустая переменная — теста нет.
+func (l *envLoader) promptVariants(key string) []PromptVariant {
+    var variants []PromptVariant
+    seen := make(map[string]bool)
+    for _, part := range l.stringList(key) {
+        name, weight, ok := strings.Cut(part, "=")
+        name = strings.ToLower(strings.TrimSpace(name))
+        w, err := strconv.Atoi(strings.TrimSpace(weight))
+        switch {
+        case !ok || err != nil || w <= 0:
+            l.fail("вариант %q в %s должен иметь вид имя=вес с весом больше нуля", part, key)
+            continue
+        case !variantNameRe.MatchString(name):
+            l.fail("имя варианта %q в %s: допустимы до 32 символов a-z, 0-9 и _", name, key)
+            continue
+        case seen[name]:
+            l.fail("вариант %q указан в %s дважды", name, key)
+            continue
+        }
+        seen[name] = true
+        variants = append(variants, PromptVariant{Name: name, Weight: w, Prompt: l.variantPrompt(name)})
+    }
+    return variants
+}
+
+// variantPrompt — промпт варианта из PROMPT_VARIANT_<ИМЯ>_FILE или пусто
+func (l *envLoader) variantPrompt(name string) string {
+    key := "PROMPT_VARIANT_" + strings.ToUpper(name) + "_FILE"
+    path := l.getEnv(key, "")
+    if path == "" {
+        return ""
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        l.fail("не удалось прочитать %s: %v", key, err)
+        return ""
+    }
+    prompt := strings.TrimSpace(string(data))
+    if prompt == "" {
+        l.fail("файл %s %s пуст", key, path)
+    }
+    return prompt
+}
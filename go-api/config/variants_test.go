@@ -0,0 +1,67 @@
+package config
+
+import (
+    "math"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestPickPromptVariantStable(t *testing.T) {
+    variants := []PromptVariant{{Name: "control", Weight: 50}, {Name: "sales", Weight: 50}}
+    for chatID := int64(1); chatID <= 100; chatID++ {
+        first := PickPromptVariant(variants, chatID)
+        if again := PickPromptVariant(variants, chatID); again != first {
+            t.Fatalf("чат %d: получено %q, затем %q", chatID, first, again)
+        }
+    }
+}
+
+func TestPickPromptVariantWeights(t *testing.T) {
+    variants := []PromptVariant{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}}
+    counts := map[string]int{}
+    const chats = 20000
+    for chatID := int64(1); chatID <= chats; chatID++ {
+        counts[PickPromptVariant(variants, chatID)]++
+    }
+    share := float64(counts["b"]) / chats
+    if math.Abs(share-0.75) > 0.03 {
+        t.Fatalf("доля варианта b: получено %.3f, ожидалось около 0.75 (%v)", share, counts)
+    }
+}
+
+func TestPickPromptVariantEmpty(t *testing.T) {
+    if got := PickPromptVariant(nil, 1); got != "" {
+        t.Fatalf("без вариантов: получено %q", got)
+    }
+    if got := PickPromptVariant([]PromptVariant{{Name: "only", Weight: 5}}, 42); got != "only" {
+        t.Fatalf("один вариант: получено %q", got)
+    }
+}
+
+func TestPromptVariantsParsing(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "sales.txt")
+    if err := os.WriteFile(file, []byte("  Продавай смелее.\n"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    cfg := mustConfig(t, map[string]string{
+        "PROMPT_VARIANTS":           "Control=50, sales=50",
+        "PROMPT_VARIANT_SALES_FILE": file,
+    })
+    if len(cfg.PromptVariants) != 2 {
+        t.Fatalf("вариантов: получено %d, ожидалось 2", len(cfg.PromptVariants))
+    }
+    if v := cfg.PromptVariants[0]; v.Name != "control" || v.Prompt != "" {
+        t.Fatalf("контрольная группа: %+v", v)
+    }
+    if v := cfg.PromptVariants[1]; v.Prompt != "Продавай смелее." {
+        t.Fatalf("промпт варианта: %q", v.Prompt)
+    }
+
+    for _, bad := range []string{"a=0", "a", "a=1,a=2", "Имя=1"} {
+        if msg := configError(t, map[string]string{"PROMPT_VARIANTS": bad}); !strings.Contains(msg, "PROMPT_VARIANTS") {
+            t.Errorf("PROMPT_VARIANTS=%q: ошибка не называет переменную: %s", bad, msg)
+        }
+    }
+}
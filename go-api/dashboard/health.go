@@ -0,0 +1,68 @@
+package dashboard
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "sort"
+    "time"
+)
+
+// probeTimeout — предел на проверку зависимостей, чтобы пробы не зависали
+const probeTimeout = 2 * time.Second
+
+// Check — проверка доступности одной зависимости
+type Check func(ctx context.Context) error
+
+// readyStatus — ответ /readyz
+type readyStatus struct {
+    Status   string   `json:"status"`
+    Failed   []string `json:"failed,omitempty"`
+    Degraded []string `json:"degraded,omitempty"`
+}
+
+// HealthzHandler — liveness-проба: процесс жив и обслуживает HTTP
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, readyStatus{Status: "ok"})
+}
+
+// ReadyzHandler — readiness-проба: проверяет все зависимости и
+// возвращает 503 со списком недоступных, если не отвечает хотя бы одна из
+// required. Недоступные optional (без них сервис работает хуже, но работает)
+// попадают в ответ как degraded со статусом 200.
+func ReadyzHandler(required, optional map[string]Check) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+        defer cancel()
+
+        failed := runChecks(ctx, required)
+        degraded := runChecks(ctx, optional)
+
+        switch {
+        case len(failed) > 0:
+            writeJSON(w, http.StatusServiceUnavailable, readyStatus{Status: "unavailable", Failed: failed, Degraded: degraded})
+        case len(degraded) > 0:
+            writeJSON(w, http.StatusOK, readyStatus{Status: "degraded", Degraded: degraded})
+        default:
+            writeJSON(w, http.StatusOK, readyStatus{Status: "ok"})
+        }
+    }
+}
+
+// runChecks — отсортированные имена проверок, вернувших ошибку
+func runChecks(ctx context.Context, checks map[string]Check) []string {
+    var failed []string
+    for name, check := range checks {
+        if err := check(ctx); err != nil {
+            failed = append(failed, name)
+        }
+    }
+    sort.Strings(failed)
+    return failed
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
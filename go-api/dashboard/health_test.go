@@ -0,0 +1,46 @@
+package dashboard
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "slices"
+    "testing"
+)
+
+func TestReadyz(t *testing.T) {
+    up := func(context.Context) error { return nil }
+    down := func(context.Context) error { return errors.New("connection refused") }
+    cases := []struct {
+        name     string
+        required map[string]Check
+        optional map[string]Check
+        code     int
+        want     readyStatus
+    }{
+        {"всё доступно", map[string]Check{"postgres": up}, map[string]Check{"redis": up},
+            http.StatusOK, readyStatus{Status: "ok"}},
+        {"Redis недоступен", map[string]Check{"postgres": up}, map[string]Check{"redis": down},
+            http.StatusOK, readyStatus{Status: "degraded", Degraded: []string{"redis"}}},
+        {"PostgreSQL недоступен", map[string]Check{"postgres": down}, map[string]Check{"redis": down},
+            http.StatusServiceUnavailable, readyStatus{Status: "unavailable", Failed: []string{"postgres"}, Degraded: []string{"redis"}}},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            w := httptest.NewRecorder()
+            ReadyzHandler(tc.required, tc.optional)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+            if w.Code != tc.code {
+                t.Fatalf("код %d, нужно %d", w.Code, tc.code)
+            }
+            var got readyStatus
+            if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+                t.Fatal(err)
+            }
+            if got.Status != tc.want.Status || !slices.Equal(got.Failed, tc.want.Failed) || !slices.Equal(got.Degraded, tc.want.Degraded) {
+                t.Fatalf("ответ %+v, нужно %+v", got, tc.want)
+            }
+        })
+    }
+}
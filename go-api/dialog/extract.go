@@ -0,0 +1,117 @@
+package dialog
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+
+    "ai_seller/logging"
+    "ai_seller/openai"
+)
+
+// maxExtractQuantity — больше этого количество из текста считается ошибкой разбора
+const maxExtractQuantity = 100
+
+// extractPrompt — инструкция модели для разбора заказа
+const extractPrompt = "Извлеки из сообщения покупателя, что он хочет заказать. " +
+    `Ответь JSON-объектом {"product": string, "quantity": integer, "size": string, "color": string}. ` +
+    "product — название или тип товара без размера и цвета; quantity — сколько штук, по умолчанию 1; " +
+    "size и color — пустая строка, если не указаны. Если покупатель не называет товар, product — пустая строка."
+
+// extractRetryPrompt — добавка ко второй попытке, если первый ответ не разобрался
+const extractRetryPrompt = "Предыдущий ответ не подошёл: %s. Ответь строго одним JSON-объектом " +
+    "ровно с полями product, quantity, size, color, без пояснений и без других полей."
+
+// ErrNoOrderDetails — в сообщении не нашлось товара для заказа
+var ErrNoOrderDetails = errors.New("в сообщении не указан товар")
+
+// JSONCompleter — модель с режимом JSON; реализуется *openai.Client
+type JSONCompleter interface {
+    JSONCompletion(ctx context.Context, messages []openai.Message) (string, error)
+}
+
+var _ JSONCompleter = (*openai.Client)(nil)
+
+// OrderDetails — что покупатель хочет заказать, по его сообщению
+type OrderDetails struct {
+    Product  string `json:"product"`
+    Quantity int    `json:"quantity"`
+    Size     string `json:"size"`
+    Color    string `json:"color"`
+}
+
+// ExtractOrder разбирает свободный текст покупателя в OrderDetails. Модели
+// с режимом JSON спрашиваются в нём, остальные — обычным запросом с той же
+// инструкцией. Ответ, который не разобрался, переспрашивается один раз
+// с более строгой инструкцией.
+func ExtractOrder(ctx context.Context, ai Completer, text string) (OrderDetails, error) {
+    messages := []openai.Message{
+        openai.System(extractPrompt),
+        openai.User(text),
+    }
+
+    details, raw, err := extractOnce(ctx, ai, messages)
+    if err == nil || !errors.Is(err, errBadExtraction) {
+        return details, err
+    }
+    logging.FromContext(ctx).Warn("модель вернула некорректный JSON заказа, переспрашиваем", "err", err)
+
+    messages = append(messages,
+        openai.Assistant(raw),
+        openai.System(fmt.Sprintf(extractRetryPrompt, err)),
+    )
+    details, _, err = extractOnce(ctx, ai, messages)
+    return details, err
+}
+
+// errBadExtraction — ответ модели не разобрался в OrderDetails
+var errBadExtraction = errors.New("некорректный ответ модели")
+
+// extractOnce — один запрос к модели и разбор ответа; raw — ответ как есть
+func extractOnce(ctx context.Context, ai Completer, messages []openai.Message) (OrderDetails, string, error) {
+    var raw string
+    var err error
+    if jc, ok := ai.(JSONCompleter); ok {
+        raw, err = jc.JSONCompletion(ctx, messages)
+    } else {
+        raw, err = ai.ChatCompletion(ctx, messages)
+    }
+    if err != nil {
+        return OrderDetails{}, "", err
+    }
+
+    details, err := parseOrderDetails(raw)
+    return details, raw, err
+}
+
+// parseOrderDetails разбирает и проверяет ответ модели. Лишние поля —
+// признак того, что модель не следует схеме, и тоже ошибка.
+func parseOrderDetails(raw string) (OrderDetails, error) {
+    dec := json.NewDecoder(bytes.NewReader([]byte(strings.TrimSpace(raw))))
+    dec.DisallowUnknownFields()
+
+    var d OrderDetails
+    if err := dec.Decode(&d); err != nil {
+        return OrderDetails{}, fmt.Errorf("%w: %v", errBadExtraction, err)
+    }
+    if dec.More() {
+        return OrderDetails{}, fmt.Errorf("%w: после объекта есть лишний текст", errBadExtraction)
+    }
+
+    d.Product = strings.TrimSpace(d.Product)
+    d.Size = strings.TrimSpace(d.Size)
+    d.Color = strings.TrimSpace(d.Color)
+    if d.Quantity == 0 {
+        d.Quantity = 1
+    }
+    if d.Quantity < 0 || d.Quantity > maxExtractQuantity {
+        return OrderDetails{}, fmt.Errorf("%w: количество %d вне диапазона 1..%d", errBadExtraction, d.Quantity, maxExtractQuantity)
+    }
+    if d.Product == "" {
+        return OrderDetails{}, ErrNoOrderDetails
+    }
+    return d, nil
+}
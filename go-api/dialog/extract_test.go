@@ -0,0 +1,113 @@
+package dialog
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "ai_seller/openai"
+)
+
+// jsonModel — модель с режимом JSON, отвечающая по порядку из replies
+// (последний повторяется) и запоминающая диалоги
+type jsonModel struct {
+    replies  []string
+    requests [][]openai.Message
+}
+
+func (m *jsonModel) ChatCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    return "", errors.New("запрос не в режиме JSON")
+}
+
+func (m *jsonModel) JSONCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    m.requests = append(m.requests, messages)
+    return m.replies[min(len(m.requests), len(m.replies))-1], nil
+}
+
+func TestExtractOrder(t *testing.T) {
+    cases := []struct {
+        name     string
+        replies  []string
+        want     OrderDetails
+        err      error
+        requests int
+    }{
+        {"корректный JSON", []string{`{"product": " футболка ", "quantity": 2, "size": "M", "color": "чёрный"}`},
+            OrderDetails{Product: "футболка", Quantity: 2, Size: "M", Color: "чёрный"}, nil, 1},
+        {"количество по умолчанию", []string{`{"product": "худи", "quantity": 0, "size": "", "color": ""}`},
+            OrderDetails{Product: "худи", Quantity: 1}, nil, 1},
+        {"не JSON, потом корректный", []string{"Конечно! Вот заказ: футболка", `{"product": "футболка", "quantity": 1, "size": "L", "color": ""}`},
+            OrderDetails{Product: "футболка", Quantity: 1, Size: "L"}, nil, 2},
+        {"лишнее поле, потом корректный", []string{`{"product": "кепка", "quantity": 1, "size": "", "color": "", "price": 990}`, `{"product": "кепка", "quantity": 1, "size": "", "color": ""}`},
+            OrderDetails{Product: "кепка", Quantity: 1}, nil, 2},
+        {"текст после объекта дважды", []string{`{"product": "кепка", "quantity": 1, "size": "", "color": ""} {}`}, OrderDetails{}, errBadExtraction, 2},
+        {"количество вне диапазона дважды", []string{`{"product": "носки", "quantity": 1000, "size": "", "color": ""}`}, OrderDetails{}, errBadExtraction, 2},
+        {"товар не назван", []string{`{"product": "", "quantity": 1, "size": "M", "color": ""}`}, OrderDetails{}, ErrNoOrderDetails, 1},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            m := &jsonModel{replies: tc.replies}
+            got, err := ExtractOrder(context.Background(), m, "две чёрные футболки M")
+            if !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+                t.Fatalf("ошибка %v, нужна %v", err, tc.err)
+            }
+            if got != tc.want {
+                t.Fatalf("разобрано %+v, нужно %+v", got, tc.want)
+            }
+            if len(m.requests) != tc.requests {
+                t.Fatalf("запросов к модели %d, нужно %d", len(m.requests), tc.requests)
+            }
+        })
+    }
+}
+
+// Повторный запрос показывает модели её ответ и строгую инструкцию с причиной
+func TestExtractOrderRetryPrompt(t *testing.T) {
+    m := &jsonModel{replies: []string{"футболка, 2 шт", `{"product": "футболка", "quantity": 2, "size": "", "color": ""}`}}
+    if _, err := ExtractOrder(context.Background(), m, "две футболки"); err != nil {
+        t.Fatal(err)
+    }
+    retry := m.requests[1]
+    if len(retry) != 4 || retry[2].Role != openai.RoleAssistant || retry[2].Content != "футболка, 2 шт" {
+        t.Fatalf("повторный запрос %+v", retry)
+    }
+    if last := retry[3]; last.Role != openai.RoleSystem || !strings.Contains(last.Content, "строго одним JSON-объектом") {
+        t.Fatalf("последнее сообщение повтора %+v", last)
+    }
+}
+
+// *openai.Client просит ответ в режиме JSON, а в сообщениях есть слово JSON,
+// без которого OpenAI такой запрос отклоняет
+func TestExtractOrderJSONMode(t *testing.T) {
+    var req struct {
+        ResponseFormat struct {
+            Type string `json:"type"`
+        } `json:"response_format"`
+        Messages []openai.Message `json:"messages"`
+    }
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        json.NewDecoder(r.Body).Decode(&req)
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"product\":\"худи\",\"quantity\":1,\"size\":\"XL\",\"color\":\"\"}"}}]}`))
+    }))
+    defer srv.Close()
+
+    ai := openai.NewClient("sk-test", openai.Options{BaseURL: srv.URL})
+    got, err := ExtractOrder(context.Background(), ai, "худи XL")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got != (OrderDetails{Product: "худи", Quantity: 1, Size: "XL"}) {
+        t.Fatalf("разобрано %+v", got)
+    }
+    if req.ResponseFormat.Type != "json_object" {
+        t.Fatalf("response_format %q", req.ResponseFormat.Type)
+    }
+    if len(req.Messages) == 0 || !strings.Contains(req.Messages[0].Content, "JSON") {
+        t.Fatalf("сообщения без слова JSON: %+v", req.Messages)
+    }
+}
@@ -0,0 +1,86 @@
+package dialog
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "ai_seller/openai"
+)
+
+// update — перезаписать эталоны: go test ./dialog -run TestAssemblePromptGolden -update
+var update = flag.Bool("update", false, "перезаписать эталоны в testdata")
+
+// renderPrompt — промпт в читаемом виде для сверки с эталоном
+func renderPrompt(messages []openai.Message, latest string) string {
+    var sb strings.Builder
+    for _, m := range messages {
+        fmt.Fprintf(&sb, "--- %s ---\n%s\n", m.Role, m.Content)
+    }
+    fmt.Fprintf(&sb, "--- latest ---\n%s\n", latest)
+    return sb.String()
+}
+
+func TestAssemblePromptGolden(t *testing.T) {
+    history := []openai.Message{
+        openai.User("есть улун?"),
+        openai.Assistant("Есть Те Гуань Инь, 500 ₽ за 100 г."),
+        openai.User("а пуэр?"),
+        openai.Assistant("Шу пуэр 2015 года, 800 ₽ за блин."),
+    }
+    cases := map[string]PromptInputs{
+        "minimal": {
+            SystemPrompt: "Ты продавец чайного магазина.",
+            Latest:       "привет",
+            TokenBudget:  4000,
+        },
+        "full": {
+            SystemPrompt: "Ты продавец чайного магазина.",
+            Profile:      "Покупателя зовут Анна, пишет по-русски.",
+            Summary:      "Анна раньше брала зелёный чай и спрашивала о доставке в Казань.",
+            Brief:        true,
+            History:      history,
+            Latest:       "давайте улун",
+            TokenBudget:  4000,
+        },
+        "shared_answer": {
+            SystemPrompt: "Ты продавец чайного магазина.",
+            Profile:      "Покупатель пишет по-русски.",
+            Latest:       "сколько стоит доставка?",
+            TokenBudget:  4000,
+        },
+        "trimmed_history": {
+            SystemPrompt: "Ты продавец чайного магазина.",
+            History:      history,
+            Latest:       "а что посоветуете?",
+            TokenBudget:  60,
+        },
+        "over_budget": {
+            SystemPrompt: "Ты продавец чайного магазина.",
+            History:      history,
+            Latest:       strings.Repeat("расскажите подробнее про каждый чай ", 200),
+            TokenBudget:  300,
+        },
+    }
+    for name, in := range cases {
+        t.Run(name, func(t *testing.T) {
+            got := renderPrompt(AssemblePrompt(in))
+            path := filepath.Join("testdata", name+".golden")
+            if *update {
+                if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            want, err := os.ReadFile(path)
+            if err != nil {
+                t.Fatalf("эталон %s: %v (создать: -update)", path, err)
+            }
+            if got != string(want) {
+                t.Fatalf("промпт расходится с %s (обновить: -update)\nполучено:\n%s\nэталон:\n%s", path, got, want)
+            }
+        })
+    }
+}
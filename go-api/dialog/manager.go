@@ -16,10 +16,8 @@ func NewManager() DialogManager {
 }
 
 // HandleMessage — обработка входящего сообщения
-func (m *DefaultManager) HandleMessage(userID string, input string) 
-(string, error) {
+func (m *DefaultManager) HandleMessage(userID string, input string) (string, error) {
     // TODO: В будущем здесь будет вызов LLM и логика контекста
     fmt.Printf("📨 [%s] %s\n", userID, input)
     return "🔁 Ответ будет здесь (заглушка)", nil
 }
-
@@ -0,0 +1,151 @@
+package dialog
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "sync"
+
+    "ai_seller/logging"
+    "ai_seller/openai"
+    "ai_seller/storage"
+
+    "golang.org/x/sync/singleflight"
+)
+
+// summarizeBatch — сколько старых сообщений сворачивать за один запрос к модели
+const summarizeBatch = 100
+
+// summaryPrompt — инструкция модели для обновления сводки
+const summaryPrompt = "Ты ведёшь краткую сводку переписки продавца с покупателем. " +
+    "Обнови сводку с учётом новых реплик: что ищет покупатель, его предпочтения и бюджет, " +
+    "обсуждённые товары, заказы и договорённости. Не больше 150 слов, без приветствий. " +
+    "Ответь только текстом сводки."
+
+// Completer — запрос к модели без инструментов
+type Completer interface {
+    ChatCompletion(ctx context.Context, messages []openai.Message) (string, error)
+}
+
+// SummaryReader — сводка ранней части диалога; реализуется *storage.SummaryStore
+// и storage.GuardedSummaries
+type SummaryReader interface {
+    GetSummary(ctx context.Context, chatID int64) (storage.Summary, error)
+}
+
+// SummaryStore — чтение и запись сводок
+type SummaryStore interface {
+    SummaryReader
+    SaveSummary(ctx context.Context, chatID int64, text string, upTo int64) error
+}
+
+// UnsummarizedReader — сообщения, ещё не вошедшие в сводку
+type UnsummarizedReader interface {
+    Unsummarized(ctx context.Context, chatID, after int64, keep, limit int) ([]storage.Message, error)
+}
+
+// Summarizer сворачивает старые реплики чата в сводку, которую ContextBuilder
+// подставляет вместо них. Последние historyLimit сообщений в сводку не
+// попадают: их модель видит дословно.
+type Summarizer struct {
+    ai        Completer
+    messages  UnsummarizedReader
+    summaries SummaryStore
+    // every — сколько новых старых сообщений копится до обновления сводки
+    every int
+    // inflight — одна сводка на чат за раз
+    inflight singleflight.Group
+
+    // replies — ответы чата с последней проверки сводки, см. Due
+    mu      sync.Mutex
+    replies map[int64]int
+}
+
+// NewSummarizer — фабрика сводок; every — порог для SummarizeIfDue
+func NewSummarizer(ai Completer, messages UnsummarizedReader, summaries SummaryStore, every int) *Summarizer {
+    return &Summarizer{ai: ai, messages: messages, summaries: summaries, every: max(every, 1), replies: make(map[int64]int)}
+}
+
+// Due отмечает ответ в чате и сообщает, пора ли звать SummarizeIfDue: ответ
+// добавляет две реплики, поэтому every новых реплик набирается не раньше
+// чем за every/2 ответов, и до этого сводку нет смысла даже проверять.
+// Счётчики живут в памяти процесса: после перезапуска проверка лишь
+// сдвигается на несколько ответов.
+func (s *Summarizer) Due(chatID int64) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.replies[chatID]++
+    if s.replies[chatID]*2 < s.every {
+        return false
+    }
+    delete(s.replies, chatID)
+    return true
+}
+
+// Summarize дописывает в сводку все сообщения старше последних historyLimit.
+// Если таких нет, ничего не делает.
+func (s *Summarizer) Summarize(ctx context.Context, chatID int64) error {
+    return s.summarize(ctx, chatID, 1)
+}
+
+// SummarizeIfDue обновляет сводку, только когда за её пределами накопилось
+// не меньше every сообщений, — чтобы не звать модель после каждой реплики
+func (s *Summarizer) SummarizeIfDue(ctx context.Context, chatID int64) error {
+    return s.summarize(ctx, chatID, s.every)
+}
+
+func (s *Summarizer) summarize(ctx context.Context, chatID int64, minPending int) error {
+    _, err, _ := s.inflight.Do(strconv.FormatInt(chatID, 10), func() (any, error) {
+        prev, err := s.summaries.GetSummary(ctx, chatID)
+        if err != nil && !errors.Is(err, storage.ErrNotFound) {
+            return nil, err
+        }
+
+        pending, err := s.messages.Unsummarized(ctx, chatID, prev.UpTo, historyLimit, summarizeBatch)
+        if err != nil {
+            return nil, err
+        }
+        if len(pending) < minPending {
+            return nil, nil
+        }
+
+        text, err := s.ai.ChatCompletion(ctx, summaryRequest(prev.Text, pending))
+        if err != nil {
+            return nil, fmt.Errorf("ошибка расчёта сводки: %w", err)
+        }
+        text = strings.TrimSpace(text)
+        if text == "" {
+            return nil, errors.New("модель вернула пустую сводку")
+        }
+
+        upTo := pending[len(pending)-1].ID
+        if err := s.summaries.SaveSummary(ctx, chatID, text, upTo); err != nil {
+            return nil, err
+        }
+        logging.FromContext(ctx).Info("сводка диалога обновлена", "chat_id", chatID, "messages", len(pending), "up_to", upTo)
+        return nil, nil
+    })
+    return err
+}
+
+// summaryRequest — запрос к модели: прежняя сводка и новые реплики
+func summaryRequest(prev string, pending []storage.Message) []openai.Message {
+    var sb strings.Builder
+    if prev != "" {
+        sb.WriteString("Текущая сводка:\n" + prev + "\n\n")
+    }
+    sb.WriteString("Новые реплики:\n")
+    for _, m := range pending {
+        who := "Покупатель"
+        if m.Role == "assistant" {
+            who = "Продавец"
+        }
+        sb.WriteString(who + ": " + m.Content + "\n")
+    }
+    return []openai.Message{
+        openai.System(summaryPrompt),
+        openai.User(sb.String()),
+    }
+}
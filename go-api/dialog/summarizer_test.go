@@ -0,0 +1,73 @@
+package dialog
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "testing"
+
+    "ai_seller/memstore"
+    "ai_seller/openai"
+)
+
+// countingCompleter — модель, которая считает запросы и отвечает reply
+type countingCompleter struct {
+    calls int
+    reply string
+    last  []openai.Message
+}
+
+func (c *countingCompleter) ChatCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    c.calls++
+    c.last = messages
+    return c.reply, nil
+}
+
+func TestSummarizerDueDebouncesReplies(t *testing.T) {
+    s := NewSummarizer(&countingCompleter{}, &memstore.Messages{}, &memstore.Summaries{}, 10)
+    for round := range 2 {
+        for i := 1; i < 5; i++ {
+            if s.Due(42) {
+                t.Fatalf("круг %d: проверка после %d ответов, every=10 требует 5", round, i)
+            }
+        }
+        if !s.Due(42) {
+            t.Fatalf("круг %d: после 5 ответов сводку пора проверить", round)
+        }
+    }
+    // Счётчики чатов независимы
+    if s.Due(7) {
+        t.Fatal("первый ответ другого чата не должен запускать проверку")
+    }
+}
+
+func TestSummarizeIfDueFoldsMessagesOutsideHistory(t *testing.T) {
+    ctx := context.Background()
+    messages, summaries := &memstore.Messages{}, &memstore.Summaries{}
+    ai := &countingCompleter{reply: "Ищет зелёный чай до 1000 ₽"}
+    s := NewSummarizer(ai, messages, summaries, 10)
+
+    for i := range historyLimit + 9 {
+        messages.SaveMessage(ctx, 42, "user", fmt.Sprintf("реплика %d", i))
+    }
+    if err := s.SummarizeIfDue(ctx, 42); err != nil || ai.calls != 0 {
+        t.Fatalf("9 реплик за окном истории: вызовов модели %d, ошибка %v; порог 10", ai.calls, err)
+    }
+
+    messages.SaveMessage(ctx, 42, "assistant", "ответ")
+    if err := s.SummarizeIfDue(ctx, 42); err != nil {
+        t.Fatal(err)
+    }
+    sum, err := summaries.GetSummary(ctx, 42)
+    if err != nil || sum.Text != ai.reply || sum.UpTo != 10 {
+        t.Fatalf("сводка %+v, %v; ожидалась по первым 10 репликам", sum, err)
+    }
+    if req := ai.last[len(ai.last)-1].Content; !strings.Contains(req, "реплика 0") || strings.Contains(req, "реплика 10") {
+        t.Fatalf("в сводку ушли не те реплики:\n%s", req)
+    }
+
+    // Свёрнутое второй раз не сворачивается
+    if err := s.SummarizeIfDue(ctx, 42); err != nil || ai.calls != 1 {
+        t.Fatalf("повторная сводка без новых реплик: вызовов %d, ошибка %v", ai.calls, err)
+    }
+}
@@ -0,0 +1,156 @@
+// Package faq отвечает на типовые вопросы (доставка, часы работы) без обращения к модели
+package faq
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+    "unicode"
+)
+
+// Entry — пара «варианты вопроса — ответ» из файла FAQ
+type Entry struct {
+    Questions []string `json:"questions"`
+    Answer    string   `json:"answer"`
+    // Translations — ответ на других языках: {"en": "..."}; без перевода отдаётся Answer
+    Translations map[string]string `json:"translations"`
+}
+
+// Matcher ищет в FAQ вопрос, близкий к тексту покупателя
+type Matcher struct {
+    entries   []entry
+    threshold float64
+}
+
+// entry — запись с заранее нормализованными вопросами
+type entry struct {
+    questions    [][]string
+    answer       string
+    translations map[string]string
+}
+
+// Load читает FAQ из JSON-файла вида [{"questions": [...], "answer": "..."}].
+// threshold — минимальная похожесть (0..1), при которой отвечаем из FAQ.
+func Load(path string, threshold float64) (*Matcher, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения FAQ: %w", err)
+    }
+
+    var entries []Entry
+    if err := json.Unmarshal(data, &entries); err != nil {
+        return nil, fmt.Errorf("ошибка разбора FAQ %s: %w", path, err)
+    }
+    return New(entries, threshold)
+}
+
+// New — матчер по готовому списку записей
+func New(entries []Entry, threshold float64) (*Matcher, error) {
+    m := &Matcher{threshold: threshold}
+    for i, e := range entries {
+        if strings.TrimSpace(e.Answer) == "" || len(e.Questions) == 0 {
+            return nil, fmt.Errorf("запись FAQ %d: нужны вопросы и ответ", i+1)
+        }
+        n := entry{answer: e.Answer, translations: e.Translations}
+        for _, q := range e.Questions {
+            if words := normalize(q); len(words) > 0 {
+                n.questions = append(n.questions, words)
+            }
+        }
+        m.entries = append(m.entries, n)
+    }
+    return m, nil
+}
+
+// Match возвращает ответ на самый похожий вопрос, если похожесть не ниже порога.
+// Ответ берётся на языке lang, если для него есть перевод.
+func (m *Matcher) Match(text, lang string) (answer string, ok bool) {
+    words := normalize(text)
+    if len(words) == 0 {
+        return "", false
+    }
+
+    best := 0.0
+    var found *entry
+    for i, e := range m.entries {
+        for _, q := range e.questions {
+            if s := similarity(words, q); s > best {
+                best, found = s, &m.entries[i]
+            }
+        }
+    }
+    if found == nil || best < m.threshold {
+        return "", false
+    }
+    if t, ok := found.translations[lang]; ok && t != "" {
+        return t, true
+    }
+    return found.answer, true
+}
+
+// normalize переводит текст в список слов: нижний регистр, ё→е, без пунктуации
+func normalize(text string) []string {
+    text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+    return strings.FieldsFunc(text, func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+}
+
+// similarity — коэффициент Дайса по словам: 1, если наборы слов совпадают,
+// и тем меньше, чем больше в тексте лишних или недостающих слов
+func similarity(text, question []string) float64 {
+    matched := 0
+    for _, q := range question {
+        for _, w := range text {
+            if sameWord(w, q) {
+                matched++
+                break
+            }
+        }
+    }
+    return 2 * float64(matched) / float64(len(text)+len(question))
+}
+
+// sameWord — нестрогое сравнение слов: совпадение основы (разные окончания:
+// «доставка» / «доставку») или одна опечатка в длинном слове
+func sameWord(a, b string) bool {
+    if a == b {
+        return true
+    }
+    ra, rb := []rune(a), []rune(b)
+    if len(ra) < 5 || len(rb) < 5 {
+        return false
+    }
+    return commonPrefix(ra, rb) >= max(len(ra), len(rb))-2 || editDistance(ra, rb) <= 1
+}
+
+// commonPrefix — длина общего начала двух слов
+func commonPrefix(a, b []rune) int {
+    n := 0
+    for n < len(a) && n < len(b) && a[n] == b[n] {
+        n++
+    }
+    return n
+}
+
+// editDistance — расстояние Левенштейна
+func editDistance(a, b []rune) int {
+    prev := make([]int, len(b)+1)
+    cur := make([]int, len(b)+1)
+    for j := range prev {
+        prev[j] = j
+    }
+    for i := 1; i <= len(a); i++ {
+        cur[0] = i
+        for j := 1; j <= len(b); j++ {
+            cost := 1
+            if a[i-1] == b[j-1] {
+                cost = 0
+            }
+            cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+        }
+        prev, cur = cur, prev
+    }
+    return prev[len(b)]
+}
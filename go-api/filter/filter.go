@@ -0,0 +1,119 @@
+// Package filter проверяет ответы модели перед отправкой: скрывает личные
+// данные и не пропускает ответы с запрещёнными словами
+package filter
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+)
+
+// defaultReplacement — чем заменяется скрытый фрагмент, если в файле не задано
+const defaultReplacement = "[скрыто]"
+
+// yo — ё и е в запрещённых словах не различаются
+var yo = strings.NewReplacer("ё", "е", "Ё", "Е")
+
+// builtin — готовые шаблоны, на которые файл ссылается по имени
+var builtin = map[string]string{
+    "card":     `\b(?:\d[ -]?){12,18}\d\b`,
+    "passport": `\b\d{2}\s?\d{2}\s?№?\s?\d{6}\b`,
+    "snils":    `\b\d{3}-\d{3}-\d{3}[\s-]\d{2}\b`,
+    // Телефон — международный номер с «+» или российский с 7/8 и кодом из
+    // трёх цифр; даты, суммы с пробелами и номера заказов под него не подходят
+    "phone": `\+\d{1,3}(?:[\s\-]?\(?\d{2,4}\)?){3,4}\b|` +
+        `\b[78][\s\-]?\(?\d{3}\)?[\s\-]?\d{3}[\s\-]?\d{2}[\s\-]?\d{2}\b`,
+    "email": `[\p{L}\p{N}._%+\-]+@[\p{L}\p{N}.\-]+\.\p{L}{2,}`,
+    // Улица и всё после неё до запятой, затем дом, корпус, квартира.
+    // \b в Go понимает только ASCII, поэтому начало слова — отдельным символом.
+    "address": `(?i)(?:^|[^\p{L}])(?:ул\.|улица|проспект|пр-т|переулок|пер\.|шоссе|бульвар|набережная|наб\.)\s*[^,\n]+` +
+        `(?:,\s*(?:д\.|дом|корп\.|стр\.|кв\.|квартира|офис)\s*[\p{L}\p{N}/\-]+)*`,
+}
+
+// builtinOrder — порядок применения готовых шаблонов: номера карт и
+// документов раньше телефона, который иначе съел бы их частично
+var builtinOrder = []string{"card", "passport", "snils", "phone", "email", "address"}
+
+// Builtin — имена всех готовых шаблонов в порядке применения
+func Builtin() []string {
+    return append([]string(nil), builtinOrder...)
+}
+
+// Rules — содержимое файла фильтра
+type Rules struct {
+    // Redact — регулярные выражения или имена готовых шаблонов ("phone",
+    // "email", "card", "passport", "snils", "address"); совпадения
+    // заменяются на Replacement
+    Redact []string `json:"redact"`
+    // Blocked — слова и фразы, с которыми ответ не отправляется вовсе
+    Blocked []string `json:"blocked"`
+    // Replacement — замена скрытых фрагментов (по умолчанию «[скрыто]»)
+    Replacement string `json:"replacement"`
+}
+
+// Filter — скомпилированные правила
+type Filter struct {
+    redact      []*regexp.Regexp
+    blocked     *regexp.Regexp
+    replacement string
+}
+
+// Load читает правила из JSON-файла вида
+// {"redact": ["phone", "email", "..."], "blocked": ["..."], "replacement": "..."}
+func Load(path string) (*Filter, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения фильтра ответов: %w", err)
+    }
+
+    var rules Rules
+    if err := json.Unmarshal(data, &rules); err != nil {
+        return nil, fmt.Errorf("ошибка разбора фильтра ответов %s: %w", path, err)
+    }
+    return New(rules)
+}
+
+// New — фильтр по готовым правилам
+func New(rules Rules) (*Filter, error) {
+    f := &Filter{replacement: rules.Replacement}
+    if f.replacement == "" {
+        f.replacement = defaultReplacement
+    }
+
+    for _, p := range rules.Redact {
+        if named, ok := builtin[p]; ok {
+            p = named
+        }
+        re, err := regexp.Compile(p)
+        if err != nil {
+            return nil, fmt.Errorf("некорректный шаблон фильтра %q: %w", p, err)
+        }
+        f.redact = append(f.redact, re)
+    }
+
+    var words []string
+    for _, w := range rules.Blocked {
+        if w = strings.TrimSpace(w); w != "" {
+            words = append(words, regexp.QuoteMeta(yo.Replace(w)))
+        }
+    }
+    if len(words) > 0 {
+        // \b в Go понимает только ASCII — границы слова для кириллицы задаём сами
+        f.blocked = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(?:` + strings.Join(words, "|") + `)(?:$|[^\p{L}\p{N}])`)
+    }
+    return f, nil
+}
+
+// Apply возвращает текст со скрытыми фрагментами. ok=false — в тексте
+// запрещённое слово и отправлять его нельзя ни в каком виде.
+func (f *Filter) Apply(text string) (filtered string, ok bool) {
+    if f.blocked != nil && f.blocked.MatchString(yo.Replace(text)) {
+        return "", false
+    }
+    for _, re := range f.redact {
+        text = re.ReplaceAllString(text, f.replacement)
+    }
+    return text, true
+}
@@ -0,0 +1,84 @@
+package filter
+
+import "testing"
+
+func TestRedactPhone(t *testing.T) {
+    f, err := New(Rules{Redact: []string{"phone"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, tc := range []struct {
+        name, in, want string
+    }{
+        {"с плюсом слитно", "Звоните: +79991234567", "Звоните: [скрыто]"},
+        {"с плюсом и пробелами", "Номер +7 999 123-45-67, ждём", "Номер [скрыто], ждём"},
+        {"через восьмёрку со скобками", "тел. 8 (495) 123-45-67", "тел. [скрыто]"},
+        {"через восьмёрку слитно", "89991234567 — менеджер", "[скрыто] — менеджер"},
+        {"международный", "Офис: +44 20 7946 0958.", "Офис: [скрыто]."},
+        {"международный со скобками", "+1 (212) 555-0100", "[скрыто]"},
+
+        {"дата и время", "Доставим 2024-10-14 12:00", "Доставим 2024-10-14 12:00"},
+        {"дата через точку", "с 14.10.2024 по 21.10.2024", "с 14.10.2024 по 21.10.2024"},
+        {"сумма с пробелами", "Итого 1 000 000 000 ₽", "Итого 1 000 000 000 ₽"},
+        {"сумма с семёркой", "Итого 7 999 000 ₽", "Итого 7 999 000 ₽"},
+        {"номер заказа", "Заказ №1234567890 оформлен", "Заказ №1234567890 оформлен"},
+        {"номер в артикуле", "артикул AB89991234567", "артикул AB89991234567"},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            got, ok := f.Apply(tc.in)
+            if !ok {
+                t.Fatalf("ответ %q заблокирован", tc.in)
+            }
+            if got != tc.want {
+                t.Fatalf("Apply(%q) = %q, ожидалось %q", tc.in, got, tc.want)
+            }
+        })
+    }
+}
+
+func TestRedactReplacement(t *testing.T) {
+    f, err := New(Rules{Redact: []string{"email", `секрет\d+`}, Replacement: "***"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    got, ok := f.Apply("Пишите на shop@example.ru, код секрет42")
+    if !ok || got != "Пишите на ***, код ***" {
+        t.Fatalf("Apply = %q, %v", got, ok)
+    }
+}
+
+// Ответ с запрещённым словом не отправляется ни в каком виде; ё и е не
+// различаются, а слово внутри другого не считается
+func TestBlocked(t *testing.T) {
+    f, err := New(Rules{Blocked: []string{"казино", "ёлка"}, Redact: []string{"phone"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, tc := range []struct {
+        name string
+        in   string
+        ok   bool
+    }{
+        {"слово", "Приходите в Казино!", false},
+        {"ё как е", "Ель или елка?", false},
+        {"вместе с телефоном", "казино: +79991234567", false},
+        {"часть другого слова", "казиночный — не слово из списка", true},
+        {"без запрещённых слов", "Зелёный чай в наличии", true},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            got, ok := f.Apply(tc.in)
+            if ok != tc.ok {
+                t.Fatalf("Apply(%q) ok = %v, ожидалось %v", tc.in, ok, tc.ok)
+            }
+            if !ok && got != "" {
+                t.Fatalf("заблокированный ответ вернул текст %q", got)
+            }
+        })
+    }
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+    if _, err := New(Rules{Redact: []string{"("}}); err == nil {
+        t.Fatal("некорректный шаблон принят")
+    }
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+    "context"
+
+    "ai_seller/config"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+)
+
+// admitted — обслуживать ли чат апдейта. Заблокированные чаты не получают
+// ничего; чатам вне списка допущенных на обращённое к боту сообщение
+// отвечает ClosedAccessMessage, а в режиме обслуживания всем, кроме
+// админов, — MaintenanceMessage. Нажатия кнопок в отклонённых чатах
+// подтверждаются без ответа, чтобы у кнопки не крутились «часики».
+// Апдейты без чата (неподдерживаемые типы) пропускаются дальше.
+func (b *Bot) admitted(ctx context.Context, update TelegramUpdate) bool {
+    chatID := updateChatID(update)
+    if chatID == 0 {
+        return true
+    }
+
+    switch b.Config.Access(chatID) {
+    case config.AccessBlocked:
+        logging.FromContext(ctx).Debug("апдейт заблокированного чата пропущен", "chat_id", chatID)
+        b.dismissCallback(ctx, update)
+        return false
+    case config.AccessClosed:
+        logging.FromContext(ctx).Info("чат не в списке допущенных", "chat_id", chatID)
+        // На нажатия кнопок отказ не пишем, иначе каждое нажатие дублировало
+        // бы его. В группе отказ — только на обращение к боту: иначе бот
+        // отвечал бы на каждое сообщение чужого разговора.
+        b.dismissCallback(ctx, update)
+        if update.Message != nil && b.addressedToBot(update.Message) {
+            b.reply(ctx, chatID, i18n.T(update.Message.lang(), b.Config.ClosedAccessMessage))
+        }
+        return false
+    }
+
+    // В режиме обслуживания бот не ходит в OpenAI и не обслуживает
+    // покупателей; админы работают как обычно, чтобы проверить бота до снятия
+    // режима. Служебные my_chat_member проходят: блокировка бота или его
+    // удаление из группы во время обслуживания иначе потерялись бы.
+    if update.MyChatMember == nil && !b.Config.IsAdmin(chatID) && b.featureEnabled(ctx, config.FlagMaintenance) {
+        logging.FromContext(ctx).Info("апдейт пропущен: режим обслуживания", "chat_id", chatID)
+        b.dismissCallback(ctx, update)
+        if update.Message != nil && b.addressedToBot(update.Message) {
+            b.reply(ctx, chatID, i18n.T(update.Message.lang(), b.Config.MaintenanceMessage))
+        }
+        return false
+    }
+    return true
+}
+
+// dismissCallback подтверждает нажатие кнопки в апдейте, который дальше не
+// обрабатывается; прочие апдейты не трогает
+func (b *Bot) dismissCallback(ctx context.Context, update TelegramUpdate) {
+    if update.CallbackQuery == nil {
+        return
+    }
+    if err := b.Telegram.AnswerCallbackQuery(update.CallbackQuery.ID); err != nil {
+        logging.FromContext(ctx).Error("ошибка answerCallbackQuery", "callback_id", update.CallbackQuery.ID, "err", err)
+    }
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+    "testing"
+
+    "ai_seller/config"
+)
+
+const (
+    closedReply      = "Извините, бот пока в закрытом доступе."
+    maintenanceReply = "Идут технические работы, скоро вернусь. Пожалуйста, напишите чуть позже."
+)
+
+// groupText — сообщение участника memberID в группе groupID
+func groupText(updateID int64, body string) TelegramUpdate {
+    u := groupCommand(updateID, body)
+    u.Message.Entities = nil
+    return u
+}
+
+// button — нажатие кнопки в чате chatID
+func button(updateID, chatID int64, id string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, CallbackQuery: &TelegramCallbackQuery{
+        ID:      id,
+        From:    &TelegramUser{ID: chatID, LanguageCode: "ru"},
+        Data:    "noop:1",
+        Message: &TelegramMessage{MessageID: 1, Chat: TelegramChat{ID: chatID, Type: "private"}},
+    }}
+}
+
+// «Часики» на кнопке гаснут и в заблокированном, и в закрытом чате, но
+// отказ на нажатие не приходит
+func TestRefusedChatsAnswerCallbacks(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"BLOCKED_CHAT_IDS": "10", "ALLOWED_CHAT_IDS": "30"})
+    tb.process(t, button(1, 10, "blocked"))
+    tb.process(t, button(2, 20, "closed"))
+
+    answered := map[string]bool{}
+    for _, id := range tb.tg.Answered {
+        answered[id] = true
+    }
+    if !answered["blocked"] || !answered["closed"] {
+        t.Fatalf("подтверждены нажатия %q, нужны оба", tb.tg.Answered)
+    }
+    if got := append(tb.sentTo(10), tb.sentTo(20)...); len(got) != 0 {
+        t.Fatalf("на нажатие кнопки пришёл ответ: %q", got)
+    }
+}
+
+// Закрытая группа не получает отказ на каждое сообщение разговора — только
+// на обращение к боту; в личке отказ приходит всегда
+func TestClosedAccessRefusesOnlyWhenAddressed(t *testing.T) {
+    env := map[string]string{"ALLOWED_CHAT_IDS": "30", "TELEGRAM_BOT_USERNAME": "shop_bot"}
+    tb := newTestBot(t, env)
+
+    tb.process(t, groupText(1, "коллеги, кто пойдёт обедать?"))
+    tb.process(t, groupText(2, "я"))
+    if got := tb.sentTo(groupID); len(got) != 0 {
+        t.Fatalf("закрытая группа получила отказ на чужой разговор: %q", got)
+    }
+
+    tb.process(t, groupCommand(3, "/start"))
+    if got := tb.sentTo(groupID); len(got) != 1 || got[0] != closedReply {
+        t.Fatalf("на команду боту в закрытой группе отправлено %q", got)
+    }
+
+    tb.process(t, text(4, 42, "привет"))
+    tb.process(t, text(5, 42, "есть чай?"))
+    if got := tb.sentTo(42); len(got) != 2 || got[0] != closedReply {
+        t.Fatalf("в личке закрытого чата отправлено %q", got)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("закрытый чат дошёл до модели: %d запросов", len(tb.ai.Requests))
+    }
+}
+
+// Заблокированный чат не получает ответа на текст, и до модели сообщение не доходит
+func TestBlockedChatIgnored(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"BLOCKED_CHAT_IDS": "10"})
+    tb.process(t, text(1, 10, "есть чай?"))
+    tb.process(t, text(2, 10, "/start"))
+    if got := tb.sentTo(10); len(got) != 0 {
+        t.Fatalf("заблокированному чату отправлено %q", got)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("заблокированный чат дошёл до модели: %d запросов", len(tb.ai.Requests))
+    }
+}
+
+// Админ обслуживается, даже если он в BLOCKED_CHAT_IDS или вне ALLOWED_CHAT_IDS
+func TestAdminBypassesAccessLists(t *testing.T) {
+    for _, tc := range []struct {
+        name string
+        env  map[string]string
+    }{
+        {"в списке заблокированных", map[string]string{"ADMIN_CHAT_IDS": "1", "BLOCKED_CHAT_IDS": "1"}},
+        {"вне списка допущенных", map[string]string{"ADMIN_CHAT_IDS": "1", "ALLOWED_CHAT_IDS": "30"}},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, tc.env)
+            tb.process(t, text(1, 1, "есть чай?"))
+            if got := tb.sentTo(1); len(got) != 1 || got[0] != "ответ модели" {
+                t.Fatalf("админу отправлено %q", got)
+            }
+            if len(tb.ai.Requests) != 1 {
+                t.Fatalf("запросов к модели %d, ожидался один", len(tb.ai.Requests))
+            }
+        })
+    }
+}
+
+// myChatMember — бот в чате chatID перешёл из статуса from в to
+func myChatMember(updateID, chatID int64, from, to string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, MyChatMember: &TelegramChatMemberUpdated{
+        Chat:          TelegramChat{ID: chatID, Type: "private"},
+        From:          TelegramUser{ID: chatID},
+        OldChatMember: TelegramChatMember{Status: from},
+        NewChatMember: TelegramChatMember{Status: to},
+    }}
+}
+
+// В режиме обслуживания покупатели получают MAINTENANCE_MESSAGE, нажатия
+// кнопок подтверждаются, а блокировка и разблокировка бота не теряются
+func TestMaintenanceKeepsServiceUpdates(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"MAINTENANCE": "true", "TELEGRAM_BOT_USERNAME": "shop_bot"})
+    ctx := t.Context()
+    for _, id := range []int64{42, 43} {
+        if err := tb.messages.SaveMessage(ctx, id, "user", "привет"); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tb.process(t, text(1, 42, "есть чай?"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != maintenanceReply {
+        t.Fatalf("в режиме обслуживания отправлено %q", got)
+    }
+    tb.process(t, groupText(2, "обсуждаем своё"))
+    if got := tb.sentTo(groupID); len(got) != 0 {
+        t.Fatalf("группа получила MAINTENANCE_MESSAGE без обращения к боту: %q", got)
+    }
+    tb.process(t, button(3, 42, "cb-maintenance"))
+    if len(tb.tg.Answered) != 1 || tb.tg.Answered[0] != "cb-maintenance" {
+        t.Fatalf("подтверждены нажатия %q", tb.tg.Answered)
+    }
+    if len(tb.sentTo(42)) != 1 {
+        t.Fatalf("на нажатие кнопки пришёл ответ: %q", tb.sentTo(42))
+    }
+
+    tb.process(t, myChatMember(4, 42, "member", "kicked"))
+    tb.process(t, myChatMember(5, 43, "member", "kicked"))
+    tb.process(t, myChatMember(6, 43, "kicked", "member"))
+    active, err := tb.chats.ActiveChatIDs(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(active) != 1 || active[0] != 43 {
+        t.Fatalf("активные чаты %v: блокировка в режиме обслуживания потеряна", active)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("в режиме обслуживания %d запросов к модели", len(tb.ai.Requests))
+    }
+}
+
+// /maintenance on и off переключают режим без перезапуска, через Redis;
+// админ из ADMIN_CHAT_IDS и в режиме обслуживания получает ответы модели
+func TestMaintenanceToggledAtRuntime(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+
+    tb.process(t, text(1, 1, "/maintenance on"))
+    if got := tb.lastSent(t, 1); got != "Режим обслуживания: вкл." {
+        t.Fatalf("ответ на /maintenance on %q", got)
+    }
+    if on, ok, _ := tb.Flags.Override(t.Context(), config.FlagMaintenance); !ok || !on {
+        t.Fatal("режим обслуживания не записан в Redis")
+    }
+
+    tb.process(t, text(2, 42, "есть чай?"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != maintenanceReply {
+        t.Fatalf("покупателю в режиме обслуживания отправлено %q", got)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("в режиме обслуживания %d запросов к модели", len(tb.ai.Requests))
+    }
+
+    tb.process(t, text(3, 1, "есть чай?"))
+    if got := tb.lastSent(t, 1); got != "ответ модели" {
+        t.Fatalf("админу в режиме обслуживания отправлено %q", got)
+    }
+    if len(tb.ai.Requests) != 1 {
+        t.Fatalf("запросов к модели %d, ожидался один — от админа", len(tb.ai.Requests))
+    }
+
+    tb.process(t, text(4, 1, "/maintenance off"))
+    if got := tb.lastSent(t, 1); got != "Режим обслуживания: выкл." {
+        t.Fatalf("ответ на /maintenance off %q", got)
+    }
+    tb.process(t, text(5, 42, "есть чай?"))
+    if got := tb.sentTo(42); len(got) != 2 || got[1] != "ответ модели" {
+        t.Fatalf("после снятия режима покупателю отправлено %q", got)
+    }
+}
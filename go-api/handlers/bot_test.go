@@ -0,0 +1,235 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/cache"
+    "ai_seller/config"
+    "ai_seller/dialog"
+    "ai_seller/handlers/mocks"
+    "ai_seller/memstore"
+    "ai_seller/redistest"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// testBot — бот на фейках: Telegram и модель из mocks, хранилища из
+// memstore, кэши — на redistest
+type testBot struct {
+    *Bot
+    tg    *mocks.Telegram
+    ai    *mocks.OpenAI
+    redis *redistest.Server
+    rdb   *redis.Client
+
+    messages *memstore.Messages
+    users    *memstore.Users
+    carts    *memstore.Carts
+    orders   *memstore.Orders
+    chats    *memstore.Chats
+    catalog  *memstore.Catalog
+    privacy  *memstore.Privacy
+}
+
+// testConfig — конфигурация из минимального окружения и overrides
+func testConfig(t *testing.T, overrides map[string]string) *config.Config {
+    t.Helper()
+    env := map[string]string{
+        "POSTGRES_DSN":        "postgres://localhost/test",
+        "REDIS_ADDR":          "localhost:6379",
+        "TELEGRAM_TOKEN":      "123456:ABCdefGhIJKlmnOPQRstuVWXyz0123456789",
+        "OPENAI_KEY":          "sk-test",
+        "WEBHOOK_URL":         "https://example.com/webhook",
+        "TELEGRAM_PARSE_MODE": "",
+    }
+    for k, v := range overrides {
+        env[k] = v
+    }
+    cfg, err := config.NewConfig(config.WithEnv(env))
+    if err != nil {
+        t.Fatalf("конфигурация: %v", err)
+    }
+    return cfg
+}
+
+// newTestBot — бот с переменными окружения env; setup правит зависимости до NewBot
+func newTestBot(t *testing.T, env map[string]string, setup ...func(*Deps)) *testBot {
+    t.Helper()
+    cfg := testConfig(t, env)
+    srv, rdb := redistest.NewClient(t)
+    // Lua redistest не исполняет: снятие блокировки чата — тем же сравнением на Go
+    srv.HandleScript(cache.UnlockScriptHash(), redistest.DelIfEquals)
+    tb := &testBot{
+        tg:       &mocks.Telegram{},
+        ai:       &mocks.OpenAI{Reply: "ответ модели"},
+        redis:    srv,
+        rdb:      rdb,
+        messages: &memstore.Messages{},
+        users:    &memstore.Users{},
+        carts:    &memstore.Carts{},
+        orders:   &memstore.Orders{},
+        catalog:  &memstore.Catalog{},
+        privacy:  &memstore.Privacy{},
+    }
+    tb.chats = &memstore.Chats{Messages: tb.messages}
+    sessions := cache.NewSessionCache(rdb, cfg.SessionMaxTurns, cfg.SessionTTL)
+    deps := Deps{
+        Config:        cfg,
+        Telegram:      tb.tg,
+        OpenAI:        tb.ai,
+        Messages:      tb.messages,
+        Sessions:      sessions,
+        Limiter:       cache.NewRateLimiter(rdb, cfg.RateLimitPerMinute),
+        Catalog:       tb.catalog,
+        Carts:         tb.carts,
+        Usage:         cache.NewUsageCounter(rdb),
+        Orders:        tb.orders,
+        Chats:         tb.chats,
+        Updates:       cache.NewUpdateDeduper(rdb),
+        Dialog:        dialog.NewContextBuilder(sessions, tb.messages, tb.users, nil, cfg.SystemPrompt, cfg.ContextTokenBudget),
+        Users:         tb.users,
+        Feedback:      &memstore.Feedback{},
+        Privacy:       tb.privacy,
+        Flags:         cache.NewFlagOverrides(rdb),
+        Digests:       cache.NewDigestMarks(rdb),
+        OffHoursNotes: cache.NewOffHoursNotes(rdb),
+        DraftOffers:   cache.NewDraftOffers(rdb),
+    }
+    // Как в main: кэш ответов и передача оператору — только под своими флагами
+    if cfg.Features.IsEnabled(config.FlagResponseCache) {
+        deps.Responses = cache.NewResponseCache(rdb, cfg.ResponseCacheTTL)
+    }
+    if cfg.Features.IsEnabled(config.FlagHandoff) {
+        deps.Handoffs = cache.NewHandoffs(rdb, cfg.HandoffTTL)
+    }
+    for _, f := range setup {
+        f(&deps)
+    }
+    tb.Bot = NewBot(deps)
+    return tb
+}
+
+// text — апдейт с текстом от покупателя из личного чата chatID
+func text(updateID, chatID int64, body string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, Message: &TelegramMessage{
+        MessageID: updateID,
+        From:      &TelegramUser{ID: chatID, FirstName: "Покупатель", LanguageCode: "ru"},
+        Chat:      TelegramChat{ID: chatID, Type: "private"},
+        Text:      body,
+    }}
+}
+
+// process прогоняет апдейт; ошибка обработки валит тест
+func (tb *testBot) process(t *testing.T, update TelegramUpdate) {
+    t.Helper()
+    if err := tb.ProcessUpdate(context.Background(), update); err != nil {
+        t.Fatalf("ProcessUpdate: %v", err)
+    }
+}
+
+// sentTo — тексты, отправленные в чат, по порядку
+func (tb *testBot) sentTo(chatID int64) []string {
+    var out []string
+    for _, m := range tb.tg.Messages() {
+        if m.ChatID == chatID {
+            out = append(out, m.Text)
+        }
+    }
+    return out
+}
+
+func TestProcessUpdateAnswersWithModel(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "есть зелёный чай?"))
+
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("отправлено %q, ожидался ответ модели", got)
+    }
+    history := tb.messages.History(42)
+    if len(history) != 2 || history[0].Content != "есть зелёный чай?" || history[1].Role != "assistant" {
+        t.Fatalf("история: %+v", history)
+    }
+    if len(tb.ai.Requests) != 1 {
+        t.Fatalf("запросов к модели: получено %d, ожидался 1", len(tb.ai.Requests))
+    }
+    last := tb.ai.Requests[0][len(tb.ai.Requests[0])-1]
+    if last.Role != "user" || !strings.Contains(last.Content, "зелёный чай") {
+        t.Fatalf("последнее сообщение модели: %+v", last)
+    }
+}
+
+func TestProcessUpdateDropsRedelivery(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(7, 42, "привет"))
+    tb.process(t, text(7, 42, "привет"))
+
+    if got := tb.sentTo(42); len(got) != 1 {
+        t.Fatalf("на повторную доставку ответили: %q", got)
+    }
+}
+
+func TestProcessUpdateFallbackOnModelError(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.ai.Err = context.DeadlineExceeded
+    tb.process(t, text(1, 42, "привет"))
+
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != tb.Config.FallbackMessage {
+        t.Fatalf("отправлено %q, ожидался FALLBACK_MESSAGE", got)
+    }
+}
+
+func TestProcessUpdateSkipsInvalid(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, TelegramUpdate{UpdateID: 1})
+    if sent := tb.tg.Messages(); len(sent) != 0 {
+        t.Fatalf("на пустой апдейт отправлено %v", sent)
+    }
+}
+
+// Сообщение, которое одно не влезает в OPENAI_CONTEXT_BUDGET, уходит модели
+// обрезанным, а покупатель получает предупреждение
+func TestProcessUpdateWarnsOnTruncatedMessage(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"OPENAI_CONTEXT_BUDGET": "500"})
+    huge := strings.Repeat("расскажите про чай ", 500)
+    tb.process(t, text(1, 42, huge))
+
+    if got := tb.sentTo(42); len(got) != 2 || got[0] != truncatedMessageReply || got[1] != "ответ модели" {
+        t.Fatalf("отправлено %q, нужны предупреждение и ответ", got)
+    }
+    last := tb.ai.Requests[0][len(tb.ai.Requests[0])-1]
+    if last.Content == huge || !strings.HasPrefix(huge, last.Content) {
+        t.Fatalf("модели ушло %d символов из %d", len([]rune(last.Content)), len([]rune(huge)))
+    }
+}
+
+// Команды разбирает ProcessUpdate: известные идут в свой обработчик без
+// запроса к модели, незнакомые — модели как обычный текст
+func TestProcessUpdateRoutesCommands(t *testing.T) {
+    cases := []struct {
+        name  string
+        body  string
+        want  string
+        model bool
+    }{
+        {"команда", "/help", "Доступные команды:", false},
+        {"регистр и имя бота", "/HELP@shop_bot", "Доступные команды:", false},
+        {"админская от покупателя", "/stats", noAccessReply, false},
+        {"незнакомая команда", "/skidki", "ответ модели", true},
+        {"текст", "help", "ответ модели", true},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+            tb.process(t, text(1, 42, tc.body))
+
+            if got := tb.sentTo(42); len(got) != 1 || !strings.HasPrefix(got[0], tc.want) {
+                t.Fatalf("отправлено %q, нужно начало %q", got, tc.want)
+            }
+            if asked := len(tb.ai.Requests) > 0; asked != tc.model {
+                t.Fatalf("запросов к модели %d", len(tb.ai.Requests))
+            }
+        })
+    }
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/openai"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+const (
+    // briefOnReply — ответ на /brief
+    briefOnReply = "Буду отвечать кратко. Вернуть подробные ответы — /detailed."
+    // briefOffReply — ответ на /detailed
+    briefOffReply = "Буду отвечать подробно."
+)
+
+// cmdBrief — команда /brief: отвечать кратко
+func (b *Bot) cmdBrief(ctx context.Context, msg *TelegramMessage, args string) error {
+    return b.setBrief(ctx, msg.Chat.ID, true, briefOnReply)
+}
+
+// cmdDetailed — команда /detailed: вернуть обычную длину ответов
+func (b *Bot) cmdDetailed(ctx context.Context, msg *TelegramMessage, args string) error {
+    return b.setBrief(ctx, msg.Chat.ID, false, briefOffReply)
+}
+
+// setBrief сохраняет режим ответов в профиле, чтобы он пережил перезапуск
+func (b *Bot) setBrief(ctx context.Context, chatID int64, brief bool, phrase string) error {
+    if err := b.Users.SetBrief(ctx, chatID, brief); err != nil {
+        return apperr.WithMessage(err, "Не удалось сохранить настройку, попробуйте ещё раз.")
+    }
+    updateProfile(ctx, chatID, func(u *storage.User) { u.Brief = brief })
+    b.replyPhrase(ctx, chatID, phrase)
+    return nil
+}
+
+// withAnswerLength переносит в контекст режим ответов чата: краткий режим
+// добавляет указание в системный промпт и снижает max_tokens до BRIEF_MAX_TOKENS.
+// Если профиль недоступен, отвечаем как обычно.
+func (b *Bot) withAnswerLength(ctx context.Context, chatID int64) context.Context {
+    u, err := b.profile(ctx, chatID)
+    if err != nil {
+        if !errors.Is(err, storage.ErrNotFound) {
+            logging.FromContext(ctx).Warn("ошибка чтения режима ответов", "chat_id", chatID, "err", err)
+        }
+        return ctx
+    }
+    if !u.Brief {
+        return ctx
+    }
+    ctx = reqctx.WithBrief(ctx, true)
+    return openai.WithMaxTokens(ctx, b.Config.BriefMaxTokens)
+}
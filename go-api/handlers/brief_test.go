@@ -0,0 +1,104 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "ai_seller/memstore"
+    "ai_seller/openai"
+)
+
+// sentRequest — системный промпт и max_tokens запроса к модели
+type sentRequest struct {
+    system    string
+    maxTokens int
+}
+
+// recordingModel — API модели, запоминающий системный промпт и max_tokens
+func recordingModel(t *testing.T) (*httptest.Server, func() []sentRequest) {
+    t.Helper()
+    var (
+        mu   sync.Mutex
+        seen []sentRequest
+    )
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var req struct {
+            Messages  []openai.Message `json:"messages"`
+            MaxTokens int              `json:"max_tokens"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            t.Errorf("тело запроса: %v", err)
+        }
+        mu.Lock()
+        seen = append(seen, sentRequest{system: req.Messages[0].Content, maxTokens: req.MaxTokens})
+        mu.Unlock()
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Есть улун."}}]}`)
+    }))
+    t.Cleanup(srv.Close)
+    return srv, func() []sentRequest {
+        mu.Lock()
+        defer mu.Unlock()
+        return append([]sentRequest(nil), seen...)
+    }
+}
+
+// Краткий режим добавляет указание в системный промпт и снижает max_tokens;
+// он хранится в профиле и переживает перезапуск, /detailed его снимает
+func TestBriefMode(t *testing.T) {
+    srv, seen := recordingModel(t)
+    users := &memstore.Users{}
+    bot := func(maxTokens int) *testBot {
+        tb := newTestBot(t, map[string]string{"BRIEF_MAX_TOKENS": "150"}, func(d *Deps) { d.Users = users })
+        tb.OpenAI = openai.NewClient("sk-test", openai.Options{BaseURL: srv.URL, MaxTokens: maxTokens})
+        return tb
+    }
+
+    tb := bot(0)
+    tb.process(t, text(1, 42, "есть улун?"))
+    tb.process(t, text(2, 42, "/brief"))
+    tb.process(t, text(3, 42, "а пуэр?"))
+    if got := tb.sentTo(42); len(got) != 3 || got[1] != briefOnReply {
+        t.Fatalf("отправлено %q", got)
+    }
+
+    // Новый экземпляр бота читает режим из того же хранилища профилей
+    restarted := bot(0)
+    restarted.process(t, text(4, 42, "а габа?"))
+    restarted.process(t, text(5, 42, "/detailed"))
+    restarted.process(t, text(6, 42, "а шен?"))
+
+    // Предел клиента строже краткого режима — он и действует
+    strict := bot(100)
+    strict.process(t, text(7, 42, "/brief"))
+    strict.process(t, text(8, 42, "а матча?"))
+
+    cases := []struct {
+        name      string
+        brief     bool
+        maxTokens int
+    }{
+        {"обычный режим", false, 0},
+        {"после /brief", true, 150},
+        {"после перезапуска", true, 150},
+        {"после /detailed", false, 0},
+        {"предел клиента строже", true, 100},
+    }
+    got := seen()
+    if len(got) != len(cases) {
+        t.Fatalf("запросов к модели %d, нужно %d", len(got), len(cases))
+    }
+    for i, tc := range cases {
+        if brief := strings.Contains(got[i].system, "отвечать кратко"); brief != tc.brief {
+            t.Errorf("%s: указание о краткости в промпте: %v, нужно %v", tc.name, brief, tc.brief)
+        }
+        if got[i].maxTokens != tc.maxTokens {
+            t.Errorf("%s: max_tokens %d, нужно %d", tc.name, got[i].maxTokens, tc.maxTokens)
+        }
+    }
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/telegram"
+)
+
+// broadcastInterval — пауза между сообщениями рассылки: ~25 в секунду,
+// ниже общего лимита Telegram в 30 сообщений в секунду
+const broadcastInterval = 40 * time.Millisecond
+
+// cmdBroadcast — команда /broadcast <текст>: рассылка всем активным чатам.
+// Рассылка идёт в фоне, чтобы не держать вебхук; по окончании админ получает отчёт.
+func (b *Bot) cmdBroadcast(ctx context.Context, msg *TelegramMessage, args string) error {
+    if args == "" {
+        return apperr.Validation("Использование: /broadcast <текст>")
+    }
+
+    chatIDs, err := b.Chats.ActiveChatIDs(ctx)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось получить список чатов.")
+    }
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Рассылка запущена: %d чатов.", len(chatIDs)))
+
+    go func() {
+        ctx, cancel := b.detach(ctx)
+        defer cancel()
+        b.broadcast(ctx, msg.Chat.ID, chatIDs, args)
+    }()
+    return nil
+}
+
+// broadcast отправляет текст каждому чату с ограничением скорости. Повторы
+// после 429 и сбоев и пометку чатов, где бот заблокирован, берёт на себя
+// send. Отмена ctx (остановка сервиса) прерывает рассылку, в том числе
+// ожидание retry_after.
+func (b *Bot) broadcast(ctx context.Context, adminChatID int64, chatIDs []int64, text string) {
+    ticker := time.NewTicker(broadcastInterval)
+    defer ticker.Stop()
+
+    sendCtx := withoutThreading(ctx)
+    var sent, failed, blocked int
+    for i, chatID := range chatIDs {
+        if i > 0 {
+            select {
+            case <-ctx.Done():
+            case <-ticker.C:
+            }
+        }
+        if ctx.Err() != nil {
+            logging.FromContext(ctx).Warn("рассылка прервана", "sent", sent, "failed", failed, "blocked", blocked, "left", len(chatIDs)-i)
+            return
+        }
+
+        _, err := b.send(sendCtx, chatID, text)
+        switch {
+        case err == nil:
+            sent++
+        case telegram.IsForbidden(err):
+            blocked++
+        default:
+            failed++
+        }
+    }
+
+    logging.FromContext(ctx).Info("рассылка завершена", "sent", sent, "failed", failed, "blocked", blocked)
+    b.reply(ctx, adminChatID, fmt.Sprintf("Рассылка завершена: отправлено %d, ошибок %d, заблокировали бота %d.", sent, failed, blocked))
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+    "context"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/telegram"
+)
+
+// chatFailures — Telegram, у которого отправки в чат по очереди
+// возвращают errs[chat], а когда ошибки кончились — проходят
+type chatFailures struct {
+    *mocks.Telegram
+    mu    sync.Mutex
+    errs  map[int64][]error
+    calls map[int64]int
+}
+
+func (c *chatFailures) SendMessage(chatID int64, text string, opts ...telegram.SendOption) (int64, error) {
+    c.mu.Lock()
+    c.calls[chatID]++
+    var err error
+    if errs := c.errs[chatID]; len(errs) > 0 {
+        err, c.errs[chatID] = errs[0], errs[1:]
+    }
+    c.mu.Unlock()
+    if err != nil {
+        return 0, err
+    }
+    return c.Telegram.SendMessage(chatID, text, opts...)
+}
+
+func (c *chatFailures) Calls(chatID int64) int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.calls[chatID]
+}
+
+// broadcastBot — бот, отправки которого падают по сценарию errs
+func broadcastBot(t *testing.T, env map[string]string, errs map[int64][]error) (*testBot, *chatFailures) {
+    t.Helper()
+    tb := newTestBot(t, env)
+    tg := &chatFailures{Telegram: tb.tg, errs: errs, calls: map[int64]int{}}
+    tb.Telegram = tg
+    return tb, tg
+}
+
+func TestBroadcastReport(t *testing.T) {
+    tb, tg := broadcastBot(t, nil, map[int64][]error{
+        2: {apiStatus(403)},
+        3: {tooMany(10 * time.Millisecond)},
+        4: {apiStatus(400)},
+    })
+    tb.broadcast(context.Background(), 99, []int64{1, 2, 3, 4}, "акция")
+
+    for _, chatID := range []int64{1, 3} {
+        if got := tb.sentTo(chatID); len(got) != 1 || got[0] != "акция" {
+            t.Fatalf("чату %d отправлено %q", chatID, got)
+        }
+    }
+    // 429 повторяет только send, клиент и рассылка сверху не добавляют попыток
+    if n := tg.Calls(3); n != 2 {
+        t.Fatalf("попыток отправки после 429: %d, ожидалось 2", n)
+    }
+    if !tb.chats.Inactive(2) || tb.chats.Inactive(4) {
+        t.Fatal("неактивным должен стать только чат, заблокировавший бота")
+    }
+    want := "Рассылка завершена: отправлено 2, ошибок 1, заблокировали бота 1."
+    if got := tb.sentTo(99); len(got) != 1 || got[0] != want {
+        t.Fatalf("отчёт %q, ожидался %q", got, want)
+    }
+}
+
+func TestBroadcastStopsOnShutdown(t *testing.T) {
+    tb, _ := broadcastBot(t, map[string]string{"TELEGRAM_SEND_MAX_WAIT": "10m"}, map[int64][]error{
+        1: {tooMany(time.Minute)},
+    })
+    ctx, cancel := tb.detach(context.Background())
+    defer cancel()
+    done := make(chan struct{})
+    go func() {
+        tb.broadcast(ctx, 99, []int64{1, 2}, "акция")
+        close(done)
+    }()
+
+    time.Sleep(20 * time.Millisecond)
+    tb.StopWorkers(time.Second)
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("рассылка ждёт retry_after после остановки")
+    }
+    if got := tb.sentTo(2); len(got) != 0 {
+        t.Fatalf("после остановки рассылка продолжилась: %q", got)
+    }
+}
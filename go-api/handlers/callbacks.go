@@ -0,0 +1,60 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+)
+
+// TelegramCallbackQuery — нажатие на кнопку inline-клавиатуры
+type TelegramCallbackQuery struct {
+    ID      string           `json:"id"`
+    From    *TelegramUser    `json:"from"`
+    Data    string           `json:"data"`
+    Message *TelegramMessage `json:"message"`
+}
+
+// CallbackFunc — обработчик нажатия кнопки; payload — данные после "action:"
+type CallbackFunc func(ctx context.Context, cq *TelegramCallbackQuery, payload string) error
+
+// RegisterCallback регистрирует обработчик для callback_data вида "<action>:<payload>"
+func (b *Bot) RegisterCallback(action string, fn CallbackFunc) {
+    b.callbacks[action] = fn
+}
+
+// handleCallback маршрутизирует нажатие кнопки по action из callback_data
+func (b *Bot) handleCallback(ctx context.Context, cq *TelegramCallbackQuery) error {
+    // Убираем "часики" на кнопке в любом случае, даже если действие неизвестно
+    defer func() {
+        if err := b.Telegram.AnswerCallbackQuery(cq.ID); err != nil {
+            logging.FromContext(ctx).Error("ошибка answerCallbackQuery", "callback_id", cq.ID, "err", err)
+        }
+    }()
+
+    if cq.Message == nil || cq.Message.Chat.ID == 0 {
+        return nil
+    }
+
+    ctx = reqctx.WithChatID(ctx, cq.Message.Chat.ID)
+    if cq.From != nil {
+        ctx = reqctx.WithLang(ctx, i18n.Resolve(cq.From.LanguageCode))
+    }
+    ctx = b.withProfile(ctx, cq.Message.Chat.ID)
+    ctx = b.withPreferredLang(ctx, langOwner(cq.Message.Chat, cq.From))
+
+    action, payload, _ := strings.Cut(cq.Data, ":")
+    fn, ok := b.callbacks[action]
+    if !ok {
+        logging.FromContext(ctx).Warn("неизвестное действие кнопки", "chat_id", cq.Message.Chat.ID, "data", cq.Data)
+        return nil
+    }
+
+    if err := fn(ctx, cq, payload); err != nil {
+        b.replyError(ctx, cq.Message.Chat.ID, fmt.Errorf("ошибка обработки кнопки %q: %w", cq.Data, err))
+    }
+    return nil
+}
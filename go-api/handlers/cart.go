@@ -0,0 +1,225 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "slices"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/money"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// checkoutWindow — повторный /checkout той же корзины в пределах окна
+// возвращает уже оформленный заказ вместо нового
+const checkoutWindow = 10 * time.Minute
+
+// addToCartAction — action кнопки "В корзину", callback_data "add:<productID>"
+const addToCartAction = "add"
+
+const (
+    addedToCartReply = "Товар добавлен в корзину. Оформить заказ — /checkout"
+    outOfStockReply  = "Этого товара сейчас нет в наличии."
+)
+
+// cartLine — позиция корзины вместе с данными товара
+type cartLine struct {
+    Product storage.Product
+    Qty     int
+}
+
+// Sum — стоимость позиции
+func (l cartLine) Sum() money.Money {
+    return l.Product.Price.MulQty(l.Qty)
+}
+
+// mixedCurrencyReply — ответ, если в корзине товары в разных валютах
+const mixedCurrencyReply = "В корзине товары в разных валютах — оформите их отдельными заказами."
+
+// cartTotal — итог корзины; товары в разных валютах сложить нельзя
+func cartTotal(lines []cartLine) (money.Money, error) {
+    var total money.Money
+    for _, l := range lines {
+        var err error
+        if total, err = total.Add(l.Sum()); err != nil {
+            return money.Money{}, apperr.WithMessage(err, mixedCurrencyReply)
+        }
+    }
+    return total, nil
+}
+
+// cartLines загружает товары для позиций корзины чата.
+// Товары, удалённые из каталога, пропускаются.
+func (b *Bot) cartLines(ctx context.Context, chatID int64) ([]cartLine, error) {
+    cart, err := b.Carts.GetCart(ctx, chatID)
+    if err != nil {
+        return nil, err
+    }
+
+    lines := make([]cartLine, 0, len(cart.Items))
+    for _, item := range cart.Items {
+        p, err := b.Catalog.GetProduct(ctx, item.ProductID)
+        if errors.Is(err, storage.ErrNotFound) {
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        lines = append(lines, cartLine{Product: p, Qty: item.Qty})
+    }
+    return lines, nil
+}
+
+// renderCart — текстовое описание корзины для пользователя; суммы — в его валюте
+func renderCart(lines []cartLine, total money.Money, prices *priceFormat) string {
+    if len(lines) == 0 {
+        return "Корзина пуста."
+    }
+
+    var sb strings.Builder
+    sb.WriteString("🛒 Ваша корзина:\n")
+    for i, l := range lines {
+        fmt.Fprintf(&sb, "%d. %s × %d — %s\n", i+1, l.Product.Name, l.Qty, prices.Format(l.Sum()))
+    }
+    fmt.Fprintf(&sb, "\nИтого: %s", prices.Format(total))
+    sb.WriteString(prices.Note())
+    return sb.String()
+}
+
+// cmdCart — команда /cart: показать содержимое корзины
+func (b *Bot) cmdCart(ctx context.Context, msg *TelegramMessage, args string) error {
+    lines, err := b.cartLines(ctx, msg.Chat.ID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить корзину, попробуйте позже.")
+    }
+    total, err := cartTotal(lines)
+    if err != nil {
+        return err
+    }
+    b.reply(ctx, msg.Chat.ID, renderCart(lines, total, b.prices(ctx)))
+    return nil
+}
+
+// cmdCheckout — команда /checkout: оформить заказ из корзины и очистить её
+func (b *Bot) cmdCheckout(ctx context.Context, msg *TelegramMessage, args string) error {
+    chatID := msg.Chat.ID
+
+    lines, err := b.cartLines(ctx, chatID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить корзину, попробуйте позже.")
+    }
+    if len(lines) == 0 {
+        b.reply(ctx, chatID, "Корзина пуста — добавьте товары, и я оформлю заказ.")
+        return nil
+    }
+
+    total, err := cartTotal(lines)
+    if err != nil {
+        return err
+    }
+    items := make([]storage.OrderItem, 0, len(lines))
+    for _, l := range lines {
+        items = append(items, storage.OrderItem{ProductID: l.Product.ID, Qty: l.Qty, Price: l.Product.Price})
+    }
+
+    key := storage.IdempotencyKey(chatID, items)
+    orderID, err := b.Orders.CreateOrder(ctx, chatID, items, total, key, checkoutWindow)
+    var outOfStock *storage.OutOfStockError
+    if errors.As(err, &outOfStock) {
+        b.reply(ctx, chatID, soldOutReply(lines, outOfStock.ProductIDs))
+        return nil
+    }
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось оформить заказ, попробуйте ещё раз.")
+    }
+
+    // Заказ уже сохранён — ошибка очистки корзины не должна его отменять
+    if err := b.Carts.ClearCart(ctx, chatID); err != nil {
+        logging.FromContext(ctx).Error("ошибка очистки корзины после заказа", "chat_id", chatID, "order_id", orderID, "err", err)
+    }
+    // До подтверждения заказ — черновик: если процесс упадёт здесь, покупателю
+    // предложат вернуть товары в корзину (offerDraft)
+    if err := b.Orders.ConfirmOrder(ctx, orderID); err != nil {
+        return apperr.WithMessage(err, "Не удалось оформить заказ, попробуйте ещё раз.")
+    }
+
+    // Заказ оформлен — удачный момент свернуть переписку о выборе товара
+    b.summarizeLater(ctx, chatID, true)
+    b.reply(ctx, chatID, fmt.Sprintf("✅ Заказ №%d оформлен на сумму %s. Мы свяжемся с вами для подтверждения.", orderID, total.Format(reqctx.LangFromContext(ctx))))
+    return nil
+}
+
+// soldOutReply — какие товары из корзины закончились, пока покупатель
+// оформлял заказ
+func soldOutReply(lines []cartLine, productIDs []int64) string {
+    var sb strings.Builder
+    sb.WriteString("Не удалось оформить заказ: этих товаров нет в нужном количестве:")
+    for _, l := range lines {
+        if slices.Contains(productIDs, l.Product.ID) {
+            fmt.Fprintf(&sb, "\n• %s", l.Product.Name)
+        }
+    }
+    sb.WriteString("\n\nУберите их из корзины или уменьшите количество и оформите заказ ещё раз.")
+    return sb.String()
+}
+
+// showProduct отправляет фото товара с названием и ценой. Если фото нет
+// или Telegram не смог его скачать, отправляет ту же подпись текстом.
+func (b *Bot) showProduct(ctx context.Context, chatID int64, p storage.Product) {
+    prices := b.prices(ctx)
+    caption := fmt.Sprintf("%s — %s", p.Name, prices.Format(p.Price)) + prices.Note()
+    markup := telegram.WithReplyMarkup(productKeyboard(p))
+    if p.ImageURL != "" {
+        err := b.Telegram.SendPhoto(chatID, p.ImageURL, caption, markup)
+        if err == nil {
+            return
+        }
+        logging.FromContext(ctx).Warn("не удалось отправить фото товара", "chat_id", chatID, "product_id", p.ID, "err", err)
+    }
+    // Подпись — простой текст: в названии могут быть символы разметки
+    b.send(ctx, chatID, caption, markup, telegram.WithParseMode(""))
+}
+
+// cbAddToCart — кнопка "В корзину" под карточкой товара
+func (b *Bot) cbAddToCart(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    chatID := cq.Message.Chat.ID
+    productID, err := strconv.ParseInt(payload, 10, 64)
+    if err != nil {
+        return fmt.Errorf("некорректный id товара %q: %w", payload, err)
+    }
+
+    p, err := b.Catalog.GetProduct(ctx, productID)
+    if errors.Is(err, storage.ErrNotFound) {
+        return apperr.NotFound(outOfStockReply)
+    }
+    if err != nil {
+        return err
+    }
+    if !p.InStock {
+        b.replyPhrase(ctx, chatID, outOfStockReply)
+        return nil
+    }
+
+    if err := b.Carts.AddItem(ctx, chatID, productID, 1); err != nil {
+        return err
+    }
+    b.noteShopping(ctx)
+    b.replyPhrase(ctx, chatID, addedToCartReply)
+    return nil
+}
+
+// productKeyboard — кнопки под карточкой товара; nil, если кнопок нет
+func productKeyboard(p storage.Product) *telegram.InlineKeyboard {
+    if !p.InStock {
+        return nil
+    }
+    return telegram.NewInlineKeyboard().
+        Row(telegram.CallbackButton("🛒 В корзину", fmt.Sprintf("%s:%d", addToCartAction, p.ID)))
+}
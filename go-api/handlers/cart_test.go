@@ -0,0 +1,102 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "testing"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+func TestCartTotal(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.catalog.Add(
+        storage.Product{ID: 1, Name: "Сенча", Price: money.New(149900, "RUB"), InStock: true},
+        storage.Product{ID: 2, Name: "Кружка", Price: money.New(50050, "RUB"), InStock: true},
+    )
+    ctx := context.Background()
+    _ = tb.carts.AddItem(ctx, 42, 1, 2)
+    _ = tb.carts.AddItem(ctx, 42, 2, 1)
+
+    tb.process(t, text(1, 42, "/cart"))
+    got := tb.sentTo(42)
+    if len(got) != 1 {
+        t.Fatalf("отправлено %q", got)
+    }
+    for _, want := range []string{"Сенча × 2 — 2\u00a0998\u00a0₽", "Кружка × 1 — 500,50\u00a0₽", "Итого: 3\u00a0498,50\u00a0₽"} {
+        if !strings.Contains(got[0], want) {
+            t.Errorf("в корзине нет %q:\n%s", want, got[0])
+        }
+    }
+}
+
+// Товары в разных валютах не складываются: покупатель получает объяснение,
+// заказ не создаётся
+func TestCartMixedCurrencies(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.catalog.Add(
+        storage.Product{ID: 1, Name: "Сенча", Price: money.New(149900, "RUB"), InStock: true},
+        storage.Product{ID: 2, Name: "Матча", Price: money.New(2500, "USD"), InStock: true},
+    )
+    ctx := context.Background()
+    _ = tb.carts.AddItem(ctx, 42, 1, 1)
+    _ = tb.carts.AddItem(ctx, 42, 2, 1)
+
+    tb.process(t, text(1, 42, "/cart"))
+    tb.process(t, text(2, 42, "/checkout"))
+    if got := tb.sentTo(42); len(got) != 2 || got[0] != mixedCurrencyReply || got[1] != mixedCurrencyReply {
+        t.Fatalf("отправлено %q, нужно объяснение про валюты", got)
+    }
+    if orders, _ := tb.orders.ChatOrders(ctx, 42); len(orders) != 0 {
+        t.Fatalf("оформлен заказ в разных валютах: %+v", orders)
+    }
+}
+
+// Два покупателя одновременно оформляют последнюю единицу: заказ получает
+// один, второму приходит список закончившихся товаров
+func TestCheckoutLastUnit(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.orders.Catalog = tb.catalog
+    one := 1
+    tb.catalog.Add(storage.Product{ID: 1, Name: "Да Хун Пао", Price: money.New(300000, "RUB"), InStock: true, Stock: &one})
+    ctx := context.Background()
+    buyers := []int64{42, 43}
+    for _, chatID := range buyers {
+        _ = tb.carts.AddItem(ctx, chatID, 1, 1)
+    }
+
+    start := make(chan struct{})
+    var wg sync.WaitGroup
+    for i, chatID := range buyers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            if err := tb.ProcessUpdate(ctx, text(int64(i+1), chatID, "/checkout")); err != nil {
+                t.Errorf("ProcessUpdate: %v", err)
+            }
+        }()
+    }
+    close(start)
+    wg.Wait()
+
+    var ordered, soldOut int
+    for _, chatID := range buyers {
+        orders, _ := tb.orders.ChatOrders(ctx, chatID)
+        ordered += len(orders)
+        for _, m := range tb.sentTo(chatID) {
+            if strings.HasPrefix(m, "Не удалось оформить заказ") && strings.Contains(m, "• Да Хун Пао") {
+                soldOut++
+            }
+        }
+    }
+    if ordered != 1 || soldOut != 1 {
+        t.Fatalf("заказов %d, отказов %d — нужно по одному", ordered, soldOut)
+    }
+    p, _ := tb.catalog.GetProduct(ctx, 1)
+    if *p.Stock != 0 || p.InStock {
+        t.Fatalf("после продажи последней единицы остаток %d, в наличии %v", *p.Stock, p.InStock)
+    }
+}
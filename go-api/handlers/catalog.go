@@ -0,0 +1,126 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// catalogAction — action кнопок листания каталога, callback_data "page:<offset>"
+const catalogAction = "page"
+
+// catalogPageSize — товаров на одной странице /catalog
+const catalogPageSize = 10
+
+// emptyCatalogReply — ответ /catalog, если в каталоге нет товаров
+const emptyCatalogReply = "Каталог пока пуст."
+
+// catalogPage — страница каталога, готовая к показу
+type catalogPage struct {
+    Text     string
+    Keyboard *telegram.InlineKeyboard
+}
+
+// clampOffset приводит смещение к началу существующей страницы: отрицательное —
+// к первой, за концом каталога — к последней
+func clampOffset(offset, total int) int {
+    if offset < 0 || total == 0 {
+        return 0
+    }
+    if offset >= total {
+        offset = total - 1
+    }
+    return offset - offset%catalogPageSize
+}
+
+// loadCatalogPage читает страницу каталога со смещения offset
+func (b *Bot) loadCatalogPage(ctx context.Context, offset int) (catalogPage, error) {
+    b.noteShopping(ctx)
+    total, err := b.Catalog.CountProducts(ctx)
+    if err != nil {
+        return catalogPage{}, err
+    }
+    if total == 0 {
+        return catalogPage{Text: emptyCatalogReply}, nil
+    }
+    offset = clampOffset(offset, total)
+    products, err := b.Catalog.ListProducts(ctx, catalogPageSize, offset)
+    if err != nil {
+        return catalogPage{}, err
+    }
+    return catalogPage{
+        Text:     renderCatalogPage("📦 Каталог", products, offset, total, b.prices(ctx)),
+        Keyboard: catalogKeyboard(offset, total),
+    }, nil
+}
+
+// renderCatalogPage — текст страницы под заголовком title: товары с ценой,
+// общее число и номер страницы
+func renderCatalogPage(title string, products []storage.Product, offset, total int, prices *priceFormat) string {
+    pages := (total + catalogPageSize - 1) / catalogPageSize
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "%s: %d товаров, страница %d из %d\n\n", title, total, offset/catalogPageSize+1, pages)
+    for i, p := range products {
+        fmt.Fprintf(&sb, "%d. %s — %s", offset+i+1, p.Name, prices.Format(p.Price))
+        if !p.InStock {
+            sb.WriteString(" (нет в наличии)")
+        }
+        sb.WriteByte('\n')
+    }
+    sb.WriteString(prices.Note())
+    return sb.String()
+}
+
+// catalogKeyboard — кнопки "Назад"/"Вперёд"; nil, если страница единственная
+func catalogKeyboard(offset, total int) *telegram.InlineKeyboard {
+    buttons := pageButtons(offset, total, func(offset int) string {
+        return fmt.Sprintf("%s:%d", catalogAction, offset)
+    })
+    if len(buttons) == 0 {
+        return nil
+    }
+    return telegram.NewInlineKeyboard().Row(buttons...)
+}
+
+// pageButtons — кнопки "Назад"/"Вперёд" для страницы со смещением offset;
+// data — callback_data кнопки, ведущей на страницу с данным смещением
+func pageButtons(offset, total int, data func(offset int) string) []telegram.InlineKeyboardButton {
+    var buttons []telegram.InlineKeyboardButton
+    if offset > 0 {
+        buttons = append(buttons, telegram.CallbackButton("◀️ Назад", data(offset-catalogPageSize)))
+    }
+    if offset+catalogPageSize < total {
+        buttons = append(buttons, telegram.CallbackButton("Вперёд ▶️", data(offset+catalogPageSize)))
+    }
+    return buttons
+}
+
+// cmdCatalog — команда /catalog: первая страница каталога
+func (b *Bot) cmdCatalog(ctx context.Context, msg *TelegramMessage, args string) error {
+    page, err := b.loadCatalogPage(ctx, 0)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить каталог, попробуйте позже.")
+    }
+    // Простой текст: в названиях товаров могут быть символы разметки
+    _, err = b.Telegram.SendMessage(msg.Chat.ID, page.Text, telegram.WithReplyMarkup(page.Keyboard), telegram.WithParseMode(""))
+    return err
+}
+
+// cbCatalog — листание каталога: правит то же сообщение, а не шлёт новое
+func (b *Bot) cbCatalog(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    offset, err := strconv.Atoi(payload)
+    if err != nil {
+        return fmt.Errorf("некорректное смещение каталога %q: %w", payload, err)
+    }
+    page, err := b.loadCatalogPage(ctx, offset)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить каталог, попробуйте позже.")
+    }
+    return b.Telegram.EditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, page.Text,
+        telegram.WithReplyMarkup(page.Keyboard), telegram.WithParseMode(""))
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// fillCatalog — n товаров «Чай 1»…«Чай n»
+func fillCatalog(tb *testBot, n int) {
+    for i := 1; i <= n; i++ {
+        tb.catalog.Add(storage.Product{ID: int64(i), Name: fmt.Sprintf("Чай %d", i), Price: money.New(10000, "RUB"), InStock: true})
+    }
+}
+
+// pageTurn — нажатие кнопки листания каталога под сообщением messageID
+func pageTurn(updateID, chatID, messageID int64, offset string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, CallbackQuery: &TelegramCallbackQuery{
+        ID:      "page",
+        From:    &TelegramUser{ID: chatID, LanguageCode: "ru"},
+        Data:    catalogAction + ":" + offset,
+        Message: &TelegramMessage{MessageID: messageID, Chat: TelegramChat{ID: chatID, Type: "private"}},
+    }}
+}
+
+// pageData — callback_data кнопок листания сообщения
+func pageData(m telegram.ReplyMarkup) []string {
+    kb, ok := m.(*telegram.InlineKeyboard)
+    if !ok || kb == nil {
+        return nil
+    }
+    var out []string
+    for _, row := range kb.Rows {
+        for _, b := range row {
+            out = append(out, b.CallbackData)
+        }
+    }
+    return out
+}
+
+// Крайние страницы: у первой нет «Назад», у последней — «Вперёд»; смещение
+// за концом каталога и отрицательное приводятся к существующей странице, а
+// листание правит то же сообщение
+func TestCatalogBoundaryPages(t *testing.T) {
+    tb := newTestBot(t, nil)
+    fillCatalog(tb, 25)
+    tb.process(t, text(1, 42, "/catalog"))
+
+    sent := tb.tg.Messages()
+    if len(sent) != 1 {
+        t.Fatalf("отправлено %d сообщений", len(sent))
+    }
+    msgID := sent[0].MessageID
+
+    cases := []struct {
+        name    string
+        offset  string
+        page    string
+        first   string
+        last    string
+        buttons []string
+    }{
+        {"первая", "", "страница 1 из 3", "1. Чай 1", "10. Чай 10", []string{"page:10"}},
+        {"средняя", "10", "страница 2 из 3", "11. Чай 11", "20. Чай 20", []string{"page:0", "page:20"}},
+        {"последняя", "20", "страница 3 из 3", "21. Чай 21", "25. Чай 25", []string{"page:10"}},
+        {"за концом", "1000", "страница 3 из 3", "21. Чай 21", "25. Чай 25", []string{"page:10"}},
+        {"внутри последней", "24", "страница 3 из 3", "21. Чай 21", "25. Чай 25", []string{"page:10"}},
+        {"отрицательное", "-10", "страница 1 из 3", "1. Чай 1", "10. Чай 10", []string{"page:10"}},
+    }
+    for i, tc := range cases {
+        if tc.offset != "" {
+            tb.process(t, pageTurn(int64(i+2), 42, msgID, tc.offset))
+        }
+        sent := tb.tg.Messages()
+        if len(sent) != 1 {
+            t.Fatalf("%s: листание прислало новое сообщение: %d", tc.name, len(sent))
+        }
+        got := sent[0]
+        if !strings.Contains(got.Text, "25 товаров, "+tc.page) || !strings.Contains(got.Text, tc.first) || !strings.Contains(got.Text, tc.last) {
+            t.Errorf("%s: текст\n%s", tc.name, got.Text)
+        }
+        if data := pageData(got.Markup); strings.Join(data, " ") != strings.Join(tc.buttons, " ") {
+            t.Errorf("%s: кнопки %q, нужны %q", tc.name, data, tc.buttons)
+        }
+    }
+}
+
+func TestCatalogSinglePage(t *testing.T) {
+    tb := newTestBot(t, nil)
+    fillCatalog(tb, catalogPageSize)
+    tb.process(t, text(1, 42, "/catalog"))
+
+    sent := tb.tg.Messages()
+    if len(sent) != 1 || !strings.Contains(sent[0].Text, "страница 1 из 1") {
+        t.Fatalf("отправлено %+v", sent)
+    }
+    if data := pageData(sent[0].Markup); len(data) != 0 {
+        t.Fatalf("у единственной страницы кнопки %q", data)
+    }
+}
+
+func TestCatalogEmpty(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/catalog"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != emptyCatalogReply {
+        t.Fatalf("отправлено %q", got)
+    }
+}
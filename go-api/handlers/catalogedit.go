@@ -0,0 +1,130 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/money"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// maxStock — предел остатка в /setstock: больше — почти наверняка опечатка
+const maxStock = 1_000_000
+
+// cmdSetPrice — админская команда /setprice <id> <цена>: новая цена товара
+// в его валюте, например /setprice 12 1990,50
+func (b *Bot) cmdSetPrice(ctx context.Context, msg *TelegramMessage, args string) error {
+    const usage = "Использование: /setprice <id товара> <цена>, например /setprice 12 1990,50"
+    id, value, err := editArgs(args, usage)
+    if err != nil {
+        return err
+    }
+    if strings.HasPrefix(value, "-") {
+        return apperr.Validation("Цена не может быть отрицательной.")
+    }
+    price, err := money.Parse(value, "")
+    if err != nil {
+        return apperr.Validation(fmt.Sprintf("Не понял цену %q: нужно число, копейки — через точку или запятую.", value))
+    }
+
+    p, err := b.Catalog.SetPrice(ctx, id, price.Minor, msg.Chat.ID)
+    return b.editedReply(ctx, msg, id, "price", p, err)
+}
+
+// cmdSetStock — админская команда /setstock <id> <остаток>: остаток товара
+// на складе; нулевой остаток снимает товар с продажи
+func (b *Bot) cmdSetStock(ctx context.Context, msg *TelegramMessage, args string) error {
+    const usage = "Использование: /setstock <id товара> <остаток>, например /setstock 12 5"
+    id, value, err := editArgs(args, usage)
+    if err != nil {
+        return err
+    }
+    qty, err := strconv.Atoi(value)
+    switch {
+    case err != nil:
+        return apperr.Validation(fmt.Sprintf("Не понял остаток %q: нужно целое число.", value))
+    case qty < 0:
+        return apperr.Validation("Остаток не может быть отрицательным.")
+    case qty > maxStock:
+        return apperr.Validation(fmt.Sprintf("Остаток больше %d — проверьте число.", maxStock))
+    }
+
+    p, err := b.Catalog.SetStock(ctx, id, qty, msg.Chat.ID)
+    return b.editedReply(ctx, msg, id, "stock", p, err)
+}
+
+// cmdSetCategory — админская команда /setcategory <id> <категория>: перенос
+// товара в категорию по id, названию или пути «Раздел/Категория»;
+// «-» убирает товар из категорий
+func (b *Bot) cmdSetCategory(ctx context.Context, msg *TelegramMessage, args string) error {
+    const usage = "Использование: /setcategory <id товара> <категория>, например /setcategory 12 Чай/Зелёный; «-» — без категории"
+    rawID, ref, _ := strings.Cut(strings.TrimSpace(args), " ")
+    id, err := strconv.ParseInt(rawID, 10, 64)
+    ref = strings.TrimSpace(ref)
+    if err != nil || id < 1 || ref == "" {
+        return apperr.Validation(usage)
+    }
+
+    var categoryID int64
+    name := "без категории"
+    if ref != "-" {
+        categories, err := b.Catalog.ListCategories(ctx)
+        if err != nil {
+            return apperr.WithMessage(err, "Не удалось загрузить категории каталога, попробуйте позже.")
+        }
+        if categoryID, err = newCategoryLookup(categories).resolve(ref); err != nil {
+            return apperr.Validation(fmt.Sprintf("Не получилось выбрать категорию: %v. Разделы — /categories", err))
+        }
+        name = newCategoryTree(categories).byID[categoryID].Name
+    }
+
+    p, err := b.Catalog.SetCategory(ctx, id, categoryID, msg.Chat.ID)
+    return b.editedReply(ctx, msg, id, "category", p, err, "Категория: "+name)
+}
+
+// editArgs разбирает аргументы /setprice и /setstock: id товара и значение
+func editArgs(args, usage string) (int64, string, error) {
+    fields := strings.Fields(args)
+    if len(fields) != 2 {
+        return 0, "", apperr.Validation(usage)
+    }
+    id, err := strconv.ParseInt(fields[0], 10, 64)
+    if err != nil || id < 1 {
+        return 0, "", apperr.Validation(usage)
+    }
+    return id, fields[1], nil
+}
+
+// editedReply отвечает админу товаром после правки field или объясняет
+// ошибку; extra — дополнительные строки ответа
+func (b *Bot) editedReply(ctx context.Context, msg *TelegramMessage, id int64, field string, p storage.Product, err error, extra ...string) error {
+    if errors.Is(err, storage.ErrNotFound) {
+        return apperr.NotFound(fmt.Sprintf("Товара с id %d нет в каталоге.", id))
+    }
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось изменить товар, попробуйте ещё раз.")
+    }
+    logging.FromContext(ctx).Info("товар изменён вручную", "product_id", id, "field", field, "admin_chat_id", msg.Chat.ID)
+
+    stock := "не ведётся"
+    if p.Stock != nil {
+        stock = strconv.Itoa(*p.Stock)
+    }
+    availability := "в наличии"
+    if !p.InStock {
+        availability = "нет в наличии"
+    }
+    text := fmt.Sprintf("✅ Товар %d «%s»\nЦена: %s\nОстаток: %s, %s", p.ID, p.Name, p.Price, stock, availability)
+    for _, line := range extra {
+        text += "\n" + line
+    }
+    // Простой текст: в названии могут быть символы разметки
+    _, err = b.Telegram.SendMessage(msg.Chat.ID, text, telegram.WithParseMode(""))
+    return err
+}
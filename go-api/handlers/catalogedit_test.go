@@ -0,0 +1,111 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+const editAdmin int64 = 1
+
+func editBot(t *testing.T) *testBot {
+    t.Helper()
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    tb.catalog.Add(storage.Product{ID: 12, Name: "Улун *Те Гуань Инь*", Price: money.New(50000, "RUB"), InStock: true})
+    return tb
+}
+
+func TestSetPriceCommand(t *testing.T) {
+    cases := []struct {
+        args  string
+        price int64
+        reply string
+    }{
+        {"12 1990,50", 199050, "Цена: 1"},
+        {"12 1990.5", 199050, "Цена: 1"},
+        {"12 0", 0, "Цена: 0"},
+        {"12 -5", 50000, "не может быть отрицательной"},
+        {"12 дорого", 50000, "Не понял цену"},
+        {"12 1,999", 50000, "Не понял цену"},
+        {"12", 50000, "Использование: /setprice"},
+        {"0 100", 50000, "Использование: /setprice"},
+        {"99 100", 50000, "Товара с id 99 нет"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.args, func(t *testing.T) {
+            tb := editBot(t)
+            tb.process(t, text(1, editAdmin, "/setprice "+tc.args))
+
+            p, _ := tb.catalog.GetProduct(context.Background(), 12)
+            if p.Price.Minor != tc.price || p.Price.Currency != "RUB" {
+                t.Fatalf("цена %+v, нужно %d RUB", p.Price, tc.price)
+            }
+            if got := tb.lastSent(t, editAdmin); !strings.Contains(got, tc.reply) {
+                t.Fatalf("ответ %q, нужно %q", got, tc.reply)
+            }
+        })
+    }
+}
+
+func TestSetStockCommand(t *testing.T) {
+    cases := []struct {
+        args string
+        // stock — остаток после команды; -1 — остаток не ведётся
+        stock   int
+        inStock bool
+        reply   string
+    }{
+        {"12 5", 5, true, "Остаток: 5, в наличии"},
+        {"12 0", 0, false, "Остаток: 0, нет в наличии"},
+        {"12 -1", -1, true, "не может быть отрицательным"},
+        {"12 2,5", -1, true, "нужно целое число"},
+        {"12 1000001", -1, true, "проверьте число"},
+        {"12 5 6", -1, true, "Использование: /setstock"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.args, func(t *testing.T) {
+            tb := editBot(t)
+            tb.process(t, text(1, editAdmin, "/setstock "+tc.args))
+
+            p, _ := tb.catalog.GetProduct(context.Background(), 12)
+            stock := -1
+            if p.Stock != nil {
+                stock = *p.Stock
+            }
+            if stock != tc.stock || p.InStock != tc.inStock {
+                t.Fatalf("остаток %d, в наличии %v; нужно %d, %v", stock, p.InStock, tc.stock, tc.inStock)
+            }
+            if got := tb.lastSent(t, editAdmin); !strings.Contains(got, tc.reply) {
+                t.Fatalf("ответ %q, нужно %q", got, tc.reply)
+            }
+        })
+    }
+}
+
+// Правка попадает в журнал с админом; ответ идёт простым текстом, потому
+// что в названии товара бывают символы разметки
+func TestCatalogEditAuditAndPlainReply(t *testing.T) {
+    tb := editBot(t)
+    tb.process(t, text(1, editAdmin, "/setprice 12 700"))
+
+    if len(tb.catalog.Audit) != 1 || tb.catalog.Audit[0].Field != "price" || tb.catalog.Audit[0].ChangedBy != editAdmin {
+        t.Fatalf("журнал правок %+v", tb.catalog.Audit)
+    }
+    msgs := tb.tg.Messages()
+    last := msgs[len(msgs)-1]
+    if last.ParseMode != "" || !strings.Contains(last.Text, "«Улун *Те Гуань Инь*»") {
+        t.Fatalf("ответ %q с разметкой %q", last.Text, last.ParseMode)
+    }
+}
+
+func TestCatalogEditRequiresAdmin(t *testing.T) {
+    tb := editBot(t)
+    tb.process(t, text(1, 42, "/setprice 12 1"))
+    tb.process(t, text(2, 42, "/setstock 12 0"))
+    if p, _ := tb.catalog.GetProduct(context.Background(), 12); p.Price.Minor != 50000 || p.Stock != nil {
+        t.Fatalf("не админ изменил товар: %+v", p)
+    }
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/i18n"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+const (
+    // categoriesAction — action кнопок дерева категорий, callback_data
+    // "cats:<id категории>"; 0 — список корневых категорий
+    categoriesAction = "cats"
+    // categoryPageAction — листание товаров категории, callback_data
+    // "catp:<id категории>:<offset>"
+    categoryPageAction = "catp"
+    // noCategoriesReply — ответ /categories, если категории не заданы
+    noCategoriesReply = "Разделы каталога пока не настроены. Посмотрите все товары — /catalog"
+)
+
+// categoryTree — категории каталога, собранные по ParentID
+type categoryTree struct {
+    byID     map[int64]storage.Category
+    children map[int64][]storage.Category
+}
+
+// newCategoryTree раскладывает категории по родителям с сохранением порядка
+func newCategoryTree(categories []storage.Category) categoryTree {
+    t := categoryTree{
+        byID:     make(map[int64]storage.Category, len(categories)),
+        children: make(map[int64][]storage.Category),
+    }
+    for _, c := range categories {
+        t.byID[c.ID] = c
+        t.children[c.ParentID] = append(t.children[c.ParentID], c)
+    }
+    return t
+}
+
+// total — товары категории вместе с подкатегориями
+func (t categoryTree) total(id int64) int {
+    n := t.byID[id].Products
+    for _, child := range t.children[id] {
+        n += child.Products
+    }
+    return n
+}
+
+// categoryLookup — id категорий по ссылке из файла каталога или /setcategory:
+// по id, по названию без учёта регистра или, для подкатегории, по пути
+// «Раздел/Категория»
+type categoryLookup map[string]int64
+
+// ambiguousCategory — отметка названия, которое носят несколько категорий
+const ambiguousCategory = -1
+
+// newCategoryLookup собирает ссылки на категории
+func newCategoryLookup(categories []storage.Category) categoryLookup {
+    tree := newCategoryTree(categories)
+    l := make(categoryLookup, 3*len(categories))
+    for _, c := range categories {
+        l[strconv.FormatInt(c.ID, 10)] = c.ID
+        name := categoryRef(c.Name)
+        if _, dup := l[name]; dup {
+            l[name] = ambiguousCategory
+        } else {
+            l[name] = c.ID
+        }
+        if parent, ok := tree.byID[c.ParentID]; ok {
+            l[categoryRef(parent.Name+"/"+c.Name)] = c.ID
+        }
+    }
+    return l
+}
+
+// categoryRef — ссылка на категорию без регистра и пробелов вокруг «/»
+func categoryRef(s string) string {
+    parts := strings.Split(strings.ToLower(s), "/")
+    for i, p := range parts {
+        parts[i] = strings.TrimSpace(p)
+    }
+    return strings.Join(parts, "/")
+}
+
+// resolve — id категории по ссылке ref
+func (l categoryLookup) resolve(ref string) (int64, error) {
+    id, ok := l[categoryRef(ref)]
+    switch {
+    case !ok:
+        return 0, fmt.Errorf("неизвестная категория %q", ref)
+    case id == ambiguousCategory:
+        return 0, fmt.Errorf("категорий %q несколько — укажите раздел: «Раздел/%s»", ref, strings.TrimSpace(ref))
+    }
+    return id, nil
+}
+
+// categoryButtons — кнопки категорий с числом товаров, по две в ряд
+func (t categoryTree) categoryButtons(kb *telegram.InlineKeyboard, categories []storage.Category) {
+    for i := 0; i < len(categories); i += 2 {
+        row := []telegram.InlineKeyboardButton{t.categoryButton(categories[i])}
+        if i+1 < len(categories) {
+            row = append(row, t.categoryButton(categories[i+1]))
+        }
+        kb.Row(row...)
+    }
+}
+
+// categoryButton — кнопка категории: «Название (N)»
+func (t categoryTree) categoryButton(c storage.Category) telegram.InlineKeyboardButton {
+    return telegram.CallbackButton(fmt.Sprintf("%s (%d)", c.Name, t.total(c.ID)), fmt.Sprintf("%s:%d", categoriesAction, c.ID))
+}
+
+// backButton — кнопка на уровень выше: к родителю или к списку категорий
+func backButton(parentID int64) telegram.InlineKeyboardButton {
+    label := "⬅️ Все категории"
+    if parentID != 0 {
+        label = "⬅️ Назад"
+    }
+    return telegram.CallbackButton(label, fmt.Sprintf("%s:%d", categoriesAction, parentID))
+}
+
+// loadCategoryView — экран категории id: корневые категории (id 0),
+// подкатегории или, если их нет, первая страница товаров. Удалённая
+// категория из старой кнопки показывает корневой список.
+func (b *Bot) loadCategoryView(ctx context.Context, id int64) (catalogPage, error) {
+    b.noteShopping(ctx)
+    categories, err := b.Catalog.ListCategories(ctx)
+    if err != nil {
+        return catalogPage{}, err
+    }
+    tree := newCategoryTree(categories)
+    c, ok := tree.byID[id]
+    if !ok {
+        id = 0
+    }
+
+    children := tree.children[id]
+    switch {
+    case id == 0 && len(children) == 0:
+        return catalogPage{Text: i18n.T(reqctx.LangFromContext(ctx), noCategoriesReply)}, nil
+    case id == 0:
+        kb := telegram.NewInlineKeyboard()
+        tree.categoryButtons(kb, children)
+        return catalogPage{Text: "🗂 Разделы каталога:", Keyboard: kb}, nil
+    case len(children) > 0:
+        kb := telegram.NewInlineKeyboard()
+        tree.categoryButtons(kb, children)
+        kb.Row(telegram.CallbackButton(fmt.Sprintf("Все товары раздела (%d)", tree.total(id)), categoryPageData(id, 0)))
+        kb.Row(backButton(c.ParentID))
+        return catalogPage{Text: "📂 " + c.Name + ":", Keyboard: kb}, nil
+    }
+    return b.loadCategoryPage(ctx, c, 0, c.ParentID)
+}
+
+// loadCategoryPage — страница товаров категории c со смещения offset; back —
+// куда ведёт кнопка возврата
+func (b *Bot) loadCategoryPage(ctx context.Context, c storage.Category, offset int, back int64) (catalogPage, error) {
+    total, err := b.Catalog.CountProductsByCategory(ctx, c.ID)
+    if err != nil {
+        return catalogPage{}, err
+    }
+    if total == 0 {
+        return catalogPage{
+            Text:     i18n.T(reqctx.LangFromContext(ctx), emptyCategoryReply),
+            Keyboard: telegram.NewInlineKeyboard().Row(backButton(back)),
+        }, nil
+    }
+    offset = clampOffset(offset, total)
+    products, err := b.Catalog.ListProductsByCategory(ctx, c.ID, catalogPageSize, offset)
+    if err != nil {
+        return catalogPage{}, err
+    }
+
+    kb := telegram.NewInlineKeyboard()
+    if buttons := pageButtons(offset, total, func(offset int) string { return categoryPageData(c.ID, offset) }); len(buttons) > 0 {
+        kb.Row(buttons...)
+    }
+    kb.Row(backButton(back))
+    return catalogPage{
+        Text:     renderCatalogPage("📂 "+c.Name, products, offset, total, b.prices(ctx)),
+        Keyboard: kb,
+    }, nil
+}
+
+// categoryPageData — callback_data страницы товаров категории
+func categoryPageData(id int64, offset int) string {
+    return fmt.Sprintf("%s:%d:%d", categoryPageAction, id, offset)
+}
+
+// cmdCategories — команда /categories: кнопки разделов каталога
+func (b *Bot) cmdCategories(ctx context.Context, msg *TelegramMessage, args string) error {
+    page, err := b.loadCategoryView(ctx, 0)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить каталог, попробуйте позже.")
+    }
+    opts := []telegram.SendOption{telegram.WithParseMode("")}
+    if page.Keyboard != nil {
+        opts = append(opts, telegram.WithReplyMarkup(page.Keyboard))
+    }
+    _, err = b.Telegram.SendMessage(msg.Chat.ID, page.Text, opts...)
+    return err
+}
+
+// cbCategories — переход по дереву категорий: правит то же сообщение
+func (b *Bot) cbCategories(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    id, err := strconv.ParseInt(payload, 10, 64)
+    if err != nil {
+        return fmt.Errorf("некорректная категория %q: %w", payload, err)
+    }
+    page, err := b.loadCategoryView(ctx, id)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить каталог, попробуйте позже.")
+    }
+    return b.editCatalogMessage(cq, page)
+}
+
+// cbCategoryPage — листание товаров категории
+func (b *Bot) cbCategoryPage(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    rawID, rawOffset, _ := strings.Cut(payload, ":")
+    id, err := strconv.ParseInt(rawID, 10, 64)
+    if err != nil {
+        return fmt.Errorf("некорректная категория %q: %w", payload, err)
+    }
+    offset, err := strconv.Atoi(rawOffset)
+    if err != nil {
+        return fmt.Errorf("некорректное смещение категории %q: %w", payload, err)
+    }
+
+    categories, err := b.Catalog.ListCategories(ctx)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить каталог, попробуйте позже.")
+    }
+    tree := newCategoryTree(categories)
+    c, ok := tree.byID[id]
+    if !ok {
+        return b.cbCategories(ctx, cq, "0")
+    }
+    // У раздела с подкатегориями «Назад» ведёт к его подкатегориям
+    back := c.ParentID
+    if len(tree.children[id]) > 0 {
+        back = id
+    }
+    page, err := b.loadCategoryPage(ctx, c, offset, back)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить каталог, попробуйте позже.")
+    }
+    return b.editCatalogMessage(cq, page)
+}
+
+// editCatalogMessage заменяет сообщение с кнопкой на страницу page
+func (b *Bot) editCatalogMessage(cq *TelegramCallbackQuery, page catalogPage) error {
+    opts := []telegram.SendOption{telegram.WithParseMode("")}
+    if page.Keyboard != nil {
+        opts = append(opts, telegram.WithReplyMarkup(page.Keyboard))
+    }
+    return b.Telegram.EditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, page.Text, opts...)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// categoryCatalog — два раздела, у «Чая» подкатегория «Зелёный»; товар 3 без категории
+func categoryCatalog(tb *testBot) {
+    price := money.New(10000, "RUB")
+    tb.catalog.Add(
+        storage.Product{ID: 1, Name: "Сенча", Price: price, InStock: true},
+        storage.Product{ID: 2, Name: "Кружка", Price: price, InStock: true},
+        storage.Product{ID: 3, Name: "Открытка", Price: price, InStock: true},
+    )
+    tb.catalog.AddCategory(storage.Category{ID: 10, Name: "Чай"})
+    tb.catalog.AddCategory(storage.Category{ID: 11, Name: "Зелёный", ParentID: 10}, 1)
+    tb.catalog.AddCategory(storage.Category{ID: 20, Name: "Посуда"}, 2)
+}
+
+func TestCategoriesWithoutCategories(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/categories"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != noCategoriesReply {
+        t.Fatalf("отправлено %q, ожидался ответ без разделов", got)
+    }
+}
+
+func TestCategoryViewTree(t *testing.T) {
+    tb := newTestBot(t, nil)
+    categoryCatalog(tb)
+    ctx := context.Background()
+
+    root, err := tb.loadCategoryView(ctx, 0)
+    if err != nil {
+        t.Fatalf("корень: %v", err)
+    }
+    if len(root.Keyboard.Rows) != 1 || root.Keyboard.Rows[0][0].Text != "Чай (1)" || root.Keyboard.Rows[0][1].Text != "Посуда (1)" {
+        t.Fatalf("кнопки корня: %+v", root.Keyboard.Rows)
+    }
+
+    leaf, err := tb.loadCategoryView(ctx, 11)
+    if err != nil {
+        t.Fatalf("подкатегория: %v", err)
+    }
+    if !strings.Contains(leaf.Text, "Сенча") || strings.Contains(leaf.Text, "Открытка") {
+        t.Fatalf("товары подкатегории: %q", leaf.Text)
+    }
+}
+
+func TestSetCategoryCommand(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    categoryCatalog(tb)
+    ctx := context.Background()
+
+    tb.process(t, text(1, 1, "/setcategory 3 чай / зелёный"))
+    if p, _ := tb.catalog.GetProduct(ctx, 3); p.CategoryID != 11 {
+        t.Fatalf("категория товара: получено %d, ожидалось 11", p.CategoryID)
+    }
+    if n, _ := tb.catalog.CountProductsByCategory(ctx, 10); n != 2 {
+        t.Fatalf("товаров в разделе с подкатегориями: получено %d, ожидалось 2", n)
+    }
+    if last := tb.catalog.Audit[len(tb.catalog.Audit)-1]; last.Field != "category" || last.ChangedBy != 1 {
+        t.Fatalf("журнал правок: %+v", last)
+    }
+
+    tb.process(t, text(2, 1, "/setcategory 3 Кофе"))
+    if p, _ := tb.catalog.GetProduct(ctx, 3); p.CategoryID != 11 {
+        t.Fatal("неизвестная категория изменила товар")
+    }
+    tb.process(t, text(3, 1, "/setcategory 3 -"))
+    if p, _ := tb.catalog.GetProduct(ctx, 3); p.CategoryID != 0 {
+        t.Fatalf("«-» оставил категорию %d", p.CategoryID)
+    }
+
+    tb.process(t, text(4, 42, "/setcategory 2 Чай"))
+    if p, _ := tb.catalog.GetProduct(ctx, 2); p.CategoryID != 20 {
+        t.Fatal("не админ перенёс товар")
+    }
+}
+
+func TestImportAssignsCategory(t *testing.T) {
+    lookup := newCategoryLookup([]storage.Category{
+        {ID: 10, Name: "Чай"},
+        {ID: 11, Name: "Прочее", ParentID: 10},
+        {ID: 20, Name: "Посуда"},
+        {ID: 21, Name: "Прочее", ParentID: 20},
+    })
+    csv := "name;price;category\nСенча;100;чай\nКружка;200;Посуда/Прочее\nЛожка;50;Прочее\nОткрытка;10;Кофе\nЧайник;900;20\nСахар;30;\n"
+    products, rowErrs, err := parseCatalogFile("catalog.csv", "text/csv", []byte(csv), "RUB", lookup)
+    if err != nil {
+        t.Fatalf("разбор: %v", err)
+    }
+    got := map[string]int64{}
+    for _, p := range products {
+        got[p.Name] = p.CategoryID
+    }
+    want := map[string]int64{"Сенча": 10, "Кружка": 21, "Чайник": 20, "Сахар": 0}
+    if len(got) != len(want) {
+        t.Fatalf("товары: получено %v, ожидалось %v", got, want)
+    }
+    for name, id := range want {
+        if got[name] != id {
+            t.Fatalf("категория %q: получено %d, ожидалось %d", name, got[name], id)
+        }
+    }
+    if len(rowErrs) != 2 || !strings.Contains(rowErrs[0].Error(), "строка 4") || !strings.Contains(rowErrs[1].Error(), "неизвестная категория") {
+        t.Fatalf("ошибки строк: %v", rowErrs)
+    }
+}
+
+func TestImportKeepsCategoryWhenEmpty(t *testing.T) {
+    tb := newTestBot(t, nil)
+    categoryCatalog(tb)
+    ctx := context.Background()
+    res, err := tb.catalog.ImportProducts(ctx, []storage.Product{
+        {ID: 1, Name: "Сенча", Price: money.New(12000, "RUB"), InStock: true},
+        {ID: 3, Name: "Открытка", Price: money.New(10000, "RUB"), InStock: true, CategoryID: 20},
+    })
+    if err != nil || res.Updated != 2 {
+        t.Fatalf("импорт: %+v, %v", res, err)
+    }
+    if p, _ := tb.catalog.GetProduct(ctx, 1); p.CategoryID != 11 {
+        t.Fatalf("пустая категория затёрла заведённую: %d", p.CategoryID)
+    }
+    if p, _ := tb.catalog.GetProduct(ctx, 3); p.CategoryID != 20 {
+        t.Fatalf("категория из файла: получено %d, ожидалось 20", p.CategoryID)
+    }
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "unicode"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+)
+
+// CommandFunc — обработчик команды бота; args — текст после имени команды
+type CommandFunc func(ctx context.Context, msg *TelegramMessage, args string) error
+
+// command — зарегистрированная команда
+type command struct {
+    fn        CommandFunc
+    adminOnly bool
+}
+
+// noAccessReply — ответ не-админу на админскую команду
+const noAccessReply = "нет доступа"
+
+// RegisterCommand регистрирует обработчик команды (имя без "/")
+func (b *Bot) RegisterCommand(name string, fn CommandFunc) {
+    b.commands[strings.ToLower(name)] = command{fn: fn}
+}
+
+// RegisterAdminCommand регистрирует команду, доступную только чатам из ADMIN_CHAT_IDS
+func (b *Bot) RegisterAdminCommand(name string, fn CommandFunc) {
+    b.commands[strings.ToLower(name)] = command{fn: fn, adminOnly: true}
+}
+
+// registerDefaultCommands — команды, доступные всегда
+func (b *Bot) registerDefaultCommands() {
+    b.RegisterCommand("start", b.cmdStart)
+    b.RegisterCommand("help", b.cmdHelp)
+    b.RegisterCommand("catalog", b.cmdCatalog)
+    b.RegisterCommand("categories", b.cmdCategories)
+    b.RegisterCommand("search", b.cmdSearch)
+    b.RegisterCommand("cart", b.cmdCart)
+    b.RegisterCommand("add", b.cmdAdd)
+    b.RegisterCommand("checkout", b.cmdCheckout)
+    b.RegisterCommand("order", b.cmdOrder)
+    b.RegisterCommand("export", b.cmdExport)
+    b.RegisterCommand("reset", b.cmdReset)
+    b.RegisterCommand("history", b.cmdHistory)
+    b.RegisterCommand("currency", b.cmdCurrency)
+    b.RegisterCommand("lang", b.cmdLang)
+    b.RegisterCommand("feedback", b.cmdFeedback)
+    b.RegisterCommand("mydata", b.cmdMyData)
+    b.RegisterCommand("deletedata", b.cmdDeleteData)
+    b.RegisterCommand("menu", b.cmdMenu)
+    b.RegisterCommand("brief", b.cmdBrief)
+    b.RegisterCommand("detailed", b.cmdDetailed)
+    b.RegisterCommand("whoami", b.cmdWhoami)
+    // Права проверяются по отправителю: команду вызывают в группе
+    b.RegisterCommand("verifymembers", b.cmdVerifyMembers)
+
+    b.RegisterAdminCommand("stats", b.cmdStats)
+    b.RegisterAdminCommand("ping", b.cmdPing)
+    b.RegisterAdminCommand("broadcast", b.cmdBroadcast)
+    b.RegisterAdminCommand("flags", b.cmdFlags)
+    b.RegisterAdminCommand("maintenance", b.cmdMaintenance)
+    b.RegisterAdminCommand("feedbackstats", b.cmdFeedbackStats)
+    b.RegisterAdminCommand("retryfailed", b.cmdRetryFailed)
+    b.RegisterAdminCommand("importcatalog", b.cmdImportCatalog)
+    b.RegisterAdminCommand("setprice", b.cmdSetPrice)
+    b.RegisterAdminCommand("setstock", b.cmdSetStock)
+    b.RegisterAdminCommand("setcategory", b.cmdSetCategory)
+    b.RegisterAdminCommand("orderstatus", b.cmdOrderStatus)
+    b.RegisterAdminCommand("resolve", b.cmdResolve)
+    b.RegisterAdminCommand("variant", b.cmdVariant)
+
+    b.RegisterCallback(addToCartAction, b.cbAddToCart)
+    b.RegisterCallback(categoryAction, b.cbCategory)
+    b.RegisterCallback(feedbackAction, b.cbFeedback)
+    b.RegisterCallback(catalogAction, b.cbCatalog)
+    b.RegisterCallback(categoriesAction, b.cbCategories)
+    b.RegisterCallback(categoryPageAction, b.cbCategoryPage)
+    b.RegisterCallback(eraseAction, b.cbErase)
+    b.RegisterCallback(verifyAction, b.cbVerify)
+    b.RegisterCallback(restoreDraftAction, b.cbRestoreDraft)
+}
+
+// dispatchCommand вызывает обработчик команды, если он зарегистрирован.
+// Команда ищется по разметке Telegram (см. TelegramMessage.command) или
+// по тексту нажатой кнопки меню (MENU_BUTTONS).
+// Возвращает false, если сообщение не команда или команда неизвестна —
+// тогда сообщение обрабатывается как обычный текст.
+func (b *Bot) dispatchCommand(ctx context.Context, msg *TelegramMessage) (bool, error) {
+    name, args, ok := msg.command()
+    if !ok {
+        name, ok = b.menuCommand(ctx, msg.Chat.ID, msg.Text)
+    }
+    if !ok {
+        return false, nil
+    }
+
+    cmd, ok := b.commands[name]
+    if !ok {
+        return false, nil
+    }
+    if cmd.adminOnly && !b.Config.IsAdmin(msg.Chat.ID) {
+        logging.FromContext(ctx).Warn("попытка вызвать админскую команду", "chat_id", msg.Chat.ID, "command", name)
+        b.replyPhrase(ctx, msg.Chat.ID, noAccessReply)
+        return true, nil
+    }
+    return true, cmd.fn(ctx, msg, args)
+}
+
+// parseCommand разбирает "/cmd@bot аргументы" на имя команды и аргументы
+func parseCommand(text string) (name, args string, ok bool) {
+    if !strings.HasPrefix(text, "/") {
+        return "", "", false
+    }
+
+    head, rest := text[1:], ""
+    if i := strings.IndexFunc(head, unicode.IsSpace); i >= 0 {
+        head, rest = head[:i], head[i:]
+    }
+    // В группах Telegram добавляет к команде упоминание бота: /start@mybot
+    head, _, _ = strings.Cut(head, "@")
+    if head == "" {
+        return "", "", false
+    }
+    return strings.ToLower(head), strings.TrimSpace(rest), true
+}
+
+// cmdHelp — список доступных команд
+func (b *Bot) cmdHelp(ctx context.Context, msg *TelegramMessage, args string) error {
+    b.reply(ctx, msg.Chat.ID, "Доступные команды:\n/start — начать диалог\n/help — эта справка\n/catalog — каталог товаров\n/categories — разделы каталога\n/search <запрос> — поиск товара\n/cart — ваша корзина\n/add <что добавить> — положить товар в корзину\n/checkout — оформить заказ\n/order <номер> — статус заказа\n/export — выгрузить ваши заказы в CSV\n/reset — начать диалог заново\n/history [n] — последние сообщения переписки\n/currency [код] — валюта, в которой показываются цены\n/lang [код] — язык общения\n/feedback — оценить мои ответы\n/mydata — какие данные о вас хранятся\n/deletedata — удалить ваши данные\n/menu — показать меню (/menu off — скрыть)\n/brief — отвечать кратко\n/detailed — отвечать подробно\n/whoami — что бот о вас знает\n\nИли просто напишите свой вопрос.")
+    return nil
+}
+
+// cmdReset — команда /reset: забыть контекст диалога.
+// История в PostgreSQL архивируется, а не удаляется — она нужна для статистики.
+func (b *Bot) cmdReset(ctx context.Context, msg *TelegramMessage, args string) error {
+    if err := b.Sessions.Clear(ctx, msg.Chat.ID); err != nil {
+        return apperr.WithMessage(err, "Не удалось очистить контекст, попробуйте ещё раз.")
+    }
+    if err := b.Messages.ArchiveHistory(ctx, msg.Chat.ID); err != nil {
+        return apperr.WithMessage(err, "Не удалось очистить контекст, попробуйте ещё раз.")
+    }
+    b.reply(ctx, msg.Chat.ID, "Контекст очищен — начнём сначала. Что вы ищете?")
+    return nil
+}
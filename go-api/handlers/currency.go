@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/money"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// SetRates подменяет курсы валют на лету (обновление из CURRENCY_RATES_URL)
+func (b *Bot) SetRates(r *money.Rates) {
+    b.rates.Store(r)
+}
+
+// priceFormat — показ цен одному покупателю: на его языке и в выбранной им
+// валюте. Если курса нет, цена показывается как есть, а Note объясняет почему.
+type priceFormat struct {
+    lang     string
+    currency string
+    rates    *money.Rates
+    // missing — хотя бы одну цену не удалось пересчитать
+    missing bool
+}
+
+// prices — показ цен для чата из ctx. Без выбранной валюты или при ошибке
+// чтения профиля цены показываются в той валюте, в которой заведены.
+func (b *Bot) prices(ctx context.Context) *priceFormat {
+    f := &priceFormat{lang: reqctx.LangFromContext(ctx), rates: b.rates.Load()}
+    chatID := reqctx.ChatIDFromContext(ctx)
+    u, err := b.profile(ctx, chatID)
+    switch {
+    case err == nil:
+        f.currency = u.Currency
+    case !errors.Is(err, storage.ErrNotFound):
+        logging.FromContext(ctx).Warn("ошибка чтения валюты покупателя", "chat_id", chatID, "err", err)
+    }
+    return f
+}
+
+// Format — сумма для покупателя, пересчитанная в его валюту; «≈» напоминает,
+// что сумма к оплате — в валюте магазина
+func (f *priceFormat) Format(m money.Money) string {
+    if f.currency == "" || f.currency == m.Currency {
+        return m.Format(f.lang)
+    }
+    converted, ok := f.rates.Convert(m, f.currency)
+    if !ok {
+        f.missing = true
+        return m.Format(f.lang)
+    }
+    return "≈" + converted.Format(f.lang)
+}
+
+// Note — пояснение к ценам, которые не удалось пересчитать; пустое, если все пересчитаны
+func (f *priceFormat) Note() string {
+    if !f.missing {
+        return ""
+    }
+    return fmt.Sprintf("\n\nКурс %s сейчас недоступен — цены указаны в валюте магазина.", f.currency)
+}
+
+// cmdCurrency — команда /currency [код]: валюта, в которой показываются цены.
+// Без аргумента — текущая валюта и доступные; /currency reset — валюта магазина.
+func (b *Bot) cmdCurrency(ctx context.Context, msg *TelegramMessage, args string) error {
+    rates := b.rates.Load()
+    available := []string{b.Config.DefaultCurrency}
+    if rates != nil {
+        for code := range rates.PerBase {
+            available = append(available, code)
+        }
+        slices.Sort(available[1:])
+    }
+
+    code := strings.ToUpper(strings.TrimSpace(args))
+    switch {
+    case code == "":
+        current := b.Config.DefaultCurrency
+        if u, err := b.profile(ctx, msg.Chat.ID); err == nil && u.Currency != "" {
+            current = u.Currency
+        }
+        b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Цены показываются в %s. Доступно: %s.\nСменить — /currency <код>, например /currency %s.",
+            current, strings.Join(available, ", "), available[len(available)-1]))
+        return nil
+    case code == "RESET" || code == b.Config.DefaultCurrency:
+        code = ""
+    case !slices.Contains(available, code):
+        return apperr.Validation(fmt.Sprintf("Для %s нет курса. Доступно: %s.", code, strings.Join(available, ", ")))
+    }
+
+    if err := b.Users.SetCurrency(ctx, msg.Chat.ID, code); err != nil {
+        return apperr.WithMessage(err, "Не удалось сохранить настройку, попробуйте ещё раз.")
+    }
+    updateProfile(ctx, msg.Chat.ID, func(u *storage.User) { u.Currency = code })
+    if code == "" {
+        code = b.Config.DefaultCurrency
+    }
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Буду показывать цены в %s. Оплата — в %s.", code, b.Config.DefaultCurrency))
+    return nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+func currencyBot(t *testing.T) *testBot {
+    t.Helper()
+    tb := newTestBot(t, map[string]string{"CURRENCY_RATES": "USD=0.011"})
+    tb.catalog.Add(storage.Product{ID: 1, Name: "Улун", Price: money.New(100000, "RUB"), InStock: true})
+    return tb
+}
+
+func TestCurrencyConversion(t *testing.T) {
+    tb := currencyBot(t)
+    tb.process(t, text(1, 42, "/currency usd"))
+    if u, _ := tb.users.GetUser(context.Background(), 42); u.Currency != "USD" {
+        t.Fatalf("валюта покупателя %q, нужно USD", u.Currency)
+    }
+
+    tb.process(t, text(2, 42, "/search улун"))
+    got := tb.lastSent(t, 42)
+    if !strings.Contains(got, "≈11\u00a0$") || strings.Contains(got, "недоступен") {
+        t.Fatalf("поиск: %q, нужна цена ≈11 $ без пояснения", got)
+    }
+}
+
+// Курс пропал (источник не ответил) — цены в валюте магазина с пояснением
+func TestCurrencyFallbackWithoutRate(t *testing.T) {
+    tb := currencyBot(t)
+    tb.process(t, text(1, 42, "/currency USD"))
+    tb.SetRates(nil)
+
+    tb.process(t, text(2, 42, "/search улун"))
+    got := tb.lastSent(t, 42)
+    if !strings.Contains(got, "1\u00a0000\u00a0₽") || strings.Contains(got, "≈") {
+        t.Fatalf("поиск: %q, нужна цена в рублях", got)
+    }
+    if !strings.Contains(got, "Курс USD сейчас недоступен") {
+        t.Fatalf("поиск: %q, нет пояснения о курсе", got)
+    }
+}
+
+func TestCurrencyCommand(t *testing.T) {
+    tb := currencyBot(t)
+
+    tb.process(t, text(1, 42, "/currency KZT"))
+    if got := tb.lastSent(t, 42); !strings.Contains(got, "Для KZT нет курса") {
+        t.Fatalf("неизвестная валюта: %q", got)
+    }
+    if u, err := tb.users.GetUser(context.Background(), 42); err == nil && u.Currency != "" {
+        t.Fatalf("сохранена валюта без курса %q", u.Currency)
+    }
+
+    tb.process(t, text(2, 42, "/currency USD"))
+    tb.process(t, text(3, 42, "/currency reset"))
+    if u, _ := tb.users.GetUser(context.Background(), 42); u.Currency != "" {
+        t.Fatalf("после reset валюта %q", u.Currency)
+    }
+    tb.process(t, text(4, 42, "/currency"))
+    if got := tb.lastSent(t, 42); !strings.Contains(got, "Цены показываются в RUB. Доступно: RUB, USD.") {
+        t.Fatalf("/currency: %q", got)
+    }
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/storage"
+)
+
+// retryBatch — сколько записей журнала повторяет одна команда /retryfailed
+const retryBatch = 20
+
+// recordFailure сохраняет апдейт в журнал необработанных, чтобы его можно было
+// повторить через /retryfailed. Запись делается с отдельным дедлайном:
+// контекст обработки к этому моменту часто уже истёк.
+func (b *Bot) recordFailure(ctx context.Context, update TelegramUpdate, cause error) {
+    if b.FailedUpdates == nil {
+        return
+    }
+    payload, err := json.Marshal(update)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка сериализации апдейта", "update_id", update.UpdateID, "err", err)
+        return
+    }
+    ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+    defer cancel()
+    if err := b.FailedUpdates.Record(ctx, update.UpdateID, updateChatID(update), payload, cause); err != nil {
+        logging.FromContext(ctx).Error("ошибка записи в журнал необработанных апдейтов", "update_id", update.UpdateID, "err", err)
+    }
+}
+
+// updateChatID — чат апдейта; 0, если апдейт не относится к чату
+func updateChatID(update TelegramUpdate) int64 {
+    switch {
+    case update.Message != nil:
+        return update.Message.Chat.ID
+    case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+        return update.CallbackQuery.Message.Chat.ID
+    case update.MyChatMember != nil:
+        return update.MyChatMember.Chat.ID
+    }
+    return 0
+}
+
+// cmdRetryFailed — админская команда /retryfailed: повторно обработать
+// самые старые апдейты из журнала. Повтор идёт в фоне, как рассылка:
+// у каждого апдейта свой дедлайн. Успешные удаляются из журнала, упавшие
+// остаются до следующего раза или до очистки по сроку хранения.
+func (b *Bot) cmdRetryFailed(ctx context.Context, msg *TelegramMessage, args string) error {
+    if b.FailedUpdates == nil {
+        return apperr.Validation("Журнал необработанных апдейтов не подключён.")
+    }
+    failed, err := b.FailedUpdates.ListFailed(ctx, retryBatch)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось прочитать журнал необработанных апдейтов.")
+    }
+    if len(failed) == 0 {
+        b.reply(ctx, msg.Chat.ID, "Необработанных апдейтов нет.")
+        return nil
+    }
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Повторяю апдейтов: %d.", len(failed)))
+
+    go b.retryFailed(context.WithoutCancel(ctx), msg.Chat.ID, failed)
+    return nil
+}
+
+// retryFailed повторяет апдейты из журнала и отправляет админу отчёт
+func (b *Bot) retryFailed(ctx context.Context, adminChatID int64, failed []storage.FailedUpdate) {
+    var done, stillFailing, skipped, busy int
+    for _, f := range failed {
+        var update TelegramUpdate
+        if f.Truncated || json.Unmarshal(f.Payload, &update) != nil {
+            skipped++
+            continue
+        }
+        release, ok := b.claimRetry(ctx, f.ID)
+        if !ok {
+            busy++
+            continue
+        }
+        err := b.retryUpdate(ctx, update)
+        release()
+        if err != nil {
+            logging.FromContext(ctx).Warn("повтор апдейта не удался", "update_id", f.UpdateID, "err", err)
+            stillFailing++
+            continue
+        }
+        if err := b.FailedUpdates.DeleteFailed(ctx, f.ID); err != nil {
+            logging.FromContext(ctx).Error("ошибка удаления из журнала", "update_id", f.UpdateID, "err", err)
+        }
+        done++
+    }
+
+    logging.FromContext(ctx).Info("повтор необработанных апдейтов завершён", "done", done, "failed", stillFailing, "skipped", skipped, "busy", busy)
+    report := fmt.Sprintf("Повторено: %d\nСнова с ошибкой: %d\nНе подлежат повтору: %d", done, stillFailing, skipped)
+    if busy > 0 {
+        report += fmt.Sprintf("\nУже повторяются другой командой: %d", busy)
+    }
+    b.reply(ctx, adminChatID, report)
+}
+
+// claimRetry занимает запись журнала на время повтора; false — её уже
+// повторяет другая команда. Без Redis повтор идёт без отметки.
+func (b *Bot) claimRetry(ctx context.Context, failedID int64) (release func(), ok bool) {
+    claimed, err := b.Updates.ClaimRetry(ctx, failedID)
+    if err != nil {
+        logging.FromContext(ctx).Warn("не удалось отметить повтор апдейта, повторяем без отметки", "failed_id", failedID, "err", err)
+        return func() {}, true
+    }
+    if !claimed {
+        return nil, false
+    }
+    return func() {
+        if err := b.Updates.ReleaseRetry(context.WithoutCancel(ctx), failedID); err != nil {
+            logging.FromContext(ctx).Warn("не удалось снять отметку повтора апдейта", "failed_id", failedID, "err", err)
+        }
+    }, true
+}
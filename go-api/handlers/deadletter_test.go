@@ -0,0 +1,84 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/memstore"
+)
+
+// journalBot — бот с журналом необработанных апдейтов в памяти и одной
+// записью: вопрос покупателя из чата 42
+func journalBot(t *testing.T) (*testBot, *memstore.FailedUpdates) {
+    t.Helper()
+    journal := &memstore.FailedUpdates{}
+    tb := newTestBot(t, map[string]string{"UPDATE_TIMEOUT": "300ms", "CHAT_LOCK_WAIT": "100ms"},
+        func(d *Deps) { d.FailedUpdates = journal })
+    tb.recordFailure(context.Background(), text(1, 42, "есть зелёный чай?"), errors.New("модель недоступна"))
+    return tb, journal
+}
+
+func TestRetryFailedDeletesProcessedUpdate(t *testing.T) {
+    tb, journal := journalBot(t)
+    failed, _ := journal.ListFailed(context.Background(), retryBatch)
+
+    tb.retryFailed(context.Background(), 1, failed)
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("покупателю ушло %q, ожидался ответ модели", got)
+    }
+    if journal.Len() != 0 {
+        t.Fatal("обработанный апдейт остался в журнале")
+    }
+    if report := tb.sentTo(1); len(report) != 1 || !strings.Contains(report[0], "Повторено: 1") {
+        t.Fatalf("отчёт админу %q", report)
+    }
+}
+
+// Повтор ждёт, пока чат занят живым апдейтом, и не обрабатывает апдейт параллельно
+func TestRetryFailedRespectsChatLock(t *testing.T) {
+    tb, journal := journalBot(t)
+    tb.Locks = cache.NewChatLocker(tb.rdb, time.Minute)
+    if _, err := tb.Locks.Lock(context.Background(), 42); err != nil {
+        t.Fatal(err)
+    }
+    failed, _ := journal.ListFailed(context.Background(), retryBatch)
+
+    tb.retryFailed(context.Background(), 1, failed)
+    if got := tb.sentTo(42); len(got) != 0 {
+        t.Fatalf("повтор обработал апдейт занятого чата: %q", got)
+    }
+    if journal.Len() != 1 {
+        t.Fatal("неповторённый апдейт пропал из журнала")
+    }
+    if report := tb.sentTo(1); len(report) != 1 || !strings.Contains(report[0], "Снова с ошибкой: 1") {
+        t.Fatalf("отчёт админу %q", report)
+    }
+}
+
+// Запись, которую уже повторяет другая команда, второй раз не обрабатывается
+func TestRetryFailedSkipsEntryBeingRetried(t *testing.T) {
+    tb, journal := journalBot(t)
+    failed, _ := journal.ListFailed(context.Background(), retryBatch)
+    if ok, err := tb.Updates.ClaimRetry(context.Background(), failed[0].ID); !ok || err != nil {
+        t.Fatalf("ClaimRetry: %v, %v", ok, err)
+    }
+
+    tb.retryFailed(context.Background(), 1, failed)
+    if got := tb.sentTo(42); len(got) != 0 {
+        t.Fatalf("апдейт обработан вторым повтором: %q", got)
+    }
+    if report := tb.sentTo(1); len(report) != 1 || !strings.Contains(report[0], "Уже повторяются другой командой: 1") {
+        t.Fatalf("отчёт админу %q", report)
+    }
+
+    // Первый повтор закончился — запись снова можно повторить
+    tb.Updates.ReleaseRetry(context.Background(), failed[0].ID)
+    tb.retryFailed(context.Background(), 1, failed)
+    if got := tb.sentTo(42); len(got) != 1 {
+        t.Fatalf("после снятия отметки ушло %q, ожидался ответ", got)
+    }
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "slices"
+    "strconv"
+    "strings"
+
+    "ai_seller/logging"
+    "ai_seller/storage"
+)
+
+// Префиксы параметра ссылки t.me/<бот>?start=<параметр>
+const (
+    // productLinkPrefix — карточка товара: product_<id>
+    productLinkPrefix = "product_"
+    // referralLinkPrefix — приглашение от покупателя: ref_<chat_id>
+    referralLinkPrefix = "ref_"
+)
+
+// Виды параметра /start
+const (
+    startLinkNone = iota
+    startLinkCampaign
+    startLinkProduct
+    startLinkReferral
+)
+
+// startLink — разобранный параметр /start
+type startLink struct {
+    kind int
+    // code — параметр как есть
+    code string
+    // id — товар для startLinkProduct, пригласивший для startLinkReferral
+    id int64
+}
+
+// parseStartLink разбирает параметр /start. Коды кампаний сверяются
+// с CAMPAIGN_CODES; неизвестный параметр — startLinkNone.
+func parseStartLink(payload string, campaigns []string) startLink {
+    payload = strings.TrimSpace(payload)
+    if payload == "" {
+        return startLink{}
+    }
+    if rest, ok := strings.CutPrefix(payload, productLinkPrefix); ok {
+        if id, err := strconv.ParseInt(rest, 10, 64); err == nil && id > 0 {
+            return startLink{kind: startLinkProduct, code: payload, id: id}
+        }
+    }
+    if rest, ok := strings.CutPrefix(payload, referralLinkPrefix); ok {
+        if id, err := strconv.ParseInt(rest, 10, 64); err == nil && id > 0 {
+            return startLink{kind: startLinkReferral, code: payload, id: id}
+        }
+    }
+    if slices.Contains(campaigns, payload) {
+        return startLink{kind: startLinkCampaign, code: payload}
+    }
+    return startLink{code: payload}
+}
+
+// followStartLink исполняет параметр /start после приветствия: записывает
+// кампанию или пригласившего и показывает товар из ссылки. seen — покупатель
+// уже писал боту: приглашение засчитывается только новому покупателю и
+// только от существующего, иначе ref_<любой id> приписал бы его кому угодно.
+// Ошибки только логируются — приветствие уже отправлено.
+func (b *Bot) followStartLink(ctx context.Context, chatID int64, link startLink, seen bool) {
+    log := logging.FromContext(ctx)
+    switch link.kind {
+    case startLinkCampaign:
+        b.recordAttribution(ctx, chatID, storage.Attribution{Kind: storage.AttributionCampaign, Code: link.code})
+    case startLinkReferral:
+        if seen || link.id == chatID {
+            return
+        }
+        if _, err := b.profile(ctx, link.id); err != nil {
+            if errors.Is(err, storage.ErrNotFound) {
+                log.Info("пригласивший из ссылки не найден", "chat_id", chatID, "referrer_id", link.id)
+            } else {
+                log.Error("ошибка проверки пригласившего", "chat_id", chatID, "referrer_id", link.id, "err", err)
+            }
+            return
+        }
+        b.recordAttribution(ctx, chatID, storage.Attribution{Kind: storage.AttributionReferral, Code: link.code, ReferrerID: link.id})
+    case startLinkProduct:
+        p, err := b.Catalog.GetProduct(ctx, link.id)
+        if errors.Is(err, storage.ErrNotFound) {
+            log.Info("товар из ссылки не найден", "chat_id", chatID, "product_id", link.id)
+            return
+        }
+        if err != nil {
+            log.Error("ошибка чтения товара из ссылки", "chat_id", chatID, "product_id", link.id, "err", err)
+            return
+        }
+        b.showProduct(ctx, chatID, p)
+    default:
+        if link.code != "" {
+            log.Info("неизвестный параметр /start", "chat_id", chatID, "payload", link.code)
+        }
+    }
+}
+
+// recordAttribution сохраняет источник покупателя, если хранилище подключено
+func (b *Bot) recordAttribution(ctx context.Context, chatID int64, a storage.Attribution) {
+    if b.Attributions == nil {
+        return
+    }
+    recorded, err := b.Attributions.Record(ctx, chatID, a)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка сохранения источника покупателя", "chat_id", chatID, "kind", a.Kind, "err", err)
+        return
+    }
+    if recorded {
+        logging.FromContext(ctx).Info("источник покупателя записан", "chat_id", chatID, "kind", a.Kind, "code", a.Code, "referrer_id", a.ReferrerID)
+    }
+}
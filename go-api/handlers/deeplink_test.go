@@ -0,0 +1,126 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/memstore"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// deeplinkBot — бот с учётом источников и кампанией promo123
+func deeplinkBot(t *testing.T) (*testBot, *memstore.Attributions) {
+    t.Helper()
+    attributions := &memstore.Attributions{}
+    tb := newTestBot(t, map[string]string{"CAMPAIGN_CODES": "promo123"}, func(d *Deps) { d.Attributions = attributions })
+    return tb, attributions
+}
+
+func TestParseStartLink(t *testing.T) {
+    campaigns := []string{"promo123"}
+    tests := []struct {
+        payload string
+        want    startLink
+    }{
+        {"", startLink{}},
+        {"promo123", startLink{kind: startLinkCampaign, code: "promo123"}},
+        {"product_5", startLink{kind: startLinkProduct, code: "product_5", id: 5}},
+        {"ref_77", startLink{kind: startLinkReferral, code: "ref_77", id: 77}},
+        {"product_0", startLink{code: "product_0"}},
+        {"ref_abc", startLink{code: "ref_abc"}},
+        {"promo999", startLink{code: "promo999"}},
+    }
+    for _, tt := range tests {
+        if got := parseStartLink(tt.payload, campaigns); got != tt.want {
+            t.Errorf("parseStartLink(%q) = %+v, ожидалось %+v", tt.payload, got, tt.want)
+        }
+    }
+}
+
+func TestStartCampaignRecordsAttribution(t *testing.T) {
+    tb, attributions := deeplinkBot(t)
+    tb.process(t, text(1, 42, "/start promo123"))
+
+    a, ok := attributions.Get(42)
+    if !ok || a.Kind != storage.AttributionCampaign || a.Code != "promo123" {
+        t.Fatalf("источник %+v (%v), ожидалась кампания promo123", a, ok)
+    }
+    if got := tb.sentTo(42); len(got) == 0 || got[0] != tb.Config.WelcomeMessage {
+        t.Fatalf("отправлено %q, ожидалось приветствие", got)
+    }
+}
+
+func TestStartProductShowsProduct(t *testing.T) {
+    tb, attributions := deeplinkBot(t)
+    tb.catalog.Add(storage.Product{ID: 5, Name: "Улун молочный", Price: money.New(45000, "RUB"), InStock: true})
+    tb.process(t, text(1, 42, "/start product_5"))
+
+    got := tb.sentTo(42)
+    if len(got) < 2 || got[0] != tb.Config.WelcomeMessage || !strings.Contains(got[len(got)-1], "Улун молочный") {
+        t.Fatalf("отправлено %q, ожидались приветствие и карточка товара", got)
+    }
+    if _, ok := attributions.Get(42); ok {
+        t.Fatal("ссылка на товар записана как источник")
+    }
+}
+
+func TestStartUnknownPayloadWelcomes(t *testing.T) {
+    plain, _ := deeplinkBot(t)
+    plain.process(t, text(1, 42, "/start"))
+    want := strings.Join(plain.sentTo(42), "\n")
+
+    for _, payload := range []string{"promo999", "product_404", "ref_abc"} {
+        t.Run(payload, func(t *testing.T) {
+            tb, attributions := deeplinkBot(t)
+            tb.process(t, text(1, 42, "/start "+payload))
+
+            if got := strings.Join(tb.sentTo(42), "\n"); got != want {
+                t.Fatalf("отправлено %q, ожидалось обычное приветствие %q", got, want)
+            }
+            if _, ok := attributions.Get(42); ok {
+                t.Fatal("неизвестный параметр записан как источник")
+            }
+        })
+    }
+}
+
+func TestStartReferral(t *testing.T) {
+    const referrer int64 = 77
+    tests := []struct {
+        name     string
+        payload  string
+        known    bool
+        seen     bool
+        recorded bool
+    }{
+        {name: "существующий покупатель", payload: "ref_77", known: true, recorded: true},
+        {name: "несуществующий пригласивший", payload: "ref_77"},
+        {name: "сам себя", payload: "ref_42", known: true},
+        {name: "покупатель уже писал", payload: "ref_77", known: true, seen: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tb, attributions := deeplinkBot(t)
+            ctx := context.Background()
+            if tt.known {
+                if err := tb.users.Upsert(ctx, referrer, "friend", "ru", "Друг"); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            if tt.seen {
+                _ = tb.messages.SaveMessage(ctx, 42, "user", "привет")
+            }
+            tb.process(t, text(1, 42, "/start "+tt.payload))
+
+            a, ok := attributions.Get(42)
+            if ok != tt.recorded {
+                t.Fatalf("приглашение записано: %v, ожидалось %v", ok, tt.recorded)
+            }
+            if ok && (a.Kind != storage.AttributionReferral || a.ReferrerID != referrer) {
+                t.Fatalf("источник %+v, ожидалось приглашение от %d", a, referrer)
+            }
+        })
+    }
+}
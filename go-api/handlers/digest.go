@@ -0,0 +1,162 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// digestCheckInterval — как часто проверять, не пора ли отправить сводку
+const digestCheckInterval = time.Minute
+
+// SendOrderDigests раз в digestCheckInterval проверяет, закончился ли
+// очередной период сводки (Config.DigestSchedule), и отправляет сводку
+// админам. Период считается по часам, а не по тикам: пропущенный тик или
+// перезапуск не сдвигают окно, а сводка за период уходит один раз.
+// Работает до отмены ctx.
+func (b *Bot) SendOrderDigests(ctx context.Context) {
+    ticker := time.NewTicker(digestCheckInterval)
+    defer ticker.Stop()
+    for {
+        b.sendDueDigest(ctx, time.Now())
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// sendDueDigest отправляет сводку за последний завершившийся период, если
+// её ещё не отправляли
+func (b *Bot) sendDueDigest(ctx context.Context, now time.Time) {
+    loc := b.Config.DigestLocation
+    from, to := digestWindow(now, loc, b.Config.DigestAt, b.Config.DigestSchedule == "weekly")
+
+    // Заказы читаем до отметки: если база недоступна, попробуем на следующем тике
+    orders, err := b.Orders.OrdersBetween(ctx, from, to)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка выборки заказов для сводки", "from", from, "to", to, "err", err)
+        return
+    }
+    claimed, err := b.Digests.Claim(ctx, to)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка отметки сводки заказов", "err", err)
+        return
+    }
+    if !claimed {
+        return
+    }
+
+    text := renderDigest(from, to, loc, orders)
+    var report []byte
+    if b.Config.DigestCSV && len(orders) > 0 {
+        if report, err = digestCSV(orders, loc); err != nil {
+            logging.FromContext(ctx).Error("ошибка подготовки CSV сводки", "err", err)
+        }
+    }
+    filename := "orders-" + from.In(loc).Format("2006-01-02") + ".csv"
+
+    for chatID := range b.Config.AdminChatIDs {
+        b.reply(ctx, chatID, text)
+        if report == nil {
+            continue
+        }
+        if err := b.Telegram.SendDocument(ctx, chatID, filename, report, ""); err != nil {
+            logging.FromContext(ctx).Error("ошибка отправки CSV сводки", "chat_id", chatID, "err", err)
+        }
+    }
+    logging.FromContext(ctx).Info("сводка заказов отправлена", "from", from, "to", to, "orders", len(orders))
+}
+
+// digestWindow — последний завершившийся на момент now период сводки:
+// сутки или неделя (с понедельника), закончившиеся в at по часам loc.
+// Границы берутся по календарю, а не сдвигом на 24 часа, поэтому сутки
+// перехода на летнее или зимнее время короче или длиннее — как и на часах.
+func digestWindow(now time.Time, loc *time.Location, at time.Duration, weekly bool) (from, to time.Time) {
+    local := now.In(loc)
+    hour, minute := int(at/time.Hour), int(at%time.Hour/time.Minute)
+    to = time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+
+    days := 1
+    if weekly {
+        days = 7
+        sinceMonday := (int(to.Weekday()) + 6) % 7
+        to = to.AddDate(0, 0, -sinceMonday)
+    }
+    if to.After(now) {
+        to = to.AddDate(0, 0, -days)
+    }
+    return to.AddDate(0, 0, -days), to
+}
+
+// renderDigest — текст сводки: число заказов по статусам и выручка.
+// Выручка — оплаченные и отправленные заказы, по каждой валюте отдельно.
+func renderDigest(from, to time.Time, loc *time.Location, orders []storage.OrderSummary) string {
+    byStatus := make(map[storage.OrderStatus]int)
+    revenue := make(map[string]money.Money)
+    var currencies []string
+    for _, o := range orders {
+        byStatus[o.Status]++
+        if o.Status != storage.OrderPaid && o.Status != storage.OrderShipped {
+            continue
+        }
+        sum, seen := revenue[o.Total.Currency]
+        if !seen {
+            currencies = append(currencies, o.Total.Currency)
+        }
+        // Валюта одна и та же — ошибки сложения быть не может
+        revenue[o.Total.Currency], _ = sum.Add(o.Total)
+    }
+
+    period := from.In(loc).Format("02.01.2006")
+    if last := to.In(loc).AddDate(0, 0, -1); !sameDay(from.In(loc), last) {
+        period = from.In(loc).Format("02.01") + "–" + last.Format("02.01.2006")
+    }
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "📊 Сводка заказов за %s\n", period)
+    fmt.Fprintf(&sb, "Заказов: %d (новых %d, оплачено %d, отправлено %d, отменено %d)\n",
+        len(orders), byStatus[storage.OrderNew], byStatus[storage.OrderPaid],
+        byStatus[storage.OrderShipped], byStatus[storage.OrderCancelled])
+    if len(currencies) == 0 {
+        sb.WriteString("Выручка: 0")
+        return sb.String()
+    }
+    parts := make([]string, len(currencies))
+    for i, c := range currencies {
+        parts[i] = revenue[c].Format("ru")
+    }
+    sb.WriteString("Выручка: " + strings.Join(parts, ", "))
+    return sb.String()
+}
+
+func sameDay(a, b time.Time) bool {
+    return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// digestCSV — список заказов периода для выгрузки в таблицу
+func digestCSV(orders []storage.OrderSummary, loc *time.Location) ([]byte, error) {
+    var buf bytes.Buffer
+    w := csv.NewWriter(&buf)
+    w.Write([]string{"id", "created_at", "status", "total", "currency"})
+    for _, o := range orders {
+        w.Write([]string{
+            strconv.FormatInt(o.ID, 10),
+            o.CreatedAt.In(loc).Format(time.RFC3339),
+            string(o.Status),
+            strconv.FormatFloat(o.Total.Major(), 'f', 2, 64),
+            o.Total.Currency,
+        })
+    }
+    w.Flush()
+    return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+    "time"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// Окно сводки считается по календарю часового пояса: сутки перехода на
+// летнее время короче, на зимнее — длиннее, а пропущенный тик не сдвигает
+// границы
+func TestDigestWindowAcrossDST(t *testing.T) {
+    berlin, err := time.LoadLocation("Europe/Berlin")
+    if err != nil {
+        t.Fatal(err)
+    }
+    at := 9 * time.Hour
+    local := func(month time.Month, day, hour, minute int) time.Time {
+        return time.Date(2026, month, day, hour, minute, 0, 0, berlin)
+    }
+    cases := []struct {
+        name     string
+        now      time.Time
+        weekly   bool
+        from, to time.Time
+        length   time.Duration
+    }{
+        {"обычные сутки", local(time.March, 20, 10, 0), false,
+            local(time.March, 19, 9, 0), local(time.March, 20, 9, 0), 24 * time.Hour},
+        {"переход на летнее", local(time.March, 29, 9, 0), false,
+            local(time.March, 28, 9, 0), local(time.March, 29, 9, 0), 23 * time.Hour},
+        {"переход на зимнее", local(time.October, 25, 23, 59), false,
+            local(time.October, 24, 9, 0), local(time.October, 25, 9, 0), 25 * time.Hour},
+        {"до времени отправки — прошлые сутки", local(time.March, 30, 8, 59), false,
+            local(time.March, 28, 9, 0), local(time.March, 29, 9, 0), 23 * time.Hour},
+        {"пропущенный тик", local(time.March, 30, 15, 37), false,
+            local(time.March, 29, 9, 0), local(time.March, 30, 9, 0), 24 * time.Hour},
+        {"неделя с переходом", local(time.March, 31, 12, 0), true,
+            local(time.March, 23, 9, 0), local(time.March, 30, 9, 0), 7*24*time.Hour - time.Hour},
+        {"неделя до понедельника 9:00", local(time.March, 30, 8, 0), true,
+            local(time.March, 16, 9, 0), local(time.March, 23, 9, 0), 7 * 24 * time.Hour},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            // Сервер может жить в UTC: результат не зависит от пояса now
+            from, to := digestWindow(tc.now.UTC(), berlin, at, tc.weekly)
+            if !from.Equal(tc.from) || !to.Equal(tc.to) {
+                t.Fatalf("окно %s — %s, нужно %s — %s", from, to, tc.from, tc.to)
+            }
+            if got := to.Sub(from); got != tc.length {
+                t.Fatalf("длина окна %s, нужно %s", got, tc.length)
+            }
+        })
+    }
+}
+
+func TestRenderDigest(t *testing.T) {
+    moscow, _ := time.LoadLocation("Europe/Moscow")
+    from := time.Date(2026, time.March, 19, 9, 0, 0, 0, moscow)
+    orders := []storage.OrderSummary{
+        {ID: 1, Status: storage.OrderPaid, Total: money.New(149900, "RUB")},
+        {ID: 2, Status: storage.OrderShipped, Total: money.New(50000, "RUB")},
+        {ID: 3, Status: storage.OrderNew, Total: money.New(990000, "RUB")},
+        {ID: 4, Status: storage.OrderCancelled, Total: money.New(10000, "RUB")},
+        {ID: 5, Status: storage.OrderPaid, Total: money.New(2500, "USD")},
+    }
+    got := renderDigest(from, from.AddDate(0, 0, 1), moscow, orders)
+    for _, want := range []string{"за 19.03.2026", "Заказов: 5 (новых 1, оплачено 2, отправлено 1, отменено 1)", "Выручка: 1\u00a0999\u00a0₽, 25\u00a0$"} {
+        if !strings.Contains(got, want) {
+            t.Errorf("в сводке нет %q:\n%s", want, got)
+        }
+    }
+    if week := renderDigest(from, from.AddDate(0, 0, 7), moscow, nil); !strings.Contains(week, "за 19.03–25.03.2026") || !strings.Contains(week, "Выручка: 0") {
+        t.Errorf("недельная сводка:\n%s", week)
+    }
+}
+
+// Сводка за период уходит один раз, сколько бы тиков ни пришлось на него
+func TestDigestSentOncePerWindow(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ORDER_DIGEST": "daily", "ADMIN_CHAT_IDS": "1"})
+    ctx := context.Background()
+    now := time.Date(2026, time.March, 20, 10, 0, 0, 0, tb.Config.DigestLocation)
+
+    tb.sendDueDigest(ctx, now)
+    tb.sendDueDigest(ctx, now.Add(time.Hour))
+    if got := tb.sentTo(1); len(got) != 1 || !strings.Contains(got[0], "за 19.03.2026") {
+        t.Fatalf("отправлено %q, нужна одна сводка за 19.03", got)
+    }
+    tb.sendDueDigest(ctx, now.AddDate(0, 0, 1))
+    if got := tb.sentTo(1); len(got) != 2 || !strings.Contains(got[1], "за 20.03.2026") {
+        t.Fatalf("на следующие сутки отправлено %q", got)
+    }
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// restoreDraftAction — действие кнопки «вернуть черновик в корзину»; данные — id заказа
+const restoreDraftAction = "restore"
+
+const (
+    draftOfferReply    = "Вы не закончили оформление заказа. Вернуть эти товары в корзину?"
+    draftRestoreButton = "🛒 Вернуть в корзину"
+    draftRestoredReply = "Товары снова в корзине. Посмотреть — /cart, оформить заказ — /checkout"
+    draftGoneReply     = "Этот заказ уже не вернуть в корзину — соберите её заново в /catalog."
+)
+
+// offerDraft предлагает вернуть в корзину черновик заказа, брошенный посреди
+// /checkout (например, процесс упал между созданием заказа и его
+// подтверждением), если корзина при этом пуста. Черновики старше
+// DraftRestoreWindow не предлагаются. Ошибки только логируются: сообщение
+// покупателя обрабатывается дальше как обычно.
+func (b *Bot) offerDraft(ctx context.Context, chatID int64) {
+    window := b.Config.DraftRestoreWindow
+    if window <= 0 {
+        return
+    }
+    log := logging.FromContext(ctx)
+
+    cart, err := b.Carts.GetCart(ctx, chatID)
+    if err != nil {
+        log.Warn("не удалось проверить корзину перед предложением черновика", "chat_id", chatID, "err", err)
+        return
+    }
+    if !cart.Empty() {
+        return
+    }
+    draft, err := b.Orders.LatestDraft(ctx, chatID, time.Now().Add(-window))
+    if errors.Is(err, storage.ErrNotFound) {
+        return
+    }
+    if err != nil {
+        log.Warn("ошибка поиска черновика заказа", "chat_id", chatID, "err", err)
+        return
+    }
+    if b.DraftOffers != nil {
+        claimed, err := b.DraftOffers.Claim(ctx, draft.ID, window)
+        if err != nil {
+            log.Warn("не удалось отметить предложенный черновик", "chat_id", chatID, "order_id", draft.ID, "err", err)
+            return
+        }
+        if !claimed {
+            return
+        }
+    }
+
+    lang := reqctx.LangFromContext(ctx)
+    var sb strings.Builder
+    sb.WriteString(i18n.T(lang, draftOfferReply))
+    for _, item := range draft.Items {
+        fmt.Fprintf(&sb, "\n• %s × %d", item.Name, item.Qty)
+    }
+    kb := telegram.NewInlineKeyboard().
+        Row(telegram.CallbackButton(i18n.T(lang, draftRestoreButton), fmt.Sprintf("%s:%d", restoreDraftAction, draft.ID)))
+    log.Info("предложен черновик заказа", "chat_id", chatID, "order_id", draft.ID)
+    // Простой текст: в названиях товаров могут быть символы разметки
+    b.send(ctx, chatID, sb.String(), telegram.WithReplyMarkup(kb), telegram.WithParseMode(""))
+}
+
+// cbRestoreDraft — нажатие кнопки под предложением черновика: черновик
+// отменяется, остатки возвращаются на склад, а позиции — в корзину.
+// Черновик отменяется первым, поэтому повторное нажатие не удвоит корзину.
+func (b *Bot) cbRestoreDraft(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    chatID := cq.Message.Chat.ID
+    orderID, err := strconv.ParseInt(payload, 10, 64)
+    if err != nil {
+        return fmt.Errorf("некорректный id заказа %q: %w", payload, err)
+    }
+
+    draft, err := b.Orders.GetOrder(ctx, orderID, chatID)
+    if errors.Is(err, storage.ErrNotFound) {
+        b.replyPhrase(ctx, chatID, draftGoneReply)
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if draft.Status != storage.OrderDraft || time.Since(draft.CreatedAt) > b.Config.DraftRestoreWindow {
+        b.replyPhrase(ctx, chatID, draftGoneReply)
+        return nil
+    }
+
+    err = b.Orders.DiscardDraft(ctx, orderID)
+    if errors.Is(err, storage.ErrNotFound) {
+        b.replyPhrase(ctx, chatID, draftGoneReply)
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    for _, item := range draft.Items {
+        if err := b.Carts.AddItem(ctx, chatID, item.ProductID, item.Qty); err != nil {
+            return err
+        }
+    }
+    logging.FromContext(ctx).Info("черновик заказа возвращён в корзину", "chat_id", chatID, "order_id", orderID)
+    b.noteShopping(ctx)
+    b.replyPhrase(ctx, chatID, draftRestoredReply)
+    return nil
+}
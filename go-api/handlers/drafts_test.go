@@ -0,0 +1,159 @@
+package handlers
+
+import (
+    "context"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// restoreDraft — нажатие кнопки «вернуть в корзину» под предложением черновика
+func restoreDraft(updateID, chatID int64, payload string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, CallbackQuery: &TelegramCallbackQuery{
+        ID:      "restore",
+        From:    &TelegramUser{ID: chatID, LanguageCode: "ru"},
+        Data:    restoreDraftAction + ":" + payload,
+        Message: &TelegramMessage{MessageID: 1, Chat: TelegramChat{ID: chatID, Type: "private"}},
+    }}
+}
+
+// abandonDraft — черновик заказа в чате 42, брошенный посреди /checkout:
+// заказ создан, а корзина уже очищена
+func abandonDraft(t *testing.T, tb *testBot) int64 {
+    t.Helper()
+    items := []storage.OrderItem{
+        {ProductID: 1, Qty: 2, Price: money.New(10000, "RUB"), Name: "Сенча"},
+        {ProductID: 2, Qty: 1, Price: money.New(10000, "RUB"), Name: "Улун"},
+    }
+    id, err := tb.orders.CreateOrder(context.Background(), 42, items, money.New(30000, "RUB"), "draft", time.Minute)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return id
+}
+
+// draftButton — callback_data кнопки под сообщением или пусто
+func draftButton(m telegram.ReplyMarkup) string {
+    kb, ok := m.(*telegram.InlineKeyboard)
+    if !ok || kb == nil || len(kb.Rows) != 1 || len(kb.Rows[0]) != 1 {
+        return ""
+    }
+    return kb.Rows[0][0].CallbackData
+}
+
+// Брошенный черновик предлагается один раз: на первое сообщение при пустой
+// корзине, с составом заказа и кнопкой; само сообщение обрабатывается как обычно
+func TestDraftRestoreOffered(t *testing.T) {
+    tb := newTestBot(t, nil)
+    id := abandonDraft(t, tb)
+
+    tb.process(t, text(1, 42, "привет"))
+    sent := tb.tg.Messages()
+    if len(sent) != 2 || !strings.Contains(sent[0].Text, draftOfferReply) ||
+        !strings.Contains(sent[0].Text, "Сенча × 2") || !strings.Contains(sent[0].Text, "Улун × 1") {
+        t.Fatalf("отправлено %+v, нужны предложение черновика и ответ модели", sent)
+    }
+    if got, want := draftButton(sent[0].Markup), restoreDraftAction+":"+strconv.FormatInt(id, 10); got != want {
+        t.Fatalf("кнопка %q, ожидалась %q", got, want)
+    }
+    if sent[1].Text != "ответ модели" {
+        t.Fatalf("после предложения отправлено %q", sent[1].Text)
+    }
+
+    tb.process(t, text(2, 42, "есть улун?"))
+    if got := tb.sentTo(42); len(got) != 3 || strings.Contains(got[2], draftOfferReply) {
+        t.Fatalf("черновик предложен повторно: %q", got)
+    }
+}
+
+// Пока в корзине есть товары, черновик не предлагается; подтверждённый
+// заказ — не черновик
+func TestDraftRestoreNotOffered(t *testing.T) {
+    t.Run("корзина не пуста", func(t *testing.T) {
+        tb := newTestBot(t, nil)
+        abandonDraft(t, tb)
+        if err := tb.carts.AddItem(context.Background(), 42, 3, 1); err != nil {
+            t.Fatal(err)
+        }
+        tb.process(t, text(1, 42, "привет"))
+        if got := tb.sentTo(42); len(got) != 1 || got[0] != "ответ модели" {
+            t.Fatalf("отправлено %q, нужен только ответ модели", got)
+        }
+    })
+
+    t.Run("заказ оформлен", func(t *testing.T) {
+        tb := newTestBot(t, nil)
+        id := abandonDraft(t, tb)
+        if err := tb.orders.ConfirmOrder(context.Background(), id); err != nil {
+            t.Fatal(err)
+        }
+        tb.process(t, text(1, 42, "привет"))
+        if got := tb.sentTo(42); len(got) != 1 || got[0] != "ответ модели" {
+            t.Fatalf("отправлено %q, нужен только ответ модели", got)
+        }
+    })
+}
+
+// Кнопка возвращает позиции в корзину и отменяет черновик; повторное
+// нажатие корзину не удваивает, а то же оформление создаёт новый заказ
+func TestDraftRestoreAccepted(t *testing.T) {
+    tb := newTestBot(t, nil)
+    fillCatalog(tb, 2)
+    id := abandonDraft(t, tb)
+    ctx := context.Background()
+
+    tb.process(t, restoreDraft(1, 42, strconv.FormatInt(id, 10)))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != draftRestoredReply {
+        t.Fatalf("отправлено %q, ожидалось %q", got, draftRestoredReply)
+    }
+    cart, err := tb.carts.GetCart(ctx, 42)
+    if err != nil || len(cart.Items) != 2 || cart.Items[0].Qty != 2 || cart.Items[1].Qty != 1 {
+        t.Fatalf("корзина %+v, %v", cart.Items, err)
+    }
+    if st, _ := tb.orders.OrderState(ctx, id); st.Status != storage.OrderCancelled {
+        t.Fatalf("статус черновика %s, ожидался %s", st.Status, storage.OrderCancelled)
+    }
+
+    tb.process(t, restoreDraft(2, 42, strconv.FormatInt(id, 10)))
+    if got := tb.sentTo(42); len(got) != 2 || got[1] != draftGoneReply {
+        t.Fatalf("повторное нажатие: отправлено %q", got)
+    }
+    if cart, _ := tb.carts.GetCart(ctx, 42); cart.Items[0].Qty != 2 {
+        t.Fatalf("корзина после повторного нажатия %+v", cart.Items)
+    }
+
+    tb.process(t, text(3, 42, "/checkout"))
+    orders, _ := tb.orders.ChatOrders(ctx, 42)
+    if len(orders) != 2 || orders[1].ID == id || orders[1].Status != storage.OrderNew {
+        t.Fatalf("заказы после оформления %+v", orders)
+    }
+}
+
+// Черновик старше DRAFT_RESTORE_WINDOW не предлагается, а кнопка под
+// старым предложением его уже не возвращает
+func TestDraftRestoreExpired(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"DRAFT_RESTORE_WINDOW": "50ms"})
+    id := abandonDraft(t, tb)
+    time.Sleep(100 * time.Millisecond)
+
+    tb.process(t, text(1, 42, "привет"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("отправлено %q, нужен только ответ модели", got)
+    }
+
+    tb.process(t, restoreDraft(2, 42, strconv.FormatInt(id, 10)))
+    if got := tb.sentTo(42); len(got) != 2 || got[1] != draftGoneReply {
+        t.Fatalf("нажатие после окна: отправлено %q", got)
+    }
+    if cart, _ := tb.carts.GetCart(context.Background(), 42); !cart.Empty() {
+        t.Fatalf("корзина %+v, ожидалась пустая", cart.Items)
+    }
+    if st, _ := tb.orders.OrderState(context.Background(), id); st.Status != storage.OrderDraft {
+        t.Fatalf("статус %s, черновик не должен меняться", st.Status)
+    }
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+    "strings"
+    "unicode/utf16"
+)
+
+// TelegramEntity — размеченный фрагмент текста: команда, упоминание, ссылка.
+// Offset и Length считаются в единицах UTF-16, как их отдаёт Telegram.
+type TelegramEntity struct {
+    Type   string `json:"type"`
+    Offset int    `json:"offset"`
+    Length int    `json:"length"`
+    // URL — адрес ссылки для type=text_link
+    URL string `json:"url"`
+    // User — упомянутый пользователь для type=text_mention (упоминание
+    // без username)
+    User *TelegramUser `json:"user"`
+}
+
+// entityTexts возвращает текст каждой сущности; сущности, выходящие за
+// границы текста (битый апдейт), пропускаются
+func entityTexts(text string, entities []TelegramEntity, types ...string) []string {
+    var units []uint16
+    var out []string
+    for _, e := range entities {
+        if !matchesType(e.Type, types) {
+            continue
+        }
+        if units == nil {
+            units = utf16.Encode([]rune(text))
+        }
+        if e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > len(units) {
+            continue
+        }
+        out = append(out, string(utf16.Decode(units[e.Offset:e.Offset+e.Length])))
+    }
+    return out
+}
+
+func matchesType(t string, types []string) bool {
+    for _, want := range types {
+        if t == want {
+            return true
+        }
+    }
+    return false
+}
+
+// command находит команду по сущности bot_command — в том числе в середине
+// текста («покажите /catalog»). Аргументы — текст после команды. Если
+// сущностей нет (апдейт собран вручную, повтор из журнала), команда
+// разбирается по префиксу, как раньше.
+func (m *TelegramMessage) command() (name, args string, ok bool) {
+    // У файлов команда пишется в подписи
+    text, entities := m.Text, m.Entities
+    if text == "" {
+        text, entities = m.Caption, m.CaptionEntities
+    }
+    if len(entities) == 0 {
+        return parseCommand(text)
+    }
+
+    units := utf16.Encode([]rune(text))
+    for _, e := range entities {
+        if e.Type != "bot_command" || e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > len(units) {
+            continue
+        }
+        cmd := string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+        rest := string(utf16.Decode(units[e.Offset+e.Length:]))
+        return parseCommand(cmd + rest)
+    }
+    return "", "", false
+}
+
+// Links — ссылки из сообщения: явные URL и ссылки, спрятанные под текстом
+func (m *TelegramMessage) Links() []string {
+    text, entities := m.Text, m.Entities
+    if text == "" {
+        text, entities = m.Caption, m.CaptionEntities
+    }
+    links := entityTexts(text, entities, "url")
+    for _, e := range entities {
+        if e.Type == "text_link" && e.URL != "" {
+            links = append(links, e.URL)
+        }
+    }
+    return links
+}
+
+// Emails — адреса почты, которые Telegram распознал в сообщении
+func (m *TelegramMessage) Emails() []string {
+    text, entities := m.Text, m.Entities
+    if text == "" {
+        text, entities = m.Caption, m.CaptionEntities
+    }
+    emails := entityTexts(text, entities, "email")
+    for i, e := range emails {
+        emails[i] = strings.ToLower(e)
+    }
+    return emails
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+    "slices"
+    "strings"
+    "testing"
+    "unicode/utf16"
+)
+
+// entity — сущность typ на первом вхождении fragment в text, со смещением
+// в единицах UTF-16, как считает Telegram
+func entity(text, typ, fragment string) TelegramEntity {
+    i := strings.Index(text, fragment)
+    return TelegramEntity{
+        Type:   typ,
+        Offset: len(utf16.Encode([]rune(text[:i]))),
+        Length: len(utf16.Encode([]rune(fragment))),
+    }
+}
+
+// Эмодзи до сущностей занимают по две единицы UTF-16: смещения не должны
+// съезжать ни для команды, ни для ссылок и почты
+func TestMultiEntityMessage(t *testing.T) {
+    body := "🍵🍵 Привет @shop_bot! Покажите /search@shop_bot улун, прайс на https://tea.example/price, пишите на Sales@Tea.Example"
+    msg := &TelegramMessage{Text: body, Entities: []TelegramEntity{
+        entity(body, "mention", "@shop_bot"),
+        entity(body, "bot_command", "/search@shop_bot"),
+        entity(body, "url", "https://tea.example/price"),
+        entity(body, "email", "Sales@Tea.Example"),
+        {Type: "text_link", Offset: 0, Length: 2, URL: "https://tea.example/promo"},
+        {Type: "url", Offset: 1000, Length: 5},
+    }}
+
+    name, args, ok := msg.command()
+    if !ok || name != "search" || !strings.HasPrefix(args, "улун, прайс") {
+        t.Fatalf("команда %q с аргументами %q (%v)", name, args, ok)
+    }
+    if got, want := msg.Links(), []string{"https://tea.example/price", "https://tea.example/promo"}; !slices.Equal(got, want) {
+        t.Errorf("ссылки %q, нужно %q", got, want)
+    }
+    if got := msg.Emails(); !slices.Equal(got, []string{"sales@tea.example"}) {
+        t.Errorf("почта %q", got)
+    }
+    if got := entityTexts(body, msg.Entities, "mention"); !slices.Equal(got, []string{"@shop_bot"}) {
+        t.Errorf("упоминания %q", got)
+    }
+}
+
+func TestCommandFromEntities(t *testing.T) {
+    cases := []struct {
+        name     string
+        msg      *TelegramMessage
+        wantName string
+        wantArgs string
+        ok       bool
+    }{
+        {"без сущностей — по префиксу", &TelegramMessage{Text: "/Cart сейчас"}, "cart", "сейчас", true},
+        {"слэш без сущности команды — не команда", &TelegramMessage{Text: "скидка 10/20 или /cart", Entities: []TelegramEntity{{Type: "bold", Offset: 0, Length: 6}}}, "", "", false},
+        {"битая сущность пропускается", &TelegramMessage{Text: "/cart", Entities: []TelegramEntity{{Type: "bot_command", Offset: 3, Length: 10}}}, "", "", false},
+        {"в подписи к фото", func() *TelegramMessage {
+            caption := "фото 📷 /help"
+            return &TelegramMessage{Caption: caption, CaptionEntities: []TelegramEntity{entity(caption, "bot_command", "/help")}}
+        }(), "help", "", true},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            name, args, ok := tc.msg.command()
+            if name != tc.wantName || args != tc.wantArgs || ok != tc.ok {
+                t.Fatalf("получено %q %q %v, нужно %q %q %v", name, args, ok, tc.wantName, tc.wantArgs, tc.ok)
+            }
+        })
+    }
+}
+
+// Команда в середине фразы выполняется, а не уходит модели
+func TestCommandMidMessage(t *testing.T) {
+    tb := newTestBot(t, nil)
+    update := text(1, 42, "Подскажите, 🙏 /catalog пожалуйста")
+    update.Message.Entities = []TelegramEntity{entity(update.Message.Text, "bot_command", "/catalog")}
+    tb.process(t, update)
+
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != emptyCatalogReply {
+        t.Fatalf("отправлено %q, нужен ответ /catalog", got)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatal("команда ушла модели")
+    }
+}
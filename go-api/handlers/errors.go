@@ -0,0 +1,41 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+)
+
+const (
+    // invalidRequestReply — ответ на ErrValidation без своего текста
+    invalidRequestReply = "Не получилось разобрать запрос, попробуйте ещё раз."
+    // notFoundReply — ответ на ErrNotFound без своего текста
+    notFoundReply = "Ничего не найдено."
+    // internalErrorReply — ответ на ошибку без категории (сбой БД, Redis)
+    internalErrorReply = "Что-то пошло не так, попробуйте ещё раз позже."
+)
+
+// replyError отвечает пользователю по категории ошибки и пишет её в лог:
+// ошибки пользователя — на уровне Info, остальные — Error
+func (b *Bot) replyError(ctx context.Context, chatID int64, err error) {
+    logging.FromContext(ctx).Log(ctx, apperr.Level(err), "ошибка обработки запроса", "chat_id", chatID, "err", err)
+    b.replyPhrase(ctx, chatID, b.errorReply(err))
+}
+
+// errorReply — текст ответа на ошибку: свой текст ошибки, если он задан, иначе по категории
+func (b *Bot) errorReply(err error) string {
+    if msg := apperr.UserMessage(err); msg != "" {
+        return msg
+    }
+    switch {
+    case errors.Is(err, apperr.ErrValidation):
+        return invalidRequestReply
+    case errors.Is(err, apperr.ErrNotFound):
+        return notFoundReply
+    case errors.Is(err, apperr.ErrUpstream):
+        return b.Config.FallbackMessage
+    }
+    return internalErrorReply
+}
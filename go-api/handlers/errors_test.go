@@ -0,0 +1,127 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "slices"
+    "sync"
+    "testing"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+)
+
+// logEntry — запись лога с атрибутами, приведёнными к строкам
+type logEntry struct {
+    msg   string
+    level slog.Level
+    attrs map[string]string
+}
+
+// logRecorder — slog.Handler, запоминающий записи; производные через With
+// логгеры пишут в те же записи
+type logRecorder struct {
+    mu      *sync.Mutex
+    entries *[]logEntry
+    attrs   []slog.Attr
+}
+
+func (h *logRecorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *logRecorder) Handle(_ context.Context, r slog.Record) error {
+    e := logEntry{msg: r.Message, level: r.Level, attrs: make(map[string]string)}
+    for _, a := range h.attrs {
+        e.attrs[a.Key] = a.Value.String()
+    }
+    r.Attrs(func(a slog.Attr) bool {
+        e.attrs[a.Key] = a.Value.String()
+        return true
+    })
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    *h.entries = append(*h.entries, e)
+    return nil
+}
+
+func (h *logRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+    return &logRecorder{mu: h.mu, entries: h.entries, attrs: append(slices.Clip(h.attrs), attrs...)}
+}
+
+func (h *logRecorder) WithGroup(string) slog.Handler { return h }
+
+// records — записанные записи
+func (h *logRecorder) records() []logEntry {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return slices.Clone(*h.entries)
+}
+
+// levels — уровни записей с текстом msg
+func (h *logRecorder) levels(msg string) []slog.Level {
+    var out []slog.Level
+    for _, e := range h.records() {
+        if e.msg == msg {
+            out = append(out, e.level)
+        }
+    }
+    return out
+}
+
+// recordLogs подменяет общий логгер на время теста
+func recordLogs(t *testing.T) *logRecorder {
+    t.Helper()
+    h := &logRecorder{mu: &sync.Mutex{}, entries: &[]logEntry{}}
+    prev := logging.Logger()
+    logging.SetLogger(slog.New(h))
+    t.Cleanup(func() { logging.SetLogger(prev) })
+    return h
+}
+
+// Каждая категория ошибки даёт свой ответ и уровень лога
+func TestReplyErrorCategories(t *testing.T) {
+    cases := []struct {
+        name  string
+        err   error
+        reply string
+        level slog.Level
+    }{
+        {"проверка с объяснением", apperr.Validation("Количество должно быть числом."), "Количество должно быть числом.", slog.LevelInfo},
+        {"проверка без объяснения", fmt.Errorf("разбор: %w", apperr.ErrValidation), invalidRequestReply, slog.LevelInfo},
+        {"не найдено", apperr.NotFound(""), notFoundReply, slog.LevelInfo},
+        {"не найдено с объяснением", apperr.NotFound("Заказ не найден."), "Заказ не найден.", slog.LevelInfo},
+        {"внешний сервис", apperr.Upstream(errors.New("503")), "Модель недоступна", slog.LevelError},
+        {"внешний сервис с объяснением", apperr.WithMessage(apperr.Upstream(errors.New("503")), "Оплата недоступна."), "Оплата недоступна.", slog.LevelError},
+        {"без категории", errors.New("connection refused"), internalErrorReply, slog.LevelError},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            logs := recordLogs(t)
+            tb := newTestBot(t, map[string]string{"FALLBACK_MESSAGE": "Модель недоступна"})
+
+            tb.replyError(context.Background(), 42, tc.err)
+            if got := tb.lastSent(t, 42); got != tc.reply {
+                t.Fatalf("ответ %q, нужно %q", got, tc.reply)
+            }
+            levels := logs.levels("ошибка обработки запроса")
+            if len(levels) != 1 || levels[0] != tc.level {
+                t.Fatalf("уровни лога %v, нужно %v", levels, tc.level)
+            }
+        })
+    }
+}
+
+// Ошибка команды доходит до покупателя через replyError
+func TestCommandErrorReply(t *testing.T) {
+    logs := recordLogs(t)
+    tb := newTestBot(t, nil)
+
+    tb.process(t, text(1, 42, "/order abc"))
+    if got := tb.lastSent(t, 42); got != "Укажите номер заказа, например: /order 123" {
+        t.Fatalf("ответ %q", got)
+    }
+    if levels := logs.levels("ошибка обработки запроса"); len(levels) != 1 || levels[0] != slog.LevelInfo {
+        t.Fatalf("уровни лога %v, нужно INFO", levels)
+    }
+}
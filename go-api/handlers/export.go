@@ -0,0 +1,88 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/storage"
+)
+
+// noOrdersReply — ответ на /export, если заказов ещё нет
+const noOrdersReply = "У вас пока нет заказов — выгружать нечего. Загляните в /catalog!"
+
+// utf8BOM — метка порядка байт: без неё Excel открывает CSV в кодировке
+// Windows-1251 и кириллица превращается в кракозябры
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// cmdExport — команда /export: заказы покупателя файлом CSV
+func (b *Bot) cmdExport(ctx context.Context, msg *TelegramMessage, args string) error {
+    chatID := msg.Chat.ID
+    orders, err := b.Orders.ChatOrders(ctx, chatID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось выгрузить заказы, попробуйте позже.")
+    }
+    if len(orders) == 0 {
+        b.replyPhrase(ctx, chatID, noOrdersReply)
+        return nil
+    }
+    data, err := ordersCSV(orders, b.Config.DigestLocation)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось выгрузить заказы, попробуйте позже.")
+    }
+    filename := "orders-" + time.Now().In(b.Config.DigestLocation).Format("2006-01-02") + ".csv"
+    if err := b.Telegram.SendDocument(ctx, chatID, filename, data, fmt.Sprintf("Заказов: %d", len(orders))); err != nil {
+        return apperr.WithMessage(fmt.Errorf("ошибка отправки выгрузки заказов: %w", err), "Не удалось отправить файл, попробуйте позже.")
+    }
+    return nil
+}
+
+// ordersCSV — заказы покупателя таблицей: номер, дата, позиции, сумма, статус.
+// Файл — как его ждёт Excel с русской локалью: BOM UTF-8, разделитель «;»,
+// дробная часть суммы через запятую; дата — по часам loc.
+func ordersCSV(orders []storage.Order, loc *time.Location) ([]byte, error) {
+    var buf bytes.Buffer
+    buf.Write(utf8BOM)
+    w := csv.NewWriter(&buf)
+    w.Comma = ';'
+    w.Write([]string{"Заказ", "Дата", "Товары", "Сумма", "Валюта", "Статус"})
+    for _, o := range orders {
+        items := make([]string, len(o.Items))
+        for i, item := range o.Items {
+            name := item.Name
+            if name == "" {
+                name = fmt.Sprintf("Товар #%d", item.ProductID)
+            }
+            items[i] = fmt.Sprintf("%s × %d", name, item.Qty)
+        }
+        status, ok := orderStatusLabels[o.Status]
+        if !ok {
+            status = string(o.Status)
+        }
+        w.Write([]string{
+            strconv.FormatInt(o.ID, 10),
+            o.CreatedAt.In(loc).Format("02.01.2006 15:04"),
+            csvText(strings.Join(items, ", ")),
+            strings.Replace(strconv.FormatFloat(o.Total.Major(), 'f', 2, 64), ".", ",", 1),
+            o.Total.Currency,
+            csvText(status),
+        })
+    }
+    w.Flush()
+    return buf.Bytes(), w.Error()
+}
+
+// csvText защищает ячейку от подстановки формулы: текст, который таблица
+// приняла бы за формулу (начинается с =, +, -, @, табуляции или возврата
+// каретки), получает впереди апостроф и показывается как есть
+func csvText(s string) string {
+    if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+        return "'" + s
+    }
+    return s
+}
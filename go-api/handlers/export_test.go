@@ -0,0 +1,89 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "testing"
+    "time"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+func TestOrdersCSV(t *testing.T) {
+    orders := []storage.Order{{
+        ID:        7,
+        Total:     money.New(199050, "RUB"),
+        Status:    storage.OrderStatus("new"),
+        CreatedAt: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+        Items: []storage.OrderItem{
+            {ProductID: 1, Qty: 2, Name: `=HYPERLINK("http://evil","чай")`},
+            {ProductID: 2, Qty: 1},
+        },
+    }}
+    data, err := ordersCSV(orders, time.UTC)
+    if err != nil {
+        t.Fatalf("ordersCSV: %v", err)
+    }
+    if !bytes.HasPrefix(data, utf8BOM) {
+        t.Fatal("файл без BOM UTF-8")
+    }
+    r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+    r.Comma = ';'
+    rows, err := r.ReadAll()
+    if err != nil {
+        t.Fatalf("разбор с разделителем «;»: %v", err)
+    }
+    if len(rows) != 2 || len(rows[0]) != 6 || rows[0][0] != "Заказ" {
+        t.Fatalf("строки: %q", rows)
+    }
+    row := rows[1]
+    if row[2] != `'=HYPERLINK("http://evil","чай") × 2, Товар #2 × 1` {
+        t.Fatalf("позиции: %q", row[2])
+    }
+    if row[1] != "01.03.2026 09:30" || row[3] != "1990,50" || row[4] != "RUB" {
+        t.Fatalf("строка заказа: %q", row)
+    }
+}
+
+func TestCSVText(t *testing.T) {
+    for in, want := range map[string]string{
+        "=1+2":    "'=1+2",
+        "+7 999":  "'+7 999",
+        "-5":      "'-5",
+        "@SUM(A)": "'@SUM(A)",
+        "\tx":     "'\tx",
+        "Сенча":   "Сенча",
+        "":        "",
+    } {
+        if got := csvText(in); got != want {
+            t.Errorf("csvText(%q): получено %q, ожидалось %q", in, got, want)
+        }
+    }
+}
+
+func TestExportSendsCSV(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/export"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != noOrdersReply {
+        t.Fatalf("без заказов отправлено %q", got)
+    }
+
+    items := []storage.OrderItem{{ProductID: 1, Qty: 1, Price: money.New(10000, "RUB"), Name: "Сенча"}}
+    id, err := tb.orders.CreateOrder(context.Background(), 42, items, money.New(10000, "RUB"), "k1", time.Minute)
+    if err != nil {
+        t.Fatalf("CreateOrder: %v", err)
+    }
+    if err := tb.orders.ConfirmOrder(context.Background(), id); err != nil {
+        t.Fatalf("ConfirmOrder: %v", err)
+    }
+    tb.process(t, text(2, 42, "/export"))
+    docs := tb.tg.Documents
+    if len(docs) != 1 || docs[0].ChatID != 42 || !bytes.HasPrefix(docs[0].Data, utf8BOM) {
+        t.Fatalf("выгрузка: %+v", docs)
+    }
+    if !bytes.Contains(docs[0].Data, []byte("Сенча × 1;100,00;RUB")) {
+        t.Fatalf("содержимое: %s", docs[0].Data)
+    }
+}
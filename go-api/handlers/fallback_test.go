@@ -0,0 +1,60 @@
+package handlers
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "ai_seller/openai"
+)
+
+// OpenAI, который не отвечает после всех повторов, — отозванный ключ, сбой
+// OpenAI, таймаут — покупатель получает FALLBACK_MESSAGE
+func TestFallbackWhenOpenAIUnavailable(t *testing.T) {
+    cases := []struct {
+        name     string
+        attempts int
+        handler  func(w http.ResponseWriter, r *http.Request, stop <-chan struct{})
+        calls    int64
+    }{
+        {"401", 3, func(w http.ResponseWriter, r *http.Request, stop <-chan struct{}) {
+            http.Error(w, `{"error":{"message":"Incorrect API key"}}`, http.StatusUnauthorized)
+        }, 1},
+        {"503 после повторов", 2, func(w http.ResponseWriter, r *http.Request, stop <-chan struct{}) {
+            http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+        }, 2},
+        {"таймаут", 1, func(w http.ResponseWriter, r *http.Request, stop <-chan struct{}) {
+            <-stop
+        }, 1},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            var calls atomic.Int64
+            stop := make(chan struct{})
+            srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                calls.Add(1)
+                tc.handler(w, r, stop)
+            }))
+            // Сначала отпускаем зависший запрос, иначе Close его ждёт
+            t.Cleanup(srv.Close)
+            t.Cleanup(func() { close(stop) })
+
+            tb := newTestBot(t, map[string]string{"FALLBACK_MESSAGE": "Модель недоступна, напишите позже"})
+            tb.OpenAI = openai.NewClient("sk-test", openai.Options{
+                BaseURL:     srv.URL,
+                MaxAttempts: tc.attempts,
+                Timeout:     50 * time.Millisecond,
+            })
+
+            tb.process(t, text(1, 42, "есть улун?"))
+            if got := tb.sentTo(42); len(got) != 1 || got[0] != "Модель недоступна, напишите позже" {
+                t.Fatalf("отправлено %q, нужен FALLBACK_MESSAGE", got)
+            }
+            if n := calls.Load(); n != tc.calls {
+                t.Fatalf("запросов к OpenAI %d, нужно %d", n, tc.calls)
+            }
+        })
+    }
+}
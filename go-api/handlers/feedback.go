@@ -0,0 +1,62 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+
+    "ai_seller/apperr"
+    "ai_seller/i18n"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// feedbackAction — action кнопок оценки, callback_data "fb:up" / "fb:down".
+// Какое сообщение оценено, видно из самого callback_query.
+const feedbackAction = "fb"
+
+// feedbackPrompt — сообщение команды /feedback
+const feedbackPrompt = "Как вам мои ответы? Оцените, пожалуйста, — это поможет мне стать лучше."
+
+// feedbackKeyboard — кнопки 👍/👎 под ответом
+func feedbackKeyboard() *telegram.InlineKeyboard {
+    return telegram.NewInlineKeyboard().Row(
+        telegram.CallbackButton("👍", feedbackAction+":up"),
+        telegram.CallbackButton("👎", feedbackAction+":down"),
+    )
+}
+
+// cmdFeedback — команда /feedback: оценить ответы бота в целом
+func (b *Bot) cmdFeedback(ctx context.Context, msg *TelegramMessage, args string) error {
+    text := i18n.T(reqctx.LangFromContext(ctx), feedbackPrompt)
+    _, err := b.Telegram.SendMessage(msg.Chat.ID, text, telegram.WithReplyMarkup(feedbackKeyboard()))
+    return err
+}
+
+// cbFeedback — нажатие 👍/👎; повторное нажатие меняет оценку
+func (b *Bot) cbFeedback(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    var rating int
+    switch payload {
+    case "up":
+        rating = storage.RatingUp
+    case "down":
+        rating = storage.RatingDown
+    default:
+        return fmt.Errorf("неизвестная оценка %q", payload)
+    }
+    return b.Feedback.Record(ctx, cq.Message.Chat.ID, cq.Message.MessageID, rating)
+}
+
+// cmdFeedbackStats — админская команда /feedbackstats: сводка оценок
+func (b *Bot) cmdFeedbackStats(ctx context.Context, msg *TelegramMessage, args string) error {
+    st, err := b.Feedback.Stats(ctx)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось получить оценки.")
+    }
+    text := fmt.Sprintf("Оценки ответов:\n👍 %d\n👎 %d", st.Up, st.Down)
+    if total := st.Up + st.Down; total > 0 {
+        text += fmt.Sprintf("\nДоля положительных: %d%%", st.Up*100/total)
+    }
+    b.reply(ctx, msg.Chat.ID, text)
+    return nil
+}
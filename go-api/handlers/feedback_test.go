@@ -0,0 +1,94 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/memstore"
+    "ai_seller/telegram"
+)
+
+// rate — нажатие 👍/👎 (payload up/down) под сообщением messageID
+func rate(updateID, chatID, messageID int64, payload string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, CallbackQuery: &TelegramCallbackQuery{
+        ID:      "fb",
+        From:    &TelegramUser{ID: chatID, LanguageCode: "ru"},
+        Data:    feedbackAction + ":" + payload,
+        Message: &TelegramMessage{MessageID: messageID, Chat: TelegramChat{ID: chatID, Type: "private"}},
+    }}
+}
+
+// Под ответом модели — кнопки оценки; повторное нажатие меняет оценку, а не
+// добавляет вторую
+func TestFeedbackRating(t *testing.T) {
+    feedback := &memstore.Feedback{}
+    tb := newTestBot(t, nil, func(d *Deps) { d.Feedback = feedback })
+    tb.process(t, text(1, 42, "есть улун?"))
+
+    sent := tb.tg.Messages()
+    if len(sent) != 1 {
+        t.Fatalf("отправлено %d сообщений, нужен ответ модели", len(sent))
+    }
+    kb, ok := sent[0].Markup.(*telegram.InlineKeyboard)
+    if !ok || len(kb.Rows) != 1 || len(kb.Rows[0]) != 2 ||
+        kb.Rows[0][0].CallbackData != "fb:up" || kb.Rows[0][1].CallbackData != "fb:down" {
+        t.Fatalf("клавиатура ответа %+v, нужны 👍/👎", sent[0].Markup)
+    }
+
+    answer := sent[0].MessageID
+    tb.process(t, rate(2, 42, answer, "up"))
+    tb.process(t, rate(3, 42, answer, "down"))
+    st, _ := feedback.Stats(context.Background())
+    if st.Up != 0 || st.Down != 1 {
+        t.Fatalf("оценки %+v, нужна одна 👎 вместо 👍", st)
+    }
+
+    tb.process(t, rate(4, 43, 1, "up"))
+    if st, _ := feedback.Stats(context.Background()); st.Up != 1 || st.Down != 1 {
+        t.Fatalf("оценки %+v после оценки другого чата", st)
+    }
+}
+
+func TestFeedbackStats(t *testing.T) {
+    feedback := &memstore.Feedback{}
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"}, func(d *Deps) { d.Feedback = feedback })
+    ctx := context.Background()
+    for id, rating := range []int{1, 1, 1, -1} {
+        if err := feedback.Record(ctx, 42, int64(id), rating); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tb.process(t, text(1, 1, "/feedbackstats"))
+    if got := tb.sentTo(1); len(got) != 1 || !strings.Contains(got[0], "👍 3") ||
+        !strings.Contains(got[0], "👎 1") || !strings.Contains(got[0], "75%") {
+        t.Fatalf("сводка %q", got)
+    }
+
+    tb.process(t, text(2, 42, "/feedbackstats"))
+    for _, m := range tb.sentTo(42) {
+        if strings.Contains(m, "👍") {
+            t.Fatalf("покупатель получил сводку оценок: %q", m)
+        }
+    }
+}
+
+// /feedback присылает кнопки оценки, нажатие под ним записывается
+func TestFeedbackCommand(t *testing.T) {
+    feedback := &memstore.Feedback{}
+    tb := newTestBot(t, nil, func(d *Deps) { d.Feedback = feedback })
+    tb.process(t, text(1, 42, "/feedback"))
+
+    sent := tb.tg.Messages()
+    if len(sent) != 1 || sent[0].Text != feedbackPrompt {
+        t.Fatalf("отправлено %+v", sent)
+    }
+    if _, ok := sent[0].Markup.(*telegram.InlineKeyboard); !ok {
+        t.Fatalf("у /feedback клавиатура %T", sent[0].Markup)
+    }
+    tb.process(t, rate(2, 42, sent[0].MessageID, "up"))
+    if st, _ := feedback.Stats(context.Background()); st.Up != 1 {
+        t.Fatalf("оценки %+v", st)
+    }
+}
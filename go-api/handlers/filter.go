@@ -0,0 +1,34 @@
+package handlers
+
+import (
+    "context"
+
+    "ai_seller/filter"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+)
+
+// blockedAnswerReply — замена ответа модели, который фильтр не пропустил целиком
+const blockedAnswerReply = "Извините, на это я ответить не могу. Давайте вернёмся к выбору товара?"
+
+// SetFilter подменяет фильтр ответов на лету (перезагрузка по SIGHUP); nil выключает фильтр
+func (b *Bot) SetFilter(f *filter.Filter) {
+    b.filter.Store(f)
+}
+
+// filterAnswer пропускает ответ модели через фильтр: личные данные скрываются,
+// ответ с запрещёнными словами заменяется на blockedAnswerReply. blocked=true —
+// ответ заменён; такой ответ не кэшируется.
+func (b *Bot) filterAnswer(ctx context.Context, answer string) (filtered string, blocked bool) {
+    f := b.filter.Load()
+    if f == nil {
+        return answer, false
+    }
+    filtered, ok := f.Apply(answer)
+    if !ok {
+        logging.FromContext(ctx).Warn("ответ модели заблокирован фильтром", "chat_id", reqctx.ChatIDFromContext(ctx))
+        return i18n.T(reqctx.LangFromContext(ctx), blockedAnswerReply), true
+    }
+    return filtered, false
+}
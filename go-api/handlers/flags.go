@@ -0,0 +1,134 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/config"
+    "ai_seller/logging"
+)
+
+// featureEnabled — включена ли функция. Для флагов, переключаемых на лету,
+// переключение админа в Redis важнее окружения; если Redis недоступен,
+// действует значение из окружения.
+func (b *Bot) featureEnabled(ctx context.Context, name string) bool {
+    enabled := b.Config.Features.IsEnabled(name)
+    if b.Flags == nil {
+        return enabled
+    }
+    if flag, ok := config.LookupFlag(name); !ok || !flag.Runtime {
+        return enabled
+    }
+    on, ok, err := b.Flags.Override(ctx, name)
+    if err != nil {
+        logging.FromContext(ctx).Warn("ошибка чтения переключения флага, берём значение из окружения", "flag", name, "err", err)
+        return enabled
+    }
+    if ok {
+        return on
+    }
+    return enabled
+}
+
+// cmdFlags — команда /flags: список флагов функций, а /flags <имя> on|off|reset
+// переключает флаг на лету (только флаги, проверяемые на каждом сообщении)
+func (b *Bot) cmdFlags(ctx context.Context, msg *TelegramMessage, args string) error {
+    if args == "" {
+        return b.listFlags(ctx, msg.Chat.ID)
+    }
+    if b.Flags == nil {
+        return apperr.Validation("Переключение флагов недоступно.")
+    }
+
+    fields := strings.Fields(args)
+    if len(fields) != 2 {
+        return apperr.Validation("Использование: /flags <имя> on|off|reset")
+    }
+    name, action := strings.ToLower(fields[0]), strings.ToLower(fields[1])
+    flag, ok := config.LookupFlag(name)
+    if !ok {
+        return apperr.NotFound("Нет такого флага: " + name)
+    }
+    if !flag.Runtime {
+        return apperr.Validation(fmt.Sprintf("Флаг %s читается при старте — измените %s и перезапустите бота.", name, flag.Env))
+    }
+
+    var err error
+    switch action {
+    case "on", "off":
+        err = b.Flags.Set(ctx, name, action == "on")
+    case "reset":
+        err = b.Flags.Reset(ctx, name)
+    default:
+        return apperr.Validation("Использование: /flags <имя> on|off|reset")
+    }
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось переключить флаг.")
+    }
+    logging.FromContext(ctx).Info("флаг переключён", "flag", name, "action", action, "admin_chat_id", msg.Chat.ID)
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Флаг %s: %s.", name, onOff(b.featureEnabled(ctx, name))))
+    return nil
+}
+
+// listFlags отправляет текущие значения флагов с пометкой переключённых
+func (b *Bot) listFlags(ctx context.Context, chatID int64) error {
+    var overrides map[string]bool
+    if b.Flags != nil {
+        var err error
+        if overrides, err = b.Flags.Overrides(ctx); err != nil {
+            return apperr.WithMessage(err, "Не удалось получить флаги.")
+        }
+    }
+
+    var sb strings.Builder
+    sb.WriteString("Флаги функций:\n")
+    for _, f := range config.Flags() {
+        env := b.Config.Features.IsEnabled(f.Name)
+        fmt.Fprintf(&sb, "  %s: %s", f.Name, onOff(env))
+        if on, ok := overrides[f.Name]; ok && f.Runtime {
+            fmt.Fprintf(&sb, " → %s (переключён)", onOff(on))
+        }
+        if !f.Runtime {
+            sb.WriteString(" (при старте)")
+        }
+        sb.WriteString("\n")
+    }
+    b.reply(ctx, chatID, sb.String())
+    return nil
+}
+
+func onOff(on bool) string {
+    if on {
+        return "вкл"
+    }
+    return "выкл"
+}
+
+// cmdMaintenance — админская команда /maintenance on|off: режим обслуживания.
+// Короткий путь к /flags maintenance on|off; переключение действует поверх
+// MAINTENANCE, пока его не сбросят через /flags maintenance reset.
+func (b *Bot) cmdMaintenance(ctx context.Context, msg *TelegramMessage, args string) error {
+    if b.Flags == nil {
+        return apperr.Validation("Переключение флагов недоступно.")
+    }
+    var err error
+    switch action := strings.ToLower(strings.TrimSpace(args)); action {
+    case "":
+        b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Режим обслуживания: %s.", onOff(b.featureEnabled(ctx, config.FlagMaintenance))))
+        return nil
+    case "on":
+        err = b.Flags.Set(ctx, config.FlagMaintenance, true)
+    case "off":
+        err = b.Flags.Set(ctx, config.FlagMaintenance, false)
+    default:
+        return apperr.Validation("Использование: /maintenance on|off")
+    }
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось переключить режим обслуживания.")
+    }
+    logging.FromContext(ctx).Warn("режим обслуживания переключён", "args", args, "admin_chat_id", msg.Chat.ID)
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Режим обслуживания: %s.", onOff(b.featureEnabled(ctx, config.FlagMaintenance))))
+    return nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/config"
+)
+
+// Переключение в Redis важнее окружения, но только для флагов, проверяемых
+// на каждом сообщении; без Redis действует окружение
+func TestFeatureEnabledPrecedence(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"VISION_ENABLED": "true", "RESPONSE_CACHE": "false"})
+    ctx := context.Background()
+
+    if !tb.featureEnabled(ctx, config.FlagVision) {
+        t.Fatal("флаг из окружения выключен")
+    }
+    if err := tb.Flags.Set(ctx, config.FlagVision, false); err != nil {
+        t.Fatal(err)
+    }
+    if tb.featureEnabled(ctx, config.FlagVision) {
+        t.Fatal("переключение в Redis не перекрыло окружение")
+    }
+
+    tb.redis.SetError("ERR down")
+    if !tb.featureEnabled(ctx, config.FlagVision) {
+        t.Fatal("без Redis не взято значение из окружения")
+    }
+    tb.redis.SetError("")
+
+    if err := tb.Flags.Reset(ctx, config.FlagVision); err != nil {
+        t.Fatal(err)
+    }
+    if !tb.featureEnabled(ctx, config.FlagVision) {
+        t.Fatal("после сброса флаг не вернулся к окружению")
+    }
+
+    // Флаг, читаемый при старте, переключение не меняет
+    if err := tb.Flags.Set(ctx, config.FlagResponseCache, true); err != nil {
+        t.Fatal(err)
+    }
+    if tb.featureEnabled(ctx, config.FlagResponseCache) {
+        t.Fatal("переключение включило флаг, читаемый при старте")
+    }
+}
+
+func TestFlagsCommand(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1", "VOICE_ENABLED": "true"})
+
+    tb.process(t, text(1, 1, "/flags voice off"))
+    tb.process(t, text(2, 1, "/flags response_cache on"))
+    tb.process(t, text(3, 1, "/flags"))
+    got := tb.sentTo(1)
+    if len(got) != 3 {
+        t.Fatalf("отправлено %q", got)
+    }
+    if got[0] != "Флаг voice: выкл." {
+        t.Errorf("ответ на переключение %q", got[0])
+    }
+    if !strings.Contains(got[1], "RESPONSE_CACHE") {
+        t.Errorf("отказ для флага при старте %q", got[1])
+    }
+    for _, want := range []string{"voice: вкл → выкл (переключён)", "response_cache: выкл (при старте)", "cart_reminders: вкл (при старте)"} {
+        if !strings.Contains(got[2], want) {
+            t.Errorf("в списке нет %q:\n%s", want, got[2])
+        }
+    }
+
+    tb.process(t, text(4, 42, "/flags voice on"))
+    if on, ok, _ := tb.Flags.Override(context.Background(), config.FlagVoice); !ok || on {
+        t.Fatal("покупатель переключил флаг")
+    }
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "strconv"
+    "strings"
+)
+
+// addressedToBot — обращено ли сообщение к боту. В личке — всегда. В группе
+// бот отвечает, только если его упомянули (@username или упоминанием без
+// username), вызвали команду без адресата или со своим @username, или
+// ответили на его сообщение — иначе он встревал бы в чужие разговоры. Себя
+// бот узнаёт по Deps.Me: ответ другому боту группы обращением не считается.
+func (b *Bot) addressedToBot(msg *TelegramMessage) bool {
+    if !msg.Chat.isGroup() {
+        return true
+    }
+
+    if r := msg.ReplyToMessage; r != nil && r.From != nil && b.isSelf(r.From) {
+        return true
+    }
+    for _, entities := range [][]TelegramEntity{msg.Entities, msg.CaptionEntities} {
+        for _, e := range entities {
+            if e.Type == "text_mention" && e.User != nil && b.isSelf(e.User) {
+                return true
+            }
+        }
+    }
+
+    username := b.Me.Username
+    for _, m := range append(entityTexts(msg.Text, msg.Entities, "mention"), entityTexts(msg.Caption, msg.CaptionEntities, "mention")...) {
+        if username != "" && strings.EqualFold(strings.TrimPrefix(m, "@"), username) {
+            return true
+        }
+    }
+    for _, cmd := range entityTexts(msg.Text, msg.Entities, "bot_command") {
+        _, to, addressed := strings.Cut(cmd, "@")
+        if !addressed || (username != "" && strings.EqualFold(to, username)) {
+            return true
+        }
+    }
+    return false
+}
+
+// isSelf — u и есть этот бот: по ID, а если ID неизвестен — по username
+func (b *Bot) isSelf(u *TelegramUser) bool {
+    if !u.IsBot {
+        return false
+    }
+    if b.Me.ID != 0 {
+        return u.ID == b.Me.ID
+    }
+    return b.Me.Username != "" && strings.EqualFold(u.Username, b.Me.Username)
+}
+
+// tokenBotID — ID бота из токена вида <id>:<секрет>; 0, если токен другой
+func tokenBotID(token string) int64 {
+    id, _, ok := strings.Cut(token, ":")
+    if !ok {
+        return 0
+    }
+    n, err := strconv.ParseInt(id, 10, 64)
+    if err != nil {
+        return 0
+    }
+    return n
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "testing"
+
+    "ai_seller/telegram"
+)
+
+// ID бота из токена testConfig
+const testBotID = 123456
+
+func TestAddressedToBot(t *testing.T) {
+    self := &TelegramUser{ID: testBotID, IsBot: true, Username: "shop_bot"}
+    otherBot := &TelegramUser{ID: 999, IsBot: true, Username: "other_bot"}
+    member := &TelegramUser{ID: memberID, FirstName: "Участник"}
+
+    group := func(text string, entities ...TelegramEntity) *TelegramMessage {
+        return &TelegramMessage{Chat: TelegramChat{ID: groupID, Type: "supergroup"}, From: member, Text: text, Entities: entities}
+    }
+    replyTo := func(to *TelegramUser) *TelegramMessage {
+        m := group("ну так что?")
+        m.ReplyToMessage = &TelegramMessage{From: to}
+        return m
+    }
+    caption := group("")
+    caption.Caption = "@shop_bot такой есть?"
+    caption.CaptionEntities = []TelegramEntity{{Type: "mention", Offset: 0, Length: 9}}
+
+    cases := []struct {
+        name string
+        msg  *TelegramMessage
+        want bool
+    }{
+        {"личка", &TelegramMessage{Chat: TelegramChat{ID: 42, Type: "private"}, Text: "привет"}, true},
+        {"разговор в группе", group("кто пойдёт обедать?"), false},
+        {"ответ боту", replyTo(self), true},
+        {"ответ другому боту", replyTo(otherBot), false},
+        {"ответ участнику", replyTo(member), false},
+        {"упоминание", group("@shop_bot есть улун?", TelegramEntity{Type: "mention", Offset: 0, Length: 9}), true},
+        {"упоминание другого бота", group("@other_bot привет", TelegramEntity{Type: "mention", Offset: 0, Length: 10}), false},
+        {"упоминание в подписи", caption, true},
+        {"упоминание без username", group("Магазин, есть улун?", TelegramEntity{Type: "text_mention", Offset: 0, Length: 7, User: self}), true},
+        {"упоминание участника без username", group("Иван, глянь", TelegramEntity{Type: "text_mention", Offset: 0, Length: 4, User: member}), false},
+        {"команда без адресата", group("/catalog", TelegramEntity{Type: "bot_command", Offset: 0, Length: 8}), true},
+        {"команда боту", group("/catalog@shop_bot", TelegramEntity{Type: "bot_command", Offset: 0, Length: 17}), true},
+        {"команда другому боту", group("/catalog@other_bot", TelegramEntity{Type: "bot_command", Offset: 0, Length: 18}), false},
+    }
+
+    // Username приходит из getMe, переменная окружения не нужна
+    fromGetMe := newTestBot(t, nil, func(d *Deps) { d.Me = telegram.BotInfo{ID: testBotID, Username: "shop_bot"} })
+    // getMe не ответил: username из TELEGRAM_BOT_USERNAME, ID из токена
+    fromConfig := newTestBot(t, map[string]string{"TELEGRAM_BOT_USERNAME": "shop_bot"})
+    for _, tb := range []*testBot{fromGetMe, fromConfig} {
+        for _, tc := range cases {
+            if got := tb.addressedToBot(tc.msg); got != tc.want {
+                t.Errorf("Me=%+v, %s: addressedToBot = %v, нужно %v", tb.Me, tc.name, got, tc.want)
+            }
+        }
+    }
+}
+
+// Без username ответ другому боту группы не считается обращением: бот узнаёт
+// себя по ID из токена
+func TestAddressedToBotWithoutUsername(t *testing.T) {
+    tb := newTestBot(t, nil)
+    replyTo := func(to *TelegramUser) *TelegramMessage {
+        return &TelegramMessage{
+            Chat:           TelegramChat{ID: groupID, Type: "group"},
+            Text:           "да",
+            ReplyToMessage: &TelegramMessage{From: to},
+        }
+    }
+    if tb.addressedToBot(replyTo(&TelegramUser{ID: 999, IsBot: true, Username: "other_bot"})) {
+        t.Fatal("ответ другому боту считается обращением")
+    }
+    if !tb.addressedToBot(replyTo(&TelegramUser{ID: testBotID, IsBot: true})) {
+        t.Fatal("ответ этому боту не считается обращением")
+    }
+}
+
+func TestTokenBotID(t *testing.T) {
+    for token, want := range map[string]int64{
+        "123456:ABCdef": 123456,
+        "":              0,
+        "abc:def":       0,
+        "123456":        0,
+    } {
+        if got := tokenBotID(token); got != want {
+            t.Errorf("tokenBotID(%q) = %d, нужно %d", token, got, want)
+        }
+    }
+}
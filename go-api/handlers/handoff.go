@@ -0,0 +1,173 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "slices"
+    "strconv"
+    "strings"
+    "unicode"
+
+    "ai_seller/apperr"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/telegram"
+)
+
+const (
+    // handoffReply — ответ покупателю, попросившему живого человека
+    handoffReply = "Передал ваш вопрос менеджеру — он скоро свяжется с вами. Пока он не ответит, я помолчу."
+    // handoffResolvedReply — сообщение покупателю, когда оператор вернул чат боту
+    handoffResolvedReply = "Менеджер завершил разговор. Если появятся вопросы — пишите, я на связи."
+    // keywordHandoffReason — причина передачи по ключевой фразе покупателя
+    keywordHandoffReason = "покупатель попросил живого человека"
+)
+
+// wantsHuman — просит ли покупатель живого человека одной из фраз
+// HANDOFF_KEYWORDS. Фраза ищется целыми словами подряд: «живой человек»
+// не срабатывает на «неживой человек», а «оператор» — на «операторский».
+func (b *Bot) wantsHuman(text string) bool {
+    words := handoffWords(text)
+    for _, kw := range b.Config.HandoffKeywords {
+        if phrase := handoffWords(kw); len(phrase) > 0 && containsPhrase(words, phrase) {
+            return true
+        }
+    }
+    return false
+}
+
+// handoffWords — слова текста: нижний регистр, ё→е, без пунктуации
+func handoffWords(text string) []string {
+    text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+    return strings.FieldsFunc(text, func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+}
+
+// containsPhrase — встречаются ли слова phrase в words подряд
+func containsPhrase(words, phrase []string) bool {
+    for i := 0; i+len(phrase) <= len(words); i++ {
+        if slices.Equal(words[i:i+len(phrase)], phrase) {
+            return true
+        }
+    }
+    return false
+}
+
+// handedOff — передан ли чат оператору. Если Redis недоступен, бот отвечает
+// сам: лучше лишний ответ бота, чем тишина.
+func (b *Bot) handedOff(ctx context.Context, chatID int64) bool {
+    if b.Handoffs == nil {
+        return false
+    }
+    active, err := b.Handoffs.Active(ctx, chatID)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка чтения передачи оператору", "chat_id", chatID, "err", err)
+        return false
+    }
+    return active
+}
+
+// escalate передаёт чат оператору и зовёт админов. false — чат уже был передан,
+// админы о нём знают.
+func (b *Bot) escalate(ctx context.Context, chatID int64, reason string) (bool, error) {
+    started, err := b.Handoffs.Start(ctx, chatID, reason)
+    if err != nil || !started {
+        return false, err
+    }
+    logging.FromContext(ctx).Info("чат передан оператору", "chat_id", chatID, "reason", reason)
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "🙋 Покупатель просит оператора: %s\nПричина: %s\n", b.chatTitle(ctx, chatID), reason)
+    // В личном чате id чата совпадает с id пользователя — по нему открывается профиль
+    if chatID > 0 {
+        fmt.Fprintf(&sb, "Написать: tg://user?id=%d\n", chatID)
+    }
+    fmt.Fprintf(&sb, "Вернуть чат боту: /resolve %d", chatID)
+    b.notifyAdmins(ctx, sb.String())
+    return true, nil
+}
+
+// relayToOperator пересылает админам сообщение покупателя из переданного
+// оператору чата, чтобы оператор видел, что тот пишет боту. Текст уходит
+// как есть, без разметки (см. notifyAdmins).
+func (b *Bot) relayToOperator(ctx context.Context, chatID int64, text string) {
+    b.remember(ctx, chatID, "user", text)
+    b.notifyAdmins(ctx, fmt.Sprintf("💬 %s: %s", b.chatTitle(ctx, chatID), text))
+}
+
+// chatTitle — имя покупателя и id чата для сообщений админам
+func (b *Bot) chatTitle(ctx context.Context, chatID int64) string {
+    u, err := b.profile(ctx, chatID)
+    switch {
+    case err != nil:
+    case u.Name != "" && u.Username != "":
+        return fmt.Sprintf("%s (@%s, чат %d)", u.Name, u.Username, chatID)
+    case u.Name != "":
+        return fmt.Sprintf("%s (чат %d)", u.Name, chatID)
+    case u.Username != "":
+        return fmt.Sprintf("@%s (чат %d)", u.Username, chatID)
+    }
+    return fmt.Sprintf("чат %d", chatID)
+}
+
+// notifyAdmins отправляет текст всем чатам из ADMIN_CHAT_IDS. В тексте
+// слова покупателя и его имя, поэтому он идёт без разметки: символы
+// MarkdownV2 в них не ломают отправку.
+func (b *Bot) notifyAdmins(ctx context.Context, text string) {
+    if len(b.Config.AdminChatIDs) == 0 {
+        logging.FromContext(ctx).Warn("некому сообщить о передаче оператору: ADMIN_CHAT_IDS пуст")
+        return
+    }
+    for adminID := range b.Config.AdminChatIDs {
+        b.send(ctx, adminID, text, telegram.WithParseMode(""))
+    }
+}
+
+// cmdResolve — команда /resolve <chat_id>: оператор закончил, чат снова обслуживает бот
+func (b *Bot) cmdResolve(ctx context.Context, msg *TelegramMessage, args string) error {
+    if b.Handoffs == nil {
+        return apperr.Validation("Передача оператору выключена.")
+    }
+    chatID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+    if err != nil {
+        return apperr.Validation("Использование: /resolve <chat_id>")
+    }
+
+    resolved, err := b.Handoffs.Resolve(ctx, chatID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось вернуть чат боту, попробуйте ещё раз.")
+    }
+    if !resolved {
+        b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Чат %d не был передан оператору.", chatID))
+        return nil
+    }
+    logging.FromContext(ctx).Info("чат возвращён боту", "chat_id", chatID, "admin_chat_id", msg.Chat.ID)
+    lang := i18n.DefaultLang
+    if u, err := b.profile(ctx, chatID); err == nil {
+        lang = u.Lang
+    }
+    b.reply(ctx, chatID, i18n.T(lang, handoffResolvedReply))
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Чат %d возвращён боту.", chatID))
+    return nil
+}
+
+// toolEscalateToHuman — инструмент escalate_to_human
+func (b *Bot) toolEscalateToHuman(ctx context.Context, args json.RawMessage) (string, error) {
+    var in struct {
+        Reason string `json:"reason"`
+    }
+    if err := json.Unmarshal(args, &in); err != nil {
+        return "", fmt.Errorf("некорректные аргументы: %w", err)
+    }
+    if in.Reason = strings.TrimSpace(in.Reason); in.Reason == "" {
+        in.Reason = keywordHandoffReason
+    }
+
+    if _, err := b.escalate(ctx, reqctx.ChatIDFromContext(ctx), in.Reason); err != nil {
+        return "", err
+    }
+    return `{"escalated":true}`, nil
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/openai"
+)
+
+// handoffEnv — передача оператору с админом в чате 1
+var handoffEnv = map[string]string{"HANDOFF_ENABLED": "true", "ADMIN_CHAT_IDS": "1"}
+
+// adminMessages — тексты, отправленные админу 1
+func (tb *testBot) adminMessages() []mocks.SentMessage {
+    var out []mocks.SentMessage
+    for _, m := range tb.tg.Messages() {
+        if m.ChatID == 1 {
+            out = append(out, m)
+        }
+    }
+    return out
+}
+
+func TestWantsHuman(t *testing.T) {
+    tb := newTestBot(t, nil)
+    for msg, want := range map[string]bool{
+        "Позовите оператора!":              true,
+        "мне нужен живой человек":          true,
+        "Talk to a human, please":          true,
+        "у меня оператор связи МТС":        false,
+        "какой операторский тариф выбрать": false,
+        "это неживой человек, а бот?":      false,
+        "сколько стоит улун":               false,
+    } {
+        if got := tb.wantsHuman(msg); got != want {
+            t.Errorf("wantsHuman(%q) = %v, нужно %v", msg, got, want)
+        }
+    }
+}
+
+// Слова покупателя и его имя уходят админам без разметки: символы
+// MarkdownV2 в них не ломают отправку
+func TestHandoffNotifiesAdminsInPlainText(t *testing.T) {
+    const customer int64 = 42
+    tb := newTestBot(t, handoffEnv)
+
+    tb.process(t, text(1, customer, "позовите оператора"))
+    if got := tb.lastSent(t, customer); got != handoffReply {
+        t.Fatalf("покупателю: %q, нужно %q", got, handoffReply)
+    }
+    relayed := "скидка *50%* на [улун]_"
+    tb.process(t, text(2, customer, relayed))
+
+    toAdmin := tb.adminMessages()
+    if len(toAdmin) != 2 {
+        t.Fatalf("админу %d сообщений, нужно 2: вызов и пересылка", len(toAdmin))
+    }
+    for _, m := range toAdmin {
+        if m.ParseMode != "" {
+            t.Errorf("сообщение админу %q с разметкой %q", m.Text, m.ParseMode)
+        }
+    }
+    if !strings.HasSuffix(toAdmin[1].Text, ": "+relayed) {
+        t.Fatalf("пересылка %q, нужен текст покупателя как есть", toAdmin[1].Text)
+    }
+}
+
+// Пока чат у оператора, модель не вызывается: сообщения покупателя идут
+// админам, а /resolve возвращает чат боту
+func TestHandoffSilencesModelUntilResolved(t *testing.T) {
+    const customer int64 = 42
+    tb := newTestBot(t, handoffEnv)
+
+    tb.process(t, text(1, customer, "позовите оператора"))
+    tb.process(t, text(2, customer, "где мой заказ?"))
+    if n := len(tb.ai.Requests); n != 0 {
+        t.Fatalf("запросов к модели после передачи оператору: %d", n)
+    }
+    if got := tb.sentTo(customer); len(got) != 1 || got[0] != handoffReply {
+        t.Fatalf("покупателю отправлено %q, нужно только %q", got, handoffReply)
+    }
+
+    tb.process(t, text(3, 1, fmt.Sprintf("/resolve %d", customer)))
+    if got := tb.lastSent(t, customer); got != handoffResolvedReply {
+        t.Fatalf("покупателю после /resolve: %q, нужно %q", got, handoffResolvedReply)
+    }
+    if toAdmin := tb.adminMessages(); !strings.Contains(toAdmin[len(toAdmin)-1].Text, "возвращён боту") {
+        t.Fatalf("админу после /resolve: %q", toAdmin[len(toAdmin)-1].Text)
+    }
+    if active, _ := tb.Handoffs.Active(context.Background(), customer); active {
+        t.Fatal("чат остался у оператора после /resolve")
+    }
+
+    tb.process(t, text(4, customer, "есть улун?"))
+    if n := len(tb.ai.Requests); n != 1 {
+        t.Fatalf("запросов к модели после /resolve: %d, нужен один", n)
+    }
+    if got := tb.lastSent(t, customer); got != "ответ модели" {
+        t.Fatalf("после /resolve бот ответил %q", got)
+    }
+
+    tb.process(t, text(5, 1, fmt.Sprintf("/resolve %d", customer)))
+    if toAdmin := tb.adminMessages(); !strings.Contains(toAdmin[len(toAdmin)-1].Text, "не был передан") {
+        t.Fatalf("повторный /resolve: админу %q", toAdmin[len(toAdmin)-1].Text)
+    }
+}
+
+// Модель сама передаёт разговор инструментом escalate_to_human: админ
+// получает причину, а следующее сообщение покупателя модель уже не видит
+func TestHandoffByTool(t *testing.T) {
+    const customer int64 = 42
+    var calls atomic.Int64
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        if calls.Add(1) == 1 {
+            fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function",`+
+                `"function":{"name":"escalate_to_human","arguments":"{\"reason\":\"возврат товара\"}"}}]}}]}`)
+            return
+        }
+        fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Менеджер скоро свяжется с вами."}}]}`)
+    }))
+    t.Cleanup(srv.Close)
+    tb := newTestBot(t, handoffEnv)
+    tb.OpenAI = openai.NewClient("sk-test", openai.Options{BaseURL: srv.URL})
+
+    tb.process(t, text(1, customer, "хочу вернуть чайник"))
+    if got := tb.lastSent(t, customer); got != "Менеджер скоро свяжется с вами." {
+        t.Fatalf("покупателю: %q", got)
+    }
+    toAdmin := tb.adminMessages()
+    if len(toAdmin) != 1 || !strings.Contains(toAdmin[0].Text, "Причина: возврат товара") {
+        t.Fatalf("админу %+v, нужен вызов с причиной", toAdmin)
+    }
+
+    tb.process(t, text(2, customer, "алло?"))
+    if n := calls.Load(); n != 2 {
+        t.Fatalf("запросов к модели %d, после передачи новых быть не должно", n)
+    }
+    if toAdmin := tb.adminMessages(); len(toAdmin) != 2 || !strings.HasSuffix(toAdmin[1].Text, ": алло?") {
+        t.Fatalf("сообщение покупателя не переслано админу: %+v", toAdmin)
+    }
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+const (
+    // defaultHistoryTurns — сколько сообщений показывает /history без аргумента
+    defaultHistoryTurns = 10
+    // maxHistoryTurns — больше /history не показывает, сколько бы ни попросили
+    maxHistoryTurns = 50
+)
+
+// historyUsage — подсказка по аргументам /history
+const historyUsage = "Использование: /history [n] — последние n сообщений (по умолчанию %d, не больше %d)."
+
+// cmdHistory — команда /history [n]: последние сообщения переписки с ботом.
+// Админ может добавить id чата — /history [n] <chat_id>, — остальные видят
+// только свой чат.
+func (b *Bot) cmdHistory(ctx context.Context, msg *TelegramMessage, args string) error {
+    n, chatID, err := b.historyArgs(msg, args)
+    if err != nil {
+        return err
+    }
+
+    history, err := b.Messages.GetHistory(ctx, chatID, n)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось прочитать историю, попробуйте позже.")
+    }
+    if len(history) == 0 {
+        b.reply(ctx, msg.Chat.ID, "История переписки пуста.")
+        return nil
+    }
+    // Простой текст: в переписке могут быть символы разметки. Длинную
+    // историю клиент сам разобьёт на несколько сообщений.
+    _, err = b.Telegram.SendMessage(msg.Chat.ID, renderHistory(history, chatID == msg.Chat.ID), telegram.WithParseMode(""))
+    return err
+}
+
+// historyArgs разбирает аргументы /history: число сообщений и чат
+func (b *Bot) historyArgs(msg *TelegramMessage, args string) (int, int64, error) {
+    usage := apperr.Validation(fmt.Sprintf(historyUsage, defaultHistoryTurns, maxHistoryTurns))
+    n, chatID := defaultHistoryTurns, msg.Chat.ID
+
+    fields := strings.Fields(args)
+    if len(fields) > 2 {
+        return 0, 0, usage
+    }
+    if len(fields) > 0 {
+        v, err := strconv.Atoi(fields[0])
+        if err != nil || v < 1 {
+            return 0, 0, usage
+        }
+        n = min(v, maxHistoryTurns)
+    }
+    if len(fields) == 2 {
+        if !b.Config.IsAdmin(msg.Chat.ID) {
+            return 0, 0, apperr.Validation("Можно посмотреть только свою переписку.")
+        }
+        id, err := strconv.ParseInt(fields[1], 10, 64)
+        if err != nil {
+            return 0, 0, usage
+        }
+        chatID = id
+    }
+    return n, chatID, nil
+}
+
+// renderHistory — сообщения с временем и ролью; own — история своего чата,
+// иначе её смотрит админ и реплики покупателя подписаны иначе
+func renderHistory(history []storage.Message, own bool) string {
+    userLabel := "Покупатель"
+    if own {
+        userLabel = "Вы"
+    }
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "🕘 Последние сообщения (%d):\n", len(history))
+    for _, m := range history {
+        label := "Бот"
+        if m.Role == "user" {
+            label = userLabel
+        }
+        fmt.Fprintf(&sb, "\n[%s] %s: %s\n", m.CreatedAt.Format("02.01 15:04"), label, m.Content)
+    }
+    return sb.String()
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// fillHistory — n сообщений чата по очереди от покупателя и бота:
+// «сообщение 1» … «сообщение n»
+func fillHistory(tb *testBot, chatID int64, n int) {
+    for i := 1; i <= n; i++ {
+        role := "user"
+        if i%2 == 0 {
+            role = "assistant"
+        }
+        _ = tb.messages.SaveMessage(context.Background(), chatID, role, fmt.Sprintf("сообщение %d", i))
+    }
+}
+
+// shownTurns — номера сообщений, попавших в ответ /history
+func shownTurns(reply string) []string {
+    var shown []string
+    for _, line := range strings.Split(reply, "\n") {
+        if _, after, ok := strings.Cut(line, ": сообщение "); ok {
+            shown = append(shown, after)
+        }
+    }
+    return shown
+}
+
+// По умолчанию /history показывает последние 10 сообщений, больше 50 — никогда
+func TestHistoryTurns(t *testing.T) {
+    cases := []struct {
+        args        string
+        first, last string
+        count       int
+    }{
+        {"", "51", "60", defaultHistoryTurns},
+        {" 3", "58", "60", 3},
+        {" 100", "11", "60", maxHistoryTurns},
+    }
+    for _, tc := range cases {
+        t.Run("/history"+tc.args, func(t *testing.T) {
+            tb := newTestBot(t, nil)
+            fillHistory(tb, 42, 60)
+
+            tb.process(t, text(1, 42, "/history"+tc.args))
+            got := tb.sentTo(42)
+            if len(got) != 1 {
+                t.Fatalf("отправлено %q", got)
+            }
+            shown := shownTurns(got[0])
+            if len(shown) != tc.count || shown[0] != tc.first || shown[len(shown)-1] != tc.last {
+                t.Fatalf("показаны сообщения %q, нужно %d с %s по %s", shown, tc.count, tc.first, tc.last)
+            }
+            if !strings.Contains(got[0], fmt.Sprintf("(%d)", tc.count)) || !strings.Contains(got[0], "Вы: сообщение 59") || !strings.Contains(got[0], "Бот: сообщение 60") {
+                t.Fatalf("заголовок или подписи ролей:\n%s", got[0])
+            }
+        })
+    }
+}
+
+func TestHistoryBadArgs(t *testing.T) {
+    for _, args := range []string{"0", "-5", "десять", "5 42 7"} {
+        t.Run(args, func(t *testing.T) {
+            tb := newTestBot(t, nil)
+            fillHistory(tb, 42, 3)
+
+            tb.process(t, text(1, 42, "/history "+args))
+            want := fmt.Sprintf(historyUsage, defaultHistoryTurns, maxHistoryTurns)
+            if got := tb.sentTo(42); len(got) != 1 || got[0] != want {
+                t.Fatalf("отправлено %q, нужна подсказка", got)
+            }
+        })
+    }
+}
+
+// Покупатель видит только свой чат; админ — любой, с подписью «Покупатель»
+func TestHistoryScoping(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    fillHistory(tb, 42, 3)
+    fillHistory(tb, 7, 1)
+
+    tb.process(t, text(1, 7, "/history 5 42"))
+    if got := tb.sentTo(7); len(got) != 1 || got[0] != "Можно посмотреть только свою переписку." {
+        t.Fatalf("покупателю отправлено %q", got)
+    }
+
+    tb.process(t, text(2, 7, "/history"))
+    got := tb.sentTo(7)
+    if shown := shownTurns(got[len(got)-1]); len(shown) != 1 || strings.Contains(got[len(got)-1], "сообщение 3") {
+        t.Fatalf("в своей истории чужие сообщения:\n%s", got[len(got)-1])
+    }
+
+    tb.process(t, text(3, 1, "/history 5 42"))
+    got = tb.sentTo(1)
+    if len(got) != 1 || len(shownTurns(got[0])) != 3 {
+        t.Fatalf("админу отправлено %q", got)
+    }
+    if !strings.Contains(got[0], "Покупатель: сообщение 1") || !strings.Contains(got[0], "Бот: сообщение 2") || strings.Contains(got[0], "Вы:") {
+        t.Fatalf("подписи ролей в чужой истории:\n%s", got[0])
+    }
+}
+
+func TestHistoryEmpty(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/history"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != "История переписки пуста." {
+        t.Fatalf("отправлено %q", got)
+    }
+}
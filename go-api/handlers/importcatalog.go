@@ -0,0 +1,293 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/url"
+    "path"
+    "slices"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+const (
+    // maxCatalogFileSize — предел размера файла каталога
+    maxCatalogFileSize = 5 << 20
+    // maxReportedRowErrors — сколько ошибок строк показать в отчёте
+    maxReportedRowErrors = 10
+)
+
+// TelegramDocument — файл, присланный документом
+type TelegramDocument struct {
+    FileID   string `json:"file_id"`
+    FileName string `json:"file_name"`
+    MimeType string `json:"mime_type"`
+    FileSize int64  `json:"file_size"`
+}
+
+// cmdImportCatalog — команда /importcatalog: загрузка каталога из CSV или JSON.
+// Файл присылают документом с командой в подписи или отвечают командой на
+// сообщение с файлом. Строки с ошибками пропускаются, в строгом режиме
+// (CATALOG_IMPORT_STRICT) любая ошибка отменяет весь импорт.
+func (b *Bot) cmdImportCatalog(ctx context.Context, msg *TelegramMessage, args string) error {
+    doc := msg.Document
+    if doc == nil && msg.ReplyToMessage != nil {
+        doc = msg.ReplyToMessage.Document
+    }
+    if doc == nil {
+        return apperr.Validation("Пришлите файл CSV или JSON с подписью /importcatalog или ответьте этой командой на сообщение с файлом.\nКолонки: id, name, description, price, currency, in_stock, stock, image_url, category; обязательны name и price.")
+    }
+    if doc.FileSize > maxCatalogFileSize {
+        return apperr.Validation(fmt.Sprintf("Файл слишком большой: не больше %d МБ.", maxCatalogFileSize>>20))
+    }
+
+    data, _, err := b.downloadFile(ctx, doc.FileID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось скачать файл, пришлите его ещё раз.")
+    }
+    categories, err := b.Catalog.ListCategories(ctx)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить категории каталога, попробуйте позже.")
+    }
+    products, rowErrs, err := parseCatalogFile(doc.FileName, doc.MimeType, data, b.Config.DefaultCurrency, newCategoryLookup(categories))
+    if err != nil {
+        return apperr.Validation("Не удалось прочитать файл: " + err.Error())
+    }
+
+    if len(rowErrs) > 0 && b.Config.CatalogImportStrict {
+        b.reply(ctx, msg.Chat.ID, "Импорт отменён: в файле есть ошибки, каталог не изменён."+formatRowErrors(rowErrs))
+        return nil
+    }
+    if len(products) == 0 {
+        b.reply(ctx, msg.Chat.ID, "В файле нет товаров для загрузки."+formatRowErrors(rowErrs))
+        return nil
+    }
+
+    res, err := b.Catalog.ImportProducts(ctx, products)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось сохранить каталог, изменения отменены.")
+    }
+    logging.FromContext(ctx).Info("каталог загружен из файла", "file", doc.FileName,
+        "added", res.Added, "updated", res.Updated, "unchanged", res.Unchanged, "skipped", len(rowErrs))
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Каталог загружен: добавлено %d, обновлено %d, без изменений %d, пропущено строк с ошибками %d.",
+        res.Added, res.Updated, res.Unchanged, len(rowErrs))+formatRowErrors(rowErrs))
+    return nil
+}
+
+// formatRowErrors — первые ошибки строк для отчёта админу
+func formatRowErrors(rowErrs []error) string {
+    if len(rowErrs) == 0 {
+        return ""
+    }
+    var sb strings.Builder
+    sb.WriteString("\n\nОшибки:")
+    for i, err := range rowErrs {
+        if i == maxReportedRowErrors {
+            fmt.Fprintf(&sb, "\n…и ещё %d", len(rowErrs)-i)
+            break
+        }
+        sb.WriteString("\n" + err.Error())
+    }
+    return sb.String()
+}
+
+// parseCatalogFile разбирает файл каталога. JSON узнаётся по расширению,
+// типу или первому символу, остальное читается как CSV. Ошибка возвращается,
+// только если файл не прочитать целиком; ошибки отдельных строк — в rowErrs.
+// Цены без валюты считаются в defaultCurrency, колонка category ищется в categories.
+func parseCatalogFile(filename, mimeType string, data []byte, defaultCurrency string, categories categoryLookup) (products []storage.Product, rowErrs []error, err error) {
+    data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+    records, first, err := catalogRecords(filename, mimeType, data)
+    if err != nil {
+        return nil, nil, err
+    }
+    for i, rec := range records {
+        p, err := productFromRecord(rec, defaultCurrency, categories)
+        if err != nil {
+            rowErrs = append(rowErrs, fmt.Errorf("строка %d: %w", first+i, err))
+            continue
+        }
+        products = append(products, p)
+    }
+    return products, rowErrs, nil
+}
+
+// catalogRecords — строки файла как «колонка → значение» и номер первой
+// строки с данными (в CSV первая строка — заголовок)
+func catalogRecords(filename, mimeType string, data []byte) ([]map[string]string, int, error) {
+    ext := strings.ToLower(path.Ext(filename))
+    if ext == ".json" || mimeType == "application/json" || bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+        records, err := jsonRecords(data)
+        return records, 1, err
+    }
+    records, err := csvRecords(data)
+    return records, 2, err
+}
+
+// jsonRecords читает массив объектов; числа и флаги приводятся к строкам,
+// чтобы проверка полей была общей с CSV
+func jsonRecords(data []byte) ([]map[string]string, error) {
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    var items []map[string]any
+    if err := dec.Decode(&items); err != nil {
+        return nil, fmt.Errorf("ожидается JSON-массив товаров: %w", err)
+    }
+
+    records := make([]map[string]string, len(items))
+    for i, item := range items {
+        rec := make(map[string]string, len(item))
+        for k, v := range item {
+            switch v := v.(type) {
+            case nil:
+            case string:
+                rec[strings.ToLower(k)] = v
+            default:
+                rec[strings.ToLower(k)] = fmt.Sprint(v)
+            }
+        }
+        records[i] = rec
+    }
+    return records, nil
+}
+
+// csvRecords читает CSV с заголовком. Разделитель — запятая или точка
+// с запятой (так сохраняет Excel с русской локалью).
+func csvRecords(data []byte) ([]map[string]string, error) {
+    header, _, _ := bytes.Cut(data, []byte("\n"))
+    r := csv.NewReader(bytes.NewReader(data))
+    if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+        r.Comma = ';'
+    }
+    r.FieldsPerRecord = -1
+    r.TrimLeadingSpace = true
+
+    columns, err := r.Read()
+    if errors.Is(err, io.EOF) {
+        return nil, errors.New("файл пуст")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения заголовка: %w", err)
+    }
+    for i, c := range columns {
+        columns[i] = strings.ToLower(strings.TrimSpace(c))
+    }
+    if !slices.Contains(columns, "name") || !slices.Contains(columns, "price") {
+        return nil, errors.New("в заголовке нужны колонки name и price")
+    }
+
+    var records []map[string]string
+    for {
+        row, err := r.Read()
+        if errors.Is(err, io.EOF) {
+            return records, nil
+        }
+        if err != nil {
+            return nil, fmt.Errorf("ошибка разбора CSV: %w", err)
+        }
+        rec := make(map[string]string, len(columns))
+        for i, v := range row {
+            if i < len(columns) {
+                rec[columns[i]] = v
+            }
+        }
+        records = append(records, rec)
+    }
+}
+
+// productFromRecord проверяет поля строки и собирает товар
+func productFromRecord(rec map[string]string, defaultCurrency string, categories categoryLookup) (storage.Product, error) {
+    field := func(name string) string { return strings.TrimSpace(rec[name]) }
+
+    p := storage.Product{
+        Name:        field("name"),
+        Description: field("description"),
+        ImageURL:    field("image_url"),
+        InStock:     true,
+    }
+    if p.Name == "" {
+        return storage.Product{}, errors.New("не указано название (name)")
+    }
+
+    if raw := field("id"); raw != "" {
+        id, err := strconv.ParseInt(raw, 10, 64)
+        if err != nil || id <= 0 {
+            return storage.Product{}, fmt.Errorf("некорректный id %q", raw)
+        }
+        p.ID = id
+    }
+
+    currency := strings.ToUpper(field("currency"))
+    if currency == "" {
+        currency = defaultCurrency
+    }
+    if len(currency) != 3 {
+        return storage.Product{}, fmt.Errorf("некорректная валюта %q", currency)
+    }
+    if field("price") == "" {
+        return storage.Product{}, errors.New("не указана цена (price)")
+    }
+    price, err := money.Parse(field("price"), currency)
+    if err != nil {
+        return storage.Product{}, err
+    }
+    p.Price = price
+
+    if raw := field("in_stock"); raw != "" {
+        inStock, ok := parseYesNo(raw)
+        if !ok {
+            return storage.Product{}, fmt.Errorf("некорректное значение in_stock %q", raw)
+        }
+        p.InStock = inStock
+    }
+
+    if raw := field("stock"); raw != "" {
+        stock, err := strconv.Atoi(raw)
+        if err != nil || stock < 0 {
+            return storage.Product{}, fmt.Errorf("некорректный остаток stock %q", raw)
+        }
+        p.Stock = &stock
+        // Нулевой остаток — товар закончился, даже если in_stock не указан
+        if stock == 0 {
+            p.InStock = false
+        }
+    }
+
+    // Категория — id, название или «Раздел/Категория»; пусто — не меняется
+    if raw := field("category"); raw != "" {
+        id, err := categories.resolve(raw)
+        if err != nil {
+            return storage.Product{}, err
+        }
+        p.CategoryID = id
+    }
+
+    if p.ImageURL != "" {
+        u, err := url.Parse(p.ImageURL)
+        if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+            return storage.Product{}, fmt.Errorf("некорректная ссылка на фото %q", p.ImageURL)
+        }
+    }
+    return p, nil
+}
+
+// parseYesNo понимает флаги, как их пишут в таблицах: true/false, 1/0, да/нет
+func parseYesNo(s string) (value, ok bool) {
+    switch strings.ToLower(s) {
+    case "true", "1", "yes", "y", "да", "+":
+        return true, true
+    case "false", "0", "no", "n", "нет", "-":
+        return false, true
+    }
+    return false, false
+}
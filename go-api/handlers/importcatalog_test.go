@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/money"
+)
+
+// importFile — админ присылает файл filename с подписью /importcatalog
+func importFile(tb *testBot, updateID, chatID int64, filename string, data string) TelegramUpdate {
+    fileID := "file-" + filename
+    if tb.tg.Files == nil {
+        tb.tg.Files = map[string][]byte{}
+    }
+    tb.tg.Files[fileID] = []byte(data)
+    return TelegramUpdate{UpdateID: updateID, Message: &TelegramMessage{
+        MessageID: updateID,
+        From:      &TelegramUser{ID: chatID, LanguageCode: "ru"},
+        Chat:      TelegramChat{ID: chatID, Type: "private"},
+        Caption:   "/importcatalog",
+        Document:  &TelegramDocument{FileID: fileID, FileName: filename, FileSize: int64(len(data))},
+    }}
+}
+
+// Таблица из Excel (BOM, точка с запятой, запятая в цене) загружается
+// целиком; повторная загрузка обновляет изменённое
+func TestImportCatalogWellFormed(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    const file = "\xef\xbb\xbfname;price;in_stock;stock\nСенча;450;да;10\nУлун;600,50;нет;\n"
+    tb.process(t, importFile(tb, 1, 1, "catalog.csv", file))
+
+    want := "Каталог загружен: добавлено 2, обновлено 0, без изменений 0, пропущено строк с ошибками 0."
+    if got := tb.sentTo(1); len(got) != 1 || got[0] != want {
+        t.Fatalf("отчёт %q, нужно %q", got, want)
+    }
+    products, _ := tb.catalog.ListProducts(context.Background(), 10, 0)
+    if len(products) != 2 || products[1].Name != "Улун" || products[1].Price != money.New(60050, "RUB") || products[1].InStock {
+        t.Fatalf("каталог после импорта %+v", products)
+    }
+
+    tb.process(t, importFile(tb, 2, 1, "catalog.json", `[{"name":"Сенча","price":450,"stock":10},{"name":"Улун","price":"650","in_stock":false}]`))
+    want = "Каталог загружен: добавлено 0, обновлено 1, без изменений 1, пропущено строк с ошибками 0."
+    if got := tb.sentTo(1); len(got) != 2 || got[1] != want {
+        t.Fatalf("отчёт повторной загрузки %q, нужно %q", got, want)
+    }
+}
+
+// Строки с ошибками пропускаются с номером строки файла, остальные
+// загружаются; в строгом режиме каталог не меняется вовсе
+func TestImportCatalogBadRows(t *testing.T) {
+    const file = "name,price,currency,image_url\n" +
+        "Сенча,450,,\n" +
+        ",300,,\n" +
+        "Пуэр,дорого,,\n" +
+        "Матча,25,US,\n" +
+        "Кружка,500,,ftp://cdn/mug.jpg\n" +
+        "Улун,600,RUB,https://cdn.example/oolong.jpg\n"
+
+    t.Run("пропуск строк", func(t *testing.T) {
+        tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+        tb.process(t, importFile(tb, 1, 1, "catalog.csv", file))
+
+        got := tb.sentTo(1)
+        if len(got) != 1 || !strings.HasPrefix(got[0], "Каталог загружен: добавлено 2, обновлено 0, без изменений 0, пропущено строк с ошибками 4.") {
+            t.Fatalf("отчёт %q", got)
+        }
+        for _, want := range []string{"строка 3: не указано название", "строка 4: некорректная сумма", "строка 5: некорректная валюта", "строка 6: некорректная ссылка на фото"} {
+            if !strings.Contains(got[0], want) {
+                t.Errorf("в отчёте нет %q:\n%s", want, got[0])
+            }
+        }
+        if n, _ := tb.catalog.CountProducts(context.Background()); n != 2 {
+            t.Fatalf("в каталоге %d товаров, нужно 2", n)
+        }
+    })
+
+    t.Run("строгий режим", func(t *testing.T) {
+        tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1", "CATALOG_IMPORT_STRICT": "true"})
+        tb.process(t, importFile(tb, 1, 1, "catalog.csv", file))
+
+        if got := tb.sentTo(1); len(got) != 1 || !strings.HasPrefix(got[0], "Импорт отменён") || !strings.Contains(got[0], "строка 3") {
+            t.Fatalf("отчёт %q", got)
+        }
+        if n, _ := tb.catalog.CountProducts(context.Background()); n != 0 {
+            t.Fatalf("строгий импорт с ошибками записал %d товаров", n)
+        }
+    })
+
+    t.Run("нет обязательных колонок", func(t *testing.T) {
+        tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+        tb.process(t, importFile(tb, 1, 1, "catalog.csv", "title,cost\nСенча,450\n"))
+        if got := tb.sentTo(1); len(got) != 1 || !strings.Contains(got[0], "name и price") {
+            t.Fatalf("отчёт %q", got)
+        }
+    })
+}
+
+func TestImportCatalogRequiresAdmin(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    tb.process(t, importFile(tb, 1, 42, "catalog.csv", "name,price\nСенча,1\n"))
+    if n, _ := tb.catalog.CountProducts(context.Background()); n != 0 {
+        t.Fatal("покупатель загрузил каталог")
+    }
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// langSetReply — ответ на /lang <код>, уже на выбранном языке
+const langSetReply = "Готово, теперь общаемся по-русски. Вернуть язык Telegram — /lang reset."
+
+// cmdLang — команда /lang [код|reset]: язык общения вместо языка
+// интерфейса Telegram. Без аргумента показывает текущий и доступные.
+// В группе язык меняется только у написавшего участника.
+func (b *Bot) cmdLang(ctx context.Context, msg *TelegramMessage, args string) error {
+    chatID := msg.Chat.ID
+    owner := langOwner(msg.Chat, msg.From)
+    supported := i18n.Supported()
+    code := strings.ToLower(strings.TrimSpace(args))
+    switch {
+    case code == "":
+        b.reply(ctx, chatID, fmt.Sprintf("Язык общения: %s. Доступно: %s.\nСменить — /lang <код>, вернуть язык Telegram — /lang reset.",
+            reqctx.LangFromContext(ctx), strings.Join(supported, ", ")))
+        return nil
+    case code == "reset":
+        code = ""
+    case !slices.Contains(supported, code):
+        return apperr.Validation(fmt.Sprintf("Язык %q не поддерживается. Доступно: %s.", code, strings.Join(supported, ", ")))
+    }
+
+    if err := b.Users.SetLang(ctx, owner, code); err != nil {
+        return apperr.WithMessage(err, "Не удалось сохранить настройку, попробуйте ещё раз.")
+    }
+    updateProfile(ctx, owner, func(u *storage.User) { u.PreferredLang = code })
+    lang := code
+    if lang == "" {
+        lang = msg.lang()
+    }
+    b.replyPhrase(reqctx.WithLang(ctx, lang), chatID, langSetReply)
+    return nil
+}
+
+// langOwner — чей профиль хранит язык общения: в личном чате это сам
+// чат, в группе — написавший участник, чтобы /lang одного не менял язык
+// всем. ID личного чата совпадает с ID пользователя, поэтому язык,
+// выбранный в личке, действует и в группах.
+func langOwner(chat TelegramChat, from *TelegramUser) int64 {
+    if chat.isGroup() && from != nil {
+        return from.ID
+    }
+    return chat.ID
+}
+
+// withPreferredLang заменяет в контексте язык из Telegram на выбранный
+// через /lang. В личном чате профиль уже загружен для апдейта, отдельного
+// чтения нет; в группе читается профиль участника. Если профиль
+// недоступен, остаётся язык Telegram.
+func (b *Bot) withPreferredLang(ctx context.Context, owner int64) context.Context {
+    u, err := b.profile(ctx, owner)
+    if err != nil {
+        if !errors.Is(err, storage.ErrNotFound) {
+            logging.FromContext(ctx).Warn("ошибка чтения языка покупателя", "user_id", owner, "err", err)
+        }
+        return ctx
+    }
+    if u.PreferredLang == "" {
+        return ctx
+    }
+    return reqctx.WithLang(ctx, u.PreferredLang)
+}
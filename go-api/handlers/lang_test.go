@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/i18n"
+    "ai_seller/memstore"
+)
+
+// memberCommand — команда участника from в группе groupID
+func memberCommand(updateID, from int64, body string) TelegramUpdate {
+    u := groupCommand(updateID, body)
+    u.Message.From = &TelegramUser{ID: from, FirstName: "Участник", LanguageCode: "ru"}
+    return u
+}
+
+// lastSent — последнее сообщение в чат
+func (tb *testBot) lastSent(t *testing.T, chatID int64) string {
+    t.Helper()
+    got := tb.sentTo(chatID)
+    if len(got) == 0 {
+        t.Fatalf("в чат %d ничего не отправлено", chatID)
+    }
+    return got[len(got)-1]
+}
+
+func TestLangInGroupIsPerMember(t *testing.T) {
+    const other int64 = 8
+    tb := newTestBot(t, groupEnv)
+    tb.process(t, memberCommand(1, memberID, "/lang en"))
+
+    tb.process(t, memberCommand(2, other, "/lang"))
+    if got := tb.lastSent(t, groupID); !strings.Contains(got, "Язык общения: ru") {
+        t.Fatalf("другому участнику: %q, ожидался его язык ru", got)
+    }
+    tb.process(t, memberCommand(3, memberID, "/lang"))
+    if got := tb.lastSent(t, groupID); !strings.Contains(got, "en") {
+        t.Fatalf("сменившему язык: %q, ожидался en", got)
+    }
+    if u, err := tb.users.GetUser(context.Background(), groupID); err == nil && u.PreferredLang != "" {
+        t.Fatalf("язык записан группе: %q", u.PreferredLang)
+    }
+    if u, _ := tb.users.GetUser(context.Background(), memberID); u.PreferredLang != "en" {
+        t.Fatalf("язык участника %q, ожидался en", u.PreferredLang)
+    }
+}
+
+func TestLangChosenInPrivateAppliesInGroup(t *testing.T) {
+    tb := newTestBot(t, groupEnv)
+    tb.process(t, text(1, memberID, "/lang en"))
+    tb.process(t, memberCommand(2, memberID, "/lang"))
+    if got := tb.lastSent(t, groupID); !strings.Contains(got, "en") {
+        t.Fatalf("в группе: %q, ожидался язык из лички en", got)
+    }
+}
+
+// Неизвестный код отклоняется со списком поддерживаемых языков, а язык не меняется
+func TestLangRejectsUnsupported(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/lang xx"))
+
+    got := tb.lastSent(t, 42)
+    if !strings.Contains(got, `Язык "xx" не поддерживается`) {
+        t.Fatalf("ответ на /lang xx: %q", got)
+    }
+    for _, lang := range i18n.Supported() {
+        if !strings.Contains(got, lang) {
+            t.Errorf("в ответе %q нет языка %s", got, lang)
+        }
+    }
+    if u, err := tb.users.GetUser(context.Background(), 42); err == nil && u.PreferredLang != "" {
+        t.Fatalf("записан язык %q", u.PreferredLang)
+    }
+}
+
+func TestPreferredLangReadsProfileOnce(t *testing.T) {
+    users := &countingUsers{Users: &memstore.Users{}}
+    tb := newTestBot(t, nil, func(d *Deps) { d.Users = users })
+    if err := users.SetLang(context.Background(), 42, "en"); err != nil {
+        t.Fatal(err)
+    }
+    tb.process(t, text(1, 42, "/lang"))
+
+    if got := tb.lastSent(t, 42); !strings.Contains(got, "en") {
+        t.Fatalf("ответ %q, ожидался выбранный язык en", got)
+    }
+    if n := users.reads.Load(); n != 1 {
+        t.Fatalf("профиль прочитан %d раз за апдейт, ожидался 1", n)
+    }
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "ai_seller/config"
+    "ai_seller/llm"
+    "ai_seller/logging"
+    "ai_seller/openai"
+)
+
+const (
+    // photoRetryReply — ответ, если фото не удалось скачать
+    photoRetryReply = "Не получилось открыть фото. Пожалуйста, отправьте его ещё раз."
+    // photoDisabledReply — ответ, если разбор фото выключен
+    photoDisabledReply = "К сожалению, я пока не умею смотреть фото. Опишите, пожалуйста, товар словами."
+    // defaultPhotoPrompt — вопрос к модели, если фото пришло без подписи
+    defaultPhotoPrompt = "Покупатель прислал фото. Опиши, что на нём, и предложи похожие товары."
+)
+
+// TelegramPhotoSize — один из размеров присланного фото
+type TelegramPhotoSize struct {
+    FileID   string `json:"file_id"`
+    Width    int    `json:"width"`
+    Height   int    `json:"height"`
+    FileSize int64  `json:"file_size"`
+}
+
+// largestPhoto выбирает самое крупное превью из присланных размеров
+func largestPhoto(sizes []TelegramPhotoSize) TelegramPhotoSize {
+    best := sizes[0]
+    for _, s := range sizes[1:] {
+        if s.Width*s.Height > best.Width*best.Height {
+            best = s
+        }
+    }
+    return best
+}
+
+// handlePhoto скачивает фото и отвечает на него через vision-модель
+func (b *Bot) handlePhoto(ctx context.Context, msg *TelegramMessage) {
+    chatID := msg.Chat.ID
+    if !b.featureEnabled(ctx, config.FlagVision) {
+        b.replyPhrase(ctx, chatID, photoDisabledReply)
+        return
+    }
+
+    if b.overBudget(ctx) {
+        b.replyPhrase(ctx, chatID, overBudgetReply)
+        return
+    }
+
+    if b.handedOff(ctx, chatID) {
+        b.relayToOperator(ctx, chatID, "[фото] "+msg.Caption)
+        return
+    }
+
+    now := time.Now()
+    switch b.offHoursMode(now) {
+    case "ack":
+        b.ackOffHours(ctx, chatID, "")
+        return
+    case "note":
+        b.noteOffHours(ctx, chatID, now)
+    }
+
+    photo := largestPhoto(msg.Photo)
+    data, contentType, err := b.downloadFile(ctx, photo.FileID, "image/")
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка скачивания фото", "chat_id", chatID, "file_id", photo.FileID, "err", err)
+        b.replyPhrase(ctx, chatID, photoRetryReply)
+        return
+    }
+
+    prompt := strings.TrimSpace(msg.Caption)
+    if prompt == "" {
+        prompt = defaultPhotoPrompt
+    }
+
+    ctx = b.withAnswerLength(ctx, chatID)
+    messages, prompt := b.buildContext(ctx, chatID, prompt)
+    messages = append(messages, openai.Message{
+        Role:  openai.RoleUser,
+        Parts: []openai.ContentPart{openai.TextPart(prompt), openai.ImagePart(data, contentType)},
+    })
+    b.remember(ctx, chatID, "user", "[фото] "+msg.Caption)
+
+    stopTyping := b.keepTyping(ctx, chatID)
+    answer, err := b.OpenAI.VisionCompletion(ctx, messages)
+    stopTyping()
+    if errors.Is(err, llm.ErrUnsupported) {
+        logging.FromContext(ctx).Warn("поставщик модели не разбирает фото", "chat_id", chatID)
+        b.replyPhrase(ctx, chatID, photoDisabledReply)
+        return
+    }
+    if err != nil {
+        b.aiUnavailable(ctx, chatID, err)
+        return
+    }
+
+    if reply, err := b.sendReply(ctx, chatID, answer); err == nil {
+        b.remember(ctx, chatID, "assistant", reply.Text)
+    }
+}
+
+// downloadFile получает файл Telegram по file_id и проверяет, что его тип
+// начинается с одного из allowed (например, "image/"); без allowed подходит любой.
+// Это отсекает, скажем, документ, присланный вместо фото, до запроса к модели.
+func (b *Bot) downloadFile(ctx context.Context, fileID string, allowed ...string) ([]byte, string, error) {
+    data, contentType, err := b.Telegram.DownloadFile(ctx, fileID)
+    if err != nil {
+        return nil, "", err
+    }
+    if len(allowed) == 0 {
+        return data, contentType, nil
+    }
+    for _, prefix := range allowed {
+        if strings.HasPrefix(contentType, prefix) {
+            return data, contentType, nil
+        }
+    }
+    return nil, "", fmt.Errorf("неожиданный тип файла %s: %q", fileID, contentType)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "slices"
+    "testing"
+
+    "ai_seller/llm"
+)
+
+var mediaEnv = map[string]string{"VOICE_ENABLED": "true", "VISION_ENABLED": "true"}
+
+// Поставщик без распознавания речи или разбора фото (Ollama) — ответ
+// «не умею» вместо FALLBACK_MESSAGE
+func TestMediaUnsupportedByProvider(t *testing.T) {
+    cases := []struct {
+        name string
+        msg  func(m *TelegramMessage)
+        want string
+    }{
+        {"голосовое", func(m *TelegramMessage) {
+            m.Voice = &TelegramVoice{FileID: "voice", Duration: 3}
+        }, voiceDisabledReply},
+        {"фото", func(m *TelegramMessage) {
+            m.Photo = []TelegramPhotoSize{{FileID: "photo", Width: 10, Height: 10}}
+        }, photoDisabledReply},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, mediaEnv)
+            tb.ai.Err = fmt.Errorf("распознавание: %w", llm.ErrUnsupported)
+            tb.tg.Files = map[string][]byte{
+                "voice": []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00"),
+                "photo": []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"),
+            }
+
+            u := text(1, 42, "")
+            tc.msg(u.Message)
+            tb.process(t, u)
+
+            got := tb.sentTo(42)
+            if !slices.Contains(got, tc.want) {
+                t.Fatalf("ответы %q, нужен %q", got, tc.want)
+            }
+            if slices.Contains(got, tb.Config.FallbackMessage) {
+                t.Fatalf("ответы %q: FALLBACK_MESSAGE вместо «не умею»", got)
+            }
+        })
+    }
+}
+
+// Файл, тип которого не совпал с ожидаемым (текст под видом фото), не уходит
+// модели: покупатель получает просьбу прислать фото ещё раз
+func TestPhotoWrongContentType(t *testing.T) {
+    tb := newTestBot(t, mediaEnv)
+    tb.ai.Reply = "ответ модели"
+    tb.tg.Files = map[string][]byte{"photo": []byte("это не картинка")}
+
+    u := text(1, 42, "")
+    u.Message.Photo = []TelegramPhotoSize{{FileID: "photo", Width: 10, Height: 10}}
+    tb.process(t, u)
+
+    got := tb.sentTo(42)
+    if !slices.Contains(got, photoRetryReply) || slices.Contains(got, "ответ модели") {
+        t.Fatalf("ответы %q, нужен %q без ответа модели", got, photoRetryReply)
+    }
+}
+
+// Пустое, из одних пробелов или нетекстовое сообщение получает подсказку
+// написать текстом и до модели не доходит
+func TestNonTextMessageHint(t *testing.T) {
+    const textHint = "Напишите, пожалуйста, ваш вопрос текстом."
+    cases := []struct {
+        name string
+        msg  func(m *TelegramMessage)
+        want string
+    }{
+        {"пустое", func(m *TelegramMessage) {}, textHint},
+        {"одни пробелы", func(m *TelegramMessage) { m.Text = " \n\t " }, textHint},
+        {"стикер", func(m *TelegramMessage) {
+            m.Sticker = json.RawMessage(`{"file_id":"sticker","emoji":"👍"}`)
+        }, "Отличный стикер! Напишите, пожалуйста, ваш вопрос текстом — я помогу подобрать товар."},
+        {"геопозиция", func(m *TelegramMessage) {
+            m.Location = json.RawMessage(`{"latitude":55.75,"longitude":37.61}`)
+        }, "Спасибо! Чтобы рассчитать доставку, напишите, пожалуйста, адрес текстом."},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, mediaEnv)
+            u := text(1, 42, "")
+            tc.msg(u.Message)
+            tb.process(t, u)
+
+            if got := tb.sentTo(42); len(got) != 1 || got[0] != tc.want {
+                t.Fatalf("отправлено %q, ожидалось %q", got, tc.want)
+            }
+            if len(tb.ai.Requests) != 0 {
+                t.Fatalf("%d запросов к модели", len(tb.ai.Requests))
+            }
+        })
+    }
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "context"
+
+    "ai_seller/logging"
+)
+
+// TelegramChatMemberUpdated — смена статуса участника чата; в my_chat_member
+// участник — сам бот
+type TelegramChatMemberUpdated struct {
+    Chat          TelegramChat       `json:"chat"`
+    From          TelegramUser       `json:"from"`
+    OldChatMember TelegramChatMember `json:"old_chat_member"`
+    NewChatMember TelegramChatMember `json:"new_chat_member"`
+}
+
+// TelegramChatMember — статус участника: creator, administrator, member,
+// restricted, left или kicked
+type TelegramChatMember struct {
+    Status string `json:"status"`
+    // IsMember — для restricted: остаётся ли участник в чате
+    IsMember bool `json:"is_member"`
+}
+
+// present — бот может писать в чат с этим статусом
+func (m TelegramChatMember) present() bool {
+    switch m.Status {
+    case "creator", "administrator", "member":
+        return true
+    case "restricted":
+        return m.IsMember
+    }
+    return false
+}
+
+// handleMyChatMember отслеживает статус бота в чате. Заблокировавший бота
+// покупатель и группа, откуда бота удалили, помечаются неактивными, чтобы
+// рассылки и напоминания их пропускали; разблокировка и добавление в группу
+// пометку снимают, группа записывается.
+func (b *Bot) handleMyChatMember(ctx context.Context, upd *TelegramChatMemberUpdated) error {
+    chat := upd.Chat
+    log := logging.FromContext(ctx).With("chat_id", chat.ID, "old_status", upd.OldChatMember.Status, "new_status", upd.NewChatMember.Status)
+
+    if !upd.NewChatMember.present() {
+        if upd.OldChatMember.present() {
+            log.Info("бот заблокирован или удалён из чата")
+        }
+        if chat.isGroup() {
+            if err := b.Chats.LeaveGroup(ctx, chat.ID); err != nil {
+                return err
+            }
+        }
+        return b.Chats.MarkInactive(ctx, chat.ID)
+    }
+
+    if chat.isGroup() {
+        if err := b.Chats.JoinGroup(ctx, chat.ID, chat.Title); err != nil {
+            return err
+        }
+    }
+    if !upd.OldChatMember.present() {
+        log.Info("бот снова доступен в чате", "title", chat.Title)
+    }
+    return b.Chats.MarkActive(ctx, chat.ID)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import "testing"
+
+// Заблокировавший бота покупатель выпадает из рассылок, разблокировка
+// возвращает его
+func TestMyChatMemberBlock(t *testing.T) {
+    tb := newTestBot(t, nil)
+    ctx := t.Context()
+    if err := tb.messages.SaveMessage(ctx, 42, "user", "привет"); err != nil {
+        t.Fatal(err)
+    }
+
+    tb.process(t, myChatMember(1, 42, "member", "kicked"))
+    if !tb.chats.Inactive(42) {
+        t.Fatal("заблокировавший бота чат не помечен неактивным")
+    }
+    if active, err := tb.chats.ActiveChatIDs(ctx); err != nil || len(active) != 0 {
+        t.Fatalf("активные чаты %v (%v): заблокированный остался в рассылках", active, err)
+    }
+
+    tb.process(t, myChatMember(2, 42, "kicked", "member"))
+    if tb.chats.Inactive(42) {
+        t.Fatal("разблокировка не сняла пометку")
+    }
+    if len(tb.tg.Messages()) != 0 {
+        t.Fatalf("на смену статуса бот написал: %+v", tb.tg.Messages())
+    }
+}
+
+// groupMembership — бота в группе groupID перевели из from в to
+func groupMembership(updateID int64, from, to TelegramChatMember) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, MyChatMember: &TelegramChatMemberUpdated{
+        Chat:          TelegramChat{ID: groupID, Type: "supergroup", Title: "Чайный клуб"},
+        From:          TelegramUser{ID: memberID},
+        OldChatMember: from,
+        NewChatMember: to,
+    }}
+}
+
+// Добавление в группу записывает её, удаление отмечает уход и выключает
+// группу из рассылок, повторное добавление возвращает
+func TestMyChatMemberGroup(t *testing.T) {
+    var (
+        left       = TelegramChatMember{Status: "left"}
+        member     = TelegramChatMember{Status: "member"}
+        kicked     = TelegramChatMember{Status: "kicked"}
+        restricted = TelegramChatMember{Status: "restricted", IsMember: true}
+        muted      = TelegramChatMember{Status: "restricted"}
+    )
+    tb := newTestBot(t, nil)
+
+    steps := []struct {
+        name         string
+        from, to     TelegramChatMember
+        joined, gone bool
+    }{
+        {"добавили", left, member, true, false},
+        {"ограничили, но оставили", member, restricted, true, false},
+        {"ограничили и вывели", restricted, muted, false, true},
+        {"вернули", muted, member, true, false},
+        {"удалили", member, kicked, false, true},
+    }
+    for i, s := range steps {
+        tb.process(t, groupMembership(int64(i+1), s.from, s.to))
+        title, joined := tb.chats.Group(groupID)
+        if title != "Чайный клуб" || joined != s.joined {
+            t.Fatalf("%s: группа %q, в ней бот: %v, нужно %v", s.name, title, joined, s.joined)
+        }
+        if tb.chats.Inactive(groupID) != s.gone {
+            t.Fatalf("%s: неактивна %v, нужно %v", s.name, !s.gone, s.gone)
+        }
+    }
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+const (
+    // menuButtonsPerRow — кнопок меню в ряд: больше не помещаются на узком экране
+    menuButtonsPerRow = 3
+    // menuShownReply — сообщение, с которым показывается меню
+    menuShownReply = "Меню — на кнопках под полем ввода."
+    // menuHiddenReply — ответ на /menu off
+    menuHiddenReply = "Меню скрыто. Вернуть его — /menu."
+)
+
+// menuKeyboard — постоянное меню из MENU_BUTTONS на языке lang; nil, если меню выключено
+func (b *Bot) menuKeyboard(lang string) *telegram.ReplyKeyboard {
+    buttons := b.Config.MenuButtons
+    if len(buttons) == 0 {
+        return nil
+    }
+    kb := telegram.NewReplyKeyboard()
+    for i := 0; i < len(buttons); i += menuButtonsPerRow {
+        var row []string
+        for _, btn := range buttons[i:min(i+menuButtonsPerRow, len(buttons))] {
+            row = append(row, i18n.T(lang, btn.Label))
+        }
+        kb.Row(row...)
+    }
+    return kb
+}
+
+// menuCommand — команда кнопки меню, нажатие которой пришло текстом text.
+// Текст сверяется и с исходной надписью, и с переводом: меню могли
+// показать до того, как у покупателя сменился язык. Пока чат скрыл меню,
+// и для кнопок без публичной команды надпись — обычный текст для модели.
+func (b *Bot) menuCommand(ctx context.Context, chatID int64, text string) (string, bool) {
+    text = strings.TrimSpace(text)
+    if text == "" || b.menuHidden(ctx, chatID) {
+        return "", false
+    }
+    lang := reqctx.LangFromContext(ctx)
+    for _, btn := range b.Config.MenuButtons {
+        if text != btn.Label && text != i18n.T(lang, btn.Label) {
+            continue
+        }
+        if cmd, ok := b.commands[btn.Command]; !ok || cmd.adminOnly {
+            return "", false
+        }
+        return btn.Command, true
+    }
+    return "", false
+}
+
+// menuHidden — чат скрыл меню через /menu off
+func (b *Bot) menuHidden(ctx context.Context, chatID int64) bool {
+    u, err := b.profile(ctx, chatID)
+    return err == nil && u.MenuHidden
+}
+
+// setMenuHidden запоминает видимость меню; ошибка записи не мешает ответу
+func (b *Bot) setMenuHidden(ctx context.Context, chatID int64, hidden bool) {
+    if err := b.Users.SetMenuHidden(ctx, chatID, hidden); err != nil {
+        logging.FromContext(ctx).Error("ошибка сохранения видимости меню", "chat_id", chatID, "err", err)
+        return
+    }
+    updateProfile(ctx, chatID, func(u *storage.User) { u.MenuHidden = hidden })
+}
+
+// cmdMenu — команда /menu: показать меню под полем ввода, /menu off — скрыть
+func (b *Bot) cmdMenu(ctx context.Context, msg *TelegramMessage, args string) error {
+    lang := reqctx.LangFromContext(ctx)
+    if strings.EqualFold(strings.TrimSpace(args), "off") {
+        b.setMenuHidden(ctx, msg.Chat.ID, true)
+        _, err := b.send(ctx, msg.Chat.ID, i18n.T(lang, menuHiddenReply), telegram.WithReplyMarkup(telegram.RemoveKeyboard()))
+        return err
+    }
+
+    kb := b.menuKeyboard(lang)
+    if kb == nil {
+        return apperr.Validation("Меню не настроено.")
+    }
+    b.setMenuHidden(ctx, msg.Chat.ID, false)
+    _, err := b.send(ctx, msg.Chat.ID, i18n.T(lang, menuShownReply), telegram.WithReplyMarkup(kb))
+    return err
+}
+
+// checkMenuCommands предупреждает о кнопках меню, за которыми нет команды:
+// нажатие такой кнопки уйдёт модели как обычный текст
+func (b *Bot) checkMenuCommands() {
+    for _, btn := range b.Config.MenuButtons {
+        if cmd, ok := b.commands[btn.Command]; !ok || cmd.adminOnly {
+            logging.Logger().Warn("кнопка меню ссылается на неизвестную команду", "button", btn.Label, "command", btn.Command)
+        }
+    }
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+)
+
+func TestMenuButtonRunsCommand(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "Помощь"))
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("нажатие кнопки ушло модели: %v", tb.ai.Requests)
+    }
+    if len(tb.sentTo(42)) == 0 {
+        t.Fatal("на кнопку не ответили")
+    }
+}
+
+func TestMenuHiddenButtonGoesToModel(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/menu off"))
+    if u, _ := tb.users.GetUser(context.Background(), 42); !u.MenuHidden {
+        t.Fatal("/menu off не запомнился")
+    }
+    tb.process(t, text(2, 42, "Помощь"))
+    if len(tb.ai.Requests) != 1 {
+        t.Fatalf("при скрытом меню запросов к модели: получено %d, ожидался 1", len(tb.ai.Requests))
+    }
+
+    tb.process(t, text(3, 42, "/menu"))
+    tb.process(t, text(4, 42, "Помощь"))
+    if len(tb.ai.Requests) != 1 {
+        t.Fatalf("после /menu кнопка ушла модели")
+    }
+}
+
+func TestMenuButtonWithoutCommandGoesToModel(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"MENU_BUTTONS": "Скидки=sales,Статистика=stats"})
+    tb.process(t, text(1, 42, "Скидки"))
+    tb.process(t, text(2, 42, "Статистика"))
+    if len(tb.ai.Requests) != 2 {
+        t.Fatalf("запросов к модели: получено %d, ожидалось 2", len(tb.ai.Requests))
+    }
+}
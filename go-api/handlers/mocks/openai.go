@@ -0,0 +1,57 @@
+package mocks
+
+import (
+    "context"
+    "sync"
+
+    "ai_seller/openai"
+)
+
+// OpenAI — фейк модели: на любой запрос отвечает Reply или возвращает Err
+// и запоминает присланные диалоги
+type OpenAI struct {
+    mu sync.Mutex
+
+    Reply    string
+    Err      error
+    Requests [][]openai.Message
+    // Transcript — ответ Transcribe
+    Transcript string
+}
+
+// ChatCompletion отвечает Reply
+func (o *OpenAI) ChatCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    o.Requests = append(o.Requests, messages)
+    return o.Reply, o.Err
+}
+
+// ChatWithTools отвечает Reply, инструменты не вызывает
+func (o *OpenAI) ChatWithTools(ctx context.Context, messages []openai.Message, tools *openai.ToolRegistry) (string, error) {
+    return o.ChatCompletion(ctx, messages)
+}
+
+// VisionCompletion отвечает Reply
+func (o *OpenAI) VisionCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    return o.ChatCompletion(ctx, messages)
+}
+
+// Transcribe возвращает Transcript или Err
+func (o *OpenAI) Transcribe(ctx context.Context, audio []byte, filename string) (string, error) {
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    return o.Transcript, o.Err
+}
+
+// ChatCompletionStream отдаёт Reply одним фрагментом
+func (o *OpenAI) ChatCompletionStream(ctx context.Context, messages []openai.Message) (<-chan openai.StreamChunk, error) {
+    reply, err := o.ChatCompletion(ctx, messages)
+    if err != nil {
+        return nil, err
+    }
+    chunks := make(chan openai.StreamChunk, 1)
+    chunks <- openai.StreamChunk{Delta: reply}
+    close(chunks)
+    return chunks, nil
+}
@@ -0,0 +1,212 @@
+// Package mocks — фейки Telegram и OpenAI в памяти для тестов обработчиков
+// без сети
+package mocks
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "sync"
+    "time"
+
+    "ai_seller/telegram"
+)
+
+// SentMessage — сообщение, «отправленное» фейком
+type SentMessage struct {
+    ChatID    int64
+    MessageID int64
+    Text      string
+    // ReplyTo — сообщение, которое цитирует ответ (WithReplyTo), или 0
+    ReplyTo int64
+    // ParseMode — разметка сообщения; без WithParseMode — MarkdownV2,
+    // как у клиента по умолчанию
+    ParseMode string
+    // Markup — клавиатура сообщения (WithReplyMarkup) или nil
+    Markup telegram.ReplyMarkup
+}
+
+// SentDocument — файл, «отправленный» фейком
+type SentDocument struct {
+    ChatID   int64
+    Filename string
+    Data     []byte
+}
+
+// Telegram — фейк Bot API: запоминает отправленное, ничего не шлёт.
+// Err, если задан, возвращается из методов отправки.
+type Telegram struct {
+    mu sync.Mutex
+
+    Err      error
+    Sent     []SentMessage
+    Answered []string
+    Actions  []string
+    // Files — содержимое файлов по file_id для DownloadFile
+    Files map[string][]byte
+    // Restricted — ограничен ли участник группы: [чат][участник]
+    Restricted map[int64]map[int64]bool
+    // Banned — удалённые из групп участники: пары {чат, участник}
+    Banned [][2]int64
+    // Admins — администраторы групп: [группа][участник]
+    Admins map[int64]map[int64]bool
+    // Documents — отправленные файлы по порядку
+    Documents []SentDocument
+    // Updates — пачки апдейтов, которые по одной на вызов отдаёт GetUpdates
+    Updates [][]json.RawMessage
+
+    nextID int64
+}
+
+// SendMessage запоминает сообщение и выдаёт ему очередной message_id
+func (t *Telegram) SendMessage(chatID int64, text string, opts ...telegram.SendOption) (int64, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.Err != nil {
+        return 0, t.Err
+    }
+    t.nextID++
+    t.Sent = append(t.Sent, SentMessage{
+        ChatID:    chatID,
+        MessageID: t.nextID,
+        Text:      text,
+        ReplyTo:   telegram.ReplyToOf(opts...),
+        ParseMode: telegram.ParseModeOf(telegram.ParseModeMarkdownV2, opts...),
+        Markup:    telegram.ReplyMarkupOf(opts...),
+    })
+    return t.nextID, nil
+}
+
+// SendPhoto запоминает фото как сообщение с текстом "<ссылка> <подпись>"
+func (t *Telegram) SendPhoto(chatID int64, photoURL, caption string, opts ...telegram.SendOption) error {
+    _, err := t.SendMessage(chatID, photoURL+" "+caption)
+    return err
+}
+
+// SendDocument запоминает файл в Documents и как сообщение с текстом
+// "<имя файла> <подпись>"
+func (t *Telegram) SendDocument(ctx context.Context, chatID int64, filename string, data []byte, caption string) error {
+    if _, err := t.SendMessage(chatID, filename+" "+caption); err != nil {
+        return err
+    }
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.Documents = append(t.Documents, SentDocument{ChatID: chatID, Filename: filename, Data: append([]byte(nil), data...)})
+    return nil
+}
+
+// EditMessageText заменяет текст и клавиатуру ранее отправленного сообщения;
+// как в Bot API, правка без клавиатуры её убирает
+func (t *Telegram) EditMessageText(chatID, messageID int64, text string, opts ...telegram.SendOption) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.Err != nil {
+        return t.Err
+    }
+    for i := range t.Sent {
+        if t.Sent[i].ChatID == chatID && t.Sent[i].MessageID == messageID {
+            t.Sent[i].Text = text
+            t.Sent[i].Markup = telegram.ReplyMarkupOf(opts...)
+        }
+    }
+    return nil
+}
+
+// DeleteMessage убирает сообщение из отправленных
+func (t *Telegram) DeleteMessage(chatID, messageID int64) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.Err != nil {
+        return t.Err
+    }
+    for i := range t.Sent {
+        if t.Sent[i].ChatID == chatID && t.Sent[i].MessageID == messageID {
+            t.Sent = append(t.Sent[:i], t.Sent[i+1:]...)
+            break
+        }
+    }
+    return nil
+}
+
+// SendChatAction запоминает статус чата
+func (t *Telegram) SendChatAction(chatID int64, action string) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.Actions = append(t.Actions, action)
+    return nil
+}
+
+// AnswerCallbackQuery запоминает подтверждённое нажатие кнопки
+func (t *Telegram) AnswerCallbackQuery(callbackID string) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.Answered = append(t.Answered, callbackID)
+    return nil
+}
+
+// RestrictChatMember запоминает ограничение участника: Restricted[чат][участник]
+func (t *Telegram) RestrictChatMember(chatID, userID int64, allowed bool) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.Restricted == nil {
+        t.Restricted = make(map[int64]map[int64]bool)
+    }
+    if t.Restricted[chatID] == nil {
+        t.Restricted[chatID] = make(map[int64]bool)
+    }
+    t.Restricted[chatID][userID] = !allowed
+    return nil
+}
+
+// BanChatMember запоминает удалённого из группы участника
+func (t *Telegram) BanChatMember(chatID, userID int64, until time.Time) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.Banned = append(t.Banned, [2]int64{chatID, userID})
+    return nil
+}
+
+// IsChatAdmin сверяется с Admins
+func (t *Telegram) IsChatAdmin(chatID, userID int64) (bool, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.Err != nil {
+        return false, t.Err
+    }
+    return t.Admins[chatID][userID], nil
+}
+
+// DownloadFile отдаёт содержимое файла из Files с типом, определённым по содержимому
+func (t *Telegram) DownloadFile(ctx context.Context, fileID string) ([]byte, string, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    data := t.Files[fileID]
+    return data, http.DetectContentType(data), nil
+}
+
+// GetUpdates отдаёт очередную пачку из Updates; когда они кончились, ждёт
+// отмены контекста
+func (t *Telegram) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]json.RawMessage, error) {
+    t.mu.Lock()
+    if len(t.Updates) > 0 {
+        batch := t.Updates[0]
+        t.Updates = t.Updates[1:]
+        t.mu.Unlock()
+        return batch, nil
+    }
+    t.mu.Unlock()
+    <-ctx.Done()
+    return nil, ctx.Err()
+}
+
+// DeleteWebhook ничего не делает
+func (t *Telegram) DeleteWebhook() error {
+    return nil
+}
+
+// Messages возвращает копию отправленных сообщений
+func (t *Telegram) Messages() []SentMessage {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return append([]SentMessage(nil), t.Sent...)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+    "context"
+
+    "ai_seller/config"
+    "ai_seller/logging"
+)
+
+// moderationReply — ответ на сообщение, которое не прошло модерацию
+const moderationReply = "Не могу ответить на такое сообщение. Давайте вернёмся к выбору товара."
+
+// wordlistCategory — категория в логе, когда сообщение остановил словарь
+const wordlistCategory = "wordlist"
+
+// moderationFlag проверяет входящий текст модерацией OpenAI; если поставщик
+// её не умеет или она не ответила — словарём MODERATION_WORDS_FILE.
+// Возвращает категорию нарушения и true, если отвечать на текст нельзя.
+func (b *Bot) moderationFlag(ctx context.Context, chatID int64, text string) (string, bool) {
+    if !b.featureEnabled(ctx, config.FlagModeration) {
+        return "", false
+    }
+
+    if m, ok := b.OpenAI.(Moderator); ok {
+        res, err := m.Moderate(ctx, text)
+        if err == nil {
+            category, score := res.Top()
+            return category, score >= b.Config.ModerationThreshold
+        }
+        logging.FromContext(ctx).Warn("модерация OpenAI недоступна, проверяем словарём", "chat_id", chatID, "err", err)
+    }
+    if b.ModerationWords != nil {
+        if _, ok := b.ModerationWords.Apply(text); !ok {
+            return wordlistCategory, true
+        }
+    }
+    return "", false
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "slices"
+    "testing"
+
+    "ai_seller/filter"
+    "ai_seller/handlers/mocks"
+    "ai_seller/openai"
+)
+
+// fakeModerator — модель с модерацией: отвечает result или err
+type fakeModerator struct {
+    *mocks.OpenAI
+    result openai.ModerationResult
+    err    error
+    calls  int
+}
+
+func (m *fakeModerator) Moderate(ctx context.Context, text string) (openai.ModerationResult, error) {
+    m.calls++
+    return m.result, m.err
+}
+
+func scores(category string, score float64) openai.ModerationResult {
+    return openai.ModerationResult{Scores: map[string]float64{category: score, "violence": 0.01}}
+}
+
+func TestModeration(t *testing.T) {
+    words, err := filter.New(filter.Rules{Blocked: []string{"казино"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    cases := []struct {
+        name      string
+        moderator *fakeModerator
+        words     *filter.Filter
+        text      string
+        blocked   bool
+    }{
+        {"оценка выше порога", &fakeModerator{result: scores("harassment", 0.9)}, nil, "ты тупой бот", true},
+        {"оценка ровно на пороге", &fakeModerator{result: scores("harassment", 0.5)}, nil, "ну ты даёшь", true},
+        {"оценка ниже порога", &fakeModerator{result: scores("harassment", 0.2)}, words, "есть улун?", false},
+        {"модерация упала — словарь", &fakeModerator{err: errors.New("timeout")}, words, "лучшее казино тут", true},
+        {"модерация упала, словарь пропустил", &fakeModerator{err: errors.New("timeout")}, words, "есть улун?", false},
+        {"без модерации у поставщика — словарь", nil, words, "Казино!", true},
+        {"без модерации и словаря", nil, nil, "казино", false},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, map[string]string{"MODERATION_ENABLED": "true"})
+            tb.ModerationWords = tc.words
+            if tc.moderator != nil {
+                tc.moderator.OpenAI = tb.ai
+                tb.OpenAI = tc.moderator
+            }
+            tb.process(t, text(1, 42, tc.text))
+
+            got := tb.sentTo(42)
+            if blocked := slices.Contains(got, moderationReply); blocked != tc.blocked {
+                t.Fatalf("ответы %q: отклонено %v, нужно %v", got, blocked, tc.blocked)
+            }
+            if asked := len(tb.ai.Requests) > 0; asked == tc.blocked {
+                t.Fatalf("запросов к модели %d при отклонении %v", len(tb.ai.Requests), tc.blocked)
+            }
+        })
+    }
+}
+
+func TestModerationDisabled(t *testing.T) {
+    tb := newTestBot(t, nil)
+    m := &fakeModerator{OpenAI: tb.ai, result: scores("harassment", 1)}
+    tb.OpenAI = m
+    tb.process(t, text(1, 42, "ты тупой бот"))
+    if m.calls != 0 || slices.Contains(tb.sentTo(42), moderationReply) {
+        t.Fatalf("модерация без MODERATION_ENABLED: вызовов %d, ответы %q", m.calls, tb.sentTo(42))
+    }
+}
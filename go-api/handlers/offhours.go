@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "context"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/telegram"
+)
+
+// offHoursMode — как отвечать на сообщение, пришедшее в момент now: пусто
+// в рабочее время, иначе Config.OffHoursMode (note или ack)
+func (b *Bot) offHoursMode(now time.Time) string {
+    if !b.Config.OffHours(now) {
+        return ""
+    }
+    return b.Config.OffHoursMode
+}
+
+// noteOffHours в режиме note предупреждает о нерабочем времени перед
+// ответом модели — один раз за нерабочий период чата, а не перед каждым
+// ответом до утра. Если отметку не записать, фраза уходит: лучше повторить
+// её, чем промолчать о нерабочем времени.
+func (b *Bot) noteOffHours(ctx context.Context, chatID int64, now time.Time) {
+    if b.OffHoursNotes != nil {
+        claimed, err := b.OffHoursNotes.Claim(ctx, chatID, now, b.Config.BusinessHours.NextOpen(now))
+        if err != nil {
+            logging.FromContext(ctx).Warn("не удалось отметить фразу о нерабочем времени", "chat_id", chatID, "err", err)
+        }
+        if err == nil && !claimed {
+            return
+        }
+    }
+    b.replyPhrase(ctx, chatID, b.Config.OffHoursMessage)
+}
+
+// ackOffHours отвечает вне часов работы без модели: сообщение сохраняется
+// в историю, чтобы менеджер увидел его утром, а покупатель получает
+// OffHoursMessage. С OFF_HOURS_SEARCH текст сначала ищется в каталоге.
+func (b *Bot) ackOffHours(ctx context.Context, chatID int64, text string) {
+    logging.FromContext(ctx).Info("нерабочее время, ответ без модели", "chat_id", chatID)
+    if text != "" {
+        b.remember(ctx, chatID, "user", text)
+        if b.Config.OffHoursSearch {
+            b.offHoursSearch(ctx, chatID, text)
+        }
+    }
+    b.replyPhrase(ctx, chatID, b.Config.OffHoursMessage)
+}
+
+// offHoursSearch показывает товары, подходящие под текст сообщения; если
+// ничего не нашлось или поиск не удался, покупатель получит только
+// OffHoursMessage
+func (b *Bot) offHoursSearch(ctx context.Context, chatID int64, text string) {
+    products, err := b.findProducts(ctx, text)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка поиска в нерабочее время", "chat_id", chatID, "err", err)
+        return
+    }
+    if len(products) == 0 {
+        return
+    }
+    if _, err := b.Telegram.SendMessage(chatID, renderSearchResults(products, b.prices(ctx)), telegram.WithParseMode("")); err != nil {
+        logging.FromContext(ctx).Error("ошибка отправки результатов поиска", "chat_id", chatID, "err", err)
+    }
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+    "time"
+)
+
+// closedNow — BUSINESS_HOURS, по которым магазин сейчас закрыт: один час
+// через два дня
+func closedNow() string {
+    day := time.Now().AddDate(0, 0, 2).Weekday().String()
+    return strings.ToLower(day[:3]) + " 10:00-11:00"
+}
+
+func TestOffHoursNoteOncePerPeriod(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"BUSINESS_HOURS": closedNow(), "OFF_HOURS_MODE": "note"})
+    note := tb.Config.OffHoursMessage
+
+    for i := int64(1); i <= 3; i++ {
+        tb.process(t, text(i, 42, "есть улун?"))
+    }
+    got := tb.sentTo(42)
+    var notes, answers int
+    for _, m := range got {
+        switch m {
+        case note:
+            notes++
+        case "ответ модели":
+            answers++
+        }
+    }
+    if notes != 1 || answers != 3 {
+        t.Fatalf("отправлено %q: фраза о нерабочем времени %d раз, ответов %d; нужно 1 и 3", got, notes, answers)
+    }
+    if got[0] != note {
+        t.Fatalf("первым отправлено %q, нужна фраза о нерабочем времени", got[0])
+    }
+
+    // Другой чат получает фразу сам
+    tb.process(t, text(4, 43, "есть улун?"))
+    if got := tb.sentTo(43); len(got) == 0 || got[0] != note {
+        t.Fatalf("другому чату: %q, нужна фраза о нерабочем времени", got)
+    }
+}
+
+// Следующий нерабочий период — новая фраза
+func TestOffHoursNoteNextPeriod(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"BUSINESS_HOURS": "mon-fri 09:00-18:00"})
+    msk := tb.Config.BusinessHours.Location
+    // 12 октября 2026 — понедельник
+    evening := time.Date(2026, 10, 12, 20, 0, 0, 0, msk)
+    night := time.Date(2026, 10, 13, 3, 0, 0, 0, msk)
+    nextEvening := time.Date(2026, 10, 13, 19, 0, 0, 0, msk)
+
+    ctx := context.Background()
+    for _, step := range []struct {
+        now  time.Time
+        want int
+    }{{evening, 1}, {night, 1}, {nextEvening, 2}} {
+        tb.noteOffHours(ctx, 42, step.now)
+        if got := len(tb.sentTo(42)); got != step.want {
+            t.Fatalf("%s: фраз о нерабочем времени %d, нужно %d", step.now, got, step.want)
+        }
+    }
+}
+
+// В режиме ack фраза — единственный ответ, она идёт на каждое сообщение
+func TestOffHoursAckEveryMessage(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"BUSINESS_HOURS": closedNow(), "OFF_HOURS_MODE": "ack", "OFF_HOURS_SEARCH": "false"})
+    for i := int64(1); i <= 2; i++ {
+        tb.process(t, text(i, 42, "есть улун?"))
+    }
+    note := tb.Config.OffHoursMessage
+    if got := tb.sentTo(42); len(got) != 2 || got[0] != note || got[1] != note {
+        t.Fatalf("отправлено %q, нужна фраза о нерабочем времени на каждое сообщение", got)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("запросов к модели %d в режиме ack", len(tb.ai.Requests))
+    }
+}
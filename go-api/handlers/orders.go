@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// orderStatusLabels — статусы заказа в том виде, в каком их видит покупатель
+var orderStatusLabels = map[storage.OrderStatus]string{
+    storage.OrderDraft:     "📝 не оформлен",
+    storage.OrderNew:       "🕐 принят, ждёт оплаты",
+    storage.OrderPaid:      "💳 оплачен, готовится к отправке",
+    storage.OrderShipped:   "🚚 отправлен",
+    storage.OrderCancelled: "❌ отменён",
+}
+
+// orderStatusNotices — уведомления покупателю о переходе заказа в статус;
+// %d — номер заказа
+var orderStatusNotices = map[storage.OrderStatus]string{
+    storage.OrderPaid:      "✅ Оплата заказа №%d получена. Спасибо! Мы сообщим, когда заказ будет отправлен.",
+    storage.OrderShipped:   "🚚 Заказ №%d отправлен. Статус можно посмотреть командой /order %[1]d.",
+    storage.OrderCancelled: "❌ Заказ №%d отменён. Если это ошибка — напишите нам, разберёмся.",
+}
+
+// setOrderStatus меняет статус заказа и ставит уведомление покупателю в
+// outbox той же транзакцией, затем будит RunOutbox
+func (b *Bot) setOrderStatus(ctx context.Context, orderID int64, to storage.OrderStatus) error {
+    var notice string
+    if format, ok := orderStatusNotices[to]; ok {
+        notice = fmt.Sprintf(format, orderID)
+    }
+    if err := b.Orders.SetStatus(ctx, orderID, to, notice); err != nil {
+        return err
+    }
+    b.wakeOutbox()
+    return nil
+}
+
+// cmdOrderStatus — админская команда /orderstatus <номер> <статус>:
+// ручная смена статуса заказа (отправка, отмена) с уведомлением покупателя
+func (b *Bot) cmdOrderStatus(ctx context.Context, msg *TelegramMessage, args string) error {
+    const usage = "Использование: /orderstatus <номер заказа> shipped|cancelled, например /orderstatus 123 shipped"
+    fields := strings.Fields(args)
+    if len(fields) != 2 {
+        return apperr.Validation(usage)
+    }
+    orderID, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "№"), 10, 64)
+    if err != nil || orderID <= 0 {
+        return apperr.Validation(usage)
+    }
+    to := storage.OrderStatus(strings.ToLower(fields[1]))
+    if _, ok := orderStatusLabels[to]; !ok {
+        return apperr.Validation(usage)
+    }
+
+    err = b.setOrderStatus(ctx, orderID, to)
+    switch {
+    case errors.Is(err, storage.ErrNotFound):
+        return apperr.NotFound(fmt.Sprintf("Заказа №%d нет.", orderID))
+    case errors.Is(err, storage.ErrInvalidTransition):
+        return apperr.Validation(fmt.Sprintf("Нельзя перевести заказ №%d в статус %s: %v", orderID, to, err))
+    case err != nil:
+        return apperr.WithMessage(err, "Не удалось сменить статус заказа, попробуйте позже.")
+    }
+    logging.FromContext(ctx).Info("статус заказа изменён", "order_id", orderID, "status", to, "admin_chat_id", msg.Chat.ID)
+    b.reply(ctx, msg.Chat.ID, fmt.Sprintf("Заказ №%d: %s. Покупатель получит уведомление.", orderID, orderStatusLabels[to]))
+    return nil
+}
+
+// renderOrder — текст статуса заказа с позициями и итогом; суммы на языке lang
+func renderOrder(o storage.Order, lang string) string {
+    status, ok := orderStatusLabels[o.Status]
+    if !ok {
+        status = string(o.Status)
+    }
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "Заказ №%d от %s\nСтатус: %s\n\n", o.ID, o.CreatedAt.Format("02.01.2006"), status)
+    for _, item := range o.Items {
+        name := item.Name
+        if name == "" {
+            name = fmt.Sprintf("Товар #%d", item.ProductID)
+        }
+        fmt.Fprintf(&sb, "• %s × %d — %s\n", name, item.Qty, item.Price.MulQty(item.Qty).Format(lang))
+    }
+    fmt.Fprintf(&sb, "\nИтого: %s", o.Total.Format(lang))
+    return sb.String()
+}
+
+// cmdOrder — команда /order <номер>: статус заказа покупателя
+func (b *Bot) cmdOrder(ctx context.Context, msg *TelegramMessage, args string) error {
+    chatID := msg.Chat.ID
+    orderID, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(args), "№"), 10, 64)
+    if err != nil || orderID <= 0 {
+        return apperr.Validation("Укажите номер заказа, например: /order 123")
+    }
+
+    order, err := b.Orders.GetOrder(ctx, orderID, chatID)
+    // Чужой заказ неотличим от несуществующего — storage.ErrNotFound
+    if errors.Is(err, storage.ErrNotFound) {
+        return apperr.NotFound("Заказ не найден.")
+    }
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось загрузить заказ, попробуйте позже.")
+    }
+    b.reply(ctx, chatID, renderOrder(order, reqctx.LangFromContext(ctx)))
+    return nil
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+const (
+    // outboxPollInterval — как часто проверять очередь ответов без подсказки
+    // от enqueueAnswer (отсроченные повторы, записи других экземпляров)
+    outboxPollInterval = 2 * time.Second
+    // outboxBatch — сколько ответов забирать за один запрос к базе
+    outboxBatch = 20
+    // outboxSenders — скольким чатам ответы из очереди отправляются одновременно
+    outboxSenders = 8
+    // outboxStale — через сколько запись в sending считается брошенной
+    // упавшим процессом и отправляется снова
+    outboxStale = time.Minute
+    // outboxMaxAttempts — после стольких неудач ответ снимается с отправки
+    outboxMaxAttempts = 8
+    outboxRetryBase   = 2 * time.Second
+    outboxRetryMax    = 5 * time.Minute
+    // outboxRetention — сколько хранить отправленные записи для разбора
+    outboxRetention = 7 * 24 * time.Hour
+    // outboxPruneInterval — как часто чистить очередь от старых записей
+    outboxPruneInterval = time.Hour
+)
+
+// enqueueAnswer отправляет ответ модели через очередь Deps.Outbox: ответ
+// сначала сохраняется и только потом уходит в Telegram, поэтому падение
+// процесса между генерацией и отправкой его не теряет. Без Outbox, а также
+// если сохранить не удалось, ответ отправляется сразу. Ошибка — ответ не
+// сохранён и не отправлен.
+func (b *Bot) enqueueAnswer(ctx context.Context, chatID int64, text string, withFeedback bool) error {
+    if b.Outbox != nil {
+        _, err := b.Outbox.Enqueue(ctx, chatID, text, withFeedback, threadedMessage(ctx, chatID))
+        if err == nil {
+            b.wakeOutbox()
+            return nil
+        }
+        logging.FromContext(ctx).Error("ошибка постановки ответа в очередь, отправляем сразу", "chat_id", chatID, "err", err)
+    }
+    _, err := b.send(ctx, chatID, text, answerOptions(withFeedback)...)
+    return err
+}
+
+// deliver — одна попытка отправить ответ из очереди, начиная с первой
+// недоставленной части: доставленные части длинного ответа запоминаются,
+// и повтор после ошибки не присылает их второй раз. Повторы — забота RunOutbox.
+func (b *Bot) deliver(ctx context.Context, m storage.OutboxMessage) error {
+    parts := telegram.SplitMessage(m.Text)
+    for i := m.SentParts; i < len(parts); i++ {
+        // Как в SendMessage: кнопки — у последней части, цитата — у первой
+        var opts []telegram.SendOption
+        if i == len(parts)-1 {
+            opts = answerOptions(m.WithFeedback)
+        }
+        if i == 0 && m.ReplyTo != 0 {
+            opts = append(opts, telegram.WithReplyTo(m.ReplyTo))
+        }
+        if _, err := b.Telegram.SendMessage(m.ChatID, parts[i], opts...); err != nil {
+            if len(parts) == 1 {
+                return err
+            }
+            return fmt.Errorf("часть %d из %d: %w", i+1, len(parts), err)
+        }
+        if i == len(parts)-1 {
+            break
+        }
+        if err := b.Outbox.MarkPartSent(ctx, m.ID, i+1); err != nil {
+            logging.FromContext(ctx).Warn("не удалось запомнить отправленную часть ответа, повтор может её продублировать",
+                "chat_id", m.ChatID, "outbox_id", m.ID, "part", i+1, "err", err)
+        }
+    }
+    return nil
+}
+
+// answerOptions — параметры отправки ответа модели
+func answerOptions(withFeedback bool) []telegram.SendOption {
+    if !withFeedback {
+        return nil
+    }
+    return []telegram.SendOption{telegram.WithReplyMarkup(feedbackKeyboard())}
+}
+
+// wakeOutbox будит RunOutbox, не дожидаясь outboxPollInterval
+func (b *Bot) wakeOutbox() {
+    select {
+    case b.outboxWake <- struct{}{}:
+    default:
+    }
+}
+
+// outboxChats — отправка ответов из очереди по чатам: у каждого чата своя
+// горутина, ответы одного чата уходят по порядку, и 429 или медленный чат
+// задерживают только себя. Одновременно отправляется не больше outboxSenders чатов.
+type outboxChats struct {
+    sem chan struct{}
+    wg  sync.WaitGroup
+
+    mu sync.Mutex
+    // queued — ответы, ждущие горутину своего чата; ключ есть, пока она работает
+    queued map[int64][]storage.OutboxMessage
+}
+
+// RunOutbox отправляет ответы из очереди Deps.Outbox, повторяя неудачные
+// с экспоненциальной отсрочкой, и раз в outboxPruneInterval удаляет старые
+// записи. Доставка «хотя бы один раз»: ответ, забранный упавшим процессом,
+// через outboxStale уйдёт повторно. Работает до отмены ctx; после неё
+// дожидается ответов, которые уже отправляются, а остальные возвращает в очередь.
+func (b *Bot) RunOutbox(ctx context.Context) {
+    poll := time.NewTicker(outboxPollInterval)
+    defer poll.Stop()
+    prune := time.NewTicker(outboxPruneInterval)
+    defer prune.Stop()
+    chats := &outboxChats{sem: make(chan struct{}, outboxSenders), queued: make(map[int64][]storage.OutboxMessage)}
+    defer chats.wg.Wait()
+
+    for {
+        b.drainOutbox(ctx, chats)
+        select {
+        case <-ctx.Done():
+            return
+        case <-b.outboxWake:
+        case <-poll.C:
+        case <-prune.C:
+            n, err := b.Outbox.PruneOutbox(ctx, outboxRetention)
+            if err != nil {
+                logging.FromContext(ctx).Error("ошибка очистки очереди ответов", "err", err)
+            } else if n > 0 {
+                logging.FromContext(ctx).Info("очередь ответов очищена", "deleted", n)
+            }
+        }
+    }
+}
+
+// drainOutbox раздаёт чатам всё, чему пора уйти, пачками по outboxBatch
+func (b *Bot) drainOutbox(ctx context.Context, chats *outboxChats) {
+    for ctx.Err() == nil {
+        batch, err := b.Outbox.Claim(ctx, outboxBatch, outboxStale)
+        if err != nil {
+            logging.FromContext(ctx).Error("ошибка выборки очереди ответов", "err", err)
+            return
+        }
+        for _, m := range batch {
+            b.dispatchOutbox(ctx, chats, m)
+        }
+        if len(batch) < outboxBatch {
+            return
+        }
+    }
+}
+
+// dispatchOutbox отдаёт ответ горутине его чата или запускает её. Вызывается
+// только из drainOutbox, поэтому горутины чатов запускает одна горутина.
+func (b *Bot) dispatchOutbox(ctx context.Context, chats *outboxChats, m storage.OutboxMessage) {
+    chats.mu.Lock()
+    if queued, busy := chats.queued[m.ChatID]; busy {
+        chats.queued[m.ChatID] = append(queued, m)
+        chats.mu.Unlock()
+        return
+    }
+    chats.mu.Unlock()
+
+    select {
+    case chats.sem <- struct{}{}:
+    case <-ctx.Done():
+        b.returnToOutbox(ctx, m)
+        return
+    }
+    chats.mu.Lock()
+    chats.queued[m.ChatID] = nil
+    chats.mu.Unlock()
+    chats.wg.Add(1)
+    go func() {
+        defer chats.wg.Done()
+        defer func() { <-chats.sem }()
+        b.sendChatOutbox(ctx, chats, m)
+    }()
+}
+
+// sendChatOutbox отправляет ответы одного чата по порядку, пока они есть.
+// Ответ отложен — следующие ответы чата откладываются так же, чтобы не
+// обогнать его.
+func (b *Bot) sendChatOutbox(ctx context.Context, chats *outboxChats, m storage.OutboxMessage) {
+    deferred := b.sendOutboxMessage(ctx, m)
+    for {
+        chats.mu.Lock()
+        queued := chats.queued[m.ChatID]
+        if len(queued) == 0 {
+            delete(chats.queued, m.ChatID)
+            chats.mu.Unlock()
+            return
+        }
+        m, chats.queued[m.ChatID] = queued[0], queued[1:]
+        chats.mu.Unlock()
+
+        switch {
+        case ctx.Err() != nil:
+            b.returnToOutbox(ctx, m)
+        case deferred > 0:
+            b.retryOutbox(ctx, m, deferred, errChatDeferred)
+        default:
+            deferred = b.sendOutboxMessage(ctx, m)
+        }
+    }
+}
+
+// errChatDeferred — причина отсрочки ответа, ждущего предыдущий ответ чата
+var errChatDeferred = errors.New("отложен вслед за предыдущим ответом чата")
+
+// returnToOutbox возвращает забранный, но не отправленный при остановке
+// ответ в очередь, не дожидаясь outboxStale
+func (b *Bot) returnToOutbox(ctx context.Context, m storage.OutboxMessage) {
+    b.retryOutbox(ctx, m, 0, context.Canceled)
+}
+
+// retryOutbox откладывает ответ на after
+func (b *Bot) retryOutbox(ctx context.Context, m storage.OutboxMessage, after time.Duration, cause error) {
+    if err := b.Outbox.Retry(context.WithoutCancel(ctx), m.ID, after, cause); err != nil {
+        logging.FromContext(ctx).Error("ошибка обновления очереди ответов", "chat_id", m.ChatID, "outbox_id", m.ID, "err", err)
+    }
+}
+
+// sendOutboxMessage отправляет один ответ из очереди и записывает итог;
+// возвращает отсрочку, если ответ отложен до следующей попытки. Итог
+// записывается и после отмены ctx: иначе ответ остался бы в sending до
+// outboxStale.
+func (b *Bot) sendOutboxMessage(ctx context.Context, m storage.OutboxMessage) (deferred time.Duration) {
+    log := logging.FromContext(ctx).With("chat_id", m.ChatID, "outbox_id", m.ID)
+    ctx = context.WithoutCancel(ctx)
+
+    err := b.deliver(ctx, m)
+    verdict, delay := classifyTelegramError(err)
+    switch {
+    case verdict == sendDone:
+        err = b.Outbox.MarkSent(ctx, m.ID)
+    case verdict == sendBlocked:
+        // Бот заблокирован — повторять бессмысленно
+        log.Warn("ответ не доставлен: бот заблокирован", "err", err)
+        if err := b.Chats.MarkInactive(ctx, m.ChatID); err != nil {
+            log.Error("ошибка пометки чата", "err", err)
+        }
+        err = b.Outbox.Fail(ctx, m.ID, err)
+    case verdict == sendRejected:
+        log.Error("ответ не доставлен: Telegram его отклонил", "err", err)
+        err = b.Outbox.Fail(ctx, m.ID, err)
+    case m.Attempts >= outboxMaxAttempts:
+        log.Error("ответ не доставлен, попытки исчерпаны", "attempts", m.Attempts, "err", err)
+        err = b.Outbox.Fail(ctx, m.ID, err)
+    default:
+        // На 429 Telegram сам говорит, когда повторить, — это точнее нашей отсрочки
+        if verdict != sendWait {
+            delay = outboxRetryDelay(m.Attempts)
+        }
+        log.Warn("ошибка отправки ответа, повторим позже", "attempts", m.Attempts, "retry_in", delay, "err", err)
+        deferred = max(delay, time.Millisecond)
+        err = b.Outbox.Retry(ctx, m.ID, delay, err)
+    }
+    if err != nil {
+        // Запись останется в sending и через outboxStale уйдёт повторно
+        log.Error("ошибка обновления очереди ответов", "err", err)
+    }
+    return deferred
+}
+
+// outboxRetryDelay — отсрочка после attempt неудачных попыток: 2с, 4с, 8с… до outboxRetryMax
+func outboxRetryDelay(attempt int) time.Duration {
+    d := outboxRetryBase << (attempt - 1)
+    if d <= 0 || d > outboxRetryMax {
+        d = outboxRetryMax
+    }
+    return d
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/memstore"
+    "ai_seller/money"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// scriptedTelegram — Telegram, у которого отправка в чат может падать или
+// ждать: fail[chat] — сколько ближайших отправок в чат упадут сетевой
+// ошибкой, hold[chat] — канал, до закрытия которого отправка в чат ждёт
+type scriptedTelegram struct {
+    *mocks.Telegram
+    mu   sync.Mutex
+    fail map[int64]int
+    hold map[int64]chan struct{}
+}
+
+var errNetwork = errors.New("connection reset by peer")
+
+func (s *scriptedTelegram) SendMessage(chatID int64, text string, opts ...telegram.SendOption) (int64, error) {
+    s.mu.Lock()
+    hold := s.hold[chatID]
+    failing := s.fail[chatID] > 0
+    if failing {
+        s.fail[chatID]--
+    }
+    s.mu.Unlock()
+    if hold != nil {
+        <-hold
+    }
+    if failing {
+        return 0, errNetwork
+    }
+    return s.Telegram.SendMessage(chatID, text, opts...)
+}
+
+// outboxBot — бот с очередью ответов в памяти и управляемым Telegram
+func outboxBot(t *testing.T) (*testBot, *memstore.Outbox, *scriptedTelegram) {
+    t.Helper()
+    outbox := &memstore.Outbox{}
+    tb := newTestBot(t, nil, func(d *Deps) { d.Outbox = outbox })
+    tg := &scriptedTelegram{Telegram: tb.tg, fail: map[int64]int{}, hold: map[int64]chan struct{}{}}
+    tb.Telegram = tg
+    return tb, outbox, tg
+}
+
+// drainOnce — один проход RunOutbox: раздать очередь и дождаться отправки
+func (tb *testBot) drainOnce(ctx context.Context) {
+    chats := &outboxChats{sem: make(chan struct{}, outboxSenders), queued: make(map[int64][]storage.OutboxMessage)}
+    tb.drainOutbox(ctx, chats)
+    chats.wg.Wait()
+}
+
+// Повтор длинного ответа продолжает с недоставленной части
+func TestOutboxResumesMultiPartAnswer(t *testing.T) {
+    tb, outbox, tg := outboxBot(t)
+    first := strings.Repeat("а", 4000)
+    second := strings.Repeat("б", 3000)
+    id, _ := outbox.Enqueue(context.Background(), 42, first+"\n\n"+second, false, 0)
+
+    // Первая часть уходит, вторая падает
+    tb.Telegram = &failNth{scriptedTelegram: tg, n: 2}
+    tb.drainOnce(context.Background())
+    if status, parts, _ := outbox.Status(id); status != "pending" || parts != 1 {
+        t.Fatalf("после обрыва на второй части: %s, доставлено частей %d; ожидалось pending и 1", status, parts)
+    }
+
+    tb.Telegram = tg
+    outbox.Due()
+    tb.drainOnce(context.Background())
+    if status, _, attempts := outbox.Status(id); status != "sent" || attempts != 2 {
+        t.Fatalf("после повтора: %s за %d попыток, ожидалось sent за 2", status, attempts)
+    }
+    got := tb.sentTo(42)
+    if len(got) != 2 || got[0] != first || got[1] != second {
+        t.Fatalf("в чат ушло %d сообщений; ожидались две части без повтора первой", len(got))
+    }
+}
+
+// failNth — Telegram, у которого падает только n-я отправка
+type failNth struct {
+    *scriptedTelegram
+    mu    sync.Mutex
+    n     int
+    calls int
+}
+
+func (f *failNth) SendMessage(chatID int64, text string, opts ...telegram.SendOption) (int64, error) {
+    f.mu.Lock()
+    f.calls++
+    fail := f.calls == f.n
+    f.mu.Unlock()
+    if fail {
+        return 0, errNetwork
+    }
+    return f.scriptedTelegram.SendMessage(chatID, text, opts...)
+}
+
+// Медленный чат не задерживает ответы другим чатам
+func TestOutboxSlowChatDoesNotBlockOthers(t *testing.T) {
+    tb, outbox, tg := outboxBot(t)
+    release := make(chan struct{})
+    tg.hold[1] = release
+    outbox.Enqueue(context.Background(), 1, "медленному чату", false, 0)
+    slowNext, _ := outbox.Enqueue(context.Background(), 1, "и ещё", false, 0)
+    fast, _ := outbox.Enqueue(context.Background(), 2, "быстрому чату", false, 0)
+
+    chats := &outboxChats{sem: make(chan struct{}, outboxSenders), queued: make(map[int64][]storage.OutboxMessage)}
+    tb.drainOutbox(context.Background(), chats)
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        if status, _, _ := outbox.Status(fast); status == "sent" {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("ответ второму чату ждёт, пока освободится первый")
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+    if status, _, _ := outbox.Status(slowNext); status != "sending" {
+        t.Fatalf("второй ответ медленному чату: %s, ожидалось sending — он ждёт первый", status)
+    }
+    close(release)
+    chats.wg.Wait()
+    if got := tb.sentTo(1); len(got) != 2 || got[0] != "медленному чату" || got[1] != "и ещё" {
+        t.Fatalf("медленному чату ушло %q, ожидались оба ответа по порядку", got)
+    }
+}
+
+// Отложенный ответ не обгоняют следующие ответы того же чата
+func TestOutboxDefersLaterAnswersOfChat(t *testing.T) {
+    tb, outbox, tg := outboxBot(t)
+    tg.fail[42] = 1
+    first, _ := outbox.Enqueue(context.Background(), 42, "первый", false, 0)
+    second, _ := outbox.Enqueue(context.Background(), 42, "второй", false, 0)
+
+    tb.drainOnce(context.Background())
+    if got := tb.sentTo(42); len(got) != 0 {
+        t.Fatalf("ушло %q до повтора первого ответа", got)
+    }
+    for _, id := range []int64{first, second} {
+        if status, _, _ := outbox.Status(id); status != "pending" {
+            t.Fatalf("ответ %d: %s, ожидалось pending", id, status)
+        }
+    }
+
+    outbox.Due()
+    tb.drainOnce(context.Background())
+    if got := tb.sentTo(42); len(got) != 2 || got[0] != "первый" || got[1] != "второй" {
+        t.Fatalf("после повтора ушло %q, ожидались оба по порядку", got)
+    }
+}
+
+// Остановка возвращает в очередь ответы, до которых не дошла отправка
+func TestOutboxStopReturnsQueuedAnswers(t *testing.T) {
+    tb, outbox, tg := outboxBot(t)
+    release := make(chan struct{})
+    tg.hold[42] = release
+    first, _ := outbox.Enqueue(context.Background(), 42, "первый", false, 0)
+    second, _ := outbox.Enqueue(context.Background(), 42, "второй", false, 0)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    chats := &outboxChats{sem: make(chan struct{}, outboxSenders), queued: make(map[int64][]storage.OutboxMessage)}
+    tb.drainOutbox(ctx, chats)
+    cancel()
+    close(release)
+    chats.wg.Wait()
+
+    if status, _, _ := outbox.Status(first); status != "sent" {
+        t.Fatalf("ответ в отправке при остановке: %s, ожидалось sent", status)
+    }
+    if status, _, _ := outbox.Status(second); status != "pending" {
+        t.Fatalf("ответ в очереди при остановке: %s, ожидалось pending", status)
+    }
+}
+
+// Процесс упал сразу после смены статуса заказа: уведомление уже лежит в
+// outbox и уходит покупателю после перезапуска, причём один раз, даже если
+// смену статуса повторили
+func TestOrderStatusNoticeSurvivesCrash(t *testing.T) {
+    ctx := context.Background()
+    tb, outbox, _ := outboxBot(t)
+    tb.orders.Outbox = outbox
+    items := []storage.OrderItem{{ProductID: 1, Qty: 1, Price: money.New(150000, "RUB")}}
+    id, err := tb.orders.CreateOrder(ctx, 42, items, money.New(150000, "RUB"), "key", time.Minute)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := tb.orders.ConfirmOrder(ctx, id); err != nil {
+        t.Fatal(err)
+    }
+
+    // Статус сохранён, а до отправки дело не дошло: RunOutbox не запускался
+    if err := tb.setOrderStatus(ctx, id, storage.OrderPaid); err != nil {
+        t.Fatal(err)
+    }
+    if got := tb.sentTo(42); len(got) != 0 {
+        t.Fatalf("до перезапуска отправлено %q", got)
+    }
+    // Повтор уведомления об оплате (вебхук доставлен дважды) не ставит второе сообщение
+    if err := tb.setOrderStatus(ctx, id, storage.OrderPaid); !errors.Is(err, storage.ErrInvalidTransition) {
+        t.Fatalf("повторная оплата: %v", err)
+    }
+    if err := tb.orders.SetStatus(ctx, id, storage.OrderShipped, "отправлен"); err != nil {
+        t.Fatal(err)
+    }
+
+    restarted := newTestBot(t, nil, func(d *Deps) {
+        d.Outbox = outbox
+        d.Orders = tb.orders
+    })
+    restarted.drainOnce(ctx)
+    restarted.drainOnce(ctx)
+    got := restarted.sentTo(42)
+    if len(got) != 2 || !strings.Contains(got[0], "Оплата заказа") || got[1] != "отправлен" {
+        t.Fatalf("после перезапуска отправлено %q: нужно по уведомлению на оплату и отправку", got)
+    }
+}
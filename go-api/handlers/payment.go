@@ -0,0 +1,85 @@
+package handlers
+
+import (
+    "errors"
+    "io"
+    "net/http"
+
+    "ai_seller/logging"
+    "ai_seller/storage"
+)
+
+// maxPaymentBody — предел тела уведомления об оплате
+const maxPaymentBody = 64 << 10
+
+// PaymentWebhookHandler принимает уведомления платёжного провайдера.
+// Подпись проверяется по сырому телу до любого разбора: с неверной подписью
+// ответ 400 и никаких изменений. Оплаченный заказ переводится в paid, а
+// сообщение покупателю ставится в outbox в той же транзакции — только если
+// оплаченная сумма и валюта совпадают с заказом. Повторное уведомление об
+// уже оплаченном заказе подтверждается без повторного сообщения. Ошибки
+// базы отвечают 500, чтобы провайдер повторил доставку.
+func (b *Bot) PaymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+    ctx := r.Context()
+    body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPaymentBody))
+    if err != nil {
+        logging.FromContext(ctx).Warn("ошибка чтения уведомления об оплате", "err", err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+
+    if err := b.Payments.Verify(r.Header, body); err != nil {
+        logging.FromContext(ctx).Warn("уведомление об оплате отклонено", "err", err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    event, err := b.Payments.Parse(body)
+    if err != nil {
+        logging.FromContext(ctx).Warn("уведомление об оплате отклонено", "err", err)
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    if !event.Paid {
+        w.WriteHeader(http.StatusOK)
+        return
+    }
+
+    order, err := b.Orders.OrderState(ctx, event.OrderID)
+    switch {
+    case errors.Is(err, storage.ErrNotFound):
+        logging.FromContext(ctx).Warn("оплата неизвестного заказа", "order_id", event.OrderID)
+        w.WriteHeader(http.StatusOK)
+        return
+    case err != nil:
+        logging.FromContext(ctx).Error("ошибка чтения заказа при оплате", "order_id", event.OrderID, "err", err)
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    case order.Status == storage.OrderPaid:
+        w.WriteHeader(http.StatusOK)
+        return
+    case event.Amount != order.Total:
+        // Повтор доставки сумму не исправит — подтверждаем, а разбираться
+        // с недоплатой или чужой валютой придётся вручную
+        logging.FromContext(ctx).Error("сумма оплаты не совпадает с заказом", "order_id", event.OrderID,
+            "paid", event.Amount.Minor, "paid_currency", event.Amount.Currency,
+            "total", order.Total.Minor, "currency", order.Total.Currency)
+        w.WriteHeader(http.StatusOK)
+        return
+    }
+
+    err = b.setOrderStatus(ctx, event.OrderID, storage.OrderPaid)
+    switch {
+    case errors.Is(err, storage.ErrInvalidTransition):
+        // Например, заказ уже отменён — деньги придётся вернуть вручную
+        logging.FromContext(ctx).Error("оплата заказа в неподходящем статусе", "order_id", event.OrderID, "err", err)
+        w.WriteHeader(http.StatusOK)
+        return
+    case err != nil:
+        logging.FromContext(ctx).Error("ошибка отметки оплаты", "order_id", event.OrderID, "err", err)
+        w.WriteHeader(http.StatusInternalServerError)
+        return
+    }
+
+    logging.FromContext(ctx).Info("заказ оплачен", "order_id", event.OrderID, "chat_id", order.ChatID)
+    w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "ai_seller/money"
+    "ai_seller/payments"
+    "ai_seller/storage"
+)
+
+// paymentSecret — секрет подписи уведомлений в тестах
+const paymentSecret = "payment-secret"
+
+// paymentBot — бот с HMAC-провайдером и заказом на 1500 ₽ в чате 42
+func paymentBot(t *testing.T) (*testBot, int64) {
+    t.Helper()
+    provider, err := payments.New(payments.ProviderHMAC, paymentSecret)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tb := newTestBot(t, nil, func(d *Deps) { d.Payments = provider })
+    items := []storage.OrderItem{{ProductID: 1, Qty: 1, Price: money.New(150000, "RUB")}}
+    id, err := tb.orders.CreateOrder(context.Background(), 42, items, money.New(150000, "RUB"), "key", time.Minute)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := tb.orders.ConfirmOrder(context.Background(), id); err != nil {
+        t.Fatal(err)
+    }
+    return tb, id
+}
+
+// notify отправляет подписанное уведомление и возвращает код ответа
+func notify(tb *testBot, body, secret string) int {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(body))
+    r := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+    r.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+    w := httptest.NewRecorder()
+    tb.PaymentWebhookHandler(w, r)
+    return w.Code
+}
+
+// orderStatus — текущий статус заказа
+func orderStatus(t *testing.T, tb *testBot, id int64) storage.OrderStatus {
+    t.Helper()
+    st, err := tb.orders.OrderState(context.Background(), id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return st.Status
+}
+
+func TestPaymentWebhookMarksOrderPaid(t *testing.T) {
+    tb, id := paymentBot(t)
+    body := `{"order_id":1,"status":"paid","amount":150000,"currency":"rub"}`
+    if code := notify(tb, body, paymentSecret); code != http.StatusOK {
+        t.Fatalf("код %d, ожидался 200", code)
+    }
+    if got := orderStatus(t, tb, id); got != storage.OrderPaid {
+        t.Fatalf("статус %s, ожидался paid", got)
+    }
+    if n := tb.orders.Notices(); len(n) != 1 || n[0].ChatID != 42 {
+        t.Fatalf("уведомления покупателю: %+v", n)
+    }
+}
+
+func TestPaymentWebhookRejectsAmountMismatch(t *testing.T) {
+    for name, body := range map[string]string{
+        "меньшая сумма":   `{"order_id":1,"status":"paid","amount":100,"currency":"RUB"}`,
+        "другая валюта":   `{"order_id":1,"status":"paid","amount":150000,"currency":"USD"}`,
+        "сумма не пришла": `{"order_id":1,"status":"paid"}`,
+    } {
+        t.Run(name, func(t *testing.T) {
+            tb, id := paymentBot(t)
+            notify(tb, body, paymentSecret)
+            if got := orderStatus(t, tb, id); got != storage.OrderNew {
+                t.Fatalf("статус %s, заказ не должен считаться оплаченным", got)
+            }
+            if n := tb.orders.Notices(); len(n) != 0 {
+                t.Fatalf("покупателю ушло уведомление: %+v", n)
+            }
+        })
+    }
+}
+
+func TestPaymentWebhookRejectsBadSignature(t *testing.T) {
+    tb, id := paymentBot(t)
+    body := `{"order_id":1,"status":"paid","amount":150000,"currency":"RUB"}`
+    if code := notify(tb, body, "чужой секрет"); code != http.StatusBadRequest {
+        t.Fatalf("код %d, ожидался 400", code)
+    }
+    if got := orderStatus(t, tb, id); got != storage.OrderNew {
+        t.Fatalf("статус %s после неверной подписи", got)
+    }
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    "ai_seller/telegram"
+)
+
+// probeTimeout — предел на проверку одной зависимости: медленная
+// зависимость не задерживает отчёт по остальным
+const probeTimeout = 3 * time.Second
+
+// Probe — проверка зависимости для /ping
+type Probe struct {
+    Name  string
+    Check func(ctx context.Context) error
+}
+
+// probeResult — итог одной проверки
+type probeResult struct {
+    name    string
+    err     error
+    latency time.Duration
+}
+
+// cmdPing — админ-команда /ping: задержки до зависимостей бота
+func (b *Bot) cmdPing(ctx context.Context, msg *TelegramMessage, args string) error {
+    if len(b.Probes) == 0 {
+        b.reply(ctx, msg.Chat.ID, "Проверки зависимостей не настроены.")
+        return nil
+    }
+    // Тексты ошибок зависимостей — не Markdown: «_» в них не должно стать курсивом
+    b.send(ctx, msg.Chat.ID, renderPing(runProbes(ctx, b.Probes)), telegram.WithParseMode(""))
+    return nil
+}
+
+// runProbes выполняет проверки параллельно, каждую со своим пределом
+// probeTimeout; результаты — в порядке probes
+func runProbes(ctx context.Context, probes []Probe) []probeResult {
+    results := make([]probeResult, len(probes))
+    var wg sync.WaitGroup
+    for i, p := range probes {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+            defer cancel()
+            start := time.Now()
+            err := p.Check(ctx)
+            results[i] = probeResult{name: p.Name, err: err, latency: time.Since(start)}
+        }()
+    }
+    wg.Wait()
+    return results
+}
+
+// renderPing — отчёт /ping: по строке на зависимость
+func renderPing(results []probeResult) string {
+    var sb strings.Builder
+    sb.WriteString("Проверка зависимостей:")
+    for _, r := range results {
+        if r.err != nil {
+            fmt.Fprintf(&sb, "\n❌ %s — %d мс: %v", r.name, r.latency.Milliseconds(), r.err)
+            continue
+        }
+        fmt.Fprintf(&sb, "\n✅ %s — %d мс", r.name, r.latency.Milliseconds())
+    }
+    return sb.String()
+}
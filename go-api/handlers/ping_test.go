@@ -0,0 +1,88 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+// probe — проверка name, отвечающая err через delay
+func probe(name string, delay time.Duration, err error) Probe {
+    return Probe{Name: name, Check: func(ctx context.Context) error {
+        select {
+        case <-time.After(delay):
+            return err
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }}
+}
+
+func TestPingCommand(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    tb.Probes = []Probe{
+        probe("PostgreSQL", 0, nil),
+        probe("Redis", 0, errors.New("dial tcp: pool_timeout")),
+        probe("Telegram", 20*time.Millisecond, nil),
+    }
+
+    tb.process(t, text(1, 1, "/ping"))
+    sent := tb.tg.Messages()
+    if len(sent) != 1 {
+        t.Fatalf("отправлено %d сообщений", len(sent))
+    }
+    lines := strings.Split(sent[0].Text, "\n")
+    if len(lines) != 4 || lines[0] != "Проверка зависимостей:" {
+        t.Fatalf("отчёт %q", sent[0].Text)
+    }
+    for i, want := range []string{"✅ PostgreSQL — ", "❌ Redis — ", "✅ Telegram — "} {
+        if !strings.HasPrefix(lines[i+1], want) || !strings.Contains(lines[i+1], " мс") {
+            t.Errorf("строка %q, нужно начало %q и задержка в мс", lines[i+1], want)
+        }
+    }
+    if !strings.HasSuffix(lines[2], ": dial tcp: pool_timeout") {
+        t.Fatalf("строка %q без текста ошибки", lines[2])
+    }
+    if sent[0].ParseMode != "" {
+        t.Fatalf("отчёт с разметкой %q: «_» в ошибке станет курсивом", sent[0].ParseMode)
+    }
+}
+
+func TestPingRequiresAdmin(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "1"})
+    called := false
+    tb.Probes = []Probe{{Name: "PostgreSQL", Check: func(context.Context) error { called = true; return nil }}}
+
+    tb.process(t, text(1, 42, "/ping"))
+    if called {
+        t.Fatal("покупатель запустил проверки зависимостей")
+    }
+}
+
+// Зависшая зависимость упирается в свой предел и не задерживает остальные
+func TestRunProbesIndependent(t *testing.T) {
+    ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+    start := time.Now()
+    results := runProbes(ctx, []Probe{
+        probe("OpenAI", time.Hour, nil),
+        probe("Redis", 0, nil),
+        probe("PostgreSQL", 50*time.Millisecond, nil),
+    })
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("проверки шли %v: зависшая держит отчёт", elapsed)
+    }
+    if !errors.Is(results[0].err, context.DeadlineExceeded) {
+        t.Fatalf("зависшая проверка: %v", results[0].err)
+    }
+    for _, r := range results[1:] {
+        if r.err != nil {
+            t.Fatalf("%s: %v", r.name, r.err)
+        }
+    }
+    if results[2].name != "PostgreSQL" || results[2].latency < 50*time.Millisecond {
+        t.Fatalf("результаты не в порядке проверок или без задержки: %+v", results)
+    }
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+
+    "ai_seller/config"
+    "ai_seller/logging"
+)
+
+// Reply — ответ модели на пути от генерации до покупателя
+type Reply struct {
+    ChatID int64
+    Text   string
+    // WithFeedback — прикрепить к ответу кнопки оценки 👍/👎
+    WithFeedback bool
+    // Blocked — фильтр заменил ответ целиком; такой ответ не кэшируется
+    Blocked bool
+}
+
+// ReplyStage — этап обработки ответа. Ошибка останавливает конвейер:
+// следующие этапы, включая отправку, не выполняются.
+type ReplyStage func(ctx context.Context, r *Reply) error
+
+// ReplyPipeline — этапы обработки ответа по порядку; последний этап отправляет
+// ответ в Telegram
+type ReplyPipeline []ReplyStage
+
+// Run проводит ответ через этапы по порядку до первой ошибки
+func (p ReplyPipeline) Run(ctx context.Context, r *Reply) error {
+    for _, stage := range p {
+        if err := stage(ctx, r); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// newReplyPipeline собирает конвейер из этапов Config.ReplyStages и отправки
+func (b *Bot) newReplyPipeline() ReplyPipeline {
+    stages := map[string]ReplyStage{
+        config.ReplyStageFilter: b.filterStage,
+        config.ReplyStagePause:  b.pauseStage,
+    }
+    p := make(ReplyPipeline, 0, len(b.Config.ReplyStages)+1)
+    for _, name := range b.Config.ReplyStages {
+        p = append(p, stages[name])
+    }
+    return append(p, b.sendStage)
+}
+
+// newStreamPipeline — этапы Config.ReplyStages для ответа, который правится
+// по мере генерации. Паузы нет — текст уже на экране, отправки тоже:
+// её заменяет правка сообщения в streamReply.
+func (b *Bot) newStreamPipeline() ReplyPipeline {
+    var p ReplyPipeline
+    for _, name := range b.Config.ReplyStages {
+        if name == config.ReplyStageFilter {
+            p = append(p, b.filterStage)
+        }
+    }
+    return p
+}
+
+// sendReply проводит ответ модели через конвейер и возвращает его в том виде,
+// в каком он ушёл покупателю. Ошибка — ответ до покупателя не дошёл.
+func (b *Bot) sendReply(ctx context.Context, chatID int64, text string) (*Reply, error) {
+    r := &Reply{ChatID: chatID, Text: text, WithFeedback: true}
+    if err := b.replies.Run(ctx, r); err != nil {
+        logging.FromContext(ctx).Error("ответ не отправлен", "chat_id", chatID, "err", err)
+        return r, err
+    }
+    return r, nil
+}
+
+// filterStage — этап ReplyStageFilter: фильтр ответов
+func (b *Bot) filterStage(ctx context.Context, r *Reply) error {
+    var blocked bool
+    r.Text, blocked = b.filterAnswer(ctx, r.Text)
+    r.Blocked = r.Blocked || blocked
+    return nil
+}
+
+// pauseStage — этап ReplyStagePause: пауза «набора текста» перед отправкой.
+// Отмена ctx во время паузы останавливает конвейер: обработка апдейта
+// прервана, и ответ не отправляется.
+func (b *Bot) pauseStage(ctx context.Context, r *Reply) error {
+    if err := b.humanPause(ctx, r.ChatID, r.Text); err != nil {
+        return fmt.Errorf("пауза перед ответом прервана: %w", err)
+    }
+    return nil
+}
+
+// sendStage — последний этап: ответ уходит через outbox или сразу
+func (b *Bot) sendStage(ctx context.Context, r *Reply) error {
+    return b.enqueueAnswer(ctx, r.ChatID, r.Text, r.WithFeedback)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+
+    "ai_seller/filter"
+)
+
+// Этапы выполняются по порядку, и первая ошибка останавливает конвейер
+func TestReplyPipelineRunsInOrderUntilError(t *testing.T) {
+    var ran []string
+    stage := func(name string, err error) ReplyStage {
+        return func(ctx context.Context, r *Reply) error {
+            ran = append(ran, name)
+            r.Text += name
+            return err
+        }
+    }
+    errStop := errors.New("стоп")
+    p := ReplyPipeline{stage("a", nil), stage("b", errStop), stage("c", nil)}
+
+    r := &Reply{}
+    if err := p.Run(context.Background(), r); !errors.Is(err, errStop) {
+        t.Fatalf("Run = %v, нужна ошибка этапа", err)
+    }
+    if want := []string{"a", "b"}; !reflect.DeepEqual(ran, want) {
+        t.Fatalf("выполнены этапы %q, нужно %q", ran, want)
+    }
+    if r.Text != "ab" {
+        t.Fatalf("текст = %q: этап видит ответ, изменённый предыдущими", r.Text)
+    }
+}
+
+// Конвейер собирается из REPLY_STAGES: отправка всегда последняя, потоковый
+// путь берёт те же этапы без паузы
+func TestReplyPipelineComposition(t *testing.T) {
+    for _, tc := range []struct {
+        stages            string
+        replies, streamed int
+    }{
+        {"", 1, 0},
+        {"filter", 2, 1},
+        {"pause", 2, 0},
+        {"pause,filter", 3, 1},
+    } {
+        tb := newTestBot(t, map[string]string{"REPLY_STAGES": tc.stages})
+        if got := len(tb.replies); got != tc.replies {
+            t.Errorf("REPLY_STAGES=%q: этапов ответа %d, нужно %d", tc.stages, got, tc.replies)
+        }
+        if got := len(tb.streamed); got != tc.streamed {
+            t.Errorf("REPLY_STAGES=%q: этапов потокового ответа %d, нужно %d", tc.stages, got, tc.streamed)
+        }
+    }
+}
+
+// Без этапа filter ответ уходит как есть — и обычный, и потоковый
+func TestReplyStagesApplyToStreamedReplies(t *testing.T) {
+    const answer = "Звоните +7 999 123 45 67."
+    for _, tc := range []struct {
+        env  map[string]string
+        want string
+    }{
+        {map[string]string{}, "Звоните [скрыто]."},
+        {map[string]string{"REPLY_STAGES": ""}, answer},
+        {map[string]string{"STREAMING_ENABLED": "true"}, "Звоните [скрыто]."},
+        {map[string]string{"STREAMING_ENABLED": "true", "REPLY_STAGES": ""}, answer},
+    } {
+        tb := newTestBot(t, tc.env, func(d *Deps) { d.Filter = phoneFilter(t) })
+        tb.ai.Reply = answer
+        tb.process(t, text(1, 10, "телефон?"))
+        if got := tb.sentTo(10); len(got) != 1 || got[0] != tc.want {
+            t.Errorf("%v: отправлено %q, нужно %q", tc.env, got, tc.want)
+        }
+        if history := tb.messages.History(10); len(history) != 2 || history[1].Content != tc.want {
+            t.Errorf("%v: в истории %+v, нужно %q", tc.env, history, tc.want)
+        }
+    }
+}
+
+// Прерванная пауза останавливает конвейер до отправки
+func TestPauseStageCancelledSkipsSend(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"TYPING_DELAY": "true"})
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    if _, err := tb.sendReply(ctx, 10, "ответ"); !errors.Is(err, context.Canceled) {
+        t.Fatalf("sendReply = %v, нужна отмена паузы", err)
+    }
+    if got := tb.sentTo(10); len(got) != 0 {
+        t.Fatalf("после прерванной паузы отправлено %q", got)
+    }
+}
+
+// Ответ, который не удалось отправить, не попадает в историю диалога
+func TestUnsentReplyNotRemembered(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"TELEGRAM_SEND_ATTEMPTS": "1"})
+    tg := &scriptedTelegram{Telegram: tb.tg, fail: map[int64]int{10: 1}}
+    tb.Telegram = tg
+
+    tb.process(t, text(1, 10, "есть чай?"))
+    history := tb.messages.History(10)
+    if len(history) != 1 || history[0].Role != "user" {
+        t.Fatalf("история = %+v, нужно только сообщение покупателя", history)
+    }
+}
+
+func phoneFilter(t *testing.T) *filter.Filter {
+    t.Helper()
+    f, err := filter.New(filter.Rules{Redact: []string{"phone"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return f
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "runtime/debug"
+    "time"
+
+    "ai_seller/logging"
+)
+
+const (
+    // pollTimeout — сколько Telegram держит запрос getUpdates, если апдейтов нет
+    pollTimeout = 30 * time.Second
+    // pollRetryDelay — пауза после ошибки getUpdates
+    pollRetryDelay = 3 * time.Second
+)
+
+// Poll получает апдейты через getUpdates вместо вебхука — для локальной
+// разработки за NAT. Апдейты обрабатываются по одному тем же конвейером,
+// что и в TelegramHandler. Возвращается после отмены ctx, дообработав
+// текущий апдейт: к этому моменту он уже отмечен полученным, и повтор от
+// Telegram его бы отбросил. Остальные апдейты пачки остаются Telegram —
+// подтверждения offset им не было, и их получит следующий запуск.
+func (b *Bot) Poll(ctx context.Context) {
+    // Пока вебхук выставлен, getUpdates отвечает 409
+    if err := b.Telegram.DeleteWebhook(); err != nil {
+        logging.FromContext(ctx).Error("не удалось снять вебхук", "err", err)
+    }
+    logging.FromContext(ctx).Info("запущен long polling")
+
+    // Апдейт дорабатывается и после отмены ctx; предел задаёт UpdateTimeout
+    // в ProcessUpdate
+    work := context.WithoutCancel(ctx)
+    var offset int64
+    drained := 0
+    for ctx.Err() == nil {
+        updates, err := b.Telegram.GetUpdates(ctx, offset, pollTimeout)
+        if err != nil {
+            if ctx.Err() != nil {
+                break
+            }
+            logging.FromContext(ctx).Error("ошибка getUpdates", "err", err)
+            select {
+            case <-ctx.Done():
+            case <-time.After(pollRetryDelay):
+            }
+            continue
+        }
+
+        for _, raw := range updates {
+            if ctx.Err() != nil {
+                break
+            }
+            var update TelegramUpdate
+            err := json.Unmarshal(raw, &update)
+            // Сдвигаем offset до обработки: апдейт, который не разобрался или
+            // уронил обработку, не должен приходить снова и снова
+            if update.UpdateID >= offset {
+                offset = update.UpdateID + 1
+            }
+            if err != nil {
+                logging.FromContext(ctx).Warn("ошибка разбора апдейта", "update_id", update.UpdateID, "err", err)
+                continue
+            }
+            b.processRecovering(work, update)
+            if ctx.Err() != nil {
+                drained++
+            }
+        }
+    }
+    logging.FromContext(ctx).Info("long polling остановлен", "drained", drained)
+}
+
+// processRecovering обрабатывает апдейт вне HTTP-горутины (long polling,
+// очередь вебхука). Паника здесь не проходит через RecoverMiddleware,
+// поэтому перехватываем её сами.
+func (b *Bot) processRecovering(ctx context.Context, update TelegramUpdate) (err error) {
+    ctx = withTrace(ctx, update)
+    defer func() {
+        if rec := recover(); rec != nil {
+            logging.FromContext(ctx).Error("паника при обработке апдейта",
+                "update_id", update.UpdateID, "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+            err = fmt.Errorf("паника: %v", rec)
+            b.recordFailure(ctx, update, err)
+        }
+    }()
+
+    if err = b.ProcessUpdate(ctx, update); err != nil {
+        logging.FromContext(ctx).Error("ошибка обработки апдейта", "update_id", update.UpdateID, "err", err)
+    }
+    return err
+}
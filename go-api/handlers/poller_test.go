@@ -0,0 +1,93 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "testing"
+    "time"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/openai"
+)
+
+// gatedOpenAI — модель, которая отвечает только после release и
+// проверяет, что контекст запроса к этому моменту не отменён
+type gatedOpenAI struct {
+    *mocks.OpenAI
+    started chan struct{}
+    release chan struct{}
+}
+
+func (g *gatedOpenAI) ChatCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    g.started <- struct{}{}
+    <-g.release
+    if err := ctx.Err(); err != nil {
+        return "", err
+    }
+    return g.OpenAI.ChatCompletion(ctx, messages)
+}
+
+func (g *gatedOpenAI) ChatWithTools(ctx context.Context, messages []openai.Message, tools *openai.ToolRegistry) (string, error) {
+    return g.ChatCompletion(ctx, messages)
+}
+
+func rawUpdate(t *testing.T, update TelegramUpdate) json.RawMessage {
+    t.Helper()
+    raw, err := json.Marshal(update)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return raw
+}
+
+// Остановка посреди пачки: текущий апдейт дообрабатывается с живым
+// контекстом, следующий остаётся Telegram и не помечается полученным
+func TestPollDrainsCurrentUpdateOnStop(t *testing.T) {
+    ai := &gatedOpenAI{
+        OpenAI:  &mocks.OpenAI{Reply: "ответ модели"},
+        started: make(chan struct{}),
+        release: make(chan struct{}),
+    }
+    tb := newTestBot(t, nil, func(d *Deps) { d.OpenAI = ai })
+    first, second := text(1, 10, "привет"), text(2, 20, "здравствуйте")
+    tb.tg.Updates = [][]json.RawMessage{{rawUpdate(t, first), rawUpdate(t, second)}}
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        tb.Poll(ctx)
+    }()
+
+    <-ai.started
+    cancel()
+    close(ai.release)
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("Poll не вернулся после остановки")
+    }
+
+    if got := tb.sentTo(10); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("ответ на текущий апдейт = %q, нужен ответ модели", got)
+    }
+    if got := tb.sentTo(20); len(got) != 0 {
+        t.Fatalf("апдейт после остановки обработан: %q", got)
+    }
+
+    // Повтор от Telegram следующему запуску: второй апдейт не отброшен как
+    // дубль, первый — отброшен
+    go func() {
+        for range ai.started {
+        }
+    }()
+    t.Cleanup(func() { close(ai.started) })
+    tb.process(t, first)
+    tb.process(t, second)
+    if got := tb.sentTo(10); len(got) != 1 {
+        t.Fatalf("повтор обработанного апдейта прислал ответ ещё раз: %q", got)
+    }
+    if got := tb.sentTo(20); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("ответ на повтор неполученного апдейта = %q", got)
+    }
+}
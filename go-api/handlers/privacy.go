@@ -0,0 +1,127 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// eraseAction — action кнопок подтверждения /deletedata: "erase:yes" / "erase:no"
+const eraseAction = "erase"
+
+const (
+    eraseConfirmPrompt  = "Удалить историю переписки, профиль и корзину? Заказы останутся в учёте без привязки к вам."
+    erasedReply         = "Ваши данные удалены."
+    eraseCancelledReply = "Удаление отменено."
+)
+
+// cmdMyData — команда /mydata: выгрузка данных, которые магазин хранит о чате
+func (b *Bot) cmdMyData(ctx context.Context, msg *TelegramMessage, args string) error {
+    export, err := b.Privacy.Export(ctx, msg.Chat.ID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось выгрузить данные, попробуйте позже.")
+    }
+    // Простой текст: в переписке могут быть символы разметки. Длинную
+    // выгрузку клиент сам разобьёт на несколько сообщений.
+    _, err = b.Telegram.SendMessage(msg.Chat.ID, renderDataExport(export, reqctx.LangFromContext(ctx)), telegram.WithParseMode(""))
+    return err
+}
+
+// renderDataExport — выгрузка в читаемом виде: профиль, заказы, переписка
+func renderDataExport(e storage.DataExport, lang string) string {
+    var sb strings.Builder
+    sb.WriteString("📄 Ваши данные\n\nПрофиль: ")
+    if e.User == nil {
+        sb.WriteString("не сохранён\n")
+    } else {
+        fmt.Fprintf(&sb, "имя %q, username %q, язык %q\n", e.User.Name, e.User.Username, e.User.Lang)
+    }
+
+    fmt.Fprintf(&sb, "\nЗаказы (%d):\n", len(e.Orders))
+    for _, o := range e.Orders {
+        fmt.Fprintf(&sb, "№%d от %s — %s, %s\n", o.ID, o.CreatedAt.Format("02.01.2006"), o.Total.Format(lang), o.Status)
+    }
+
+    if e.Summary != "" {
+        sb.WriteString("\nСводка прошлого разговора:\n" + e.Summary + "\n")
+    }
+
+    fmt.Fprintf(&sb, "\nСообщения (%d):\n", len(e.Messages))
+    for _, m := range e.Messages {
+        fmt.Fprintf(&sb, "[%s] %s: %s\n", m.CreatedAt.Format("02.01.2006 15:04"), m.Role, m.Content)
+    }
+    return sb.String()
+}
+
+// canErase — данными личного чата распоряжается сам покупатель, данными
+// группы — только её администратор
+func (b *Bot) canErase(ctx context.Context, chat TelegramChat, from *TelegramUser) bool {
+    if !chat.isGroup() {
+        return true
+    }
+    if from == nil {
+        return false
+    }
+    admin, err := b.Telegram.IsChatAdmin(chat.ID, from.ID)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка проверки прав в группе", "chat_id", chat.ID, "user_id", from.ID, "err", err)
+        return false
+    }
+    return admin
+}
+
+// cmdDeleteData — команда /deletedata: удаление только после подтверждения
+// кнопкой; в группе — только администратором группы
+func (b *Bot) cmdDeleteData(ctx context.Context, msg *TelegramMessage, args string) error {
+    if !b.canErase(ctx, msg.Chat, msg.From) {
+        b.replyPhrase(ctx, msg.Chat.ID, noAccessReply)
+        return nil
+    }
+    kb := telegram.NewInlineKeyboard().Row(
+        telegram.CallbackButton("🗑 Да, удалить", eraseAction+":yes"),
+        telegram.CallbackButton("Отмена", eraseAction+":no"),
+    )
+    _, err := b.Telegram.SendMessage(msg.Chat.ID, i18n.T(reqctx.LangFromContext(ctx), eraseConfirmPrompt), telegram.WithReplyMarkup(kb))
+    return err
+}
+
+// cbErase — ответ на подтверждение /deletedata. Удаляются данные в PostgreSQL
+// и Redis (контекст диалога, корзина, отметка активности); сообщение с кнопками заменяется итогом.
+// В группе кнопки срабатывают только у её администраторов.
+func (b *Bot) cbErase(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    chatID := cq.Message.Chat.ID
+    lang := reqctx.LangFromContext(ctx)
+    if !b.canErase(ctx, cq.Message.Chat, cq.From) {
+        logging.FromContext(ctx).Warn("кнопку удаления данных группы нажал не администратор", "chat_id", chatID)
+        return nil
+    }
+    if payload != "yes" {
+        return b.Telegram.EditMessageText(chatID, cq.Message.MessageID, i18n.T(lang, eraseCancelledReply))
+    }
+
+    orders, err := b.Privacy.Erase(ctx, chatID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось удалить данные, попробуйте позже.")
+    }
+    if err := b.Sessions.Clear(ctx, chatID); err != nil {
+        logging.FromContext(ctx).Error("ошибка удаления контекста диалога", "chat_id", chatID, "err", err)
+    }
+    if err := b.Carts.ClearCart(ctx, chatID); err != nil {
+        logging.FromContext(ctx).Error("ошибка удаления корзины", "chat_id", chatID, "err", err)
+    }
+    if b.Shopping != nil {
+        if err := b.Shopping.Forget(ctx, chatID); err != nil {
+            logging.FromContext(ctx).Error("ошибка удаления активности покупателя", "chat_id", chatID, "err", err)
+        }
+    }
+    logging.FromContext(ctx).Info("данные чата удалены по запросу покупателя", "chat_id", chatID, "anonymized_orders", orders)
+
+    return b.Telegram.EditMessageText(chatID, cq.Message.MessageID, i18n.T(lang, erasedReply))
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+    "errors"
+    "strings"
+    "testing"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/storage"
+)
+
+// groupID — группа, в которой пишет участник memberID
+const (
+    groupID  int64 = -100500
+    memberID int64 = 7
+)
+
+// groupEnv — без username бота команды в группе не считаются обращёнными к нему
+var groupEnv = map[string]string{"TELEGRAM_BOT_USERNAME": "shop_bot"}
+
+// groupCommand — команда участника memberID в группе groupID
+func groupCommand(updateID int64, body string) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, Message: &TelegramMessage{
+        MessageID: updateID,
+        From:      &TelegramUser{ID: memberID, FirstName: "Участник", LanguageCode: "ru"},
+        Chat:      TelegramChat{ID: groupID, Type: "supergroup"},
+        Text:      body,
+        Entities:  []TelegramEntity{{Type: "bot_command", Offset: 0, Length: len(body)}},
+    }}
+}
+
+// eraseButton — нажатие кнопки подтверждения /deletedata
+func eraseButton(updateID, chatID int64, chatType string, from int64, messageID int64) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, CallbackQuery: &TelegramCallbackQuery{
+        ID:      "cb",
+        From:    &TelegramUser{ID: from, LanguageCode: "ru"},
+        Data:    eraseAction + ":yes",
+        Message: &TelegramMessage{MessageID: messageID, Chat: TelegramChat{ID: chatID, Type: chatType}},
+    }}
+}
+
+func TestDeleteDataInGroupRequiresAdmin(t *testing.T) {
+    tb := newTestBot(t, groupEnv)
+    tb.process(t, groupCommand(1, "/deletedata"))
+
+    if got := tb.sentTo(groupID); len(got) != 1 || got[0] != noAccessReply {
+        t.Fatalf("отправлено %q, ожидался отказ", got)
+    }
+    tb.process(t, eraseButton(2, groupID, "supergroup", memberID, 1))
+    if erased := tb.privacy.Erased(); len(erased) != 0 {
+        t.Fatalf("не администратор удалил данные группы: %v", erased)
+    }
+}
+
+func TestDeleteDataInGroupByAdmin(t *testing.T) {
+    tb := newTestBot(t, groupEnv)
+    tb.tg.Admins = map[int64]map[int64]bool{groupID: {memberID: true}}
+    tb.process(t, groupCommand(1, "/deletedata"))
+
+    sent := tb.tg.Messages()
+    if len(sent) != 1 || sent[0].Text != eraseConfirmPrompt {
+        t.Fatalf("отправлено %+v, ожидался запрос подтверждения", sent)
+    }
+    tb.process(t, eraseButton(2, groupID, "supergroup", memberID, sent[0].MessageID))
+    if erased := tb.privacy.Erased(); len(erased) != 1 || erased[0] != groupID {
+        t.Fatalf("удалены чаты %v, ожидалась группа %d", erased, groupID)
+    }
+    if got := tb.sentTo(groupID); got[0] != erasedReply {
+        t.Fatalf("сообщение с кнопками: %q, ожидалось %q", got[0], erasedReply)
+    }
+}
+
+func TestDeleteDataInPrivateChat(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "/deletedata"))
+    sent := tb.tg.Messages()
+    if len(sent) != 1 || sent[0].Text != eraseConfirmPrompt {
+        t.Fatalf("отправлено %+v, ожидался запрос подтверждения", sent)
+    }
+    tb.process(t, eraseButton(2, 42, "private", 42, sent[0].MessageID))
+    if erased := tb.privacy.Erased(); len(erased) != 1 || erased[0] != 42 {
+        t.Fatalf("удалены чаты %v, ожидался 42", erased)
+    }
+}
+
+func TestAdminCheckErrorDeniesErase(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.tg.Admins = map[int64]map[int64]bool{groupID: {memberID: true}}
+    tb.process(t, eraseButton(1, groupID, "supergroup", memberID, 1))
+    if len(tb.privacy.Erased()) != 1 {
+        t.Fatal("администратор группы не смог удалить данные")
+    }
+
+    failing := &adminCheckFails{Telegram: tb.tg}
+    tb.Telegram = failing
+    tb.process(t, eraseButton(2, groupID, "supergroup", memberID, 1))
+    if len(tb.privacy.Erased()) != 1 {
+        t.Fatal("при ошибке проверки прав данные группы удалены")
+    }
+}
+
+// errTelegramDown — ошибка недоступного Bot API
+var errTelegramDown = errors.New("telegram недоступен")
+
+// adminCheckFails — Telegram, у которого getChatMember не отвечает
+type adminCheckFails struct {
+    *mocks.Telegram
+}
+
+func (adminCheckFails) IsChatAdmin(chatID, userID int64) (bool, error) {
+    return false, errTelegramDown
+}
+
+func TestMyDataShowsSummary(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.privacy.Exports = map[int64]storage.DataExport{42: {
+        Summary:  "покупатель выбирал зелёный чай",
+        Messages: []storage.Message{{Role: "user", Content: "есть улун?"}},
+    }}
+    tb.process(t, text(1, 42, "/mydata"))
+
+    got := tb.sentTo(42)
+    if len(got) != 1 {
+        t.Fatalf("отправлено %q, ожидалась выгрузка", got)
+    }
+    if !strings.Contains(got[0], "покупатель выбирал зелёный чай") || !strings.Contains(got[0], "есть улун?") {
+        t.Fatalf("в выгрузке нет сводки или переписки:\n%s", got[0])
+    }
+}
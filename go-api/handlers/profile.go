@@ -0,0 +1,59 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+
+    "ai_seller/logging"
+    "ai_seller/storage"
+)
+
+// profileKey — ключ контекста с профилем покупателя, прочитанным для апдейта
+type profileKey struct{}
+
+// loadedProfile — профиль чата; found — false, если профиля ещё нет,
+// err — ошибка чтения (тогда настройки берутся по умолчанию)
+type loadedProfile struct {
+    chatID int64
+    user   storage.User
+    found  bool
+    err    error
+}
+
+// withProfile читает профиль чата один раз на апдейт: язык, вариант промпта,
+// краткий режим и валюта берутся из него, а не отдельными запросами
+func (b *Bot) withProfile(ctx context.Context, chatID int64) context.Context {
+    p := &loadedProfile{chatID: chatID}
+    p.user, p.err = b.Users.GetUser(ctx, chatID)
+    switch {
+    case p.err == nil:
+        p.found = true
+    case errors.Is(p.err, storage.ErrNotFound):
+        p.err = nil
+    default:
+        logging.FromContext(ctx).Warn("ошибка чтения профиля покупателя", "chat_id", chatID, "err", p.err)
+    }
+    return context.WithValue(ctx, profileKey{}, p)
+}
+
+// profile — профиль чата из контекста или, если апдейт его не читал
+// (фоновые задачи, другой чат), из хранилища. Профиля нет — storage.ErrNotFound.
+func (b *Bot) profile(ctx context.Context, chatID int64) (storage.User, error) {
+    if p, ok := ctx.Value(profileKey{}).(*loadedProfile); ok && p.chatID == chatID {
+        if !p.found && p.err == nil {
+            return storage.User{}, storage.ErrNotFound
+        }
+        return p.user, p.err
+    }
+    return b.Users.GetUser(ctx, chatID)
+}
+
+// updateProfile применяет change к профилю в контексте после записи в
+// хранилище, чтобы дальнейшая обработка апдейта видела новое значение
+func updateProfile(ctx context.Context, chatID int64, change func(*storage.User)) {
+    if p, ok := ctx.Value(profileKey{}).(*loadedProfile); ok && p.chatID == chatID && p.err == nil {
+        p.user.ChatID = chatID
+        change(&p.user)
+        p.found = true
+    }
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+    "context"
+    "sync/atomic"
+    "testing"
+
+    "ai_seller/config"
+    "ai_seller/memstore"
+    "ai_seller/storage"
+)
+
+// countingUsers считает чтения профиля
+type countingUsers struct {
+    *memstore.Users
+    reads atomic.Int64
+}
+
+func (u *countingUsers) GetUser(ctx context.Context, chatID int64) (storage.User, error) {
+    u.reads.Add(1)
+    return u.Users.GetUser(ctx, chatID)
+}
+
+func TestProfileReadOncePerUpdate(t *testing.T) {
+    users := &countingUsers{Users: &memstore.Users{}}
+    tb := newTestBot(t, map[string]string{"PROMPT_VARIANTS": "a=1,b=1"}, func(d *Deps) { d.Users = users })
+    _ = users.SetBrief(context.Background(), 42, true)
+    _ = users.SetCurrency(context.Background(), 42, "USD")
+
+    users.reads.Store(0)
+    tb.process(t, text(1, 42, "что посоветуете?"))
+    if n := users.reads.Load(); n != 1 {
+        t.Fatalf("чтений профиля за апдейт: получено %d, ожидалось 1", n)
+    }
+}
+
+func TestPromptVariantPinned(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"PROMPT_VARIANTS": "a=1,b=1"})
+    tb.process(t, text(1, 42, "привет"))
+
+    u, err := tb.users.GetUser(context.Background(), 42)
+    if err != nil {
+        t.Fatalf("профиль: %v", err)
+    }
+    want := config.PickPromptVariant(tb.Config.PromptVariants, 42)
+    if u.PromptVariant != want {
+        t.Fatalf("закреплён вариант %q, ожидался %q", u.PromptVariant, want)
+    }
+
+    // Вариант, убранный из теста, назначается заново
+    _ = tb.users.SetPromptVariant(context.Background(), 42, "old")
+    tb.process(t, text(2, 42, "ещё вопрос"))
+    if u, _ := tb.users.GetUser(context.Background(), 42); u.PromptVariant != want {
+        t.Fatalf("после смены вариантов: получено %q, ожидалось %q", u.PromptVariant, want)
+    }
+}
+
+func TestProfileSeesOwnWrites(t *testing.T) {
+    tb := newTestBot(t, nil)
+    ctx := tb.withProfile(context.Background(), 42)
+    if _, err := tb.profile(ctx, 42); err != storage.ErrNotFound {
+        t.Fatalf("нового профиля нет: получено %v", err)
+    }
+    updateProfile(ctx, 42, func(u *storage.User) { u.Currency = "EUR" })
+    if u, err := tb.profile(ctx, 42); err != nil || u.Currency != "EUR" {
+        t.Fatalf("после записи: %+v, %v", u, err)
+    }
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/dialog"
+    "ai_seller/storage"
+)
+
+// cmdAdd — команда /add <текст>: модель разбирает, что покупатель хочет
+// («две чёрные футболки M»), бот находит товар в каталоге и кладёт его в корзину
+func (b *Bot) cmdAdd(ctx context.Context, msg *TelegramMessage, args string) error {
+    if args == "" {
+        return apperr.Validation("Использование: /add <что добавить>, например: /add 2 чёрные футболки M")
+    }
+    chatID := msg.Chat.ID
+    if b.overBudget(ctx) {
+        b.replyPhrase(ctx, chatID, overBudgetReply)
+        return nil
+    }
+
+    details, err := dialog.ExtractOrder(ctx, b.OpenAI, args)
+    if errors.Is(err, dialog.ErrNoOrderDetails) {
+        return apperr.Validation("Не понял, какой товар добавить. Напишите название, например: /add футболка оверсайз M")
+    }
+    if err != nil {
+        return apperr.WithMessage(apperr.Upstream(err), "Не получилось разобрать запрос, попробуйте ещё раз.")
+    }
+
+    products, err := b.searchProducts(ctx, details.Product)
+    if err != nil {
+        return err
+    }
+    p, ok := bestMatch(products, details)
+    if !ok {
+        return apperr.NotFound(fmt.Sprintf("Не нашёл «%s» в наличии. Посмотрите весь каталог — /catalog", details.Product))
+    }
+
+    if err := b.Carts.AddItem(ctx, chatID, p.ID, details.Quantity); err != nil {
+        return err
+    }
+    b.noteShopping(ctx)
+    b.reply(ctx, chatID, addedReply(p, details))
+    return nil
+}
+
+// bestMatch — товар в наличии, в названии или описании которого больше
+// всего совпадений с размером и цветом; при равенстве — первый из поиска
+func bestMatch(products []storage.Product, d dialog.OrderDetails) (storage.Product, bool) {
+    var best storage.Product
+    bestScore, found := -1, false
+    for _, p := range products {
+        if !p.InStock {
+            continue
+        }
+        text := strings.ToLower(p.Name + " " + p.Description)
+        score := 0
+        for _, want := range []string{d.Size, d.Color} {
+            if want != "" && strings.Contains(text, strings.ToLower(want)) {
+                score++
+            }
+        }
+        if score > bestScore {
+            best, bestScore, found = p, score, true
+        }
+    }
+    return best, found
+}
+
+// addedReply — что добавлено в корзину; размер и цвет, которых нет в
+// карточке товара, напоминаются, чтобы их уточнили при оформлении
+func addedReply(p storage.Product, d dialog.OrderDetails) string {
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "Добавил в корзину: %s × %d.", p.Name, d.Quantity)
+    var notes []string
+    if d.Size != "" {
+        notes = append(notes, "размер "+d.Size)
+    }
+    if d.Color != "" {
+        notes = append(notes, "цвет "+d.Color)
+    }
+    if len(notes) > 0 {
+        fmt.Fprintf(&sb, "\nВы указали %s — уточним при оформлении.", strings.Join(notes, ", "))
+    }
+    sb.WriteString("\nКорзина — /cart, оформить заказ — /checkout")
+    return sb.String()
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "ai_seller/cache"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// Разобранный моделью заказ кладёт в корзину подходящий по цвету товар
+// в наличии; если первый ответ модели не JSON, она переспрашивается
+func TestAddCommand(t *testing.T) {
+    cases := []struct {
+        name  string
+        reply string
+        cart  []cache.CartItem
+        want  string
+    }{
+        {"корректный JSON", `{"product": "футболка", "quantity": 2, "size": "M", "color": "чёрная"}`,
+            []cache.CartItem{{ProductID: 2, Qty: 2}}, "Добавил в корзину: Футболка чёрная × 2."},
+        {"товар не назван", `{"product": "", "quantity": 1, "size": "M", "color": ""}`,
+            nil, "Не понял, какой товар добавить."},
+        {"не JSON оба раза", "Две чёрные футболки, размер M",
+            nil, "Не получилось разобрать запрос"},
+        {"нет в наличии", `{"product": "худи", "quantity": 1, "size": "", "color": ""}`,
+            nil, "Не нашёл «худи» в наличии."},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, nil)
+            tb.catalog.Add(
+                storage.Product{ID: 1, Name: "Футболка белая", Price: money.New(99000, "RUB"), InStock: true},
+                storage.Product{ID: 2, Name: "Футболка чёрная", Price: money.New(99000, "RUB"), InStock: true},
+                storage.Product{ID: 3, Name: "Худи", Price: money.New(299000, "RUB"), InStock: false},
+            )
+            tb.ai.Reply = tc.reply
+
+            tb.process(t, text(1, 42, "/add две чёрные футболки M"))
+            got := tb.sentTo(42)
+            if len(got) != 1 || !strings.Contains(got[0], tc.want) {
+                t.Fatalf("ответы %q, нужен %q", got, tc.want)
+            }
+            cart, _ := tb.carts.GetCart(context.Background(), 42)
+            if len(cart.Items) != len(tc.cart) || (len(tc.cart) > 0 && cart.Items[0] != tc.cart[0]) {
+                t.Fatalf("корзина %+v, нужно %+v", cart.Items, tc.cart)
+            }
+        })
+    }
+}
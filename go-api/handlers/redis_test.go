@@ -0,0 +1,47 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+)
+
+// Redis лежит: покупатель всё равно получает ответ, а контекст диалога
+// берётся из постоянной истории
+func TestAnswersWhenRedisDown(t *testing.T) {
+    tb := newTestBot(t, nil)
+    ctx := context.Background()
+    if err := tb.messages.SaveMessage(ctx, 42, "user", "меня зовут Анна"); err != nil {
+        t.Fatal(err)
+    }
+    tb.redis.SetError("ERR down")
+
+    tb.process(t, text(1, 42, "есть улун?"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("при недоступном Redis отправлено %q", got)
+    }
+    if len(tb.ai.Requests) != 1 {
+        t.Fatalf("запросов к модели %d", len(tb.ai.Requests))
+    }
+    var prompt []string
+    for _, m := range tb.ai.Requests[0] {
+        prompt = append(prompt, m.Content)
+    }
+    if joined := strings.Join(prompt, "\n"); !strings.Contains(joined, "меня зовут Анна") {
+        t.Fatalf("контекст без истории из хранилища:\n%s", joined)
+    }
+}
+
+// RATE_LIMIT_FAIL_OPEN=false: без лимитера сообщения отклоняются, модель не вызывается
+func TestRateLimitFailClosed(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"RATE_LIMIT_FAIL_OPEN": "false"})
+    tb.redis.SetError("ERR down")
+
+    tb.process(t, text(1, 42, "есть улун?"))
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != rateLimitedReply {
+        t.Fatalf("отправлено %q, нужен отказ лимитера", got)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("запросов к модели %d", len(tb.ai.Requests))
+    }
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/openai"
+    "ai_seller/reqctx"
+)
+
+const (
+    // reengageInterval — как часто искать замолчавших покупателей
+    reengageInterval = 30 * time.Minute
+    // nudgeTimeout — предел на составление и отправку одной подсказки
+    nudgeTimeout = time.Minute
+    // nudgeHistory — сколько последних реплик видит модель, составляя подсказку
+    nudgeHistory = 10
+    // nudgeMaxTokens — предел длины подсказки
+    nudgeMaxTokens = 150
+)
+
+// nudgePrompt — инструкция модели для подсказки замолчавшему покупателю
+const nudgePrompt = "Ты продавец интернет-магазина. Покупатель смотрел товары, но давно не писал. " +
+    "Напиши ему одно короткое дружелюбное сообщение, не больше двух предложений: напомни, что его " +
+    "интересовало, и предложи помочь с выбором или заказом. Не выдумывай скидки, цены и наличие, " +
+    "не торопи покупателя. Пиши на языке покупателя. Ответь только текстом сообщения."
+
+// ReengageIdleCustomers раз в reengageInterval пишет покупателям, которые
+// смотрели товары или меняли корзину (Deps.Shopping), но замолчали дольше
+// REENGAGE_AFTER, короткую подсказку от модели по их переписке и корзине. Каждому — не чаще раза за
+// REENGAGE_COOLDOWN, в тихие часы — никому. Работает до отмены ctx.
+func (b *Bot) ReengageIdleCustomers(ctx context.Context) {
+    ticker := time.NewTicker(reengageInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            b.reengage(ctx, time.Now())
+        }
+    }
+}
+
+// reengage — один проход по замолчавшим покупателям. Подсказка отмечается
+// до запроса к модели, поэтому неудачная попытка тоже занимает окно
+// REENGAGE_COOLDOWN: лучше пропустить подсказку, чем написать дважды.
+func (b *Bot) reengage(ctx context.Context, now time.Time) {
+    cfg := b.Config
+    if cfg.ReengageQuietHours != nil && cfg.ReengageQuietHours.Open(now) {
+        return
+    }
+    if b.Shopping == nil {
+        return
+    }
+    shoppers, err := b.Shopping.Since(ctx, now.Add(-cfg.ReengageLookback))
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка чтения активности покупателей", "err", err)
+        return
+    }
+    chatIDs, err := b.Reengagement.Candidates(ctx, now, cfg.ReengageAfter, cfg.ReengageLookback, cfg.ReengageCooldown, cfg.ReengageBatch, shoppers)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка поиска замолчавших покупателей", "err", err)
+        return
+    }
+
+    throttle := time.NewTicker(broadcastInterval)
+    defer throttle.Stop()
+
+    var sent int
+    for _, chatID := range chatIDs {
+        if b.handedOff(ctx, chatID) {
+            continue
+        }
+        claimed, err := b.Reengagement.ClaimNudge(ctx, chatID, now, cfg.ReengageCooldown)
+        if err != nil {
+            logging.FromContext(ctx).Error("ошибка отметки подсказки", "chat_id", chatID, "err", err)
+            continue
+        }
+        if !claimed {
+            continue
+        }
+
+        if sent > 0 {
+            select {
+            case <-ctx.Done():
+                return
+            case <-throttle.C:
+            }
+        }
+        if b.sendNudge(ctx, chatID) {
+            sent++
+        }
+    }
+    if sent > 0 {
+        logging.FromContext(ctx).Info("отправлены подсказки замолчавшим покупателям", "sent", sent)
+    }
+}
+
+// noteShopping отмечает, что покупатель из ctx смотрит товары или меняет
+// корзину. Ошибка только логируется: без отметки он всего лишь не получит
+// подсказку.
+func (b *Bot) noteShopping(ctx context.Context) {
+    chatID := reqctx.ChatIDFromContext(ctx)
+    if b.Shopping == nil || chatID == 0 {
+        return
+    }
+    if err := b.Shopping.Touch(ctx, chatID, time.Now()); err != nil {
+        logging.FromContext(ctx).Warn("не удалось отметить активность покупателя", "chat_id", chatID, "err", err)
+    }
+}
+
+// sendNudge составляет подсказку и отправляет её; false — подсказки не было
+// (модель не ответила, фильтр её не пропустил или Telegram отказал)
+func (b *Bot) sendNudge(ctx context.Context, chatID int64) bool {
+    ctx, cancel := context.WithTimeout(reqctx.WithChatID(ctx, chatID), nudgeTimeout)
+    defer cancel()
+    // В фоне языка из Telegram нет: берём его из профиля, как напоминания о корзине
+    lang := i18n.DefaultLang
+    if u, err := b.profile(ctx, chatID); err == nil && u.Language() != "" {
+        lang = u.Language()
+    }
+    ctx = reqctx.WithLang(ctx, lang)
+    log := logging.FromContext(ctx).With("chat_id", chatID)
+
+    text, err := b.composeNudge(ctx, chatID)
+    if err != nil {
+        log.Warn("подсказка не составлена", "err", err)
+        return false
+    }
+    text, blocked := b.filterAnswer(ctx, text)
+    if blocked || text == "" {
+        return false
+    }
+    if _, err := b.send(ctx, chatID, text); err != nil {
+        return false
+    }
+    // Покупатель ответит на подсказку — модель должна её видеть
+    b.remember(ctx, chatID, "assistant", text)
+    return true
+}
+
+// composeNudge просит модель написать подсказку по последним репликам и корзине
+func (b *Bot) composeNudge(ctx context.Context, chatID int64) (string, error) {
+    history, err := b.Messages.GetHistory(ctx, chatID, nudgeHistory)
+    if err != nil {
+        return "", err
+    }
+    if len(history) == 0 {
+        return "", errors.New("история чата пуста")
+    }
+
+    var sb strings.Builder
+    sb.WriteString("Последние реплики:\n")
+    for _, m := range history {
+        who := "Покупатель"
+        if m.Role == "assistant" {
+            who = "Продавец"
+        }
+        sb.WriteString(who + ": " + m.Content + "\n")
+    }
+    if lines, err := b.cartLines(ctx, chatID); err == nil && len(lines) > 0 {
+        sb.WriteString("\nВ корзине:\n")
+        for _, l := range lines {
+            sb.WriteString("- " + l.Product.Name + " × " + strconv.Itoa(l.Qty) + "\n")
+        }
+    }
+    sb.WriteString("\nЯзык покупателя: " + reqctx.LangFromContext(ctx))
+
+    text, err := b.OpenAI.ChatCompletion(openai.WithMaxTokens(ctx, nudgeMaxTokens), []openai.Message{
+        openai.System(nudgePrompt),
+        openai.User(sb.String()),
+    })
+    if err != nil {
+        return "", err
+    }
+    return strings.TrimSpace(text), nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/memstore"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// reengageBot — бот с подсказками замолчавшим покупателям без тихих часов
+func reengageBot(t *testing.T, env map[string]string) *testBot {
+    t.Helper()
+    tb := newTestBot(t, env)
+    tb.Reengagement = &memstore.Reengagement{Messages: tb.messages, Orders: tb.orders, Chats: tb.chats}
+    tb.Shopping = cache.NewShoppingActivity(tb.rdb, tb.Config.ReengageLookback)
+    tb.Config.ReengageQuietHours = nil
+    return tb
+}
+
+// customer — покупатель chatID задал вопрос и, если browsed, смотрел каталог
+func (tb *testBot) customer(t *testing.T, updateID, chatID int64, browsed bool) {
+    t.Helper()
+    if err := tb.messages.SaveMessage(context.Background(), chatID, "user", "ищу зелёный чай"); err != nil {
+        t.Fatal(err)
+    }
+    if browsed {
+        tb.process(t, text(updateID, chatID, "/catalog"))
+    }
+}
+
+// nudges — сколько подсказок получил чат
+func (tb *testBot) nudges(chatID int64) int {
+    var n int
+    for _, m := range tb.sentTo(chatID) {
+        if m == tb.ai.Reply {
+            n++
+        }
+    }
+    return n
+}
+
+func TestReengageEligibility(t *testing.T) {
+    const (
+        shopper int64 = 101
+        talker  int64 = 102
+        buyer   int64 = 103
+        blocked int64 = 104
+    )
+    tb := reengageBot(t, nil)
+    ctx := context.Background()
+    tb.customer(t, 1, shopper, true)
+    tb.customer(t, 2, talker, false)
+    tb.customer(t, 3, buyer, true)
+    tb.customer(t, 4, blocked, true)
+    if _, err := tb.orders.CreateOrder(ctx, buyer, []storage.OrderItem{{ProductID: 1, Qty: 1}}, money.Money{}, "k", time.Minute); err != nil {
+        t.Fatal(err)
+    }
+    if err := tb.chats.MarkInactive(ctx, blocked); err != nil {
+        t.Fatal(err)
+    }
+
+    // Покупатель ещё не успел замолчать
+    tb.reengage(ctx, time.Now().Add(time.Hour))
+    if n := tb.nudges(shopper); n != 0 {
+        t.Fatalf("подсказка до REENGAGE_AFTER: %d", n)
+    }
+
+    tb.reengage(ctx, time.Now().Add(25*time.Hour))
+    want := map[int64]int{shopper: 1, talker: 0, buyer: 0, blocked: 0}
+    for chatID, n := range want {
+        if got := tb.nudges(chatID); got != n {
+            t.Errorf("чат %d: подсказок %d, ожидалось %d", chatID, got, n)
+        }
+    }
+
+    // За пределами REENGAGE_LOOKBACK давняя активность не в счёт
+    tb.Config.ReengageCooldown = time.Hour
+    tb.reengage(ctx, time.Now().Add(8*24*time.Hour))
+    if n := tb.nudges(shopper); n != 1 {
+        t.Fatalf("подсказка после REENGAGE_LOOKBACK: всего %d", n)
+    }
+}
+
+func TestReengageOnePerWindow(t *testing.T) {
+    const shopper int64 = 101
+    tb := reengageBot(t, map[string]string{"REENGAGE_COOLDOWN": "48h"})
+    ctx := context.Background()
+    tb.customer(t, 1, shopper, true)
+
+    first := time.Now().Add(25 * time.Hour)
+    tb.reengage(ctx, first)
+    tb.reengage(ctx, first.Add(time.Hour))
+    if n := tb.nudges(shopper); n != 1 {
+        t.Fatalf("подсказок за окно REENGAGE_COOLDOWN: %d, ожидалась 1", n)
+    }
+    tb.reengage(ctx, first.Add(48*time.Hour+time.Minute))
+    if n := tb.nudges(shopper); n != 2 {
+        t.Fatalf("после окна подсказок %d, ожидалось 2", n)
+    }
+}
+
+func TestEraseForgetsShoppingActivity(t *testing.T) {
+    tb := reengageBot(t, nil)
+    tb.customer(t, 1, 42, true)
+
+    tb.process(t, text(2, 42, "/deletedata"))
+    sent := tb.tg.Messages()
+    tb.process(t, eraseButton(3, 42, "private", 42, sent[len(sent)-1].MessageID))
+
+    shoppers, err := tb.Shopping.Since(context.Background(), time.Now().Add(-time.Hour))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(shoppers) != 0 {
+        t.Fatalf("после удаления данных осталась активность: %v", shoppers)
+    }
+}
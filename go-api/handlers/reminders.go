@@ -0,0 +1,96 @@
+package handlers
+
+import (
+    "context"
+    "time"
+
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/telegram"
+)
+
+// cartReminderInterval — как часто искать брошенные корзины
+const cartReminderInterval = 10 * time.Minute
+
+// cartReminderReply — напоминание о брошенной корзине
+const cartReminderReply = "У вас остались товары в корзине 🛒 Посмотреть — /cart, оформить заказ — /checkout"
+
+// RemindAbandonedCarts раз в cartReminderInterval напоминает о корзинах,
+// которые не менялись дольше idle. О каждой корзине напоминает один раз —
+// до следующего её изменения. Работает до отмены ctx.
+func (b *Bot) RemindAbandonedCarts(ctx context.Context, idle time.Duration) {
+    ticker := time.NewTicker(cartReminderInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            b.remindCarts(ctx, idle)
+        }
+    }
+}
+
+// remindCarts — один проход по брошенным корзинам. Отправка идёт с той же
+// паузой, что и рассылка, чтобы не упереться в лимиты Telegram.
+func (b *Bot) remindCarts(ctx context.Context, idle time.Duration) {
+    chatIDs, err := b.Carts.IdleCarts(ctx, idle)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка поиска брошенных корзин", "err", err)
+        return
+    }
+
+    throttle := time.NewTicker(broadcastInterval)
+    defer throttle.Stop()
+
+    var sent int
+    for _, chatID := range chatIDs {
+        claimed, err := b.Carts.ClaimReminder(ctx, chatID)
+        if err != nil {
+            logging.FromContext(ctx).Error("ошибка отметки напоминания", "chat_id", chatID, "err", err)
+            continue
+        }
+        if !claimed {
+            continue
+        }
+        cart, err := b.Carts.GetCart(ctx, chatID)
+        if err != nil || cart.Empty() {
+            continue
+        }
+
+        if sent > 0 {
+            select {
+            case <-ctx.Done():
+                return
+            case <-throttle.C:
+            }
+        }
+        b.sendCartReminder(ctx, chatID)
+        sent++
+    }
+    if sent > 0 {
+        logging.FromContext(ctx).Info("отправлены напоминания о корзинах", "sent", sent)
+    }
+}
+
+// sendCartReminder отправляет напоминание на языке покупателя из профиля и
+// помечает неактивными чаты, где бот заблокирован
+func (b *Bot) sendCartReminder(ctx context.Context, chatID int64) {
+    lang := i18n.DefaultLang
+    if u, err := b.profile(ctx, chatID); err == nil {
+        lang = u.Lang
+    }
+    ctx = reqctx.WithChatID(ctx, chatID)
+
+    _, err := b.Telegram.SendMessage(chatID, i18n.T(lang, cartReminderReply))
+    switch {
+    case err == nil:
+    case telegram.IsForbidden(err):
+        if err := b.Chats.MarkInactive(ctx, chatID); err != nil {
+            logging.FromContext(ctx).Error("ошибка пометки чата", "chat_id", chatID, "err", err)
+        }
+    default:
+        logging.FromContext(ctx).Warn("ошибка отправки напоминания о корзине", "chat_id", chatID, "err", err)
+    }
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+// remindedChats — чаты, получившие напоминание о корзине, по порядку
+func remindedChats(tb *testBot) []int64 {
+    var out []int64
+    for _, m := range tb.tg.Messages() {
+        if m.Text == cartReminderReply {
+            out = append(out, m.ChatID)
+        }
+    }
+    return out
+}
+
+// О брошенной корзине напоминают один раз — до следующего её изменения
+func TestCartReminderOnce(t *testing.T) {
+    tb := newTestBot(t, nil)
+    ctx := context.Background()
+    for _, chatID := range []int64{42, 43} {
+        if err := tb.carts.AddItem(ctx, chatID, 1, 1); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tb.remindCarts(ctx, 0)
+    if got := remindedChats(tb); len(got) != 2 || got[0] != 42 || got[1] != 43 {
+        t.Fatalf("напомнили чатам %v, нужно 42 и 43", got)
+    }
+    tb.remindCarts(ctx, 0)
+    if got := remindedChats(tb); len(got) != 2 {
+        t.Fatalf("повторный проход напомнил снова: %v", got)
+    }
+
+    if err := tb.carts.AddItem(ctx, 42, 2, 1); err != nil {
+        t.Fatal(err)
+    }
+    tb.remindCarts(ctx, 0)
+    if got := remindedChats(tb); len(got) != 3 || got[2] != 42 {
+        t.Fatalf("после изменения корзины напомнили %v, нужно ещё раз чату 42", got)
+    }
+}
+
+func TestCartReminderSkips(t *testing.T) {
+    tb := newTestBot(t, nil)
+    ctx := context.Background()
+    if err := tb.carts.AddItem(ctx, 42, 1, 1); err != nil {
+        t.Fatal(err)
+    }
+    // Корзина 43 опустела, но осталась в списке изменённых
+    if err := tb.carts.AddItem(ctx, 43, 1, 1); err != nil {
+        t.Fatal(err)
+    }
+    if err := tb.carts.RemoveItem(ctx, 43, 1); err != nil {
+        t.Fatal(err)
+    }
+
+    tb.remindCarts(ctx, time.Hour)
+    if got := remindedChats(tb); len(got) != 0 {
+        t.Fatalf("напомнили о свежей корзине: %v", got)
+    }
+    tb.remindCarts(ctx, 0)
+    if got := remindedChats(tb); len(got) != 1 || got[0] != 42 {
+        t.Fatalf("напомнили чатам %v, нужно только 42", got)
+    }
+}
+
+// Бот, которого покупатель заблокировал, помечает чат неактивным
+func TestCartReminderBlockedBot(t *testing.T) {
+    tb, _ := broadcastBot(t, nil, map[int64][]error{42: {apiStatus(403)}})
+    ctx := context.Background()
+    if err := tb.carts.AddItem(ctx, 42, 1, 1); err != nil {
+        t.Fatal(err)
+    }
+    tb.remindCarts(ctx, 0)
+    if !tb.chats.Inactive(42) {
+        t.Fatal("чат, заблокировавший бота, не помечен неактивным")
+    }
+}
+
+// На остановке проход прерывается на паузе между отправками
+func TestCartReminderStopsOnShutdown(t *testing.T) {
+    tb := newTestBot(t, nil)
+    ctx, cancel := context.WithCancel(context.Background())
+    for _, chatID := range []int64{42, 43, 44} {
+        if err := tb.carts.AddItem(ctx, chatID, 1, 1); err != nil {
+            t.Fatal(err)
+        }
+    }
+    cancel()
+    tb.remindCarts(ctx, 0)
+    if got := remindedChats(tb); len(got) != 1 {
+        t.Fatalf("после отмены напомнили %v, нужно только первому", got)
+    }
+
+    done := make(chan struct{})
+    go func() {
+        tb.RemindAbandonedCarts(ctx, 0)
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatal("RemindAbandonedCarts не остановился по отмене контекста")
+    }
+}
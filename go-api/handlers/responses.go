@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "context"
+
+    "ai_seller/cache"
+    "ai_seller/logging"
+    "ai_seller/metrics"
+    "ai_seller/openai"
+    "ai_seller/reqctx"
+)
+
+// withSharedAnswer отмечает, что ответ может уйти в кэш ответов: тогда
+// промпт первого вопроса собирается без имени покупателя (см. reqctx.WithSharedAnswer)
+func (b *Bot) withSharedAnswer(ctx context.Context) context.Context {
+    if b.Responses == nil {
+        return ctx
+    }
+    return reqctx.WithSharedAnswer(ctx)
+}
+
+// responseCacheKey — ключ кэша ответов для вопроса text или пустая строка,
+// если кэш не применяется. Кэшируются только первые вопросы чата: с историей
+// ответ зависит от разговора и другому покупателю не подойдёт. Такой промпт
+// собран без имени (withSharedAnswer), поэтому ответ не личный; язык, вариант
+// промпта и краткий режим меняют ответ и входят в ключ.
+func (b *Bot) responseCacheKey(ctx context.Context, prompt []openai.Message, text string) string {
+    if b.Responses == nil || len(prompt) > 1 || !reqctx.SharedAnswerFromContext(ctx) {
+        return ""
+    }
+    length := "full"
+    if reqctx.BriefFromContext(ctx) {
+        length = "brief"
+    }
+    return cache.ResponseKey(b.Config.OpenAIModel, b.Dialog.SystemPromptFor(reqctx.PromptVariantFromContext(ctx)),
+        reqctx.LangFromContext(ctx), length, text)
+}
+
+// cachedResponse — ответ из кэша. Ошибки Redis не мешают ответу — идём в модель.
+func (b *Bot) cachedResponse(ctx context.Context, key string) (string, bool) {
+    if key == "" {
+        return "", false
+    }
+    answer, ok, err := b.Responses.Get(ctx, key)
+    if err != nil {
+        logging.FromContext(ctx).Warn("кэш ответов недоступен", "err", err)
+        return "", false
+    }
+    if !ok {
+        metrics.ResponseCacheTotal.WithLabelValues("miss").Inc()
+        return "", false
+    }
+    metrics.ResponseCacheTotal.WithLabelValues("hit").Inc()
+    logging.FromContext(ctx).Info("ответ из кэша", "chat_id", reqctx.ChatIDFromContext(ctx))
+    return answer, true
+}
+
+// storeResponse сохраняет ответ модели в кэш, если для вопроса есть ключ
+func (b *Bot) storeResponse(ctx context.Context, key, answer string) {
+    if key == "" || answer == "" {
+        return
+    }
+    if err := b.Responses.Set(ctx, key, answer); err != nil {
+        logging.FromContext(ctx).Warn("не удалось сохранить ответ в кэш", "err", err)
+    }
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+)
+
+func TestResponseCacheSharesAnonymousFirstAnswer(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"RESPONSE_CACHE": "true"})
+    ctx := context.Background()
+    _ = tb.users.Upsert(ctx, 1, "anna", "ru", "Покупатель")
+
+    tb.process(t, text(1, 1, "Есть доставка?"))
+    if len(tb.ai.Requests) != 1 {
+        t.Fatalf("запросов к модели: получено %d, ожидался 1", len(tb.ai.Requests))
+    }
+    if system := tb.ai.Requests[0][0].Content; strings.Contains(system, "Покупатель") {
+        t.Fatalf("в промпт кэшируемого ответа попало имя: %q", system)
+    }
+
+    tb.process(t, text(2, 2, "есть  доставка?"))
+    if len(tb.ai.Requests) != 1 {
+        t.Fatal("одинаковый первый вопрос снова ушёл модели")
+    }
+    if got := tb.sentTo(2); len(got) != 1 || got[0] != "ответ модели" {
+        t.Fatalf("второму покупателю отправлено %q", got)
+    }
+}
+
+func TestResponseCacheSeparatesBrief(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"RESPONSE_CACHE": "true"})
+    tb.process(t, text(1, 1, "Есть доставка?"))
+    _ = tb.users.SetBrief(context.Background(), 2, true)
+    tb.process(t, text(2, 2, "Есть доставка?"))
+    if len(tb.ai.Requests) != 2 {
+        t.Fatalf("краткий ответ взят из кэша полных: запросов %d, ожидалось 2", len(tb.ai.Requests))
+    }
+}
+
+func TestResponseCacheSkipsConversation(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"RESPONSE_CACHE": "true"})
+    _ = tb.users.Upsert(context.Background(), 1, "anna", "ru", "Покупатель")
+    tb.process(t, text(1, 1, "привет"))
+    tb.process(t, text(2, 1, "Есть доставка?"))
+    if system := tb.ai.Requests[1][0].Content; !strings.Contains(system, "Покупатель") {
+        t.Fatalf("в разговоре пропало имя покупателя: %q", system)
+    }
+
+    tb.process(t, text(3, 2, "привет"))
+    tb.process(t, text(4, 2, "Есть доставка?"))
+    if len(tb.ai.Requests) != 3 {
+        t.Fatalf("ответ из середины разговора попал в кэш: запросов %d, ожидалось 3", len(tb.ai.Requests))
+    }
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+const (
+    // searchResultsLimit — сколько найденных товаров показывать в ответе /search
+    searchResultsLimit = 5
+    // nothingFoundReply — ответ /search, если ни один товар не подошёл
+    nothingFoundReply = "По вашему запросу ничего не найдено. Посмотрите весь каталог — /catalog"
+)
+
+// cmdSearch — команда /search <запрос>: сначала поиск по подстроке, а если
+// он ничего не дал — по похожести названия, чтобы прощать опечатки
+func (b *Bot) cmdSearch(ctx context.Context, msg *TelegramMessage, args string) error {
+    query := strings.TrimSpace(args)
+    if query == "" {
+        return apperr.Validation("Использование: /search <название товара>")
+    }
+
+    products, err := b.findProducts(ctx, query)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось выполнить поиск, попробуйте позже.")
+    }
+    if len(products) == 0 {
+        b.replyPhrase(ctx, msg.Chat.ID, nothingFoundReply)
+        return nil
+    }
+
+    // Простой текст: в названиях товаров могут быть символы разметки
+    _, err = b.Telegram.SendMessage(msg.Chat.ID, renderSearchResults(products, b.prices(ctx)), telegram.WithParseMode(""))
+    return err
+}
+
+// findProducts ищет товары по подстроке, а если она ничего не дала — по
+// похожести названия
+func (b *Bot) findProducts(ctx context.Context, query string) ([]storage.Product, error) {
+    b.noteShopping(ctx)
+    products, err := b.Catalog.SearchProducts(ctx, query)
+    if err == nil && len(products) == 0 {
+        products, err = b.Catalog.FuzzySearchProducts(ctx, query, b.Config.SearchSimilarity)
+    }
+    return products, err
+}
+
+// renderSearchResults — первые searchResultsLimit найденных товаров с ценами
+func renderSearchResults(products []storage.Product, prices *priceFormat) string {
+    if len(products) > searchResultsLimit {
+        products = products[:searchResultsLimit]
+    }
+    var sb strings.Builder
+    sb.WriteString("🔎 Нашлось:\n")
+    for i, p := range products {
+        fmt.Fprintf(&sb, "%d. %s — %s", i+1, p.Name, prices.Format(p.Price))
+        if !p.InStock {
+            sb.WriteString(" (нет в наличии)")
+        }
+        sb.WriteByte('\n')
+    }
+    sb.WriteString(prices.Note())
+    return sb.String()
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+    "strings"
+    "testing"
+
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// searchCatalog — чаи для поиска с опечатками
+func searchCatalog(tb *testBot) {
+    tb.catalog.Add(
+        storage.Product{ID: 1, Name: "Сенча", Price: money.New(45000, "RUB"), InStock: true},
+        storage.Product{ID: 2, Name: "Улун", Price: money.New(60000, "RUB"), InStock: true},
+        storage.Product{ID: 3, Name: "Пуэр", Price: money.New(90000, "RUB"), InStock: false},
+    )
+}
+
+func TestSearchWithTypos(t *testing.T) {
+    cases := []struct {
+        query   string
+        want    string
+        notWant string
+    }{
+        {"улун", "1. Улун — 600", "Сенча"},
+        {"сенчя", "1. Сенча — 450", "Улун"},
+        {"улунн", "1. Улун — 600", "Пуэр"},
+        {"пуэрр", "1. Пуэр — 900", "Сенча"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.query, func(t *testing.T) {
+            tb := newTestBot(t, nil)
+            searchCatalog(tb)
+            tb.process(t, text(1, 42, "/search "+tc.query))
+
+            got := tb.sentTo(42)
+            if len(got) != 1 || !strings.Contains(got[0], tc.want) || strings.Contains(got[0], tc.notWant) {
+                t.Fatalf("на %q ответ %q, нужно %q без %q", tc.query, got, tc.want, tc.notWant)
+            }
+        })
+    }
+}
+
+func TestSearchNothingFound(t *testing.T) {
+    cases := []struct {
+        name  string
+        env   map[string]string
+        query string
+    }{
+        {"непохожий запрос", nil, "вертолёт"},
+        {"строгий порог", map[string]string{"SEARCH_SIMILARITY_THRESHOLD": "0.9"}, "сенчя"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, tc.env)
+            searchCatalog(tb)
+            tb.process(t, text(1, 42, "/search "+tc.query))
+            if got := tb.sentTo(42); len(got) != 1 || got[0] != nothingFoundReply {
+                t.Fatalf("отправлено %q, нужно «ничего не найдено» со ссылкой на /catalog", got)
+            }
+        })
+    }
+}
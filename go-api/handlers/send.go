@@ -0,0 +1,114 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/telegram"
+)
+
+// sendRetryBase — пауза перед первым повтором отправки; удваивается с каждой попыткой
+const sendRetryBase = 500 * time.Millisecond
+
+// sendVerdict — что делать после неудачной отправки в Telegram
+type sendVerdict int
+
+const (
+    // sendDone — сообщение отправлено
+    sendDone sendVerdict = iota
+    // sendRetry — сетевой сбой или 5xx: повторить с отсрочкой
+    sendRetry
+    // sendWait — 429: повторить, выждав retry_after
+    sendWait
+    // sendBlocked — 403: бот заблокирован, чат помечается неактивным
+    sendBlocked
+    // sendRejected — 400 и прочие отказы: повтор ничего не изменит
+    sendRejected
+)
+
+func (v sendVerdict) String() string {
+    switch v {
+    case sendDone:
+        return "done"
+    case sendRetry:
+        return "retry"
+    case sendWait:
+        return "wait"
+    case sendBlocked:
+        return "blocked"
+    default:
+        return "rejected"
+    }
+}
+
+// classifyTelegramError решает, стоит ли повторять отправку после err;
+// для sendWait возвращает паузу, которую просит Telegram
+func classifyTelegramError(err error) (sendVerdict, time.Duration) {
+    if err == nil {
+        return sendDone, 0
+    }
+    if wait, ok := telegram.RetryAfter(err); ok {
+        return sendWait, wait
+    }
+    if telegram.IsForbidden(err) {
+        return sendBlocked, 0
+    }
+    var apiErr *telegram.APIError
+    if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+        return sendRejected, 0
+    }
+    // Ответа от Bot API нет вовсе (таймаут, обрыв соединения) или он упал сам
+    return sendRetry, 0
+}
+
+// send отправляет сообщение, повторяя сетевые сбои и 5xx с отсрочкой и 429 —
+// через retry_after, но не дольше Config.TelegramSendMaxWait. Попыток
+// не больше Config.TelegramSendAttempts. Чат, где бот заблокирован,
+// помечается неактивным. Неудача логируется здесь же, вызывающему коду
+// достаточно проверить ошибку.
+func (b *Bot) send(ctx context.Context, chatID int64, text string, opts ...telegram.SendOption) (int64, error) {
+    log := logging.FromContext(ctx).With("chat_id", chatID)
+    if replyTo := threadedMessage(ctx, chatID); replyTo != 0 {
+        opts = append(opts, telegram.WithReplyTo(replyTo))
+    }
+    delay := sendRetryBase
+    for attempt := 1; ; attempt++ {
+        messageID, err := b.Telegram.SendMessage(chatID, text, opts...)
+        verdict, wait := classifyTelegramError(err)
+        switch verdict {
+        case sendDone:
+            if attempt > 1 {
+                log.Info("сообщение отправлено после повторов", "attempts", attempt)
+            }
+            return messageID, nil
+        case sendBlocked:
+            // Покупатель заблокировал бота: рассылки и напоминания ему больше не шлём
+            log.Info("бот заблокирован в чате", "err", err)
+            if err := b.Chats.MarkInactive(context.WithoutCancel(ctx), chatID); err != nil {
+                log.Error("ошибка пометки чата", "err", err)
+            }
+            return 0, err
+        case sendRetry:
+            wait = delay
+            delay *= 2
+        }
+
+        if verdict == sendRejected || attempt >= b.Config.TelegramSendAttempts || wait > b.Config.TelegramSendMaxWait {
+            log.Error("сообщение не отправлено", "attempts", attempt, "verdict", verdict.String(), "err", err)
+            return 0, err
+        }
+        log.Warn("ошибка отправки в Telegram, повторяем", "attempt", attempt, "verdict", verdict.String(), "retry_in", wait, "err", err)
+
+        timer := time.NewTimer(wait)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            log.Error("сообщение не отправлено: отправка отменена", "attempts", attempt, "err", err)
+            return 0, err
+        case <-timer.C:
+        }
+    }
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/money"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+// tooMany — 429 с паузой wait
+func tooMany(wait time.Duration) error {
+    return &telegram.APIError{Method: "sendMessage", StatusCode: 429, Description: "Too Many Requests", RetryAfter: wait}
+}
+
+// apiStatus — отказ Bot API с кодом status
+func apiStatus(status int) error {
+    return &telegram.APIError{Method: "sendMessage", StatusCode: status, Description: "отказ"}
+}
+
+func TestClassifyTelegramError(t *testing.T) {
+    tests := []struct {
+        name    string
+        err     error
+        verdict sendVerdict
+        wait    time.Duration
+    }{
+        {"успех", nil, sendDone, 0},
+        {"429", tooMany(3 * time.Second), sendWait, 3 * time.Second},
+        {"429 в части длинного сообщения", fmt.Errorf("часть 2 из 3: %w", tooMany(time.Second)), sendWait, time.Second},
+        {"429 без retry_after", apiStatus(429), sendRejected, 0},
+        {"403", apiStatus(403), sendBlocked, 0},
+        {"400", apiStatus(400), sendRejected, 0},
+        {"500", apiStatus(500), sendRetry, 0},
+        {"502", apiStatus(502), sendRetry, 0},
+        {"сеть", errNetwork, sendRetry, 0},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            verdict, wait := classifyTelegramError(tt.err)
+            if verdict != tt.verdict || wait != tt.wait {
+                t.Fatalf("получено %s/%s, ожидалось %s/%s", verdict, wait, tt.verdict, tt.wait)
+            }
+        })
+    }
+}
+
+// failingSends — Telegram, у которого отправки по очереди возвращают errs,
+// а когда ошибки кончились — проходят
+type failingSends struct {
+    *mocks.Telegram
+    mu    sync.Mutex
+    errs  []error
+    calls int
+}
+
+func (f *failingSends) SendMessage(chatID int64, text string, opts ...telegram.SendOption) (int64, error) {
+    f.mu.Lock()
+    f.calls++
+    var err error
+    if len(f.errs) > 0 {
+        err, f.errs = f.errs[0], f.errs[1:]
+    }
+    f.mu.Unlock()
+    if err != nil {
+        return 0, err
+    }
+    return f.Telegram.SendMessage(chatID, text, opts...)
+}
+
+func TestSendRetries(t *testing.T) {
+    tests := []struct {
+        name     string
+        env      map[string]string
+        errs     []error
+        calls    int
+        sent     bool
+        inactive bool
+    }{
+        {name: "5xx повторяется", errs: []error{apiStatus(502)}, calls: 2, sent: true},
+        {name: "сетевой сбой повторяется", errs: []error{errNetwork}, calls: 2, sent: true},
+        {name: "429 ждёт retry_after", errs: []error{tooMany(10 * time.Millisecond)}, calls: 2, sent: true},
+        {name: "429 дольше допустимого", env: map[string]string{"TELEGRAM_SEND_MAX_WAIT": "1s"},
+            errs: []error{tooMany(5 * time.Second)}, calls: 1},
+        {name: "400 не повторяется", errs: []error{apiStatus(400)}, calls: 1},
+        {name: "403 помечает чат", errs: []error{apiStatus(403)}, calls: 1, inactive: true},
+        {name: "попытки кончились", env: map[string]string{"TELEGRAM_SEND_ATTEMPTS": "2"},
+            errs: []error{tooMany(time.Millisecond), tooMany(time.Millisecond), tooMany(time.Millisecond)}, calls: 2},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tb := newTestBot(t, tt.env)
+            tg := &failingSends{Telegram: tb.tg, errs: tt.errs}
+            tb.Telegram = tg
+
+            _, err := tb.send(context.Background(), 42, "ответ")
+            if (err == nil) != tt.sent {
+                t.Fatalf("ошибка %v, ожидалась доставка: %v", err, tt.sent)
+            }
+            if tg.calls != tt.calls {
+                t.Fatalf("попыток %d, ожидалось %d", tg.calls, tt.calls)
+            }
+            if got := len(tb.sentTo(42)); got != map[bool]int{true: 1}[tt.sent] {
+                t.Fatalf("в чат ушло %d сообщений", got)
+            }
+            if inactive := tb.chats.Inactive(42); inactive != tt.inactive {
+                t.Fatalf("чат помечен неактивным: %v, ожидалось %v", inactive, tt.inactive)
+            }
+        })
+    }
+}
+
+// Остановка сервиса прерывает ожидание retry_after у reply
+func TestReplyStopsWaitingOnCancel(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"TELEGRAM_SEND_MAX_WAIT": "10m"})
+    tb.Telegram = &failingSends{Telegram: tb.tg, errs: []error{tooMany(time.Minute)}}
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    done := make(chan struct{})
+    go func() {
+        tb.reply(ctx, 42, "ответ")
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("reply не прервал ожидание 429 после остановки")
+    }
+    if len(tb.sentTo(42)) != 0 {
+        t.Fatal("после отмены сообщение всё равно отправлено")
+    }
+}
+
+// Приветствие, меню и карточка товара уходят через send: временный сбой
+// Telegram повторяется, а не теряет сообщение
+func TestCommandRepliesRetried(t *testing.T) {
+    tests := []struct {
+        name string
+        run  func(t *testing.T, tb *testBot)
+    }{
+        {"/start", func(t *testing.T, tb *testBot) { tb.process(t, text(1, 42, "/start")) }},
+        {"/menu", func(t *testing.T, tb *testBot) { tb.process(t, text(1, 42, "/menu")) }},
+        {"/menu off", func(t *testing.T, tb *testBot) { tb.process(t, text(1, 42, "/menu off")) }},
+        {"карточка товара", func(t *testing.T, tb *testBot) {
+            tb.showProduct(context.Background(), 42, storage.Product{ID: 1, Name: "Сенча", Price: money.New(149900, "RUB"), InStock: true})
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tb := newTestBot(t, map[string]string{"MENU_BUTTONS": "Каталог=catalog"})
+            tg := &failingSends{Telegram: tb.tg, errs: []error{apiStatus(502)}}
+            tb.Telegram = tg
+
+            tt.run(t, tb)
+            sent := len(tb.sentTo(42))
+            if sent == 0 {
+                t.Fatal("после сбоя сообщение не отправлено")
+            }
+            if tg.calls != sent+1 {
+                t.Fatalf("попыток %d на %d сообщений, ожидался один повтор", tg.calls, sent)
+            }
+        })
+    }
+}
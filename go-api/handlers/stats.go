@@ -0,0 +1,76 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    "ai_seller/apperr"
+)
+
+// statsCacheTTL — сколько переиспользовать сводку /stats: повторные нажатия
+// не должны каждый раз гонять агрегаты по базе
+const statsCacheTTL = time.Minute
+
+// statsCache — последняя сводка /stats. Расход чата в неё не входит —
+// он дешёвый и у каждого админа свой.
+type statsCache struct {
+    mu      sync.Mutex
+    text    string
+    expires time.Time
+}
+
+// cmdStats — команда /stats: пользователи, сообщения, активные чаты,
+// расход токенов OpenAI за месяц и доля ошибок обработки
+func (b *Bot) cmdStats(ctx context.Context, msg *TelegramMessage, args string) error {
+    overview, err := b.statsOverview(ctx)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось получить статистику.")
+    }
+    chat, err := b.Usage.ChatMonthTotal(ctx, msg.Chat.ID)
+    if err != nil {
+        return apperr.WithMessage(err, "Не удалось получить статистику.")
+    }
+    b.reply(ctx, msg.Chat.ID, overview+fmt.Sprintf("\n  этот чат: %d", chat.Total))
+    return nil
+}
+
+// statsOverview — общая часть /stats из кэша или свежая
+func (b *Bot) statsOverview(ctx context.Context) (string, error) {
+    b.stats.mu.Lock()
+    defer b.stats.mu.Unlock()
+    if time.Now().Before(b.stats.expires) {
+        return b.stats.text, nil
+    }
+
+    o, err := b.Stats.Overview(ctx)
+    if err != nil {
+        return "", err
+    }
+    total, err := b.Usage.MonthTotal(ctx)
+    if err != nil {
+        return "", err
+    }
+
+    var sb strings.Builder
+    sb.WriteString("📊 Статистика\n")
+    fmt.Fprintf(&sb, "Пользователей: %d\n", o.Users)
+    fmt.Fprintf(&sb, "Сообщений сегодня: %d\n", o.MessagesToday)
+    fmt.Fprintf(&sb, "Активных чатов за 24 ч: %d\n", o.ActiveChats)
+    if o.UserMessages24h > 0 {
+        fmt.Fprintf(&sb, "Ошибок обработки за 24 ч: %d (%.1f%%)\n", o.FailedUpdates24h,
+            float64(o.FailedUpdates24h)*100/float64(o.UserMessages24h))
+    } else {
+        fmt.Fprintf(&sb, "Ошибок обработки за 24 ч: %d\n", o.FailedUpdates24h)
+    }
+    fmt.Fprintf(&sb, "\nТокены OpenAI за месяц:\n  всего: %d (запрос %d, ответ %d)", total.Total, total.Prompt, total.Completion)
+    if budget := b.Config.MonthlyTokenBudget; budget > 0 {
+        fmt.Fprintf(&sb, "\n  бюджет: %d, осталось: %d", budget, max(budget-total.Total, 0))
+    }
+
+    b.stats.text = sb.String()
+    b.stats.expires = time.Now().Add(statsCacheTTL)
+    return b.stats.text, nil
+}
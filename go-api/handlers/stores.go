@@ -0,0 +1,162 @@
+package handlers
+
+import (
+    "context"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// Фейки этих хранилищ в памяти — в пакете memstore; сценарии, общие для
+// фейков и настоящих реализаций, — в пакете storetest.
+
+// CatalogStore — каталог товаров и категорий; реализуется *storage.CatalogStore
+type CatalogStore interface {
+    ListProducts(ctx context.Context, limit, offset int) ([]storage.Product, error)
+    CountProducts(ctx context.Context) (int, error)
+    GetProduct(ctx context.Context, id int64) (storage.Product, error)
+    SearchProducts(ctx context.Context, query string) ([]storage.Product, error)
+    FuzzySearchProducts(ctx context.Context, query string, threshold float64) ([]storage.Product, error)
+    SemanticSearch(ctx context.Context, query string, k int) ([]storage.Product, error)
+    SetPrice(ctx context.Context, id int64, minor int64, changedBy int64) (storage.Product, error)
+    SetStock(ctx context.Context, id int64, qty int, changedBy int64) (storage.Product, error)
+    SetCategory(ctx context.Context, id, categoryID int64, changedBy int64) (storage.Product, error)
+    ImportProducts(ctx context.Context, products []storage.Product) (storage.ImportResult, error)
+    ListCategories(ctx context.Context) ([]storage.Category, error)
+    ListProductsByCategory(ctx context.Context, categoryID int64, limit, offset int) ([]storage.Product, error)
+    CountProductsByCategory(ctx context.Context, categoryID int64) (int, error)
+}
+
+// MessageStore — история переписки; реализуется *storage.MessageStore
+type MessageStore interface {
+    SaveMessage(ctx context.Context, chatID int64, role, text string) error
+    SaveIncompleteMessage(ctx context.Context, chatID int64, role, text string) error
+    HasMessages(ctx context.Context, chatID int64) (bool, error)
+    CountMessages(ctx context.Context, chatID int64) (int, error)
+    ArchiveHistory(ctx context.Context, chatID int64) error
+    GetHistory(ctx context.Context, chatID int64, limit int) ([]storage.Message, error)
+}
+
+// ChatStore — чаты для рассылок и группы с ботом; реализуется
+// *storage.ChatStore
+type ChatStore interface {
+    ActiveChatIDs(ctx context.Context) ([]int64, error)
+    MarkInactive(ctx context.Context, chatID int64) error
+    MarkActive(ctx context.Context, chatID int64) error
+    JoinGroup(ctx context.Context, chatID int64, title string) error
+    LeaveGroup(ctx context.Context, chatID int64) error
+    SetMemberVerification(ctx context.Context, chatID int64, on bool) error
+    MemberVerification(ctx context.Context, chatID int64) (bool, error)
+}
+
+// FailedUpdateStore — журнал необработанных апдейтов; реализуется
+// *storage.FailedUpdateStore
+type FailedUpdateStore interface {
+    Record(ctx context.Context, updateID, chatID int64, payload []byte, cause error) error
+    ListFailed(ctx context.Context, limit int) ([]storage.FailedUpdate, error)
+    DeleteFailed(ctx context.Context, id int64) error
+}
+
+// OutboxStore — очередь исходящих ответов; реализуется *storage.OutboxStore
+type OutboxStore interface {
+    Enqueue(ctx context.Context, chatID int64, text string, withFeedback bool, replyTo int64) (int64, error)
+    Claim(ctx context.Context, limit int, stale time.Duration) ([]storage.OutboxMessage, error)
+    MarkPartSent(ctx context.Context, id int64, parts int) error
+    MarkSent(ctx context.Context, id int64) error
+    Retry(ctx context.Context, id int64, after time.Duration, cause error) error
+    Fail(ctx context.Context, id int64, cause error) error
+    PruneOutbox(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// MemberChallengeStore — незавершённые проверки новых участников групп;
+// реализуется *storage.MemberChallengeStore
+type MemberChallengeStore interface {
+    Create(ctx context.Context, c storage.MemberChallenge) error
+    Resolve(ctx context.Context, chatID, userID int64) (storage.MemberChallenge, error)
+    ClaimExpired(ctx context.Context, now time.Time, limit int) ([]storage.MemberChallenge, error)
+}
+
+// UserStore — профили покупателей; реализуется *storage.UserStore
+type UserStore interface {
+    Upsert(ctx context.Context, chatID int64, username, lang, name string) error
+    GetUser(ctx context.Context, chatID int64) (storage.User, error)
+    SetBrief(ctx context.Context, chatID int64, brief bool) error
+    SetCurrency(ctx context.Context, chatID int64, currency string) error
+    SetLang(ctx context.Context, chatID int64, lang string) error
+    SetPromptVariant(ctx context.Context, chatID int64, variant string) error
+    SetMenuHidden(ctx context.Context, chatID int64, hidden bool) error
+}
+
+// CartStore — корзины; реализуется *cache.CartStore
+type CartStore interface {
+    AddItem(ctx context.Context, chatID, productID int64, qty int) error
+    RemoveItem(ctx context.Context, chatID, productID int64) error
+    GetCart(ctx context.Context, chatID int64) (cache.Cart, error)
+    ClearCart(ctx context.Context, chatID int64) error
+    IdleCarts(ctx context.Context, idle time.Duration) ([]int64, error)
+    ClaimReminder(ctx context.Context, chatID int64) (bool, error)
+}
+
+// ReengagementStore — замолчавшие покупатели и отметки подсказок им;
+// реализуется *storage.ReengagementStore
+type ReengagementStore interface {
+    Candidates(ctx context.Context, now time.Time, idle, lookback, cooldown time.Duration, limit int, shoppers []int64) ([]int64, error)
+    ClaimNudge(ctx context.Context, chatID int64, now time.Time, cooldown time.Duration) (bool, error)
+}
+
+// ShoppingActivity — отметки о том, что покупатель смотрел товары или
+// менял корзину; реализуется *cache.ShoppingActivity
+type ShoppingActivity interface {
+    Touch(ctx context.Context, chatID int64, now time.Time) error
+    Since(ctx context.Context, from time.Time) ([]int64, error)
+    Forget(ctx context.Context, chatID int64) error
+}
+
+// OrderStore — заказы; реализуется *storage.OrderStore
+type OrderStore interface {
+    CreateOrder(ctx context.Context, chatID int64, items []storage.OrderItem, total money.Money, idempotencyKey string, window time.Duration) (int64, error)
+    GetOrder(ctx context.Context, orderID, chatID int64) (storage.Order, error)
+    ChatOrders(ctx context.Context, chatID int64) ([]storage.Order, error)
+    OrderState(ctx context.Context, orderID int64) (storage.OrderState, error)
+    SetStatus(ctx context.Context, orderID int64, to storage.OrderStatus, notice string) error
+    ConfirmOrder(ctx context.Context, orderID int64) error
+    LatestDraft(ctx context.Context, chatID int64, since time.Time) (storage.Order, error)
+    DiscardDraft(ctx context.Context, orderID int64) error
+    OrdersBetween(ctx context.Context, from, to time.Time) ([]storage.OrderSummary, error)
+}
+
+// FeedbackStore — оценки ответов; реализуется *storage.FeedbackStore
+type FeedbackStore interface {
+    Record(ctx context.Context, chatID, messageID int64, rating int) error
+    Stats(ctx context.Context) (storage.FeedbackStats, error)
+}
+
+// PrivacyStore — выгрузка и удаление данных покупателя; реализуется
+// *storage.PrivacyStore
+type PrivacyStore interface {
+    Export(ctx context.Context, chatID int64) (storage.DataExport, error)
+    Erase(ctx context.Context, chatID int64) (int64, error)
+}
+
+// AttributionStore — источники покупателей по ссылкам с параметром /start;
+// реализуется *storage.AttributionStore
+type AttributionStore interface {
+    Record(ctx context.Context, chatID int64, a storage.Attribution) (bool, error)
+}
+
+var (
+    _ CatalogStore      = (*storage.CatalogStore)(nil)
+    _ MessageStore      = (*storage.MessageStore)(nil)
+    _ UserStore         = (*storage.UserStore)(nil)
+    _ CartStore         = (*cache.CartStore)(nil)
+    _ OrderStore        = (*storage.OrderStore)(nil)
+    _ FeedbackStore     = (*storage.FeedbackStore)(nil)
+    _ PrivacyStore      = (*storage.PrivacyStore)(nil)
+    _ OutboxStore       = (*storage.OutboxStore)(nil)
+    _ FailedUpdateStore = (*storage.FailedUpdateStore)(nil)
+
+    _ AttributionStore  = (*storage.AttributionStore)(nil)
+    _ ReengagementStore = (*storage.ReengagementStore)(nil)
+)
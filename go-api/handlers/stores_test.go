@@ -0,0 +1,23 @@
+package handlers
+
+import (
+    "ai_seller/handlers/mocks"
+    "ai_seller/memstore"
+)
+
+var (
+    _ CatalogStore         = (*memstore.Catalog)(nil)
+    _ MessageStore         = (*memstore.Messages)(nil)
+    _ UserStore            = (*memstore.Users)(nil)
+    _ CartStore            = (*memstore.Carts)(nil)
+    _ OrderStore           = (*memstore.Orders)(nil)
+    _ FeedbackStore        = (*memstore.Feedback)(nil)
+    _ ChatStore            = (*memstore.Chats)(nil)
+    _ MemberChallengeStore = (*memstore.MemberChallenges)(nil)
+    _ AttributionStore     = (*memstore.Attributions)(nil)
+    _ ReengagementStore    = (*memstore.Reengagement)(nil)
+    _ PrivacyStore         = (*memstore.Privacy)(nil)
+
+    _ AIClient    = (*mocks.OpenAI)(nil)
+    _ TelegramAPI = (*mocks.Telegram)(nil)
+)
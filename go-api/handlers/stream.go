@@ -0,0 +1,144 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/openai"
+    "ai_seller/reqctx"
+    "ai_seller/telegram"
+)
+
+const (
+    // streamPlaceholder — сообщение, которое правится по мере генерации ответа
+    streamPlaceholder = "печатает..."
+    // streamEditInterval — не чаще одной правки в секунду, иначе Telegram вернёт 429
+    streamEditInterval = time.Second
+)
+
+// streamReply отправляет заглушку и правит её по мере поступления фрагментов ответа.
+// При обрыве потока пользователь получает то, что успело накопиться.
+func (b *Bot) streamReply(ctx context.Context, chatID int64, messages []openai.Message) {
+    chunks, err := b.OpenAI.ChatCompletionStream(ctx, messages)
+    if err != nil {
+        b.aiUnavailable(ctx, chatID, err)
+        return
+    }
+
+    // Ответ правится по частям, незакрытая разметка в середине потока сломала бы правку
+    opts := []telegram.SendOption{telegram.WithParseMode("")}
+    if replyTo := threadedMessage(ctx, chatID); replyTo != 0 {
+        opts = append(opts, telegram.WithReplyTo(replyTo))
+    }
+    messageID, err := b.Telegram.SendMessage(chatID, i18n.T(reqctx.LangFromContext(ctx), streamPlaceholder), opts...)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка отправки заглушки", "chat_id", chatID, "err", err)
+    }
+
+    var (
+        sb          strings.Builder
+        interrupted bool
+    )
+    shown := ""
+    lastEdit := time.Now()
+    edit := func(text string) {
+        // Промежуточный текст тоже проходит этапы REPLY_STAGES: он виден
+        // покупателю до конца потока
+        text, err := b.prepareStreamed(ctx, chatID, text)
+        if err != nil {
+            logging.FromContext(ctx).Warn("правка ответа остановлена конвейером", "chat_id", chatID, "err", err)
+            return
+        }
+        if messageID == 0 || text == "" || text == shown {
+            return
+        }
+        if err := b.Telegram.EditMessageText(chatID, messageID, text); err != nil {
+            logging.FromContext(ctx).Warn("ошибка правки сообщения", "chat_id", chatID, "err", err)
+            return
+        }
+        shown = text
+        lastEdit = time.Now()
+    }
+
+    for chunk := range chunks {
+        if chunk.Err != nil {
+            logging.FromContext(ctx).Warn("поток OpenAI оборвался", "chat_id", chatID, "err", chunk.Err)
+            interrupted = true
+            break
+        }
+        sb.WriteString(chunk.Delta)
+        if time.Since(lastEdit) >= streamEditInterval {
+            // Телефон или почта могут прийти разными фрагментами, и фильтр не
+            // узнал бы их начало. Поэтому до конца потока видно только текст по
+            // последнее законченное предложение: внутри контактов конца не бывает
+            edit(finishedSentences(sb.String()))
+        }
+    }
+
+    // При отмене ctx поток закрывается без ошибки, но ответ так же не дописан
+    interrupted = interrupted || ctx.Err() != nil
+    answer := sb.String()
+    received := answer != ""
+    if !received {
+        answer = i18n.T(reqctx.LangFromContext(ctx), b.Config.FallbackMessage)
+    }
+
+    switch {
+    case messageID == 0 && received:
+        // Заглушки нет — ответ уходит обычным путём, через конвейер
+        reply, err := b.sendReply(ctx, chatID, answer)
+        answer, received = reply.Text, err == nil
+    case messageID == 0:
+        b.reply(ctx, chatID, answer)
+    case received:
+        // Ответ уже на экране: из конвейера нужны этапы без паузы и отправки
+        final, err := b.prepareStreamed(ctx, chatID, answer)
+        if err != nil {
+            logging.FromContext(ctx).Error("ответ не отправлен", "chat_id", chatID, "err", err)
+            received = false
+            break
+        }
+        answer = final
+        // Последняя правка добавляет кнопки оценки, даже если текст уже показан
+        err = b.Telegram.EditMessageText(chatID, messageID, answer, telegram.WithReplyMarkup(feedbackKeyboard()))
+        if err != nil {
+            logging.FromContext(ctx).Warn("ошибка правки сообщения", "chat_id", chatID, "err", err)
+        }
+    default:
+        edit(answer)
+    }
+    // Ответ сохраняется один раз, уже отправленным, в том виде, в каком его
+    // видит покупатель
+    switch {
+    case received && interrupted:
+        b.rememberIncomplete(ctx, chatID, answer)
+    case received:
+        b.remember(ctx, chatID, "assistant", answer)
+    }
+}
+
+// prepareStreamed проводит текст потокового ответа через Bot.streamed
+func (b *Bot) prepareStreamed(ctx context.Context, chatID int64, text string) (string, error) {
+    r := &Reply{ChatID: chatID, Text: text}
+    if err := b.streamed.Run(ctx, r); err != nil {
+        return "", err
+    }
+    return r.Text, nil
+}
+
+// finishedSentences — text до конца последнего законченного предложения:
+// знак . ! ? … и следом пробел или перевод строки. Без такого конца — пусто
+func finishedSentences(text string) string {
+    for i := len(text) - 1; i > 0; i-- {
+        if text[i] != ' ' && text[i] != '\n' {
+            continue
+        }
+        if strings.ContainsAny(text[i-1:i], ".!?") || strings.HasSuffix(text[:i], "…") {
+            return text[:i]
+        }
+    }
+    return ""
+}
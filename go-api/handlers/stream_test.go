@@ -0,0 +1,158 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/filter"
+    "ai_seller/handlers/mocks"
+    "ai_seller/openai"
+    "ai_seller/telegram"
+)
+
+// pausedStream — модель, которая отдаёт ответ фрагментами с паузой дольше
+// интервала правок, так что после каждого фрагмента сообщение правится
+type pausedStream struct {
+    *mocks.OpenAI
+    deltas []string
+}
+
+func (p *pausedStream) ChatCompletionStream(ctx context.Context, messages []openai.Message) (<-chan openai.StreamChunk, error) {
+    chunks := make(chan openai.StreamChunk)
+    go func() {
+        defer close(chunks)
+        for i, delta := range p.deltas {
+            if i > 0 {
+                time.Sleep(streamEditInterval + 100*time.Millisecond)
+            }
+            chunks <- openai.StreamChunk{Delta: delta}
+        }
+    }()
+    return chunks, nil
+}
+
+// editLog — Telegram, запоминающий текст каждой правки
+type editLog struct {
+    *mocks.Telegram
+    mu    sync.Mutex
+    edits []string
+}
+
+func (e *editLog) EditMessageText(chatID, messageID int64, text string, opts ...telegram.SendOption) error {
+    e.mu.Lock()
+    e.edits = append(e.edits, text)
+    e.mu.Unlock()
+    return e.Telegram.EditMessageText(chatID, messageID, text, opts...)
+}
+
+// Телефон, разрезанный между фрагментами, не появляется на экране ни на
+// одной промежуточной правке: видно только законченные предложения
+func TestStreamHidesContactSplitAcrossChunks(t *testing.T) {
+    redact, err := filter.New(filter.Rules{Redact: []string{"phone"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    ai := &pausedStream{
+        OpenAI: &mocks.OpenAI{},
+        deltas: []string{"Звоните. Номер +7 999", " 12", "3 45 67, ждём."},
+    }
+    tb := newTestBot(t, map[string]string{"STREAMING_ENABLED": "true"}, func(d *Deps) {
+        d.OpenAI = ai
+        d.Filter = redact
+    })
+    tg := &editLog{Telegram: tb.tg}
+    tb.Telegram = tg
+
+    tb.process(t, text(1, 10, "как с вами связаться?"))
+
+    if len(tg.edits) < 2 {
+        t.Fatalf("правки = %q, нужны промежуточная и последняя", tg.edits)
+    }
+    for _, edit := range tg.edits {
+        if strings.Contains(edit, "999") {
+            t.Fatalf("правка показала часть телефона: %q (все правки %q)", edit, tg.edits)
+        }
+    }
+    if got := tg.edits[0]; got != "Звоните." {
+        t.Fatalf("промежуточная правка = %q, нужно законченное предложение", got)
+    }
+    if got, want := tg.edits[len(tg.edits)-1], "Звоните. Номер [скрыто], ждём."; got != want {
+        t.Fatalf("последняя правка = %q, нужно %q", got, want)
+    }
+}
+
+func TestFinishedSentences(t *testing.T) {
+    for _, tc := range []struct{ in, want string }{
+        {"", ""},
+        {"Без конца", ""},
+        {"Одно. Второе", "Одно."},
+        {"Да! Нет? Может", "Да! Нет?"},
+        {"Строка.\nещё", "Строка."},
+        {"Подумаю… и", "Подумаю…"},
+        {"Почта a.b@mail.ru и", ""},
+        {"Конец.", ""},
+    } {
+        if got := finishedSentences(tc.in); got != tc.want {
+            t.Errorf("finishedSentences(%q) = %q, нужно %q", tc.in, got, tc.want)
+        }
+    }
+}
+
+// brokenStream — поток, который обрывается после фрагментов deltas
+type brokenStream struct {
+    *mocks.OpenAI
+    deltas []string
+}
+
+func (s *brokenStream) ChatCompletionStream(ctx context.Context, messages []openai.Message) (<-chan openai.StreamChunk, error) {
+    s.OpenAI.Requests = append(s.OpenAI.Requests, messages)
+    chunks := make(chan openai.StreamChunk, len(s.deltas)+1)
+    for _, delta := range s.deltas {
+        chunks <- openai.StreamChunk{Delta: delta}
+    }
+    chunks <- openai.StreamChunk{Err: errors.New("unexpected EOF")}
+    close(chunks)
+    return chunks, nil
+}
+
+// Завершённый поток — одна обычная запись ответа в истории
+func TestStreamCompleteSavesOneRecord(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"STREAMING_ENABLED": "true"})
+    tb.process(t, text(1, 10, "есть чай?"))
+
+    history := tb.messages.History(10)
+    if len(history) != 2 || history[1].Role != "assistant" || history[1].Content != "ответ модели" || history[1].Incomplete {
+        t.Fatalf("история = %+v, нужна одна завершённая запись ответа", history)
+    }
+}
+
+// Оборванный поток — одна запись с пометкой, и следующий запрос к модели
+// знает, что покупатель видел только начало ответа
+func TestStreamInterruptedMarkedInNextContext(t *testing.T) {
+    ai := &brokenStream{OpenAI: &mocks.OpenAI{Reply: "ответ модели"}, deltas: []string{"Зелёный чай есть, ", "цена"}}
+    tb := newTestBot(t, map[string]string{"STREAMING_ENABLED": "true"}, func(d *Deps) { d.OpenAI = ai })
+    tb.process(t, text(1, 10, "есть чай?"))
+
+    history := tb.messages.History(10)
+    if len(history) != 2 || !history[1].Incomplete || history[1].Content != "Зелёный чай есть, цена" {
+        t.Fatalf("история = %+v, нужна одна незавершённая запись ответа", history)
+    }
+
+    tb.process(t, text(2, 10, "и сколько?"))
+    if len(ai.Requests) != 2 {
+        t.Fatalf("запросов к модели %d, нужно 2", len(ai.Requests))
+    }
+    var marked bool
+    for _, m := range ai.Requests[1] {
+        if m.Role == openai.RoleAssistant && strings.HasPrefix(m.Content, "Зелёный чай есть, цена") {
+            marked = strings.Contains(m.Content, "оборвался")
+        }
+    }
+    if !marked {
+        t.Fatalf("оборванный ответ в контексте не помечен: %+v", ai.Requests[1])
+    }
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+    "context"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/openai"
+)
+
+// summarizeTimeout — предел на обновление сводки в фоне
+const summarizeTimeout = time.Minute
+
+// summarizeLater обновляет сводку диалога в фоне, не задерживая ответ.
+// now — свернуть всё, что вышло за окно истории (после оформления заказа);
+// иначе сводка обновляется, только когда накопилось SUMMARY_EVERY реплик,
+// а до того не запускается даже проверка (см. Summarizer.Due).
+func (b *Bot) summarizeLater(ctx context.Context, chatID int64, now bool) {
+    if b.Summarizer == nil || (!now && !b.Summarizer.Due(chatID)) {
+        return
+    }
+    // Предел краткого режима касается ответов покупателю, не сводки
+    ctx = openai.WithMaxTokens(context.WithoutCancel(ctx), 0)
+    go func() {
+        ctx, cancel := context.WithTimeout(ctx, summarizeTimeout)
+        defer cancel()
+
+        summarize := b.Summarizer.SummarizeIfDue
+        if now {
+            summarize = b.Summarizer.Summarize
+        }
+        if err := summarize(ctx, chatID); err != nil {
+            logging.FromContext(ctx).Warn("ошибка обновления сводки диалога", "chat_id", chatID, "err", err)
+        }
+    }()
+}
@@ -1,41 +1,843 @@
 package handlers
 
 import (
+    "context"
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
-    "log"
+    "mime"
     "net/http"
+    "strings"
+    "sync/atomic"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/config"
+    "ai_seller/dialog"
+    "ai_seller/faq"
+    "ai_seller/filter"
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/metrics"
+    "ai_seller/money"
+    "ai_seller/openai"
+    "ai_seller/payments"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
 )
 
+// secretTokenHeader — заголовок с секретом, заданным при setWebhook
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// rateLimitedReply — ответ, когда чат превысил лимит сообщений
+const rateLimitedReply = "Слишком много сообщений, подождите"
+
+// accountLimitReply — ответ, когда запросы к OpenAI упёрлись в минутный лимит аккаунта
+const accountLimitReply = "Сейчас очень много вопросов — отвечу чуть позже. Повторите, пожалуйста, через минуту."
+
+// overBudgetReply — ответ, когда исчерпан месячный бюджет токенов
+const overBudgetReply = "Сервис временно недоступен, попробуйте позже."
+
+// truncatedMessageReply — предупреждение, что сообщение не влезло в контекст модели
+const truncatedMessageReply = "Сообщение слишком длинное — я прочитал только его начало."
+
 // TelegramUpdate — минимальная структура запроса от Telegram
 type TelegramUpdate struct {
-    Message struct {
-        Text string `json:"text"`
-        Chat struct {
-            ID int64 `json:"id"`
-        } `json:"chat"`
-    } `json:"message"`
+    UpdateID      int64                  `json:"update_id"`
+    Message       *TelegramMessage       `json:"message"`
+    CallbackQuery *TelegramCallbackQuery `json:"callback_query"`
+    EditedMessage *TelegramMessage       `json:"edited_message"`
+    // MyChatMember — изменился статус самого бота в чате: заблокировали,
+    // разблокировали, добавили в группу или удалили из неё
+    MyChatMember *TelegramChatMemberUpdated `json:"my_chat_member"`
+}
+
+// TelegramMessage — входящее сообщение
+type TelegramMessage struct {
+    MessageID int64               `json:"message_id"`
+    From      *TelegramUser       `json:"from"`
+    Text      string              `json:"text"`
+    Caption   string              `json:"caption"`
+    Chat      TelegramChat        `json:"chat"`
+    Photo     []TelegramPhotoSize `json:"photo"`
+    Voice     *TelegramVoice      `json:"voice"`
+    // Document — присланный файл; бот читает его только по команде (/importcatalog)
+    Document *TelegramDocument `json:"document"`
+    // Entities и CaptionEntities — разметка Text и Caption: команды, ссылки, почта
+    Entities        []TelegramEntity `json:"entities"`
+    CaptionEntities []TelegramEntity `json:"caption_entities"`
+    // ReplyToMessage — сообщение, на которое отвечают; в группах так обращаются к боту
+    ReplyToMessage *TelegramMessage `json:"reply_to_message"`
+    // NewChatMembers — служебное сообщение группы о новых участниках
+    NewChatMembers []TelegramUser `json:"new_chat_members"`
+
+    // Нетекстовое содержимое: разбирать его не нужно, достаточно знать, что оно есть
+    Sticker  json.RawMessage `json:"sticker"`
+    Location json.RawMessage `json:"location"`
+}
+
+// TelegramChat — чат, из которого пришло сообщение
+type TelegramChat struct {
+    ID   int64  `json:"id"`
+    Type string `json:"type"`
+    // Title — название группы; у личных чатов пустое
+    Title string `json:"title"`
+}
+
+// TelegramUser — отправитель сообщения
+type TelegramUser struct {
+    ID           int64  `json:"id"`
+    IsBot        bool   `json:"is_bot"`
+    FirstName    string `json:"first_name"`
+    LastName     string `json:"last_name"`
+    Username     string `json:"username"`
+    LanguageCode string `json:"language_code"`
+}
+
+// lang — язык фиксированных ответов для отправителя сообщения
+func (m *TelegramMessage) lang() string {
+    if m.From == nil {
+        return i18n.DefaultLang
+    }
+    return i18n.Resolve(m.From.LanguageCode)
+}
+
+// FullName — имя и фамилия через пробел; фамилии в Telegram может не быть
+func (u *TelegramUser) FullName() string {
+    return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// isGroup — групповой чат или канал: там на служебные сообщения не отвечаем
+func (c TelegramChat) isGroup() bool {
+    return c.Type == "group" || c.Type == "supergroup" || c.Type == "channel"
+}
+
+// Sender — отправка текстовых сообщений
+type Sender interface {
+    SendMessage(chatID int64, text string, opts ...telegram.SendOption) (int64, error)
+}
+
+// Completer — простой запрос к модели без инструментов
+type Completer interface {
+    ChatCompletion(ctx context.Context, messages []openai.Message) (string, error)
+}
+
+// TelegramAPI — методы Bot API, которыми пользуется бот; реализуется *telegram.Client,
+// в тестах — mocks.Telegram
+type TelegramAPI interface {
+    Sender
+    SendPhoto(chatID int64, photoURL, caption string, opts ...telegram.SendOption) error
+    EditMessageText(chatID, messageID int64, text string, opts ...telegram.SendOption) error
+    DeleteMessage(chatID, messageID int64) error
+    SendChatAction(chatID int64, action string) error
+    AnswerCallbackQuery(callbackID string) error
+    DownloadFile(ctx context.Context, fileID string) ([]byte, string, error)
+    SendDocument(ctx context.Context, chatID int64, filename string, data []byte, caption string) error
+    RestrictChatMember(chatID, userID int64, allowed bool) error
+    BanChatMember(chatID, userID int64, until time.Time) error
+    IsChatAdmin(chatID, userID int64) (bool, error)
+    GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]json.RawMessage, error)
+    DeleteWebhook() error
+}
+
+// AIClient — запросы к модели; реализуется llm.New (OpenAI, Ollama), в тестах — mocks.OpenAI
+type AIClient interface {
+    Completer
+    ChatCompletionStream(ctx context.Context, messages []openai.Message) (<-chan openai.StreamChunk, error)
+    VisionCompletion(ctx context.Context, messages []openai.Message) (string, error)
+    Transcribe(ctx context.Context, audio []byte, filename string) (string, error)
+}
+
+// ToolCaller — модель с вызовом инструментов; есть не у всех поставщиков,
+// без него бот отвечает обычным запросом
+type ToolCaller interface {
+    ChatWithTools(ctx context.Context, messages []openai.Message, tools *openai.ToolRegistry) (string, error)
+}
+
+// Moderator — модерация входящих сообщений; есть не у всех поставщиков,
+// без неё сообщения проверяются только словарём
+type Moderator interface {
+    Moderate(ctx context.Context, text string) (openai.ModerationResult, error)
+}
+
+var (
+    _ TelegramAPI = (*telegram.Client)(nil)
+    _ AIClient    = (*openai.Client)(nil)
+    _ ToolCaller  = (*openai.Client)(nil)
+    _ Moderator   = (*openai.Client)(nil)
+)
+
+// Deps — внешние зависимости бота
+type Deps struct {
+    Config   *config.Config
+    Telegram TelegramAPI
+    OpenAI   AIClient
+    Messages MessageStore
+    Sessions *cache.SessionCache
+    Limiter  *cache.RateLimiter
+    Catalog  CatalogStore
+    Carts    CartStore
+    Usage    *cache.UsageCounter
+    Orders   OrderStore
+    Chats    ChatStore
+    Updates  *cache.UpdateDeduper
+    // Locks — поочерёдная обработка апдейтов одного чата; nil — без блокировок
+    Locks    *cache.ChatLocker
+    Dialog   *dialog.ContextBuilder
+    Users    UserStore
+    Feedback FeedbackStore
+    Stats    *storage.StatsStore
+    Privacy  PrivacyStore
+    // Payments — проверка уведомлений об оплате; nil, если оплата не подключена
+    Payments payments.Provider
+    // Digests — отметки об отправленных сводках заказов
+    Digests *cache.DigestMarks
+    // OffHoursNotes — кому уже сказано о нерабочем времени (OFF_HOURS_MODE=note);
+    // nil — фраза идёт перед каждым ответом
+    OffHoursNotes *cache.OffHoursNotes
+    // DraftOffers — кому уже предложен брошенный черновик заказа; nil —
+    // предложение повторяется на каждое сообщение, пока черновик не вернут
+    DraftOffers *cache.DraftOffers
+    // Handoffs — чаты, переданные оператору; nil — передача оператору выключена
+    Handoffs *cache.Handoffs
+    // Flags — переключения флагов функций на лету; nil — только окружение
+    Flags *cache.FlagOverrides
+    // Summarizer — сводки старых реплик; nil — старые реплики просто забываются
+    Summarizer *dialog.Summarizer
+    // Outbox — очередь исходящих ответов модели; nil — отправлять сразу
+    Outbox OutboxStore
+    // FailedUpdates — журнал необработанных апдейтов; nil — не вести
+    FailedUpdates FailedUpdateStore
+    // Filter — фильтр ответов модели; nil, если не настроен. Заменяется через SetFilter.
+    Filter *filter.Filter
+    // ModerationWords — словарь запрещённых слов для модерации входящих,
+    // когда модерация OpenAI недоступна; nil — без словаря
+    ModerationWords *filter.Filter
+    // Responses — кэш ответов модели; nil, если кэш выключен
+    Responses *cache.ResponseCache
+    // FAQ — готовые ответы на типовые вопросы; nil, если FAQ не настроен.
+    // После старта заменяется через SetFAQ.
+    FAQ *faq.Matcher
+    // Attributions — источники покупателей по ссылкам с параметром /start;
+    // nil — кампании и приглашения не учитываются
+    Attributions AttributionStore
+    // Probes — проверки зависимостей для /ping; пусто — /ping ничего не проверяет
+    Probes []Probe
+    // Challenges — проверки новых участников групп; nil — проверка выключена
+    // во всех группах, /verifymembers не работает
+    Challenges MemberChallengeStore
+    // Reengagement — подсказки замолчавшим покупателям; nil — не писать им
+    Reengagement ReengagementStore
+    // Shopping — кто недавно смотрел товары или менял корзину: подсказки
+    // пишутся только им; nil — активность не отмечается
+    Shopping ShoppingActivity
+    // Me — сам бот по getMe: по ID и username бот узнаёт обращения к себе в
+    // группах. Пустые поля NewBot берёт из TELEGRAM_BOT_USERNAME и токена.
+    Me telegram.BotInfo
+}
+
+// Bot — обработчик апдейтов Telegram
+type Bot struct {
+    Deps
+
+    commands  map[string]command
+    callbacks map[string]CallbackFunc
+    tools     *openai.ToolRegistry
+    // queue — очередь вебхука; nil, пока не вызван StartWorkers
+    queue *updateQueue
+    // faq — текущий FAQ: Deps.FAQ при старте, затем SetFAQ
+    faq atomic.Pointer[faq.Matcher]
+    // filter — текущий фильтр ответов: Deps.Filter при старте, затем SetFilter
+    filter atomic.Pointer[filter.Filter]
+    // rates — курсы валют для показа цен: Config.CurrencyRates, затем SetRates
+    rates atomic.Pointer[money.Rates]
+    // stats — сводка /stats за последнюю минуту
+    stats statsCache
+    // outboxWake — подсказка RunOutbox, что в очереди появился ответ
+    outboxWake chan struct{}
+    // replies — конвейер обработки ответов модели (Config.ReplyStages)
+    replies ReplyPipeline
+    // streamed — те же этапы для ответа, показываемого по мере генерации
+    streamed ReplyPipeline
+    // stopping отменяется в StopWorkers: по нему прерываются задачи команд,
+    // которые переживают апдейт (рассылка /broadcast)
+    stopping context.Context
+    stop     context.CancelFunc
+}
+
+// SetFAQ подменяет FAQ на лету (перезагрузка по SIGHUP); nil выключает FAQ
+func (b *Bot) SetFAQ(m *faq.Matcher) {
+    b.faq.Store(m)
+}
+
+// NewBot — фабрика бота с зарегистрированными командами по умолчанию
+func NewBot(deps Deps) *Bot {
+    b := &Bot{
+        Deps:       deps,
+        commands:   make(map[string]command),
+        callbacks:  make(map[string]CallbackFunc),
+        tools:      openai.NewToolRegistry(),
+        outboxWake: make(chan struct{}, 1),
+    }
+    b.stopping, b.stop = context.WithCancel(context.Background())
+    if b.Me.Username == "" {
+        b.Me.Username = deps.Config.BotUsername
+    }
+    if b.Me.ID == 0 {
+        b.Me.ID = tokenBotID(deps.Config.TelegramToken)
+    }
+    b.faq.Store(deps.FAQ)
+    b.filter.Store(deps.Filter)
+    b.rates.Store(deps.Config.CurrencyRates)
+    b.replies = b.newReplyPipeline()
+    b.streamed = b.newStreamPipeline()
+    b.registerDefaultCommands()
+    b.checkMenuCommands()
+    b.registerTools()
+    return b
 }
 
 // TelegramHandler — базовый HTTP-хендлер для Telegram webhook
-func TelegramHandler(w http.ResponseWriter, r *http.Request) {
+func (b *Bot) TelegramHandler(w http.ResponseWriter, r *http.Request) {
     if r.Method != http.MethodPost {
         w.WriteHeader(http.StatusMethodNotAllowed)
         w.Write([]byte("Метод не поддерживается"))
         return
     }
+    // Telegram всегда присылает JSON; остальное — сканеры и ошибочно
+    // направленный трафик, его незачем даже разбирать
+    if !isJSON(r) {
+        w.WriteHeader(http.StatusUnsupportedMediaType)
+        return
+    }
+
+    if !b.validSecret(r) {
+        logging.Logger().Warn("запрос к webhook с неверным секретом", "client_ip", reqctx.ClientIPFromContext(r.Context()))
+        w.WriteHeader(http.StatusForbidden)
+        return
+    }
+
+    // Апдейт Telegram — единицы килобайт; больше лимита не читаем, чтобы
+    // чужой POST не съел память. Глубину вложенности encoding/json ограничивает сам.
+    r.Body = http.MaxBytesReader(w, r.Body, b.Config.WebhookMaxBodyBytes)
 
     var update TelegramUpdate
     if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-        log.Printf("❌ Ошибка разбора запроса Telegram: %v", err)
+        var tooLarge *http.MaxBytesError
+        if errors.As(err, &tooLarge) {
+            logging.Logger().Warn("слишком большой запрос к webhook", "limit", tooLarge.Limit, "client_ip", reqctx.ClientIPFromContext(r.Context()))
+            w.WriteHeader(http.StatusRequestEntityTooLarge)
+            return
+        }
+        logging.Logger().Warn("ошибка разбора запроса Telegram", "err", err)
         w.WriteHeader(http.StatusBadRequest)
         return
     }
 
-    log.Printf("📩 Получено сообщение от Telegram: %s", 
-update.Message.Text)
+    // По X-Request-ID ответа вебхука можно найти в логах обработку апдейта
+    ctx := withTrace(r.Context(), update)
+    w.Header().Set("X-Request-ID", reqctx.TraceIDFromContext(ctx))
+
+    if b.queue != nil {
+        if err := b.queue.enqueue(ctx, update); err != nil {
+            // Апдейт не принят — отвечаем ошибкой, чтобы Telegram доставил его повторно
+            logging.FromContext(ctx).Error("ошибка постановки апдейта в очередь", "update_id", update.UpdateID, "err", err)
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+    } else if err := b.ProcessUpdate(ctx, update); err != nil {
+        logging.FromContext(ctx).Error("ошибка обработки апдейта", "update_id", update.UpdateID, "err", err)
+    }
+
+    // Всегда отвечаем 200, иначе Telegram будет повторять доставку
+    w.WriteHeader(http.StatusOK)
+}
+
+// ProcessUpdate обрабатывает один апдейт: маршрутизация команд и кнопок,
+// запросы к модели и ответы. Общий для вебхука и long polling.
+// Пользователь получает ответ и при ошибке — она возвращается для журнала,
+// а сам апдейт сохраняется в журнал необработанных для /retryfailed.
+func (b *Bot) ProcessUpdate(ctx context.Context, update TelegramUpdate) error {
+    ctx = withTrace(ctx, update)
+    // Без дедлайна зависший OpenAI или БД держали бы горутину и соединение вечно
+    ctx, cancel := context.WithTimeout(ctx, b.Config.UpdateTimeout)
+    defer cancel()
+
+    // Некорректный апдейт повторная доставка не исправит — подтверждаем и пропускаем
+    if err := update.Validate(); err != nil {
+        logging.FromContext(ctx).Warn("апдейт пропущен", "update_id", update.UpdateID, "err", err)
+        return nil
+    }
+    if !b.firstDelivery(ctx, update.UpdateID) {
+        return nil
+    }
+    if !b.admitted(ctx, update) {
+        return nil
+    }
+    unlock, err := b.lockChat(ctx, update)
+    if err != nil {
+        if redeliverable(ctx) {
+            // Апдейт не начали обрабатывать — вернём его очереди, и повторная
+            // доставка не должна считаться дублем
+            b.forgetDelivery(ctx, update.UpdateID)
+            return fmt.Errorf("%w: %w", errRedeliver, err)
+        }
+        b.recordFailure(ctx, update, err)
+        return err
+    }
+    defer unlock()
+
+    err = b.dispatch(ctx, update)
+    if err != nil {
+        b.recordFailure(ctx, update, err)
+    }
+    return err
+}
 
-    // Временно отвечаем заглушкой
-    fmt.Fprintf(w, "Принято сообщение: %s", update.Message.Text)
+// lockChat занимает чат апдейта на время обработки, чтобы сообщения одного
+// чата не перемешивали корзину и контекст. Апдейт из очереди ждёт не дольше
+// ChatLockWait — потом очередь получит его обратно, а обработчик возьмёт
+// другой чат; вебхук без очереди и long polling вернуть апдейт некуда, и
+// они ждут до дедлайна апдейта. Без Redis обработка идёт без блокировки,
+// как и с остальными кэшами.
+func (b *Bot) lockChat(ctx context.Context, update TelegramUpdate) (func(), error) {
+    chatID := updateChatID(update)
+    if b.Locks == nil || chatID == 0 {
+        return func() {}, nil
+    }
+    lockCtx := ctx
+    if redeliverable(ctx) {
+        var cancel context.CancelFunc
+        lockCtx, cancel = context.WithTimeout(ctx, b.Config.ChatLockWait)
+        defer cancel()
+    }
+    unlock, err := b.Locks.Lock(lockCtx, chatID)
+    if errors.Is(err, cache.ErrChatLocked) {
+        return nil, err
+    }
+    if err != nil {
+        logging.FromContext(ctx).Warn("блокировка чата недоступна, обрабатываем без неё", "chat_id", chatID, "err", err)
+        return func() {}, nil
+    }
+    return unlock, nil
 }
 
+// retryUpdate повторно обрабатывает апдейт из журнала: без проверки
+// повторной доставки и без новой записи в журнал при ошибке. Чат занимается,
+// как для живого апдейта, иначе повтор перемешал бы корзину и историю с
+// новым сообщением покупателя.
+func (b *Bot) retryUpdate(ctx context.Context, update TelegramUpdate) error {
+    ctx = reqctx.WithTraceID(ctx, fmt.Sprintf("retry-%d", update.UpdateID))
+    ctx, cancel := context.WithTimeout(ctx, b.Config.UpdateTimeout)
+    defer cancel()
+    unlock, err := b.lockChat(ctx, update)
+    if err != nil {
+        return err
+    }
+    defer unlock()
+    return b.dispatch(ctx, update)
+}
+
+// dispatch передаёт апдейт обработчику по его типу
+func (b *Bot) dispatch(ctx context.Context, update TelegramUpdate) error {
+    kind := update.Type()
+    metrics.UpdatesTotal.WithLabelValues(string(kind)).Inc()
+
+    var err error
+    switch kind {
+    case UpdateCallback:
+        logging.FromContext(ctx).Info("получен апдейт", "update_type", kind)
+        err = b.handleCallback(ctx, update.CallbackQuery)
+    case UpdateMessage:
+        logging.FromContext(ctx).Info("получен апдейт", "update_type", kind, "chat_id", update.Message.Chat.ID, "lang", update.Message.lang())
+        err = b.handleMessage(ctx, update.Message)
+    case UpdateMyChatMember:
+        logging.FromContext(ctx).Info("получен апдейт", "update_type", kind, "chat_id", update.MyChatMember.Chat.ID)
+        err = b.handleMyChatMember(ctx, update.MyChatMember)
+    default:
+        // Правки сообщений и неподдерживаемые виды подтверждаются, но не обрабатываются
+        logging.FromContext(ctx).Debug("апдейт без поддерживаемого содержимого пропущен", "update_type", kind)
+    }
+
+    if err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+        err = fmt.Errorf("обработка не уложилась в %s: %w", b.Config.UpdateTimeout, ctx.Err())
+    }
+    return err
+}
+
+// withTrace кладёт в контекст id трассировки апдейта, если его там ещё нет.
+// Id выводится из update_id, поэтому вебхук, очередь и обработчик называют
+// апдейт одинаково без передачи контекста между горутинами.
+func withTrace(ctx context.Context, update TelegramUpdate) context.Context {
+    if reqctx.TraceIDFromContext(ctx) != "" {
+        return ctx
+    }
+    if update.UpdateID != 0 {
+        return reqctx.WithTraceID(ctx, fmt.Sprintf("upd-%d", update.UpdateID))
+    }
+    var buf [8]byte
+    rand.Read(buf[:])
+    return reqctx.WithTraceID(ctx, hex.EncodeToString(buf[:]))
+}
+
+// handleMessage — обработка обычного сообщения: команда или вопрос к AI
+func (b *Bot) handleMessage(ctx context.Context, msg *TelegramMessage) error {
+    if msg.Chat.ID == 0 {
+        return nil
+    }
+    if len(msg.NewChatMembers) > 0 && msg.Chat.isGroup() {
+        b.challengeNewMembers(ctx, msg)
+        return nil
+    }
+    empty := strings.TrimSpace(msg.Text) == "" && len(msg.Photo) == 0 && msg.Voice == nil
+    if empty && msg.Chat.isGroup() {
+        // Служебные сообщения групп (вход участника, закреп) не касаются бота
+        return nil
+    }
+    if !b.addressedToBot(msg) {
+        return nil
+    }
+    ctx = reqctx.WithChatID(ctx, msg.Chat.ID)
+    ctx = reqctx.WithLang(ctx, msg.lang())
+    metrics.TouchChat(msg.Chat.ID)
+
+    if !b.allow(ctx, msg.Chat.ID) {
+        b.replyPhrase(ctx, msg.Chat.ID, rateLimitedReply)
+        return nil
+    }
+
+    b.saveProfile(ctx, msg)
+    ctx = b.withProfile(ctx, msg.Chat.ID)
+    ctx = b.withPreferredLang(ctx, langOwner(msg.Chat, msg.From))
+    ctx = b.withPromptVariant(ctx, msg.Chat.ID)
+    ctx = b.withThreading(ctx, msg)
+    if !msg.Chat.isGroup() {
+        b.offerDraft(ctx, msg.Chat.ID)
+    }
+
+    if empty {
+        // Файл с командой в подписи — единственное нетекстовое сообщение,
+        // которое разбирают команды
+        if msg.Document != nil {
+            handled, err := b.dispatchCommand(ctx, msg)
+            if err != nil {
+                b.replyError(ctx, msg.Chat.ID, fmt.Errorf("ошибка выполнения команды: %w", err))
+            }
+            if handled {
+                return nil
+            }
+        }
+        // Стикер, геопозиция, файл или пустое сообщение: модели нечего отвечать
+        b.replyPhrase(ctx, msg.Chat.ID, nonTextReply(msg))
+        return nil
+    }
+
+    if len(msg.Photo) > 0 {
+        b.handlePhoto(ctx, msg)
+        return nil
+    }
+    if msg.Voice != nil {
+        b.handleVoice(ctx, msg)
+        return nil
+    }
+
+    handled, err := b.dispatchCommand(ctx, msg)
+    if err != nil {
+        // Ответ и уровень лога выбираются по категории ошибки
+        b.replyError(ctx, msg.Chat.ID, fmt.Errorf("ошибка выполнения команды: %w", err))
+        return nil
+    }
+    if !handled {
+        if links, emails := msg.Links(), msg.Emails(); len(links) > 0 || len(emails) > 0 {
+            logging.FromContext(ctx).Debug("в сообщении есть ссылки или почта", "chat_id", msg.Chat.ID, "links", len(links), "emails", len(emails))
+        }
+        b.replyWithAI(ctx, msg)
+    }
+    return nil
+}
+
+// saveProfile обновляет профиль покупателя по полю from. Ошибка не мешает ответу.
+func (b *Bot) saveProfile(ctx context.Context, msg *TelegramMessage) {
+    if msg.From == nil || msg.From.IsBot {
+        return
+    }
+    if err := b.Users.Upsert(ctx, msg.Chat.ID, msg.From.Username, msg.From.LanguageCode, msg.From.FullName()); err != nil {
+        logging.FromContext(ctx).Error("ошибка сохранения профиля", "chat_id", msg.Chat.ID, "err", err)
+    }
+}
+
+// nonTextReply — подсказка написать вопрос текстом
+func nonTextReply(msg *TelegramMessage) string {
+    switch {
+    case len(msg.Sticker) > 0:
+        return "Отличный стикер! Напишите, пожалуйста, ваш вопрос текстом — я помогу подобрать товар."
+    case len(msg.Location) > 0:
+        return "Спасибо! Чтобы рассчитать доставку, напишите, пожалуйста, адрес текстом."
+    default:
+        return "Напишите, пожалуйста, ваш вопрос текстом."
+    }
+}
+
+// isJSON — запрос с Content-Type application/json; параметры вроде
+// charset не учитываются
+func isJSON(r *http.Request) bool {
+    mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+    return err == nil && mediaType == "application/json"
+}
+
+// validSecret сверяет секрет webhook за постоянное время.
+// Если секрет не настроен, проверка пропускается.
+func (b *Bot) validSecret(r *http.Request) bool {
+    secret := b.Config.WebhookSecret
+    if secret == "" {
+        return true
+    }
+    got := r.Header.Get(secretTokenHeader)
+    return subtle.ConstantTimeCompare([]byte(got), []byte(secret)) == 1
+}
+
+// firstDelivery — пришёл ли апдейт впервые. Telegram повторяет доставку,
+// если не дождался ответа, и без проверки бот ответил бы дважды.
+// При недоступности Redis апдейт обрабатываем.
+func (b *Bot) firstDelivery(ctx context.Context, updateID int64) bool {
+    if updateID == 0 {
+        return true
+    }
+    first, err := b.Updates.FirstSeen(ctx, updateID)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка дедупликации апдейта", "update_id", updateID, "err", err)
+        return true
+    }
+    if !first {
+        metrics.DuplicateUpdatesTotal.Inc()
+        logging.FromContext(ctx).Info("отброшен повторный апдейт", "update_id", updateID)
+    }
+    return first
+}
+
+// forgetDelivery снимает отметку firstDelivery с апдейта, который будет
+// доставлен повторно
+func (b *Bot) forgetDelivery(ctx context.Context, updateID int64) {
+    if updateID == 0 {
+        return
+    }
+    // Дедлайн апдейта мог уже истечь в ожидании блокировки чата
+    ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+    defer cancel()
+    if err := b.Updates.Forget(ctx, updateID); err != nil {
+        logging.FromContext(ctx).Error("ошибка снятия отметки апдейта", "update_id", updateID, "err", err)
+    }
+}
+
+// allow проверяет лимит сообщений чата. При недоступности Redis по умолчанию
+// пропускаем сообщение — лучше ответить, чем молчать; RATE_LIMIT_FAIL_OPEN=false
+// меняет это на отказ, если важнее защита от перерасхода.
+func (b *Bot) allow(ctx context.Context, chatID int64) bool {
+    ok, err := b.Limiter.Allow(ctx, chatID)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка лимитера", "chat_id", chatID, "fail_open", b.Config.RateLimitFailOpen, "err", err)
+        return b.Config.RateLimitFailOpen
+    }
+    if !ok {
+        logging.FromContext(ctx).Info("превышен лимит сообщений", "chat_id", chatID)
+    }
+    return ok
+}
+
+// overBudget — исчерпан ли месячный бюджет токенов OpenAI.
+// Если счётчик недоступен, запрос пропускаем: бюджет — защита от перерасхода, а не от работы.
+func (b *Bot) overBudget(ctx context.Context) bool {
+    budget := b.Config.MonthlyTokenBudget
+    if budget <= 0 {
+        return false
+    }
+
+    total, err := b.Usage.MonthTotal(ctx)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка чтения счётчика токенов", "err", err)
+        return false
+    }
+    if total.Total >= budget {
+        logging.FromContext(ctx).Error("ALERT: исчерпан месячный бюджет токенов OpenAI", "used", total.Total, "budget", budget)
+        return true
+    }
+    return false
+}
+
+// replyWithAI отправляет текст пользователя в OpenAI и пересылает ответ модели в чат
+func (b *Bot) replyWithAI(ctx context.Context, msg *TelegramMessage) {
+    chatID := msg.Chat.ID
+
+    if b.handedOff(ctx, chatID) {
+        b.relayToOperator(ctx, chatID, msg.Text)
+        return
+    }
+    if category, flagged := b.moderationFlag(ctx, chatID, msg.Text); flagged {
+        logging.FromContext(ctx).Warn("сообщение не прошло модерацию", "chat_id", chatID, "category", category)
+        b.replyPhrase(ctx, chatID, moderationReply)
+        return
+    }
+    if b.Handoffs != nil && b.wantsHuman(msg.Text) {
+        if _, err := b.escalate(ctx, chatID, keywordHandoffReason); err != nil {
+            logging.FromContext(ctx).Error("ошибка передачи оператору", "chat_id", chatID, "err", err)
+        } else {
+            b.remember(ctx, chatID, "user", msg.Text)
+            b.replyPhrase(ctx, chatID, handoffReply)
+            return
+        }
+    }
+
+    if matcher := b.faq.Load(); matcher != nil {
+        if answer, ok := matcher.Match(msg.Text, reqctx.LangFromContext(ctx)); ok {
+            logging.FromContext(ctx).Info("ответ из FAQ", "chat_id", chatID)
+            b.remember(ctx, chatID, "user", msg.Text)
+            b.reply(ctx, chatID, answer)
+            b.remember(ctx, chatID, "assistant", answer)
+            return
+        }
+    }
+
+    now := time.Now()
+    switch b.offHoursMode(now) {
+    case "ack":
+        b.ackOffHours(ctx, chatID, msg.Text)
+        return
+    case "note":
+        b.noteOffHours(ctx, chatID, now)
+    }
+
+    ctx = b.withSharedAnswer(b.withAnswerLength(ctx, chatID))
+    messages, text := b.buildContext(ctx, chatID, msg.Text)
+    if text != msg.Text {
+        logging.FromContext(ctx).Warn("сообщение не влезло в контекст и обрезано", "chat_id", chatID)
+        b.replyPhrase(ctx, chatID, truncatedMessageReply)
+    }
+    cacheKey := b.responseCacheKey(ctx, messages, text)
+    messages = append(messages, openai.User(text))
+    b.remember(ctx, chatID, "user", text)
+
+    if answer, ok := b.cachedResponse(ctx, cacheKey); ok {
+        // Правила фильтра могли измениться после того, как ответ попал в кэш
+        if reply, err := b.sendReply(ctx, chatID, answer); err == nil {
+            b.remember(ctx, chatID, "assistant", reply.Text)
+        }
+        return
+    }
+
+    if b.overBudget(ctx) {
+        b.replyPhrase(ctx, chatID, overBudgetReply)
+        return
+    }
+
+    if b.featureEnabled(ctx, config.FlagStreaming) {
+        b.streamReply(ctx, chatID, messages)
+        return
+    }
+
+    stopTyping := b.keepTyping(ctx, chatID)
+    toolCtx, toolCalls := openai.CountToolCalls(ctx)
+    answer, err := b.complete(toolCtx, messages)
+    stopTyping()
+    if err != nil {
+        b.aiUnavailable(ctx, chatID, err)
+        return
+    }
+
+    reply, err := b.sendReply(ctx, chatID, answer)
+    if err != nil {
+        // Покупатель ответа не увидел: в историю и кэш он не попадает
+        return
+    }
+    b.remember(ctx, chatID, "assistant", reply.Text)
+    // Ответ, ради которого модель меняла корзину или показывала товар,
+    // без повторного вызова инструментов был бы неправдой
+    if toolCalls.Load() == 0 && !reply.Blocked {
+        b.storeResponse(ctx, cacheKey, reply.Text)
+    }
+}
+
+// complete запрашивает ответ модели с инструментами, если поставщик их
+// поддерживает, иначе — обычным запросом без доступа к каталогу и корзине
+func (b *Bot) complete(ctx context.Context, messages []openai.Message) (string, error) {
+    if tc, ok := b.OpenAI.(ToolCaller); ok {
+        return tc.ChatWithTools(ctx, messages, b.tools)
+    }
+    return b.OpenAI.ChatCompletion(ctx, messages)
+}
+
+// aiUnavailable — модель не ответила даже после повторов (ключ отозван, OpenAI
+// лежит, истёк таймаут): пишем в лог причину и отвечаем FALLBACK_MESSAGE
+func (b *Bot) aiUnavailable(ctx context.Context, chatID int64, err error) {
+    var apiErr *openai.APIError
+    switch {
+    case errors.Is(err, cache.ErrAccountLimit):
+        logging.FromContext(ctx).Warn("запрос отложен лимитом аккаунта OpenAI", "chat_id", chatID)
+        b.replyPhrase(ctx, chatID, accountLimitReply)
+        return
+    case errors.Is(err, openai.ErrUnknownTool):
+        logging.FromContext(ctx).Warn("модель не смогла ответить без несуществующих инструментов", "chat_id", chatID, "err", err)
+        b.replyPhrase(ctx, chatID, b.Config.UnknownToolReply)
+        return
+    case errors.As(err, &apiErr):
+        logging.FromContext(ctx).Error("OpenAI вернул ошибку", "chat_id", chatID, "status", apiErr.StatusCode, "body", apiErr.Body)
+    case errors.Is(err, context.DeadlineExceeded):
+        logging.FromContext(ctx).Error("OpenAI не ответил вовремя", "chat_id", chatID, "timeout", b.Config.UpdateTimeout, "err", err)
+    default:
+        logging.FromContext(ctx).Error("ошибка запроса к OpenAI", "chat_id", chatID, "err", err)
+    }
+    b.replyPhrase(ctx, chatID, b.Config.FallbackMessage)
+}
+
+// remember сохраняет реплику в постоянную историю и в кратковременный контекст
+func (b *Bot) remember(ctx context.Context, chatID int64, role, text string) {
+    b.rememberTurn(ctx, chatID, role, text, b.Messages.SaveMessage, b.Sessions.AppendTurn)
+}
+
+// rememberIncomplete сохраняет ответ модели, поток которого оборвался: в
+// истории и контексте он помечен незавершённым и остаётся тем, что
+// покупатель успел увидеть. Поток мог оборваться из-за отмены ctx, поэтому
+// сохранение от неё не зависит.
+func (b *Bot) rememberIncomplete(ctx context.Context, chatID int64, text string) {
+    b.rememberTurn(context.WithoutCancel(ctx), chatID, "assistant", text, b.Messages.SaveIncompleteMessage, b.Sessions.AppendIncompleteTurn)
+}
+
+// rememberTurn сохраняет реплику через save и добавляет её в контекст чата через appendTurn
+func (b *Bot) rememberTurn(ctx context.Context, chatID int64, role, text string, save, appendTurn func(ctx context.Context, chatID int64, role, text string) error) {
+    if err := save(ctx, chatID, role, text); err != nil {
+        logging.FromContext(ctx).Error("ошибка сохранения истории", "chat_id", chatID, "err", err)
+    }
+    if err := appendTurn(ctx, chatID, role, text); err != nil {
+        logging.FromContext(ctx).Error("ошибка записи контекста", "chat_id", chatID, "err", err)
+    }
+    if role == "assistant" {
+        b.summarizeLater(ctx, chatID, false)
+    }
+}
+
+// buildContext — системный промпт и история чата для модели, плюс сообщение
+// пользователя text, обрезанное под бюджет контекста.
+// Ошибки не фатальны — модель ответит без контекста.
+func (b *Bot) buildContext(ctx context.Context, chatID int64, text string) ([]openai.Message, string) {
+    messages, text, err := b.Dialog.BuildContext(ctx, chatID, text)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка чтения истории", "chat_id", chatID, "err", err)
+        return []openai.Message{openai.System(b.Dialog.SystemPromptFor(reqctx.PromptVariantFromContext(ctx)))}, text
+    }
+    return messages, text
+}
+
+// reply отправляет ответ в чат с повторами send; ошибки только логируются.
+// ctx нужен, чтобы остановка сервиса прерывала ожидание повтора, а в
+// группе ответ цитировал сообщение участника.
+func (b *Bot) reply(ctx context.Context, chatID int64, text string) {
+    b.send(ctx, chatID, text)
+}
+
+// replyPhrase отправляет фиксированную фразу бота на языке пользователя из ctx
+func (b *Bot) replyPhrase(ctx context.Context, chatID int64, phrase string) {
+    b.send(ctx, chatID, i18n.T(reqctx.LangFromContext(ctx), phrase))
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+    "context"
+)
+
+// threadKey — ключ контекста с сообщением, на которое отвечает бот
+type threadKey struct{}
+
+// thread — сообщение покупателя, ответы на которое его цитируют
+type thread struct {
+    chatID    int64
+    messageID int64
+}
+
+// withThreading отмечает в контексте, что ответы на msg в группе должны
+// цитировать его (GROUP_REPLY_THREADING): в общем чате иначе непонятно,
+// кому отвечает бот. В личке цитата ничего не добавляет.
+func (b *Bot) withThreading(ctx context.Context, msg *TelegramMessage) context.Context {
+    if !b.Config.ThreadGroupReplies || !msg.Chat.isGroup() || msg.MessageID == 0 {
+        return ctx
+    }
+    return context.WithValue(ctx, threadKey{}, thread{chatID: msg.Chat.ID, messageID: msg.MessageID})
+}
+
+// withoutThreading — сообщения из ctx никого не цитируют: рассылка из
+// группы админов не должна цитировать команду в каждом чате
+func withoutThreading(ctx context.Context) context.Context {
+    return context.WithValue(ctx, threadKey{}, thread{})
+}
+
+// threadedMessage — сообщение, которое должен цитировать ответ в chatID, или
+// 0. Сообщения в другие чаты (например, админам) из того же контекста не
+// цитируют.
+func threadedMessage(ctx context.Context, chatID int64) int64 {
+    t, ok := ctx.Value(threadKey{}).(thread)
+    if !ok || t.chatID != chatID {
+        return 0
+    }
+    return t.messageID
+}
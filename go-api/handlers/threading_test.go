@@ -0,0 +1,45 @@
+package handlers
+
+import (
+    "testing"
+)
+
+// groupMention — вопрос участника с упоминанием бота в начале
+func groupMention(updateID int64, body string) TelegramUpdate {
+    u := groupText(updateID, "@shop_bot "+body)
+    u.Message.Entities = []TelegramEntity{{Type: "mention", Offset: 0, Length: len("@shop_bot")}}
+    return u
+}
+
+func TestGroupRepliesQuoteMessage(t *testing.T) {
+    tests := []struct {
+        name   string
+        env    map[string]string
+        update TelegramUpdate
+        chatID int64
+        quote  int64
+    }{
+        {name: "/help в группе", env: groupEnv, update: groupCommand(11, "/help"), chatID: groupID, quote: 11},
+        {name: "ответ модели в группе", env: groupEnv, update: groupMention(12, "что посоветуете?"), chatID: groupID, quote: 12},
+        {name: "личный чат", update: text(13, 42, "/help"), chatID: 42},
+        {name: "цитирование выключено",
+            env:    map[string]string{"TELEGRAM_BOT_USERNAME": "shop_bot", "GROUP_REPLY_THREADING": "false"},
+            update: groupCommand(14, "/help"), chatID: groupID},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tb := newTestBot(t, tt.env)
+            tb.process(t, tt.update)
+
+            sent := tb.tg.Messages()
+            if len(sent) == 0 {
+                t.Fatal("ответа нет")
+            }
+            for _, m := range sent {
+                if m.ChatID == tt.chatID && m.ReplyTo != tt.quote {
+                    t.Fatalf("ответ цитирует %d, ожидалось %d", m.ReplyTo, tt.quote)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+
+    "ai_seller/config"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// semanticSearchLimit — сколько ближайших товаров показывать модели
+const semanticSearchLimit = 10
+
+// productView — товар в том виде, в каком его видит модель
+type productView struct {
+    ID          int64   `json:"id"`
+    Name        string  `json:"name"`
+    Description string  `json:"description"`
+    Price       float64 `json:"price"`
+    Currency    string  `json:"currency"`
+    InStock     bool    `json:"in_stock"`
+}
+
+func newProductView(p storage.Product) productView {
+    return productView{
+        ID:          p.ID,
+        Name:        p.Name,
+        Description: p.Description,
+        Price:       p.Price.Major(),
+        Currency:    p.Price.Currency,
+        InStock:     p.InStock,
+    }
+}
+
+// registerTools — инструменты, которые модель может вызывать при ответе
+func (b *Bot) registerTools() {
+    b.tools.Register("search_products",
+        "Поиск товаров в каталоге магазина по названию или описанию. Используй перед тем, как называть товары, цены или наличие.",
+        `{"type":"object","properties":{"query":{"type":"string","description":"Что ищет покупатель, например: футболка оверсайз"}},"required":["query"]}`,
+        b.toolSearchProducts)
+
+    b.tools.Register("show_product",
+        "Показать покупателю фото товара с названием и ценой. Вызывай, когда рекомендуешь конкретный товар.",
+        `{"type":"object","properties":{"product_id":{"type":"integer"}},"required":["product_id"]}`,
+        b.toolShowProduct)
+
+    b.tools.Register("add_to_cart",
+        "Добавить товар в корзину покупателя. product_id бери только из результатов search_products.",
+        `{"type":"object","properties":{"product_id":{"type":"integer"},"quantity":{"type":"integer","minimum":1}},"required":["product_id","quantity"]}`,
+        b.toolAddToCart)
+    b.tools.Register("remove_from_cart",
+        "Убрать товар из корзины покупателя целиком.",
+        `{"type":"object","properties":{"product_id":{"type":"integer"}},"required":["product_id"]}`,
+        b.toolRemoveFromCart)
+    b.tools.Register("view_cart",
+        "Показать текущее содержимое корзины покупателя и итоговую сумму.",
+        `{"type":"object","properties":{}}`,
+        b.toolViewCart)
+
+    if b.Handoffs != nil {
+        b.tools.Register("escalate_to_human",
+            "Передать разговор живому менеджеру. Вызывай, если покупатель просит человека или ты не можешь помочь (жалоба, возврат, вопрос не о товарах). После вызова коротко скажи, что менеджер скоро свяжется.",
+            `{"type":"object","properties":{"reason":{"type":"string","description":"Кратко, почему нужен человек"}},"required":["reason"]}`,
+            b.toolEscalateToHuman)
+    }
+}
+
+// toolSearchProducts — инструмент search_products
+func (b *Bot) toolSearchProducts(ctx context.Context, args json.RawMessage) (string, error) {
+    var in struct {
+        Query string `json:"query"`
+    }
+    if err := json.Unmarshal(args, &in); err != nil {
+        return "", fmt.Errorf("некорректные аргументы: %w", err)
+    }
+
+    products, err := b.searchProducts(ctx, in.Query)
+    if err != nil {
+        return "", err
+    }
+
+    views := make([]productView, 0, len(products))
+    for _, p := range products {
+        views = append(views, newProductView(p))
+    }
+    return marshalToolResult(views)
+}
+
+// searchProducts ищет по смыслу, если включён семантический поиск, и по
+// подстроке — если он выключен, сломался или ещё ничего не проиндексировал
+func (b *Bot) searchProducts(ctx context.Context, query string) ([]storage.Product, error) {
+    b.noteShopping(ctx)
+    if b.Config.Features.IsEnabled(config.FlagSemanticSearch) {
+        products, err := b.Catalog.SemanticSearch(ctx, query, semanticSearchLimit)
+        if err == nil && len(products) > 0 {
+            return products, nil
+        }
+        if err != nil {
+            logging.FromContext(ctx).Warn("семантический поиск не удался, ищем по подстроке", "err", err)
+        }
+    }
+    return b.Catalog.SearchProducts(ctx, query)
+}
+
+// toolShowProduct — инструмент show_product
+func (b *Bot) toolShowProduct(ctx context.Context, args json.RawMessage) (string, error) {
+    var in cartToolArgs
+    if err := json.Unmarshal(args, &in); err != nil {
+        return "", fmt.Errorf("некорректные аргументы: %w", err)
+    }
+
+    p, err := b.Catalog.GetProduct(ctx, in.ProductID)
+    if errors.Is(err, storage.ErrNotFound) {
+        return "", fmt.Errorf("товар %d не найден", in.ProductID)
+    }
+    if err != nil {
+        return "", err
+    }
+
+    b.showProduct(ctx, reqctx.ChatIDFromContext(ctx), p)
+    return `{"shown":true}`, nil
+}
+
+// cartToolArgs — аргументы инструментов корзины
+type cartToolArgs struct {
+    ProductID int64 `json:"product_id"`
+    Quantity  int   `json:"quantity"`
+}
+
+// toolAddToCart — инструмент add_to_cart
+func (b *Bot) toolAddToCart(ctx context.Context, args json.RawMessage) (string, error) {
+    var in cartToolArgs
+    if err := json.Unmarshal(args, &in); err != nil {
+        return "", fmt.Errorf("некорректные аргументы: %w", err)
+    }
+
+    p, err := b.Catalog.GetProduct(ctx, in.ProductID)
+    if errors.Is(err, storage.ErrNotFound) {
+        return "", fmt.Errorf("товар %d не найден", in.ProductID)
+    }
+    if err != nil {
+        return "", err
+    }
+    if !p.InStock {
+        return "", fmt.Errorf("товара %q нет в наличии", p.Name)
+    }
+
+    chatID := reqctx.ChatIDFromContext(ctx)
+    if err := b.Carts.AddItem(ctx, chatID, in.ProductID, in.Quantity); err != nil {
+        return "", err
+    }
+    b.noteShopping(ctx)
+    return b.toolViewCart(ctx, nil)
+}
+
+// toolRemoveFromCart — инструмент remove_from_cart
+func (b *Bot) toolRemoveFromCart(ctx context.Context, args json.RawMessage) (string, error) {
+    var in cartToolArgs
+    if err := json.Unmarshal(args, &in); err != nil {
+        return "", fmt.Errorf("некорректные аргументы: %w", err)
+    }
+    if err := b.Carts.RemoveItem(ctx, reqctx.ChatIDFromContext(ctx), in.ProductID); err != nil {
+        return "", err
+    }
+    b.noteShopping(ctx)
+    return b.toolViewCart(ctx, nil)
+}
+
+// toolViewCart — инструмент view_cart
+func (b *Bot) toolViewCart(ctx context.Context, _ json.RawMessage) (string, error) {
+    lines, err := b.cartLines(ctx, reqctx.ChatIDFromContext(ctx))
+    if err != nil {
+        return "", err
+    }
+
+    type lineView struct {
+        Product productView `json:"product"`
+        Qty     int         `json:"quantity"`
+    }
+    out := struct {
+        Items []lineView `json:"items"`
+        Total float64    `json:"total"`
+    }{Items: []lineView{}}
+    total, err := cartTotal(lines)
+    if err != nil {
+        return "", err
+    }
+    for _, l := range lines {
+        out.Items = append(out.Items, lineView{Product: newProductView(l.Product), Qty: l.Qty})
+    }
+    out.Total = total.Major()
+    return marshalToolResult(out)
+}
+
+func marshalToolResult(v interface{}) (string, error) {
+    out, err := json.Marshal(v)
+    if err != nil {
+        return "", fmt.Errorf("ошибка сериализации результата: %w", err)
+    }
+    return string(out), nil
+}
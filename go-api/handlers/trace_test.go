@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "ai_seller/openai"
+)
+
+// id трассировки апдейта проходит весь путь: ответ вебхука, каждая запись
+// лога обработки и запрос к OpenAI
+func TestTraceIDFlowsEndToEnd(t *testing.T) {
+    logs := recordLogs(t)
+    var (
+        mu       sync.Mutex
+        upstream []string
+    )
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        upstream = append(upstream, r.Header.Get("X-Client-Request-Id"))
+        mu.Unlock()
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Улун есть"}}]}`)
+    }))
+    t.Cleanup(srv.Close)
+    tb := newTestBot(t, nil)
+    tb.OpenAI = openai.NewClient("sk-test", openai.Options{BaseURL: srv.URL})
+
+    update, err := json.Marshal(text(77, 42, "есть улун?"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    r := httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(string(update)))
+    r.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    tb.TelegramHandler(w, r)
+
+    if got := w.Header().Get("X-Request-ID"); got != "upd-77" {
+        t.Fatalf("X-Request-ID %q, нужно upd-77", got)
+    }
+    if got := tb.sentTo(42); len(got) != 1 || got[0] != "Улун есть" {
+        t.Fatalf("отправлено %q", got)
+    }
+    if len(upstream) == 0 {
+        t.Fatal("запроса к OpenAI не было")
+    }
+    for _, id := range upstream {
+        if id != "upd-77" {
+            t.Fatalf("запрос к OpenAI с id %q", id)
+        }
+    }
+    records := logs.records()
+    if len(records) == 0 {
+        t.Fatal("обработка не оставила записей в логе")
+    }
+    for _, e := range records {
+        if e.attrs["trace_id"] != "upd-77" {
+            t.Errorf("запись %q без id трассировки: %v", e.msg, e.attrs)
+        }
+    }
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+    "context"
+    "time"
+    "unicode/utf8"
+
+    "ai_seller/config"
+    "ai_seller/logging"
+)
+
+const (
+    // typingRefreshInterval — индикатор "печатает" гаснет через 5 секунд, обновляем чаще
+    typingRefreshInterval = 4 * time.Second
+    // sendReserve — сколько времени до дедлайна запроса оставить на саму отправку
+    sendReserve = time.Second
+)
+
+// keepTyping показывает "печатает..." и обновляет статус, пока не будет вызвана
+// возвращённая функция остановки (или не отменён ctx)
+func (b *Bot) keepTyping(ctx context.Context, chatID int64) (stop func()) {
+    ctx, cancel := context.WithCancel(ctx)
+    done := make(chan struct{})
+
+    go func() {
+        defer close(done)
+        ticker := time.NewTicker(typingRefreshInterval)
+        defer ticker.Stop()
+
+        for {
+            if err := b.Telegram.SendChatAction(chatID, "typing"); err != nil {
+                logging.FromContext(ctx).Debug("ошибка sendChatAction", "chat_id", chatID, "err", err)
+            }
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+
+    return func() {
+        cancel()
+        <-done
+    }
+}
+
+// typingPause — пауза перед ответом длиной text: perChar на символ, но не
+// дольше limit и не дальше дедлайна ctx за вычетом sendReserve
+func typingPause(ctx context.Context, text string, perChar, limit time.Duration) time.Duration {
+    d := min(time.Duration(utf8.RuneCountInString(text))*perChar, limit)
+    if deadline, ok := ctx.Deadline(); ok {
+        d = min(d, time.Until(deadline)-sendReserve)
+    }
+    return max(d, 0)
+}
+
+// humanPause выдерживает паузу «набора текста» перед отправкой ответа, если
+// включён флаг typing_delay. Вызывается, когда модель уже ответила, а запись
+// в базу ещё не началась: пауза не держит ни запрос к OpenAI, ни соединение
+// с PostgreSQL. Отмена ctx прерывает паузу и возвращается ошибкой ctx.
+func (b *Bot) humanPause(ctx context.Context, chatID int64, text string) error {
+    if !b.featureEnabled(ctx, config.FlagTypingDelay) {
+        return nil
+    }
+    d := typingPause(ctx, text, b.Config.TypingDelayPerChar, b.Config.TypingDelayMax)
+    if d <= 0 {
+        return nil
+    }
+
+    stopTyping := b.keepTyping(ctx, chatID)
+    defer stopTyping()
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
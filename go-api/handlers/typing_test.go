@@ -0,0 +1,76 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestTypingPauseBounded(t *testing.T) {
+    long := strings.Repeat("а", 1000)
+    withDeadline := func(d time.Duration) context.Context {
+        ctx, cancel := context.WithTimeout(context.Background(), d)
+        t.Cleanup(cancel)
+        return ctx
+    }
+    cases := []struct {
+        name     string
+        ctx      context.Context
+        text     string
+        min, max time.Duration
+    }{
+        {"по длине", context.Background(), "привет", 60 * time.Millisecond, 60 * time.Millisecond},
+        {"не дольше предела", context.Background(), long, 3 * time.Second, 3 * time.Second},
+        {"до дедлайна с запасом на отправку", withDeadline(2 * time.Second), long, 900 * time.Millisecond, time.Second},
+        {"дедлайн ближе запаса", withDeadline(500 * time.Millisecond), long, 0, 0},
+        {"пустой ответ", context.Background(), "", 0, 0},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            got := typingPause(tc.ctx, tc.text, 10*time.Millisecond, 3*time.Second)
+            if got < tc.min || got > tc.max {
+                t.Fatalf("пауза %s, нужно от %s до %s", got, tc.min, tc.max)
+            }
+        })
+    }
+}
+
+// Пауза выдерживается с индикатором «печатает» и длится не дольше
+// TYPING_DELAY_MAX; без флага её нет
+func TestHumanPause(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"TYPING_DELAY": "true", "TYPING_DELAY_PER_CHAR": "10ms", "TYPING_DELAY_MAX": "50ms"})
+    start := time.Now()
+    if err := tb.humanPause(context.Background(), 42, strings.Repeat("а", 1000)); err != nil {
+        t.Fatal(err)
+    }
+    if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+        t.Fatalf("пауза длилась %s при пределе 50ms", elapsed)
+    }
+    if len(tb.tg.Actions) == 0 || tb.tg.Actions[0] != "typing" {
+        t.Fatalf("действия чата %q, нужно typing", tb.tg.Actions)
+    }
+
+    off := newTestBot(t, map[string]string{"TYPING_DELAY_MAX": "10s"})
+    start = time.Now()
+    if err := off.humanPause(context.Background(), 42, strings.Repeat("а", 1000)); err != nil || time.Since(start) > 100*time.Millisecond {
+        t.Fatalf("без флага пауза %s, %v", time.Since(start), err)
+    }
+}
+
+// Отмена контекста прерывает паузу сразу
+func TestHumanPauseCancelled(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"TYPING_DELAY": "true", "TYPING_DELAY_MAX": "10s"})
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(20*time.Millisecond, cancel)
+
+    start := time.Now()
+    err := tb.humanPause(ctx, 42, strings.Repeat("а", 1000))
+    if !errors.Is(err, context.Canceled) {
+        t.Fatalf("humanPause = %v, нужна отмена", err)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("отменённая пауза длилась %s", elapsed)
+    }
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+)
+
+// UpdateType — вид апдейта Telegram по тому, какое поле в нём заполнено
+type UpdateType string
+
+const (
+    UpdateMessage  UpdateType = "message"
+    UpdateCallback UpdateType = "callback_query"
+    // UpdateEdited — правка уже отправленного сообщения; бот на правки не отвечает
+    UpdateEdited UpdateType = "edited_message"
+    // UpdateMyChatMember — бота заблокировали, разблокировали, добавили в группу или удалили
+    UpdateMyChatMember UpdateType = "my_chat_member"
+    // UpdateUnknown — channel_post и прочее, что бот не обрабатывает
+    UpdateUnknown UpdateType = "unknown"
+)
+
+// ErrInvalidUpdate — апдейт разобран, но в нём нет обязательных полей
+var ErrInvalidUpdate = errors.New("некорректный апдейт Telegram")
+
+// Type определяет вид апдейта. Telegram заполняет ровно одно поле
+// содержимого; кнопки проверяются первыми, как и при обработке.
+func (u TelegramUpdate) Type() UpdateType {
+    switch {
+    case u.CallbackQuery != nil:
+        return UpdateCallback
+    case u.Message != nil:
+        return UpdateMessage
+    case u.EditedMessage != nil:
+        return UpdateEdited
+    case u.MyChatMember != nil:
+        return UpdateMyChatMember
+    }
+    return UpdateUnknown
+}
+
+// Validate проверяет обязательные поля апдейта его вида: у сообщения должен
+// быть чат, у нажатия кнопки — id для answerCallbackQuery. Нажатие без
+// сообщения (старое inline-сообщение) допустимо: часики на кнопке всё равно
+// надо убрать. Неизвестные виды не проверяются — их и так пропускают.
+func (u TelegramUpdate) Validate() error {
+    switch u.Type() {
+    case UpdateMessage:
+        if u.Message.Chat.ID == 0 {
+            return fmt.Errorf("%w: сообщение без id чата", ErrInvalidUpdate)
+        }
+    case UpdateCallback:
+        if u.CallbackQuery.ID == "" {
+            return fmt.Errorf("%w: нажатие кнопки без id", ErrInvalidUpdate)
+        }
+    case UpdateMyChatMember:
+        if u.MyChatMember.Chat.ID == 0 {
+            return fmt.Errorf("%w: смена статуса бота без id чата", ErrInvalidUpdate)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "log/slog"
+    "net/http"
+    "testing"
+)
+
+// Вид апдейта определяется по заполненному полю, обязательные поля
+// проверяются только у тех видов, что бот обрабатывает
+func TestUpdateTypeAndValidate(t *testing.T) {
+    cases := []struct {
+        name    string
+        payload string
+        kind    UpdateType
+        invalid bool
+    }{
+        {"сообщение", `{"update_id":1,"message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"привет"}}`, UpdateMessage, false},
+        {"сообщение без чата", `{"update_id":1,"message":{"message_id":1,"text":"привет"}}`, UpdateMessage, true},
+        {"кнопка", `{"update_id":1,"callback_query":{"id":"cb","data":"page:10"}}`, UpdateCallback, false},
+        {"кнопка без id", `{"update_id":1,"callback_query":{"data":"page:10"}}`, UpdateCallback, true},
+        {"правка", `{"update_id":1,"edited_message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"привет!"}}`, UpdateEdited, false},
+        {"пост канала", `{"update_id":1,"channel_post":{"message_id":1,"chat":{"id":-100,"type":"channel"},"text":"новости"}}`, UpdateUnknown, false},
+        {"статус бота без чата", `{"update_id":1,"my_chat_member":{"new_chat_member":{"status":"kicked"}}}`, UpdateMyChatMember, true},
+        {"пустой", `{"update_id":1}`, UpdateUnknown, false},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            var u TelegramUpdate
+            if err := json.Unmarshal([]byte(tc.payload), &u); err != nil {
+                t.Fatal(err)
+            }
+            if got := u.Type(); got != tc.kind {
+                t.Fatalf("вид %q, нужен %q", got, tc.kind)
+            }
+            if err := u.Validate(); errors.Is(err, ErrInvalidUpdate) != tc.invalid {
+                t.Fatalf("Validate: %v, некорректен: %v", err, tc.invalid)
+            }
+        })
+    }
+}
+
+// Правки, посты каналов и апдейты без обязательных полей подтверждаются
+// кодом 200 без ответа, чтобы Telegram не повторял доставку; мусор, который
+// не разбирается как апдейт, — 400
+func TestWebhookSkipsUnsupportedUpdates(t *testing.T) {
+    cases := []struct {
+        name    string
+        payload string
+        code    int
+    }{
+        {"правка", `{"update_id":1,"edited_message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"привет!"}}`, http.StatusOK},
+        {"пост канала", `{"update_id":2,"channel_post":{"message_id":1,"chat":{"id":42,"type":"channel"},"text":"новости"}}`, http.StatusOK},
+        {"сообщение без чата", `{"update_id":3,"message":{"message_id":1,"text":"привет"}}`, http.StatusOK},
+        {"не JSON", `<xml>привет</xml>`, http.StatusBadRequest},
+        {"массив", `[1,2,3]`, http.StatusBadRequest},
+        {"чужие типы полей", `{"update_id":"один","message":"привет"}`, http.StatusBadRequest},
+        {"обрыв", `{"update_id":4,"message":{"chat":`, http.StatusBadRequest},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, nil)
+            if code := post(tb, http.MethodPost, "application/json", tc.payload); code != tc.code {
+                t.Fatalf("код %d, нужно %d", code, tc.code)
+            }
+            if sent := tb.tg.Messages(); len(sent) != 0 {
+                t.Fatalf("на апдейт отправлено %+v", sent)
+            }
+            if len(tb.ai.Requests) != 0 {
+                t.Fatal("апдейт дошёл до модели")
+            }
+        })
+    }
+}
+
+// Пропуск неподдерживаемого вида пишется на уровне Debug, некорректного — Warn
+func TestSkippedUpdateLogLevels(t *testing.T) {
+    logs := recordLogs(t)
+    tb := newTestBot(t, nil)
+    post(tb, http.MethodPost, "application/json", `{"update_id":1,"channel_post":{"message_id":1,"chat":{"id":-100,"type":"channel"}}}`)
+    post(tb, http.MethodPost, "application/json", `{"update_id":2,"message":{"message_id":1}}`)
+
+    if got := logs.levels("апдейт без поддерживаемого содержимого пропущен"); len(got) != 1 || got[0] != slog.LevelDebug {
+        t.Errorf("пропуск поста канала: уровни %v, нужен Debug", got)
+    }
+    if got := logs.levels("апдейт пропущен"); len(got) != 1 || got[0] != slog.LevelWarn {
+        t.Errorf("пропуск некорректного апдейта: уровни %v, нужен Warn", got)
+    }
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "ai_seller/apperr"
+    "ai_seller/config"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// withPromptVariant переносит в контекст вариант системного промпта чата в
+// A/B-тесте (PROMPT_VARIANTS). Вариант закрепляется в профиле при первом
+// сообщении: смена весов перераспределяет только новые чаты, а покупатель
+// до конца теста видит тот же промпт. Вариант, убранный из конфигурации,
+// назначается заново.
+func (b *Bot) withPromptVariant(ctx context.Context, chatID int64) context.Context {
+    variants := b.Config.PromptVariants
+    if len(variants) == 0 {
+        return ctx
+    }
+    u, err := b.profile(ctx, chatID)
+    if err != nil && !errors.Is(err, storage.ErrNotFound) {
+        // Без профиля — вариант по хэшу: при тех же весах он совпадает с закреплённым
+        logging.FromContext(ctx).Warn("ошибка чтения варианта промпта", "chat_id", chatID, "err", err)
+        return reqctx.WithPromptVariant(ctx, config.PickPromptVariant(variants, chatID))
+    }
+    if knownVariant(variants, u.PromptVariant) {
+        return reqctx.WithPromptVariant(ctx, u.PromptVariant)
+    }
+
+    variant := config.PickPromptVariant(variants, chatID)
+    if err := b.Users.SetPromptVariant(ctx, chatID, variant); err != nil {
+        logging.FromContext(ctx).Error("ошибка закрепления варианта промпта", "chat_id", chatID, "err", err)
+    } else {
+        updateProfile(ctx, chatID, func(u *storage.User) { u.PromptVariant = variant })
+        logging.FromContext(ctx).Info("чату назначен вариант промпта", "chat_id", chatID, "variant", variant, "previous", u.PromptVariant)
+    }
+    return reqctx.WithPromptVariant(ctx, variant)
+}
+
+// knownVariant — есть ли вариант name среди текущих
+func knownVariant(variants []config.PromptVariant, name string) bool {
+    for _, v := range variants {
+        if v.Name == name {
+            return true
+        }
+    }
+    return false
+}
+
+// cmdVariant — админская команда /variant [chat_id]: какой вариант промпта
+// закреплён за чатом; без аргумента — за чатом администратора
+func (b *Bot) cmdVariant(ctx context.Context, msg *TelegramMessage, args string) error {
+    variants := b.Config.PromptVariants
+    if len(variants) == 0 {
+        return apperr.Validation("A/B-тест промптов выключен: PROMPT_VARIANTS не задан.")
+    }
+    chatID := msg.Chat.ID
+    if args = strings.TrimSpace(args); args != "" {
+        id, err := strconv.ParseInt(args, 10, 64)
+        if err != nil {
+            return apperr.Validation("Использование: /variant [chat_id]")
+        }
+        chatID = id
+    }
+
+    u, err := b.profile(ctx, chatID)
+    if err != nil && !errors.Is(err, storage.ErrNotFound) {
+        return apperr.WithMessage(err, "Не удалось прочитать профиль чата, попробуйте позже.")
+    }
+    b.reply(ctx, msg.Chat.ID, renderVariant(chatID, u.PromptVariant, variants))
+    return nil
+}
+
+// renderVariant — ответ /variant: закреплённый вариант и все варианты теста
+func renderVariant(chatID int64, assigned string, variants []config.PromptVariant) string {
+    var sb strings.Builder
+    if knownVariant(variants, assigned) {
+        fmt.Fprintf(&sb, "Чат %d: вариант %s.", chatID, assigned)
+    } else {
+        fmt.Fprintf(&sb, "Чат %d: вариант ещё не закреплён, с первым сообщением будет %s.", chatID, config.PickPromptVariant(variants, chatID))
+    }
+    sb.WriteString("\n\nВарианты:")
+    for _, v := range variants {
+        prompt := "свой промпт"
+        if v.Prompt == "" {
+            prompt = "базовый промпт"
+        }
+        fmt.Fprintf(&sb, "\n• %s — вес %d, %s", v.Name, v.Weight, prompt)
+    }
+    return sb.String()
+}
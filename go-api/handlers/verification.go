@@ -0,0 +1,199 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/logging"
+    "ai_seller/storage"
+    "ai_seller/telegram"
+)
+
+const (
+    // verifyAction — action кнопки проверки участника, callback_data "verify:<user id>"
+    verifyAction = "verify"
+    // challengeSweepInterval — как часто удалять не нажавших кнопку
+    challengeSweepInterval = 10 * time.Second
+    // challengeBatch — сколько просроченных проверок забирать за один запрос
+    challengeBatch = 50
+    // kickBanDuration — бан не прошедшего проверку: короче 30 секунд Telegram
+    // считает вечным, а через минуту человек может вернуться и пройти проверку
+    kickBanDuration = time.Minute
+    // challengeRetryDelay — через сколько повторить удаление участника,
+    // если Telegram отказал в бане
+    challengeRetryDelay = time.Minute
+)
+
+// challengeNewMembers ограничивает новых участников группы с включённой
+// проверкой (/verifymembers) и просит каждого нажать кнопку. Боты и
+// админы бота, а также участники, которых добавил админ бота, проверку
+// не проходят. Ошибки только логируются: служебное сообщение повторять незачем.
+func (b *Bot) challengeNewMembers(ctx context.Context, msg *TelegramMessage) {
+    if b.Challenges == nil {
+        return
+    }
+    chatID := msg.Chat.ID
+    on, err := b.Chats.MemberVerification(ctx, chatID)
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка чтения настройки проверки участников", "chat_id", chatID, "err", err)
+        return
+    }
+    if !on {
+        return
+    }
+    addedByAdmin := msg.From != nil && b.Config.IsAdmin(msg.From.ID)
+    for _, u := range msg.NewChatMembers {
+        if u.IsBot || b.Config.IsAdmin(u.ID) || (addedByAdmin && msg.From.ID != u.ID) {
+            continue
+        }
+        b.challengeMember(ctx, chatID, u)
+    }
+}
+
+// challengeMember запрещает участнику писать и отправляет кнопку проверки.
+// Если проверку не удалось начать, ограничение снимается: без кнопки
+// участник не смог бы его снять сам.
+func (b *Bot) challengeMember(ctx context.Context, chatID int64, u TelegramUser) {
+    log := logging.FromContext(ctx).With("chat_id", chatID, "user_id", u.ID)
+    if err := b.Telegram.RestrictChatMember(chatID, u.ID, false); err != nil {
+        log.Error("не удалось ограничить нового участника: бот должен быть администратором группы", "err", err)
+        return
+    }
+
+    timeout := b.Config.MemberVerifyTimeout
+    text := fmt.Sprintf("👋 %s, добро пожаловать! Чтобы писать в группе, нажмите кнопку ниже в течение %d мин. — так мы защищаемся от спам-ботов.",
+        u.FullName(), max(1, int(timeout.Round(time.Minute)/time.Minute)))
+    kb := telegram.NewInlineKeyboard().Row(telegram.CallbackButton("✅ Я не бот", fmt.Sprintf("%s:%d", verifyAction, u.ID)))
+    messageID, err := b.Telegram.SendMessage(chatID, text, telegram.WithReplyMarkup(kb), telegram.WithParseMode(""))
+    if err == nil {
+        err = b.Challenges.Create(ctx, storage.MemberChallenge{
+            ChatID: chatID, UserID: u.ID, MessageID: messageID, ExpiresAt: time.Now().Add(timeout),
+        })
+        if err != nil && messageID != 0 {
+            b.Telegram.DeleteMessage(chatID, messageID)
+        }
+    }
+    if err != nil {
+        log.Error("не удалось начать проверку участника, ограничение снято", "err", err)
+        if err := b.Telegram.RestrictChatMember(chatID, u.ID, true); err != nil {
+            log.Error("ошибка снятия ограничения с участника", "err", err)
+        }
+        return
+    }
+    log.Info("новый участник ограничен до проверки", "timeout", timeout)
+}
+
+// cbVerify — нажатие кнопки проверки: снимает ограничение и убирает кнопку.
+// Чужие нажатия и нажатия после удаления по таймауту пропускаются.
+func (b *Bot) cbVerify(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    userID, err := strconv.ParseInt(payload, 10, 64)
+    if err != nil {
+        return fmt.Errorf("некорректный участник проверки %q: %w", payload, err)
+    }
+    if b.Challenges == nil || cq.From == nil || cq.From.ID != userID {
+        return nil
+    }
+    chatID := cq.Message.Chat.ID
+    c, err := b.Challenges.Resolve(ctx, chatID, userID)
+    if errors.Is(err, storage.ErrNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if err := b.Telegram.RestrictChatMember(chatID, userID, true); err != nil {
+        return fmt.Errorf("ошибка снятия ограничения с участника %d: %w", userID, err)
+    }
+    if err := b.Telegram.DeleteMessage(chatID, c.MessageID); err != nil {
+        logging.FromContext(ctx).Warn("не удалось удалить кнопку проверки", "chat_id", chatID, "err", err)
+    }
+    logging.FromContext(ctx).Info("участник прошёл проверку", "chat_id", chatID, "user_id", userID)
+    return nil
+}
+
+// RunMemberChallenges раз в challengeSweepInterval удаляет из групп
+// участников, не нажавших кнопку проверки вовремя. Работает до отмены ctx.
+func (b *Bot) RunMemberChallenges(ctx context.Context) {
+    ticker := time.NewTicker(challengeSweepInterval)
+    defer ticker.Stop()
+    for {
+        b.expireChallenges(ctx, time.Now())
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// expireChallenges удаляет участников с истёкшей к now проверкой. ClaimExpired
+// снимает проверку сразу, поэтому при отказе Telegram она ставится обратно
+// со сроком через challengeRetryDelay: иначе участник остался бы
+// ограниченным навсегда с кнопкой, которая уже ничего не делает.
+func (b *Bot) expireChallenges(ctx context.Context, now time.Time) {
+    for ctx.Err() == nil {
+        expired, err := b.Challenges.ClaimExpired(ctx, now, challengeBatch)
+        if err != nil {
+            logging.FromContext(ctx).Error("ошибка выборки просроченных проверок", "err", err)
+            return
+        }
+        for _, c := range expired {
+            log := logging.FromContext(ctx).With("chat_id", c.ChatID, "user_id", c.UserID)
+            if err := b.Telegram.BanChatMember(c.ChatID, c.UserID, now.Add(kickBanDuration)); err != nil {
+                log.Error("не удалось удалить участника без проверки, повторим позже", "retry_in", challengeRetryDelay, "err", err)
+                c.ExpiresAt = now.Add(challengeRetryDelay)
+                if err := b.Challenges.Create(context.WithoutCancel(ctx), c); err != nil {
+                    log.Error("проверка участника потеряна: он останется ограниченным", "err", err)
+                }
+                continue
+            }
+            if err := b.Telegram.DeleteMessage(c.ChatID, c.MessageID); err != nil {
+                log.Warn("не удалось удалить кнопку проверки", "err", err)
+            }
+            log.Info("участник удалён: не прошёл проверку вовремя")
+        }
+        if len(expired) < challengeBatch {
+            return
+        }
+    }
+}
+
+// cmdVerifyMembers — команда админа бота в группе /verifymembers on|off:
+// проверка новых участников кнопкой. Права проверяются по отправителю,
+// а не по чату, поэтому команда не админская в смысле RegisterAdminCommand.
+func (b *Bot) cmdVerifyMembers(ctx context.Context, msg *TelegramMessage, args string) error {
+    if msg.From == nil || !b.Config.IsAdmin(msg.From.ID) {
+        b.replyPhrase(ctx, msg.Chat.ID, noAccessReply)
+        return nil
+    }
+    if !msg.Chat.isGroup() {
+        return apperr.Validation("Отправьте команду в группе, где нужна проверка новых участников.")
+    }
+    if b.Challenges == nil {
+        return apperr.Validation("Проверка участников не настроена на сервере.")
+    }
+
+    var on bool
+    switch strings.ToLower(strings.TrimSpace(args)) {
+    case "on":
+        on = true
+    case "off":
+    default:
+        return apperr.Validation("Использование: /verifymembers on|off")
+    }
+    if err := b.Chats.SetMemberVerification(ctx, msg.Chat.ID, on); err != nil {
+        return apperr.WithMessage(err, "Не удалось сохранить настройку, попробуйте позже.")
+    }
+    logging.FromContext(ctx).Info("проверка участников группы переключена", "chat_id", msg.Chat.ID, "on", on, "admin_id", msg.From.ID)
+    if on {
+        b.reply(ctx, msg.Chat.ID, "Проверка новых участников включена: боту нужны права администратора на ограничение и удаление участников.")
+    } else {
+        b.reply(ctx, msg.Chat.ID, "Проверка новых участников выключена.")
+    }
+    return nil
+}
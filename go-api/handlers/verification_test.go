@@ -0,0 +1,179 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+
+    "ai_seller/handlers/mocks"
+    "ai_seller/memstore"
+)
+
+// verificationBot — бот с проверкой новых участников в группе groupID
+func verificationBot(t *testing.T, env map[string]string) (*testBot, *memstore.MemberChallenges) {
+    t.Helper()
+    challenges := &memstore.MemberChallenges{}
+    tb := newTestBot(t, env, func(d *Deps) { d.Challenges = challenges })
+    if err := tb.chats.SetMemberVerification(context.Background(), groupID, true); err != nil {
+        t.Fatal(err)
+    }
+    return tb, challenges
+}
+
+// joined — служебное сообщение: from добавил в группу участников members
+func joined(updateID, from int64, members ...TelegramUser) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, Message: &TelegramMessage{
+        MessageID:      updateID,
+        From:           &TelegramUser{ID: from},
+        Chat:           TelegramChat{ID: groupID, Type: "supergroup"},
+        NewChatMembers: members,
+    }}
+}
+
+// verifyButton — нажатие кнопки проверки участника userID пользователем from
+func verifyButton(updateID, from, userID int64) TelegramUpdate {
+    return TelegramUpdate{UpdateID: updateID, CallbackQuery: &TelegramCallbackQuery{
+        ID:      "cb",
+        From:    &TelegramUser{ID: from, LanguageCode: "ru"},
+        Data:    fmt.Sprintf("%s:%d", verifyAction, userID),
+        Message: &TelegramMessage{MessageID: 1, Chat: TelegramChat{ID: groupID, Type: "supergroup"}},
+    }}
+}
+
+// newcomer — новый участник memberID
+var newcomer = TelegramUser{ID: memberID, FirstName: "Новичок"}
+
+// restricted — ограничен ли участник группы; апдейты обрабатываются
+// синхронно, поэтому фейк читается без блокировки
+func (tb *testBot) restricted(userID int64) bool {
+    return tb.tg.Restricted[groupID][userID]
+}
+
+// banned — удалён ли участник из группы
+func (tb *testBot) banned(userID int64) bool {
+    for _, b := range tb.tg.Banned {
+        if b == [2]int64{groupID, userID} {
+            return true
+        }
+    }
+    return false
+}
+
+func TestNewMemberPassesVerification(t *testing.T) {
+    tb, _ := verificationBot(t, nil)
+    tb.process(t, joined(1, memberID, newcomer))
+    if !tb.restricted(memberID) {
+        t.Fatal("новый участник не ограничен")
+    }
+    if got := tb.sentTo(groupID); len(got) != 1 || !strings.Contains(got[0], "Новичок") {
+        t.Fatalf("в группу отправлено %q, ожидалась кнопка проверки", got)
+    }
+
+    // Чужое нажатие ограничение не снимает
+    tb.process(t, verifyButton(2, 8, memberID))
+    if !tb.restricted(memberID) {
+        t.Fatal("ограничение снято чужим нажатием")
+    }
+    tb.process(t, verifyButton(3, memberID, memberID))
+    if tb.restricted(memberID) {
+        t.Fatal("ограничение не снято после нажатия")
+    }
+    if got := tb.sentTo(groupID); len(got) != 0 {
+        t.Fatalf("кнопка проверки осталась: %q", got)
+    }
+
+    tb.expireChallenges(context.Background(), time.Now().Add(time.Hour))
+    if tb.banned(memberID) {
+        t.Fatal("прошедший проверку участник удалён по таймауту")
+    }
+}
+
+func TestUnverifiedMemberKickedOnTimeout(t *testing.T) {
+    tb, _ := verificationBot(t, map[string]string{"MEMBER_VERIFY_TIMEOUT": "2m"})
+    tb.process(t, joined(1, memberID, newcomer))
+
+    tb.expireChallenges(context.Background(), time.Now().Add(time.Minute))
+    if tb.banned(memberID) {
+        t.Fatal("участник удалён до истечения проверки")
+    }
+    tb.expireChallenges(context.Background(), time.Now().Add(3*time.Minute))
+    if !tb.banned(memberID) {
+        t.Fatal("не нажавший кнопку участник не удалён")
+    }
+    if got := tb.sentTo(groupID); len(got) != 0 {
+        t.Fatalf("кнопка проверки осталась после удаления: %q", got)
+    }
+
+    // Нажатие после удаления ничего не снимает
+    tb.process(t, verifyButton(2, memberID, memberID))
+    if !tb.restricted(memberID) {
+        t.Fatal("ограничение снято нажатием после таймаута")
+    }
+}
+
+// banFails — Telegram, который отказывает в бане
+type banFails struct {
+    *mocks.Telegram
+}
+
+func (banFails) BanChatMember(chatID, userID int64, until time.Time) error {
+    return errors.New("Bad Request: not enough rights to restrict/unrestrict chat member")
+}
+
+func TestFailedKickRetried(t *testing.T) {
+    tb, challenges := verificationBot(t, nil)
+    tb.process(t, joined(1, memberID, newcomer))
+
+    tb.Telegram = banFails{tb.tg}
+    now := time.Now().Add(time.Hour)
+    tb.expireChallenges(context.Background(), now)
+    if tb.banned(memberID) {
+        t.Fatal("бан не должен был пройти")
+    }
+    if expired, _ := challenges.ClaimExpired(context.Background(), now, 10); len(expired) != 0 {
+        t.Fatalf("проверка повторяется раньше challengeRetryDelay: %+v", expired)
+    }
+
+    tb.Telegram = tb.tg
+    tb.expireChallenges(context.Background(), now.Add(challengeRetryDelay))
+    if !tb.banned(memberID) {
+        t.Fatal("после отказа Telegram участник так и не удалён")
+    }
+}
+
+func TestVerificationExemptions(t *testing.T) {
+    const admin int64 = 500
+    env := map[string]string{"ADMIN_CHAT_IDS": fmt.Sprint(admin)}
+    tests := []struct {
+        name   string
+        update TelegramUpdate
+        userID int64
+    }{
+        {"админ бота", joined(1, admin, TelegramUser{ID: admin}), admin},
+        {"добавлен админом", joined(1, admin, newcomer), memberID},
+        {"бот", joined(1, memberID, TelegramUser{ID: 900, IsBot: true}), 900},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tb, _ := verificationBot(t, env)
+            tb.process(t, tt.update)
+            if tb.restricted(tt.userID) || len(tb.sentTo(groupID)) != 0 {
+                t.Fatal("освобождённый от проверки участник ограничен")
+            }
+        })
+    }
+}
+
+func TestVerificationOffByDefault(t *testing.T) {
+    tb, _ := verificationBot(t, nil)
+    if err := tb.chats.SetMemberVerification(context.Background(), groupID, false); err != nil {
+        t.Fatal(err)
+    }
+    tb.process(t, joined(1, memberID, newcomer))
+    if tb.restricted(memberID) {
+        t.Fatal("участник ограничен в группе без проверки")
+    }
+}
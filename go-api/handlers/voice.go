@@ -0,0 +1,85 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "time"
+
+    "ai_seller/config"
+    "ai_seller/llm"
+    "ai_seller/logging"
+)
+
+const (
+    // voiceDisabledReply — ответ, если распознавание голосовых выключено
+    voiceDisabledReply = "К сожалению, я пока не умею слушать голосовые. Напишите, пожалуйста, ваш вопрос текстом."
+    // voiceTooLongReply — ответ на голосовое длиннее VOICE_MAX_DURATION
+    voiceTooLongReply = "Голосовое слишком длинное. Запишите, пожалуйста, сообщение покороче или напишите текстом."
+    // voiceRetryReply — ответ, если голосовое не удалось скачать или распознать
+    voiceRetryReply = "Не получилось разобрать голосовое. Пожалуйста, отправьте его ещё раз или напишите текстом."
+)
+
+// TelegramVoice — голосовое сообщение (OGG/Opus)
+type TelegramVoice struct {
+    FileID   string `json:"file_id"`
+    Duration int    `json:"duration"`
+    MimeType string `json:"mime_type"`
+    FileSize int64  `json:"file_size"`
+}
+
+// handleVoice распознаёт голосовое и отвечает на расшифровку как на обычный текст
+func (b *Bot) handleVoice(ctx context.Context, msg *TelegramMessage) {
+    chatID := msg.Chat.ID
+    if !b.featureEnabled(ctx, config.FlagVoice) {
+        b.replyPhrase(ctx, chatID, voiceDisabledReply)
+        return
+    }
+    if time.Duration(msg.Voice.Duration)*time.Second > b.Config.VoiceMaxDuration {
+        logging.FromContext(ctx).Info("голосовое длиннее лимита", "chat_id", chatID, "duration", msg.Voice.Duration)
+        b.replyPhrase(ctx, chatID, voiceTooLongReply)
+        return
+    }
+    // Вне часов работы модель не отвечает — распознавать незачем
+    if b.offHoursMode(time.Now()) == "ack" {
+        b.ackOffHours(ctx, chatID, "")
+        return
+    }
+    // Распознавание тоже расходует бюджет OpenAI
+    if b.overBudget(ctx) {
+        b.replyPhrase(ctx, chatID, overBudgetReply)
+        return
+    }
+
+    // Голосовые Telegram — Opus в контейнере OGG: по содержимому это application/ogg
+    data, _, err := b.downloadFile(ctx, msg.Voice.FileID, "audio/", "application/ogg")
+    if err != nil {
+        logging.FromContext(ctx).Error("ошибка скачивания голосового", "chat_id", chatID, "file_id", msg.Voice.FileID, "err", err)
+        b.replyPhrase(ctx, chatID, voiceRetryReply)
+        return
+    }
+
+    stopTyping := b.keepTyping(ctx, chatID)
+    transcript, err := b.OpenAI.Transcribe(ctx, data, "voice.ogg")
+    stopTyping()
+    if errors.Is(err, llm.ErrUnsupported) {
+        logging.FromContext(ctx).Warn("поставщик модели не распознаёт речь", "chat_id", chatID)
+        b.replyPhrase(ctx, chatID, voiceDisabledReply)
+        return
+    }
+    if err != nil {
+        b.aiUnavailable(ctx, chatID, err)
+        return
+    }
+    transcript = strings.TrimSpace(transcript)
+    if transcript == "" {
+        b.replyPhrase(ctx, chatID, voiceRetryReply)
+        return
+    }
+    logging.FromContext(ctx).Info("голосовое распознано", "chat_id", chatID, "duration", msg.Voice.Duration)
+
+    text := *msg
+    text.Text = transcript
+    text.Voice = nil
+    b.replyWithAI(ctx, &text)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+)
+
+// post отправляет в webhook тело body с Content-Type contentType и
+// возвращает код ответа
+func post(tb *testBot, method, contentType, body string) int {
+    r := httptest.NewRequest(method, "/webhook", strings.NewReader(body))
+    if contentType != "" {
+        r.Header.Set("Content-Type", contentType)
+    }
+    w := httptest.NewRecorder()
+    tb.TelegramHandler(w, r)
+    return w.Code
+}
+
+func TestWebhookContentType(t *testing.T) {
+    update, err := json.Marshal(text(1, 42, "привет"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    form := url.Values{"update": {string(update)}}.Encode()
+
+    cases := []struct {
+        name        string
+        method      string
+        contentType string
+        body        string
+        want        int
+        answered    bool
+    }{
+        {"JSON", http.MethodPost, "application/json", string(update), http.StatusOK, true},
+        {"JSON с кодировкой", http.MethodPost, "application/json; charset=utf-8", string(update), http.StatusOK, true},
+        {"форма", http.MethodPost, "application/x-www-form-urlencoded", form, http.StatusUnsupportedMediaType, false},
+        {"JSON под чужим типом", http.MethodPost, "text/plain", string(update), http.StatusUnsupportedMediaType, false},
+        {"без Content-Type", http.MethodPost, "", string(update), http.StatusUnsupportedMediaType, false},
+        {"GET", http.MethodGet, "application/json", "", http.StatusMethodNotAllowed, false},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            tb := newTestBot(t, nil)
+            if got := post(tb, tc.method, tc.contentType, tc.body); got != tc.want {
+                t.Fatalf("код %d, нужно %d", got, tc.want)
+            }
+            if answered := len(tb.sentTo(42)) > 0; answered != tc.answered {
+                t.Fatalf("покупателю отправлено %q", tb.sentTo(42))
+            }
+        })
+    }
+}
+
+// endlessJSON — бесконечное тело запроса, начинающееся как апдейт; read —
+// сколько байт из него прочитали
+type endlessJSON struct {
+    prefix string
+    read   int64
+}
+
+func (e *endlessJSON) Read(p []byte) (int, error) {
+    n := 0
+    for n < len(p) {
+        if e.read < int64(len(e.prefix)) {
+            p[n] = e.prefix[e.read]
+        } else {
+            p[n] = 'a'
+        }
+        n++
+        e.read++
+    }
+    return n, nil
+}
+
+// Тело больше WEBHOOK_MAX_BODY_BYTES отклоняется с 413, и дальше лимита
+// оно не читается
+func TestWebhookBodyLimit(t *testing.T) {
+    const limit = 4096
+    tb := newTestBot(t, map[string]string{"WEBHOOK_MAX_BODY_BYTES": "4096"})
+
+    body := &endlessJSON{prefix: `{"update_id":1,"message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"`}
+    r := httptest.NewRequest(http.MethodPost, "/webhook", body)
+    r.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    tb.TelegramHandler(w, r)
+    if w.Code != http.StatusRequestEntityTooLarge {
+        t.Fatalf("код %d, нужно 413", w.Code)
+    }
+    if body.read > 2*limit {
+        t.Fatalf("прочитано %d байт при лимите %d", body.read, limit)
+    }
+    if got := tb.sentTo(42); len(got) != 0 {
+        t.Fatalf("слишком большой апдейт обработан: %q", got)
+    }
+
+    update, err := json.Marshal(text(2, 42, strings.Repeat("а", limit/4)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if code := post(tb, http.MethodPost, "application/json", string(update)); code != http.StatusOK {
+        t.Fatalf("апдейт в пределах лимита: код %d", code)
+    }
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+    "context"
+
+    "ai_seller/i18n"
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/telegram"
+)
+
+// categoryAction — action кнопки категории, callback_data "cat:<категория>"
+const categoryAction = "cat"
+
+// categoryProductsLimit — сколько карточек товаров показать по кнопке категории
+const categoryProductsLimit = 3
+
+// emptyCategoryReply — ответ, если в категории ничего не нашлось
+const emptyCategoryReply = "В этой категории пока ничего нет. Расскажите, что вы ищете, — подберу похожее."
+
+// cmdStart — приветствие: при первом контакте WELCOME_MESSAGE с кнопками
+// категорий, при повторном — короткое WELCOME_BACK_MESSAGE.
+// Параметр из ссылки t.me/<бот>?start=<параметр> разбирается
+// в followStartLink; неизвестный параметр — обычное приветствие.
+// Профиль покупателя к этому моменту уже сохранён в handleMessage.
+func (b *Bot) cmdStart(ctx context.Context, msg *TelegramMessage, args string) error {
+    chatID := msg.Chat.ID
+    seen, err := b.Messages.HasMessages(ctx, chatID)
+    if err != nil {
+        // Лучше поприветствовать вернувшегося покупателя полностью, чем промолчать
+        logging.FromContext(ctx).Error("ошибка проверки первого контакта", "chat_id", chatID, "err", err)
+    }
+
+    lang := reqctx.LangFromContext(ctx)
+    text := b.Config.WelcomeMessage
+    if seen {
+        text = b.Config.WelcomeBackMessage
+    }
+    // У сообщения одна клавиатура: при кнопках категорий меню приходит
+    // следующим сообщением
+    var categories *telegram.InlineKeyboard
+    if !seen {
+        categories = b.welcomeKeyboard()
+    }
+    var menu *telegram.ReplyKeyboard
+    if !b.menuHidden(ctx, chatID) {
+        menu = b.menuKeyboard(lang)
+    }
+
+    var opts []telegram.SendOption
+    switch {
+    case categories != nil:
+        opts = append(opts, telegram.WithReplyMarkup(categories))
+    case menu != nil:
+        opts = append(opts, telegram.WithReplyMarkup(menu))
+    }
+    b.send(ctx, chatID, i18n.T(lang, text), opts...)
+    if categories != nil && menu != nil {
+        b.send(ctx, chatID, i18n.T(lang, menuShownReply), telegram.WithReplyMarkup(menu))
+    }
+    // Приветствие попадает в историю: следующий /start — уже не первый контакт
+    b.remember(ctx, chatID, "assistant", text)
+    b.followStartLink(ctx, chatID, parseStartLink(args, b.Config.CampaignCodes), seen)
+    return nil
+}
+
+// welcomeKeyboard — кнопки категорий из WELCOME_CATEGORIES, по две в ряд
+func (b *Bot) welcomeKeyboard() *telegram.InlineKeyboard {
+    categories := b.Config.WelcomeCategories
+    if len(categories) == 0 {
+        return nil
+    }
+    kb := telegram.NewInlineKeyboard()
+    for i := 0; i < len(categories); i += 2 {
+        row := []telegram.InlineKeyboardButton{telegram.CallbackButton(categories[i], categoryAction+":"+categories[i])}
+        if i+1 < len(categories) {
+            row = append(row, telegram.CallbackButton(categories[i+1], categoryAction+":"+categories[i+1]))
+        }
+        kb.Row(row...)
+    }
+    return kb
+}
+
+// cbCategory — кнопка категории из приветствия: показывает первые товары категории
+func (b *Bot) cbCategory(ctx context.Context, cq *TelegramCallbackQuery, payload string) error {
+    chatID := cq.Message.Chat.ID
+    products, err := b.searchProducts(ctx, payload)
+    if err != nil {
+        return err
+    }
+    if len(products) == 0 {
+        b.replyPhrase(ctx, chatID, emptyCategoryReply)
+        return nil
+    }
+    for _, p := range products[:min(len(products), categoryProductsLimit)] {
+        b.showProduct(ctx, chatID, p)
+    }
+    return nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+    "context"
+    "testing"
+
+    "ai_seller/telegram"
+)
+
+func TestStartFirstContact(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"WELCOME_CATEGORIES": "Чай,Посуда,Сладости"})
+    tb.process(t, text(1, 42, "/start"))
+
+    sent := tb.tg.Messages()
+    if len(sent) != 2 || sent[0].Text != tb.Config.WelcomeMessage || sent[1].Text != menuShownReply {
+        t.Fatalf("отправлено %+v, нужны приветствие и меню", sent)
+    }
+    kb, ok := sent[0].Markup.(*telegram.InlineKeyboard)
+    if !ok {
+        t.Fatalf("у приветствия клавиатура %T, нужны кнопки категорий", sent[0].Markup)
+    }
+    if len(kb.Rows) != 2 || len(kb.Rows[0]) != 2 || kb.Rows[1][0].Text != "Сладости" || kb.Rows[0][0].CallbackData != categoryAction+":Чай" {
+        t.Fatalf("кнопки категорий %+v", kb.Rows)
+    }
+    if _, ok := sent[1].Markup.(*telegram.ReplyKeyboard); !ok {
+        t.Fatalf("меню с клавиатурой %T", sent[1].Markup)
+    }
+    if _, err := tb.users.GetUser(context.Background(), 42); err != nil {
+        t.Fatalf("профиль покупателя не создан: %v", err)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("/start ушёл модели: %d запросов", len(tb.ai.Requests))
+    }
+}
+
+func TestStartReturningUser(t *testing.T) {
+    tb := newTestBot(t, map[string]string{"WELCOME_CATEGORIES": "Чай,Посуда"})
+    tb.process(t, text(1, 42, "/start"))
+    first := len(tb.tg.Messages())
+
+    tb.process(t, text(2, 42, "/start"))
+    again := tb.tg.Messages()[first:]
+    if len(again) != 1 || again[0].Text != tb.Config.WelcomeBackMessage {
+        t.Fatalf("повторный /start: %+v", again)
+    }
+    if _, ok := again[0].Markup.(*telegram.ReplyKeyboard); !ok {
+        t.Fatalf("повторное приветствие с клавиатурой %T, нужно только меню", again[0].Markup)
+    }
+}
+
+// Покупатель, который уже писал боту, — не первый контакт, даже без /start
+func TestStartAfterConversation(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.process(t, text(1, 42, "есть улун?"))
+    tb.process(t, text(2, 42, "/start"))
+    if got := tb.lastSent(t, 42); got != tb.Config.WelcomeBackMessage {
+        t.Fatalf("после переписки на /start: %q", got)
+    }
+}
+
+func TestStartWithoutCategoriesOrMenu(t *testing.T) {
+    tb := newTestBot(t, nil)
+    // /menu off в переписке сделал бы контакт не первым — прячем меню в профиле
+    if err := tb.users.SetMenuHidden(context.Background(), 43, true); err != nil {
+        t.Fatal(err)
+    }
+    tb.process(t, text(1, 43, "/start"))
+    sent := tb.sentTo(43)
+    if len(sent) != 1 || sent[0] != tb.Config.WelcomeMessage {
+        t.Fatalf("отправлено %q", sent)
+    }
+    for _, m := range tb.tg.Messages() {
+        if m.ChatID == 43 && m.Markup != nil {
+            t.Fatalf("приветствие с клавиатурой %T при скрытом меню и без категорий", m.Markup)
+        }
+    }
+}
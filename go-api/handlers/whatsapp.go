@@ -1,4 +1,3 @@
 package handlers
 
 // TODO: Реализовать WhatsApp handler позже
-
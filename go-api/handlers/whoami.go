@@ -0,0 +1,110 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+)
+
+// whoami — что бот знает о чате и учитывает при ответах
+type whoami struct {
+    ChatID int64
+    // Lang — язык, на котором бот отвечает
+    Lang string
+    // TelegramLang — язык интерфейса Telegram; PreferredLang — выбранный через /lang
+    TelegramLang  string
+    PreferredLang string
+    Admin         bool
+    Brief         bool
+    // CartItems и Messages — -1, если хранилище недоступно
+    CartItems int
+    Messages  int
+}
+
+// cmdWhoami — команда /whoami: что бот знает о собеседнике, для разбора
+// обращений в поддержку. Модель не вызывается.
+func (b *Bot) cmdWhoami(ctx context.Context, msg *TelegramMessage, args string) error {
+    b.reply(ctx, msg.Chat.ID, renderWhoami(b.whoami(ctx, msg)))
+    return nil
+}
+
+// whoami собирает сведения о чате; недоступное хранилище не мешает
+// показать остальное
+func (b *Bot) whoami(ctx context.Context, msg *TelegramMessage) whoami {
+    chatID := msg.Chat.ID
+    log := logging.FromContext(ctx).With("chat_id", chatID)
+    w := whoami{
+        ChatID:       chatID,
+        Lang:         reqctx.LangFromContext(ctx),
+        TelegramLang: msg.lang(),
+        Admin:        b.Config.IsAdmin(chatID),
+        CartItems:    -1,
+        Messages:     -1,
+    }
+
+    if u, err := b.profile(ctx, chatID); err == nil {
+        w.PreferredLang = u.PreferredLang
+        w.Brief = u.Brief
+    } else if !errors.Is(err, storage.ErrNotFound) {
+        log.Warn("ошибка чтения профиля для /whoami", "err", err)
+    }
+    // В группе язык выбирает каждый участник для себя
+    if owner := langOwner(msg.Chat, msg.From); owner != chatID {
+        w.PreferredLang = ""
+        if u, err := b.profile(ctx, owner); err == nil {
+            w.PreferredLang = u.PreferredLang
+        } else if !errors.Is(err, storage.ErrNotFound) {
+            log.Warn("ошибка чтения языка участника для /whoami", "err", err)
+        }
+    }
+    if cart, err := b.Carts.GetCart(ctx, chatID); err == nil {
+        w.CartItems = len(cart.Items)
+    } else {
+        log.Warn("ошибка чтения корзины для /whoami", "err", err)
+    }
+    if n, err := b.Messages.CountMessages(ctx, chatID); err == nil {
+        w.Messages = n
+    } else {
+        log.Warn("ошибка подсчёта сообщений для /whoami", "err", err)
+    }
+    return w
+}
+
+// renderWhoami — ответ /whoami
+func renderWhoami(w whoami) string {
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "Чат: %d\n", w.ChatID)
+    fmt.Fprintf(&sb, "Язык ответов: %s (Telegram: %s, /lang: %s)\n", w.Lang, w.TelegramLang, orDash(w.PreferredLang))
+    fmt.Fprintf(&sb, "Администратор: %s\n", yesNo(w.Admin))
+    fmt.Fprintf(&sb, "Краткие ответы: %s\n", yesNo(w.Brief))
+    fmt.Fprintf(&sb, "Позиций в корзине: %s\n", countOrUnknown(w.CartItems))
+    fmt.Fprintf(&sb, "Сообщений в истории: %s", countOrUnknown(w.Messages))
+    return sb.String()
+}
+
+func yesNo(v bool) string {
+    if v {
+        return "да"
+    }
+    return "нет"
+}
+
+func orDash(s string) string {
+    if s == "" {
+        return "—"
+    }
+    return s
+}
+
+// countOrUnknown — число или пометка, что хранилище не ответило
+func countOrUnknown(n int) string {
+    if n < 0 {
+        return "недоступно"
+    }
+    return fmt.Sprint(n)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+
+    "ai_seller/cache"
+    "ai_seller/memstore"
+)
+
+func TestWhoami(t *testing.T) {
+    ctx := context.Background()
+    tb := newTestBot(t, map[string]string{"ADMIN_CHAT_IDS": "42"})
+    tb.process(t, text(1, 42, "/lang en"))
+    if err := tb.users.SetBrief(ctx, 42, true); err != nil {
+        t.Fatal(err)
+    }
+    for _, id := range []int64{1, 2} {
+        if err := tb.carts.AddItem(ctx, 42, id, 1); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for _, body := range []string{"есть улун?", "сколько стоит?", "беру"} {
+        if err := tb.messages.SaveMessage(ctx, 42, "user", body); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tb.process(t, text(2, 42, "/whoami"))
+    want := "Чат: 42\n" +
+        "Язык ответов: en (Telegram: ru, /lang: en)\n" +
+        "Администратор: да\n" +
+        "Краткие ответы: да\n" +
+        "Позиций в корзине: 2\n" +
+        "Сообщений в истории: 3"
+    if got := tb.lastSent(t, 42); got != want {
+        t.Fatalf("/whoami:\n%s\nнужно:\n%s", got, want)
+    }
+    if len(tb.ai.Requests) != 0 {
+        t.Fatalf("/whoami обратился к модели: %d запросов", len(tb.ai.Requests))
+    }
+}
+
+// brokenCarts — корзины, которые не читаются
+type brokenCarts struct{ *memstore.Carts }
+
+func (brokenCarts) GetCart(ctx context.Context, chatID int64) (cache.Cart, error) {
+    return cache.Cart{}, errors.New("redis недоступен")
+}
+
+// Недоступное хранилище не мешает показать остальное; покупатель без
+// профиля видит язык Telegram
+func TestWhoamiWithoutProfileAndCart(t *testing.T) {
+    tb := newTestBot(t, nil, func(d *Deps) { d.Carts = brokenCarts{&memstore.Carts{}} })
+
+    tb.process(t, text(1, 42, "/whoami"))
+    got := tb.lastSent(t, 42)
+    for _, want := range []string{
+        "Язык ответов: ru (Telegram: ru, /lang: —)",
+        "Администратор: нет",
+        "Краткие ответы: нет",
+        "Позиций в корзине: недоступно",
+        "Сообщений в истории: 0",
+    } {
+        if !strings.Contains(got, want) {
+            t.Errorf("в ответе нет %q:\n%s", want, got)
+        }
+    }
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/metrics"
+    "ai_seller/queue"
+)
+
+// updateQueue — очередь апдейтов вебхука с фиксированным числом обработчиков.
+// Ограничивает число одновременных запросов к OpenAI и отпускает Telegram
+// сразу после приёма апдейта. С очередью в Redis апдейт, принятый одной
+// репликой, может разобрать любая.
+type updateQueue struct {
+    q  queue.Queue
+    wg sync.WaitGroup
+    // inFlight — апдейты в обработке; handled — обработанные с запуска
+    inFlight atomic.Int64
+    handled  atomic.Int64
+}
+
+// redeliveryKey — ключ контекста: апдейт можно вернуть в очередь
+type redeliveryKey struct{}
+
+// errRedeliver — апдейт не обработан и не записан в журнал: обработчик
+// очереди вернёт его в очередь
+var errRedeliver = errors.New("апдейт будет доставлен повторно")
+
+// withRedelivery помечает, что апдейт пришёл из очереди и его можно вернуть
+func withRedelivery(ctx context.Context) context.Context {
+    return context.WithValue(ctx, redeliveryKey{}, true)
+}
+
+// redeliverable — вызывающий доставит апдейт повторно, если он вернёт errRedeliver
+func redeliverable(ctx context.Context) bool {
+    v, _ := ctx.Value(redeliveryKey{}).(bool)
+    return v
+}
+
+// StartWorkers переводит вебхук на асинхронную обработку: апдейты попадают
+// в q и разбираются workers горутинами.
+// Сообщение подтверждается, если апдейт обработан или его ошибка записана в
+// журнал необработанных (повтор из очереди прислал бы покупателю ответы
+// второй раз). Апдейт, который не начали обрабатывать из-за занятого чата,
+// ставится в конец очереди, чтобы обработчик не простаивал.
+// Вызывается один раз до запуска сервера; остановка — StopWorkers.
+func (b *Bot) StartWorkers(q queue.Queue, workers int) {
+    uq := &updateQueue{q: q}
+    // Запрос вебхука к этому моменту уже завершён, поэтому контекст свой;
+    // дедлайн на апдейт задаёт ProcessUpdate
+    ctx := context.Background()
+    handle := func(ctx context.Context, payload []byte) error {
+        ctx = withRedelivery(ctx)
+        var update TelegramUpdate
+        if err := json.Unmarshal(payload, &update); err != nil {
+            // Битый апдейт повторная доставка не исправит — подтверждаем и пропускаем
+            logging.Logger().Error("ошибка разбора апдейта из очереди", "err", err)
+            return nil
+        }
+        uq.inFlight.Add(1)
+        defer uq.inFlight.Add(-1)
+        defer uq.handled.Add(1)
+        if err := b.processRecovering(ctx, update); errors.Is(err, errRedeliver) {
+            return uq.requeue(ctx, update, payload)
+        }
+        return nil
+    }
+    for i := range workers {
+        uq.wg.Add(1)
+        consumer := consumerName(i)
+        go func() {
+            defer uq.wg.Done()
+            q.Consume(ctx, consumer, handle)
+        }()
+    }
+    b.queue = uq
+    logging.Logger().Info("запущены обработчики апдейтов", "workers", workers)
+}
+
+// StopWorkers перестаёт принимать апдейты и ждёт, пока обработчики
+// доработают текущие, но не дольше grace. Вызывается после остановки
+// HTTP-сервера. Брошенные апдейты из Redis доставятся повторно другой
+// реплике, из очереди в памяти — пропадут. Фоновые задачи команд (см.
+// detach) отменяются сразу.
+func (b *Bot) StopWorkers(grace time.Duration) {
+    b.stop()
+    q := b.queue
+    if q == nil {
+        return
+    }
+    if err := q.q.Close(); err != nil {
+        logging.Logger().Error("ошибка закрытия очереди апдейтов", "err", err)
+    }
+    before := q.handled.Load()
+    logging.Logger().Info("ожидание обработки очереди апдейтов", "in_flight", q.inFlight.Load(), "grace", grace)
+
+    done := make(chan struct{})
+    go func() {
+        q.wg.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        logging.Logger().Info("обработчики апдейтов остановлены", "drained", q.handled.Load()-before, "abandoned", 0)
+    case <-time.After(grace):
+        logging.Logger().Warn("обработчики апдейтов не успели доработать",
+            "drained", q.handled.Load()-before, "abandoned", q.inFlight.Load())
+    }
+}
+
+// detach — контекст задачи команды, которая переживает апдейт: значения
+// ctx (логгер, trace_id) сохраняются, а отменяет его остановка бота
+func (b *Bot) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+    ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+    unwatch := context.AfterFunc(b.stopping, cancel)
+    return ctx, func() {
+        unwatch()
+        cancel()
+    }
+}
+
+// enqueue ставит апдейт в очередь не блокируясь: при переполнении апдейт
+// отбрасывается, иначе Telegram ждал бы ответа и слал повторы. Ошибка —
+// очередь недоступна, и апдейт стоит получить от Telegram повторно.
+func (q *updateQueue) enqueue(ctx context.Context, update TelegramUpdate) error {
+    payload, err := json.Marshal(update)
+    if err != nil {
+        return fmt.Errorf("ошибка сериализации апдейта: %w", err)
+    }
+
+    err = q.q.Publish(ctx, orderKey(update), payload)
+    switch {
+    case errors.Is(err, queue.ErrClosed):
+        logging.FromContext(ctx).Warn("апдейт получен во время остановки, отброшен", "update_id", update.UpdateID)
+        metrics.DroppedUpdatesTotal.Inc()
+        return nil
+    case errors.Is(err, queue.ErrFull):
+        logging.FromContext(ctx).Warn("очередь апдейтов переполнена, апдейт отброшен", "update_id", update.UpdateID)
+        metrics.DroppedUpdatesTotal.Inc()
+        return nil
+    }
+    return err
+}
+
+// requeue ставит апдейт занятого чата в конец очереди, а исходное сообщение
+// подтверждается. Если поставить не удалось, очередь с повторной доставкой
+// получает ошибку и вернёт сообщение сама, из очереди в памяти апдейт
+// пропадает, как при переполнении.
+func (q *updateQueue) requeue(ctx context.Context, update TelegramUpdate, payload []byte) error {
+    err := q.q.Publish(ctx, orderKey(update), payload)
+    if err == nil {
+        return nil
+    }
+    if q.q.Redelivers() {
+        return fmt.Errorf("%w: %w", errRedeliver, err)
+    }
+    logging.FromContext(ctx).Warn("апдейт занятого чата не вернулся в очередь, отброшен", "update_id", update.UpdateID, "err", err)
+    metrics.DroppedUpdatesTotal.Inc()
+    return nil
+}
+
+// orderKey — ключ порядка апдейта в очереди: апдейты одного чата очередь в
+// памяти отдаёт одному обработчику по порядку, и корзина с историей не
+// меняются параллельно
+func orderKey(update TelegramUpdate) string {
+    return strconv.FormatInt(updateChatID(update), 10)
+}
+
+// consumerName — имя обработчика в группе потребителей: уникально в пределах
+// реплики и между репликами, чтобы зависшие сообщения было у кого отобрать
+func consumerName(i int) string {
+    host, err := os.Hostname()
+    if err != nil {
+        host = "bot"
+    }
+    return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/handlers/mocks"
+    "ai_seller/openai"
+    "ai_seller/queue"
+)
+
+// handlerQueue — очередь, отдающая тесту обработчик StartWorkers и
+// запоминающая поставленные сообщения; publishErr — ошибка Publish
+type handlerQueue struct {
+    redelivers bool
+    publishErr error
+    handlers   chan queue.Handler
+    closed     chan struct{}
+    once       sync.Once
+
+    mu        sync.Mutex
+    published [][]byte
+}
+
+func newHandlerQueue(redelivers bool) *handlerQueue {
+    return &handlerQueue{redelivers: redelivers, handlers: make(chan queue.Handler, 1), closed: make(chan struct{})}
+}
+
+func (q *handlerQueue) Publish(ctx context.Context, key string, payload []byte) error {
+    if q.publishErr != nil {
+        return q.publishErr
+    }
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    q.published = append(q.published, payload)
+    return nil
+}
+
+func (q *handlerQueue) Consume(ctx context.Context, consumer string, handle queue.Handler) {
+    q.handlers <- handle
+    <-q.closed
+}
+
+func (q *handlerQueue) Close() error {
+    q.once.Do(func() { close(q.closed) })
+    return nil
+}
+
+func (q *handlerQueue) Redelivers() bool { return q.redelivers }
+
+func (q *handlerQueue) requeued() [][]byte {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return append([][]byte(nil), q.published...)
+}
+
+// busyChatBot — бот с блокировками чатов, у которого чат 42 занят другим
+// апдейтом; release освобождает чат
+func busyChatBot(t *testing.T) (tb *testBot, release func()) {
+    t.Helper()
+    tb = newTestBot(t, map[string]string{"UPDATE_TIMEOUT": "5s", "UPDATE_CLAIM_AFTER": "10s", "CHAT_LOCK_WAIT": "50ms"})
+    tb.Locks = cache.NewChatLocker(tb.rdb, time.Minute)
+    unlock, err := tb.Locks.Lock(context.Background(), 42)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tb, unlock
+}
+
+// overlapAI — модель, отвечающая не сразу и считающая, сколько запросов
+// шло одновременно
+type overlapAI struct {
+    *mocks.OpenAI
+
+    mu       sync.Mutex
+    inFlight int
+    peak     int
+}
+
+func (o *overlapAI) ChatCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    o.mu.Lock()
+    o.inFlight++
+    o.peak = max(o.peak, o.inFlight)
+    o.mu.Unlock()
+    time.Sleep(50 * time.Millisecond)
+    o.mu.Lock()
+    o.inFlight--
+    o.mu.Unlock()
+    return o.OpenAI.ChatCompletion(ctx, messages)
+}
+
+func (o *overlapAI) ChatWithTools(ctx context.Context, messages []openai.Message, tools *openai.ToolRegistry) (string, error) {
+    return o.ChatCompletion(ctx, messages)
+}
+
+// Два апдейта одного чата, пришедшие одновременно, обрабатываются по
+// очереди, а после обоих блокировка снята
+func TestProcessUpdateSerializesChat(t *testing.T) {
+    ai := &overlapAI{OpenAI: &mocks.OpenAI{Reply: "ответ модели"}}
+    tb := newTestBot(t, nil, func(d *Deps) { d.OpenAI = ai })
+    tb.Locks = cache.NewChatLocker(tb.rdb, time.Minute)
+
+    var wg sync.WaitGroup
+    for i := int64(1); i <= 2; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if err := tb.ProcessUpdate(context.Background(), text(i, 42, "есть улун?")); err != nil {
+                t.Errorf("апдейт %d: %v", i, err)
+            }
+        }()
+    }
+    wg.Wait()
+
+    if ai.peak != 1 {
+        t.Fatalf("одновременно к модели шло %d запросов одного чата", ai.peak)
+    }
+    if got := tb.sentTo(42); len(got) != 2 {
+        t.Fatalf("отправлено %q, ожидались два ответа", got)
+    }
+    for _, key := range tb.redis.Keys() {
+        if key == "lock:chat:42" {
+            t.Fatal("блокировка чата осталась после обработки")
+        }
+    }
+}
+
+// Занятый чат не держит обработчик до дедлайна апдейта: после CHAT_LOCK_WAIT
+// апдейт уходит в конец очереди, а после освобождения чата обрабатывается
+func TestQueuedUpdateForBusyChatIsRequeued(t *testing.T) {
+    for _, redelivers := range []bool{true, false} {
+        tb, release := busyChatBot(t)
+        q := newHandlerQueue(redelivers)
+        tb.StartWorkers(q, 1)
+        handle := <-q.handlers
+
+        payload, _ := json.Marshal(text(1, 42, "есть зелёный чай?"))
+        start := time.Now()
+        if err := handle(context.Background(), payload); err != nil {
+            t.Fatalf("занятый чат (redelivers=%v): получено %v, ожидалось подтверждение с возвратом в очередь", redelivers, err)
+        }
+        if waited := time.Since(start); waited > time.Second {
+            t.Fatalf("обработчик ждал чат %s — дольше CHAT_LOCK_WAIT", waited)
+        }
+        requeued := q.requeued()
+        if len(requeued) != 1 || string(requeued[0]) != string(payload) {
+            t.Fatalf("в очередь вернулось %q, ожидался исходный апдейт", requeued)
+        }
+        if got := tb.sentTo(42); len(got) != 0 {
+            t.Fatalf("отправлено %q, апдейт не должен был обрабатываться", got)
+        }
+
+        // Возвращённый апдейт после освобождения чата — не дубль
+        release()
+        if err := handle(context.Background(), requeued[0]); err != nil {
+            t.Fatalf("повторная доставка: %v", err)
+        }
+        if got := tb.sentTo(42); len(got) != 1 {
+            t.Fatalf("после повторной доставки отправлено %q, ожидался ответ", got)
+        }
+        tb.StopWorkers(time.Second)
+    }
+}
+
+// Вернуть апдейт в поток не удалось — сообщение остаётся неподтверждённым
+// и придёт снова
+func TestRequeueFailureLeavesUpdateForRedelivery(t *testing.T) {
+    tb, release := busyChatBot(t)
+    defer release()
+    q := newHandlerQueue(true)
+    q.publishErr = errors.New("redis недоступен")
+    tb.StartWorkers(q, 1)
+    defer tb.StopWorkers(time.Second)
+    handle := <-q.handlers
+
+    payload, _ := json.Marshal(text(1, 42, "есть зелёный чай?"))
+    if err := handle(context.Background(), payload); !errors.Is(err, errRedeliver) {
+        t.Fatalf("получено %v, ожидалось errRedeliver — сообщение должно остаться в очереди", err)
+    }
+}
+
+// Очередь в памяти закрыта — апдейт отбрасывается, как при переполнении
+func TestRequeueToClosedInProcessQueueDrops(t *testing.T) {
+    tb, release := busyChatBot(t)
+    defer release()
+    q := newHandlerQueue(false)
+    q.publishErr = queue.ErrClosed
+    tb.StartWorkers(q, 1)
+    defer tb.StopWorkers(time.Second)
+    handle := <-q.handlers
+
+    payload, _ := json.Marshal(text(1, 42, "есть зелёный чай?"))
+    if err := handle(context.Background(), payload); err != nil {
+        t.Fatalf("получено %v, ожидалось подтверждение", err)
+    }
+}
+
+func TestQueuedUpdateErrorIsAcked(t *testing.T) {
+    tb := newTestBot(t, nil)
+    tb.ai.Err = errors.New("модель недоступна")
+    q := newHandlerQueue(true)
+    tb.StartWorkers(q, 1)
+    defer tb.StopWorkers(time.Second)
+    handle := <-q.handlers
+
+    // Ошибка после начала обработки: покупатель уже получил ответ об ошибке,
+    // повтор из очереди прислал бы его снова
+    payload, _ := json.Marshal(text(1, 42, "есть зелёный чай?"))
+    if err := handle(context.Background(), payload); err != nil {
+        t.Fatalf("получено %v, ожидалось подтверждение", err)
+    }
+}
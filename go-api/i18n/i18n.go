@@ -0,0 +1,93 @@
+// Package i18n — переводы фиксированных фраз бота. Ключ перевода — сама
+// русская фраза, поэтому без перевода пользователь увидит русский текст.
+package i18n
+
+import (
+    "slices"
+    "strings"
+)
+
+// DefaultLang — язык фраз в коде и язык по умолчанию
+const DefaultLang = "ru"
+
+// translations — язык → русская фраза → перевод
+var translations = map[string]map[string]string{
+    "en": {
+        "Слишком много сообщений, подождите":                                                         "Too many messages, please wait a moment.",
+        "Сейчас очень много вопросов — отвечу чуть позже. Повторите, пожалуйста, через минуту.":      "I'm getting a lot of questions right now — please try again in a minute.",
+        "Не могу ответить на такое сообщение. Давайте вернёмся к выбору товара.":                     "I can't respond to that message. Let's get back to choosing a product.",
+        "Идут технические работы, скоро вернусь. Пожалуйста, напишите чуть позже.":                   "Maintenance in progress, I'll be back soon. Please write a bit later.",
+        "Сервис временно недоступен, попробуйте позже.":                                              "The service is temporarily unavailable, please try again later.",
+        "Извините, сейчас не могу ответить, попробуйте позже":                                        "Sorry, I can't answer right now, please try again later.",
+        "Отличный стикер! Напишите, пожалуйста, ваш вопрос текстом — я помогу подобрать товар.":      "Nice sticker! Please type your question and I'll help you pick a product.",
+        "Спасибо! Чтобы рассчитать доставку, напишите, пожалуйста, адрес текстом.":                   "Thanks! To calculate delivery, please type the address.",
+        "Напишите, пожалуйста, ваш вопрос текстом.":                                                  "Please type your question.",
+        "Не получилось открыть фото. Пожалуйста, отправьте его ещё раз.":                             "I couldn't open the photo. Please send it again.",
+        "К сожалению, я пока не умею смотреть фото. Опишите, пожалуйста, товар словами.":             "Sorry, I can't look at photos yet. Please describe the product in words.",
+        "Товар добавлен в корзину. Оформить заказ — /checkout":                                       "Added to your cart. To place the order, send /checkout",
+        "Этого товара сейчас нет в наличии.":                                                         "This product is out of stock right now.",
+        "К сожалению, я пока не умею слушать голосовые. Напишите, пожалуйста, ваш вопрос текстом.":   "Sorry, I can't listen to voice messages yet. Please type your question.",
+        "Голосовое слишком длинное. Запишите, пожалуйста, сообщение покороче или напишите текстом.":  "The voice message is too long. Please record a shorter one or type your question.",
+        "Не получилось разобрать голосовое. Пожалуйста, отправьте его ещё раз или напишите текстом.": "I couldn't make out the voice message. Please send it again or type your question.",
+        "Здравствуйте! Я AI-продавец. Расскажите, что вы ищете, и я помогу подобрать товар.":         "Hello! I'm an AI sales assistant. Tell me what you're looking for and I'll help you choose.",
+        "Рад снова видеть! Чем могу помочь?":                                                         "Good to see you again! How can I help?",
+        "В этой категории пока ничего нет. Расскажите, что вы ищете, — подберу похожее.":             "Nothing in this category yet. Tell me what you're looking for and I'll find something similar.",
+        "Как вам мои ответы? Оцените, пожалуйста, — это поможет мне стать лучше.":                    "How are my answers? Please rate them — it helps me get better.",
+        "Сообщение слишком длинное — я прочитал только его начало.":                                  "Your message is too long — I only read the beginning of it.",
+        "По вашему запросу ничего не найдено. Посмотрите весь каталог — /catalog":                    "Nothing matched your search. Take a look at the full catalog — /catalog",
+        "Извините, бот пока в закрытом доступе.":                                                     "Sorry, this bot is in private access for now.",
+        "У вас остались товары в корзине 🛒 Посмотреть — /cart, оформить заказ — /checkout":           "You still have items in your cart 🛒 View it — /cart, place an order — /checkout",
+        "Извините, на это я ответить не могу. Давайте вернёмся к выбору товара?":                     "Sorry, I can't answer that. Shall we get back to choosing a product?",
+        "Удалить историю переписки, профиль и корзину? Заказы останутся в учёте без привязки к вам.": "Delete your chat history, profile and cart? Orders stay in our records, unlinked from you.",
+        "У вас пока нет заказов — выгружать нечего. Загляните в /catalog!":                           "You have no orders yet — nothing to export. Take a look at /catalog!",
+        "Не получилось выполнить запрос. Попробуйте сформулировать его иначе.":                       "I couldn't complete that request. Please try rephrasing it.",
+        "Готово, теперь общаемся по-русски. Вернуть язык Telegram — /lang reset.":                    "Done, let's talk in English from now on. To go back to your Telegram language — /lang reset.",
+        "Сейчас нерабочее время — менеджеры ответят в начале следующего рабочего дня.":               "We're closed right now — our managers will reply at the start of the next business day.",
+        "Разделы каталога пока не настроены. Посмотрите все товары — /catalog":                       "Catalog sections aren't set up yet. See all products — /catalog",
+        "Меню — на кнопках под полем ввода.":                                                         "The menu is on the buttons below the input field.",
+        "Меню скрыто. Вернуть его — /menu.":                                                          "Menu hidden. Bring it back with /menu.",
+        "Буду отвечать кратко. Вернуть подробные ответы — /detailed.":                                "I'll keep my answers short. For detailed answers again — /detailed.",
+        "Передал ваш вопрос менеджеру — он скоро свяжется с вами. Пока он не ответит, я помолчу.":    "I've passed your question to a manager — they'll contact you soon. I'll stay quiet until then.",
+        "Менеджер завершил разговор. Если появятся вопросы — пишите, я на связи.":                    "The manager has closed the conversation. If you have more questions, just write — I'm here.",
+        "Вы не закончили оформление заказа. Вернуть эти товары в корзину?":                           "You didn't finish placing your order. Put these items back in your cart?",
+        "Товары снова в корзине. Посмотреть — /cart, оформить заказ — /checkout":                     "The items are back in your cart. View it — /cart, place an order — /checkout",
+        "Этот заказ уже не вернуть в корзину — соберите её заново в /catalog.":                       "This order can no longer be put back in your cart — please fill it again from /catalog.",
+        "Буду отвечать подробно.": "I'll give detailed answers.",
+        "Ваши данные удалены.":    "Your data has been deleted.",
+        "Удаление отменено.":      "Deletion cancelled.",
+        "Каталог":                 "Catalog",
+        "Корзина":                 "Cart",
+        "🛒 Вернуть в корзину":     "🛒 Back to cart",
+        "Помощь":                  "Help",
+        "нет доступа":             "access denied",
+        "печатает...":             "typing...",
+    },
+}
+
+// Resolve приводит language_code из Telegram ("en-US", "pt-br") к языку
+// с переводами; для остальных языков возвращает DefaultLang
+func Resolve(code string) string {
+    lang, _, _ := strings.Cut(strings.ToLower(code), "-")
+    if _, ok := translations[lang]; ok {
+        return lang
+    }
+    return DefaultLang
+}
+
+// Supported — языки фиксированных фраз по алфавиту, включая DefaultLang
+func Supported() []string {
+    langs := []string{DefaultLang}
+    for lang := range translations {
+        langs = append(langs, lang)
+    }
+    slices.Sort(langs)
+    return langs
+}
+
+// T переводит русскую фразу на язык lang; без перевода возвращает фразу как есть
+func T(lang, phrase string) string {
+    if s, ok := translations[Resolve(lang)][phrase]; ok {
+        return s
+    }
+    return phrase
+}
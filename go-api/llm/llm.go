@@ -0,0 +1,130 @@
+// Package llm выбирает поставщика языковой модели по LLM_PROVIDER.
+// Обработчики работают с интерфейсом Model и не знают, кто отвечает:
+// OpenAI (или Azure) или локальная модель через OpenAI-совместимый API Ollama.
+package llm
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "ai_seller/config"
+    "ai_seller/openai"
+)
+
+// Поставщики модели (значения LLM_PROVIDER)
+const (
+    // ProviderOpenAI — OpenAI или Azure OpenAI (см. OPENAI_PROVIDER)
+    ProviderOpenAI = "openai"
+    // ProviderOllama — Ollama или другой сервер с OpenAI-совместимым /v1
+    ProviderOllama = "ollama"
+)
+
+// ErrUnsupported — поставщик не умеет этот вид запросов
+var ErrUnsupported = errors.New("не поддерживается поставщиком модели")
+
+// Model — запросы к модели, которые есть у любого поставщика.
+// Вызов инструментов необязателен: его наличие проверяется приведением
+// к ToolCaller.
+type Model interface {
+    ChatCompletion(ctx context.Context, messages []openai.Message) (string, error)
+    ChatCompletionStream(ctx context.Context, messages []openai.Message) (<-chan openai.StreamChunk, error)
+    VisionCompletion(ctx context.Context, messages []openai.Message) (string, error)
+    Transcribe(ctx context.Context, audio []byte, filename string) (string, error)
+    Embeddings(ctx context.Context, inputs []string) ([][]float32, error)
+    // Ping — лёгкий запрос к API для диагностики (/ping)
+    Ping(ctx context.Context) error
+}
+
+// ToolCaller — модель, которая умеет вызывать инструменты
+type ToolCaller interface {
+    ChatWithTools(ctx context.Context, messages []openai.Message, tools *openai.ToolRegistry) (string, error)
+}
+
+var (
+    _ Model      = (*openai.Client)(nil)
+    _ ToolCaller = (*openai.Client)(nil)
+    _ Model      = (*ollama)(nil)
+    _ ToolCaller = (*ollamaWithTools)(nil)
+)
+
+// New — фабрика модели выбранного поставщика. В opts передаются общие
+// настройки (повторы, лимиты, температура, хук учёта токенов); адрес, ключ
+// и модели New берёт из конфига сам.
+func New(cfg *config.Config, opts openai.Options) (Model, error) {
+    switch cfg.LLMProvider {
+    case ProviderOpenAI:
+        opts.BaseURL = cfg.OpenAIBaseURL
+        opts.Provider = cfg.OpenAIProvider
+        opts.APIVersion = cfg.OpenAIAPIVersion
+        opts.Model = cfg.OpenAIModel
+        opts.VisionModel = cfg.VisionModel
+        opts.TranscriptionModel = cfg.TranscriptionModel
+        opts.EmbeddingModel = cfg.EmbeddingModel
+        return openai.NewClient(cfg.OpenAIKey, opts), nil
+    case ProviderOllama:
+        opts.BaseURL = cfg.OllamaBaseURL
+        opts.Provider = openai.ProviderOpenAI
+        opts.Model = cfg.OllamaModel
+        opts.VisionModel = cfg.OllamaVisionModel
+        // Лимиты аккаунта OpenAI к локальной модели не относятся
+        opts.Admit = nil
+        // Ollama ключ не проверяет, но прокси перед ней может
+        m := &ollama{client: openai.NewClient(cfg.OpenAIKey, opts), vision: cfg.OllamaVisionModel != ""}
+        if cfg.OllamaTools {
+            return &ollamaWithTools{m}, nil
+        }
+        return m, nil
+    default:
+        return nil, fmt.Errorf("неизвестный поставщик модели %q", cfg.LLMProvider)
+    }
+}
+
+// ollama — локальная модель. Распознавания речи и эмбеддингов нужной
+// размерности у Ollama нет, а вызов инструментов зависит от модели,
+// поэтому он включается отдельно (OLLAMA_TOOLS). Фото разбирает отдельная
+// мультимодальная модель, если она задана (OLLAMA_VISION_MODEL).
+type ollama struct {
+    client *openai.Client
+    vision bool
+}
+
+func (m *ollama) ChatCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    return m.client.ChatCompletion(ctx, messages)
+}
+
+func (m *ollama) ChatCompletionStream(ctx context.Context, messages []openai.Message) (<-chan openai.StreamChunk, error) {
+    return m.client.ChatCompletionStream(ctx, messages)
+}
+
+func (m *ollama) VisionCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    if !m.vision {
+        return "", fmt.Errorf("разбор фото: %w", ErrUnsupported)
+    }
+    return m.client.VisionCompletion(ctx, messages)
+}
+
+func (m *ollama) JSONCompletion(ctx context.Context, messages []openai.Message) (string, error) {
+    return m.client.JSONCompletion(ctx, messages)
+}
+
+func (m *ollama) Transcribe(ctx context.Context, audio []byte, filename string) (string, error) {
+    return "", fmt.Errorf("распознавание речи: %w", ErrUnsupported)
+}
+
+func (m *ollama) Embeddings(ctx context.Context, inputs []string) ([][]float32, error) {
+    return nil, fmt.Errorf("эмбеддинги: %w", ErrUnsupported)
+}
+
+func (m *ollama) Ping(ctx context.Context) error {
+    return m.client.Ping(ctx)
+}
+
+// ollamaWithTools — локальная модель с поддержкой tool calling
+type ollamaWithTools struct {
+    *ollama
+}
+
+func (m *ollamaWithTools) ChatWithTools(ctx context.Context, messages []openai.Message, tools *openai.ToolRegistry) (string, error) {
+    return m.client.ChatWithTools(ctx, messages, tools)
+}
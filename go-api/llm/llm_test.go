@@ -0,0 +1,144 @@
+package llm
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+
+    "ai_seller/config"
+    "ai_seller/openai"
+)
+
+// modelServer — OpenAI-совместимый API, запоминающий модели запросов
+type modelServer struct {
+    *httptest.Server
+    mu     sync.Mutex
+    models []string
+}
+
+func newModelServer(t *testing.T) *modelServer {
+    t.Helper()
+    s := &modelServer{}
+    s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var req struct {
+            Model string `json:"model"`
+        }
+        json.NewDecoder(r.Body).Decode(&req)
+        s.mu.Lock()
+        s.models = append(s.models, req.Model)
+        s.mu.Unlock()
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ок"}}]}`)
+    }))
+    t.Cleanup(s.Close)
+    return s
+}
+
+// requested — модели запросов к серверу по порядку
+func (s *modelServer) requested() []string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.models...)
+}
+
+var (
+    hello = []openai.Message{{Role: openai.RoleUser, Content: "привет"}}
+    photo = []openai.Message{{Role: openai.RoleUser, Parts: []openai.ContentPart{openai.TextPart("что это?")}}}
+)
+
+// ollamaConfig — конфиг LLM_PROVIDER=ollama с сервером srv
+func ollamaConfig(srv *modelServer, vision string, tools bool) *config.Config {
+    return &config.Config{
+        LLMProvider:       ProviderOllama,
+        OllamaBaseURL:     srv.URL,
+        OllamaModel:       "llama3.1",
+        OllamaVisionModel: vision,
+        OllamaTools:       tools,
+    }
+}
+
+func TestNewSelectsProvider(t *testing.T) {
+    srv := newModelServer(t)
+
+    m, err := New(&config.Config{LLMProvider: ProviderOpenAI, OpenAIBaseURL: srv.URL, OpenAIModel: "gpt-4o-mini"}, openai.Options{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := m.(*openai.Client); !ok {
+        t.Fatalf("LLM_PROVIDER=openai: %T, нужен *openai.Client", m)
+    }
+
+    for _, tools := range []bool{false, true} {
+        m, err := New(ollamaConfig(srv, "", tools), openai.Options{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if _, ok := m.(ToolCaller); ok != tools {
+            t.Fatalf("OLLAMA_TOOLS=%v: ToolCaller = %v", tools, ok)
+        }
+    }
+
+    if _, err := New(&config.Config{LLMProvider: "claude"}, openai.Options{}); err == nil {
+        t.Fatal("неизвестный поставщик принят")
+    }
+}
+
+// Ollama ходит на свой адрес со своими моделями и без лимитов аккаунта OpenAI
+func TestOllamaRequests(t *testing.T) {
+    srv := newModelServer(t)
+    admit := func(ctx context.Context, tokens int64) error { return errors.New("лимит аккаунта OpenAI") }
+    m, err := New(ollamaConfig(srv, "llava", false), openai.Options{Admit: admit})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ctx := context.Background()
+    if _, err := m.ChatCompletion(ctx, hello); err != nil {
+        t.Fatalf("ChatCompletion: %v", err)
+    }
+    if _, err := m.VisionCompletion(ctx, photo); err != nil {
+        t.Fatalf("VisionCompletion: %v", err)
+    }
+    got := srv.requested()
+    if len(got) != 2 || got[0] != "llama3.1" || got[1] != "llava" {
+        t.Fatalf("модели запросов %q, нужны llama3.1 и llava", got)
+    }
+}
+
+// Чего Ollama не умеет, то возвращает ErrUnsupported, не обращаясь к серверу
+func TestOllamaUnsupported(t *testing.T) {
+    srv := newModelServer(t)
+    m, err := New(ollamaConfig(srv, "", false), openai.Options{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ctx := context.Background()
+    calls := map[string]func() error{
+        "Transcribe": func() error {
+            _, err := m.Transcribe(ctx, []byte("OggS"), "voice.ogg")
+            return err
+        },
+        "Embeddings": func() error {
+            _, err := m.Embeddings(ctx, []string{"улун"})
+            return err
+        },
+        "VisionCompletion без OLLAMA_VISION_MODEL": func() error {
+            _, err := m.VisionCompletion(ctx, photo)
+            return err
+        },
+    }
+    for name, call := range calls {
+        if err := call(); !errors.Is(err, ErrUnsupported) {
+            t.Errorf("%s: ошибка %v, нужна ErrUnsupported", name, err)
+        }
+    }
+    if got := srv.requested(); len(got) != 0 {
+        t.Fatalf("запросы к серверу %q", got)
+    }
+}
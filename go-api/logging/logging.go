@@ -0,0 +1,51 @@
+package logging
+
+import (
+    "context"
+    "log/slog"
+    "os"
+    "sync/atomic"
+
+    "ai_seller/reqctx"
+)
+
+var current atomic.Pointer[slog.Logger]
+
+func init() {
+    current.Store(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+}
+
+// Init настраивает общий логгер под окружение: JSON в production
+// (для Loki/Datadog), человекочитаемый текст в остальных случаях.
+// Логгер также становится slog.Default, чтобы стандартный log шёл туда же.
+func Init(env string) *slog.Logger {
+    var h slog.Handler
+    if env == "production" {
+        h = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})
+    } else {
+        h = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
+    }
+
+    l := slog.New(h)
+    current.Store(l)
+    slog.SetDefault(l)
+    return l
+}
+
+// SetLogger заменяет общий логгер, например на перехватывающий записи в тесте
+func SetLogger(l *slog.Logger) {
+    current.Store(l)
+}
+
+// Logger — общий логгер приложения
+func Logger() *slog.Logger {
+    return current.Load()
+}
+
+// FromContext — общий логгер с trace_id из контекста, если он там есть
+func FromContext(ctx context.Context) *slog.Logger {
+    if id := reqctx.TraceIDFromContext(ctx); id != "" {
+        return Logger().With("trace_id", id)
+    }
+    return Logger()
+}
@@ -2,37 +2,202 @@ package main
 
 import (
     "context"
+    "database/sql"
+    "errors"
+    "flag"
     "fmt"
-    "log"
     "net/http"
     "os"
     "os/signal"
+    "strings"
     "syscall"
     "time"
 
+    "ai_seller/audit"
+    "ai_seller/breaker"
+    "ai_seller/cache"
     "ai_seller/config"
-    "ai_seller/storage"
+    "ai_seller/dashboard"
+    "ai_seller/dialog"
+    "ai_seller/faq"
+    "ai_seller/filter"
     "ai_seller/handlers"
+    "ai_seller/llm"
+    "ai_seller/logging"
+    "ai_seller/middleware"
+    "ai_seller/migrations"
+    "ai_seller/money"
+    "ai_seller/openai"
+    "ai_seller/payments"
+    "ai_seller/queue"
+    "ai_seller/reqctx"
+    "ai_seller/storage"
+    "ai_seller/telegram"
 
+    "github.com/prometheus/client_golang/prometheus/promhttp"
     "github.com/redis/go-redis/v9"
 )
 
-func initializeDependencies() (*config.Config, *redis.Client, error) {
-    cfg := config.LoadConfig()
-    fmt.Printf("🔧 Конфигурация загружена: %+v\n", cfg)
+// bootstrapTimeout — сколько ждать bootstrap, включая очередь за другими репликами
+const bootstrapTimeout = 5 * time.Minute
+
+// getMeTimeout — сколько при старте ждать getMe, прежде чем взять username
+// бота из конфигурации
+const getMeTimeout = 10 * time.Second
+
+func initializeDependencies() (*config.Config, *sql.DB, *redis.Client, error) {
+    cfg, err := config.LoadConfig()
+    if err != nil {
+        return nil, nil, nil, fmt.Errorf("ошибка загрузки конфигурации: %w", err)
+    }
+    // Секреты (ключи, токены, DSN) в лог не попадают
+    logging.Init(cfg.Env).Info("конфигурация загружена", "env", cfg.Env, "port", cfg.Port)
+    logging.Logger().Debug("системный промпт", "prompt", cfg.SystemPrompt)
+
+    db, err := openDB(cfg.StorageDriver, cfg.PostgresDSN, cfg.SQLitePath, storage.PoolOptions{
+        MaxOpenConns:    cfg.DBMaxOpenConns,
+        MaxIdleConns:    cfg.DBMaxIdleConns,
+        ConnMaxLifetime: cfg.DBConnMaxLifetime,
+    })
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    if err := bootstrap(db, cfg.StorageDriver, dbAddr(cfg.StorageDriver, cfg.PostgresDSN, cfg.SQLitePath), cfg.SeedFile); err != nil {
+        return nil, nil, nil, err
+    }
+
+    rdb := storage.ConnectRedis(cfg.RedisAddr, cfg.RedisTimeout)
+    return cfg, db, rdb, nil
+}
+
+// openDB открывает базу хранилищ драйвера STORAGE_DRIVER
+func openDB(driver, dsn, path string, opts storage.PoolOptions) (*sql.DB, error) {
+    if driver == storage.DriverSQLite {
+        return storage.OpenSQLite(path, opts)
+    }
+    return storage.Open(dsn, opts)
+}
+
+// dbAddr — адрес базы для миграций: DSN PostgreSQL или файл SQLite
+func dbAddr(driver, dsn, path string) string {
+    if driver == storage.DriverSQLite {
+        return path
+    }
+    return dsn
+}
+
+// bootstrap применяет миграции и начальные данные, дожидаясь других реплик
+func bootstrap(db *sql.DB, driver, addr, seedFile string) error {
+    ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+    defer cancel()
+    return migrations.Bootstrap(ctx, db, driver, addr, seedFile)
+}
+
+// migrateOnly — режим init-контейнера: только bootstrap базы. Полная
+// конфигурация и Redis для него не нужны.
+func migrateOnly() error {
+    cfg, err := config.LoadMigrationConfig()
+    if err != nil {
+        return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
+    }
+    logging.Init(cfg.Env)
+
+    db, err := openDB(cfg.StorageDriver, cfg.PostgresDSN, cfg.SQLitePath, storage.PoolOptions{MaxOpenConns: 2, MaxIdleConns: 1})
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+    return bootstrap(db, cfg.StorageDriver, dbAddr(cfg.StorageDriver, cfg.PostgresDSN, cfg.SQLitePath), cfg.SeedFile)
+}
+
+// recordUsage — хук клиента OpenAI, пишущий расход токенов в счётчики Redis
+func recordUsage(usage *cache.UsageCounter) openai.UsageFunc {
+    return func(ctx context.Context, u openai.Usage) {
+        err := usage.Record(ctx, reqctx.ChatIDFromContext(ctx), cache.TokenUsage{
+            Prompt:     u.PromptTokens,
+            Completion: u.CompletionTokens,
+            Total:      u.TotalTokens,
+        })
+        if err != nil {
+            logging.Logger().Error("ошибка учёта токенов", "err", err)
+        }
+    }
+}
 
-    db := storage.ConnectPostgres(cfg.PostgresDSN)
-    _ = db
+// dependencyProbes — проверки для /ping; предохранитель базы обходится
+// намеренно: /ping показывает саму базу
+func dependencyProbes(driver string, db *sql.DB, rdb *redis.Client, tg *telegram.Client, ai llm.Model) []handlers.Probe {
+    dbName := "PostgreSQL"
+    if driver == storage.DriverSQLite {
+        dbName = "SQLite"
+    }
+    return []handlers.Probe{
+        {Name: dbName, Check: func(ctx context.Context) error {
+            _, err := db.ExecContext(ctx, "SELECT 1")
+            return err
+        }},
+        {Name: "Redis", Check: func(ctx context.Context) error {
+            return rdb.Ping(ctx).Err()
+        }},
+        {Name: "Telegram", Check: func(ctx context.Context) error {
+            _, err := tg.GetMe(ctx)
+            return err
+        }},
+        {Name: "OpenAI", Check: ai.Ping},
+    }
+}
 
-    rdb := storage.ConnectRedis(cfg.RedisAddr)
-    return cfg, rdb, nil
+// admitRequest — хук клиента OpenAI, держащий запросы в минутных лимитах
+// аккаунта. Если Redis недоступен, запрос пропускаем: лимит защищает от 429,
+// а без него OpenAI их просто вернёт. nil — лимиты не заданы.
+func admitRequest(cfg *config.Config, rdb *redis.Client) openai.AdmitFunc {
+    if cfg.OpenAIAccountRPM == 0 && cfg.OpenAIAccountTPM == 0 {
+        return nil
+    }
+    limiter := cache.NewAccountLimiter(rdb, cfg.OpenAIAccountRPM, cfg.OpenAIAccountTPM, cfg.OpenAIAccountWait)
+    return func(ctx context.Context, tokens int64) error {
+        err := limiter.Wait(ctx, tokens)
+        if err != nil && !errors.Is(err, cache.ErrAccountLimit) && ctx.Err() == nil {
+            logging.FromContext(ctx).Error("ошибка лимита аккаунта OpenAI", "err", err)
+            return nil
+        }
+        return err
+    }
 }
 
-func setupRoutes() http.Handler {
+func setupRoutes(bot *handlers.Bot, db *sql.DB, rdb *redis.Client, dbBreaker *breaker.Breaker) http.Handler {
     mux := http.NewServeMux()
 
     // Telegram webhook endpoint
-    mux.HandleFunc("/telegram", handlers.TelegramHandler)
+    mux.HandleFunc("/telegram", bot.TelegramHandler)
+
+    // Пробы для оркестратора
+    mux.HandleFunc("GET /healthz", dashboard.HealthzHandler)
+    // Без Redis бот отвечает (контекст из PostgreSQL, кэши пропускаются),
+    // поэтому его недоступность — degraded, а не повод снимать под с балансировки.
+    // Разомкнутый предохранитель PostgreSQL — тоже degraded: бот отвечает без истории.
+    mux.HandleFunc("GET /readyz", dashboard.ReadyzHandler(
+        map[string]dashboard.Check{"postgres": db.PingContext},
+        map[string]dashboard.Check{
+            "redis": func(ctx context.Context) error {
+                return rdb.Ping(ctx).Err()
+            },
+            "postgres_breaker": func(ctx context.Context) error {
+                if s := dbBreaker.State(); s != breaker.Closed {
+                    return fmt.Errorf("предохранитель %s", s)
+                }
+                return nil
+            },
+        },
+    ))
+
+    // Уведомления платёжного провайдера
+    if bot.Payments != nil {
+        mux.HandleFunc("POST /payments/webhook", bot.PaymentWebhookHandler)
+    }
+
+    // Метрики Prometheus
+    mux.Handle("GET /metrics", promhttp.Handler())
 
     // Тестовый health check
     mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -42,42 +207,363 @@ func setupRoutes() http.Handler {
     return mux
 }
 
+// registerWebhook регистрирует вебхук в Telegram, если задан WEBHOOK_URL.
+// Ошибка не останавливает сервер: вебхук мог быть выставлен раньше вручную.
+func registerWebhook(tg *telegram.Client, cfg *config.Config) {
+    if cfg.WebhookURL == "" {
+        return
+    }
+    if err := tg.SetWebhook(cfg.WebhookURL, cfg.WebhookSecret); err != nil {
+        logging.Logger().Error("не удалось зарегистрировать вебхук", "url", cfg.WebhookURL, "err", err)
+        return
+    }
+    logging.Logger().Info("вебхук зарегистрирован", "url", cfg.WebhookURL, "secret", cfg.WebhookSecret != "")
+}
+
+// botIdentity — ID и username бота из getMe. Если Bot API при старте не
+// ответил, бот берёт username из TELEGRAM_BOT_USERNAME, а ID — из токена.
+func botIdentity(tg *telegram.Client, cfg *config.Config) telegram.BotInfo {
+    ctx, cancel := context.WithTimeout(context.Background(), getMeTimeout)
+    defer cancel()
+    me, err := tg.GetMe(ctx)
+    if err != nil {
+        logging.Logger().Warn("getMe не ответил, username бота — из TELEGRAM_BOT_USERNAME", "username", cfg.BotUsername, "err", err)
+        return telegram.BotInfo{}
+    }
+    if cfg.BotUsername != "" && !strings.EqualFold(cfg.BotUsername, me.Username) {
+        logging.Logger().Warn("TELEGRAM_BOT_USERNAME не совпадает с getMe, используется getMe", "config", cfg.BotUsername, "username", me.Username)
+    }
+    return me
+}
+
+// newUpdateQueue выбирает очередь апдейтов по UPDATE_TRANSPORT
+func newUpdateQueue(cfg *config.Config, rdb *redis.Client) queue.Queue {
+    if cfg.UpdateTransport == queue.TransportRedis {
+        return queue.NewRedisStream(rdb, queue.RedisOptions{
+            Stream:        cfg.UpdateStream,
+            Group:         cfg.UpdateGroup,
+            Retention:     cfg.UpdateStreamRetention,
+            ClaimAfter:    cfg.UpdateClaimAfter,
+            MaxDeliveries: cfg.UpdateMaxDeliveries,
+            DeadLetter:    cfg.UpdateDeadLetter,
+        })
+    }
+    return queue.NewInProcess(cfg.UpdateQueueSize, cfg.UpdateWorkers)
+}
+
+// reindexEmbeddings досчитывает эмбеддинги товаров, добавленных или
+// изменённых с прошлого запуска. Пока он идёт, поиск по подстроке подстраховывает.
+func reindexEmbeddings(ctx context.Context, catalog *storage.CatalogStore, batch int) {
+    res, err := catalog.ReindexEmbeddings(ctx, batch)
+    if err != nil {
+        logging.Logger().Error("ошибка индексации эмбеддингов", "indexed", res.Indexed, "failed", res.Failed, "err", err)
+        return
+    }
+    logging.Logger().Info("эмбеддинги товаров обновлены", "indexed", res.Indexed, "failed", res.Failed, "elapsed", res.Elapsed)
+}
+
+// chatLockSlack — запас TTL блокировки чата сверх дедлайна обработки апдейта
+const chatLockSlack = 5 * time.Second
+
+// pruneInterval — как часто чистить журналы от устаревших записей
+const pruneInterval = time.Hour
+
+// prunePeriodically раз в pruneInterval вызывает prune, удаляющий из
+// журнала what устаревшие записи
+func prunePeriodically(ctx context.Context, what string, prune func(context.Context) (int64, error)) {
+    ticker := time.NewTicker(pruneInterval)
+    defer ticker.Stop()
+    for {
+        n, err := prune(ctx)
+        if err != nil {
+            logging.Logger().Error("ошибка очистки журнала", "journal", what, "err", err)
+        } else if n > 0 {
+            logging.Logger().Info("журнал очищен", "journal", what, "deleted", n)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// refreshRates обновляет курсы валют из CURRENCY_RATES_URL раз в
+// CURRENCY_RATES_REFRESH. При ошибке остаются прежние курсы: с устаревшим
+// курсом покупатель увидит примерную цену, без курса — цену в валюте магазина.
+func refreshRates(ctx context.Context, cfg *config.Config, bot *handlers.Bot) {
+    client := &http.Client{}
+    ticker := time.NewTicker(cfg.CurrencyRatesRefresh)
+    defer ticker.Stop()
+    for {
+        rates, err := money.FetchRates(ctx, client, cfg.CurrencyRatesURL, cfg.DefaultCurrency)
+        if err != nil {
+            logging.Logger().Error("ошибка обновления курсов валют", "err", err)
+        } else {
+            bot.SetRates(rates)
+            logging.Logger().Info("курсы валют обновлены", "currencies", len(rates.PerBase))
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
 func main() {
-    fmt.Println("🚀 Запуск AI-продавца...")
+    onlyMigrate := flag.Bool("migrate-only", false, "применить миграции и начальные данные и завершиться (init-контейнер)")
+    flag.Parse()
+    if *onlyMigrate {
+        if err := migrateOnly(); err != nil {
+            logging.Logger().Error("ошибка bootstrap", "err", err)
+            os.Exit(1)
+        }
+        logging.Logger().Info("bootstrap завершён, выходим (--migrate-only)")
+        return
+    }
+    logging.Logger().Info("запуск AI-продавца")
 
-    cfg, rdb, err := initializeDependencies()
+    cfg, db, rdb, err := initializeDependencies()
     if err != nil {
-        log.Fatalf("❌ Ошибка инициализации: %v", err)
+        logging.Logger().Error("ошибка инициализации", "err", err)
+        os.Exit(1)
     }
-    _ = rdb
 
-    srv := &http.Server{
-        Addr:    ":" + cfg.Port,
-        Handler: setupRoutes(),
+    if cfg.WebhookSecret == "" {
+        logging.Logger().Warn("TELEGRAM_WEBHOOK_SECRET не задан — подлинность запросов webhook не проверяется")
     }
 
-    stop := make(chan os.Signal, 1)
-    signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+    var outputFilter *filter.Filter
+    if cfg.OutputFilterFile != "" {
+        outputFilter, err = filter.Load(cfg.OutputFilterFile)
+        if err != nil {
+            logging.Logger().Error("ошибка загрузки фильтра ответов", "err", err)
+            os.Exit(1)
+        }
+    }
 
-    go func() {
-        fmt.Printf("🌐 Сервер запущен: http://localhost:%s\n", cfg.Port)
-        if err := srv.ListenAndServe(); err != nil && err != 
-http.ErrServerClosed {
-            log.Fatalf("❌ Ошибка сервера: %v", err)
+    var moderationWords *filter.Filter
+    if cfg.ModerationWordsFile != "" {
+        moderationWords, err = filter.Load(cfg.ModerationWordsFile)
+        if err != nil {
+            logging.Logger().Error("ошибка загрузки словаря модерации", "err", err)
+            os.Exit(1)
         }
-    }()
+    }
 
-    <-stop
-    fmt.Println("\n⏳ Завершение работы...")
+    var faqMatcher *faq.Matcher
+    if cfg.FAQFile != "" {
+        faqMatcher, err = faq.Load(cfg.FAQFile, cfg.FAQThreshold)
+        if err != nil {
+            logging.Logger().Error("ошибка загрузки FAQ", "err", err)
+            os.Exit(1)
+        }
+    }
 
-    ctx, cancel := context.WithTimeout(context.Background(), 
-5*time.Second)
-    defer cancel()
+    usage := cache.NewUsageCounter(rdb)
+    auditStore := storage.NewLLMAuditStore(db, cfg.DBTimeout)
+    // Обращения к базе на пути ответа покупателю идут через предохранитель
+    dbBreaker := storage.NewDBBreaker(cfg.DBBreakerThreshold, cfg.DBBreakerCooldown)
+    messages := storage.GuardedMessages{MessageStore: storage.NewMessageStore(db, cfg.DBTimeout), Breaker: dbBreaker}
+    sessions := cache.NewSessionCache(rdb, cfg.SessionMaxTurns, cfg.SessionTTL)
+    users := storage.GuardedUsers{UserStore: storage.NewUserStore(db, cfg.DBTimeout), Breaker: dbBreaker}
+    // Журнал запросов к модели пишет один воркер; запросы не ждут базу
+    var (
+        auditLog   *audit.Recorder
+        onExchange openai.ExchangeFunc
+    )
+    if cfg.LLMAudit && cfg.LLMAuditSample > 0 {
+        auditLog = audit.New(auditStore, cfg.LLMAuditSample, audit.QueueSize)
+        onExchange = auditLog.Exchange
+    }
+    var adaptiveTimeout *openai.AdaptiveTimeout
+    if cfg.OpenAIAdaptiveTimeout {
+        adaptiveTimeout = openai.NewAdaptiveTimeout(cfg.OpenAITimeoutMultiplier, cfg.OpenAITimeoutMin, cfg.OpenAITimeout)
+    }
+    ai, err := llm.New(cfg, openai.Options{
+        MaxAttempts:         cfg.OpenAIMaxAttempts,
+        Timeout:             cfg.OpenAITimeout,
+        AdaptiveTimeout:     adaptiveTimeout,
+        MaxConcurrency:      cfg.OpenAIMaxConcurrency,
+        Temperature:         cfg.OpenAITemperature,
+        MaxTokens:           cfg.OpenAIMaxTokens,
+        EmbeddingDimensions: storage.EmbeddingDimensions,
+        OnUsage:             recordUsage(usage),
+        Admit:               admitRequest(cfg, rdb),
+        OnExchange:          onExchange,
+        UnknownToolRetries:  cfg.UnknownToolRetries,
+    })
+    if err != nil {
+        logging.Logger().Error("ошибка выбора поставщика модели", "err", err)
+        os.Exit(1)
+    }
+    catalog := storage.NewCatalogStore(db, cfg.DBTimeout)
+    if cfg.Features.IsEnabled(config.FlagSemanticSearch) {
+        if err := catalog.EnableSemanticSearch(context.Background(), ai); err != nil {
+            logging.Logger().Error("ошибка включения семантического поиска", "err", err)
+            os.Exit(1)
+        }
+    }
+    var (
+        summaries  dialog.SummaryReader
+        summarizer *dialog.Summarizer
+    )
+    if cfg.Features.IsEnabled(config.FlagSummaries) {
+        store := storage.GuardedSummaries{SummaryStore: storage.NewSummaryStore(db, cfg.DBTimeout), Breaker: dbBreaker}
+        summaries = store
+        summarizer = dialog.NewSummarizer(ai, messages, store, cfg.SummaryEvery)
+    }
+    dlg := dialog.NewContextBuilder(sessions, messages, users, summaries, cfg.SystemPrompt, cfg.ContextTokenBudget)
+    variantPrompts := make(map[string]string, len(cfg.PromptVariants))
+    for _, v := range cfg.PromptVariants {
+        variantPrompts[v.Name] = v.Prompt
+    }
+    dlg.SetPromptVariants(variantPrompts)
+    tg := telegram.NewClient(cfg.TelegramToken, telegram.Options{ParseMode: cfg.TelegramParseMode})
+    if cfg.TelegramMode == "webhook" {
+        registerWebhook(tg, cfg)
+    }
 
-    if err := srv.Shutdown(ctx); err != nil {
-        log.Fatalf("❌ Ошибка завершения: %v", err)
+    failed := storage.NewFailedUpdateStore(db, cfg.DBTimeout)
+
+    var payment payments.Provider
+    if cfg.PaymentProvider != "" {
+        payment, err = payments.New(cfg.PaymentProvider, cfg.PaymentWebhookSecret)
+        if err != nil {
+            logging.Logger().Error("ошибка настройки оплаты", "err", err)
+            os.Exit(1)
+        }
     }
 
-    fmt.Println("✅ Завершение успешно.")
-}
+    var responses *cache.ResponseCache
+    if cfg.Features.IsEnabled(config.FlagResponseCache) {
+        responses = cache.NewResponseCache(rdb, cfg.ResponseCacheTTL)
+    }
+
+    var handoffs *cache.Handoffs
+    if cfg.Features.IsEnabled(config.FlagHandoff) {
+        handoffs = cache.NewHandoffs(rdb, cfg.HandoffTTL)
+    }
+
+    // Подсказки пишут покупателям без их запроса, поэтому только по явному флагу
+    var reengagement handlers.ReengagementStore
+    var shopping handlers.ShoppingActivity
+    if cfg.Features.IsEnabled(config.FlagReengagement) {
+        reengagement = storage.NewReengagementStore(db, cfg.DBTimeout)
+        shopping = cache.NewShoppingActivity(rdb, cfg.ReengageLookback)
+    }
+
+    bot := handlers.NewBot(handlers.Deps{
+        Config:        cfg,
+        Telegram:      tg,
+        OpenAI:        ai,
+        Messages:      messages,
+        Sessions:      sessions,
+        Limiter:       cache.NewRateLimiter(rdb, cfg.RateLimitPerMinute),
+        Catalog:       catalog,
+        Carts:         cache.NewCartStore(rdb),
+        Usage:         usage,
+        Orders:        storage.GuardedOrders{OrderStore: storage.NewOrderStore(db, cfg.DBTimeout), Breaker: dbBreaker},
+        Chats:         storage.NewChatStore(db, cfg.DBTimeout),
+        Feedback:      storage.GuardedFeedback{FeedbackStore: storage.NewFeedbackStore(db, cfg.DBTimeout), Breaker: dbBreaker},
+        Stats:         storage.NewStatsStore(db),
+        Privacy:       storage.NewPrivacyStore(db),
+        Payments:      payment,
+        FailedUpdates: failed,
+        Outbox:        storage.NewOutboxStore(db, cfg.DBTimeout),
+        Summarizer:    summarizer,
+        Flags:         cache.NewFlagOverrides(rdb),
+        Digests:       cache.NewDigestMarks(rdb),
+        OffHoursNotes: cache.NewOffHoursNotes(rdb),
+        DraftOffers:   cache.NewDraftOffers(rdb),
+        Handoffs:      handoffs,
+        Updates:       cache.NewUpdateDeduper(rdb),
+        Locks:         cache.NewChatLocker(rdb, cfg.UpdateTimeout+chatLockSlack),
+        Dialog:        dlg,
+        FAQ:           faqMatcher,
+        Filter:        outputFilter,
+        Responses:     responses,
+        Users:         users,
+
+        ModerationWords: moderationWords,
+        Attributions:    storage.GuardedAttributions{AttributionStore: storage.NewAttributionStore(db, cfg.DBTimeout), Breaker: dbBreaker},
+        Probes:          dependencyProbes(cfg.StorageDriver, db, rdb, tg, ai),
+        Challenges:      storage.NewMemberChallengeStore(db, cfg.DBTimeout),
+        Reengagement:    reengagement,
+        Shopping:        shopping,
+        Me:              botIdentity(tg, cfg),
+    })
 
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    // Фоновые задачи останавливаются отменой ctx; после остановки сервера
+    // их ждём вместе с обработчиками апдейтов не дольше drainTimeout
+    var bg background
+    bg.Go(func() { watchReload(ctx, cfg, dlg, bot) })
+    bg.Go(func() {
+        prunePeriodically(ctx, "журнал необработанных апдейтов", func(ctx context.Context) (int64, error) {
+            return failed.PruneFailed(ctx, cfg.FailedUpdateRetention)
+        })
+    })
+    // Журнал запросов к модели чистится и после выключения LLM_AUDIT
+    bg.Go(func() {
+        prunePeriodically(ctx, "журнал запросов к модели", func(ctx context.Context) (int64, error) {
+            return auditStore.Prune(ctx, cfg.LLMAuditRetention)
+        })
+    })
+    if auditLog != nil {
+        bg.Go(func() { auditLog.Run(ctx) })
+    }
+    bg.Go(func() { bot.RunOutbox(ctx) })
+    bg.Go(func() { bot.RunMemberChallenges(ctx) })
+
+    if cfg.Features.IsEnabled(config.FlagCartReminders) {
+        bg.Go(func() { bot.RemindAbandonedCarts(ctx, cfg.CartReminderAfter) })
+    }
+
+    if cfg.Features.IsEnabled(config.FlagReengagement) {
+        bg.Go(func() { bot.ReengageIdleCustomers(ctx) })
+    }
+
+    if cfg.DigestSchedule != "" {
+        bg.Go(func() { bot.SendOrderDigests(ctx) })
+    }
+
+    if cfg.CurrencyRatesURL != "" {
+        bg.Go(func() { refreshRates(ctx, cfg, bot) })
+    }
+
+    if cfg.Features.IsEnabled(config.FlagSemanticSearch) {
+        bg.Go(func() { reindexEmbeddings(ctx, catalog, cfg.EmbeddingBatch) })
+    }
+
+    // В режиме polling HTTP-сервер остаётся ради проб и метрик
+    if cfg.TelegramMode == "polling" {
+        bg.Go(func() { bot.Poll(ctx) })
+    }
+
+    bot.StartWorkers(newUpdateQueue(cfg, rdb), cfg.UpdateWorkers)
+
+    handler := setupRoutes(bot, db, rdb, dbBreaker)
+    handler = middleware.CORSMiddleware(cfg.AllowedOrigins)(handler)
+    handler = middleware.LoggingMiddleware(cfg.Env, cfg.DebugHTTP)(handler)
+    handler = middleware.ClientIPMiddleware(cfg.TrustedProxies)(handler)
+    handler = middleware.RecoverMiddleware(cfg.Env)(handler)
+
+    err = RunServer(ctx, cfg, handler)
+    // Вебхук больше не принимает апдейты — дорабатываем уже принятые,
+    // затем ждём фоновые задачи. Сервер мог упасть и без сигнала, поэтому
+    // ctx отменяем явно — задачи завершают текущую итерацию и выходят
+    stop()
+    drainDeadline := time.Now().Add(drainTimeout)
+    bot.StopWorkers(drainTimeout)
+    bg.Wait(drainDeadline)
+    if err != nil {
+        logging.Logger().Error("сервер остановлен с ошибкой", "err", err)
+        os.Exit(1)
+    }
+
+    logging.Logger().Info("завершение успешно")
+}
@@ -0,0 +1,326 @@
+package memstore
+
+import (
+    "context"
+    "slices"
+    "sort"
+    "strings"
+    "sync"
+
+    "ai_seller/storage"
+)
+
+// Catalog — каталог товаров и категорий в памяти. Триграммный поиск
+// считает похожесть как pg_trgm, семантический выключен.
+type Catalog struct {
+    mu         sync.Mutex
+    products   map[int64]storage.Product
+    categories []storage.Category
+    // Audit — правки SetPrice, SetStock и SetCategory по порядку, как catalog_audit
+    Audit []CatalogEdit
+}
+
+// CatalogEdit — запись журнала правок каталога
+type CatalogEdit struct {
+    ProductID int64
+    Field     string
+    ChangedBy int64
+}
+
+// Add заводит товары как есть, с их ID, для подготовки тестов
+func (s *Catalog) Add(products ...storage.Product) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.products == nil {
+        s.products = make(map[int64]storage.Product)
+    }
+    for _, p := range products {
+        s.products[p.ID] = p
+    }
+}
+
+// AddCategory заводит категорию; заведённые товары productIDs переносятся в неё
+func (s *Catalog) AddCategory(c storage.Category, productIDs ...int64) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.categories = append(s.categories, c)
+    for _, id := range productIDs {
+        if p, ok := s.products[id]; ok {
+            p.CategoryID = c.ID
+            s.products[id] = p
+        }
+    }
+}
+
+// sorted — товары по id; вызывается под s.mu
+func (s *Catalog) sorted(keep func(storage.Product) bool) []storage.Product {
+    var out []storage.Product
+    for _, p := range s.products {
+        if keep == nil || keep(p) {
+            out = append(out, p)
+        }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+    return out
+}
+
+func page(products []storage.Product, limit, offset int) []storage.Product {
+    if offset >= len(products) {
+        return nil
+    }
+    products = products[offset:]
+    if len(products) > limit {
+        products = products[:limit]
+    }
+    return products
+}
+
+// ListProducts возвращает страницу каталога, упорядоченную по id
+func (s *Catalog) ListProducts(ctx context.Context, limit, offset int) ([]storage.Product, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return page(s.sorted(nil), limit, offset), nil
+}
+
+// CountProducts возвращает число товаров в каталоге
+func (s *Catalog) CountProducts(ctx context.Context) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return len(s.products), nil
+}
+
+// GetProduct возвращает товар по id или storage.ErrNotFound
+func (s *Catalog) GetProduct(ctx context.Context, id int64) (storage.Product, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    p, ok := s.products[id]
+    if !ok {
+        return storage.Product{}, storage.ErrNotFound
+    }
+    return p, nil
+}
+
+// SearchProducts ищет подстроку в названии или описании без учёта регистра;
+// товары в наличии идут первыми
+func (s *Catalog) SearchProducts(ctx context.Context, query string) ([]storage.Product, error) {
+    q := strings.ToLower(strings.TrimSpace(query))
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    found := s.sorted(func(p storage.Product) bool {
+        return strings.Contains(strings.ToLower(p.Name), q) || strings.Contains(strings.ToLower(p.Description), q)
+    })
+    sort.SliceStable(found, func(i, j int) bool { return found[i].InStock && !found[j].InStock })
+    return found, nil
+}
+
+// FuzzySearchProducts ищет товары по триграммной похожести названия, как
+// similarity() из pg_trgm: ниже threshold отбрасываются, остальные идут от
+// самых похожих
+func (s *Catalog) FuzzySearchProducts(ctx context.Context, query string, threshold float64) ([]storage.Product, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    score := make(map[int64]float64)
+    found := s.sorted(func(p storage.Product) bool {
+        score[p.ID] = storage.Similarity(query, p.Name)
+        return score[p.ID] >= threshold
+    })
+    sort.SliceStable(found, func(i, j int) bool {
+        if score[found[i].ID] != score[found[j].ID] {
+            return score[found[i].ID] > score[found[j].ID]
+        }
+        return found[i].InStock && !found[j].InStock
+    })
+    return found, nil
+}
+
+// reserve списывает остатки позиций заказа, как reserveStock в PostgreSQL:
+// если хотя бы одной не хватает, не списывается ничего и возвращается
+// *storage.OutOfStockError со всеми такими товарами
+func (s *Catalog) reserve(items []storage.OrderItem) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var missing []int64
+    for _, item := range items {
+        p, ok := s.products[item.ProductID]
+        if !ok || !p.InStock || (p.Stock != nil && *p.Stock < item.Qty) {
+            missing = append(missing, item.ProductID)
+        }
+    }
+    if len(missing) > 0 {
+        slices.Sort(missing)
+        return &storage.OutOfStockError{ProductIDs: missing}
+    }
+    for _, item := range items {
+        p := s.products[item.ProductID]
+        if p.Stock != nil {
+            left := *p.Stock - item.Qty
+            p.Stock, p.InStock = &left, left > 0
+        }
+        s.products[item.ProductID] = p
+    }
+    return nil
+}
+
+// release возвращает на склад позиции отменённого черновика; товаров,
+// удалённых из каталога, это не касается
+func (s *Catalog) release(items []storage.OrderItem) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, item := range items {
+        p, ok := s.products[item.ProductID]
+        if !ok || p.Stock == nil {
+            continue
+        }
+        left := *p.Stock + item.Qty
+        p.Stock, p.InStock = &left, true
+        s.products[item.ProductID] = p
+    }
+}
+
+// SemanticSearch выключен, как CatalogStore без EnableSemanticSearch
+func (s *Catalog) SemanticSearch(ctx context.Context, query string, k int) ([]storage.Product, error) {
+    return nil, storage.ErrSemanticDisabled
+}
+
+// SetPrice меняет цену товара, валюта остаётся прежней
+func (s *Catalog) SetPrice(ctx context.Context, id int64, minor int64, changedBy int64) (storage.Product, error) {
+    return s.edit(id, changedBy, "price", func(p *storage.Product) { p.Price.Minor = minor })
+}
+
+// SetStock задаёт остаток товара; в наличии — при остатке больше нуля
+func (s *Catalog) SetStock(ctx context.Context, id int64, qty int, changedBy int64) (storage.Product, error) {
+    return s.edit(id, changedBy, "stock", func(p *storage.Product) {
+        p.Stock = &qty
+        p.InStock = qty > 0
+    })
+}
+
+// SetCategory переносит товар в категорию; 0 — убрать из категорий.
+// Нет товара или категории — storage.ErrNotFound.
+func (s *Catalog) SetCategory(ctx context.Context, id, categoryID int64, changedBy int64) (storage.Product, error) {
+    s.mu.Lock()
+    known := categoryID == 0 || slices.ContainsFunc(s.categories, func(c storage.Category) bool { return c.ID == categoryID })
+    s.mu.Unlock()
+    if !known {
+        return storage.Product{}, storage.ErrNotFound
+    }
+    return s.edit(id, changedBy, "category", func(p *storage.Product) { p.CategoryID = categoryID })
+}
+
+func (s *Catalog) edit(id, changedBy int64, field string, change func(*storage.Product)) (storage.Product, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    p, ok := s.products[id]
+    if !ok {
+        return storage.Product{}, storage.ErrNotFound
+    }
+    change(&p)
+    s.products[id] = p
+    s.Audit = append(s.Audit, CatalogEdit{ProductID: id, Field: field, ChangedBy: changedBy})
+    return p, nil
+}
+
+// ImportProducts добавляет и обновляет товары по правилам CatalogStore:
+// по ID или по названию без учёта регистра; пустой остаток не затирает заведённый
+func (s *Catalog) ImportProducts(ctx context.Context, products []storage.Product) (storage.ImportResult, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.products == nil {
+        s.products = make(map[int64]storage.Product)
+    }
+    var res storage.ImportResult
+    for _, p := range products {
+        if p.ID == 0 {
+            for _, existing := range s.sorted(nil) {
+                if strings.EqualFold(existing.Name, p.Name) {
+                    p.ID = existing.ID
+                    break
+                }
+            }
+        }
+        old, exists := s.products[p.ID]
+        if p.ID == 0 {
+            p.ID = s.nextID()
+        }
+        if exists && p.Stock == nil {
+            p.Stock = old.Stock
+        }
+        if exists && p.CategoryID == 0 {
+            p.CategoryID = old.CategoryID
+        }
+        switch {
+        case !exists:
+            res.Added++
+        case sameProduct(old, p):
+            res.Unchanged++
+            continue
+        default:
+            res.Updated++
+        }
+        s.products[p.ID] = p
+    }
+    return res, nil
+}
+
+func (s *Catalog) nextID() int64 {
+    var max int64
+    for id := range s.products {
+        if id > max {
+            max = id
+        }
+    }
+    return max + 1
+}
+
+func sameProduct(a, b storage.Product) bool {
+    sameStock := (a.Stock == nil) == (b.Stock == nil) && (a.Stock == nil || *a.Stock == *b.Stock)
+    a.Stock, b.Stock = nil, nil
+    return a == b && sameStock
+}
+
+// ListCategories возвращает категории в порядке добавления с числом товаров
+func (s *Catalog) ListCategories(ctx context.Context) ([]storage.Category, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := append([]storage.Category(nil), s.categories...)
+    for i := range out {
+        out[i].Products = 0
+        for _, p := range s.products {
+            if p.CategoryID == out[i].ID {
+                out[i].Products++
+            }
+        }
+    }
+    return out, nil
+}
+
+// inCategory — товар в категории или её подкатегории; вызывается под s.mu
+func (s *Catalog) inCategory(p storage.Product, categoryID int64) bool {
+    c := p.CategoryID
+    if c == 0 {
+        return false
+    }
+    if c == categoryID {
+        return true
+    }
+    for _, cat := range s.categories {
+        if cat.ID == c && cat.ParentID == categoryID {
+            return true
+        }
+    }
+    return false
+}
+
+// ListProductsByCategory возвращает страницу товаров категории с подкатегориями
+func (s *Catalog) ListProductsByCategory(ctx context.Context, categoryID int64, limit, offset int) ([]storage.Product, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return page(s.sorted(func(p storage.Product) bool { return s.inCategory(p, categoryID) }), limit, offset), nil
+}
+
+// CountProductsByCategory возвращает число товаров категории с подкатегориями
+func (s *Catalog) CountProductsByCategory(ctx context.Context, categoryID int64) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return len(s.sorted(func(p storage.Product) bool { return s.inCategory(p, categoryID) })), nil
+}
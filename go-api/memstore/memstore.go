@@ -0,0 +1,1062 @@
+// Package memstore — хранилища бота в памяти для тестов без PostgreSQL и
+// Redis. Повторяют ограничения настоящих: данные разделены по chat_id,
+// уникальные ключи и допустимые переходы статусов проверяются так же.
+package memstore
+
+import (
+    "context"
+    "fmt"
+    "slices"
+    "sort"
+    "sync"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// Messages — история переписки в памяти
+type Messages struct {
+    mu     sync.Mutex
+    rows   []messageRow
+    nextID int64
+}
+
+type messageRow struct {
+    chatID   int64
+    msg      storage.Message
+    archived bool
+}
+
+// SaveMessage сохраняет сообщение чата
+func (s *Messages) SaveMessage(ctx context.Context, chatID int64, role, text string) error {
+    s.save(chatID, storage.Message{Role: role, Content: text})
+    return nil
+}
+
+// SaveIncompleteMessage сохраняет сообщение, помеченное незавершённым
+func (s *Messages) SaveIncompleteMessage(ctx context.Context, chatID int64, role, text string) error {
+    s.save(chatID, storage.Message{Role: role, Content: text, Incomplete: true})
+    return nil
+}
+
+func (s *Messages) save(chatID int64, msg storage.Message) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.nextID++
+    msg.ID, msg.CreatedAt = s.nextID, time.Now()
+    s.rows = append(s.rows, messageRow{chatID: chatID, msg: msg})
+}
+
+// HasMessages — писал ли чат когда-нибудь, включая архивную историю
+func (s *Messages) HasMessages(ctx context.Context, chatID int64) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, r := range s.rows {
+        if r.chatID == chatID {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// CountMessages — сколько неархивных сообщений чата хранится
+func (s *Messages) CountMessages(ctx context.Context, chatID int64) (int, error) {
+    return len(s.History(chatID)), nil
+}
+
+// ArchiveHistory убирает сообщения чата из контекста, не удаляя их
+func (s *Messages) ArchiveHistory(ctx context.Context, chatID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for i := range s.rows {
+        if s.rows[i].chatID == chatID {
+            s.rows[i].archived = true
+        }
+    }
+    return nil
+}
+
+// GetHistory возвращает последние limit неархивных сообщений чата, от старых к новым
+func (s *Messages) GetHistory(ctx context.Context, chatID int64, limit int) ([]storage.Message, error) {
+    history := s.History(chatID)
+    if len(history) > limit {
+        history = history[len(history)-limit:]
+    }
+    return history, nil
+}
+
+// Unsummarized — до limit неархивных сообщений чата с id больше after,
+// кроме keep самых новых, от старых к новым
+func (s *Messages) Unsummarized(ctx context.Context, chatID, after int64, keep, limit int) ([]storage.Message, error) {
+    history := s.History(chatID)
+    history = history[:max(len(history)-keep, 0)]
+    var out []storage.Message
+    for _, m := range history {
+        if m.ID > after && len(out) < limit {
+            out = append(out, m)
+        }
+    }
+    return out, nil
+}
+
+// History — неархивные сообщения чата от старых к новым, для проверок в тестах
+func (s *Messages) History(chatID int64) []storage.Message {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []storage.Message
+    for _, r := range s.rows {
+        if r.chatID == chatID && !r.archived {
+            out = append(out, r.msg)
+        }
+    }
+    return out
+}
+
+// Users — профили покупателей в памяти
+type Users struct {
+    mu    sync.Mutex
+    users map[int64]storage.User
+}
+
+// Upsert создаёт или обновляет профиль
+func (s *Users) Upsert(ctx context.Context, chatID int64, username, lang, name string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.users == nil {
+        s.users = make(map[int64]storage.User)
+    }
+    // Настройки покупателя (/brief, /currency, /lang) профиль не затирает
+    u := s.users[chatID]
+    u.ChatID, u.Username, u.Name, u.Lang = chatID, username, name, lang
+    s.users[chatID] = u
+    return nil
+}
+
+// SetBrief включает или выключает краткий режим ответов
+func (s *Users) SetBrief(ctx context.Context, chatID int64, brief bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.users == nil {
+        s.users = make(map[int64]storage.User)
+    }
+    u := s.users[chatID]
+    u.ChatID = chatID
+    u.Brief = brief
+    s.users[chatID] = u
+    return nil
+}
+
+// SetCurrency сохраняет валюту показа цен
+func (s *Users) SetCurrency(ctx context.Context, chatID int64, currency string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.users == nil {
+        s.users = make(map[int64]storage.User)
+    }
+    u := s.users[chatID]
+    u.ChatID = chatID
+    u.Currency = currency
+    s.users[chatID] = u
+    return nil
+}
+
+// SetLang сохраняет язык общения
+func (s *Users) SetLang(ctx context.Context, chatID int64, lang string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.users == nil {
+        s.users = make(map[int64]storage.User)
+    }
+    u := s.users[chatID]
+    u.ChatID = chatID
+    u.PreferredLang = lang
+    s.users[chatID] = u
+    return nil
+}
+
+// SetPromptVariant закрепляет за чатом вариант системного промпта
+func (s *Users) SetPromptVariant(ctx context.Context, chatID int64, variant string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.users == nil {
+        s.users = make(map[int64]storage.User)
+    }
+    u := s.users[chatID]
+    u.ChatID = chatID
+    u.PromptVariant = variant
+    s.users[chatID] = u
+    return nil
+}
+
+// SetMenuHidden запоминает, скрыл ли чат меню под полем ввода
+func (s *Users) SetMenuHidden(ctx context.Context, chatID int64, hidden bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.users == nil {
+        s.users = make(map[int64]storage.User)
+    }
+    u := s.users[chatID]
+    u.ChatID = chatID
+    u.MenuHidden = hidden
+    s.users[chatID] = u
+    return nil
+}
+
+// GetUser возвращает профиль или storage.ErrNotFound
+func (s *Users) GetUser(ctx context.Context, chatID int64) (storage.User, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    u, ok := s.users[chatID]
+    if !ok {
+        return storage.User{}, storage.ErrNotFound
+    }
+    return u, nil
+}
+
+// Carts — корзины в памяти. Срок жизни корзины не моделируется: корзина
+// живёт, пока её не очистят.
+type Carts struct {
+    mu    sync.Mutex
+    carts map[int64]map[int64]int
+    // touched — время последнего изменения корзины; запись снимает ClaimReminder
+    touched map[int64]time.Time
+}
+
+// AddItem добавляет qty единиц товара к уже имеющимся
+func (s *Carts) AddItem(ctx context.Context, chatID, productID int64, qty int) error {
+    if qty <= 0 {
+        return fmt.Errorf("количество должно быть положительным, получено %d", qty)
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.carts == nil {
+        s.carts = make(map[int64]map[int64]int)
+        s.touched = make(map[int64]time.Time)
+    }
+    if s.carts[chatID] == nil {
+        s.carts[chatID] = make(map[int64]int)
+    }
+    s.carts[chatID][productID] += qty
+    s.touched[chatID] = time.Now()
+    return nil
+}
+
+// RemoveItem убирает товар из корзины целиком
+func (s *Carts) RemoveItem(ctx context.Context, chatID, productID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if cart := s.carts[chatID]; cart != nil {
+        delete(cart, productID)
+        s.touched[chatID] = time.Now()
+    }
+    return nil
+}
+
+// GetCart возвращает корзину чата; позиции упорядочены по id товара
+func (s *Carts) GetCart(ctx context.Context, chatID int64) (cache.Cart, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    cart := cache.Cart{ChatID: chatID}
+    for productID, qty := range s.carts[chatID] {
+        if qty > 0 {
+            cart.Items = append(cart.Items, cache.CartItem{ProductID: productID, Qty: qty})
+        }
+    }
+    sort.Slice(cart.Items, func(i, j int) bool {
+        return cart.Items[i].ProductID < cart.Items[j].ProductID
+    })
+    return cart, nil
+}
+
+// ClearCart удаляет корзину чата
+func (s *Carts) ClearCart(ctx context.Context, chatID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.carts, chatID)
+    delete(s.touched, chatID)
+    return nil
+}
+
+// IdleCarts возвращает чаты, чьи корзины не менялись дольше idle
+func (s *Carts) IdleCarts(ctx context.Context, idle time.Duration) ([]int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    cutoff := time.Now().Add(-idle)
+    var out []int64
+    for chatID, at := range s.touched {
+        if !at.After(cutoff) {
+            out = append(out, chatID)
+        }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+    return out, nil
+}
+
+// ClaimReminder отмечает напоминание; true — отметка поставлена этим вызовом
+func (s *Carts) ClaimReminder(ctx context.Context, chatID int64) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.touched[chatID]; !ok {
+        return false, nil
+    }
+    delete(s.touched, chatID)
+    return true, nil
+}
+
+// transitions — допустимые смены статуса, как в order_status_transitions
+var transitions = map[storage.OrderStatus][]storage.OrderStatus{
+    storage.OrderDraft: {storage.OrderNew, storage.OrderCancelled},
+    storage.OrderNew:   {storage.OrderPaid, storage.OrderCancelled},
+    storage.OrderPaid:  {storage.OrderShipped, storage.OrderCancelled},
+}
+
+// Orders — заказы в памяти
+type Orders struct {
+    mu     sync.Mutex
+    orders []storage.Order
+    // byKey — id последнего заказа по ключу идемпотентности
+    byKey map[string]int64
+    // notices — уведомления о смене статуса по storage.StatusNoticeKey,
+    // как строки outbox с dedupe_key
+    notices map[string]Notice
+
+    // Outbox — очередь, куда SetStatus ставит уведомления, как PostgreSQL в
+    // той же транзакции; nil — уведомления только запоминаются в Notices
+    Outbox *Outbox
+    // Catalog — каталог, с остатков которого CreateOrder списывает позиции
+    // (а DiscardDraft возвращает); nil — остатки не проверяются
+    Catalog *Catalog
+}
+
+// Notice — уведомление покупателю, поставленное SetStatus
+type Notice struct {
+    ChatID int64
+    Text   string
+}
+
+// CreateOrder сохраняет заказ черновиком; если неотменённый заказ
+// с idempotencyKey создан за последние window, возвращает его id
+func (s *Orders) CreateOrder(ctx context.Context, chatID int64, items []storage.OrderItem, total money.Money, idempotencyKey string, window time.Duration) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if id, ok := s.byKey[idempotencyKey]; ok && time.Since(s.orders[id-1].CreatedAt) < window &&
+        s.orders[id-1].Status != storage.OrderCancelled {
+        return id, nil
+    }
+    for _, item := range items {
+        if item.Qty <= 0 {
+            return 0, fmt.Errorf("ошибка записи позиции заказа: количество %d", item.Qty)
+        }
+    }
+    if s.Catalog != nil {
+        if err := s.Catalog.reserve(items); err != nil {
+            return 0, err
+        }
+    }
+
+    id := int64(len(s.orders) + 1)
+    sorted := append([]storage.OrderItem(nil), items...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+    s.orders = append(s.orders, storage.Order{
+        ID: id, ChatID: chatID, Total: total, Status: storage.OrderDraft, CreatedAt: time.Now(), Items: sorted,
+    })
+    if s.byKey == nil {
+        s.byKey = make(map[string]int64)
+    }
+    s.byKey[idempotencyKey] = id
+    return id, nil
+}
+
+// GetOrder возвращает заказ чата; чужой заказ — storage.ErrNotFound
+func (s *Orders) GetOrder(ctx context.Context, orderID, chatID int64) (storage.Order, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    o, ok := s.find(orderID)
+    if !ok || o.ChatID != chatID {
+        return storage.Order{}, storage.ErrNotFound
+    }
+    o.Items = append([]storage.OrderItem(nil), o.Items...)
+    return *o, nil
+}
+
+// ChatOrders возвращает заказы чата по порядку создания
+func (s *Orders) ChatOrders(ctx context.Context, chatID int64) ([]storage.Order, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []storage.Order
+    for _, o := range s.orders {
+        if o.ChatID == chatID {
+            o.Items = append([]storage.OrderItem(nil), o.Items...)
+            out = append(out, o)
+        }
+    }
+    return out, nil
+}
+
+// OrderState возвращает состояние заказа или storage.ErrNotFound
+func (s *Orders) OrderState(ctx context.Context, orderID int64) (storage.OrderState, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    o, ok := s.find(orderID)
+    if !ok {
+        return storage.OrderState{}, storage.ErrNotFound
+    }
+    return storage.OrderState{ChatID: o.ChatID, Status: o.Status, Total: o.Total}, nil
+}
+
+// SetStatus меняет статус, если переход разрешён, и запоминает notice (и
+// ставит его в Outbox), если уведомления об этом переходе ещё не было
+func (s *Orders) SetStatus(ctx context.Context, orderID int64, to storage.OrderStatus, notice string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    o, ok := s.find(orderID)
+    if !ok {
+        return storage.ErrNotFound
+    }
+    for _, next := range transitions[o.Status] {
+        if next == to {
+            o.Status = to
+            key := storage.StatusNoticeKey(orderID, to)
+            if _, seen := s.notices[key]; notice != "" && !seen {
+                if s.notices == nil {
+                    s.notices = make(map[string]Notice)
+                }
+                s.notices[key] = Notice{ChatID: o.ChatID, Text: notice}
+                if s.Outbox != nil {
+                    if _, err := s.Outbox.Enqueue(ctx, o.ChatID, notice, false, 0); err != nil {
+                        return err
+                    }
+                }
+            }
+            return nil
+        }
+    }
+    return fmt.Errorf("%w: %s → %s", storage.ErrInvalidTransition, o.Status, to)
+}
+
+// ConfirmOrder переводит черновик в новый заказ; подтверждённый заказ
+// не меняется, отменённый — storage.ErrInvalidTransition
+func (s *Orders) ConfirmOrder(ctx context.Context, orderID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    o, ok := s.find(orderID)
+    if !ok {
+        return storage.ErrNotFound
+    }
+    switch o.Status {
+    case storage.OrderDraft:
+        o.Status = storage.OrderNew
+    case storage.OrderCancelled:
+        return fmt.Errorf("%w: %s → %s", storage.ErrInvalidTransition, o.Status, storage.OrderNew)
+    }
+    return nil
+}
+
+// LatestDraft возвращает последний черновик чата, созданный не раньше
+// since; storage.ErrNotFound — такого нет
+func (s *Orders) LatestDraft(ctx context.Context, chatID int64, since time.Time) (storage.Order, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for i := len(s.orders) - 1; i >= 0; i-- {
+        o := s.orders[i]
+        if o.ChatID == chatID && o.Status == storage.OrderDraft && !o.CreatedAt.Before(since) {
+            o.Items = append([]storage.OrderItem(nil), o.Items...)
+            return o, nil
+        }
+    }
+    return storage.Order{}, storage.ErrNotFound
+}
+
+// DiscardDraft отменяет черновик и возвращает его позиции в Catalog;
+// storage.ErrNotFound — заказа нет или он уже не черновик
+func (s *Orders) DiscardDraft(ctx context.Context, orderID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    o, ok := s.find(orderID)
+    if !ok || o.Status != storage.OrderDraft {
+        return storage.ErrNotFound
+    }
+    o.Status = storage.OrderCancelled
+    if s.Catalog != nil {
+        s.Catalog.release(o.Items)
+    }
+    return nil
+}
+
+// Notices возвращает уведомления о смене статуса заказов
+func (s *Orders) Notices() []Notice {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]Notice, 0, len(s.notices))
+    for _, n := range s.notices {
+        out = append(out, n)
+    }
+    return out
+}
+
+// OrdersBetween возвращает заказы, созданные в [from, to), без черновиков
+func (s *Orders) OrdersBetween(ctx context.Context, from, to time.Time) ([]storage.OrderSummary, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []storage.OrderSummary
+    for _, o := range s.orders {
+        if !o.CreatedAt.Before(from) && o.CreatedAt.Before(to) && o.Status != storage.OrderDraft {
+            out = append(out, storage.OrderSummary{ID: o.ID, Total: o.Total, Status: o.Status, CreatedAt: o.CreatedAt})
+        }
+    }
+    return out, nil
+}
+
+func (s *Orders) find(orderID int64) (*storage.Order, bool) {
+    if orderID < 1 || orderID > int64(len(s.orders)) {
+        return nil, false
+    }
+    return &s.orders[orderID-1], true
+}
+
+// Feedback — оценки ответов в памяти
+type Feedback struct {
+    mu      sync.Mutex
+    ratings map[[2]int64]int
+}
+
+// Record сохраняет оценку; повторная оценка сообщения заменяет прежнюю
+func (s *Feedback) Record(ctx context.Context, chatID, messageID int64, rating int) error {
+    if rating != storage.RatingUp && rating != storage.RatingDown {
+        return fmt.Errorf("оценка должна быть %d или %d, получено %d", storage.RatingUp, storage.RatingDown, rating)
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.ratings == nil {
+        s.ratings = make(map[[2]int64]int)
+    }
+    s.ratings[[2]int64{chatID, messageID}] = rating
+    return nil
+}
+
+// Stats считает положительные и отрицательные оценки
+func (s *Feedback) Stats(ctx context.Context) (storage.FeedbackStats, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var st storage.FeedbackStats
+    for _, r := range s.ratings {
+        if r > 0 {
+            st.Up++
+        } else {
+            st.Down++
+        }
+    }
+    return st, nil
+}
+
+// Attributions — источники покупателей в памяти
+type Attributions struct {
+    mu   sync.Mutex
+    rows map[int64]storage.Attribution
+}
+
+// Record сохраняет источник; как в PostgreSQL, учитывается первое касание
+func (s *Attributions) Record(ctx context.Context, chatID int64, a storage.Attribution) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.rows[chatID]; ok {
+        return false, nil
+    }
+    if s.rows == nil {
+        s.rows = make(map[int64]storage.Attribution)
+    }
+    s.rows[chatID] = a
+    return true, nil
+}
+
+// Get — записанный источник чата
+func (s *Attributions) Get(chatID int64) (storage.Attribution, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    a, ok := s.rows[chatID]
+    return a, ok
+}
+
+// challengeKey — участник группы
+type challengeKey struct{ chatID, userID int64 }
+
+// MemberChallenges — незавершённые проверки участников групп в памяти
+type MemberChallenges struct {
+    mu   sync.Mutex
+    rows map[challengeKey]storage.MemberChallenge
+}
+
+// Create сохраняет проверку; повторный вход начинает её заново
+func (s *MemberChallenges) Create(ctx context.Context, c storage.MemberChallenge) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.rows == nil {
+        s.rows = make(map[challengeKey]storage.MemberChallenge)
+    }
+    s.rows[challengeKey{c.ChatID, c.UserID}] = c
+    return nil
+}
+
+// Resolve снимает проверку или возвращает storage.ErrNotFound
+func (s *MemberChallenges) Resolve(ctx context.Context, chatID, userID int64) (storage.MemberChallenge, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    key := challengeKey{chatID, userID}
+    c, ok := s.rows[key]
+    if !ok {
+        return storage.MemberChallenge{}, storage.ErrNotFound
+    }
+    delete(s.rows, key)
+    return c, nil
+}
+
+// ClaimExpired забирает до limit проверок, время которых вышло к now
+func (s *MemberChallenges) ClaimExpired(ctx context.Context, now time.Time, limit int) ([]storage.MemberChallenge, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []storage.MemberChallenge
+    for key, c := range s.rows {
+        if len(out) == limit {
+            break
+        }
+        if !c.ExpiresAt.After(now) {
+            out = append(out, c)
+            delete(s.rows, key)
+        }
+    }
+    return out, nil
+}
+
+// Chats — пометки неактивных чатов и группы с ботом в памяти. Список чатов
+// для рассылок берётся из Messages, как в PostgreSQL — из истории; порядок
+// пометки и последнего сообщения не моделируется: помеченный чат неактивен.
+type Chats struct {
+    Messages *Messages
+
+    mu       sync.Mutex
+    inactive map[int64]bool
+    // groups — группа → название; false в joined — бота из неё удалили
+    groups map[int64]string
+    joined map[int64]bool
+    // verify — группы с проверкой новых участников
+    verify map[int64]bool
+}
+
+// ActiveChatIDs возвращает чаты с историей, кроме помеченных неактивными
+func (s *Chats) ActiveChatIDs(ctx context.Context) ([]int64, error) {
+    seen := make(map[int64]bool)
+    if s.Messages != nil {
+        s.Messages.mu.Lock()
+        for _, r := range s.Messages.rows {
+            seen[r.chatID] = true
+        }
+        s.Messages.mu.Unlock()
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var ids []int64
+    for id := range seen {
+        if !s.inactive[id] {
+            ids = append(ids, id)
+        }
+    }
+    sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+    return ids, nil
+}
+
+// MarkInactive помечает чат заблокировавшим бота
+func (s *Chats) MarkInactive(ctx context.Context, chatID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.inactive == nil {
+        s.inactive = make(map[int64]bool)
+    }
+    s.inactive[chatID] = true
+    return nil
+}
+
+// MarkActive снимает пометку MarkInactive
+func (s *Chats) MarkActive(ctx context.Context, chatID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.inactive, chatID)
+    return nil
+}
+
+// JoinGroup записывает группу, в которую добавили бота
+func (s *Chats) JoinGroup(ctx context.Context, chatID int64, title string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.groups == nil {
+        s.groups = make(map[int64]string)
+        s.joined = make(map[int64]bool)
+    }
+    s.groups[chatID] = title
+    s.joined[chatID] = true
+    return nil
+}
+
+// LeaveGroup отмечает, что бота удалили из группы
+func (s *Chats) LeaveGroup(ctx context.Context, chatID int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.groups[chatID]; ok {
+        s.joined[chatID] = false
+    }
+    return nil
+}
+
+// SetMemberVerification включает или выключает проверку участников группы
+func (s *Chats) SetMemberVerification(ctx context.Context, chatID int64, on bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.verify == nil {
+        s.verify = make(map[int64]bool)
+    }
+    s.verify[chatID] = on
+    return nil
+}
+
+// MemberVerification — включена ли проверка участников группы
+func (s *Chats) MemberVerification(ctx context.Context, chatID int64) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.verify[chatID], nil
+}
+
+// Inactive — помечен ли чат неактивным, для проверок в тестах
+func (s *Chats) Inactive(chatID int64) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.inactive[chatID]
+}
+
+// Group — название группы и состоит ли в ней бот, для проверок в тестах
+func (s *Chats) Group(chatID int64) (title string, joined bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.groups[chatID], s.joined[chatID]
+}
+
+// Reengagement — подсказки замолчавшим покупателям в памяти. Активность
+// берётся из Messages, заказы — из Orders, блокировки — из Chats; любое из
+// них можно не задавать. Как и в Chats, помеченный чат неактивен.
+type Reengagement struct {
+    Messages *Messages
+    Orders   *Orders
+    Chats    *Chats
+
+    mu sync.Mutex
+    // nudged — время последней подсказки чату
+    nudged map[int64]time.Time
+}
+
+// Candidates — до limit личных чатов из shoppers, где покупатель последний
+// раз писал от lookback до idle назад, без заказов за lookback и подсказок
+// за cooldown
+func (s *Reengagement) Candidates(ctx context.Context, now time.Time, idle, lookback, cooldown time.Duration, limit int, shoppers []int64) ([]int64, error) {
+    last := make(map[int64]time.Time)
+    if s.Messages != nil {
+        s.Messages.mu.Lock()
+        for _, r := range s.Messages.rows {
+            if r.chatID > 0 && slices.Contains(shoppers, r.chatID) && r.msg.Role == "user" && !r.archived && r.msg.CreatedAt.After(last[r.chatID]) {
+                last[r.chatID] = r.msg.CreatedAt
+            }
+        }
+        s.Messages.mu.Unlock()
+    }
+
+    from, to := now.Add(-lookback), now.Add(-idle)
+    ordered := make(map[int64]bool)
+    if s.Orders != nil {
+        s.Orders.mu.Lock()
+        for _, o := range s.Orders.orders {
+            if !o.CreatedAt.Before(from) {
+                ordered[o.ChatID] = true
+            }
+        }
+        s.Orders.mu.Unlock()
+    }
+    inactive := make(map[int64]bool)
+    if s.Chats != nil {
+        s.Chats.mu.Lock()
+        for id := range s.Chats.inactive {
+            inactive[id] = true
+        }
+        s.Chats.mu.Unlock()
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var ids []int64
+    for id, at := range last {
+        if at.Before(from) || at.After(to) || ordered[id] || inactive[id] {
+            continue
+        }
+        if sent, ok := s.nudged[id]; ok && sent.After(now.Add(-cooldown)) {
+            continue
+        }
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool { return last[ids[i]].After(last[ids[j]]) })
+    if len(ids) > limit {
+        ids = ids[:limit]
+    }
+    return ids, nil
+}
+
+// ClaimNudge отмечает подсказку, если прошлая была раньше чем за cooldown;
+// true — отметку поставил этот вызов
+func (s *Reengagement) ClaimNudge(ctx context.Context, chatID int64, now time.Time, cooldown time.Duration) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if sent, ok := s.nudged[chatID]; ok && sent.After(now.Add(-cooldown)) {
+        return false, nil
+    }
+    if s.nudged == nil {
+        s.nudged = make(map[int64]time.Time)
+    }
+    s.nudged[chatID] = now
+    return true, nil
+}
+
+// Privacy — выгрузка и удаление данных покупателя в памяти: Export отдаёт
+// Exports[chatID], Erase запоминает чат в Erased
+type Privacy struct {
+    mu      sync.Mutex
+    Exports map[int64]storage.DataExport
+    erased  []int64
+}
+
+// Export — заданная выгрузка чата
+func (s *Privacy) Export(ctx context.Context, chatID int64) (storage.DataExport, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.Exports[chatID], nil
+}
+
+// Erase запоминает удалённый чат; обезличенных заказов — 0
+func (s *Privacy) Erase(ctx context.Context, chatID int64) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.erased = append(s.erased, chatID)
+    delete(s.Exports, chatID)
+    return 0, nil
+}
+
+// Erased — чаты, данные которых удаляли, по порядку
+func (s *Privacy) Erased() []int64 {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]int64(nil), s.erased...)
+}
+
+// outboxRow — запись очереди ответов в памяти
+type outboxRow struct {
+    msg         storage.OutboxMessage
+    status      string
+    nextAttempt time.Time
+    claimedAt   time.Time
+    lastErr     string
+    createdAt   time.Time
+}
+
+// Outbox — очередь исходящих ответов в памяти с теми же переходами
+// pending → sending → sent/failed, что и в PostgreSQL
+type Outbox struct {
+    mu     sync.Mutex
+    rows   []*outboxRow
+    nextID int64
+}
+
+// Enqueue ставит ответ в очередь и возвращает id записи
+func (s *Outbox) Enqueue(ctx context.Context, chatID int64, text string, withFeedback bool, replyTo int64) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.nextID++
+    now := time.Now()
+    s.rows = append(s.rows, &outboxRow{
+        msg:         storage.OutboxMessage{ID: s.nextID, ChatID: chatID, Text: text, WithFeedback: withFeedback, ReplyTo: replyTo},
+        status:      "pending",
+        nextAttempt: now,
+        createdAt:   now,
+    })
+    return s.nextID, nil
+}
+
+// Claim забирает до limit записей, которым пора уйти, в порядке id
+func (s *Outbox) Claim(ctx context.Context, limit int, stale time.Duration) ([]storage.OutboxMessage, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now()
+    var out []storage.OutboxMessage
+    for _, r := range s.rows {
+        if len(out) == limit {
+            break
+        }
+        due := r.status == "pending" && !r.nextAttempt.After(now)
+        abandoned := r.status == "sending" && r.claimedAt.Before(now.Add(-stale))
+        if !due && !abandoned {
+            continue
+        }
+        r.status, r.claimedAt = "sending", now
+        r.msg.Attempts++
+        out = append(out, r.msg)
+    }
+    return out, nil
+}
+
+// MarkPartSent запоминает число доставленных частей ответа
+func (s *Outbox) MarkPartSent(ctx context.Context, id int64, parts int) error {
+    return s.update(id, func(r *outboxRow) { r.msg.SentParts = parts })
+}
+
+// MarkSent отмечает ответ отправленным
+func (s *Outbox) MarkSent(ctx context.Context, id int64) error {
+    return s.update(id, func(r *outboxRow) { r.status = "sent" })
+}
+
+// Retry возвращает ответ в очередь с отсрочкой after
+func (s *Outbox) Retry(ctx context.Context, id int64, after time.Duration, cause error) error {
+    return s.update(id, func(r *outboxRow) {
+        r.status, r.nextAttempt, r.lastErr = "pending", time.Now().Add(after), cause.Error()
+    })
+}
+
+// Fail снимает ответ с отправки
+func (s *Outbox) Fail(ctx context.Context, id int64, cause error) error {
+    return s.update(id, func(r *outboxRow) { r.status, r.lastErr = "failed", cause.Error() })
+}
+
+// PruneOutbox удаляет отправленные и неотправляемые записи старше retention
+func (s *Outbox) PruneOutbox(ctx context.Context, retention time.Duration) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    cutoff := time.Now().Add(-retention)
+    kept := s.rows[:0]
+    var deleted int64
+    for _, r := range s.rows {
+        if (r.status == "sent" || r.status == "failed") && r.createdAt.Before(cutoff) {
+            deleted++
+            continue
+        }
+        kept = append(kept, r)
+    }
+    s.rows = kept
+    return deleted, nil
+}
+
+// Status — состояние записи id, число доставленных частей и число попыток
+func (s *Outbox) Status(id int64) (status string, sentParts, attempts int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, r := range s.rows {
+        if r.msg.ID == id {
+            return r.status, r.msg.SentParts, r.msg.Attempts
+        }
+    }
+    return "", 0, 0
+}
+
+// Due делает отсроченные записи готовыми к отправке сейчас
+func (s *Outbox) Due() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, r := range s.rows {
+        r.nextAttempt = time.Time{}
+    }
+}
+
+func (s *Outbox) update(id int64, change func(*outboxRow)) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, r := range s.rows {
+        if r.msg.ID == id {
+            change(r)
+            return nil
+        }
+    }
+    return storage.ErrNotFound
+}
+
+// FailedUpdates — журнал необработанных апдейтов в памяти
+type FailedUpdates struct {
+    mu     sync.Mutex
+    rows   []storage.FailedUpdate
+    nextID int64
+}
+
+// Record сохраняет апдейт с ошибкой обработки
+func (s *FailedUpdates) Record(ctx context.Context, updateID, chatID int64, payload []byte, cause error) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.nextID++
+    s.rows = append(s.rows, storage.FailedUpdate{
+        ID: s.nextID, UpdateID: updateID, ChatID: chatID,
+        Payload: append([]byte(nil), payload...), Error: cause.Error(), CreatedAt: time.Now(),
+    })
+    return nil
+}
+
+// ListFailed — до limit самых старых записей
+func (s *FailedUpdates) ListFailed(ctx context.Context, limit int) ([]storage.FailedUpdate, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    n := min(limit, len(s.rows))
+    return append([]storage.FailedUpdate(nil), s.rows[:n]...), nil
+}
+
+// DeleteFailed удаляет запись id
+func (s *FailedUpdates) DeleteFailed(ctx context.Context, id int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for i, f := range s.rows {
+        if f.ID == id {
+            s.rows = append(s.rows[:i], s.rows[i+1:]...)
+            return nil
+        }
+    }
+    return nil
+}
+
+// Len — сколько записей в журнале
+func (s *FailedUpdates) Len() int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return len(s.rows)
+}
+
+// Summaries — сводки диалогов в памяти; как и в PostgreSQL, сводка по более
+// старым сообщениям новую не затирает
+type Summaries struct {
+    mu   sync.Mutex
+    rows map[int64]storage.Summary
+}
+
+// GetSummary — сводка чата или storage.ErrNotFound
+func (s *Summaries) GetSummary(ctx context.Context, chatID int64) (storage.Summary, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sum, ok := s.rows[chatID]
+    if !ok {
+        return storage.Summary{}, storage.ErrNotFound
+    }
+    return sum, nil
+}
+
+// SaveSummary сохраняет сводку по сообщениям до upTo включительно
+func (s *Summaries) SaveSummary(ctx context.Context, chatID int64, text string, upTo int64) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.rows == nil {
+        s.rows = make(map[int64]storage.Summary)
+    }
+    if prev, ok := s.rows[chatID]; ok && prev.UpTo >= upTo {
+        return nil
+    }
+    s.rows[chatID] = storage.Summary{Text: text, UpTo: upTo, UpdatedAt: time.Now()}
+    return nil
+}
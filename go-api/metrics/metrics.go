@@ -0,0 +1,124 @@
+// Package metrics — метрики Prometheus сервиса; все регистрируются здесь
+package metrics
+
+import (
+    "sync"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// activeChatWindow — чат считается активным, если писал за это время
+const activeChatWindow = 30 * time.Minute
+
+var (
+    // UpdatesTotal — полученные апдейты Telegram по типу
+    UpdatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "aiseller_telegram_updates_total",
+        Help: "Апдейты Telegram по типу.",
+    }, []string{"type"})
+
+    // DuplicateUpdatesTotal — отброшенные повторные доставки апдейтов
+    DuplicateUpdatesTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "aiseller_telegram_duplicate_updates_total",
+        Help: "Повторные доставки апдейтов, отброшенные по update_id.",
+    })
+
+    // DroppedUpdatesTotal — апдейты, не попавшие в переполненную очередь обработки
+    DroppedUpdatesTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "aiseller_telegram_dropped_updates_total",
+        Help: "Апдейты, отброшенные из-за переполненной очереди.",
+    })
+
+    // ResponseCacheTotal — обращения к кэшу ответов модели: hit или miss
+    ResponseCacheTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "aiseller_response_cache_requests_total",
+        Help: "Обращения к кэшу ответов модели по результату (hit, miss).",
+    }, []string{"result"})
+
+    // OpenAIRequestDuration — длительность одной попытки запроса к OpenAI
+    OpenAIRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+        Name:    "aiseller_openai_request_duration_seconds",
+        Help:    "Длительность запросов к OpenAI.",
+        Buckets: []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+    })
+
+    // OpenAIAdaptiveTimeout — текущий адаптивный предел запроса к модели
+    // (OPENAI_ADAPTIVE_TIMEOUT)
+    OpenAIAdaptiveTimeout = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "aiseller_openai_adaptive_timeout_seconds",
+        Help: "Адаптивный предел на попытку запроса к модели.",
+    })
+
+    // OpenAIInFlight — запросы к OpenAI, выполняющиеся прямо сейчас
+    OpenAIInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "aiseller_openai_requests_in_flight",
+        Help: "Запросы к OpenAI в процессе выполнения.",
+    })
+
+    // BreakerState — состояние предохранителя зависимости: 0 — замкнут,
+    // 1 — пробный вызов, 2 — разомкнут
+    BreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+        Name: "aiseller_breaker_state",
+        Help: "Состояние предохранителя зависимости (0 closed, 1 half-open, 2 open).",
+    }, []string{"dependency"})
+
+    // OpenAIErrorsTotal — ошибки OpenAI по HTTP-статусу ("network" — без ответа)
+    OpenAIErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "aiseller_openai_errors_total",
+        Help: "Ошибки запросов к OpenAI по статусу.",
+    }, []string{"status"})
+
+    // OpenAIAccountRequests — запросы к OpenAI в текущей минуте по всем экземплярам
+    OpenAIAccountRequests = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "aiseller_openai_account_requests",
+        Help: "Запросы к OpenAI за текущую минуту по всем экземплярам сервиса.",
+    })
+
+    // OpenAIAccountTokens — оценка токенов OpenAI в текущей минуте по всем экземплярам
+    OpenAIAccountTokens = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "aiseller_openai_account_tokens",
+        Help: "Оценка токенов OpenAI за текущую минуту по всем экземплярам сервиса.",
+    })
+
+    // OpenAIAccountThrottledTotal — запросы, не дождавшиеся места в минутном лимите аккаунта
+    OpenAIAccountThrottledTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "aiseller_openai_account_throttled_total",
+        Help: "Запросы к OpenAI, отклонённые минутным лимитом аккаунта.",
+    })
+)
+
+// activeChats — когда каждый чат писал в последний раз
+var activeChats = struct {
+    sync.Mutex
+    seen map[int64]time.Time
+}{seen: make(map[int64]time.Time)}
+
+func init() {
+    promauto.NewGaugeFunc(prometheus.GaugeOpts{
+        Name: "aiseller_active_chats",
+        Help: "Чаты, писавшие боту за последние 30 минут.",
+    }, countActiveChats)
+}
+
+// TouchChat отмечает активность чата
+func TouchChat(chatID int64) {
+    activeChats.Lock()
+    activeChats.seen[chatID] = time.Now()
+    activeChats.Unlock()
+}
+
+// countActiveChats считает активные чаты и забывает давно молчащие
+func countActiveChats() float64 {
+    activeChats.Lock()
+    defer activeChats.Unlock()
+
+    cutoff := time.Now().Add(-activeChatWindow)
+    for id, t := range activeChats.seen {
+        if t.Before(cutoff) {
+            delete(activeChats.seen, id)
+        }
+    }
+    return float64(len(activeChats.seen))
+}
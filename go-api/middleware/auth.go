@@ -11,8 +11,8 @@ func AuthMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         authHeader := r.Header.Get("Authorization")
         if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-            http.Error(w, "🚫 Нет токена авторизации", 
-http.StatusUnauthorized)
+            http.Error(w, "🚫 Нет токена авторизации",
+                http.StatusUnauthorized)
             return
         }
 
@@ -28,4 +28,3 @@ http.StatusUnauthorized)
         next.ServeHTTP(w, r)
     })
 }
-
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+    "net/http"
+    "slices"
+    "strconv"
+    "time"
+)
+
+const (
+    // corsAllowMethods — методы, которые разрешаем сторонним страницам
+    corsAllowMethods = "GET, POST, OPTIONS"
+    // corsAllowHeaders — заголовки, которые страница может прислать
+    corsAllowHeaders = "Authorization, Content-Type"
+    // corsMaxAge — сколько браузер кэширует ответ на preflight
+    corsMaxAge = 10 * time.Minute
+)
+
+// CORSMiddleware разрешает кросс-доменные запросы с origins (например,
+// https://admin.example.com; "*" — с любого) и отвечает на preflight OPTIONS.
+// Пустой список — CORS выключен, заголовки не ставятся.
+func CORSMiddleware(origins []string) func(http.Handler) http.Handler {
+    anyOrigin := slices.Contains(origins, "*")
+    allowed := func(origin string) bool {
+        return anyOrigin || slices.Contains(origins, origin)
+    }
+
+    return func(next http.Handler) http.Handler {
+        if len(origins) == 0 {
+            return next
+        }
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            origin := r.Header.Get("Origin")
+            if origin == "" {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            // Ответ зависит от Origin — промежуточные кэши не должны его смешивать
+            w.Header().Add("Vary", "Origin")
+            preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+            if !allowed(origin) {
+                if preflight {
+                    w.WriteHeader(http.StatusForbidden)
+                    return
+                }
+                // Без CORS-заголовков браузер сам не отдаст ответ странице
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            w.Header().Set("Access-Control-Allow-Origin", origin)
+            if preflight {
+                w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+                w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+                w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+                w.WriteHeader(http.StatusNoContent)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// corsRequest — запрос method со страницы origin; preflight — с
+// Access-Control-Request-Method
+func corsRequest(origins []string, method, origin string, preflight bool) *httptest.ResponseRecorder {
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+    r := httptest.NewRequest(method, "/stats", nil)
+    if origin != "" {
+        r.Header.Set("Origin", origin)
+    }
+    if preflight {
+        r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+    }
+    w := httptest.NewRecorder()
+    CORSMiddleware(origins)(next).ServeHTTP(w, r)
+    return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+    origins := []string{"https://admin.example.com"}
+
+    w := corsRequest(origins, http.MethodOptions, "https://admin.example.com", true)
+    if w.Code != http.StatusNoContent {
+        t.Fatalf("preflight разрешённой страницы: код %d", w.Code)
+    }
+    for header, want := range map[string]string{
+        "Access-Control-Allow-Origin":  "https://admin.example.com",
+        "Access-Control-Allow-Methods": corsAllowMethods,
+        "Access-Control-Allow-Headers": corsAllowHeaders,
+        "Access-Control-Max-Age":       "600",
+        "Vary":                         "Origin",
+    } {
+        if got := w.Header().Get(header); got != want {
+            t.Errorf("%s: %q, нужно %q", header, got, want)
+        }
+    }
+
+    w = corsRequest(origins, http.MethodOptions, "https://evil.example", true)
+    if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+        t.Fatalf("preflight чужой страницы: код %d, заголовки %v", w.Code, w.Header())
+    }
+}
+
+func TestCORSRequests(t *testing.T) {
+    cases := []struct {
+        name    string
+        origins []string
+        origin  string
+        allow   string
+    }{
+        {"разрешённая страница", []string{"https://admin.example.com"}, "https://admin.example.com", "https://admin.example.com"},
+        {"любая страница", []string{"*"}, "https://shop.example", "https://shop.example"},
+        {"чужая страница", []string{"https://admin.example.com"}, "https://evil.example", ""},
+        {"без Origin", []string{"*"}, "", ""},
+        {"CORS выключен", nil, "https://admin.example.com", ""},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            w := corsRequest(tc.origins, http.MethodGet, tc.origin, false)
+            if w.Code != http.StatusTeapot {
+                t.Fatalf("запрос не дошёл до обработчика: код %d", w.Code)
+            }
+            if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.allow {
+                t.Fatalf("Access-Control-Allow-Origin %q, нужно %q", got, tc.allow)
+            }
+        })
+    }
+}
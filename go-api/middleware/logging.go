@@ -0,0 +1,115 @@
+package middleware
+
+import (
+    "bytes"
+    "io"
+    "net/http"
+    "time"
+
+    "ai_seller/logging"
+    "ai_seller/reqctx"
+)
+
+// maxLoggedBody — сколько байт тела запроса и ответа попадает в лог
+const maxLoggedBody = 4 << 10
+
+// LoggingMiddleware пишет в лог каждый HTTP-запрос: метод, путь, статус,
+// длительность. В development (или с debugHTTP вне production) в лог попадают
+// и тела запроса и ответа, не больше maxLoggedBody. В апдейтах Telegram
+// личные данные покупателей, поэтому в production тела не пишутся никогда.
+func LoggingMiddleware(env string, debugHTTP bool) func(http.Handler) http.Handler {
+    captureBodies := env != "production" && (env == "development" || debugHTTP)
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            start := time.Now()
+            rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+            var reqBody *cappedBuffer
+            if captureBodies {
+                // Тело копируется по мере чтения обработчиком — сам поток не расходуется
+                reqBody = &cappedBuffer{}
+                r.Body = teeBody{Reader: io.TeeReader(r.Body, reqBody), Closer: r.Body}
+                rec.body = &cappedBuffer{}
+            }
+
+            next.ServeHTTP(rec, r)
+
+            attrs := []any{
+                "method", r.Method,
+                "path", r.URL.Path,
+                "status", rec.status,
+                "duration", time.Since(start),
+                "bytes", rec.written,
+                "client_ip", reqctx.ClientIPFromContext(r.Context()),
+            }
+            if captureBodies {
+                attrs = append(attrs, "request_body", reqBody.String(), "response_body", rec.body.String())
+            }
+            logging.FromContext(r.Context()).Info("http-запрос", attrs...)
+        })
+    }
+}
+
+// responseRecorder запоминает статус и размер ответа, а при body != nil —
+// и его начало
+type responseRecorder struct {
+    http.ResponseWriter
+    status      int
+    wroteHeader bool
+    written     int64
+    body        *cappedBuffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+    if !r.wroteHeader {
+        r.status = status
+        r.wroteHeader = true
+    }
+    r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+    r.wroteHeader = true
+    n, err := r.ResponseWriter.Write(p)
+    r.written += int64(n)
+    if r.body != nil {
+        r.body.Write(p[:n])
+    }
+    return n, err
+}
+
+// Unwrap нужен http.ResponseController: Flush и дедлайны доходят до
+// исходного ResponseWriter
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+    return r.ResponseWriter
+}
+
+// cappedBuffer — буфер, молча отбрасывающий всё после maxLoggedBody байт
+type cappedBuffer struct {
+    buf       bytes.Buffer
+    truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+    if room := maxLoggedBody - b.buf.Len(); len(p) > room {
+        b.buf.Write(p[:max(room, 0)])
+        b.truncated = true
+    } else {
+        b.buf.Write(p)
+    }
+    // Всегда «всё записано», иначе TeeReader вернёт ошибку обработчику
+    return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+    if b.truncated {
+        return b.buf.String() + "…(обрезано)"
+    }
+    return b.buf.String()
+}
+
+// teeBody — тело запроса, копируемое в лог при чтении; Close закрывает исходное
+type teeBody struct {
+    io.Reader
+    io.Closer
+}
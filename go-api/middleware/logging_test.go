@@ -0,0 +1,96 @@
+package middleware
+
+import (
+    "bytes"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "ai_seller/logging"
+)
+
+// logBuffer — вывод логгера, подменённого на время теста
+type logBuffer struct {
+    mu  sync.Mutex
+    buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.String()
+}
+
+// captureLogs направляет логи в буфер до конца теста
+func captureLogs(t *testing.T) *logBuffer {
+    t.Helper()
+    out := &logBuffer{}
+    prev := logging.Logger()
+    logging.SetLogger(slog.New(slog.NewTextHandler(out, nil)))
+    t.Cleanup(func() { logging.SetLogger(prev) })
+    return out
+}
+
+// echoServer — сервер за LoggingMiddleware, отвечающий телом запроса
+func echoServer(t *testing.T, env string, debugHTTP bool) *httptest.Server {
+    t.Helper()
+    echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        w.Write([]byte("ответ:" + string(body)))
+    })
+    srv := httptest.NewServer(LoggingMiddleware(env, debugHTTP)(echo))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// В production тела не пишутся и с DEBUG_HTTP, в development — всегда;
+// обработчик в любом случае получает тело запроса целиком
+func TestLoggingMiddlewareBodies(t *testing.T) {
+    for _, tc := range []struct {
+        name      string
+        env       string
+        debugHTTP bool
+        bodies    bool
+    }{
+        {"production", "production", false, false},
+        {"production с DEBUG_HTTP", "production", true, false},
+        {"development", "development", false, true},
+        {"staging", "staging", false, false},
+        {"staging с DEBUG_HTTP", "staging", true, true},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            logs := captureLogs(t)
+            srv := echoServer(t, tc.env, tc.debugHTTP)
+
+            resp, err := http.Post(srv.URL+"/webhook", "application/json", strings.NewReader(`{"phone":"+79991234567"}`))
+            if err != nil {
+                t.Fatal(err)
+            }
+            body, _ := io.ReadAll(resp.Body)
+            resp.Body.Close()
+            if string(body) != `ответ:{"phone":"+79991234567"}` {
+                t.Fatalf("обработчик получил тело не целиком: ответ %q", body)
+            }
+
+            out := logs.String()
+            if !strings.Contains(out, "path=/webhook") || !strings.Contains(out, "status=200") {
+                t.Fatalf("в логе нет метаданных запроса: %s", out)
+            }
+            logged := strings.Contains(out, "request_body") || strings.Contains(out, "response_body") ||
+                strings.Contains(out, "79991234567")
+            if logged != tc.bodies {
+                t.Fatalf("тела в логе: %v, ожидалось %v: %s", logged, tc.bodies, out)
+            }
+        })
+    }
+}
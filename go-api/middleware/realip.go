@@ -0,0 +1,80 @@
+package middleware
+
+import (
+    "net"
+    "net/http"
+    "net/netip"
+    "strings"
+
+    "ai_seller/reqctx"
+)
+
+// ClientIPMiddleware определяет адрес клиента и кладёт его в контекст запроса
+// (reqctx.ClientIPFromContext) для логов и лимитов по IP.
+// Заголовкам X-Forwarded-For и X-Real-IP верим, только если соединение
+// пришло с trusted — иначе любой клиент подделал бы свой адрес.
+func ClientIPMiddleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            ip := ClientIP(r, trusted)
+            next.ServeHTTP(w, r.WithContext(reqctx.WithClientIP(r.Context(), ip)))
+        })
+    }
+}
+
+// ClientIP — адрес клиента с учётом доверенных прокси. X-Forwarded-For
+// разбирается справа налево: каждый доверенный прокси дописывает адрес
+// того, кто к нему пришёл, поэтому первый недоверенный адрес и есть клиент.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+    remote, ok := parseIP(r.RemoteAddr)
+    if !ok {
+        return r.RemoteAddr
+    }
+    if !isTrusted(remote, trusted) {
+        return remote.String()
+    }
+
+    if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+        hops := strings.Split(strings.Join(xff, ","), ",")
+        client := remote
+        for i := len(hops) - 1; i >= 0; i-- {
+            hop, ok := parseIP(hops[i])
+            if !ok {
+                // Мусор в цепочке — дальше влево заголовку верить нельзя
+                break
+            }
+            client = hop
+            if !isTrusted(hop, trusted) {
+                break
+            }
+        }
+        return client.String()
+    }
+
+    if xri, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+        return xri.String()
+    }
+    return remote.String()
+}
+
+// parseIP разбирает адрес вида "ip", "ip:port" или "[ipv6]:port"
+func parseIP(s string) (netip.Addr, bool) {
+    s = strings.TrimSpace(s)
+    if host, _, err := net.SplitHostPort(s); err == nil {
+        s = host
+    }
+    addr, err := netip.ParseAddr(s)
+    if err != nil {
+        return netip.Addr{}, false
+    }
+    return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+    for _, p := range trusted {
+        if p.Contains(addr) {
+            return true
+        }
+    }
+    return false
+}
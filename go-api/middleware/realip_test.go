@@ -0,0 +1,59 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "net/netip"
+    "testing"
+
+    "ai_seller/reqctx"
+)
+
+func TestClientIP(t *testing.T) {
+    trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+    cases := []struct {
+        name   string
+        remote string
+        xff    []string
+        xri    string
+        want   string
+    }{
+        {"напрямую", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+        {"подделка без прокси", "203.0.113.7:5000", []string{"1.2.3.4"}, "1.2.3.4", "203.0.113.7"},
+        {"через прокси", "10.0.0.2:80", []string{"198.51.100.9"}, "", "198.51.100.9"},
+        {"цепочка прокси", "10.0.0.2:80", []string{"1.2.3.4, 198.51.100.9, 10.0.0.5"}, "", "198.51.100.9"},
+        {"несколько заголовков", "10.0.0.2:80", []string{"1.2.3.4", "198.51.100.9"}, "", "198.51.100.9"},
+        {"мусор в цепочке", "10.0.0.2:80", []string{"1.2.3.4, unknown, 10.0.0.5"}, "", "10.0.0.5"},
+        {"X-Real-IP", "10.0.0.2:80", nil, "198.51.100.9", "198.51.100.9"},
+        {"IPv6", "[::1]:80", []string{"2001:db8::1"}, "", "2001:db8::1"},
+        {"IPv4 в IPv6", "[::ffff:203.0.113.7]:5000", nil, "", "203.0.113.7"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            r := httptest.NewRequest(http.MethodPost, "/telegram", nil)
+            r.RemoteAddr = tc.remote
+            for _, v := range tc.xff {
+                r.Header.Add("X-Forwarded-For", v)
+            }
+            if tc.xri != "" {
+                r.Header.Set("X-Real-IP", tc.xri)
+            }
+            if got := ClientIP(r, trusted); got != tc.want {
+                t.Fatalf("адрес клиента %q, нужно %q", got, tc.want)
+            }
+        })
+    }
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+    var got string
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = reqctx.ClientIPFromContext(r.Context()) })
+    r := httptest.NewRequest(http.MethodPost, "/telegram", nil)
+    r.RemoteAddr = "10.0.0.2:80"
+    r.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+    ClientIPMiddleware([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})(next).ServeHTTP(httptest.NewRecorder(), r)
+    if got != "198.51.100.9" {
+        t.Fatalf("в контексте адрес %q", got)
+    }
+}
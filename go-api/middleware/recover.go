@@ -0,0 +1,41 @@
+package middleware
+
+import (
+    "fmt"
+    "net/http"
+    "runtime/debug"
+
+    "ai_seller/logging"
+)
+
+// RecoverMiddleware перехватывает панику обработчика, пишет стек в лог
+// и отвечает 500, чтобы один сбойный апдейт не ронял весь сервер.
+// Вне production стек попадает и в тело ответа — для отладки.
+func RecoverMiddleware(env string) func(http.Handler) http.Handler {
+    showStack := env != "production"
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            defer func() {
+                rec := recover()
+                if rec == nil {
+                    return
+                }
+                // net/http сам обрывает соединение по этой панике — не мешаем
+                if rec == http.ErrAbortHandler {
+                    panic(rec)
+                }
+
+                stack := debug.Stack()
+                logging.Logger().Error("паника в обработчике",
+                    "method", r.Method, "path", r.URL.Path, "panic", fmt.Sprint(rec), "stack", string(stack))
+
+                body := "Внутренняя ошибка сервера"
+                if showStack {
+                    body = fmt.Sprintf("%s: %v\n\n%s", body, rec, stack)
+                }
+                http.Error(w, body, http.StatusInternalServerError)
+            }()
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// panicServer — сервер за RecoverMiddleware, обработчик /boom которого паникует
+func panicServer(t *testing.T, env string) *httptest.Server {
+    t.Helper()
+    mux := http.NewServeMux()
+    mux.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) { panic("сбойный апдейт") })
+    mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+    srv := httptest.NewServer(RecoverMiddleware(env)(mux))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// get возвращает код и тело ответа на GET path
+func get(t *testing.T, srv *httptest.Server, path string) (int, string) {
+    t.Helper()
+    resp, err := http.Get(srv.URL + path)
+    if err != nil {
+        t.Fatalf("GET %s: %v", path, err)
+    }
+    defer resp.Body.Close()
+    body, _ := io.ReadAll(resp.Body)
+    return resp.StatusCode, string(body)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+    for _, tc := range []struct {
+        env       string
+        showStack bool
+    }{
+        {"development", true},
+        {"production", false},
+    } {
+        t.Run(tc.env, func(t *testing.T) {
+            srv := panicServer(t, tc.env)
+
+            code, body := get(t, srv, "/boom")
+            if code != http.StatusInternalServerError {
+                t.Fatalf("паника: код %d, нужно 500", code)
+            }
+            if stack := strings.Contains(body, "goroutine") && strings.Contains(body, "сбойный апдейт"); stack != tc.showStack {
+                t.Fatalf("стек в ответе: %v, нужно %v\n%s", stack, tc.showStack, body)
+            }
+            // Сервер пережил панику и отвечает дальше
+            if code, body := get(t, srv, "/ok"); code != http.StatusOK || body != "ok" {
+                t.Fatalf("после паники: %d %q", code, body)
+            }
+        })
+    }
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS products (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT    NOT NULL,
+    description TEXT    NOT NULL DEFAULT '',
+    price       BIGINT  NOT NULL CHECK (price >= 0),
+    currency    TEXT    NOT NULL DEFAULT 'RUB',
+    in_stock    BOOLEAN NOT NULL DEFAULT true
+);
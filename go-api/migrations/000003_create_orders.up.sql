@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id         BIGSERIAL PRIMARY KEY,
+    chat_id    BIGINT      NOT NULL,
+    total      BIGINT      NOT NULL,
+    currency   TEXT        NOT NULL DEFAULT 'RUB',
+    status     TEXT        NOT NULL DEFAULT 'new',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS orders_chat_id_idx ON orders (chat_id);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    order_id   BIGINT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL,
+    qty        INT    NOT NULL CHECK (qty > 0),
+    price      BIGINT NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
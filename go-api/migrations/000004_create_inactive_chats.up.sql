@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS inactive_chats (
+    chat_id   BIGINT      PRIMARY KEY,
+    marked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users (
+    chat_id    BIGINT      PRIMARY KEY,
+    username   TEXT        NOT NULL DEFAULT '',
+    name       TEXT        NOT NULL DEFAULT '',
+    lang       TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_idempotency_key_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS idempotency_key;
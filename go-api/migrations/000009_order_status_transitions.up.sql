@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS order_status_transitions (
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    PRIMARY KEY (from_status, to_status)
+);
+
+INSERT INTO order_status_transitions (from_status, to_status) VALUES
+    ('new', 'paid'),
+    ('new', 'cancelled'),
+    ('paid', 'shipped'),
+    ('paid', 'cancelled')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('new', 'paid', 'shipped', 'cancelled'));
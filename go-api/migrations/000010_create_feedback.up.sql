@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS feedback (
+    chat_id    BIGINT      NOT NULL,
+    message_id BIGINT      NOT NULL,
+    rating     SMALLINT    NOT NULL CHECK (rating IN (-1, 1)),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (chat_id, message_id)
+);
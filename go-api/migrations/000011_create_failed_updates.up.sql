@@ -0,0 +1,12 @@
+-- Апдейты, обработка которых завершилась ошибкой: журнал и очередь на повтор
+CREATE TABLE IF NOT EXISTS failed_updates (
+    id         BIGSERIAL PRIMARY KEY,
+    update_id  BIGINT      NOT NULL,
+    chat_id    BIGINT      NOT NULL DEFAULT 0,
+    payload    TEXT        NOT NULL,
+    truncated  BOOLEAN     NOT NULL DEFAULT FALSE,
+    error      TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS failed_updates_created_at_idx ON failed_updates (created_at);
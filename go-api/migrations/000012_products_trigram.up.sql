@@ -0,0 +1,3 @@
+-- Нечёткий поиск товаров по названию (/search с опечатками)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON products USING GIN (name gin_trgm_ops);
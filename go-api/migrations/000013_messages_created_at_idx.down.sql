@@ -0,0 +1 @@
+DROP INDEX IF EXISTS messages_created_at_idx;
@@ -0,0 +1,2 @@
+-- Для /stats: сообщения за день и активные чаты считаются по времени без привязки к чату
+CREATE INDEX IF NOT EXISTS messages_created_at_idx ON messages (created_at);
@@ -0,0 +1,19 @@
+-- Исходящие ответы: сначала сохраняются, затем отправляются фоновым циклом,
+-- чтобы ответ не терялся при падении между генерацией и отправкой
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    chat_id         BIGINT      NOT NULL,
+    text            TEXT        NOT NULL,
+    with_feedback   BOOLEAN     NOT NULL DEFAULT FALSE,
+    status          TEXT        NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    claimed_at      TIMESTAMPTZ,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at         TIMESTAMPTZ
+);
+
+-- Выборка очереди отправки: только неотправленные записи
+CREATE INDEX IF NOT EXISTS outbox_due_idx ON outbox (next_attempt_at) WHERE status IN ('pending', 'sending');
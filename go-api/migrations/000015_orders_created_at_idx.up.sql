@@ -0,0 +1,2 @@
+-- Выборка заказов за период для сводки
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at);
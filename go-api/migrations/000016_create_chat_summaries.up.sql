@@ -0,0 +1,9 @@
+-- Сводка ранней части диалога: вместо старых реплик в контекст модели
+-- подставляется их краткое содержание
+CREATE TABLE IF NOT EXISTS chat_summaries (
+    chat_id    BIGINT PRIMARY KEY,
+    summary    TEXT        NOT NULL,
+    -- up_to — id последнего сообщения, вошедшего в сводку
+    up_to      BIGINT      NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
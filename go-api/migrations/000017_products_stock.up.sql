@@ -0,0 +1,2 @@
+-- Остаток товара на складе: NULL — остаток не ведётся и заказ его не уменьшает
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock INT CHECK (stock >= 0);
@@ -0,0 +1,2 @@
+-- Краткий режим ответов, включается покупателем командой /brief
+ALTER TABLE users ADD COLUMN IF NOT EXISTS brief BOOLEAN NOT NULL DEFAULT false;
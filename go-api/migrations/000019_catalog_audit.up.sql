@@ -0,0 +1,12 @@
+-- Журнал ручных правок каталога админами (/setprice, /setstock)
+CREATE TABLE IF NOT EXISTS catalog_audit (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    field TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    changed_by BIGINT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS catalog_audit_product_idx ON catalog_audit (product_id, changed_at);
@@ -0,0 +1,2 @@
+-- Валюта, в которой покупатель видит цены (/currency); пусто — валюта магазина
+ALTER TABLE users ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Группы, в которые добавлен бот; left_at — когда его удалили (NULL — всё ещё там)
+CREATE TABLE IF NOT EXISTS bot_groups (
+    chat_id   BIGINT      PRIMARY KEY,
+    title     TEXT        NOT NULL DEFAULT '',
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    left_at   TIMESTAMPTZ
+);
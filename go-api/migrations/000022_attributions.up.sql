@@ -0,0 +1,11 @@
+-- Откуда пришёл покупатель: код кампании или пригласивший по ссылке t.me/<бот>?start=...
+-- Учитывается первое касание: повторная ссылка запись не меняет
+CREATE TABLE IF NOT EXISTS attributions (
+    chat_id     BIGINT      PRIMARY KEY,
+    kind        TEXT        NOT NULL,
+    code        TEXT        NOT NULL DEFAULT '',
+    referrer_id BIGINT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS attributions_referrer_idx ON attributions (referrer_id) WHERE referrer_id IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Журнал запросов к модели и её ответов (LLM_AUDIT); личные данные скрыты до записи
+CREATE TABLE IF NOT EXISTS llm_audit (
+    id                BIGSERIAL   PRIMARY KEY,
+    chat_id           BIGINT,
+    trace_id          TEXT        NOT NULL DEFAULT '',
+    model             TEXT        NOT NULL,
+    messages          JSONB       NOT NULL,
+    response          TEXT        NOT NULL DEFAULT '',
+    error             TEXT        NOT NULL DEFAULT '',
+    prompt_tokens     BIGINT      NOT NULL DEFAULT 0,
+    completion_tokens BIGINT      NOT NULL DEFAULT 0,
+    latency_ms        BIGINT      NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS llm_audit_chat_idx ON llm_audit (chat_id, created_at);
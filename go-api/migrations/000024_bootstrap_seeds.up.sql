@@ -0,0 +1,5 @@
+-- Применённые начальные данные (SEED_FILE): каждый файл применяется один раз
+CREATE TABLE IF NOT EXISTS bootstrap_seeds (
+    name       TEXT        PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+-- Язык, выбранный покупателем через /lang; пусто — язык интерфейса Telegram (lang)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferred_lang TEXT NOT NULL DEFAULT '';
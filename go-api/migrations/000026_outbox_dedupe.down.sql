@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS outbox_dedupe_key_idx;
+ALTER TABLE outbox DROP COLUMN IF EXISTS dedupe_key;
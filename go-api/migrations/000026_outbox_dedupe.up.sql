@@ -0,0 +1,4 @@
+-- Ключ уведомления: одна и та же смена статуса заказа не ставит в очередь
+-- второе сообщение, даже если её обработали дважды
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dedupe_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS outbox_dedupe_key_idx ON outbox (dedupe_key) WHERE dedupe_key IS NOT NULL;
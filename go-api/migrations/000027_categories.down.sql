@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
+DROP TABLE IF EXISTS categories;
+DROP FUNCTION IF EXISTS categories_check_depth();
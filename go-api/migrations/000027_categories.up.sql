@@ -0,0 +1,31 @@
+-- Категории каталога: корневые (parent_id IS NULL) и их подкатегории.
+-- Глубже двух уровней не вкладываются — это проверяет триггер.
+CREATE TABLE IF NOT EXISTS categories (
+    id        BIGSERIAL PRIMARY KEY,
+    name      TEXT   NOT NULL,
+    parent_id BIGINT REFERENCES categories (id) ON DELETE CASCADE,
+    -- position — порядок кнопок; при равенстве — по названию
+    position  INT    NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_idx ON categories (parent_id);
+
+CREATE OR REPLACE FUNCTION categories_check_depth() RETURNS trigger AS $$
+BEGIN
+    IF NEW.parent_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+    IF EXISTS (SELECT 1 FROM categories WHERE id = NEW.parent_id AND parent_id IS NOT NULL)
+       OR EXISTS (SELECT 1 FROM categories WHERE parent_id = NEW.id) THEN
+        RAISE EXCEPTION 'категория "%" вложена глубже двух уровней', NEW.name;
+    END IF;
+    RETURN NEW;
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS categories_check_depth ON categories;
+CREATE TRIGGER categories_check_depth BEFORE INSERT OR UPDATE ON categories
+    FOR EACH ROW EXECUTE FUNCTION categories_check_depth();
+
+-- Товар без категории виден только в общем каталоге
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id BIGINT REFERENCES categories (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS products_category_idx ON products (category_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS member_challenges;
+ALTER TABLE bot_groups DROP COLUMN IF EXISTS verify_members;
@@ -0,0 +1,13 @@
+-- Проверка новых участников группы кнопкой; включается админом для каждой группы
+ALTER TABLE bot_groups ADD COLUMN IF NOT EXISTS verify_members BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Участники, которые ещё не нажали кнопку проверки; message_id — сообщение с кнопкой
+CREATE TABLE IF NOT EXISTS member_challenges (
+    chat_id    BIGINT      NOT NULL,
+    user_id    BIGINT      NOT NULL,
+    message_id BIGINT      NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS member_challenges_expires_idx ON member_challenges (expires_at);
@@ -0,0 +1,2 @@
+-- Ответ модели, оборванный на середине потока: покупатель видел только его часть
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS incomplete BOOLEAN NOT NULL DEFAULT FALSE;
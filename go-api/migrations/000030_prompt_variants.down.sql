@@ -0,0 +1,3 @@
+ALTER TABLE feedback DROP COLUMN IF EXISTS prompt_variant;
+ALTER TABLE messages DROP COLUMN IF EXISTS prompt_variant;
+ALTER TABLE users DROP COLUMN IF EXISTS prompt_variant;
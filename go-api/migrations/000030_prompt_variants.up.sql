@@ -0,0 +1,5 @@
+-- A/B-тест системных промптов: вариант закрепляется за чатом, а сообщения и
+-- оценки помечаются вариантом, с которым шёл разговор; пусто — теста не было
+ALTER TABLE users ADD COLUMN IF NOT EXISTS prompt_variant TEXT NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS prompt_variant TEXT NOT NULL DEFAULT '';
+ALTER TABLE feedback ADD COLUMN IF NOT EXISTS prompt_variant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE outbox DROP COLUMN IF EXISTS reply_to_message_id;
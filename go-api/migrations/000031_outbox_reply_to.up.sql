@@ -0,0 +1,2 @@
+-- Сообщение покупателя, на которое отвечает ответ из очереди (цитата в группах); 0 — без цитаты
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS reply_to_message_id BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- Последняя подсказка повторного вовлечения: по ней покупателю пишут не
+-- чаще раза за REENGAGE_COOLDOWN
+CREATE TABLE IF NOT EXISTS nudges (
+    chat_id BIGINT      PRIMARY KEY,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- Меню под полем ввода скрыто покупателем (/menu off): надписи кнопок
+-- больше не считаются нажатиями и уходят модели обычным текстом
+ALTER TABLE users ADD COLUMN IF NOT EXISTS menu_hidden BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS llm_audit_created_idx;
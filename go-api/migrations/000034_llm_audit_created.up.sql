@@ -0,0 +1,2 @@
+-- Очистка журнала запросов к модели старше LLM_AUDIT_RETENTION идёт по дате
+CREATE INDEX IF NOT EXISTS llm_audit_created_idx ON llm_audit (created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_idempotency_key_created_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS orders_idempotency_key_idx ON orders (idempotency_key);
@@ -0,0 +1,5 @@
+-- Ключ идемпотентности заказа больше не содержит времени: повтор ищется
+-- среди заказов с тем же ключом за последние минуты, поэтому ключ не
+-- уникален, а поиск идёт по ключу и дате
+DROP INDEX IF EXISTS orders_idempotency_key_idx;
+CREATE INDEX IF NOT EXISTS orders_idempotency_key_created_idx ON orders (idempotency_key, created_at);
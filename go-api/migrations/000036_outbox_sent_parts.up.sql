@@ -0,0 +1,3 @@
+-- Сколько частей длинного ответа уже доставлено: повтор после ошибки
+-- продолжает с первой неотправленной, а не шлёт начало второй раз
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS sent_parts INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS orders_drafts_idx;
+DELETE FROM order_status_transitions WHERE from_status = 'draft';
+UPDATE orders SET status = 'new' WHERE status = 'draft';
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('new', 'paid', 'shipped', 'cancelled'));
@@ -0,0 +1,13 @@
+-- Заказ создаётся черновиком и становится новым, когда оформление
+-- завершено; черновик, брошенный посередине, покупателю предлагают вернуть
+-- в корзину, и тогда он отменяется
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('draft', 'new', 'paid', 'shipped', 'cancelled'));
+
+INSERT INTO order_status_transitions (from_status, to_status) VALUES
+    ('draft', 'new'),
+    ('draft', 'cancelled')
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS orders_drafts_idx ON orders (chat_id, created_at) WHERE status = 'draft';
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "ai_seller/logging"
+    "ai_seller/storage"
+)
+
+// bootstrapLockKey — ключ pg_advisory_lock, под которым выполняется Bootstrap
+const bootstrapLockKey int64 = 0x61695f7365656400 // "ai_seed\0"
+
+// Bootstrap готовит базу: применяет миграции и, если задан seedFile,
+// начальные данные. В PostgreSQL всё выполняется под advisory-блокировкой,
+// поэтому реплики, стартовавшие одновременно, проходят bootstrap по очереди:
+// первая применяет миграции и данные, остальные находят их уже на месте.
+// SQLite обслуживает один процесс, и блокировка ему не нужна.
+// Файл данных применяется один раз — отметка по имени файла хранится
+// в bootstrap_seeds; изменённые данные кладутся в файл с новым именем.
+func Bootstrap(ctx context.Context, db *sql.DB, driver, dsn, seedFile string) error {
+    // Advisory-блокировка принадлежит соединению — держим одно до конца
+    conn, err := db.Conn(ctx)
+    if err != nil {
+        return fmt.Errorf("ошибка соединения для bootstrap: %w", err)
+    }
+    defer conn.Close()
+
+    if driver == storage.DriverPostgres {
+        if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, bootstrapLockKey); err != nil {
+            return fmt.Errorf("ошибка блокировки bootstrap: %w", err)
+        }
+        defer func() {
+            // Блокировка снимается и при закрытии соединения, так что ошибка здесь не страшна
+            if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, bootstrapLockKey); err != nil {
+                logging.Logger().Warn("ошибка снятия блокировки bootstrap", "err", err)
+            }
+        }()
+    }
+
+    if err := RunMigrations(driver, dsn); err != nil {
+        return err
+    }
+    if seedFile == "" {
+        return nil
+    }
+    return applySeed(ctx, conn, seedFile)
+}
+
+// applySeed выполняет SQL из файла одной транзакцией вместе с отметкой
+// в bootstrap_seeds; уже применённый файл пропускается
+func applySeed(ctx context.Context, conn *sql.Conn, path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("ошибка чтения начальных данных: %w", err)
+    }
+    name := filepath.Base(path)
+
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer tx.Rollback()
+
+    res, err := tx.ExecContext(ctx, `INSERT INTO bootstrap_seeds (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+    if err != nil {
+        return fmt.Errorf("ошибка отметки начальных данных %s: %w", name, err)
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return fmt.Errorf("ошибка отметки начальных данных %s: %w", name, err)
+    }
+    if n == 0 {
+        logging.Logger().Info("начальные данные уже применены", "seed", name)
+        return nil
+    }
+    if _, err := tx.ExecContext(ctx, string(data)); err != nil {
+        return fmt.Errorf("ошибка применения начальных данных %s: %w", name, err)
+    }
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("ошибка применения начальных данных %s: %w", name, err)
+    }
+    logging.Logger().Info("начальные данные применены", "seed", name)
+    return nil
+}
@@ -0,0 +1,97 @@
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "math/rand/v2"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+
+    "ai_seller/storage"
+
+    _ "github.com/lib/pq"
+)
+
+// Реплики, стартовавшие одновременно, применяют данные ровно один раз;
+// без TEST_POSTGRES_DSN тест пропускается
+func TestConcurrentBootstrap(t *testing.T) {
+    dsn := os.Getenv("TEST_POSTGRES_DSN")
+    if dsn == "" {
+        t.Skip("TEST_POSTGRES_DSN не задан")
+    }
+    db, err := sql.Open("postgres", dsn)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    // Своя таблица на запуск: повторное применение упадёт на CREATE TABLE
+    table := fmt.Sprintf("bootstrap_test_%d", rand.Int64N(1_000_000_000))
+    seed := filepath.Join(t.TempDir(), table+".sql")
+    body := fmt.Sprintf("CREATE TABLE %s (n int); INSERT INTO %[1]s VALUES (1);", table)
+    if err := os.WriteFile(seed, []byte(body), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        db.Exec("DROP TABLE IF EXISTS " + table)
+        db.Exec(`DELETE FROM bootstrap_seeds WHERE name = $1`, filepath.Base(seed))
+    })
+
+    const replicas = 5
+    errs := make([]error, replicas)
+    var wg sync.WaitGroup
+    for i := range replicas {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            errs[i] = Bootstrap(context.Background(), db, storage.DriverPostgres, dsn, seed)
+        }()
+    }
+    wg.Wait()
+    for i, err := range errs {
+        if err != nil {
+            t.Fatalf("реплика %d: %v", i, err)
+        }
+    }
+
+    var rows int
+    if err := db.QueryRow("SELECT count(*) FROM " + table).Scan(&rows); err != nil {
+        t.Fatal(err)
+    }
+    if rows != 1 {
+        t.Fatalf("начальные данные применены %d раз, нужно 1", rows)
+    }
+}
+
+// На SQLite схема создаётся с нуля, а повторный bootstrap не применяет
+// данные второй раз
+func TestBootstrapSQLite(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "shop.db")
+    db, err := storage.OpenSQLite(path, storage.PoolOptions{MaxOpenConns: 2, MaxIdleConns: 1})
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    seed := filepath.Join(t.TempDir(), "seed.sql")
+    body := "INSERT INTO products (name, price) VALUES ('чай', 35000); INSERT INTO products (name, price) VALUES ('кофе', 50000);"
+    if err := os.WriteFile(seed, []byte(body), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    for i := range 2 {
+        if err := Bootstrap(context.Background(), db, storage.DriverSQLite, path, seed); err != nil {
+            t.Fatalf("bootstrap %d: %v", i+1, err)
+        }
+    }
+
+    var rows int
+    if err := db.QueryRow("SELECT count(*) FROM products").Scan(&rows); err != nil {
+        t.Fatal(err)
+    }
+    if rows != 2 {
+        t.Fatalf("товаров %d, нужно 2: данные применены не один раз", rows)
+    }
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+    "embed"
+    "errors"
+    "fmt"
+
+    "ai_seller/logging"
+    "ai_seller/storage"
+
+    "github.com/golang-migrate/migrate/v4"
+    _ "github.com/golang-migrate/migrate/v4/database/postgres"
+    _ "github.com/golang-migrate/migrate/v4/database/sqlite"
+    "github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// files — SQL-миграции, встроенные в бинарник: NNNNNN_имя.up.sql / .down.sql.
+// Миграции PostgreSQL лежат в корне, SQLite — в sqlite/ с теми же номерами.
+//
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// RunMigrations применяет все новые миграции к базе драйвера driver
+// (storage.DriverPostgres или storage.DriverSQLite): dsn — DSN PostgreSQL
+// или путь к файлу SQLite. Повторный запуск на актуальной схеме ничего не делает.
+func RunMigrations(driver, dsn string) error {
+    dir, url := ".", dsn
+    if driver == storage.DriverSQLite {
+        dir, url = "sqlite", "sqlite://"+dsn+"?_pragma=busy_timeout(5000)"
+    }
+    src, err := iofs.New(files, dir)
+    if err != nil {
+        return fmt.Errorf("ошибка чтения миграций: %w", err)
+    }
+
+    m, err := migrate.NewWithSourceInstance("iofs", src, url)
+    if err != nil {
+        return fmt.Errorf("ошибка инициализации миграций: %w", err)
+    }
+    defer m.Close()
+
+    before := version(m)
+    logging.Logger().Info("версия схемы до миграций", "version", before)
+
+    if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+        return fmt.Errorf("ошибка применения миграций: %w", err)
+    }
+
+    logging.Logger().Info("версия схемы после миграций", "version", version(m), "previous", before)
+    return nil
+}
+
+// version — текущая версия схемы для логов; 0 — миграции ещё не применялись
+func version(m *migrate.Migrate) uint {
+    v, dirty, err := m.Version()
+    if err != nil {
+        return 0
+    }
+    if dirty {
+        logging.Logger().Warn("схема в состоянии dirty — предыдущая миграция не завершилась", "version", v)
+    }
+    return v
+}
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS nudges;
+DROP TABLE IF EXISTS member_challenges;
+DROP TABLE IF EXISTS bootstrap_seeds;
+DROP TABLE IF EXISTS llm_audit;
+DROP TABLE IF EXISTS attributions;
+DROP TABLE IF EXISTS bot_groups;
+DROP TABLE IF EXISTS catalog_audit;
+DROP TABLE IF EXISTS chat_summaries;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS failed_updates;
+DROP TABLE IF EXISTS feedback;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS inactive_chats;
+DROP TABLE IF EXISTS order_status_transitions;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS messages;
@@ -0,0 +1,234 @@
+-- Схема SQLite (STORAGE_DRIVER=sqlite) целиком, в состоянии PostgreSQL после
+-- миграции 000036: номер совпадает, поэтому следующие изменения схемы идут
+-- парой файлов с одним номером в обоих каталогах.
+--
+-- Время хранится текстом в UTC ('2006-01-02 15:04:05.999999999+00:00'),
+-- поэтому сравнивается как строки; now() регистрирует storage.OpenSQLite.
+-- Нечёткий поиск (pg_trgm) — функцией similarity оттуда же, без индекса.
+
+CREATE TABLE IF NOT EXISTS messages (
+    id             INTEGER   PRIMARY KEY,
+    chat_id        BIGINT    NOT NULL,
+    role           TEXT      NOT NULL,
+    content        TEXT      NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    archived_at    TIMESTAMP,
+    incomplete     BOOLEAN   NOT NULL DEFAULT FALSE,
+    prompt_variant TEXT      NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS messages_chat_id_created_at_idx ON messages (chat_id, created_at);
+CREATE INDEX IF NOT EXISTS messages_created_at_idx ON messages (created_at);
+
+-- Категории каталога: корневые (parent_id IS NULL) и их подкатегории.
+-- Глубже двух уровней не вкладываются — это проверяют триггеры.
+CREATE TABLE IF NOT EXISTS categories (
+    id        INTEGER PRIMARY KEY,
+    name      TEXT    NOT NULL,
+    parent_id BIGINT  REFERENCES categories (id) ON DELETE CASCADE,
+    position  INT     NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS categories_parent_idx ON categories (parent_id);
+
+CREATE TRIGGER IF NOT EXISTS categories_check_depth_insert BEFORE INSERT ON categories
+    WHEN NEW.parent_id IS NOT NULL
+     AND EXISTS (SELECT 1 FROM categories WHERE id = NEW.parent_id AND parent_id IS NOT NULL)
+BEGIN
+    SELECT RAISE(ABORT, 'категория вложена глубже двух уровней');
+END;
+
+CREATE TRIGGER IF NOT EXISTS categories_check_depth_update BEFORE UPDATE ON categories
+    WHEN NEW.parent_id IS NOT NULL
+     AND (EXISTS (SELECT 1 FROM categories WHERE id = NEW.parent_id AND parent_id IS NOT NULL)
+          OR EXISTS (SELECT 1 FROM categories WHERE parent_id = NEW.id))
+BEGIN
+    SELECT RAISE(ABORT, 'категория вложена глубже двух уровней');
+END;
+
+CREATE TABLE IF NOT EXISTS products (
+    id          INTEGER PRIMARY KEY,
+    name        TEXT    NOT NULL,
+    description TEXT    NOT NULL DEFAULT '',
+    price       BIGINT  NOT NULL CHECK (price >= 0),
+    currency    TEXT    NOT NULL DEFAULT 'RUB',
+    in_stock    BOOLEAN NOT NULL DEFAULT TRUE,
+    image_url   TEXT    NOT NULL DEFAULT '',
+    -- Остаток на складе: NULL — остаток не ведётся и заказ его не уменьшает
+    stock       INT     CHECK (stock >= 0),
+    category_id BIGINT  REFERENCES categories (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS products_category_idx ON products (category_id);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id              INTEGER   PRIMARY KEY,
+    chat_id         BIGINT    NOT NULL,
+    total           BIGINT    NOT NULL,
+    currency        TEXT      NOT NULL DEFAULT 'RUB',
+    status          TEXT      NOT NULL DEFAULT 'new'
+                    CHECK (status IN ('new', 'paid', 'shipped', 'cancelled')),
+    created_at      TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    idempotency_key TEXT
+);
+CREATE INDEX IF NOT EXISTS orders_chat_id_idx ON orders (chat_id);
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at);
+CREATE INDEX IF NOT EXISTS orders_idempotency_key_created_idx ON orders (idempotency_key, created_at);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    order_id   BIGINT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL,
+    qty        INT    NOT NULL CHECK (qty > 0),
+    price      BIGINT NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS order_status_transitions (
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    PRIMARY KEY (from_status, to_status)
+);
+
+INSERT INTO order_status_transitions (from_status, to_status) VALUES
+    ('new', 'paid'),
+    ('new', 'cancelled'),
+    ('paid', 'shipped'),
+    ('paid', 'cancelled')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS inactive_chats (
+    chat_id   BIGINT    PRIMARY KEY,
+    marked_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    chat_id        BIGINT    PRIMARY KEY,
+    username       TEXT      NOT NULL DEFAULT '',
+    name           TEXT      NOT NULL DEFAULT '',
+    lang           TEXT      NOT NULL DEFAULT '',
+    created_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    brief          BOOLEAN   NOT NULL DEFAULT FALSE,
+    currency       TEXT      NOT NULL DEFAULT '',
+    preferred_lang TEXT      NOT NULL DEFAULT '',
+    prompt_variant TEXT      NOT NULL DEFAULT '',
+    menu_hidden    BOOLEAN   NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS feedback (
+    chat_id        BIGINT    NOT NULL,
+    message_id     BIGINT    NOT NULL,
+    rating         SMALLINT  NOT NULL CHECK (rating IN (-1, 1)),
+    created_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    prompt_variant TEXT      NOT NULL DEFAULT '',
+    PRIMARY KEY (chat_id, message_id)
+);
+
+-- Апдейты, обработка которых завершилась ошибкой: журнал и очередь на повтор
+CREATE TABLE IF NOT EXISTS failed_updates (
+    id         INTEGER   PRIMARY KEY,
+    update_id  BIGINT    NOT NULL,
+    chat_id    BIGINT    NOT NULL DEFAULT 0,
+    payload    TEXT      NOT NULL,
+    truncated  BOOLEAN   NOT NULL DEFAULT FALSE,
+    error      TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+CREATE INDEX IF NOT EXISTS failed_updates_created_at_idx ON failed_updates (created_at);
+
+-- Исходящие ответы: сначала сохраняются, затем отправляются фоновым циклом
+CREATE TABLE IF NOT EXISTS outbox (
+    id                  INTEGER   PRIMARY KEY,
+    chat_id             BIGINT    NOT NULL,
+    text                TEXT      NOT NULL,
+    with_feedback       BOOLEAN   NOT NULL DEFAULT FALSE,
+    status              TEXT      NOT NULL DEFAULT 'pending'
+                        CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+    attempts            INT       NOT NULL DEFAULT 0,
+    next_attempt_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    claimed_at          TIMESTAMP,
+    last_error          TEXT      NOT NULL DEFAULT '',
+    created_at          TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    sent_at             TIMESTAMP,
+    dedupe_key          TEXT,
+    reply_to_message_id BIGINT    NOT NULL DEFAULT 0,
+    sent_parts          INT       NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS outbox_due_idx ON outbox (next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE UNIQUE INDEX IF NOT EXISTS outbox_dedupe_key_idx ON outbox (dedupe_key) WHERE dedupe_key IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS chat_summaries (
+    chat_id    BIGINT    PRIMARY KEY,
+    summary    TEXT      NOT NULL,
+    -- up_to — id последнего сообщения, вошедшего в сводку
+    up_to      BIGINT    NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- Журнал ручных правок каталога админами (/setprice, /setstock)
+CREATE TABLE IF NOT EXISTS catalog_audit (
+    id         INTEGER   PRIMARY KEY,
+    product_id BIGINT    NOT NULL,
+    field      TEXT      NOT NULL,
+    old_value  TEXT,
+    new_value  TEXT,
+    changed_by BIGINT    NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+CREATE INDEX IF NOT EXISTS catalog_audit_product_idx ON catalog_audit (product_id, changed_at);
+
+-- Группы, в которые добавлен бот; left_at — когда его удалили (NULL — всё ещё там)
+CREATE TABLE IF NOT EXISTS bot_groups (
+    chat_id        BIGINT    PRIMARY KEY,
+    title          TEXT      NOT NULL DEFAULT '',
+    joined_at      TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    left_at        TIMESTAMP,
+    verify_members BOOLEAN   NOT NULL DEFAULT FALSE
+);
+
+-- Откуда пришёл покупатель: учитывается первое касание
+CREATE TABLE IF NOT EXISTS attributions (
+    chat_id     BIGINT    PRIMARY KEY,
+    kind        TEXT      NOT NULL,
+    code        TEXT      NOT NULL DEFAULT '',
+    referrer_id BIGINT,
+    created_at  TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+CREATE INDEX IF NOT EXISTS attributions_referrer_idx ON attributions (referrer_id) WHERE referrer_id IS NOT NULL;
+
+-- Журнал запросов к модели и её ответов (LLM_AUDIT); messages — JSON
+CREATE TABLE IF NOT EXISTS llm_audit (
+    id                INTEGER   PRIMARY KEY,
+    chat_id           BIGINT,
+    trace_id          TEXT      NOT NULL DEFAULT '',
+    model             TEXT      NOT NULL,
+    messages          TEXT      NOT NULL,
+    response          TEXT      NOT NULL DEFAULT '',
+    error             TEXT      NOT NULL DEFAULT '',
+    prompt_tokens     BIGINT    NOT NULL DEFAULT 0,
+    completion_tokens BIGINT    NOT NULL DEFAULT 0,
+    latency_ms        BIGINT    NOT NULL,
+    created_at        TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+CREATE INDEX IF NOT EXISTS llm_audit_chat_idx ON llm_audit (chat_id, created_at);
+CREATE INDEX IF NOT EXISTS llm_audit_created_idx ON llm_audit (created_at);
+
+-- Применённые начальные данные (SEED_FILE): каждый файл применяется один раз
+CREATE TABLE IF NOT EXISTS bootstrap_seeds (
+    name       TEXT      PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- Участники группы, которые ещё не нажали кнопку проверки
+CREATE TABLE IF NOT EXISTS member_challenges (
+    chat_id    BIGINT    NOT NULL,
+    user_id    BIGINT    NOT NULL,
+    message_id BIGINT    NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chat_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS member_challenges_expires_idx ON member_challenges (expires_at);
+
+-- Последняя подсказка повторного вовлечения
+CREATE TABLE IF NOT EXISTS nudges (
+    chat_id BIGINT    PRIMARY KEY,
+    sent_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
@@ -0,0 +1,19 @@
+DELETE FROM order_status_transitions WHERE from_status = 'draft';
+UPDATE orders SET status = 'new' WHERE status = 'draft';
+CREATE TABLE orders_old (
+    id              INTEGER   PRIMARY KEY,
+    chat_id         BIGINT    NOT NULL,
+    total           BIGINT    NOT NULL,
+    currency        TEXT      NOT NULL DEFAULT 'RUB',
+    status          TEXT      NOT NULL DEFAULT 'new'
+                    CHECK (status IN ('new', 'paid', 'shipped', 'cancelled')),
+    created_at      TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    idempotency_key TEXT
+);
+INSERT INTO orders_old (id, chat_id, total, currency, status, created_at, idempotency_key)
+    SELECT id, chat_id, total, currency, status, created_at, idempotency_key FROM orders;
+DROP TABLE orders;
+ALTER TABLE orders_old RENAME TO orders;
+CREATE INDEX IF NOT EXISTS orders_chat_id_idx ON orders (chat_id);
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at);
+CREATE INDEX IF NOT EXISTS orders_idempotency_key_created_idx ON orders (idempotency_key, created_at);
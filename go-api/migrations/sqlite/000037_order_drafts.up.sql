@@ -0,0 +1,27 @@
+-- Заказ создаётся черновиком и становится новым, когда оформление
+-- завершено. CHECK в SQLite не меняется, поэтому таблица пересоздаётся;
+-- внешние ключи на соединении миграций выключены, и order_items при замене
+-- таблицы не теряет строк.
+CREATE TABLE orders_new (
+    id              INTEGER   PRIMARY KEY,
+    chat_id         BIGINT    NOT NULL,
+    total           BIGINT    NOT NULL,
+    currency        TEXT      NOT NULL DEFAULT 'RUB',
+    status          TEXT      NOT NULL DEFAULT 'new'
+                    CHECK (status IN ('draft', 'new', 'paid', 'shipped', 'cancelled')),
+    created_at      TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    idempotency_key TEXT
+);
+INSERT INTO orders_new (id, chat_id, total, currency, status, created_at, idempotency_key)
+    SELECT id, chat_id, total, currency, status, created_at, idempotency_key FROM orders;
+DROP TABLE orders;
+ALTER TABLE orders_new RENAME TO orders;
+CREATE INDEX IF NOT EXISTS orders_chat_id_idx ON orders (chat_id);
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at);
+CREATE INDEX IF NOT EXISTS orders_idempotency_key_created_idx ON orders (idempotency_key, created_at);
+CREATE INDEX IF NOT EXISTS orders_drafts_idx ON orders (chat_id, created_at) WHERE status = 'draft';
+
+INSERT INTO order_status_transitions (from_status, to_status) VALUES
+    ('draft', 'new'),
+    ('draft', 'cancelled')
+ON CONFLICT DO NOTHING;
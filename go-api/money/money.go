@@ -0,0 +1,140 @@
+// Package money — денежные суммы в минимальных единицах валюты
+package money
+
+import (
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// ErrCurrencyMismatch — арифметика над суммами в разных валютах
+var ErrCurrencyMismatch = errors.New("суммы в разных валютах")
+
+// symbols — знаки валют для вывода; валюты без знака выводятся кодом ISO 4217
+var symbols = map[string]string{
+    "RUB": "₽",
+    "USD": "$",
+    "EUR": "€",
+}
+
+// Money — сумма в минимальных единицах (копейках, центах) с кодом валюты
+// ISO 4217. Нулевое значение — ноль без валюты: к нему можно прибавить
+// сумму в любой валюте, поэтому с него удобно начинать подсчёт итога.
+type Money struct {
+    Minor    int64
+    Currency string
+}
+
+// New — сумма minor минимальных единиц валюты currency
+func New(minor int64, currency string) Money {
+    return Money{Minor: minor, Currency: currency}
+}
+
+// Add складывает суммы одной валюты; ноль без валюты принимает валюту другой суммы
+func (m Money) Add(o Money) (Money, error) {
+    switch {
+    case m.Currency == "" && m.Minor == 0:
+        return o, nil
+    case o.Currency == "" && o.Minor == 0:
+        return m, nil
+    case m.Currency != o.Currency:
+        return Money{}, fmt.Errorf("%w: %s и %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+    }
+    return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}, nil
+}
+
+// Parse разбирает сумму в основных единицах, как её пишут в таблицах:
+// «1990», «1990.50», «1 990,5». Больше двух знаков после запятой — ошибка.
+func Parse(amount, currency string) (Money, error) {
+    s := strings.NewReplacer(" ", "", "\u00a0", "", "_", "").Replace(strings.TrimSpace(amount))
+    s = strings.Replace(s, ",", ".", 1)
+    whole, frac, hasFrac := strings.Cut(s, ".")
+    if !isDigits(whole) || len(frac) > 2 || (hasFrac && !isDigits(frac)) {
+        return Money{}, fmt.Errorf("некорректная сумма %q", amount)
+    }
+    units, err := strconv.ParseInt(whole, 10, 64)
+    if err != nil {
+        return Money{}, fmt.Errorf("некорректная сумма %q: %w", amount, err)
+    }
+    frac += strings.Repeat("0", 2-len(frac))
+    cents, _ := strconv.ParseInt(frac, 10, 64)
+    return Money{Minor: units*100 + cents, Currency: currency}, nil
+}
+
+// isDigits — непустая строка из одних цифр
+func isDigits(s string) bool {
+    if s == "" {
+        return false
+    }
+    for _, r := range s {
+        if r < '0' || r > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+// MulQty — стоимость qty единиц по цене m
+func (m Money) MulQty(qty int) Money {
+    return Money{Minor: m.Minor * int64(qty), Currency: m.Currency}
+}
+
+// Major — сумма в основных единицах (рублях, долларах) для внешних API
+func (m Money) Major() float64 {
+    return float64(m.Minor) / 100
+}
+
+// Format — сумма для покупателя на языке lang. По-русски знак валюты после
+// числа, разряды через неразрывный пробел, копейки через запятую: «1 499 ₽»,
+// «12,50 $». По-английски знак перед числом, разряды через запятую: «$1,499.50».
+// Нулевые копейки не выводятся.
+func (m Money) Format(lang string) string {
+    sign := ""
+    minor := m.Minor
+    if minor < 0 {
+        sign, minor = "-", -minor
+    }
+
+    groupSep, decimalSep := " ", ","
+    if lang == "en" {
+        groupSep, decimalSep = ",", "."
+    }
+    number := groupDigits(strconv.FormatInt(minor/100, 10), groupSep)
+    if cents := minor % 100; cents != 0 {
+        number += fmt.Sprintf("%s%02d", decimalSep, cents)
+    }
+
+    symbol, ok := symbols[m.Currency]
+    switch {
+    case !ok:
+        return sign + number + " " + m.Currency
+    case lang == "en":
+        return sign + symbol + number
+    }
+    return sign + number + " " + symbol
+}
+
+// String — сумма в русском формате, для логов и %v
+func (m Money) String() string {
+    return m.Format("ru")
+}
+
+// groupDigits разбивает целую часть на разряды по три цифры
+func groupDigits(digits, sep string) string {
+    if len(digits) <= 3 {
+        return digits
+    }
+    var sb strings.Builder
+    head := len(digits) % 3
+    if head > 0 {
+        sb.WriteString(digits[:head])
+    }
+    for i := head; i < len(digits); i += 3 {
+        if sb.Len() > 0 {
+            sb.WriteString(sep)
+        }
+        sb.WriteString(digits[i : i+3])
+    }
+    return sb.String()
+}
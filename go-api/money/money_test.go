@@ -0,0 +1,70 @@
+package money
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestParse(t *testing.T) {
+    for _, tc := range []struct {
+        in    string
+        minor int64
+        ok    bool
+    }{
+        {"1990", 199000, true},
+        {"1990.50", 199050, true},
+        {"1\u00a0990,5", 199050, true},
+        {"1 990", 199000, true},
+        {"0,01", 1, true},
+        {"1,999", 0, false},
+        {"-5", 0, false},
+        {"", 0, false},
+        {"12р", 0, false},
+    } {
+        m, err := Parse(tc.in, "RUB")
+        if (err == nil) != tc.ok || (tc.ok && m != New(tc.minor, "RUB")) {
+            t.Errorf("Parse(%q) = %+v, %v; нужно %d, ok=%v", tc.in, m, err, tc.minor, tc.ok)
+        }
+    }
+}
+
+func TestFormat(t *testing.T) {
+    for _, tc := range []struct {
+        m    Money
+        lang string
+        want string
+    }{
+        {New(149900, "RUB"), "ru", "1\u00a0499\u00a0₽"},
+        {New(1250, "USD"), "ru", "12,50\u00a0$"},
+        {New(149950, "USD"), "en", "$1,499.50"},
+        {New(100000000, "KZT"), "ru", "1\u00a0000\u00a0000\u00a0KZT"},
+        {New(-500, "EUR"), "en", "-€5"},
+    } {
+        if got := tc.m.Format(tc.lang); got != tc.want {
+            t.Errorf("Format(%+v, %s) = %q, нужно %q", tc.m, tc.lang, got, tc.want)
+        }
+    }
+}
+
+func TestAdd(t *testing.T) {
+    var total Money
+    total, err := total.Add(New(100, "USD"))
+    if err != nil || total != New(100, "USD") {
+        t.Fatalf("ноль + 1 $ = %+v, %v", total, err)
+    }
+    if total, err = total.Add(New(250, "USD")); err != nil || total != New(350, "USD") {
+        t.Fatalf("1 $ + 2,50 $ = %+v, %v", total, err)
+    }
+    if _, err := total.Add(New(100, "RUB")); !errors.Is(err, ErrCurrencyMismatch) {
+        t.Fatalf("доллары + рубли: %v, нужна ErrCurrencyMismatch", err)
+    }
+}
+
+func TestMulQty(t *testing.T) {
+    if got := New(149900, "RUB").MulQty(3); got != New(449700, "RUB") {
+        t.Fatalf("3 × 1 499 ₽ = %+v", got)
+    }
+    if got := New(1250, "USD").MulQty(0); got != New(0, "USD") {
+        t.Fatalf("0 × 12,50 $ = %+v", got)
+    }
+}
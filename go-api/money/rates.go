@@ -0,0 +1,128 @@
+package money
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ratesFetchTimeout — предел запроса курсов к внешнему источнику
+const ratesFetchTimeout = 10 * time.Second
+
+// Rates — курсы валют к базовой валюте магазина: сколько единиц валюты
+// дают за одну единицу базовой. Базовая валюта в таблице не нужна.
+type Rates struct {
+    Base    string
+    PerBase map[string]float64
+}
+
+// Has — известен ли курс валюты (базовая известна всегда)
+func (r *Rates) Has(currency string) bool {
+    if r == nil {
+        return false
+    }
+    if currency == r.Base {
+        return true
+    }
+    _, ok := r.PerBase[currency]
+    return ok
+}
+
+// Convert переводит сумму в валюту to через базовую валюту с округлением до
+// минимальных единиц. ok=false — курса одной из валют нет, сумма не изменена.
+func (r *Rates) Convert(m Money, to string) (Money, bool) {
+    if m.Currency == to {
+        return m, true
+    }
+    if !r.Has(m.Currency) || !r.Has(to) {
+        return m, false
+    }
+    base := float64(m.Minor)
+    if m.Currency != r.Base {
+        base /= r.PerBase[m.Currency]
+    }
+    if to != r.Base {
+        base *= r.PerBase[to]
+    }
+    return Money{Minor: int64(math.Round(base)), Currency: to}, true
+}
+
+// ParseRates разбирает курсы вида «USD=0.011,EUR=0.0102» к валюте base
+func ParseRates(base, s string) (*Rates, error) {
+    r := &Rates{Base: base, PerBase: make(map[string]float64)}
+    for _, item := range strings.Split(s, ",") {
+        item = strings.TrimSpace(item)
+        if item == "" {
+            continue
+        }
+        code, raw, ok := strings.Cut(item, "=")
+        if !ok {
+            return nil, fmt.Errorf("курс %q: нужен формат ВАЛЮТА=курс", item)
+        }
+        if err := r.set(code, raw); err != nil {
+            return nil, err
+        }
+    }
+    return r, nil
+}
+
+// set проверяет и добавляет курс валюты code
+func (r *Rates) set(code, raw string) error {
+    code = strings.ToUpper(strings.TrimSpace(code))
+    if len(code) != 3 {
+        return fmt.Errorf("некорректная валюта %q", code)
+    }
+    rate, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+    if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+        return fmt.Errorf("некорректный курс %s: %q", code, raw)
+    }
+    r.PerBase[code] = rate
+    return nil
+}
+
+// FetchRates загружает курсы к валюте base из JSON-источника вида
+// {"rates": {"USD": 0.011, ...}} — такой формат отдают open.er-api.com,
+// exchangerate.host и аналоги. Некорректные курсы пропускает.
+func FetchRates(ctx context.Context, client *http.Client, url, base string) (*Rates, error) {
+    ctx, cancel := context.WithTimeout(ctx, ratesFetchTimeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка создания запроса курсов: %w", err)
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка запроса курсов: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return nil, fmt.Errorf("источник курсов вернул %d: %s", resp.StatusCode, body)
+    }
+
+    var parsed struct {
+        Rates map[string]json.Number `json:"rates"`
+    }
+    if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&parsed); err != nil {
+        return nil, fmt.Errorf("ошибка разбора курсов: %w", err)
+    }
+    r := &Rates{Base: base, PerBase: make(map[string]float64, len(parsed.Rates))}
+    for code, rate := range parsed.Rates {
+        if strings.EqualFold(code, base) {
+            continue
+        }
+        // Некорректный курс одной валюты не должен отменять остальные
+        r.set(code, rate.String())
+    }
+    if len(r.PerBase) == 0 {
+        return nil, fmt.Errorf("источник курсов не вернул ни одного курса")
+    }
+    return r, nil
+}
@@ -0,0 +1,71 @@
+package money
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestConvert(t *testing.T) {
+    rates, err := ParseRates("RUB", "USD=0.011, EUR=0.01")
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, tc := range []struct {
+        m    Money
+        to   string
+        want Money
+        ok   bool
+    }{
+        {New(100000, "RUB"), "USD", New(1100, "USD"), true},
+        {New(1100, "USD"), "RUB", New(100000, "RUB"), true},
+        {New(1100, "USD"), "EUR", New(1000, "EUR"), true},
+        {New(100000, "RUB"), "RUB", New(100000, "RUB"), true},
+        {New(100000, "RUB"), "KZT", New(100000, "RUB"), false},
+        {New(100, "KZT"), "RUB", New(100, "KZT"), false},
+    } {
+        got, ok := rates.Convert(tc.m, tc.to)
+        if got != tc.want || ok != tc.ok {
+            t.Errorf("Convert(%+v, %s) = %+v, %v; нужно %+v, %v", tc.m, tc.to, got, ok, tc.want, tc.ok)
+        }
+    }
+
+    // Без курсов пересчитывается только сумма в той же валюте
+    var none *Rates
+    if _, ok := none.Convert(New(1, "RUB"), "USD"); ok {
+        t.Fatal("пересчёт без курсов")
+    }
+}
+
+func TestParseRatesErrors(t *testing.T) {
+    for _, s := range []string{"USD", "USD=0", "USD=-1", "USD=abc", "DOLLAR=1"} {
+        if _, err := ParseRates("RUB", s); err == nil {
+            t.Errorf("ParseRates(%q) без ошибки", s)
+        }
+    }
+}
+
+func TestFetchRates(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/down" {
+            http.Error(w, "maintenance", http.StatusServiceUnavailable)
+            return
+        }
+        fmt.Fprint(w, `{"rates": {"RUB": 1, "USD": 0.011, "EUR": 0, "XX": 2}}`)
+    }))
+    defer srv.Close()
+
+    rates, err := FetchRates(context.Background(), srv.Client(), srv.URL, "RUB")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Базовая валюта и некорректные курсы пропускаются, остальные остаются
+    if len(rates.PerBase) != 1 || rates.PerBase["USD"] != 0.011 {
+        t.Fatalf("курсы %v, нужен только USD", rates.PerBase)
+    }
+    if _, err := FetchRates(context.Background(), srv.Client(), srv.URL+"/down", "RUB"); err == nil {
+        t.Fatal("ошибка источника не вернулась")
+    }
+}
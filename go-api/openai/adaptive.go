@@ -0,0 +1,66 @@
+package openai
+
+import (
+    "slices"
+    "sync"
+    "time"
+
+    "ai_seller/metrics"
+)
+
+const (
+    // adaptiveWindow — по скольким последним ответам считается p95
+    adaptiveWindow = 200
+    // adaptiveMinSamples — до стольких ответов предел остаётся максимальным:
+    // по единицам замеров p95 случаен
+    adaptiveMinSamples = 20
+)
+
+// AdaptiveTimeout — предел на попытку запроса к модели, подстраивающийся
+// под её задержки: Multiplier × p95 последних ответов, но не меньше Min и
+// не больше Max. Безопасен для одновременного использования.
+type AdaptiveTimeout struct {
+    multiplier float64
+    min, max   time.Duration
+
+    mu sync.Mutex
+    // samples — кольцевой буфер последних задержек, next — куда писать
+    samples []time.Duration
+    next    int
+    current time.Duration
+}
+
+// NewAdaptiveTimeout — адаптивный предел с множителем p95 и границами [min, max]
+func NewAdaptiveTimeout(multiplier float64, min, max time.Duration) *AdaptiveTimeout {
+    a := &AdaptiveTimeout{multiplier: multiplier, min: min, max: max, current: max}
+    metrics.OpenAIAdaptiveTimeout.Set(max.Seconds())
+    return a
+}
+
+// Timeout — текущий предел на попытку запроса
+func (a *AdaptiveTimeout) Timeout() time.Duration {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.current
+}
+
+// Observe учитывает задержку попытки и пересчитывает предел
+func (a *AdaptiveTimeout) Observe(d time.Duration) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if len(a.samples) < adaptiveWindow {
+        a.samples = append(a.samples, d)
+    } else {
+        a.samples[a.next] = d
+        a.next = (a.next + 1) % adaptiveWindow
+    }
+    if len(a.samples) < adaptiveMinSamples {
+        return
+    }
+
+    sorted := slices.Clone(a.samples)
+    slices.Sort(sorted)
+    p95 := sorted[(len(sorted)*95+99)/100-1]
+    a.current = min(max(time.Duration(float64(p95)*a.multiplier), a.min), a.max)
+    metrics.OpenAIAdaptiveTimeout.Set(a.current.Seconds())
+}
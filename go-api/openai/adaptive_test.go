@@ -0,0 +1,64 @@
+package openai
+
+import (
+    "testing"
+    "time"
+)
+
+// attempt — попытка запроса с задержкой модели latency при пределе
+// a.Timeout(): оборванная по пределу попытка учитывается с самим пределом,
+// как в doPost
+func attempt(a *AdaptiveTimeout, latency time.Duration) {
+    a.Observe(min(latency, a.Timeout()))
+}
+
+func TestAdaptiveTimeoutBounds(t *testing.T) {
+    const lo, hi = 2 * time.Second, 30 * time.Second
+
+    cases := []struct {
+        name    string
+        latency time.Duration
+        want    time.Duration
+    }{
+        {"обычная задержка", time.Second, 3 * time.Second},
+        {"быстрая модель упирается в минимум", 100 * time.Millisecond, lo},
+        {"медленная модель упирается в максимум", 20 * time.Second, hi},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            a := NewAdaptiveTimeout(3, lo, hi)
+            for range adaptiveMinSamples - 1 {
+                attempt(a, tc.latency)
+            }
+            if got := a.Timeout(); got != hi {
+                t.Fatalf("до %d замеров предел = %s, нужен максимум %s", adaptiveMinSamples, got, hi)
+            }
+            for range adaptiveWindow {
+                attempt(a, tc.latency)
+            }
+            if got := a.Timeout(); got != tc.want {
+                t.Fatalf("предел = %s, нужно %s", got, tc.want)
+            }
+        })
+    }
+}
+
+// Когда модель замедляется сильнее предела, оборванные попытки
+// поднимают его, пока ответы снова не начнут успевать
+func TestAdaptiveTimeoutGrowsWhenLatencyRises(t *testing.T) {
+    a := NewAdaptiveTimeout(1.5, time.Second, time.Minute)
+    for range adaptiveWindow {
+        attempt(a, 2*time.Second)
+    }
+    if got := a.Timeout(); got != 3*time.Second {
+        t.Fatalf("предел при задержке 2s = %s, нужно 3s", got)
+    }
+
+    slow := 10 * time.Second
+    for range adaptiveWindow {
+        attempt(a, slow)
+    }
+    if got := a.Timeout(); got <= slow {
+        t.Fatalf("после замедления до %s предел = %s: ответы так и не успевают", slow, got)
+    }
+}
@@ -0,0 +1,77 @@
+package openai
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+)
+
+// defaultTranscriptionModel — модель распознавания речи по умолчанию
+const defaultTranscriptionModel = "whisper-1"
+
+// Transcribe распознаёт речь в аудиофайле через /v1/audio/transcriptions.
+// filename нужен OpenAI, чтобы определить формат по расширению (voice.ogg).
+func (c *Client) Transcribe(ctx context.Context, audio []byte, filename string) (string, error) {
+    var body bytes.Buffer
+    mw := multipart.NewWriter(&body)
+    if err := mw.WriteField("model", c.transcriptionModel); err != nil {
+        return "", fmt.Errorf("ошибка формирования запроса к OpenAI: %w", err)
+    }
+    fw, err := mw.CreateFormFile("file", filename)
+    if err != nil {
+        return "", fmt.Errorf("ошибка формирования запроса к OpenAI: %w", err)
+    }
+    if _, err := fw.Write(audio); err != nil {
+        return "", fmt.Errorf("ошибка формирования запроса к OpenAI: %w", err)
+    }
+    if err := mw.Close(); err != nil {
+        return "", fmt.Errorf("ошибка формирования запроса к OpenAI: %w", err)
+    }
+
+    var parsed struct {
+        Text string `json:"text"`
+    }
+    err = c.withRetry(ctx, nil, func() error {
+        return c.doMultipart(ctx, "/audio/transcriptions", mw.FormDataContentType(), body.Bytes(), &parsed)
+    })
+    if err != nil {
+        return "", err
+    }
+    return parsed.Text, nil
+}
+
+// doMultipart — одна попытка multipart-запроса к API
+func (c *Client) doMultipart(ctx context.Context, path, contentType string, body []byte, out interface{}) error {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(path), bytes.NewReader(body))
+    if err != nil {
+        return fmt.Errorf("ошибка создания запроса к OpenAI: %w", err)
+    }
+    c.setHeaders(ctx, req, contentType)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("ошибка запроса к OpenAI: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return &APIError{
+            StatusCode: resp.StatusCode,
+            Body:       string(respBody),
+            RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+        }
+    }
+
+    if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+        return fmt.Errorf("ошибка разбора ответа OpenAI: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,54 @@
+package openai
+
+import (
+    "context"
+    "strings"
+    "time"
+)
+
+// Exchange — запрос к /chat/completions и ответ модели, для журнала аудита
+type Exchange struct {
+    Model    string
+    Messages []Message
+    // Response — текст ответа; если модель вызвала инструменты — их вызовы
+    Response string
+    Usage    Usage
+    // Latency — от отправки запроса до ответа, с повторами; у потока — до конца потока
+    Latency time.Duration
+    // Err — ошибка запроса; nil — ответ получен
+    Err error
+}
+
+// ExchangeFunc получает каждый запрос к /chat/completions вместе с ответом.
+// Вызывается синхронно после ответа — долгую запись хук выносит сам.
+type ExchangeFunc func(ctx context.Context, ex Exchange)
+
+// recordExchange передаёт обмен с моделью в хук, если он задан
+func (c *Client) recordExchange(ctx context.Context, ex Exchange) {
+    if c.onExchange != nil {
+        c.onExchange(ctx, ex)
+    }
+}
+
+// exchangeOf — обмен по запросу req и разобранному ответу out
+func exchangeOf(req chatRequest, out interface{}, latency time.Duration, err error) Exchange {
+    ex := Exchange{Model: req.Model, Messages: req.Messages, Latency: latency, Err: err}
+    resp, ok := out.(*chatResponse)
+    if !ok || err != nil {
+        return ex
+    }
+    ex.Usage = resp.Usage
+    if len(resp.Choices) == 0 {
+        return ex
+    }
+    msg := resp.Choices[0].Message
+    ex.Response = msg.Content
+    if len(msg.ToolCalls) > 0 {
+        calls := make([]string, len(msg.ToolCalls))
+        for i, tc := range msg.ToolCalls {
+            calls[i] = tc.Function.Name + "(" + tc.Function.Arguments + ")"
+        }
+        ex.Response = strings.TrimSpace(ex.Response + "\n" + strings.Join(calls, "\n"))
+    }
+    return ex
+}
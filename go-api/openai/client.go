@@ -0,0 +1,411 @@
+package openai
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/reqctx"
+
+    "golang.org/x/sync/semaphore"
+)
+
+const (
+    // DefaultBaseURL — адрес API OpenAI по умолчанию
+    DefaultBaseURL = "https://api.openai.com/v1"
+    defaultModel   = "gpt-4o-mini"
+    // defaultTimeout — предел попытки запроса, если Options.Timeout не задан
+    defaultTimeout = 60 * time.Second
+)
+
+// Провайдеры API: отличаются заголовком с ключом и параметрами запроса
+const (
+    // ProviderOpenAI — OpenAI и совместимые прокси (LiteLLM, vLLM): Authorization: Bearer
+    ProviderOpenAI = "openai"
+    // ProviderAzure — Azure OpenAI: заголовок api-key и обязательный api-version
+    ProviderAzure = "azure"
+)
+
+// Message — сообщение диалога в формате Chat Completions API
+type Message struct {
+    Role    Role   `json:"role"`
+    Content string `json:"content"`
+
+    // ToolCalls — вызовы инструментов в ответе модели (role=assistant)
+    ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+    // ToolCallID — на какой вызов отвечает сообщение с role=tool
+    ToolCallID string `json:"tool_call_id,omitempty"`
+
+    // Parts — составное содержимое (текст + изображения); если задано, заменяет Content
+    Parts []ContentPart `json:"-"`
+}
+
+// Options — настройки клиента OpenAI
+type Options struct {
+    // BaseURL — адрес API без завершающего "/" (по умолчанию DefaultBaseURL).
+    // Для Azure — адрес деплоймента: https://<ресурс>.openai.azure.com/openai/deployments/<деплоймент>
+    BaseURL string
+    // Provider — ProviderOpenAI (по умолчанию) или ProviderAzure
+    Provider string
+    // APIVersion — версия API Azure (параметр api-version)
+    APIVersion string
+    // Model — модель для обычных запросов (по умолчанию gpt-4o-mini)
+    Model string
+    // Temperature — температура выборки, 0..2
+    Temperature float64
+    // MaxTokens — предел длины ответа в токенах (0 — на усмотрение OpenAI)
+    MaxTokens int
+    // MaxAttempts — сколько раз пробовать запрос при 429/5xx (минимум 1)
+    MaxAttempts int
+    // Timeout — предел на одну попытку запроса (по умолчанию defaultTimeout).
+    // Поток ответа им не ограничен — его длину задаёт контекст вызывающего.
+    Timeout time.Duration
+    // AdaptiveTimeout — предел для /chat/completions по недавним задержкам
+    // вместо Timeout; nil — предел постоянный
+    AdaptiveTimeout *AdaptiveTimeout
+    // MaxConcurrency — предел одновременных запросов к API (0 — без ограничения)
+    MaxConcurrency int
+    // VisionModel — модель для сообщений с изображениями
+    VisionModel string
+    // TranscriptionModel — модель распознавания голосовых (по умолчанию whisper-1)
+    TranscriptionModel string
+    // EmbeddingModel — модель эмбеддингов (по умолчанию text-embedding-3-small)
+    EmbeddingModel string
+    // EmbeddingDimensions — размерность векторов (0 — родная размерность модели)
+    EmbeddingDimensions int
+    // OnUsage — хук учёта токенов (счётчики, бюджет); может быть nil
+    OnUsage UsageFunc
+    // Admit — хук допуска запроса (общий лимит аккаунта); может быть nil
+    Admit AdmitFunc
+    // OnExchange — хук журнала запросов и ответов модели (аудит); может быть nil
+    OnExchange ExchangeFunc
+    // UnknownToolRetries — сколько вызовов несуществующих инструментов
+    // прощается модели за ответ; 0 — ни одного
+    UnknownToolRetries int
+}
+
+// Client — клиент OpenAI API
+type Client struct {
+    apiKey     string
+    baseURL    string
+    provider   string
+    apiVersion string
+    httpClient *http.Client
+    // timeout — предел на одну попытку запроса, см. withTimeout
+    timeout time.Duration
+    // adaptive — адаптивный предел запросов к модели; nil — только timeout
+    adaptive *AdaptiveTimeout
+    // streamClient — без общего таймаута: длину потока ограничивает контекст запроса
+    streamClient *http.Client
+    maxAttempts  int
+    // sem — слоты одновременных запросов; nil, если лимит не задан
+    sem                *semaphore.Weighted
+    model              string
+    visionModel        string
+    transcriptionModel string
+    // embeddingModel и embeddingDimensions — настройки Embeddings
+    embeddingModel      string
+    embeddingDimensions int
+    temperature         float64
+    maxTokens           int
+    onUsage             UsageFunc
+    admit               AdmitFunc
+    onExchange          ExchangeFunc
+    unknownToolRetries  int
+}
+
+// NewClient — фабрика клиента OpenAI с API-ключом и настройками
+func NewClient(apiKey string, opts Options) *Client {
+    if opts.MaxAttempts < 1 {
+        opts.MaxAttempts = 1
+    }
+    if opts.Timeout <= 0 {
+        opts.Timeout = defaultTimeout
+    }
+    if opts.UnknownToolRetries < 0 {
+        opts.UnknownToolRetries = 0
+    }
+    if opts.BaseURL == "" {
+        opts.BaseURL = DefaultBaseURL
+    }
+    if opts.Provider == "" {
+        opts.Provider = ProviderOpenAI
+    }
+    if opts.Model == "" {
+        opts.Model = defaultModel
+    }
+    if opts.VisionModel == "" {
+        opts.VisionModel = defaultModel
+    }
+    if opts.TranscriptionModel == "" {
+        opts.TranscriptionModel = defaultTranscriptionModel
+    }
+    if opts.EmbeddingModel == "" {
+        opts.EmbeddingModel = defaultEmbeddingModel
+    }
+    var sem *semaphore.Weighted
+    if opts.MaxConcurrency > 0 {
+        sem = semaphore.NewWeighted(int64(opts.MaxConcurrency))
+    }
+    return &Client{
+        apiKey:              apiKey,
+        sem:                 sem,
+        baseURL:             strings.TrimRight(opts.BaseURL, "/"),
+        provider:            opts.Provider,
+        apiVersion:          opts.APIVersion,
+        httpClient:          &http.Client{},
+        timeout:             opts.Timeout,
+        adaptive:            opts.AdaptiveTimeout,
+        streamClient:        &http.Client{},
+        maxAttempts:         opts.MaxAttempts,
+        model:               opts.Model,
+        visionModel:         opts.VisionModel,
+        transcriptionModel:  opts.TranscriptionModel,
+        embeddingModel:      opts.EmbeddingModel,
+        embeddingDimensions: opts.EmbeddingDimensions,
+        temperature:         opts.Temperature,
+        maxTokens:           opts.MaxTokens,
+        onUsage:             opts.OnUsage,
+        admit:               opts.Admit,
+        onExchange:          opts.OnExchange,
+        unknownToolRetries:  opts.UnknownToolRetries,
+    }
+}
+
+// APIError — ответ OpenAI с кодом, отличным от 200
+type APIError struct {
+    StatusCode int
+    Body       string
+    // RetryAfter — пауза из заголовка Retry-After, если OpenAI её прислал
+    RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("openai вернул %d: %s", e.StatusCode, e.Body)
+}
+
+var errEmptyChoices = errors.New("openai вернул пустой список choices")
+
+type chatRequest struct {
+    Model       string    `json:"model"`
+    Messages    []Message `json:"messages"`
+    Tools       []Tool    `json:"tools,omitempty"`
+    Temperature float64   `json:"temperature"`
+    MaxTokens   int       `json:"max_tokens,omitempty"`
+    // ResponseFormat — режим JSON; nil — обычный текст
+    ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// newRequest — тело запроса с настройками генерации клиента
+func (c *Client) newRequest(ctx context.Context, model string, messages []Message, tools []Tool) chatRequest {
+    return chatRequest{
+        Model:       model,
+        Messages:    messages,
+        Tools:       tools,
+        Temperature: c.temperature,
+        MaxTokens:   c.maxTokensFor(ctx),
+    }
+}
+
+type maxTokensKey struct{}
+
+// WithMaxTokens ограничивает длину ответов на запросы с этим контекстом.
+// Предел клиента (MaxTokens) остаётся в силе, если он строже.
+func WithMaxTokens(ctx context.Context, n int) context.Context {
+    return context.WithValue(ctx, maxTokensKey{}, n)
+}
+
+// maxTokensFor — предел длины ответа с учётом WithMaxTokens
+func (c *Client) maxTokensFor(ctx context.Context) int {
+    n, _ := ctx.Value(maxTokensKey{}).(int)
+    if n <= 0 || (c.maxTokens > 0 && c.maxTokens < n) {
+        return c.maxTokens
+    }
+    return n
+}
+
+type chatResponse struct {
+    Choices []struct {
+        Message Message `json:"message"`
+    } `json:"choices"`
+    Usage Usage `json:"usage"`
+}
+
+// Usage — расход токенов на один запрос
+type Usage struct {
+    PromptTokens     int64 `json:"prompt_tokens"`
+    CompletionTokens int64 `json:"completion_tokens"`
+    TotalTokens      int64 `json:"total_tokens"`
+}
+
+// UsageFunc получает расход токенов каждого успешного запроса
+type UsageFunc func(ctx context.Context, u Usage)
+
+// recordUsage передаёт расход токенов в хук, если он задан
+func (c *Client) recordUsage(ctx context.Context, u Usage) {
+    if c.onUsage != nil && u.TotalTokens > 0 {
+        c.onUsage(ctx, u)
+    }
+}
+
+// ChatCompletion отправляет диалог в /v1/chat/completions и возвращает ответ модели.
+// Временные ошибки (429, 5xx, сеть) повторяются с экспоненциальной задержкой.
+func (c *Client) ChatCompletion(ctx context.Context, messages []Message) (string, error) {
+    msg, err := c.complete(ctx, messages, nil)
+    if err != nil {
+        return "", err
+    }
+    return msg.Content, nil
+}
+
+// complete — запрос к /chat/completions, возвращающий сообщение модели целиком
+// (вместе с tool_calls, если модель решила вызвать инструмент)
+func (c *Client) complete(ctx context.Context, messages []Message, tools []Tool) (Message, error) {
+    var parsed chatResponse
+    err := c.post(ctx, "/chat/completions", c.newRequest(ctx, c.model, messages, tools), &parsed)
+    if err != nil {
+        return Message{}, err
+    }
+    c.recordUsage(ctx, parsed.Usage)
+    if len(parsed.Choices) == 0 {
+        return Message{}, errEmptyChoices
+    }
+    return parsed.Choices[0].Message, nil
+}
+
+// AdmitFunc вызывается перед каждой попыткой запроса к /chat/completions,
+// включая повторы, с грубой оценкой его токенов (запрос и предел ответа).
+// Ошибка отменяет запрос без обращения к API. Эмбеддинги и распознавание речи у OpenAI лимитируются
+// отдельно и через хук не проходят.
+type AdmitFunc func(ctx context.Context, tokens int64) error
+
+// admitJSON пропускает попытку запроса с телом body через хук Admit, если он задан
+func (c *Client) admitJSON(ctx context.Context, body []byte) error {
+    if c.admit == nil {
+        return nil
+    }
+    // JSON-тело немного длиннее текста сообщений — для лимита это запас, а не ошибка
+    tokens := int64(len(body)/4 + c.maxTokensFor(ctx))
+    if err := c.admit(ctx, tokens); err != nil {
+        return apperr.Upstream(err)
+    }
+    return nil
+}
+
+// post отправляет JSON на эндпоинт API с повторами и разбирает ответ в out
+func (c *Client) post(ctx context.Context, path string, payload, out interface{}) error {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("ошибка сериализации запроса к OpenAI: %w", err)
+    }
+    req, isChat := payload.(chatRequest)
+    if isChat {
+        if err := ValidateMessages(req.Messages); err != nil {
+            return err
+        }
+    }
+    var admit func() error
+    if path == "/chat/completions" {
+        admit = func() error { return c.admitJSON(ctx, body) }
+    }
+
+    start := time.Now()
+    err = c.withRetry(ctx, admit, func() error {
+        return c.doPost(ctx, path, body, out)
+    })
+    if isChat {
+        c.recordExchange(ctx, exchangeOf(req, out, time.Since(start), err))
+    }
+    return err
+}
+
+// endpoint — полный адрес метода API с учётом провайдера
+func (c *Client) endpoint(path string) string {
+    u := c.baseURL + path
+    if c.provider == ProviderAzure && c.apiVersion != "" {
+        u += "?api-version=" + url.QueryEscape(c.apiVersion)
+    }
+    return u
+}
+
+// setHeaders — общие заголовки запроса к API. Id трассировки уходит
+// в X-Client-Request-Id: по нему поддержка OpenAI находит запрос у себя.
+func (c *Client) setHeaders(ctx context.Context, req *http.Request, contentType string) {
+    req.Header.Set("Content-Type", contentType)
+    if c.provider == ProviderAzure {
+        req.Header.Set("api-key", c.apiKey)
+    } else {
+        req.Header.Set("Authorization", "Bearer "+c.apiKey)
+    }
+    if id := reqctx.TraceIDFromContext(ctx); id != "" {
+        req.Header.Set("X-Client-Request-Id", id)
+    }
+}
+
+// withTimeout ограничивает попытку запроса сроком OPENAI_TIMEOUT. Родительский
+// дедлайн (UPDATE_TIMEOUT апдейта) context.WithTimeout не продлевает, поэтому
+// действует тот, что наступит раньше.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+    return context.WithTimeout(ctx, c.timeout)
+}
+
+// doPost — одна попытка запроса к API
+func (c *Client) doPost(ctx context.Context, path string, body []byte, out interface{}) (err error) {
+    if path != "/chat/completions" || c.adaptive == nil {
+        ctx, cancel := c.withTimeout(ctx)
+        defer cancel()
+        return c.doPostOnce(ctx, path, body, out)
+    }
+
+    parent := ctx
+    ctx, cancel := context.WithTimeout(ctx, c.adaptive.Timeout())
+    defer cancel()
+    start := time.Now()
+    defer func() {
+        // Попытка, упёршаяся в сам предел, тоже учитывается — иначе при
+        // замедлении модели p95 видел бы только успевшие ответы и предел
+        // не подрастал бы. Отмена сверху о задержке модели ничего не говорит.
+        if err == nil || (errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil) {
+            c.adaptive.Observe(time.Since(start))
+        }
+    }()
+    return c.doPostOnce(ctx, path, body, out)
+}
+
+// doPostOnce — запрос к API в пределах дедлайна ctx
+func (c *Client) doPostOnce(ctx context.Context, path string, body []byte, out interface{}) error {
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(path), bytes.NewReader(body))
+    if err != nil {
+        return fmt.Errorf("ошибка создания запроса к OpenAI: %w", err)
+    }
+    c.setHeaders(ctx, req, "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("ошибка запроса к OpenAI: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return &APIError{
+            StatusCode: resp.StatusCode,
+            Body:       string(respBody),
+            RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+        }
+    }
+
+    if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+        return fmt.Errorf("ошибка разбора ответа OpenAI: %w", err)
+    }
+    return nil
+}
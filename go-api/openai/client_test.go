@@ -0,0 +1,140 @@
+package openai
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+)
+
+// seenRequest — что дошло до сервера
+type seenRequest struct {
+    path, query, auth, apiKey string
+}
+
+// baseServer — API по любому пути, запоминающий запросы
+func baseServer(t *testing.T) (*httptest.Server, func() []seenRequest) {
+    t.Helper()
+    var (
+        mu   sync.Mutex
+        seen []seenRequest
+    )
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        seen = append(seen, seenRequest{r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization"), r.Header.Get("api-key")})
+        mu.Unlock()
+        w.Header().Set("Content-Type", "application/json")
+        switch {
+        case r.Method == http.MethodGet:
+            fmt.Fprint(w, `{"data":[]}`)
+        case len(r.URL.Path) >= len("/embeddings") && r.URL.Path[len(r.URL.Path)-len("/embeddings"):] == "/embeddings":
+            json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"index": 0, "embedding": []float32{0.1}}}})
+        default:
+            fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ок"}}]}`)
+        }
+    }))
+    t.Cleanup(srv.Close)
+    return srv, func() []seenRequest {
+        mu.Lock()
+        defer mu.Unlock()
+        return append([]seenRequest(nil), seen...)
+    }
+}
+
+// Все эндпоинты идут на настроенный адрес: прокси вроде LiteLLM и Azure
+func TestBaseURL(t *testing.T) {
+    cases := []struct {
+        name   string
+        opts   func(base string) Options
+        prefix string
+        query  string
+        auth   string
+        apiKey string
+    }{
+        {"прокси", func(base string) Options {
+            return Options{BaseURL: base + "/litellm/v1"}
+        }, "/litellm/v1", "", "Bearer sk-test", ""},
+        {"Azure", func(base string) Options {
+            return Options{BaseURL: base + "/openai/deployments/gpt-4o", Provider: ProviderAzure, APIVersion: "2024-06-01"}
+        }, "/openai/deployments/gpt-4o", "api-version=2024-06-01", "", "sk-test"},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            srv, seen := baseServer(t)
+            c := NewClient("sk-test", tc.opts(srv.URL))
+            ctx := context.Background()
+
+            if _, err := c.ChatCompletion(ctx, hello); err != nil {
+                t.Fatalf("ChatCompletion: %v", err)
+            }
+            if _, err := c.Embeddings(ctx, []string{"улун"}); err != nil {
+                t.Fatalf("Embeddings: %v", err)
+            }
+            if err := c.Ping(ctx); err != nil {
+                t.Fatalf("Ping: %v", err)
+            }
+
+            got := seen()
+            paths := []string{"/chat/completions", "/embeddings", "/models"}
+            if len(got) != len(paths) {
+                t.Fatalf("запросы %+v", got)
+            }
+            for i, r := range got {
+                if r.path != tc.prefix+paths[i] || r.query != tc.query {
+                    t.Errorf("запрос на %s?%s, нужно %s?%s", r.path, r.query, tc.prefix+paths[i], tc.query)
+                }
+                if r.auth != tc.auth || r.apiKey != tc.apiKey {
+                    t.Errorf("%s: Authorization %q, api-key %q", r.path, r.auth, r.apiKey)
+                }
+            }
+        })
+    }
+}
+
+// MaxTokens 0 — предела нет: max_tokens не уходит вовсе и длину ответа
+// выбирает модель; предел из WithMaxTokens действует и без предела клиента
+func TestMaxTokensSent(t *testing.T) {
+    cases := []struct {
+        name   string
+        client int
+        ctx    int
+        want   any
+    }{
+        {"без предела", 0, 0, nil},
+        {"предел клиента", 512, 0, float64(512)},
+        {"предел запроса без предела клиента", 0, 150, float64(150)},
+        {"строже предел клиента", 100, 150, float64(100)},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            var body map[string]any
+            srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+                    t.Errorf("тело запроса: %v", err)
+                }
+                w.Header().Set("Content-Type", "application/json")
+                fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ок"}}]}`)
+            }))
+            defer srv.Close()
+
+            c := NewClient("sk-test", Options{BaseURL: srv.URL, MaxTokens: tc.client})
+            ctx := context.Background()
+            if tc.ctx > 0 {
+                ctx = WithMaxTokens(ctx, tc.ctx)
+            }
+            if _, err := c.ChatCompletion(ctx, hello); err != nil {
+                t.Fatalf("ChatCompletion: %v", err)
+            }
+            got, sent := body["max_tokens"]
+            if tc.want == nil && sent {
+                t.Fatalf("отправлен max_tokens=%v, предела быть не должно", got)
+            }
+            if tc.want != nil && got != tc.want {
+                t.Fatalf("max_tokens=%v, ожидалось %v", got, tc.want)
+            }
+        })
+    }
+}
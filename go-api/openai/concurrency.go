@@ -0,0 +1,29 @@
+package openai
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "ai_seller/metrics"
+)
+
+// ErrBusy — свободный слот для запроса не освободился до дедлайна контекста
+var ErrBusy = errors.New("все слоты запросов к OpenAI заняты")
+
+// acquire занимает слот запроса к API и возвращает функцию его освобождения.
+// Без настроенного лимита слот не нужен.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+    if c.sem == nil {
+        metrics.OpenAIInFlight.Inc()
+        return metrics.OpenAIInFlight.Dec, nil
+    }
+    if err := c.sem.Acquire(ctx, 1); err != nil {
+        return nil, fmt.Errorf("%w: %w", ErrBusy, err)
+    }
+    metrics.OpenAIInFlight.Inc()
+    return func() {
+        metrics.OpenAIInFlight.Dec()
+        c.sem.Release(1)
+    }, nil
+}
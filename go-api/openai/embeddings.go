@@ -0,0 +1,49 @@
+package openai
+
+import (
+    "context"
+    "fmt"
+)
+
+// defaultEmbeddingModel — модель эмбеддингов по умолчанию
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+type embeddingRequest struct {
+    Model      string   `json:"model"`
+    Input      []string `json:"input"`
+    Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+    Data []struct {
+        Index     int       `json:"index"`
+        Embedding []float32 `json:"embedding"`
+    } `json:"data"`
+    Usage Usage `json:"usage"`
+}
+
+// Embeddings возвращает векторы для inputs в том же порядке через /v1/embeddings
+func (c *Client) Embeddings(ctx context.Context, inputs []string) ([][]float32, error) {
+    if len(inputs) == 0 {
+        return nil, nil
+    }
+
+    var parsed embeddingResponse
+    req := embeddingRequest{Model: c.embeddingModel, Input: inputs, Dimensions: c.embeddingDimensions}
+    if err := c.post(ctx, "/embeddings", req, &parsed); err != nil {
+        return nil, err
+    }
+    c.recordUsage(ctx, parsed.Usage)
+
+    if len(parsed.Data) != len(inputs) {
+        return nil, fmt.Errorf("openai вернул %d эмбеддингов на %d строк", len(parsed.Data), len(inputs))
+    }
+    vectors := make([][]float32, len(inputs))
+    for _, d := range parsed.Data {
+        if d.Index < 0 || d.Index >= len(inputs) {
+            return nil, fmt.Errorf("openai вернул эмбеддинг с некорректным index %d", d.Index)
+        }
+        vectors[d.Index] = d.Embedding
+    }
+    return vectors, nil
+}
@@ -0,0 +1,26 @@
+package openai
+
+import "context"
+
+// responseFormat — формат ответа модели (response_format)
+type responseFormat struct {
+    Type string `json:"type"`
+}
+
+// JSONCompletion — ChatCompletion в режиме JSON (response_format: json_object):
+// модель обязана вернуть один JSON-объект. OpenAI отклоняет такой запрос,
+// если слово «JSON» не встречается в сообщениях, — инструкция за вызывающим.
+func (c *Client) JSONCompletion(ctx context.Context, messages []Message) (string, error) {
+    req := c.newRequest(ctx, c.model, messages, nil)
+    req.ResponseFormat = &responseFormat{Type: "json_object"}
+
+    var parsed chatResponse
+    if err := c.post(ctx, "/chat/completions", req, &parsed); err != nil {
+        return "", err
+    }
+    c.recordUsage(ctx, parsed.Usage)
+    if len(parsed.Choices) == 0 {
+        return "", errEmptyChoices
+    }
+    return parsed.Choices[0].Message.Content, nil
+}
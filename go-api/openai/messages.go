@@ -0,0 +1,74 @@
+package openai
+
+import (
+    "errors"
+    "fmt"
+)
+
+// Role — роль автора сообщения в Chat Completions API
+type Role string
+
+// Роли сообщений
+const (
+    RoleSystem    Role = "system"
+    RoleUser      Role = "user"
+    RoleAssistant Role = "assistant"
+    RoleTool      Role = "tool"
+)
+
+// Valid — роль из числа известных API
+func (r Role) Valid() bool {
+    switch r {
+    case RoleSystem, RoleUser, RoleAssistant, RoleTool:
+        return true
+    }
+    return false
+}
+
+// ErrInvalidMessages — диалог, который OpenAI отклонил бы с 400; запрос не отправляется
+var ErrInvalidMessages = errors.New("некорректные сообщения для OpenAI")
+
+// System — системное сообщение (инструкции модели)
+func System(text string) Message {
+    return Message{Role: RoleSystem, Content: text}
+}
+
+// User — реплика покупателя
+func User(text string) Message {
+    return Message{Role: RoleUser, Content: text}
+}
+
+// Assistant — реплика модели
+func Assistant(text string) Message {
+    return Message{Role: RoleAssistant, Content: text}
+}
+
+// ToolResult — результат вызова инструмента callID
+// (имя Tool занято описанием инструмента)
+func ToolResult(callID, text string) Message {
+    return Message{Role: RoleTool, ToolCallID: callID, Content: text}
+}
+
+// ValidateMessages проверяет диалог перед отправкой: роли известны, у ответа
+// инструмента есть tool_call_id, а если в диалоге есть системные сообщения,
+// он начинается с системного. Уточняющие системные сообщения дальше по
+// диалогу допустимы. Ошибка оборачивает ErrInvalidMessages.
+func ValidateMessages(messages []Message) error {
+    if len(messages) == 0 {
+        return fmt.Errorf("%w: пустой диалог", ErrInvalidMessages)
+    }
+    hasSystem := false
+    for i, m := range messages {
+        if !m.Role.Valid() {
+            return fmt.Errorf("%w: сообщение %d с неизвестной ролью %q", ErrInvalidMessages, i, m.Role)
+        }
+        if m.Role == RoleTool && m.ToolCallID == "" {
+            return fmt.Errorf("%w: ответ инструмента %d без tool_call_id", ErrInvalidMessages, i)
+        }
+        hasSystem = hasSystem || m.Role == RoleSystem
+    }
+    if hasSystem && messages[0].Role != RoleSystem {
+        return fmt.Errorf("%w: диалог начинается с %q, а не с системного сообщения", ErrInvalidMessages, messages[0].Role)
+    }
+    return nil
+}
@@ -0,0 +1,77 @@
+package openai
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+)
+
+func TestConstructorsRoles(t *testing.T) {
+    for _, tc := range []struct {
+        msg  Message
+        want Role
+    }{
+        {System("инструкции"), RoleSystem},
+        {User("привет"), RoleUser},
+        {Assistant("здравствуйте"), RoleAssistant},
+        {ToolResult("call_1", "{}"), RoleTool},
+    } {
+        if tc.msg.Role != tc.want || !tc.msg.Role.Valid() {
+            t.Errorf("%+v: роль %q, нужно %q", tc.msg, tc.msg.Role, tc.want)
+        }
+    }
+    if Role("asistant").Valid() {
+        t.Fatal("опечатка в роли считается допустимой")
+    }
+    if m := ToolResult("call_1", "{}"); m.ToolCallID != "call_1" {
+        t.Fatalf("ToolResult без tool_call_id: %+v", m)
+    }
+}
+
+func TestValidateMessages(t *testing.T) {
+    cases := []struct {
+        name     string
+        messages []Message
+        valid    bool
+    }{
+        {"только покупатель", []Message{User("привет")}, true},
+        {"система первой", []Message{System("инструкции"), User("привет"), Assistant("здравствуйте")}, true},
+        {"уточнение системы дальше", []Message{System("инструкции"), User("привет"), System("кратко")}, true},
+        {"ответ инструмента", []Message{System("инструкции"), User("найди улун"), ToolResult("call_1", "{}")}, true},
+        {"пустой диалог", nil, false},
+        {"система не первой", []Message{User("привет"), System("инструкции")}, false},
+        {"опечатка в роли", []Message{User("привет"), {Role: "asistant", Content: "ок"}}, false},
+        {"пустая роль", []Message{{Content: "привет"}}, false},
+        {"инструмент без tool_call_id", []Message{User("найди улун"), {Role: RoleTool, Content: "{}"}}, false},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            err := ValidateMessages(tc.messages)
+            if tc.valid && err != nil {
+                t.Fatalf("корректный диалог отклонён: %v", err)
+            }
+            if !tc.valid && !errors.Is(err, ErrInvalidMessages) {
+                t.Fatalf("ошибка %v, нужна ErrInvalidMessages", err)
+            }
+        })
+    }
+}
+
+// Некорректный диалог не уходит в API ни обычным запросом, ни потоком
+func TestInvalidMessagesNotSent(t *testing.T) {
+    var calls atomic.Int64
+    srv := flakyServer(t, 0, &calls)
+    c := NewClient("key", Options{BaseURL: srv.URL, MaxConcurrency: 1})
+    bad := []Message{User("привет"), System("инструкции")}
+
+    if _, err := c.ChatCompletion(context.Background(), bad); !errors.Is(err, ErrInvalidMessages) {
+        t.Fatalf("ChatCompletion: %v", err)
+    }
+    if _, err := c.ChatCompletionStream(context.Background(), bad); !errors.Is(err, ErrInvalidMessages) {
+        t.Fatalf("ChatCompletionStream: %v", err)
+    }
+    if n := calls.Load(); n != 0 {
+        t.Fatalf("запросов к API: %d", n)
+    }
+}
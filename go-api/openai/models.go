@@ -0,0 +1,34 @@
+package openai
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+)
+
+// Ping проверяет доступность API и ключа самым лёгким запросом — списком
+// моделей (GET /models). Без повторов: для диагностики важен ответ как есть.
+func (c *Client) Ping(ctx context.Context) error {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/models"), nil)
+    if err != nil {
+        return fmt.Errorf("ошибка создания запроса к OpenAI: %w", err)
+    }
+    c.setHeaders(ctx, req, "")
+    // У GET нет тела, и Content-Type ему не нужен
+    req.Header.Del("Content-Type")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("ошибка запроса к OpenAI: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+    }
+    return nil
+}
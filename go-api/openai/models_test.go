@@ -0,0 +1,39 @@
+package openai
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestPing(t *testing.T) {
+    for _, status := range []int{http.StatusOK, http.StatusUnauthorized} {
+        var calls int
+        srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            calls++
+            if r.Method != http.MethodGet || r.URL.Path != "/models" {
+                t.Errorf("запрос %s %s, нужен GET /models", r.Method, r.URL.Path)
+            }
+            if r.Header.Get("Authorization") != "Bearer key" {
+                t.Errorf("Authorization %q", r.Header.Get("Authorization"))
+            }
+            w.WriteHeader(status)
+        }))
+        c := NewClient("key", Options{BaseURL: srv.URL, MaxConcurrency: 1})
+
+        err := c.Ping(context.Background())
+        srv.Close()
+        var apiErr *APIError
+        switch {
+        case status == http.StatusOK && err != nil:
+            t.Fatalf("доступный API: %v", err)
+        case status != http.StatusOK && (!errors.As(err, &apiErr) || apiErr.StatusCode != status):
+            t.Fatalf("ответ %d: ошибка %v", status, err)
+        }
+        if calls != 1 {
+            t.Fatalf("ответ %d: запросов %d, проверка не повторяется", status, calls)
+        }
+    }
+}
@@ -0,0 +1,49 @@
+package openai
+
+import (
+    "context"
+    "errors"
+)
+
+// moderationModel — модель /v1/moderations; понимает русский и бесплатна
+const moderationModel = "omni-moderation-latest"
+
+type moderationRequest struct {
+    Model string `json:"model"`
+    Input string `json:"input"`
+}
+
+type moderationResponse struct {
+    Results []ModerationResult `json:"results"`
+}
+
+// ModerationResult — оценка текста модерацией OpenAI
+type ModerationResult struct {
+    // Flagged — OpenAI сам считает текст нарушением при своих порогах
+    Flagged bool `json:"flagged"`
+    // Scores — уверенность 0..1 по категориям (harassment, hate, violence...)
+    Scores map[string]float64 `json:"category_scores"`
+}
+
+// Top — категория с наибольшей оценкой и сама оценка
+func (r ModerationResult) Top() (category string, score float64) {
+    for c, s := range r.Scores {
+        // При равных оценках берём меньшее имя, чтобы лог не скакал между запусками
+        if category == "" || s > score || (s == score && c < category) {
+            category, score = c, s
+        }
+    }
+    return category, score
+}
+
+// Moderate проверяет текст через /v1/moderations
+func (c *Client) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+    var parsed moderationResponse
+    if err := c.post(ctx, "/moderations", moderationRequest{Model: moderationModel, Input: text}, &parsed); err != nil {
+        return ModerationResult{}, err
+    }
+    if len(parsed.Results) == 0 {
+        return ModerationResult{}, errors.New("openai вернул пустой результат модерации")
+    }
+    return parsed.Results[0], nil
+}
@@ -0,0 +1,150 @@
+package openai
+
+import (
+    "context"
+    "errors"
+    "math/rand"
+    "net/http"
+    "strconv"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/metrics"
+)
+
+const (
+    retryBaseDelay = 500 * time.Millisecond
+    retryMaxDelay  = 8 * time.Second
+)
+
+// retryable — стоит ли повторять запрос после такой ошибки
+func retryable(err error) bool {
+    var apiErr *APIError
+    if errors.As(err, &apiErr) {
+        switch apiErr.StatusCode {
+        case http.StatusTooManyRequests,
+            http.StatusInternalServerError,
+            http.StatusBadGateway,
+            http.StatusServiceUnavailable:
+            return true
+        }
+        return false
+    }
+    // Сетевые ошибки считаем временными, но не отмену/таймаут контекста
+    return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// withRetry выполняет fn до maxAttempts раз с экспоненциальной задержкой и джиттером.
+// Каждая попытка ждёт свободного слота; не дождавшись до дедлайна, возвращает ErrBusy.
+// Retry-After из ответа OpenAI имеет приоритет над расчётной задержкой.
+// Если до дедлайна контекста не успеть дождаться следующей попытки,
+// сразу возвращается последняя ошибка.
+// admit, если задан, вызывается перед каждой попыткой: повтор расходует
+// лимит аккаунта так же, как первый запрос. Его ошибка прекращает повторы.
+// Итоговая ошибка помечается apperr.ErrUpstream.
+func (c *Client) withRetry(ctx context.Context, admit, fn func() error) error {
+    _, err := c.retry(ctx, false, admit, fn)
+    return err
+}
+
+// withRetryHeld — withRetry, после удачной попытки которого слот остаётся
+// занятым, пока вызывающий не вызовет release: так потоковый ответ держит
+// слот, пока читается тело, а не только до прихода заголовков
+func (c *Client) withRetryHeld(ctx context.Context, admit, fn func() error) (release func(), err error) {
+    return c.retry(ctx, true, admit, fn)
+}
+
+// retry — попытки withRetry; hold — не освобождать слот удачной попытки
+func (c *Client) retry(ctx context.Context, hold bool, admit, fn func() error) (held func(), err error) {
+    defer func() { err = apperr.Upstream(err) }()
+
+    for attempt := 0; attempt < c.maxAttempts; attempt++ {
+        // Лимит аккаунта ждём до слота: ожидание не должно занимать его
+        if admit != nil {
+            if err := admit(); err != nil {
+                return nil, err
+            }
+        }
+        // Слот занимается на попытку, а не на все повторы: пауза между
+        // попытками не должна задерживать чужие запросы
+        release, acqErr := c.acquire(ctx)
+        if acqErr != nil {
+            return nil, acqErr
+        }
+        start := time.Now()
+        err = fn()
+        observeRequest(time.Since(start), err)
+        if err == nil && hold {
+            return release, nil
+        }
+        release()
+        if err == nil || !retryable(err) {
+            return nil, err
+        }
+        if attempt == c.maxAttempts-1 {
+            break
+        }
+
+        delay := backoffDelay(attempt)
+        var apiErr *APIError
+        if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+            delay = apiErr.RetryAfter
+        }
+
+        if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+            return nil, err
+        }
+
+        timer := time.NewTimer(delay)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return nil, err
+        case <-timer.C:
+        }
+    }
+    return nil, err
+}
+
+// observeRequest записывает длительность попытки и статус ошибки в метрики
+func observeRequest(d time.Duration, err error) {
+    metrics.OpenAIRequestDuration.Observe(d.Seconds())
+    if err == nil {
+        return
+    }
+
+    status := "network"
+    var apiErr *APIError
+    switch {
+    case errors.As(err, &apiErr):
+        status = strconv.Itoa(apiErr.StatusCode)
+    case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+        status = "canceled"
+    }
+    metrics.OpenAIErrorsTotal.WithLabelValues(status).Inc()
+}
+
+// backoffDelay — экспоненциальная задержка с «полным» джиттером
+func backoffDelay(attempt int) time.Duration {
+    d := retryBaseDelay << attempt
+    if d <= 0 || d > retryMaxDelay {
+        d = retryMaxDelay
+    }
+    return time.Duration(rand.Int63n(int64(d))) + time.Millisecond
+}
+
+// parseRetryAfter разбирает Retry-After: число секунд или HTTP-дату
+func parseRetryAfter(h string) time.Duration {
+    if h == "" {
+        return 0
+    }
+    if secs, err := strconv.Atoi(h); err == nil && secs > 0 {
+        return time.Duration(secs) * time.Second
+    }
+    if t, err := http.ParseTime(h); err == nil {
+        if d := time.Until(t); d > 0 {
+            return d
+        }
+    }
+    return 0
+}
@@ -0,0 +1,98 @@
+package openai
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+)
+
+// flakyServer — API, первые failures запросов которого падают с 503;
+// calls считает обращения
+func flakyServer(t *testing.T, failures int64, calls *atomic.Int64) *httptest.Server {
+    t.Helper()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if calls.Add(1) <= failures {
+            http.Error(w, "overloaded", http.StatusServiceUnavailable)
+            return
+        }
+        if r.Header.Get("Accept") == "text/event-stream" {
+            w.Header().Set("Content-Type", "text/event-stream")
+            fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ок\"}}]}\n\ndata: [DONE]\n\n")
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ок"}}]}`)
+    }))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// Каждая попытка, включая повтор после 503, проходит через лимит аккаунта
+func TestAdmitEveryAttempt(t *testing.T) {
+    for _, tc := range []struct {
+        name string
+        call func(c *Client) error
+    }{
+        {"обычный запрос", func(c *Client) error {
+            _, err := c.ChatCompletion(context.Background(), hello)
+            return err
+        }},
+        {"поток", func(c *Client) error {
+            chunks, err := c.ChatCompletionStream(context.Background(), hello)
+            if err != nil {
+                return err
+            }
+            for range chunks {
+            }
+            return nil
+        }},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            var calls, admitted atomic.Int64
+            srv := flakyServer(t, 1, &calls)
+            c := NewClient("key", Options{BaseURL: srv.URL, MaxConcurrency: 1, MaxAttempts: 2,
+                Admit: func(ctx context.Context, tokens int64) error {
+                    if tokens <= 0 {
+                        t.Errorf("оценка токенов %d", tokens)
+                    }
+                    admitted.Add(1)
+                    return nil
+                },
+            })
+
+            if err := tc.call(c); err != nil {
+                t.Fatal(err)
+            }
+            if calls.Load() != 2 || admitted.Load() != 2 {
+                t.Fatalf("запросов к API %d, допусков %d, нужно по 2", calls.Load(), admitted.Load())
+            }
+        })
+    }
+}
+
+// Отказ лимита на повторе прекращает повторы без обращения к API
+func TestAdmitRejectsRetry(t *testing.T) {
+    var calls, admitted atomic.Int64
+    srv := flakyServer(t, 1, &calls)
+    exhausted := errors.New("лимит исчерпан")
+    c := NewClient("key", Options{BaseURL: srv.URL, MaxConcurrency: 1, MaxAttempts: 3,
+        Admit: func(ctx context.Context, tokens int64) error {
+            if admitted.Add(1) > 1 {
+                return exhausted
+            }
+            return nil
+        },
+    })
+
+    _, err := c.ChatCompletion(context.Background(), hello)
+    if !errors.Is(err, exhausted) {
+        t.Fatalf("ошибка %v, нужен отказ лимита", err)
+    }
+    if calls.Load() != 1 {
+        t.Fatalf("запросов к API %d, нужен 1", calls.Load())
+    }
+}
@@ -0,0 +1,163 @@
+package openai
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// StreamChunk — фрагмент потокового ответа; Err заполняется при обрыве потока
+type StreamChunk struct {
+    Delta string
+    Err   error
+}
+
+type streamRequest struct {
+    chatRequest
+    Stream        bool          `json:"stream"`
+    StreamOptions streamOptions `json:"stream_options"`
+}
+
+// streamOptions — просим OpenAI прислать расход токенов последним событием потока
+type streamOptions struct {
+    IncludeUsage bool `json:"include_usage"`
+}
+
+type streamEvent struct {
+    Choices []struct {
+        Delta struct {
+            Content string `json:"content"`
+        } `json:"delta"`
+    } `json:"choices"`
+    Usage *Usage `json:"usage"`
+}
+
+// ChatCompletionStream запрашивает ответ в режиме stream:true и отдаёт
+// фрагменты текста в канал по мере поступления (SSE). Канал закрывается
+// по окончании ответа; ошибка посреди потока приходит последним фрагментом.
+// Повторяется только установка соединения — оборванный поток не перезапускается.
+// Слот MaxConcurrency занят, пока поток не дочитан или не отменён ctx.
+func (c *Client) ChatCompletionStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+    if err := ValidateMessages(messages); err != nil {
+        return nil, err
+    }
+    req := c.newRequest(ctx, c.model, messages, nil)
+    body, err := json.Marshal(streamRequest{
+        chatRequest:   req,
+        Stream:        true,
+        StreamOptions: streamOptions{IncludeUsage: true},
+    })
+    if err != nil {
+        return nil, fmt.Errorf("ошибка сериализации запроса к OpenAI: %w", err)
+    }
+
+    start := time.Now()
+    var resp *http.Response
+    admit := func() error { return c.admitJSON(ctx, body) }
+    release, err := c.withRetryHeld(ctx, admit, func() error {
+        var err error
+        resp, err = c.openStream(ctx, body)
+        return err
+    })
+    if err != nil {
+        c.recordExchange(ctx, exchangeOf(req, nil, time.Since(start), err))
+        return nil, err
+    }
+
+    chunks := make(chan StreamChunk)
+    go func() {
+        defer close(chunks)
+        defer release()
+        defer resp.Body.Close()
+
+        // Для аудита поток собирается целиком; запись — по его окончании
+        ex := Exchange{Model: req.Model, Messages: req.Messages}
+        var answer strings.Builder
+        defer func() {
+            ex.Response = answer.String()
+            ex.Latency = time.Since(start)
+            c.recordExchange(ctx, ex)
+        }()
+
+        send := func(ch StreamChunk) bool {
+            if ch.Err != nil {
+                ex.Err = ch.Err
+            }
+            answer.WriteString(ch.Delta)
+            select {
+            case chunks <- ch:
+                return true
+            case <-ctx.Done():
+                ex.Err = ctx.Err()
+                return false
+            }
+        }
+
+        scanner := bufio.NewScanner(resp.Body)
+        scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+        for scanner.Scan() {
+            line := scanner.Text()
+            data, ok := strings.CutPrefix(line, "data: ")
+            if !ok {
+                continue
+            }
+            if data == "[DONE]" {
+                return
+            }
+
+            var ev streamEvent
+            if err := json.Unmarshal([]byte(data), &ev); err != nil {
+                send(StreamChunk{Err: fmt.Errorf("ошибка разбора фрагмента OpenAI: %w", err)})
+                return
+            }
+            if ev.Usage != nil {
+                c.recordUsage(ctx, *ev.Usage)
+                ex.Usage = *ev.Usage
+            }
+            if len(ev.Choices) == 0 || ev.Choices[0].Delta.Content == "" {
+                continue
+            }
+            if !send(StreamChunk{Delta: ev.Choices[0].Delta.Content}) {
+                return
+            }
+        }
+        if err := scanner.Err(); err != nil {
+            send(StreamChunk{Err: fmt.Errorf("обрыв потока OpenAI: %w", err)})
+            return
+        }
+        send(StreamChunk{Err: io.ErrUnexpectedEOF})
+    }()
+
+    return chunks, nil
+}
+
+// openStream — одна попытка открыть потоковый ответ
+func (c *Client) openStream(ctx context.Context, body []byte) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint("/chat/completions"), bytes.NewReader(body))
+    if err != nil {
+        return nil, fmt.Errorf("ошибка создания запроса к OpenAI: %w", err)
+    }
+    c.setHeaders(ctx, req, "application/json")
+    req.Header.Set("Accept", "text/event-stream")
+
+    resp, err := c.streamClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка запроса к OpenAI: %w", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        defer resp.Body.Close()
+        respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return nil, &APIError{
+            StatusCode: resp.StatusCode,
+            Body:       string(respBody),
+            RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+        }
+    }
+    return resp, nil
+}
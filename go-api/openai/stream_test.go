@@ -0,0 +1,102 @@
+package openai
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// streamServer — API, который отдаёт первый фрагмент потока сразу, а
+// завершает поток только после finish; обычные запросы отвечают сразу
+func streamServer(t *testing.T, finish <-chan struct{}) *httptest.Server {
+    t.Helper()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+            w.Header().Set("Content-Type", "application/json")
+            fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ок"}}]}`)
+            return
+        }
+        w.Header().Set("Content-Type", "text/event-stream")
+        fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"При\"}}]}\n\n")
+        w.(http.Flusher).Flush()
+        select {
+        case <-finish:
+        case <-r.Context().Done():
+            return
+        }
+        fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"вет\"}}]}\n\ndata: [DONE]\n\n")
+    }))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+var hello = []Message{{Role: RoleUser, Content: "привет"}}
+
+// Открытый поток занимает слот MaxConcurrency до конца тела, а не только
+// до прихода заголовков
+func TestStreamHoldsConcurrencySlotUntilBodyClosed(t *testing.T) {
+    finish := make(chan struct{})
+    var once sync.Once
+    done := func() { once.Do(func() { close(finish) }) }
+    // Упавший раньше времени тест не должен оставлять сервер висеть
+    defer done()
+    srv := streamServer(t, finish)
+    c := NewClient("key", Options{BaseURL: srv.URL, MaxConcurrency: 1})
+
+    chunks, err := c.ChatCompletionStream(context.Background(), hello)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first := <-chunks; first.Delta != "При" {
+        t.Fatalf("первый фрагмент %+v", first)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+    if _, err := c.ChatCompletion(ctx, hello); !errors.Is(err, ErrBusy) {
+        t.Fatalf("запрос при открытом потоке: %v, ожидался ErrBusy", err)
+    }
+
+    done()
+    answer := strings.Builder{}
+    answer.WriteString("При")
+    for ch := range chunks {
+        answer.WriteString(ch.Delta)
+    }
+    if answer.String() != "Привет" {
+        t.Fatalf("поток собрал %q", answer.String())
+    }
+    if got, err := c.ChatCompletion(context.Background(), hello); err != nil || got != "ок" {
+        t.Fatalf("запрос после закрытия потока: %q, %v", got, err)
+    }
+}
+
+// Брошенный вызывающим поток освобождает слот по отмене контекста
+func TestCancelledStreamReleasesSlot(t *testing.T) {
+    finish := make(chan struct{})
+    defer close(finish)
+    srv := streamServer(t, finish)
+    c := NewClient("key", Options{BaseURL: srv.URL, MaxConcurrency: 1})
+
+    ctx, cancel := context.WithCancel(context.Background())
+    chunks, err := c.ChatCompletionStream(ctx, hello)
+    if err != nil {
+        t.Fatal(err)
+    }
+    <-chunks
+    cancel()
+    for range chunks {
+    }
+
+    wait, stop := context.WithTimeout(context.Background(), 2*time.Second)
+    defer stop()
+    if _, err := c.ChatCompletion(wait, hello); err != nil {
+        t.Fatalf("запрос после отмены потока: %v", err)
+    }
+}
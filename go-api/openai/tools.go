@@ -0,0 +1,164 @@
+package openai
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sync/atomic"
+
+    "ai_seller/logging"
+)
+
+// maxToolIterations — сколько раундов вызова инструментов разрешено модели
+// за один ответ, чтобы она не зациклилась
+const maxToolIterations = 3
+
+// ErrUnknownTool — модель раз за разом вызывает инструменты, которых нет;
+// ответ без них не получить
+var ErrUnknownTool = errors.New("модель вызывает несуществующий инструмент")
+
+// Tool — описание инструмента для поля tools запроса
+type Tool struct {
+    Type     string      `json:"type"`
+    Function FunctionDef `json:"function"`
+}
+
+// FunctionDef — функция, которую модель может вызвать; Parameters — JSON Schema аргументов
+type FunctionDef struct {
+    Name        string          `json:"name"`
+    Description string          `json:"description"`
+    Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall — запрос модели на вызов инструмента
+type ToolCall struct {
+    ID       string `json:"id"`
+    Type     string `json:"type"`
+    Function struct {
+        Name      string `json:"name"`
+        Arguments string `json:"arguments"`
+    } `json:"function"`
+}
+
+// ToolFunc — реализация инструмента: получает JSON-аргументы, возвращает результат для модели
+type ToolFunc func(ctx context.Context, args json.RawMessage) (string, error)
+
+// ToolRegistry — набор инструментов, доступных модели
+type ToolRegistry struct {
+    defs  []Tool
+    funcs map[string]ToolFunc
+}
+
+// NewToolRegistry — пустой реестр инструментов
+func NewToolRegistry() *ToolRegistry {
+    return &ToolRegistry{funcs: make(map[string]ToolFunc)}
+}
+
+// Register добавляет инструмент с JSON Schema его аргументов
+func (r *ToolRegistry) Register(name, description, parameters string, fn ToolFunc) {
+    r.defs = append(r.defs, Tool{
+        Type: "function",
+        Function: FunctionDef{
+            Name:        name,
+            Description: description,
+            Parameters:  json.RawMessage(parameters),
+        },
+    })
+    r.funcs[name] = fn
+}
+
+// call выполняет инструмент; ошибка превращается в текст для модели,
+// чтобы она могла сообщить о проблеме или попробовать иначе.
+// known=false — такого инструмента нет, результат — toolNotFound.
+func (r *ToolRegistry) call(ctx context.Context, tc ToolCall) (result string, known bool) {
+    fn, ok := r.funcs[tc.Function.Name]
+    if !ok {
+        return r.toolNotFound(tc.Function.Name), false
+    }
+
+    if n, ok := ctx.Value(toolCallsKey{}).(*atomic.Int32); ok {
+        n.Add(1)
+    }
+    result, err := fn(ctx, json.RawMessage(tc.Function.Arguments))
+    if err != nil {
+        return toolError(err.Error()), true
+    }
+    return result, true
+}
+
+// toolNotFound — результат вызова несуществующего инструмента: с кодом
+// ошибки и списком настоящих инструментов, чтобы модель могла исправиться
+func (r *ToolRegistry) toolNotFound(name string) string {
+    available := make([]string, len(r.defs))
+    for i, d := range r.defs {
+        available[i] = d.Function.Name
+    }
+    out, _ := json.Marshal(struct {
+        Error     string   `json:"error"`
+        Code      string   `json:"code"`
+        Available []string `json:"available"`
+    }{
+        Error:     fmt.Sprintf("инструмент %q не существует", name),
+        Code:      "tool_not_found",
+        Available: available,
+    })
+    return string(out)
+}
+
+type toolCallsKey struct{}
+
+// CountToolCalls — контекст, в котором ChatWithTools считает вызванные
+// инструменты. Нужен, чтобы отличить ответ с побочными эффектами
+// (корзина, фото) от ответа, который можно переиспользовать.
+func CountToolCalls(ctx context.Context) (context.Context, *atomic.Int32) {
+    n := new(atomic.Int32)
+    return context.WithValue(ctx, toolCallsKey{}, n), n
+}
+
+func toolError(msg string) string {
+    out, _ := json.Marshal(map[string]string{"error": msg})
+    return string(out)
+}
+
+// ChatWithTools ведёт диалог с моделью, выполняя запрошенные ею инструменты
+// и возвращая результаты, пока модель не даст текстовый ответ.
+// После maxToolIterations раундов модель просят ответить без инструментов.
+// Вызов несуществующего инструмента возвращается модели ошибкой
+// tool_not_found; если таких вызовов за ответ больше UnknownToolRetries,
+// диалог прекращается с ErrUnknownTool.
+func (c *Client) ChatWithTools(ctx context.Context, messages []Message, tools *ToolRegistry) (string, error) {
+    if tools == nil || len(tools.defs) == 0 {
+        return c.ChatCompletion(ctx, messages)
+    }
+
+    // Не портим слайс вызывающего, дописывая в него служебные сообщения
+    messages = append([]Message(nil), messages...)
+
+    unknown := 0
+    for i := 0; i < maxToolIterations; i++ {
+        msg, err := c.complete(ctx, messages, tools.defs)
+        if err != nil {
+            return "", err
+        }
+        if len(msg.ToolCalls) == 0 {
+            return msg.Content, nil
+        }
+
+        messages = append(messages, msg)
+        for _, tc := range msg.ToolCalls {
+            result, known := tools.call(ctx, tc)
+            if !known {
+                unknown++
+                // Имя пригодится для правки промпта: модель ждёт такой инструмент
+                logging.FromContext(ctx).Warn("модель вызвала несуществующий инструмент", "tool", tc.Function.Name, "attempt", unknown)
+                if unknown > c.unknownToolRetries {
+                    return "", fmt.Errorf("%w: %q", ErrUnknownTool, tc.Function.Name)
+                }
+            }
+            messages = append(messages, ToolResult(tc.ID, result))
+        }
+    }
+
+    return c.ChatCompletion(ctx, messages)
+}
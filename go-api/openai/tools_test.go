@@ -0,0 +1,122 @@
+package openai
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// scriptedModel — API, отвечающий на запросы по порядку ответами из
+// replies и запоминающий присланные диалоги
+type scriptedModel struct {
+    *httptest.Server
+    mu       sync.Mutex
+    replies  []string
+    requests [][]Message
+}
+
+func newScriptedModel(t *testing.T, replies ...string) *scriptedModel {
+    t.Helper()
+    m := &scriptedModel{replies: replies}
+    m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var req struct {
+            Messages []Message `json:"messages"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            t.Errorf("тело запроса: %v", err)
+        }
+        m.mu.Lock()
+        m.requests = append(m.requests, req.Messages)
+        n := len(m.requests)
+        m.mu.Unlock()
+        if n > len(m.replies) {
+            t.Errorf("лишний запрос к модели №%d", n)
+            http.Error(w, "no more replies", http.StatusBadRequest)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprintf(w, `{"choices":[{"message":%s}]}`, m.replies[n-1])
+    }))
+    t.Cleanup(m.Close)
+    return m
+}
+
+// callTool — ответ модели с вызовом инструмента name
+func callTool(name string) string {
+    return fmt.Sprintf(`{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":%q,"arguments":"{}"}}]}`, name)
+}
+
+// answer — текстовый ответ модели
+func answer(text string) string {
+    return fmt.Sprintf(`{"role":"assistant","content":%q}`, text)
+}
+
+func catalogTools() *ToolRegistry {
+    tools := NewToolRegistry()
+    tools.Register("search_products", "поиск товаров", `{"type":"object"}`, func(ctx context.Context, args json.RawMessage) (string, error) {
+        return `{"products":[]}`, nil
+    })
+    return tools
+}
+
+func TestChatWithToolsUnknownTool(t *testing.T) {
+    cases := []struct {
+        name     string
+        retries  int
+        replies  []string
+        want     string
+        wantErr  bool
+        requests int
+    }{
+        {"без прощения первый вызов прекращает ответ", 0,
+            []string{callTool("get_discount")}, "", true, 1},
+        {"прощённый вызов возвращается модели", 1,
+            []string{callTool("get_discount"), answer("Скидок нет")}, "Скидок нет", false, 2},
+        {"непрощённый повтор прекращает ответ", 1,
+            []string{callTool("get_discount"), callTool("get_discount")}, "", true, 2},
+        {"настоящий инструмент не в счёт", 0,
+            []string{callTool("search_products"), answer("Ничего не нашлось")}, "Ничего не нашлось", false, 2},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            model := newScriptedModel(t, tc.replies...)
+            c := NewClient("key", Options{BaseURL: model.URL, MaxConcurrency: 1, UnknownToolRetries: tc.retries})
+
+            got, err := c.ChatWithTools(context.Background(), hello, catalogTools())
+            if tc.wantErr != errors.Is(err, ErrUnknownTool) {
+                t.Fatalf("ошибка %v, ErrUnknownTool нужна: %v", err, tc.wantErr)
+            }
+            if got != tc.want {
+                t.Fatalf("ответ %q, нужно %q", got, tc.want)
+            }
+            if len(model.requests) != tc.requests {
+                t.Fatalf("запросов к модели %d, нужно %d", len(model.requests), tc.requests)
+            }
+        })
+    }
+}
+
+// Прощённый вызов несуществующего инструмента возвращается модели с
+// кодом tool_not_found и списком настоящих инструментов
+func TestUnknownToolResultListsAvailable(t *testing.T) {
+    model := newScriptedModel(t, callTool("get_discount"), answer("Скидок нет"))
+    c := NewClient("key", Options{BaseURL: model.URL, MaxConcurrency: 1, UnknownToolRetries: 1})
+    if _, err := c.ChatWithTools(context.Background(), hello, catalogTools()); err != nil {
+        t.Fatal(err)
+    }
+
+    second := model.requests[1]
+    result := second[len(second)-1]
+    if result.Role != RoleTool || result.ToolCallID != "call_1" {
+        t.Fatalf("последнее сообщение второго запроса %+v, нужен результат инструмента", result)
+    }
+    if !strings.Contains(result.Content, "tool_not_found") || !strings.Contains(result.Content, "search_products") {
+        t.Fatalf("результат %s без кода ошибки или списка инструментов", result.Content)
+    }
+}
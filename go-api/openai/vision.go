@@ -0,0 +1,57 @@
+package openai
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+)
+
+// ContentPart — часть составного содержимого сообщения (текст или картинка)
+type ContentPart struct {
+    Type     string    `json:"type"`
+    Text     string    `json:"text,omitempty"`
+    ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL — ссылка на изображение или data-URL с его содержимым
+type ImageURL struct {
+    URL string `json:"url"`
+}
+
+// TextPart — текстовая часть сообщения
+func TextPart(text string) ContentPart {
+    return ContentPart{Type: "text", Text: text}
+}
+
+// ImagePart — изображение, встроенное в сообщение как data-URL.
+// Передаём байты, а не ссылку Telegram: в ней токен бота.
+func ImagePart(data []byte, contentType string) ContentPart {
+    url := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+    return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}
+}
+
+// MarshalJSON отправляет Parts вместо Content, если сообщение составное
+func (m Message) MarshalJSON() ([]byte, error) {
+    type plain Message
+    if len(m.Parts) == 0 {
+        return json.Marshal(plain(m))
+    }
+    return json.Marshal(struct {
+        plain
+        Content []ContentPart `json:"content"`
+    }{plain: plain(m), Content: m.Parts})
+}
+
+// VisionCompletion — ChatCompletion на модели с поддержкой изображений
+func (c *Client) VisionCompletion(ctx context.Context, messages []Message) (string, error) {
+    var parsed chatResponse
+    err := c.post(ctx, "/chat/completions", c.newRequest(ctx, c.visionModel, messages, nil), &parsed)
+    if err != nil {
+        return "", err
+    }
+    c.recordUsage(ctx, parsed.Usage)
+    if len(parsed.Choices) == 0 {
+        return "", errEmptyChoices
+    }
+    return parsed.Choices[0].Message.Content, nil
+}
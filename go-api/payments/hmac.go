@@ -0,0 +1,50 @@
+package payments
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "ai_seller/money"
+)
+
+// hmacSignatureHeader — заголовок с hex-подписью тела
+const hmacSignatureHeader = "X-Signature"
+
+// hmacProvider — уведомление вида {"order_id": 42, "status": "paid",
+// "amount": 150000, "currency": "RUB"}, подписанное HMAC-SHA256 от тела;
+// amount — в минимальных единицах валюты
+type hmacProvider struct {
+    secret []byte
+}
+
+func (p *hmacProvider) Verify(header http.Header, body []byte) error {
+    got := strings.ToLower(strings.TrimSpace(header.Get(hmacSignatureHeader)))
+    if got == "" || !validMAC(got, sign(p.secret, body)) {
+        return ErrInvalidSignature
+    }
+    return nil
+}
+
+func (p *hmacProvider) Parse(body []byte) (Event, error) {
+    var n struct {
+        OrderID  int64  `json:"order_id"`
+        Status   string `json:"status"`
+        Amount   *int64 `json:"amount"`
+        Currency string `json:"currency"`
+    }
+    if err := json.Unmarshal(body, &n); err != nil {
+        return Event{}, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+    }
+    if n.OrderID <= 0 {
+        return Event{}, fmt.Errorf("%w: нет order_id", ErrInvalidPayload)
+    }
+    if n.Status != "paid" {
+        return Event{OrderID: n.OrderID}, nil
+    }
+    if n.Amount == nil || n.Currency == "" {
+        return Event{}, fmt.Errorf("%w: в уведомлении об оплате нет amount или currency", ErrInvalidPayload)
+    }
+    return Event{OrderID: n.OrderID, Paid: true, Amount: money.New(*n.Amount, strings.ToUpper(n.Currency))}, nil
+}
@@ -0,0 +1,73 @@
+// Package payments — проверка и разбор уведомлений платёжных провайдеров
+package payments
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/http"
+
+    "ai_seller/money"
+)
+
+// Провайдеры, которые можно выбрать в PAYMENT_PROVIDER
+const (
+    // ProviderStripe — заголовок Stripe-Signature: t=<unix>,v1=<hmac от "t.тело">
+    ProviderStripe = "stripe"
+    // ProviderHMAC — заголовок X-Signature: hex HMAC-SHA256 от тела; для
+    // собственного шлюза или провайдера без своего формата подписи
+    ProviderHMAC = "hmac"
+)
+
+var (
+    // ErrInvalidSignature — подпись отсутствует или не совпала
+    ErrInvalidSignature = errors.New("неверная подпись уведомления об оплате")
+    // ErrInvalidPayload — подпись верна, но уведомление не удалось разобрать
+    ErrInvalidPayload = errors.New("некорректное уведомление об оплате")
+)
+
+// Event — уведомление об оплате, сведённое к тому, что нужно магазину
+type Event struct {
+    OrderID int64
+    // Paid — оплата прошла; остальные события (возврат, отмена) пока не обрабатываются
+    Paid bool
+    // Amount — оплаченная сумма в минимальных единицах и валюта (ISO 4217,
+    // заглавными); заказ помечается оплаченным, только если она равна сумме заказа
+    Amount money.Money
+}
+
+// Provider проверяет подпись уведомления и разбирает его
+type Provider interface {
+    // Verify проверяет подпись по сырому телу; ошибка — ErrInvalidSignature
+    Verify(header http.Header, body []byte) error
+    // Parse разбирает уже проверенное тело; ошибка — ErrInvalidPayload
+    Parse(body []byte) (Event, error)
+}
+
+// New — провайдер по имени из конфигурации с общим секретом подписи
+func New(name, secret string) (Provider, error) {
+    if secret == "" {
+        return nil, errors.New("не задан секрет подписи платёжных уведомлений")
+    }
+    switch name {
+    case ProviderStripe:
+        return &stripe{secret: []byte(secret)}, nil
+    case ProviderHMAC:
+        return &hmacProvider{secret: []byte(secret)}, nil
+    }
+    return nil, fmt.Errorf("неизвестный платёжный провайдер %q", name)
+}
+
+// sign — HMAC-SHA256 сообщения в hex
+func sign(secret, msg []byte) string {
+    mac := hmac.New(sha256.New, secret)
+    mac.Write(msg)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validMAC сравнивает подписи за постоянное время
+func validMAC(got, want string) bool {
+    return hmac.Equal([]byte(got), []byte(want))
+}
@@ -0,0 +1,103 @@
+package payments
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "ai_seller/money"
+)
+
+func TestStripeParse(t *testing.T) {
+    p := &stripe{secret: []byte("whsec")}
+    for _, tc := range []struct {
+        name string
+        body string
+        want Event
+    }{
+        {
+            "сессия оплачена",
+            `{"type":"checkout.session.completed","data":{"object":{"metadata":{"order_id":"7"},"amount_total":150000,"currency":"rub","payment_status":"paid"}}}`,
+            Event{OrderID: 7, Paid: true, Amount: money.New(150000, "RUB")},
+        },
+        {
+            "сессия завершена без оплаты",
+            `{"type":"checkout.session.completed","data":{"object":{"metadata":{"order_id":"7"},"amount_total":150000,"currency":"rub","payment_status":"unpaid"}}}`,
+            Event{OrderID: 7},
+        },
+        {
+            "отложенная оплата прошла",
+            `{"type":"checkout.session.async_payment_succeeded","data":{"object":{"metadata":{"order_id":"7"},"amount_total":150000,"currency":"rub","payment_status":"paid"}}}`,
+            Event{OrderID: 7, Paid: true, Amount: money.New(150000, "RUB")},
+        },
+        {
+            "платёж получен",
+            `{"type":"payment_intent.succeeded","data":{"object":{"metadata":{"order_id":"8"},"amount":990,"amount_received":990,"currency":"usd","status":"succeeded"}}}`,
+            Event{OrderID: 8, Paid: true, Amount: money.New(990, "USD")},
+        },
+        {
+            "прочее событие",
+            `{"type":"charge.refunded","data":{"object":{}}}`,
+            Event{},
+        },
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := p.Parse([]byte(tc.body))
+            if err != nil {
+                t.Fatalf("Parse: %v", err)
+            }
+            if got != tc.want {
+                t.Fatalf("получено %+v, ожидалось %+v", got, tc.want)
+            }
+        })
+    }
+}
+
+func TestStripeParseRejectsPaymentWithoutCurrency(t *testing.T) {
+    p := &stripe{secret: []byte("whsec")}
+    body := `{"type":"payment_intent.succeeded","data":{"object":{"metadata":{"order_id":"8"},"amount_received":990,"status":"succeeded"}}}`
+    if _, err := p.Parse([]byte(body)); !errors.Is(err, ErrInvalidPayload) {
+        t.Fatalf("получено %v, ожидалось ErrInvalidPayload", err)
+    }
+}
+
+func TestStripeVerify(t *testing.T) {
+    p := &stripe{secret: []byte("whsec")}
+    body := []byte(`{"type":"payment_intent.succeeded"}`)
+    ts := strconv.FormatInt(time.Now().Unix(), 10)
+    header := http.Header{}
+    header.Set(stripeSignatureHeader, fmt.Sprintf("t=%s,v1=%s", ts, sign(p.secret, append([]byte(ts+"."), body...))))
+    if err := p.Verify(header, body); err != nil {
+        t.Fatalf("верная подпись отклонена: %v", err)
+    }
+    if err := p.Verify(header, append(body, ' ')); !errors.Is(err, ErrInvalidSignature) {
+        t.Fatalf("изменённое тело: получено %v", err)
+    }
+    old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+    header.Set(stripeSignatureHeader, fmt.Sprintf("t=%s,v1=%s", old, sign(p.secret, append([]byte(old+"."), body...))))
+    if err := p.Verify(header, body); !errors.Is(err, ErrInvalidSignature) {
+        t.Fatalf("устаревшая подпись: получено %v", err)
+    }
+}
+
+func TestHMACParse(t *testing.T) {
+    p := &hmacProvider{secret: []byte("secret")}
+    got, err := p.Parse([]byte(`{"order_id":3,"status":"paid","amount":5000,"currency":"rub"}`))
+    if err != nil {
+        t.Fatalf("Parse: %v", err)
+    }
+    if want := (Event{OrderID: 3, Paid: true, Amount: money.New(5000, "RUB")}); got != want {
+        t.Fatalf("получено %+v, ожидалось %+v", got, want)
+    }
+
+    got, err = p.Parse([]byte(`{"order_id":3,"status":"failed"}`))
+    if err != nil || got.Paid {
+        t.Fatalf("неуспешная оплата: %+v, %v", got, err)
+    }
+    if _, err := p.Parse([]byte(`{"order_id":3,"status":"paid"}`)); !errors.Is(err, ErrInvalidPayload) {
+        t.Fatalf("оплата без суммы: получено %v, ожидалось ErrInvalidPayload", err)
+    }
+}
@@ -0,0 +1,107 @@
+package payments
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/money"
+)
+
+const (
+    stripeSignatureHeader = "Stripe-Signature"
+    // stripeTolerance — насколько старой может быть подпись: защита от повтора
+    // перехваченного уведомления
+    stripeTolerance = 5 * time.Minute
+)
+
+// stripePaidEvents — события Stripe, которые могут означать успешную оплату.
+// Сессия Checkout завершается и без денег (оплата банковским переводом
+// приходит позже), поэтому платёж проверяется ещё и по статусу объекта.
+var stripePaidEvents = map[string]bool{
+    "checkout.session.completed":               true,
+    "checkout.session.async_payment_succeeded": true,
+    "payment_intent.succeeded":                 true,
+}
+
+// stripe — уведомления Stripe; id заказа передаётся в metadata.order_id
+// при создании платежа
+type stripe struct {
+    secret []byte
+}
+
+func (p *stripe) Verify(header http.Header, body []byte) error {
+    var timestamp string
+    var signatures []string
+    for _, part := range strings.Split(header.Get(stripeSignatureHeader), ",") {
+        key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+        switch key {
+        case "t":
+            timestamp = val
+        case "v1":
+            signatures = append(signatures, val)
+        }
+    }
+    ts, err := strconv.ParseInt(timestamp, 10, 64)
+    if err != nil || len(signatures) == 0 {
+        return ErrInvalidSignature
+    }
+
+    if age := time.Since(time.Unix(ts, 0)); age > stripeTolerance || age < -stripeTolerance {
+        return fmt.Errorf("%w: подпись устарела", ErrInvalidSignature)
+    }
+
+    want := sign(p.secret, append([]byte(timestamp+"."), body...))
+    for _, sig := range signatures {
+        if validMAC(sig, want) {
+            return nil
+        }
+    }
+    return ErrInvalidSignature
+}
+
+func (p *stripe) Parse(body []byte) (Event, error) {
+    var n struct {
+        Type string `json:"type"`
+        Data struct {
+            Object struct {
+                Metadata map[string]string `json:"metadata"`
+                // Сессия Checkout: сумма и статус оплаты
+                AmountTotal   int64  `json:"amount_total"`
+                PaymentStatus string `json:"payment_status"`
+                // PaymentIntent: полученная сумма и статус
+                AmountReceived int64  `json:"amount_received"`
+                Status         string `json:"status"`
+                Currency       string `json:"currency"`
+            } `json:"object"`
+        } `json:"data"`
+    }
+    if err := json.Unmarshal(body, &n); err != nil {
+        return Event{}, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+    }
+    // Остальные события, на которые подписан эндпоинт, подтверждаются без разбора
+    if !stripePaidEvents[n.Type] {
+        return Event{}, nil
+    }
+    raw := n.Data.Object.Metadata["order_id"]
+    orderID, err := strconv.ParseInt(raw, 10, 64)
+    if err != nil || orderID <= 0 {
+        return Event{}, fmt.Errorf("%w: некорректный metadata.order_id %q", ErrInvalidPayload, raw)
+    }
+    obj := n.Data.Object
+    amount := obj.AmountReceived
+    paid := obj.Status == "succeeded"
+    if strings.HasPrefix(n.Type, "checkout.session.") {
+        amount, paid = obj.AmountTotal, obj.PaymentStatus == "paid"
+    }
+    if !paid {
+        return Event{OrderID: orderID}, nil
+    }
+    if obj.Currency == "" {
+        return Event{}, fmt.Errorf("%w: нет currency", ErrInvalidPayload)
+    }
+    return Event{OrderID: orderID, Paid: true, Amount: money.New(amount, strings.ToUpper(obj.Currency))}, nil
+}
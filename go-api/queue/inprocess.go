@@ -0,0 +1,91 @@
+package queue
+
+import (
+    "context"
+    "hash/fnv"
+    "sync"
+    "sync/atomic"
+
+    "ai_seller/logging"
+)
+
+// InProcess — очередь в памяти процесса на буферизованных каналах, по
+// одному на шард. Сообщения с одним ключом попадают в один шард и
+// разбираются по порядку одним потребителем. Повторной доставки нет:
+// сообщение, обработка которого упала, теряется, а при закрытии потребители
+// дорабатывают всё, что успело попасть в очередь.
+type InProcess struct {
+    shards []chan []byte
+    // next — шард для очередного Consume
+    next atomic.Int64
+
+    // mu защищает закрытие каналов от параллельной публикации
+    mu     sync.RWMutex
+    closed bool
+}
+
+// NewInProcess — фабрика очереди на size сообщений, разделённой на shards
+// шардов; потребителей стоит запускать столько же, сколько шардов
+func NewInProcess(size, shards int) *InProcess {
+    shards = max(shards, 1)
+    perShard := max((size+shards-1)/shards, 1)
+    q := &InProcess{shards: make([]chan []byte, shards)}
+    for i := range q.shards {
+        q.shards[i] = make(chan []byte, perShard)
+    }
+    return q
+}
+
+// Publish ставит сообщение в шард ключа не блокируясь: при переполнении
+// шарда — ErrFull
+func (q *InProcess) Publish(ctx context.Context, key string, payload []byte) error {
+    q.mu.RLock()
+    defer q.mu.RUnlock()
+
+    if q.closed {
+        return ErrClosed
+    }
+    select {
+    case q.shards[q.shard(key)] <- payload:
+        return nil
+    default:
+        return ErrFull
+    }
+}
+
+// shard — номер шарда для ключа
+func (q *InProcess) shard(key string) int {
+    h := fnv.New32a()
+    h.Write([]byte(key))
+    return int(h.Sum32() % uint32(len(q.shards)))
+}
+
+// Consume разбирает сообщения своего шарда, пока очередь не закрыта и шард
+// не опустел. Шарды раздаются вызовам Consume по кругу.
+func (q *InProcess) Consume(ctx context.Context, consumer string, handle Handler) {
+    ch := q.shards[int((q.next.Add(1)-1)%int64(len(q.shards)))]
+    for payload := range ch {
+        if err := handle(ctx, payload); err != nil {
+            logging.Logger().Warn("сообщение очереди не обработано", "consumer", consumer, "err", err)
+        }
+    }
+}
+
+// Redelivers — false: обработанное с ошибкой сообщение не возвращается в очередь
+func (q *InProcess) Redelivers() bool {
+    return false
+}
+
+// Close перестаёт принимать сообщения; уже принятые будут разобраны
+func (q *InProcess) Close() error {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    if !q.closed {
+        q.closed = true
+        for _, ch := range q.shards {
+            close(ch)
+        }
+    }
+    return nil
+}
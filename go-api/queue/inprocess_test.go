@@ -0,0 +1,86 @@
+package queue
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "testing"
+)
+
+// Сообщения одного ключа разбирает один потребитель в порядке публикации,
+// даже когда потребителей несколько
+func TestInProcessKeepsKeyOrderOnOneConsumer(t *testing.T) {
+    const consumers, perKey = 4, 50
+    q := NewInProcess(1000, consumers)
+    keys := []string{"10", "20", "30", "40", "50", "60"}
+    for i := range perKey {
+        for _, key := range keys {
+            if err := q.Publish(context.Background(), key, []byte(fmt.Sprintf("%s:%d", key, i))); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    q.Close()
+
+    var mu sync.Mutex
+    seen := map[string][]string{}
+    owner := map[string]string{}
+    var wg sync.WaitGroup
+    for c := range consumers {
+        wg.Add(1)
+        consumer := fmt.Sprintf("c%d", c)
+        go func() {
+            defer wg.Done()
+            q.Consume(context.Background(), consumer, func(ctx context.Context, payload []byte) error {
+                var key string
+                var n int
+                fmt.Sscanf(string(payload), "%2s:%d", &key, &n)
+                mu.Lock()
+                defer mu.Unlock()
+                if prev, ok := owner[key]; ok && prev != consumer {
+                    t.Errorf("ключ %s разбирают %s и %s", key, prev, consumer)
+                }
+                owner[key] = consumer
+                seen[key] = append(seen[key], string(payload))
+                return nil
+            })
+        }()
+    }
+    wg.Wait()
+
+    for _, key := range keys {
+        if len(seen[key]) != perKey {
+            t.Fatalf("ключ %s: разобрано %d из %d", key, len(seen[key]), perKey)
+        }
+        for i, payload := range seen[key] {
+            if want := fmt.Sprintf("%s:%d", key, i); payload != want {
+                t.Fatalf("ключ %s: на месте %d %q, ожидалось %q", key, i, payload, want)
+            }
+        }
+    }
+}
+
+// Переполненный шард отказывает, не блокируя; после закрытия — ErrClosed
+func TestInProcessFullAndClosed(t *testing.T) {
+    q := NewInProcess(2, 2)
+    if err := q.Publish(context.Background(), "1", []byte("a")); err != nil {
+        t.Fatal(err)
+    }
+    if err := q.Publish(context.Background(), "1", []byte("b")); !errors.Is(err, ErrFull) {
+        t.Fatalf("второе сообщение в шард на одно: %v, ожидалось ErrFull", err)
+    }
+    q.Close()
+    if err := q.Publish(context.Background(), "1", []byte("c")); !errors.Is(err, ErrClosed) {
+        t.Fatalf("публикация после закрытия: %v, ожидалось ErrClosed", err)
+    }
+    // Принятое до закрытия дорабатывается
+    var got []string
+    q.Consume(context.Background(), "c", func(ctx context.Context, payload []byte) error {
+        got = append(got, string(payload))
+        return nil
+    })
+    if len(got) != 1 || got[0] != "a" {
+        t.Fatalf("после закрытия разобрано %q, ожидалось [a]", got)
+    }
+}
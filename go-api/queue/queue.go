@@ -0,0 +1,43 @@
+// Package queue — очередь сообщений между приёмом апдейтов и их обработкой.
+// В памяти процесса (inprocess) или в Redis Streams (redis), чтобы апдейты,
+// принятые одной репликой, мог разобрать любой экземпляр бота.
+package queue
+
+import (
+    "context"
+    "errors"
+)
+
+const (
+    // TransportInProcess — очередь в памяти процесса
+    TransportInProcess = "inprocess"
+    // TransportRedis — очередь в Redis Streams с группой потребителей
+    TransportRedis = "redis"
+)
+
+var (
+    // ErrFull — в очереди нет места, сообщение не принято
+    ErrFull = errors.New("очередь переполнена")
+    // ErrClosed — очередь закрыта и сообщений больше не принимает
+    ErrClosed = errors.New("очередь закрыта")
+)
+
+// Handler обрабатывает одно сообщение; nil — сообщение подтверждается
+type Handler func(ctx context.Context, payload []byte) error
+
+// Queue — очередь сообщений с подтверждением после обработки
+type Queue interface {
+    // Publish ставит сообщение в очередь не дожидаясь обработки. Сообщения
+    // с одним key (для апдейтов — чат) очередь в памяти выдаёт по порядку
+    // одному потребителю; поток Redis порядка между потребителями не
+    // гарантирует, там их разводит блокировка чата.
+    Publish(ctx context.Context, key string, payload []byte) error
+    // Consume разбирает сообщения, пока очередь не закрыта. Безопасен для
+    // вызова из нескольких горутин; consumer — имя потребителя в группе.
+    Consume(ctx context.Context, consumer string, handle Handler)
+    // Close перестаёт принимать сообщения; Consume возвращается, доработав текущее
+    Close() error
+    // Redelivers — сообщение, обработчик которого вернул ошибку, будет
+    // доставлено снова; иначе ошибка только записывается в лог
+    Redelivers() bool
+}
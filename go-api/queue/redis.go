@@ -0,0 +1,260 @@
+package queue
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "time"
+
+    "ai_seller/logging"
+
+    "github.com/redis/go-redis/v9"
+)
+
+const (
+    // payloadField — поле записи потока с телом сообщения
+    payloadField = "payload"
+    // readBlock — сколько ждать новых записей за один XREADGROUP; заодно
+    // ограничивает, как быстро потребитель замечает закрытие очереди
+    readBlock = 2 * time.Second
+    // retryPause — пауза после ошибки Redis, чтобы не крутить цикл вхолостую
+    retryPause = time.Second
+)
+
+// RedisOptions — параметры очереди в Redis Streams
+type RedisOptions struct {
+    // Stream — ключ потока
+    Stream string
+    // Group — группа потребителей; все реплики бота читают одной группой
+    Group string
+    // Retention — сколько хранятся записи потока: при публикации обрезаются
+    // записи старше (по времени в ID, а не по длине потока, чтобы всплеск
+    // апдейтов не срезал ещё не обработанные)
+    Retention time.Duration
+    // ClaimAfter — через сколько неподтверждённое сообщение забирает другой потребитель
+    ClaimAfter time.Duration
+    // MaxDeliveries — сколько раз сообщение выдаётся потребителям; после
+    // стольких неудач оно переносится в DeadLetter и больше не доставляется
+    MaxDeliveries int64
+    // DeadLetter — поток для сообщений, которые так и не удалось обработать
+    DeadLetter string
+}
+
+// RedisStream — очередь в Redis Streams с доставкой «хотя бы один раз»:
+// сообщение подтверждается (XACK) только после успешной обработки, а
+// неподтверждённые сообщения — упавшей реплики или с ошибкой обработки —
+// через ClaimAfter забирает (XAUTOCLAIM) любой живой потребитель группы.
+// Сообщение, выданное MaxDeliveries раз, переносится в поток DeadLetter.
+type RedisStream struct {
+    rdb  *redis.Client
+    opts RedisOptions
+
+    closed atomic.Bool
+}
+
+// NewRedisStream — фабрика очереди поверх потока opts.Stream
+func NewRedisStream(rdb *redis.Client, opts RedisOptions) *RedisStream {
+    return &RedisStream{rdb: rdb, opts: opts}
+}
+
+// Publish добавляет сообщение в поток; key не используется — сообщения
+// разбирают все потребители группы
+func (q *RedisStream) Publish(ctx context.Context, key string, payload []byte) error {
+    if q.closed.Load() {
+        return ErrClosed
+    }
+    // ID записи начинается с времени в миллисекундах — по нему и обрезаем
+    minID := strconv.FormatInt(time.Now().Add(-q.opts.Retention).UnixMilli(), 10) + "-0"
+    err := q.rdb.XAdd(ctx, &redis.XAddArgs{
+        Stream: q.opts.Stream,
+        MinID:  minID,
+        Approx: true,
+        Values: map[string]interface{}{payloadField: payload},
+    }).Err()
+    if err != nil {
+        return fmt.Errorf("ошибка публикации в поток Redis: %w", err)
+    }
+    return nil
+}
+
+// Consume читает поток от имени consumer, пока очередь не закрыта.
+// Сначала подбирает зависшие у других потребителей сообщения, затем новые.
+func (q *RedisStream) Consume(ctx context.Context, consumer string, handle Handler) {
+    log := logging.Logger().With("stream", q.opts.Stream, "consumer", consumer)
+    if err := q.createGroup(ctx); err != nil {
+        log.Error("не удалось создать группу потребителей", "err", err)
+    }
+
+    var lastClaim time.Time
+    for !q.closed.Load() {
+        var msgs []redis.XMessage
+        var err error
+        if time.Since(lastClaim) >= q.claimEvery() {
+            msgs, err = q.claim(ctx, log, consumer)
+            lastClaim = time.Now()
+        }
+        if err == nil && len(msgs) == 0 {
+            msgs, err = q.read(ctx, consumer)
+        }
+        if err != nil {
+            if isNoGroup(err) {
+                err = q.createGroup(ctx)
+            }
+            if err != nil {
+                log.Warn("ошибка чтения потока Redis", "err", err)
+                time.Sleep(retryPause)
+            }
+            continue
+        }
+
+        for _, msg := range msgs {
+            q.process(ctx, log, msg, handle)
+        }
+    }
+}
+
+// Redelivers — true: неподтверждённое сообщение заберёт потребитель группы
+func (q *RedisStream) Redelivers() bool {
+    return true
+}
+
+// Close перестаёт принимать и читать сообщения; неподтверждённые
+// остаются в потоке и достанутся другим потребителям группы
+func (q *RedisStream) Close() error {
+    q.closed.Store(true)
+    return nil
+}
+
+// process обрабатывает запись и подтверждает её при успехе
+func (q *RedisStream) process(ctx context.Context, log *slog.Logger, msg redis.XMessage, handle Handler) {
+    payload, ok := msg.Values[payloadField].(string)
+    if !ok {
+        // Битую запись повторная доставка не исправит — подтверждаем и пропускаем
+        log.Warn("запись потока без тела, пропущена", "id", msg.ID)
+    } else if err := handle(ctx, []byte(payload)); err != nil {
+        log.Warn("сообщение не обработано, будет доставлено повторно", "id", msg.ID, "err", err)
+        return
+    }
+    if err := q.rdb.XAck(ctx, q.opts.Stream, q.opts.Group, msg.ID).Err(); err != nil {
+        log.Warn("ошибка подтверждения сообщения", "id", msg.ID, "err", err)
+    }
+}
+
+// read ждёт новые записи, ещё не выданные ни одному потребителю группы
+func (q *RedisStream) read(ctx context.Context, consumer string) ([]redis.XMessage, error) {
+    streams, err := q.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+        Group:    q.opts.Group,
+        Consumer: consumer,
+        Streams:  []string{q.opts.Stream, ">"},
+        Count:    1,
+        // Не дольше интервала подбора зависших, чтобы не пропустить его
+        Block: min(readBlock, q.claimEvery()),
+    }).Result()
+    if errors.Is(err, redis.Nil) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    var msgs []redis.XMessage
+    for _, s := range streams {
+        msgs = append(msgs, s.Messages...)
+    }
+    return msgs, nil
+}
+
+// claimEvery — как часто подбирать зависшие сообщения
+func (q *RedisStream) claimEvery() time.Duration {
+    return max(q.opts.ClaimAfter/2, time.Millisecond)
+}
+
+// claim забирает сообщения, которые дольше ClaimAfter висят неподтверждёнными.
+// Сообщения, выданные больше MaxDeliveries раз, не возвращаются, а уходят
+// в DeadLetter.
+func (q *RedisStream) claim(ctx context.Context, log *slog.Logger, consumer string) ([]redis.XMessage, error) {
+    msgs, _, err := q.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+        Stream:   q.opts.Stream,
+        Group:    q.opts.Group,
+        Consumer: consumer,
+        MinIdle:  q.opts.ClaimAfter,
+        Start:    "0-0",
+        Count:    1,
+    }).Result()
+    if errors.Is(err, redis.Nil) {
+        return nil, nil
+    }
+    if err != nil || q.opts.MaxDeliveries <= 0 {
+        return msgs, err
+    }
+
+    var live []redis.XMessage
+    for _, msg := range msgs {
+        deliveries, err := q.deliveries(ctx, msg.ID)
+        if err != nil {
+            return nil, err
+        }
+        if deliveries <= q.opts.MaxDeliveries {
+            live = append(live, msg)
+            continue
+        }
+        if err := q.deadLetter(ctx, msg, deliveries); err != nil {
+            return nil, err
+        }
+        log.Error("сообщение не обработано за отведённые попытки, перенесено в поток недоставленных",
+            "id", msg.ID, "deliveries", deliveries-1, "dead_letter", q.opts.DeadLetter)
+    }
+    return live, nil
+}
+
+// deliveries — сколько раз сообщение id выдавалось потребителям, считая текущую выдачу
+func (q *RedisStream) deliveries(ctx context.Context, id string) (int64, error) {
+    pending, err := q.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+        Stream: q.opts.Stream,
+        Group:  q.opts.Group,
+        Start:  id,
+        End:    id,
+        Count:  1,
+    }).Result()
+    if err != nil {
+        return 0, fmt.Errorf("ошибка чтения числа доставок: %w", err)
+    }
+    if len(pending) == 0 {
+        return 0, nil
+    }
+    return pending[0].RetryCount, nil
+}
+
+// deadLetter переносит сообщение в поток DeadLetter: запись с телом, исходным
+// ID и числом доставок, затем подтверждение и удаление из основного потока
+func (q *RedisStream) deadLetter(ctx context.Context, msg redis.XMessage, deliveries int64) error {
+    values := map[string]interface{}{"id": msg.ID, "deliveries": deliveries - 1}
+    if payload, ok := msg.Values[payloadField]; ok {
+        values[payloadField] = payload
+    }
+    pipe := q.rdb.TxPipeline()
+    pipe.XAdd(ctx, &redis.XAddArgs{Stream: q.opts.DeadLetter, Values: values})
+    pipe.XAck(ctx, q.opts.Stream, q.opts.Group, msg.ID)
+    pipe.XDel(ctx, q.opts.Stream, msg.ID)
+    if _, err := pipe.Exec(ctx); err != nil {
+        return fmt.Errorf("ошибка переноса сообщения %s в поток недоставленных: %w", msg.ID, err)
+    }
+    return nil
+}
+
+// createGroup создаёт поток и группу потребителей, если их ещё нет
+func (q *RedisStream) createGroup(ctx context.Context) error {
+    err := q.rdb.XGroupCreateMkStream(ctx, q.opts.Stream, q.opts.Group, "0").Err()
+    if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+        return fmt.Errorf("ошибка создания группы %s: %w", q.opts.Group, err)
+    }
+    return nil
+}
+
+// isNoGroup — поток или группа пропали (например, после FLUSHALL)
+func isNoGroup(err error) bool {
+    return strings.HasPrefix(err.Error(), "NOGROUP")
+}
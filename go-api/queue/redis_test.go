@@ -0,0 +1,173 @@
+package queue
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "ai_seller/redistest"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// testStream — очередь на сервере в памяти; ClaimAfter короткий, чтобы
+// зависшие сообщения подбирались за доли секунды
+func testStream(t *testing.T) (*redistest.Server, *redis.Client, *RedisStream) {
+    t.Helper()
+    srv, rdb := redistest.NewClient(t)
+    q := NewRedisStream(rdb, RedisOptions{
+        Stream:        "updates",
+        Group:         "bot",
+        Retention:     time.Hour,
+        ClaimAfter:    100 * time.Millisecond,
+        MaxDeliveries: 3,
+        DeadLetter:    "updates:dead",
+    })
+    if err := q.createGroup(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    return srv, rdb, q
+}
+
+// consume запускает потребителя; возвращённая функция закрывает очередь и
+// ждёт, пока Consume вернётся
+func consume(t *testing.T, q *RedisStream, handle Handler) func() {
+    t.Helper()
+    done := make(chan struct{})
+    go func() {
+        q.Consume(context.Background(), "c1", handle)
+        close(done)
+    }()
+    return func() {
+        q.Close()
+        select {
+        case <-done:
+        case <-time.After(5 * time.Second):
+            t.Fatal("Consume не вернулся после Close")
+        }
+    }
+}
+
+// eventually ждёт условия, сдвигая часы сервера, чтобы неподтверждённые
+// сообщения считались зависшими
+func eventually(t *testing.T, srv *redistest.Server, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatal("условие не выполнилось за 5 секунд")
+        }
+        srv.FastForward(time.Second)
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestRedisStreamRedeliversFailedMessage(t *testing.T) {
+    srv, _, q := testStream(t)
+    var calls atomic.Int32
+    stop := consume(t, q, func(ctx context.Context, payload []byte) error {
+        if calls.Add(1) == 1 {
+            return errors.New("временная ошибка")
+        }
+        return nil
+    })
+    defer stop()
+
+    if err := q.Publish(context.Background(), "", []byte(`{"update_id":1}`)); err != nil {
+        t.Fatal(err)
+    }
+    eventually(t, srv, func() bool { return calls.Load() >= 2 && srv.Pending("updates", "bot") == 0 })
+    if got := calls.Load(); got != 2 {
+        t.Fatalf("обработок %d, ожидалось 2: сообщение после успеха доставлено снова", got)
+    }
+}
+
+func TestRedisStreamAcksSuccessOnce(t *testing.T) {
+    srv, _, q := testStream(t)
+    var calls atomic.Int32
+    stop := consume(t, q, func(ctx context.Context, payload []byte) error {
+        calls.Add(1)
+        return nil
+    })
+    if err := q.Publish(context.Background(), "", []byte(`{"update_id":1}`)); err != nil {
+        t.Fatal(err)
+    }
+    eventually(t, srv, func() bool { return calls.Load() == 1 })
+    // Ещё несколько циклов подбора зависших — подтверждённое не должно вернуться
+    for range 5 {
+        srv.FastForward(time.Second)
+        time.Sleep(60 * time.Millisecond)
+    }
+    stop()
+    if got := calls.Load(); got != 1 {
+        t.Fatalf("обработок %d, ожидалась одна", got)
+    }
+    if n := srv.Pending("updates", "bot"); n != 0 {
+        t.Fatalf("неподтверждённых %d", n)
+    }
+}
+
+func TestRedisStreamDeadLettersAfterMaxDeliveries(t *testing.T) {
+    srv, rdb, q := testStream(t)
+    var calls atomic.Int32
+    stop := consume(t, q, func(ctx context.Context, payload []byte) error {
+        calls.Add(1)
+        return errors.New("апдейт не обрабатывается")
+    })
+    defer stop()
+
+    if err := q.Publish(context.Background(), "", []byte(`{"update_id":7}`)); err != nil {
+        t.Fatal(err)
+    }
+    eventually(t, srv, func() bool { return srv.StreamLen("updates:dead") == 1 })
+
+    if got := calls.Load(); got != 3 {
+        t.Fatalf("обработок %d, ожидалось MaxDeliveries = 3", got)
+    }
+    if n := srv.Pending("updates", "bot"); n != 0 {
+        t.Fatalf("в основном потоке осталось неподтверждённых: %d", n)
+    }
+    if n := srv.StreamLen("updates"); n != 0 {
+        t.Fatalf("в основном потоке осталось записей: %d", n)
+    }
+    dead, err := rdb.XRange(context.Background(), "updates:dead", "-", "+").Result()
+    if err != nil || len(dead) != 1 {
+        t.Fatalf("поток недоставленных: %v, %v", dead, err)
+    }
+    if dead[0].Values[payloadField] != `{"update_id":7}` || dead[0].Values["deliveries"] != "3" {
+        t.Fatalf("запись недоставленного: %v", dead[0].Values)
+    }
+}
+
+func TestRedisStreamTrimsByAge(t *testing.T) {
+    srv, _, q := testStream(t)
+    ctx := context.Background()
+    // Всплеск публикаций не должен срезать необработанные записи
+    for range 200 {
+        if err := q.Publish(ctx, "", []byte(`{}`)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if n := srv.StreamLen("updates"); n != 200 {
+        t.Fatalf("записей %d, ожидалось 200", n)
+    }
+
+    q.opts.Retention = 50 * time.Millisecond
+    time.Sleep(100 * time.Millisecond)
+    if err := q.Publish(ctx, "", []byte(`{}`)); err != nil {
+        t.Fatal(err)
+    }
+    if n := srv.StreamLen("updates"); n != 1 {
+        t.Fatalf("записей %d, ожидалась одна: старше Retention должны обрезаться", n)
+    }
+}
+
+func TestRedisStreamPublishAfterClose(t *testing.T) {
+    _, _, q := testStream(t)
+    q.Close()
+    if err := q.Publish(context.Background(), "", []byte(`{}`)); !errors.Is(err, ErrClosed) {
+        t.Fatalf("получено %v, ожидалось ErrClosed", err)
+    }
+}
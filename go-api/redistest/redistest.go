@@ -0,0 +1,1034 @@
+// Package redistest — сервер Redis в памяти для тестов без настоящего Redis.
+// Говорит по протоколу RESP2 и понимает команды, которыми пользуются пакеты
+// cache и queue. Lua-скрипты не исполняет: их заменяют обработчики на Go,
+// см. Server.HandleScript.
+package redistest
+
+import (
+    "bufio"
+    "crypto/sha1"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "net"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// ScriptFunc — замена Lua-скрипта: call выполняет команду Redis внутри
+// скрипта, результат — ответ скрипта (int64, string, []any или nil)
+type ScriptFunc func(call func(args ...string) any, keys, args []string) any
+
+// Server — Redis в памяти. Время для сроков жизни ключей можно сдвигать
+// через FastForward.
+type Server struct {
+    ln net.Listener
+
+    mu      sync.Mutex
+    data    map[string]*entry
+    scripts map[string]ScriptFunc
+    offset  time.Duration
+    // failing — ответ об ошибке на любую команду, пока задан (SetError)
+    failing string
+}
+
+// entry — значение ключа; заполнено поле своего типа
+type entry struct {
+    str      string
+    hash     map[string]string
+    list     []string
+    zset     map[string]float64
+    stream   *stream
+    expireAt time.Time
+}
+
+// kind — тип значения для WRONGTYPE и TYPE
+func (e *entry) kind() string {
+    switch {
+    case e.hash != nil:
+        return "hash"
+    case e.list != nil:
+        return "list"
+    case e.zset != nil:
+        return "zset"
+    case e.stream != nil:
+        return "stream"
+    default:
+        return "string"
+    }
+}
+
+// errWrongType — ответ на команду не для того типа значения
+var errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// NewServer запускает сервер на случайном порту; останавливается в конце теста
+func NewServer(t testing.TB) *Server {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("redistest: %v", err)
+    }
+    s := &Server{ln: ln, data: make(map[string]*entry), scripts: make(map[string]ScriptFunc)}
+    go s.serve()
+    t.Cleanup(func() { _ = ln.Close() })
+    return s
+}
+
+// NewClient — клиент go-redis к новому серверу; закрывается в конце теста
+func NewClient(t testing.TB) (*Server, *redis.Client) {
+    t.Helper()
+    s := NewServer(t)
+    rdb := redis.NewClient(&redis.Options{Addr: s.Addr(), Protocol: 2, DisableIdentity: true})
+    t.Cleanup(func() { _ = rdb.Close() })
+    return s, rdb
+}
+
+// Addr — адрес сервера host:port
+func (s *Server) Addr() string {
+    return s.ln.Addr().String()
+}
+
+// FastForward сдвигает часы сервера: ключи со сроком жизни стареют на d
+func (s *Server) FastForward(d time.Duration) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.offset += d
+}
+
+// SetError заставляет сервер отвечать ошибкой msg на любую команду;
+// пустая строка возвращает обычную работу
+func (s *Server) SetError(msg string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.failing = msg
+}
+
+// HandleScript подменяет Lua-скрипт с SHA1 hash (redis.Script.Hash) функцией fn
+func (s *Server) HandleScript(hash string, fn ScriptFunc) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.scripts[hash] = fn
+}
+
+// DelIfEquals — замена скрипта «удалить KEYS[1], если его значение равно
+// ARGV[1]», которым снимают свою блокировку: 1 — ключ удалён, 0 — ключ чужой
+// или его нет
+func DelIfEquals(call func(args ...string) any, keys, args []string) any {
+    if v, ok := call("GET", keys[0]).([]byte); !ok || string(v) != args[0] {
+        return int64(0)
+    }
+    return call("DEL", keys[0])
+}
+
+// Keys — все живые ключи, для проверок в тестах
+func (s *Server) Keys() []string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var keys []string
+    for k := range s.data {
+        if s.lookup(k) != nil {
+            keys = append(keys, k)
+        }
+    }
+    return keys
+}
+
+// TTL — оставшийся срок жизни ключа; 0 — срока нет или ключа нет
+func (s *Server) TTL(key string) time.Duration {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    e := s.lookup(key)
+    if e == nil || e.expireAt.IsZero() {
+        return 0
+    }
+    return e.expireAt.Sub(s.now())
+}
+
+func (s *Server) now() time.Time {
+    return time.Now().Add(s.offset)
+}
+
+// lookup — значение ключа с учётом срока жизни; просроченный ключ удаляется
+func (s *Server) lookup(key string) *entry {
+    e, ok := s.data[key]
+    if !ok {
+        return nil
+    }
+    if !e.expireAt.IsZero() && !s.now().Before(e.expireAt) {
+        delete(s.data, key)
+        return nil
+    }
+    return e
+}
+
+func (s *Server) serve() {
+    for {
+        conn, err := s.ln.Accept()
+        if err != nil {
+            return
+        }
+        go s.handle(conn)
+    }
+}
+
+// handle обслуживает соединение: команды по одной, MULTI копит до EXEC
+func (s *Server) handle(conn net.Conn) {
+    defer conn.Close()
+    r := bufio.NewReader(conn)
+    w := bufio.NewWriter(conn)
+
+    var (
+        inMulti bool
+        queued  [][]string
+    )
+    for {
+        args, err := readCommand(r)
+        if err != nil {
+            return
+        }
+        if len(args) == 0 {
+            continue
+        }
+        switch name := strings.ToUpper(args[0]); {
+        case name == "MULTI":
+            inMulti, queued = true, nil
+            writeReply(w, "OK")
+        case name == "EXEC" && inMulti:
+            inMulti = false
+            s.mu.Lock()
+            results := make([]any, 0, len(queued))
+            for _, cmd := range queued {
+                results = append(results, s.exec(cmd))
+            }
+            s.mu.Unlock()
+            writeReply(w, results)
+        case name == "DISCARD" && inMulti:
+            inMulti, queued = false, nil
+            writeReply(w, "OK")
+        case inMulti:
+            queued = append(queued, args)
+            writeReply(w, "QUEUED")
+        default:
+            writeReply(w, s.execBlocking(args))
+        }
+        // Клиент шлёт пайплайн пачкой — отвечаем, когда пачка разобрана
+        if r.Buffered() == 0 {
+            if err := w.Flush(); err != nil {
+                return
+            }
+        }
+    }
+}
+
+// execBlocking выполняет команду вне MULTI; XREADGROUP с BLOCK без записей
+// опрашивает поток, пока не появятся записи или не выйдет срок ожидания
+func (s *Server) execBlocking(args []string) any {
+    s.mu.Lock()
+    res := s.exec(args)
+    s.mu.Unlock()
+    wait, ok := blockTimeout(args)
+    if !ok {
+        return res
+    }
+    deadline := time.Now().Add(wait)
+    for _, empty := res.(nilArray); empty && time.Now().Before(deadline); _, empty = res.(nilArray) {
+        time.Sleep(5 * time.Millisecond)
+        s.mu.Lock()
+        res = s.exec(args)
+        s.mu.Unlock()
+    }
+    return res
+}
+
+// blockTimeout — срок BLOCK у XREADGROUP; BLOCK 0 ждёт минуту вместо вечности
+func blockTimeout(args []string) (time.Duration, bool) {
+    if !strings.EqualFold(args[0], "XREADGROUP") {
+        return 0, false
+    }
+    for i := 1; i+1 < len(args) && !strings.EqualFold(args[i], "STREAMS"); i++ {
+        if strings.EqualFold(args[i], "BLOCK") {
+            ms, err := strconv.Atoi(args[i+1])
+            if err != nil {
+                return 0, false
+            }
+            if ms == 0 {
+                return time.Minute, true
+            }
+            return time.Duration(ms) * time.Millisecond, true
+        }
+    }
+    return 0, false
+}
+
+// exec выполняет команду; вызывается под s.mu
+func (s *Server) exec(args []string) (res any) {
+    defer func() {
+        if p := recover(); p != nil {
+            res = fmt.Errorf("ERR %v", p)
+        }
+    }()
+    if s.failing != "" {
+        return errors.New(s.failing)
+    }
+    name := strings.ToUpper(args[0])
+    cmd, ok := commands[name]
+    if !ok {
+        return fmt.Errorf("ERR unknown command '%s'", args[0])
+    }
+    if len(args)-1 < cmd.minArgs {
+        return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+    }
+    return cmd.fn(s, args[1:])
+}
+
+// command — обработчик команды и минимальное число аргументов
+type command struct {
+    minArgs int
+    fn      func(s *Server, args []string) any
+}
+
+var commands map[string]command
+
+func init() {
+    commands = map[string]command{
+        "HELLO":  {0, func(*Server, []string) any { return errors.New("ERR unknown command 'HELLO'") }},
+        "CLIENT": {0, func(*Server, []string) any { return "OK" }},
+        "SELECT": {1, func(*Server, []string) any { return "OK" }},
+        "PING":   {0, cmdPing},
+
+        "FLUSHALL": {0, func(s *Server, _ []string) any { s.data = make(map[string]*entry); return "OK" }},
+        "DEL":      {1, cmdDel},
+        "EXISTS":   {1, cmdExists},
+        "EXPIRE":   {2, cmdExpire(time.Second)},
+        "PEXPIRE":  {2, cmdExpire(time.Millisecond)},
+        "TTL":      {1, cmdTTL(time.Second)},
+        "PTTL":     {1, cmdTTL(time.Millisecond)},
+        "TYPE":     {1, cmdType},
+
+        "GET":    {1, cmdGet},
+        "SET":    {2, cmdSet},
+        "SETNX":  {2, func(s *Server, a []string) any { return boolInt(s.lookup(a[0]) == nil && s.setString(a[0], a[1])) }},
+        "INCR":   {1, func(s *Server, a []string) any { return s.incr(a[0], 1) }},
+        "INCRBY": {2, cmdIncrBy},
+        "DECR":   {1, func(s *Server, a []string) any { return s.incr(a[0], -1) }},
+
+        "HSET":    {3, cmdHSet},
+        "HGET":    {2, cmdHGet},
+        "HGETALL": {1, cmdHGetAll},
+        "HDEL":    {2, cmdHDel},
+        "HINCRBY": {3, cmdHIncrBy},
+        "HLEN":    {1, cmdHLen},
+
+        "RPUSH":  {2, cmdPush(false)},
+        "LPUSH":  {2, cmdPush(true)},
+        "LRANGE": {3, cmdLRange},
+        "LTRIM":  {3, cmdLTrim},
+        "LLEN":   {1, cmdLLen},
+
+        "ZADD":             {3, cmdZAdd},
+        "ZREM":             {2, cmdZRem},
+        "ZSCORE":           {2, cmdZScore},
+        "ZCARD":            {1, cmdZCard},
+        "ZRANGEBYSCORE":    {3, cmdZRangeByScore},
+        "ZREMRANGEBYSCORE": {3, cmdZRemRangeByScore},
+
+        "EVALSHA": {2, cmdEvalSha},
+        "EVAL":    {2, cmdEval},
+    }
+    for name, c := range streamCommands {
+        commands[name] = c
+    }
+}
+
+func cmdPing(_ *Server, args []string) any {
+    if len(args) > 0 {
+        return []byte(args[0])
+    }
+    return "PONG"
+}
+
+func cmdDel(s *Server, args []string) any {
+    var n int64
+    for _, k := range args {
+        if s.lookup(k) != nil {
+            delete(s.data, k)
+            n++
+        }
+    }
+    return n
+}
+
+func cmdExists(s *Server, args []string) any {
+    var n int64
+    for _, k := range args {
+        if s.lookup(k) != nil {
+            n++
+        }
+    }
+    return n
+}
+
+func cmdExpire(unit time.Duration) func(*Server, []string) any {
+    return func(s *Server, args []string) any {
+        e := s.lookup(args[0])
+        if e == nil {
+            return int64(0)
+        }
+        n, err := strconv.ParseInt(args[1], 10, 64)
+        if err != nil {
+            return errors.New("ERR value is not an integer or out of range")
+        }
+        e.expireAt = s.now().Add(time.Duration(n) * unit)
+        return int64(1)
+    }
+}
+
+func cmdTTL(unit time.Duration) func(*Server, []string) any {
+    return func(s *Server, args []string) any {
+        e := s.lookup(args[0])
+        switch {
+        case e == nil:
+            return int64(-2)
+        case e.expireAt.IsZero():
+            return int64(-1)
+        }
+        return int64(e.expireAt.Sub(s.now()) / unit)
+    }
+}
+
+func cmdType(s *Server, args []string) any {
+    e := s.lookup(args[0])
+    if e == nil {
+        return "none"
+    }
+    return e.kind()
+}
+
+func cmdGet(s *Server, args []string) any {
+    e := s.lookup(args[0])
+    if e == nil {
+        return nil
+    }
+    if e.kind() != "string" {
+        return errWrongType
+    }
+    return []byte(e.str)
+}
+
+// cmdSet — SET key value [NX|XX] [EX s|PX ms|KEEPTTL]
+func cmdSet(s *Server, args []string) any {
+    key, val := args[0], args[1]
+    var (
+        nx, xx, keepTTL bool
+        ttl             time.Duration
+    )
+    for i := 2; i < len(args); i++ {
+        switch strings.ToUpper(args[i]) {
+        case "NX":
+            nx = true
+        case "XX":
+            xx = true
+        case "KEEPTTL":
+            keepTTL = true
+        case "EX", "PX":
+            if i+1 >= len(args) {
+                return errors.New("ERR syntax error")
+            }
+            n, err := strconv.ParseInt(args[i+1], 10, 64)
+            if err != nil || n <= 0 {
+                return errors.New("ERR invalid expire time in 'set' command")
+            }
+            unit := time.Second
+            if strings.EqualFold(args[i], "PX") {
+                unit = time.Millisecond
+            }
+            ttl = time.Duration(n) * unit
+            i++
+        default:
+            return errors.New("ERR syntax error")
+        }
+    }
+    old := s.lookup(key)
+    if (nx && old != nil) || (xx && old == nil) {
+        return nil
+    }
+    e := &entry{str: val}
+    if keepTTL && old != nil {
+        e.expireAt = old.expireAt
+    }
+    if ttl > 0 {
+        e.expireAt = s.now().Add(ttl)
+    }
+    s.data[key] = e
+    return "OK"
+}
+
+// setString записывает строку без срока жизни; true для SETNX
+func (s *Server) setString(key, val string) bool {
+    s.data[key] = &entry{str: val}
+    return true
+}
+
+func cmdIncrBy(s *Server, args []string) any {
+    n, err := strconv.ParseInt(args[1], 10, 64)
+    if err != nil {
+        return errors.New("ERR value is not an integer or out of range")
+    }
+    return s.incr(args[0], n)
+}
+
+func (s *Server) incr(key string, by int64) any {
+    e := s.lookup(key)
+    if e == nil {
+        e = &entry{str: "0"}
+        s.data[key] = e
+    }
+    if e.kind() != "string" {
+        return errWrongType
+    }
+    n, err := strconv.ParseInt(e.str, 10, 64)
+    if err != nil {
+        return errors.New("ERR value is not an integer or out of range")
+    }
+    n += by
+    e.str = strconv.FormatInt(n, 10)
+    return n
+}
+
+// hashFor — хэш ключа; create — создать, если ключа нет
+func (s *Server) hashFor(key string, create bool) (map[string]string, error) {
+    e := s.lookup(key)
+    if e == nil {
+        if !create {
+            return nil, nil
+        }
+        e = &entry{hash: make(map[string]string)}
+        s.data[key] = e
+    }
+    if e.hash == nil {
+        return nil, errWrongType
+    }
+    return e.hash, nil
+}
+
+// dropEmpty удаляет ключ, если его коллекция опустела, как настоящий Redis
+func (s *Server) dropEmpty(key string) {
+    e := s.lookup(key)
+    if e == nil {
+        return
+    }
+    if (e.hash != nil && len(e.hash) == 0) || (e.list != nil && len(e.list) == 0) || (e.zset != nil && len(e.zset) == 0) {
+        delete(s.data, key)
+    }
+}
+
+func cmdHSet(s *Server, args []string) any {
+    if len(args)%2 != 1 {
+        return errors.New("ERR wrong number of arguments for 'hset' command")
+    }
+    h, err := s.hashFor(args[0], true)
+    if err != nil {
+        return err
+    }
+    var added int64
+    for i := 1; i < len(args); i += 2 {
+        if _, ok := h[args[i]]; !ok {
+            added++
+        }
+        h[args[i]] = args[i+1]
+    }
+    return added
+}
+
+func cmdHGet(s *Server, args []string) any {
+    h, err := s.hashFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    v, ok := h[args[1]]
+    if !ok {
+        return nil
+    }
+    return []byte(v)
+}
+
+func cmdHGetAll(s *Server, args []string) any {
+    h, err := s.hashFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    out := make([]any, 0, 2*len(h))
+    for k, v := range h {
+        out = append(out, []byte(k), []byte(v))
+    }
+    return out
+}
+
+func cmdHDel(s *Server, args []string) any {
+    h, err := s.hashFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    var n int64
+    for _, f := range args[1:] {
+        if _, ok := h[f]; ok {
+            delete(h, f)
+            n++
+        }
+    }
+    s.dropEmpty(args[0])
+    return n
+}
+
+func cmdHIncrBy(s *Server, args []string) any {
+    by, err := strconv.ParseInt(args[2], 10, 64)
+    if err != nil {
+        return errors.New("ERR value is not an integer or out of range")
+    }
+    h, err := s.hashFor(args[0], true)
+    if err != nil {
+        return err
+    }
+    n, _ := strconv.ParseInt(h[args[1]], 10, 64)
+    n += by
+    h[args[1]] = strconv.FormatInt(n, 10)
+    return n
+}
+
+func cmdHLen(s *Server, args []string) any {
+    h, err := s.hashFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    return int64(len(h))
+}
+
+// listFor — список ключа; create — создать, если ключа нет
+func (s *Server) listFor(key string, create bool) (*entry, error) {
+    e := s.lookup(key)
+    if e == nil {
+        if !create {
+            return nil, nil
+        }
+        e = &entry{list: []string{}}
+        s.data[key] = e
+    }
+    if e.list == nil {
+        return nil, errWrongType
+    }
+    return e, nil
+}
+
+func cmdPush(left bool) func(*Server, []string) any {
+    return func(s *Server, args []string) any {
+        e, err := s.listFor(args[0], true)
+        if err != nil {
+            return err
+        }
+        for _, v := range args[1:] {
+            if left {
+                e.list = append([]string{v}, e.list...)
+            } else {
+                e.list = append(e.list, v)
+            }
+        }
+        return int64(len(e.list))
+    }
+}
+
+// listRange переводит индексы LRANGE/LTRIM (отрицательные — с конца) в срез [from, to)
+func listRange(n int, startArg, stopArg string) (int, int, error) {
+    start, err1 := strconv.Atoi(startArg)
+    stop, err2 := strconv.Atoi(stopArg)
+    if err1 != nil || err2 != nil {
+        return 0, 0, errors.New("ERR value is not an integer or out of range")
+    }
+    if start < 0 {
+        start += n
+    }
+    if stop < 0 {
+        stop += n
+    }
+    start = max(start, 0)
+    stop = min(stop, n-1)
+    if start > stop {
+        return 0, 0, nil
+    }
+    return start, stop + 1, nil
+}
+
+func cmdLRange(s *Server, args []string) any {
+    e, err := s.listFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    out := []any{}
+    if e == nil {
+        return out
+    }
+    from, to, err := listRange(len(e.list), args[1], args[2])
+    if err != nil {
+        return err
+    }
+    for _, v := range e.list[from:to] {
+        out = append(out, []byte(v))
+    }
+    return out
+}
+
+func cmdLTrim(s *Server, args []string) any {
+    e, err := s.listFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    if e == nil {
+        return "OK"
+    }
+    from, to, err := listRange(len(e.list), args[1], args[2])
+    if err != nil {
+        return err
+    }
+    e.list = append([]string{}, e.list[from:to]...)
+    s.dropEmpty(args[0])
+    return "OK"
+}
+
+func cmdLLen(s *Server, args []string) any {
+    e, err := s.listFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    if e == nil {
+        return int64(0)
+    }
+    return int64(len(e.list))
+}
+
+// zsetFor — сортированное множество ключа; create — создать, если ключа нет
+func (s *Server) zsetFor(key string, create bool) (map[string]float64, error) {
+    e := s.lookup(key)
+    if e == nil {
+        if !create {
+            return nil, nil
+        }
+        e = &entry{zset: make(map[string]float64)}
+        s.data[key] = e
+    }
+    if e.zset == nil {
+        return nil, errWrongType
+    }
+    return e.zset, nil
+}
+
+func cmdZAdd(s *Server, args []string) any {
+    if len(args)%2 != 1 {
+        return errors.New("ERR syntax error")
+    }
+    z, err := s.zsetFor(args[0], true)
+    if err != nil {
+        return err
+    }
+    var added int64
+    for i := 1; i < len(args); i += 2 {
+        score, err := strconv.ParseFloat(args[i], 64)
+        if err != nil {
+            return errors.New("ERR value is not a valid float")
+        }
+        if _, ok := z[args[i+1]]; !ok {
+            added++
+        }
+        z[args[i+1]] = score
+    }
+    return added
+}
+
+func cmdZRem(s *Server, args []string) any {
+    z, err := s.zsetFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    var n int64
+    for _, m := range args[1:] {
+        if _, ok := z[m]; ok {
+            delete(z, m)
+            n++
+        }
+    }
+    s.dropEmpty(args[0])
+    return n
+}
+
+func cmdZScore(s *Server, args []string) any {
+    z, err := s.zsetFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    score, ok := z[args[1]]
+    if !ok {
+        return nil
+    }
+    return []byte(strconv.FormatFloat(score, 'g', -1, 64))
+}
+
+func cmdZCard(s *Server, args []string) any {
+    z, err := s.zsetFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    return int64(len(z))
+}
+
+// scoreBound — граница ZRANGEBYSCORE: число, (число — строго, -inf, +inf
+type scoreBound struct {
+    val       float64
+    exclusive bool
+}
+
+func parseScoreBound(raw string) (scoreBound, error) {
+    var b scoreBound
+    if strings.HasPrefix(raw, "(") {
+        b.exclusive, raw = true, raw[1:]
+    }
+    switch strings.ToLower(raw) {
+    case "-inf":
+        b.val = math.Inf(-1)
+    case "+inf", "inf":
+        b.val = math.Inf(1)
+    default:
+        v, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            return b, errors.New("ERR min or max is not a float")
+        }
+        b.val = v
+    }
+    return b, nil
+}
+
+func (b scoreBound) below(score float64) bool {
+    if b.exclusive {
+        return b.val < score
+    }
+    return b.val <= score
+}
+
+func (b scoreBound) above(score float64) bool {
+    if b.exclusive {
+        return score < b.val
+    }
+    return score <= b.val
+}
+
+// zrangeMembers — члены множества в [min, max] по возрастанию оценки
+func zrangeMembers(z map[string]float64, min, max scoreBound) []string {
+    var members []string
+    for m, score := range z {
+        if min.below(score) && max.above(score) {
+            members = append(members, m)
+        }
+    }
+    sortByScore(members, z)
+    return members
+}
+
+func sortByScore(members []string, z map[string]float64) {
+    for i := 1; i < len(members); i++ {
+        for j := i; j > 0; j-- {
+            a, b := members[j-1], members[j]
+            if z[a] < z[b] || (z[a] == z[b] && a < b) {
+                break
+            }
+            members[j-1], members[j] = b, a
+        }
+    }
+}
+
+// cmdZRangeByScore — ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+func cmdZRangeByScore(s *Server, args []string) any {
+    z, err := s.zsetFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    min, err := parseScoreBound(args[1])
+    if err != nil {
+        return err
+    }
+    max, err := parseScoreBound(args[2])
+    if err != nil {
+        return err
+    }
+    var (
+        withScores    bool
+        offset, count = 0, -1
+    )
+    for i := 3; i < len(args); i++ {
+        switch strings.ToUpper(args[i]) {
+        case "WITHSCORES":
+            withScores = true
+        case "LIMIT":
+            if i+2 >= len(args) {
+                return errors.New("ERR syntax error")
+            }
+            offset, _ = strconv.Atoi(args[i+1])
+            count, _ = strconv.Atoi(args[i+2])
+            i += 2
+        default:
+            return errors.New("ERR syntax error")
+        }
+    }
+    members := zrangeMembers(z, min, max)
+    if offset > len(members) {
+        offset = len(members)
+    }
+    members = members[offset:]
+    if count >= 0 && count < len(members) {
+        members = members[:count]
+    }
+    out := []any{}
+    for _, m := range members {
+        out = append(out, []byte(m))
+        if withScores {
+            out = append(out, []byte(strconv.FormatFloat(z[m], 'g', -1, 64)))
+        }
+    }
+    return out
+}
+
+func cmdZRemRangeByScore(s *Server, args []string) any {
+    z, err := s.zsetFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    min, err := parseScoreBound(args[1])
+    if err != nil {
+        return err
+    }
+    max, err := parseScoreBound(args[2])
+    if err != nil {
+        return err
+    }
+    members := zrangeMembers(z, min, max)
+    for _, m := range members {
+        delete(z, m)
+    }
+    s.dropEmpty(args[0])
+    return int64(len(members))
+}
+
+// cmdEvalSha — EVALSHA sha numkeys key... arg...; незнакомый скрипт — NOSCRIPT,
+// и go-redis повторит его через EVAL
+func cmdEvalSha(s *Server, args []string) any {
+    fn, ok := s.scripts[strings.ToLower(args[0])]
+    if !ok {
+        return errors.New("NOSCRIPT No matching script. Please use EVAL.")
+    }
+    return s.runScript(fn, args[1:])
+}
+
+func cmdEval(s *Server, args []string) any {
+    sum := sha1.Sum([]byte(args[0]))
+    fn, ok := s.scripts[hex.EncodeToString(sum[:])]
+    if !ok {
+        return errors.New("ERR redistest: Lua не поддерживается, подмените скрипт через HandleScript")
+    }
+    return s.runScript(fn, args[1:])
+}
+
+func (s *Server) runScript(fn ScriptFunc, args []string) any {
+    numKeys, err := strconv.Atoi(args[0])
+    if err != nil || numKeys < 0 || numKeys > len(args)-1 {
+        return errors.New("ERR Number of keys can't be greater than number of args")
+    }
+    keys, rest := args[1:1+numKeys], args[1+numKeys:]
+    call := func(cmd ...string) any { return s.exec(cmd) }
+    return fn(call, keys, rest)
+}
+
+func boolInt(b bool) int64 {
+    if b {
+        return 1
+    }
+    return 0
+}
+
+// readCommand читает команду клиента: массив bulk-строк или инлайн-строку
+func readCommand(r *bufio.Reader) ([]string, error) {
+    line, err := readLine(r)
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasPrefix(line, "*") {
+        return strings.Fields(line), nil
+    }
+    n, err := strconv.Atoi(line[1:])
+    if err != nil {
+        return nil, fmt.Errorf("redistest: длина массива %q", line)
+    }
+    args := make([]string, 0, n)
+    for i := 0; i < n; i++ {
+        head, err := readLine(r)
+        if err != nil {
+            return nil, err
+        }
+        if !strings.HasPrefix(head, "$") {
+            return nil, fmt.Errorf("redistest: ожидалась bulk-строка, получено %q", head)
+        }
+        size, err := strconv.Atoi(head[1:])
+        if err != nil {
+            return nil, err
+        }
+        buf := make([]byte, size+2)
+        if _, err := io.ReadFull(r, buf); err != nil {
+            return nil, err
+        }
+        args = append(args, string(buf[:size]))
+    }
+    return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+    line, err := r.ReadString('\n')
+    if err != nil {
+        return "", err
+    }
+    return strings.TrimRight(line, "\r\n"), nil
+}
+
+// writeReply пишет ответ в RESP2: string — простая строка, []byte — bulk,
+// int64 — число, error — ошибка, []any — массив, nil — пустой bulk
+func writeReply(w *bufio.Writer, v any) {
+    switch v := v.(type) {
+    case nil:
+        w.WriteString("$-1\r\n")
+    case nilArray:
+        w.WriteString("*-1\r\n")
+    case string:
+        w.WriteString("+" + v + "\r\n")
+    case []byte:
+        w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n")
+        w.Write(v)
+        w.WriteString("\r\n")
+    case int64:
+        w.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+    case int:
+        w.WriteString(":" + strconv.Itoa(v) + "\r\n")
+    case error:
+        w.WriteString("-" + strings.ReplaceAll(v.Error(), "\r\n", " ") + "\r\n")
+    case []any:
+        w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+        for _, item := range v {
+            writeReply(w, item)
+        }
+    default:
+        w.WriteString("-ERR redistest: неизвестный тип ответа " + fmt.Sprintf("%T", v) + "\r\n")
+    }
+}
+
+// nilArray — пустой ответ-массив (*-1), например XREADGROUP без записей
+type nilArray struct{}
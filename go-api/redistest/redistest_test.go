@@ -0,0 +1,158 @@
+package redistest
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+func TestStringsAndExpiry(t *testing.T) {
+    s, rdb := NewClient(t)
+    ctx := context.Background()
+
+    ok, err := rdb.SetNX(ctx, "lock", "a", time.Minute).Result()
+    if err != nil || !ok {
+        t.Fatalf("SetNX: %v, %v", ok, err)
+    }
+    if ok, _ := rdb.SetNX(ctx, "lock", "b", time.Minute).Result(); ok {
+        t.Fatal("повторный SetNX занял занятый ключ")
+    }
+    s.FastForward(2 * time.Minute)
+    if err := rdb.Get(ctx, "lock").Err(); err != redis.Nil {
+        t.Fatalf("ключ не истёк: %v", err)
+    }
+}
+
+func TestPipelineAndErrors(t *testing.T) {
+    s, rdb := NewClient(t)
+    ctx := context.Background()
+
+    _, err := rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+        p.HIncrBy(ctx, "h", "n", 2)
+        p.RPush(ctx, "l", "a", "b", "c")
+        p.LTrim(ctx, "l", -2, -1)
+        p.Expire(ctx, "h", time.Hour)
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("TxPipelined: %v", err)
+    }
+    if got, _ := rdb.LRange(ctx, "l", 0, -1).Result(); len(got) != 2 || got[0] != "b" {
+        t.Fatalf("LTRIM: получено %v", got)
+    }
+    if ttl := s.TTL("h"); ttl <= 0 || ttl > time.Hour {
+        t.Fatalf("TTL: получено %v", ttl)
+    }
+
+    s.SetError("ERR down")
+    if err := rdb.Get(ctx, "h").Err(); err == nil || err.Error() != "ERR down" {
+        t.Fatalf("ожидалась ошибка сервера, получено %v", err)
+    }
+}
+
+func TestScripts(t *testing.T) {
+    s, rdb := NewClient(t)
+    ctx := context.Background()
+    script := redis.NewScript(`return redis.call("INCR", KEYS[1])`)
+    s.HandleScript(script.Hash(), func(call func(args ...string) any, keys, _ []string) any {
+        return call("INCR", keys[0])
+    })
+    for want := int64(1); want <= 2; want++ {
+        got, err := script.Run(ctx, rdb, []string{"n"}).Int64()
+        if err != nil || got != want {
+            t.Fatalf("скрипт: получено %d (%v), ожидалось %d", got, err, want)
+        }
+    }
+}
+
+func TestStreamGroup(t *testing.T) {
+    s, rdb := NewClient(t)
+    ctx := context.Background()
+
+    if err := rdb.XGroupCreateMkStream(ctx, "q", "g", "0").Err(); err != nil {
+        t.Fatalf("XGROUP: %v", err)
+    }
+    if err := rdb.XGroupCreateMkStream(ctx, "q", "g", "0").Err(); err == nil {
+        t.Fatal("ожидалась BUSYGROUP")
+    }
+    for _, v := range []string{"a", "b"} {
+        if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: "q", Values: map[string]any{"v": v}}).Err(); err != nil {
+            t.Fatalf("XADD: %v", err)
+        }
+    }
+
+    read := func(consumer string) []redis.XMessage {
+        res, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+            Group: "g", Consumer: consumer, Streams: []string{"q", ">"}, Count: 1, Block: 50 * time.Millisecond,
+        }).Result()
+        if err == redis.Nil {
+            return nil
+        }
+        if err != nil {
+            t.Fatalf("XREADGROUP: %v", err)
+        }
+        return res[0].Messages
+    }
+    first := read("c1")
+    if len(first) != 1 || first[0].Values["v"] != "a" {
+        t.Fatalf("первое чтение: %v", first)
+    }
+    read("c1")
+    if got := read("c1"); got != nil {
+        t.Fatalf("пустой поток вернул %v", got)
+    }
+    if n := rdb.XAck(ctx, "q", "g", first[0].ID).Val(); n != 1 {
+        t.Fatalf("XACK: получено %d, ожидалось 1", n)
+    }
+    if n := s.Pending("q", "g"); n != 1 {
+        t.Fatalf("PEL: получено %d, ожидалось 1", n)
+    }
+
+    s.FastForward(time.Minute)
+    claimed, _, err := rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+        Stream: "q", Group: "g", Consumer: "c2", MinIdle: 30 * time.Second, Start: "0-0", Count: 1,
+    }).Result()
+    if err != nil || len(claimed) != 1 || claimed[0].Values["v"] != "b" {
+        t.Fatalf("XAUTOCLAIM: %v, %v", claimed, err)
+    }
+    pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: "q", Group: "g", Start: "-", End: "+", Count: 10}).Result()
+    if err != nil || len(pending) != 1 || pending[0].RetryCount != 2 || pending[0].Consumer != "c2" {
+        t.Fatalf("XPENDING: %+v, %v", pending, err)
+    }
+
+    // Обрезанная запись уходит из PEL при следующем XAUTOCLAIM
+    rdb.XTrimMaxLen(ctx, "q", 0)
+    s.FastForward(time.Minute)
+    _, _, err = rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+        Stream: "q", Group: "g", Consumer: "c2", MinIdle: time.Second, Start: "0-0", Count: 1,
+    }).Result()
+    if err != nil {
+        t.Fatalf("XAUTOCLAIM после обрезки: %v", err)
+    }
+    if n := s.Pending("q", "g"); n != 0 {
+        t.Fatalf("PEL после обрезки: получено %d, ожидалось 0", n)
+    }
+}
+
+func TestDelIfEquals(t *testing.T) {
+    s, rdb := NewClient(t)
+    ctx := context.Background()
+    script := redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+    s.HandleScript(script.Hash(), DelIfEquals)
+    rdb.Set(ctx, "lock", "mine", 0)
+    for _, tc := range []struct {
+        token string
+        want  int64
+    }{
+        {"чужой", 0},
+        {"mine", 1},
+        {"mine", 0},
+    } {
+        got, err := script.Run(ctx, rdb, []string{"lock"}, tc.token).Int64()
+        if err != nil || got != tc.want {
+            t.Fatalf("токен %q: получено %d (%v), ожидалось %d", tc.token, got, err, tc.want)
+        }
+    }
+}
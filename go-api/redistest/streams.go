@@ -0,0 +1,643 @@
+package redistest
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// streamID — идентификатор записи потока ms-seq
+type streamID struct {
+    ms, seq uint64
+}
+
+func (id streamID) String() string {
+    return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+func (id streamID) less(other streamID) bool {
+    return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// parseStreamID разбирает ms-seq или ms; "-" и "+" — края потока
+func parseStreamID(raw string) (streamID, error) {
+    switch raw {
+    case "-":
+        return streamID{}, nil
+    case "+":
+        return streamID{^uint64(0), ^uint64(0)}, nil
+    }
+    msPart, seqPart, hasSeq := strings.Cut(raw, "-")
+    ms, err := strconv.ParseUint(msPart, 10, 64)
+    if err != nil {
+        return streamID{}, errors.New("ERR Invalid stream ID specified as stream command argument")
+    }
+    var seq uint64
+    if hasSeq {
+        if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+            return streamID{}, errors.New("ERR Invalid stream ID specified as stream command argument")
+        }
+    }
+    return streamID{ms, seq}, nil
+}
+
+// streamEntry — запись потока: поля и значения по порядку
+type streamEntry struct {
+    id     streamID
+    fields []string
+}
+
+// pendingEntry — выданная, но не подтверждённая запись группы (PEL)
+type pendingEntry struct {
+    consumer   string
+    delivered  time.Time
+    deliveries int64
+}
+
+// group — группа потребителей
+type group struct {
+    lastID  streamID
+    pending map[streamID]*pendingEntry
+}
+
+// stream — поток с группами потребителей
+type stream struct {
+    entries []streamEntry
+    lastID  streamID
+    groups  map[string]*group
+}
+
+func (st *stream) find(id streamID) (streamEntry, bool) {
+    i := sort.Search(len(st.entries), func(i int) bool { return !st.entries[i].id.less(id) })
+    if i < len(st.entries) && st.entries[i].id == id {
+        return st.entries[i], true
+    }
+    return streamEntry{}, false
+}
+
+func (e streamEntry) reply() []any {
+    fields := make([]any, 0, len(e.fields))
+    for _, f := range e.fields {
+        fields = append(fields, []byte(f))
+    }
+    return []any{[]byte(e.id.String()), fields}
+}
+
+// StreamLen — число записей потока, для проверок в тестах
+func (s *Server) StreamLen(key string) int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    st, _ := s.streamFor(key, false)
+    if st == nil {
+        return 0
+    }
+    return len(st.entries)
+}
+
+// Pending — число неподтверждённых записей группы, для проверок в тестах
+func (s *Server) Pending(key, groupName string) int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    st, _ := s.streamFor(key, false)
+    if st == nil || st.groups[groupName] == nil {
+        return 0
+    }
+    return len(st.groups[groupName].pending)
+}
+
+// streamFor — поток ключа; create — создать, если ключа нет
+func (s *Server) streamFor(key string, create bool) (*stream, error) {
+    e := s.lookup(key)
+    if e == nil {
+        if !create {
+            return nil, nil
+        }
+        e = &entry{stream: &stream{groups: make(map[string]*group)}}
+        s.data[key] = e
+    }
+    if e.stream == nil {
+        return nil, errWrongType
+    }
+    return e.stream, nil
+}
+
+var streamCommands = map[string]command{
+    "XADD":       {3, cmdXAdd},
+    "XLEN":       {1, cmdXLen},
+    "XRANGE":     {3, cmdXRange},
+    "XDEL":       {2, cmdXDel},
+    "XTRIM":      {3, cmdXTrim},
+    "XGROUP":     {2, cmdXGroup},
+    "XREADGROUP": {6, cmdXReadGroup},
+    "XACK":       {3, cmdXAck},
+    "XAUTOCLAIM": {5, cmdXAutoClaim},
+    "XPENDING":   {2, cmdXPending},
+}
+
+// trimSpec — MAXLEN или MINID с порогом; ~ обрезает так же точно
+type trimSpec struct {
+    maxLen int64
+    minID  *streamID
+}
+
+// parseTrim разбирает MAXLEN|MINID [=|~] threshold [LIMIT n] с позиции i;
+// возвращает позицию после спецификации
+func parseTrim(args []string, i int) (trimSpec, int, error) {
+    var spec trimSpec
+    kind := strings.ToUpper(args[i])
+    i++
+    if i < len(args) && (args[i] == "~" || args[i] == "=") {
+        i++
+    }
+    if i >= len(args) {
+        return spec, i, errors.New("ERR syntax error")
+    }
+    switch kind {
+    case "MAXLEN":
+        n, err := strconv.ParseInt(args[i], 10, 64)
+        if err != nil || n < 0 {
+            return spec, i, errors.New("ERR The MAXLEN argument must be >= 0.")
+        }
+        spec.maxLen = n
+    case "MINID":
+        id, err := parseStreamID(args[i])
+        if err != nil {
+            return spec, i, err
+        }
+        spec.minID = &id
+        spec.maxLen = -1
+    }
+    i++
+    if i+1 < len(args) && strings.EqualFold(args[i], "LIMIT") {
+        i += 2
+    }
+    return spec, i, nil
+}
+
+// trim обрезает поток; записи из PEL групп остаются в PEL, как в Redis
+func (st *stream) trim(spec trimSpec) int64 {
+    var cut int
+    switch {
+    case spec.minID != nil:
+        for cut < len(st.entries) && st.entries[cut].id.less(*spec.minID) {
+            cut++
+        }
+    case int64(len(st.entries)) > spec.maxLen:
+        cut = len(st.entries) - int(spec.maxLen)
+    }
+    st.entries = st.entries[cut:]
+    return int64(cut)
+}
+
+// cmdXAdd — XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] n] *|id field value...
+func cmdXAdd(s *Server, args []string) any {
+    key := args[0]
+    i := 1
+    noMk := false
+    var spec *trimSpec
+    for i < len(args) {
+        switch strings.ToUpper(args[i]) {
+        case "NOMKSTREAM":
+            noMk = true
+            i++
+            continue
+        case "MAXLEN", "MINID":
+            t, next, err := parseTrim(args, i)
+            if err != nil {
+                return err
+            }
+            spec, i = &t, next
+            continue
+        }
+        break
+    }
+    if i >= len(args) || (len(args)-i-1)%2 != 0 || len(args)-i-1 == 0 {
+        return errors.New("ERR wrong number of arguments for 'xadd' command")
+    }
+    if noMk && s.lookup(key) == nil {
+        return nil
+    }
+    st, err := s.streamFor(key, true)
+    if err != nil {
+        return err
+    }
+
+    var id streamID
+    if args[i] == "*" {
+        ms := uint64(s.now().UnixMilli())
+        id = streamID{ms: ms}
+        if !st.lastID.less(id) {
+            id = streamID{st.lastID.ms, st.lastID.seq + 1}
+        }
+    } else {
+        id, err = parseStreamID(args[i])
+        if err != nil {
+            return err
+        }
+        if !st.lastID.less(id) {
+            return errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+        }
+    }
+    st.lastID = id
+    st.entries = append(st.entries, streamEntry{id: id, fields: append([]string{}, args[i+1:]...)})
+    if spec != nil {
+        st.trim(*spec)
+    }
+    return []byte(id.String())
+}
+
+func cmdXLen(s *Server, args []string) any {
+    st, err := s.streamFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    if st == nil {
+        return int64(0)
+    }
+    return int64(len(st.entries))
+}
+
+// cmdXRange — XRANGE key start end [COUNT n]
+func cmdXRange(s *Server, args []string) any {
+    st, err := s.streamFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    out := []any{}
+    if st == nil {
+        return out
+    }
+    start, err := parseStreamID(args[1])
+    if err != nil {
+        return err
+    }
+    end, err := parseStreamID(args[2])
+    if err != nil {
+        return err
+    }
+    count := -1
+    if len(args) >= 5 && strings.EqualFold(args[3], "COUNT") {
+        count, _ = strconv.Atoi(args[4])
+    }
+    for _, e := range st.entries {
+        if e.id.less(start) || end.less(e.id) {
+            continue
+        }
+        if count >= 0 && len(out) == count {
+            break
+        }
+        out = append(out, e.reply())
+    }
+    return out
+}
+
+func cmdXDel(s *Server, args []string) any {
+    st, err := s.streamFor(args[0], false)
+    if err != nil || st == nil {
+        return int64(0)
+    }
+    var n int64
+    for _, raw := range args[1:] {
+        id, err := parseStreamID(raw)
+        if err != nil {
+            return err
+        }
+        for i, e := range st.entries {
+            if e.id == id {
+                st.entries = append(st.entries[:i], st.entries[i+1:]...)
+                n++
+                break
+            }
+        }
+    }
+    return n
+}
+
+func cmdXTrim(s *Server, args []string) any {
+    st, err := s.streamFor(args[0], false)
+    if err != nil {
+        return err
+    }
+    if st == nil {
+        return int64(0)
+    }
+    spec, _, err := parseTrim(args, 1)
+    if err != nil {
+        return err
+    }
+    return st.trim(spec)
+}
+
+// cmdXGroup — XGROUP CREATE key group id [MKSTREAM] | XGROUP DESTROY key group
+func cmdXGroup(s *Server, args []string) any {
+    switch strings.ToUpper(args[0]) {
+    case "CREATE":
+        if len(args) < 4 {
+            return errors.New("ERR wrong number of arguments for 'xgroup|create' command")
+        }
+        mk := len(args) > 4 && strings.EqualFold(args[4], "MKSTREAM")
+        if s.lookup(args[1]) == nil && !mk {
+            return errors.New("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+        }
+        st, err := s.streamFor(args[1], true)
+        if err != nil {
+            return err
+        }
+        if _, ok := st.groups[args[2]]; ok {
+            return errors.New("BUSYGROUP Consumer Group name already exists")
+        }
+        g := &group{pending: make(map[streamID]*pendingEntry)}
+        if args[3] == "$" {
+            g.lastID = st.lastID
+        } else {
+            id, err := parseStreamID(args[3])
+            if err != nil {
+                return err
+            }
+            g.lastID = id
+        }
+        st.groups[args[2]] = g
+        return "OK"
+    case "DESTROY":
+        st, err := s.streamFor(args[1], false)
+        if err != nil || st == nil || len(args) < 3 {
+            return int64(0)
+        }
+        if _, ok := st.groups[args[2]]; !ok {
+            return int64(0)
+        }
+        delete(st.groups, args[2])
+        return int64(1)
+    }
+    return fmt.Errorf("ERR unknown subcommand '%s'", args[0])
+}
+
+// groupFor — группа потока или ошибка NOGROUP
+func (s *Server) groupFor(key, name, cmd string) (*stream, *group, error) {
+    st, err := s.streamFor(key, false)
+    if err != nil {
+        return nil, nil, err
+    }
+    if st == nil || st.groups[name] == nil {
+        return nil, nil, fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s' in %s with GROUP option", key, name, cmd)
+    }
+    return st, st.groups[name], nil
+}
+
+// readGroupArgs — разобранные аргументы XREADGROUP
+type readGroupArgs struct {
+    group, consumer string
+    count           int
+    block           time.Duration
+    blocking        bool
+    keys, ids       []string
+}
+
+func parseReadGroup(args []string) (readGroupArgs, error) {
+    var a readGroupArgs
+    if !strings.EqualFold(args[0], "GROUP") {
+        return a, errors.New("ERR syntax error")
+    }
+    a.group, a.consumer = args[1], args[2]
+    a.count = -1
+    i := 3
+    for i < len(args) && !strings.EqualFold(args[i], "STREAMS") {
+        switch strings.ToUpper(args[i]) {
+        case "COUNT":
+            a.count, _ = strconv.Atoi(args[i+1])
+            i += 2
+        case "BLOCK":
+            ms, _ := strconv.Atoi(args[i+1])
+            a.block, a.blocking = time.Duration(ms)*time.Millisecond, true
+            i += 2
+        case "NOACK":
+            i++
+        default:
+            return a, errors.New("ERR syntax error")
+        }
+    }
+    rest := args[i+1:]
+    if i >= len(args) || len(rest) == 0 || len(rest)%2 != 0 {
+        return a, errors.New("ERR Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.")
+    }
+    a.keys, a.ids = rest[:len(rest)/2], rest[len(rest)/2:]
+    return a, nil
+}
+
+// cmdXReadGroup — XREADGROUP GROUP g c [COUNT n] [BLOCK ms] STREAMS key... id...
+// Без новых записей отвечает пустым массивом; ожидание BLOCK — в handle.
+func cmdXReadGroup(s *Server, args []string) any {
+    a, err := parseReadGroup(args)
+    if err != nil {
+        return err
+    }
+    var out []any
+    for k, key := range a.keys {
+        st, g, err := s.groupFor(key, a.group, "XREADGROUP")
+        if err != nil {
+            return err
+        }
+        var msgs []any
+        if a.ids[k] == ">" {
+            for _, e := range st.entries {
+                if !g.lastID.less(e.id) {
+                    continue
+                }
+                if a.count > 0 && len(msgs) == a.count {
+                    break
+                }
+                g.lastID = e.id
+                g.pending[e.id] = &pendingEntry{consumer: a.consumer, delivered: s.now(), deliveries: 1}
+                msgs = append(msgs, e.reply())
+            }
+            if len(msgs) == 0 {
+                continue
+            }
+        } else {
+            // Повторное чтение своих неподтверждённых записей
+            from, err := parseStreamID(a.ids[k])
+            if err != nil {
+                return err
+            }
+            for _, id := range sortedPending(g) {
+                p := g.pending[id]
+                if p.consumer != a.consumer || id.less(from) || id == from && a.ids[k] != "0" {
+                    continue
+                }
+                if a.count > 0 && len(msgs) == a.count {
+                    break
+                }
+                if e, ok := st.find(id); ok {
+                    msgs = append(msgs, e.reply())
+                } else {
+                    msgs = append(msgs, []any{[]byte(id.String()), nilArray{}})
+                }
+            }
+            if msgs == nil {
+                msgs = []any{}
+            }
+        }
+        out = append(out, []any{[]byte(key), msgs})
+    }
+    if len(out) == 0 {
+        return nilArray{}
+    }
+    return out
+}
+
+func sortedPending(g *group) []streamID {
+    ids := make([]streamID, 0, len(g.pending))
+    for id := range g.pending {
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+    return ids
+}
+
+func cmdXAck(s *Server, args []string) any {
+    _, g, err := s.groupFor(args[0], args[1], "XACK")
+    if err != nil {
+        return int64(0)
+    }
+    var n int64
+    for _, raw := range args[2:] {
+        id, err := parseStreamID(raw)
+        if err != nil {
+            return err
+        }
+        if _, ok := g.pending[id]; ok {
+            delete(g.pending, id)
+            n++
+        }
+    }
+    return n
+}
+
+// cmdXAutoClaim — XAUTOCLAIM key group consumer min-idle start [COUNT n] [JUSTID].
+// Записи, удалённые из потока, снимаются с PEL и возвращаются третьим элементом.
+func cmdXAutoClaim(s *Server, args []string) any {
+    st, g, err := s.groupFor(args[0], args[1], "XAUTOCLAIM")
+    if err != nil {
+        return err
+    }
+    consumer := args[2]
+    minIdleMs, err := strconv.ParseInt(args[3], 10, 64)
+    if err != nil {
+        return errors.New("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+    }
+    start, err := parseStreamID(args[4])
+    if err != nil {
+        return err
+    }
+    count, justID := 100, false
+    for i := 5; i < len(args); i++ {
+        switch strings.ToUpper(args[i]) {
+        case "COUNT":
+            count, _ = strconv.Atoi(args[i+1])
+            i++
+        case "JUSTID":
+            justID = true
+        }
+    }
+
+    now := s.now()
+    minIdle := time.Duration(minIdleMs) * time.Millisecond
+    claimed, deleted := []any{}, []any{}
+    next := streamID{}
+    for _, id := range sortedPending(g) {
+        if id.less(start) {
+            continue
+        }
+        if len(claimed) == count {
+            next = id
+            break
+        }
+        p := g.pending[id]
+        if now.Sub(p.delivered) < minIdle {
+            continue
+        }
+        e, ok := st.find(id)
+        if !ok {
+            delete(g.pending, id)
+            deleted = append(deleted, []byte(id.String()))
+            continue
+        }
+        p.consumer, p.delivered = consumer, now
+        if !justID {
+            p.deliveries++
+            claimed = append(claimed, e.reply())
+        } else {
+            claimed = append(claimed, []byte(id.String()))
+        }
+    }
+    return []any{[]byte(next.String()), claimed, deleted}
+}
+
+// cmdXPending — XPENDING key group (сводка) или
+// XPENDING key group [IDLE ms] start end count [consumer] (подробно)
+func cmdXPending(s *Server, args []string) any {
+    _, g, err := s.groupFor(args[0], args[1], "XPENDING")
+    if err != nil {
+        return err
+    }
+    ids := sortedPending(g)
+    if len(args) == 2 {
+        if len(ids) == 0 {
+            return []any{int64(0), nil, nil, nilArray{}}
+        }
+        perConsumer := map[string]int64{}
+        for _, id := range ids {
+            perConsumer[g.pending[id].consumer]++
+        }
+        names := make([]string, 0, len(perConsumer))
+        for name := range perConsumer {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        consumers := make([]any, 0, len(names))
+        for _, name := range names {
+            consumers = append(consumers, []any{[]byte(name), []byte(strconv.FormatInt(perConsumer[name], 10))})
+        }
+        return []any{int64(len(ids)), []byte(ids[0].String()), []byte(ids[len(ids)-1].String()), consumers}
+    }
+
+    rest := args[2:]
+    var minIdle time.Duration
+    if strings.EqualFold(rest[0], "IDLE") {
+        ms, _ := strconv.ParseInt(rest[1], 10, 64)
+        minIdle = time.Duration(ms) * time.Millisecond
+        rest = rest[2:]
+    }
+    if len(rest) < 3 {
+        return errors.New("ERR syntax error")
+    }
+    start, err := parseStreamID(rest[0])
+    if err != nil {
+        return err
+    }
+    end, err := parseStreamID(rest[1])
+    if err != nil {
+        return err
+    }
+    count, _ := strconv.Atoi(rest[2])
+    consumer := ""
+    if len(rest) > 3 {
+        consumer = rest[3]
+    }
+    now := s.now()
+    out := []any{}
+    for _, id := range ids {
+        p := g.pending[id]
+        if id.less(start) || end.less(id) || (consumer != "" && p.consumer != consumer) || now.Sub(p.delivered) < minIdle {
+            continue
+        }
+        if len(out) == count {
+            break
+        }
+        out = append(out, []any{[]byte(id.String()), []byte(p.consumer), now.Sub(p.delivered).Milliseconds(), p.deliveries})
+    }
+    return out
+}
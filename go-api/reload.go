@@ -0,0 +1,65 @@
+package main
+
+import (
+    "context"
+    "os"
+    "os/signal"
+    "syscall"
+
+    "ai_seller/config"
+    "ai_seller/dialog"
+    "ai_seller/faq"
+    "ai_seller/filter"
+    "ai_seller/handlers"
+    "ai_seller/logging"
+)
+
+// watchReload перечитывает системный промпт, FAQ и фильтр ответов по SIGHUP, пока не отменён ctx.
+// Настройки, требующие переподключения (DSN, токены), не перечитываются.
+func watchReload(ctx context.Context, cfg *config.Config, dlg *dialog.ContextBuilder, bot *handlers.Bot) {
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    defer signal.Stop(hup)
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-hup:
+            reload(cfg, dlg, bot)
+        }
+    }
+}
+
+// reload подменяет промпт, FAQ и фильтр по отдельности: если новое
+// содержимое не читается или некорректно, остаётся прежнее
+func reload(cfg *config.Config, dlg *dialog.ContextBuilder, bot *handlers.Bot) {
+    logging.Logger().Info("получен SIGHUP, перезагрузка промпта, FAQ и фильтра")
+
+    if prompt, err := cfg.ReadSystemPrompt(); err != nil {
+        logging.Logger().Error("системный промпт не перезагружен, оставлен прежний", "err", err)
+    } else {
+        dlg.SetSystemPrompt(prompt)
+        logging.Logger().Info("системный промпт перезагружен", "length", len([]rune(prompt)))
+    }
+
+    if cfg.FAQFile != "" {
+        matcher, err := faq.Load(cfg.FAQFile, cfg.FAQThreshold)
+        if err != nil {
+            logging.Logger().Error("FAQ не перезагружен, оставлен прежний", "file", cfg.FAQFile, "err", err)
+        } else {
+            bot.SetFAQ(matcher)
+            logging.Logger().Info("FAQ перезагружен", "file", cfg.FAQFile)
+        }
+    }
+
+    if cfg.OutputFilterFile != "" {
+        f, err := filter.Load(cfg.OutputFilterFile)
+        if err != nil {
+            logging.Logger().Error("фильтр ответов не перезагружен, оставлен прежний", "file", cfg.OutputFilterFile, "err", err)
+        } else {
+            bot.SetFilter(f)
+            logging.Logger().Info("фильтр ответов перезагружен", "file", cfg.OutputFilterFile)
+        }
+    }
+}
@@ -0,0 +1,98 @@
+package reqctx
+
+import "context"
+
+type chatIDKey struct{}
+
+// WithChatID — кладёт id чата в контекст обработки апдейта, чтобы нижние слои
+// (инструменты модели, учёт токенов) знали, к какому чату относится вызов
+func WithChatID(ctx context.Context, chatID int64) context.Context {
+    return context.WithValue(ctx, chatIDKey{}, chatID)
+}
+
+// ChatIDFromContext — id чата из контекста или 0, если его там нет
+func ChatIDFromContext(ctx context.Context) int64 {
+    id, _ := ctx.Value(chatIDKey{}).(int64)
+    return id
+}
+
+type langKey struct{}
+
+// WithLang — кладёт в контекст язык пользователя для фиксированных ответов
+func WithLang(ctx context.Context, lang string) context.Context {
+    return context.WithValue(ctx, langKey{}, lang)
+}
+
+// LangFromContext — язык пользователя из контекста или пустая строка
+func LangFromContext(ctx context.Context) string {
+    lang, _ := ctx.Value(langKey{}).(string)
+    return lang
+}
+
+type traceIDKey struct{}
+
+// WithTraceID — кладёт в контекст id трассировки апдейта: он попадает в каждую
+// строку лога обработки и в запросы к OpenAI, чтобы найти разговор по одному id
+func WithTraceID(ctx context.Context, id string) context.Context {
+    return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext — id трассировки из контекста или пустая строка
+func TraceIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(traceIDKey{}).(string)
+    return id
+}
+
+type clientIPKey struct{}
+
+// WithClientIP — кладёт в контекст HTTP-запроса адрес клиента с учётом доверенных прокси
+func WithClientIP(ctx context.Context, ip string) context.Context {
+    return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext — адрес клиента из контекста или пустая строка
+func ClientIPFromContext(ctx context.Context) string {
+    ip, _ := ctx.Value(clientIPKey{}).(string)
+    return ip
+}
+
+type briefKey struct{}
+
+// WithBrief — отмечает в контексте, что покупатель просил отвечать кратко
+func WithBrief(ctx context.Context, brief bool) context.Context {
+    return context.WithValue(ctx, briefKey{}, brief)
+}
+
+// BriefFromContext — включён ли краткий режим ответов
+func BriefFromContext(ctx context.Context) bool {
+    brief, _ := ctx.Value(briefKey{}).(bool)
+    return brief
+}
+
+type sharedAnswerKey struct{}
+
+// WithSharedAnswer — отмечает, что ответ на первый вопрос чата попадёт в
+// общий кэш ответов и достанется другим покупателям: такой промпт
+// собирается без имени покупателя
+func WithSharedAnswer(ctx context.Context) context.Context {
+    return context.WithValue(ctx, sharedAnswerKey{}, true)
+}
+
+// SharedAnswerFromContext — ответ может уйти в общий кэш
+func SharedAnswerFromContext(ctx context.Context) bool {
+    shared, _ := ctx.Value(sharedAnswerKey{}).(bool)
+    return shared
+}
+
+type promptVariantKey struct{}
+
+// WithPromptVariant — кладёт в контекст вариант системного промпта чата
+func WithPromptVariant(ctx context.Context, variant string) context.Context {
+    return context.WithValue(ctx, promptVariantKey{}, variant)
+}
+
+// PromptVariantFromContext — вариант системного промпта или пустая строка
+func PromptVariantFromContext(ctx context.Context) string {
+    variant, _ := ctx.Value(promptVariantKey{}).(string)
+    return variant
+}
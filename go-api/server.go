@@ -0,0 +1,115 @@
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "ai_seller/config"
+    "ai_seller/logging"
+)
+
+// shutdownTimeout — сколько ждём завершения текущих запросов при остановке
+const shutdownTimeout = 10 * time.Second
+
+// drainTimeout — сколько после остановки сервера ждём обработчики апдейтов
+// и фоновые задачи вместе. Сумма с shutdownTimeout (25 с) должна быть меньше
+// terminationGracePeriodSeconds оркестратора — в Kubernetes по умолчанию 30 с.
+const drainTimeout = 15 * time.Second
+
+// RunServer обслуживает HTTP на cfg.Port до отмены ctx, после чего
+// корректно останавливает сервер, давая текущим вебхукам завершиться.
+// С TLS_CERT_FILE и TLS_KEY_FILE сервер принимает HTTPS — Telegram шлёт
+// вебхуки только на https-адреса. Возвращает ошибку запуска или остановки сервера.
+func RunServer(ctx context.Context, cfg *config.Config, handler http.Handler) error {
+    srv := &http.Server{
+        Addr:    ":" + cfg.Port,
+        Handler: handler,
+    }
+    useTLS := cfg.TLSCertFile != ""
+    if useTLS {
+        srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+    }
+
+    errCh := make(chan error, 1)
+    go func() {
+        logging.Logger().Info("сервер запущен", "addr", srv.Addr, "tls", useTLS)
+        var err error
+        if useTLS {
+            err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+        } else {
+            err = srv.ListenAndServe()
+        }
+        if err != nil && !errors.Is(err, http.ErrServerClosed) {
+            errCh <- err
+        }
+        close(errCh)
+    }()
+
+    select {
+    case err, ok := <-errCh:
+        if ok {
+            return fmt.Errorf("ошибка сервера: %w", err)
+        }
+        return nil
+    case <-ctx.Done():
+    }
+
+    logging.Logger().Info("завершение работы")
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        return fmt.Errorf("ошибка завершения: %w", err)
+    }
+    return nil
+}
+
+// background — фоновые задачи сервиса (напоминания, сводки, отправка
+// ответов): все слушают общий контекст и при остановке дорабатывают
+// текущую итерацию
+type background struct {
+    wg      sync.WaitGroup
+    running atomic.Int64
+}
+
+// Go запускает фоновую задачу; fn должна вернуться после отмены её контекста
+func (bg *background) Go(fn func()) {
+    bg.wg.Add(1)
+    bg.running.Add(1)
+    go func() {
+        defer bg.wg.Done()
+        defer bg.running.Add(-1)
+        fn()
+    }()
+}
+
+// Wait ждёт завершения фоновых задач после отмены контекста, но не дольше
+// чем до deadline: зависшая задача не должна задерживать остановку
+// бесконечно. Считаются задачи, а не обработанные ими элементы — сколько
+// дообработано, пишет в журнал сама задача. Возвращает число брошенных задач.
+func (bg *background) Wait(deadline time.Time) int64 {
+    tasks := bg.running.Load()
+    done := make(chan struct{})
+    go func() {
+        bg.wg.Wait()
+        close(done)
+    }()
+    timer := time.NewTimer(time.Until(deadline))
+    defer timer.Stop()
+    select {
+    case <-done:
+        logging.Logger().Info("фоновые задачи остановлены", "tasks", tasks)
+        return 0
+    case <-timer.C:
+        left := bg.running.Load()
+        logging.Logger().Warn("фоновые задачи не успели завершиться", "tasks", tasks, "stopped", tasks-left, "running", left)
+        return left
+    }
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "fmt"
+    "io"
+    "math/big"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "ai_seller/config"
+)
+
+// Задачи, вышедшие по отмене контекста, останавливаются чисто
+func TestBackgroundWaitStopsCleanly(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    var bg background
+    stopped := make(chan struct{}, 3)
+    for range 3 {
+        bg.Go(func() {
+            <-ctx.Done()
+            stopped <- struct{}{}
+        })
+    }
+    cancel()
+    if left := bg.Wait(time.Now().Add(time.Second)); left != 0 {
+        t.Fatalf("брошено задач: %d, ждали 0", left)
+    }
+    if len(stopped) != 3 {
+        t.Fatalf("остановилось задач: %d из 3", len(stopped))
+    }
+}
+
+// Зависшая задача не держит остановку дольше дедлайна
+func TestBackgroundWaitAbandonsHungTask(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    hang := make(chan struct{})
+    defer close(hang)
+    var bg background
+    bg.Go(func() { <-ctx.Done() })
+    bg.Go(func() { <-hang })
+    cancel()
+
+    start := time.Now()
+    if left := bg.Wait(start.Add(50 * time.Millisecond)); left != 1 {
+        t.Fatalf("брошено задач: %d, ждали 1", left)
+    }
+    if waited := time.Since(start); waited > time.Second {
+        t.Fatalf("Wait ждал %s, дольше дедлайна", waited)
+    }
+}
+
+// Весь бюджет остановки укладывается в 30 секунд, которые Kubernetes по
+// умолчанию даёт поду между SIGTERM и SIGKILL
+func TestShutdownBudgetFitsGracePeriod(t *testing.T) {
+    if total := shutdownTimeout + drainTimeout; total >= 30*time.Second {
+        t.Fatalf("shutdownTimeout + drainTimeout = %s, нужно меньше 30s", total)
+    }
+}
+
+// selfSigned пишет в dir самоподписанный сертификат для 127.0.0.1 и ключ к
+// нему; возвращает пути к файлам и сам сертификат
+func selfSigned(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+    t.Helper()
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber: big.NewInt(1),
+        Subject:      pkix.Name{CommonName: "ai-seller test"},
+        IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(time.Hour),
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cert, err = x509.ParseCertificate(der); err != nil {
+        t.Fatal(err)
+    }
+    keyDER, err := x509.MarshalECPrivateKey(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+    writePEM(t, certFile, "CERTIFICATE", der)
+    writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+    return certFile, keyFile, cert
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+    t.Helper()
+    if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// freePort — свободный TCP-порт на 127.0.0.1
+func freePort(t *testing.T) string {
+    t.Helper()
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer l.Close()
+    return fmt.Sprint(l.Addr().(*net.TCPAddr).Port)
+}
+
+// serve запускает RunServer с cfg и возвращает его остановку
+func serve(t *testing.T, cfg *config.Config) (stop func() error) {
+    t.Helper()
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        done <- RunServer(ctx, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            io.WriteString(w, "ok")
+        }))
+    }()
+    return func() error {
+        cancel()
+        return <-done
+    }
+}
+
+// get повторяет запрос, пока сервер не начнёт слушать порт
+func get(t *testing.T, client *http.Client, url string) (*http.Response, error) {
+    t.Helper()
+    var (
+        resp *http.Response
+        err  error
+    )
+    for range 50 {
+        if resp, err = client.Get(url); err == nil || !strings.Contains(err.Error(), "connection refused") {
+            return resp, err
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+    return nil, err
+}
+
+// С TLS_CERT_FILE и TLS_KEY_FILE сервер отвечает по HTTPS с этим
+// сертификатом, а по обычному HTTP — нет
+func TestRunServerTLS(t *testing.T) {
+    certFile, keyFile, cert := selfSigned(t, t.TempDir())
+    port := freePort(t)
+    cfg, err := config.NewConfig(config.WithEnv(map[string]string{
+        "POSTGRES_DSN":   "postgres://localhost/test",
+        "REDIS_ADDR":     "localhost:6379",
+        "TELEGRAM_TOKEN": "123456:ABCdefGhIJKlmnOPQRstuVWXyz0123456789",
+        "OPENAI_KEY":     "sk-test",
+        "WEBHOOK_URL":    "https://example.com/webhook",
+        "PORT":           port,
+        "TLS_CERT_FILE":  certFile,
+        "TLS_KEY_FILE":   keyFile,
+    }))
+    if err != nil {
+        t.Fatalf("конфигурация с самоподписанным сертификатом: %v", err)
+    }
+    stop := serve(t, cfg)
+
+    roots := x509.NewCertPool()
+    roots.AddCert(cert)
+    client := &http.Client{Timeout: time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+    resp, err := get(t, client, "https://127.0.0.1:"+port+"/")
+    if err != nil {
+        t.Fatalf("HTTPS: %v", err)
+    }
+    body, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || string(body) != "ok" || resp.TLS == nil {
+        t.Fatalf("HTTPS: код %d, тело %q", resp.StatusCode, body)
+    }
+    if resp.TLS.Version < tls.VersionTLS12 {
+        t.Fatalf("версия TLS %x ниже 1.2", resp.TLS.Version)
+    }
+
+    plain, err := (&http.Client{Timeout: time.Second}).Get("http://127.0.0.1:" + port + "/")
+    if err == nil {
+        plain.Body.Close()
+        if plain.StatusCode == http.StatusOK {
+            t.Fatal("сервер с TLS ответил по обычному HTTP")
+        }
+    }
+
+    if err := stop(); err != nil {
+        t.Fatalf("остановка: %v", err)
+    }
+}
+
+// Без сертификата сервер отвечает по обычному HTTP
+func TestRunServerPlainHTTP(t *testing.T) {
+    port := freePort(t)
+    stop := serve(t, &config.Config{Port: port})
+    resp, err := get(t, &http.Client{Timeout: time.Second}, "http://127.0.0.1:"+port+"/")
+    if err != nil {
+        t.Fatalf("HTTP: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("HTTP: код %d", resp.StatusCode)
+    }
+    if err := stop(); err != nil {
+        t.Fatalf("остановка: %v", err)
+    }
+}
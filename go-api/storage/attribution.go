@@ -0,0 +1,54 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// Источники покупателя по параметру /start
+const (
+    AttributionCampaign = "campaign"
+    AttributionReferral = "referral"
+)
+
+// Attribution — откуда пришёл покупатель
+type Attribution struct {
+    // Kind — AttributionCampaign или AttributionReferral
+    Kind string
+    // Code — код кампании; для приглашения — параметр ссылки как есть
+    Code string
+    // ReferrerID — чат пригласившего; 0 — не приглашение
+    ReferrerID int64
+}
+
+// AttributionStore — источники покупателей в PostgreSQL
+type AttributionStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewAttributionStore — фабрика хранилища источников покупателей
+func NewAttributionStore(db *sql.DB, queryTimeout time.Duration) *AttributionStore {
+    return &AttributionStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Record сохраняет источник покупателя. Учитывается первое касание: если
+// источник уже записан, возвращает false и прежнюю запись не трогает.
+func (s *AttributionStore) Record(ctx context.Context, chatID int64, a Attribution) (bool, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    res, err := s.db.ExecContext(ctx,
+        `INSERT INTO attributions (chat_id, kind, code, referrer_id) VALUES ($1, $2, $3, NULLIF($4, 0))
+         ON CONFLICT (chat_id) DO NOTHING`,
+        chatID, a.Kind, a.Code, a.ReferrerID)
+    if err != nil {
+        return false, fmt.Errorf("ошибка сохранения источника покупателя: %w", err)
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return false, fmt.Errorf("ошибка сохранения источника покупателя: %w", err)
+    }
+    return n > 0, nil
+}
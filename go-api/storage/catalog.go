@@ -0,0 +1,149 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/money"
+)
+
+// searchLimit — максимум товаров в результатах поиска
+const searchLimit = 20
+
+// ErrNotFound — запись не найдена; та же категория, что apperr.ErrNotFound
+var ErrNotFound = apperr.ErrNotFound
+
+// Product — товар каталога
+type Product struct {
+    ID          int64
+    Name        string
+    Description string
+    Price       money.Money
+    InStock     bool
+    // ImageURL — публичная ссылка на фото товара; пусто, если фото нет
+    ImageURL string
+    // Stock — остаток на складе; nil, если остаток не ведётся
+    Stock *int
+    // CategoryID — категория товара; 0 — без категории, товар виден только
+    // в общем каталоге. При импорте 0 не меняет заведённую категорию.
+    CategoryID int64
+}
+
+// CatalogStore — каталог товаров в PostgreSQL
+type CatalogStore struct {
+    db *sql.DB
+    queryLimit
+    // embedder — nil, пока семантический поиск не включён
+    embedder Embedder
+}
+
+// NewCatalogStore — фабрика каталога
+func NewCatalogStore(db *sql.DB, queryTimeout time.Duration) *CatalogStore {
+    return &CatalogStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+const productColumns = `id, name, description, price, currency, in_stock, image_url, stock, coalesce(category_id, 0)`
+
+// ListProducts возвращает страницу каталога, упорядоченную по id
+func (s *CatalogStore) ListProducts(ctx context.Context, limit, offset int) ([]Product, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+productColumns+` FROM products ORDER BY id LIMIT $1 OFFSET $2`,
+        limit, offset)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения каталога: %w", err)
+    }
+    return scanProducts(rows)
+}
+
+// CountProducts возвращает число товаров в каталоге
+func (s *CatalogStore) CountProducts(ctx context.Context) (int, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var n int
+    if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM products`).Scan(&n); err != nil {
+        return 0, fmt.Errorf("ошибка подсчёта товаров: %w", err)
+    }
+    return n, nil
+}
+
+// GetProduct возвращает товар по id или ErrNotFound
+func (s *CatalogStore) GetProduct(ctx context.Context, id int64) (Product, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var p Product
+    err := s.db.QueryRowContext(ctx,
+        `SELECT `+productColumns+` FROM products WHERE id = $1`, id).
+        Scan(&p.ID, &p.Name, &p.Description, &p.Price.Minor, &p.Price.Currency, &p.InStock, &p.ImageURL, &p.Stock, &p.CategoryID)
+    if errors.Is(err, sql.ErrNoRows) {
+        return Product{}, ErrNotFound
+    }
+    if err != nil {
+        return Product{}, fmt.Errorf("ошибка чтения товара %d: %w", id, err)
+    }
+    return p, nil
+}
+
+// SearchProducts ищет товары по подстроке в названии или описании (без учёта регистра)
+func (s *CatalogStore) SearchProducts(ctx context.Context, query string) ([]Product, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    pattern := "%" + escapeLike(strings.TrimSpace(query)) + "%"
+    d := dialectOf(s.db)
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+productColumns+` FROM products
+         WHERE `+d.ilike("name", "$1")+` OR `+d.ilike("description", "$1")+`
+         ORDER BY in_stock DESC, id
+         LIMIT $2`,
+        pattern, searchLimit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка поиска товаров: %w", err)
+    }
+    return scanProducts(rows)
+}
+
+// FuzzySearchProducts ищет товары по триграммной похожести названия на запрос
+// (pg_trgm) — находит и запросы с опечатками. Товары с похожестью ниже
+// threshold (0..1) отбрасываются, остальные идут от самых похожих.
+func (s *CatalogStore) FuzzySearchProducts(ctx context.Context, query string, threshold float64) ([]Product, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+productColumns+` FROM products
+         WHERE similarity(name, $1) >= $2
+         ORDER BY similarity(name, $1) DESC, in_stock DESC, id
+         LIMIT $3`,
+        strings.TrimSpace(query), threshold, searchLimit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка нечёткого поиска товаров: %w", err)
+    }
+    return scanProducts(rows)
+}
+
+func scanProducts(rows *sql.Rows) ([]Product, error) {
+    defer rows.Close()
+
+    var products []Product
+    for rows.Next() {
+        var p Product
+        if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price.Minor, &p.Price.Currency, &p.InStock, &p.ImageURL, &p.Stock, &p.CategoryID); err != nil {
+            return nil, fmt.Errorf("ошибка чтения товара: %w", err)
+        }
+        products = append(products, p)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения товаров: %w", err)
+    }
+    return products, nil
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы запрос искался буквально
+func escapeLike(s string) string {
+    return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
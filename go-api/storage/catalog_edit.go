@@ -0,0 +1,109 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+)
+
+// foreignKeyViolation — код ошибки PostgreSQL при ссылке на несуществующую строку
+const foreignKeyViolation = "23503"
+
+// SetPrice меняет цену товара (валюта остаётся прежней) и записывает правку
+// в catalog_audit от имени чата changedBy. Возвращает обновлённый товар или
+// ErrNotFound.
+func (s *CatalogStore) SetPrice(ctx context.Context, id int64, minor int64, changedBy int64) (Product, error) {
+    return s.edit(ctx, id, changedBy, "price",
+        func(p Product) string { return p.Price.String() },
+        `UPDATE products SET price = $2 WHERE id = $1`, minor)
+}
+
+// SetStock задаёт остаток товара и его наличие (в наличии, если остаток
+// больше нуля) и записывает правку в catalog_audit от имени чата changedBy.
+// Возвращает обновлённый товар или ErrNotFound.
+func (s *CatalogStore) SetStock(ctx context.Context, id int64, qty int, changedBy int64) (Product, error) {
+    return s.edit(ctx, id, changedBy, "stock",
+        func(p Product) string {
+            if p.Stock == nil {
+                return ""
+            }
+            return strconv.Itoa(*p.Stock)
+        },
+        `UPDATE products SET stock = $2, in_stock = $2 > 0 WHERE id = $1`, qty)
+}
+
+// SetCategory переносит товар в категорию categoryID (0 — убрать из
+// категорий) и записывает правку в catalog_audit от имени чата changedBy.
+// Возвращает обновлённый товар или ErrNotFound — нет товара или категории.
+func (s *CatalogStore) SetCategory(ctx context.Context, id, categoryID int64, changedBy int64) (Product, error) {
+    p, err := s.edit(ctx, id, changedBy, "category",
+        func(p Product) string {
+            if p.CategoryID == 0 {
+                return ""
+            }
+            return strconv.FormatInt(p.CategoryID, 10)
+        },
+        `UPDATE products SET category_id = NULLIF($2, 0) WHERE id = $1`, categoryID)
+    if isForeignKeyViolation(err) {
+        return Product{}, ErrNotFound
+    }
+    return p, err
+}
+
+// edit выполняет правку товара update одной транзакцией с записью в журнал:
+// value — значение поля field до и после правки
+func (s *CatalogStore) edit(ctx context.Context, id, changedBy int64, field string, value func(Product) string, update string, arg any) (p Product, err error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return Product{}, fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer func() {
+        if err != nil {
+            tx.Rollback()
+        }
+    }()
+
+    d := dialectOf(s.db)
+    before, err := productForUpdate(ctx, d, tx, id)
+    if err != nil {
+        return Product{}, err
+    }
+    if _, err = tx.ExecContext(ctx, update, id, arg); err != nil {
+        return Product{}, fmt.Errorf("ошибка изменения товара %d: %w", id, err)
+    }
+    after, err := productForUpdate(ctx, d, tx, id)
+    if err != nil {
+        return Product{}, err
+    }
+    _, err = tx.ExecContext(ctx,
+        `INSERT INTO catalog_audit (product_id, field, old_value, new_value, changed_by)
+         VALUES ($1, $2, $3, $4, $5)`,
+        id, field, value(before), value(after), changedBy)
+    if err != nil {
+        return Product{}, fmt.Errorf("ошибка записи в журнал правок каталога: %w", err)
+    }
+    if err = tx.Commit(); err != nil {
+        return Product{}, fmt.Errorf("ошибка фиксации правки товара %d: %w", id, err)
+    }
+    return after, nil
+}
+
+// productForUpdate читает товар внутри транзакции, блокируя строку
+func productForUpdate(ctx context.Context, d dialect, tx *sql.Tx, id int64) (Product, error) {
+    var p Product
+    err := tx.QueryRowContext(ctx,
+        `SELECT `+productColumns+` FROM products WHERE id = $1`+d.lockRows(), id).
+        Scan(&p.ID, &p.Name, &p.Description, &p.Price.Minor, &p.Price.Currency, &p.InStock, &p.ImageURL, &p.Stock, &p.CategoryID)
+    if errors.Is(err, sql.ErrNoRows) {
+        return Product{}, ErrNotFound
+    }
+    if err != nil {
+        return Product{}, fmt.Errorf("ошибка чтения товара %d: %w", id, err)
+    }
+    return p, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+)
+
+// ImportResult — итог загрузки каталога из файла
+type ImportResult struct {
+    Added     int
+    Updated   int
+    Unchanged int
+}
+
+// ImportProducts добавляет и обновляет товары одной транзакцией: либо
+// применяется весь файл, либо ничего. Товар с ID обновляется по ID (или
+// создаётся с этим ID), без ID — по точному совпадению названия без учёта
+// регистра; не найденный товар добавляется. Строки, которые ничего не
+// меняют, не обновляются и считаются в Unchanged. Пустой остаток (Stock == nil)
+// и пустая категория (CategoryID == 0) не затирают уже заведённые.
+func (s *CatalogStore) ImportProducts(ctx context.Context, products []Product) (res ImportResult, err error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return ImportResult{}, fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer func() {
+        if err != nil {
+            tx.Rollback()
+        }
+    }()
+
+    d := dialectOf(s.db)
+    explicitIDs := false
+    for _, p := range products {
+        if p.ID == 0 {
+            err = tx.QueryRowContext(ctx,
+                `SELECT id FROM products WHERE lower(name) = lower($1) ORDER BY id LIMIT 1`+d.lockRows(),
+                p.Name).Scan(&p.ID)
+            if errors.Is(err, sql.ErrNoRows) {
+                _, err = tx.ExecContext(ctx,
+                    `INSERT INTO products (name, description, price, currency, in_stock, image_url, stock, category_id)
+                     VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0))`,
+                    p.Name, p.Description, p.Price.Minor, p.Price.Currency, p.InStock, p.ImageURL, p.Stock, p.CategoryID)
+                if err != nil {
+                    return ImportResult{}, fmt.Errorf("ошибка добавления товара %q: %w", p.Name, err)
+                }
+                res.Added++
+                continue
+            }
+            if err != nil {
+                return ImportResult{}, fmt.Errorf("ошибка поиска товара %q: %w", p.Name, err)
+            }
+        } else {
+            explicitIDs = true
+        }
+
+        // xmax = 0 только у строки, которую вставили, а не обновили. В SQLite
+        // такого признака нет, и строку ищут заранее: транзакция держит всю
+        // базу, так что между проверкой и записью строка не появится.
+        insertedExpr := `xmax = 0`
+        if d == DriverSQLite {
+            var exists bool
+            err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, p.ID).Scan(&exists)
+            if err != nil {
+                return ImportResult{}, fmt.Errorf("ошибка поиска товара %d: %w", p.ID, err)
+            }
+            insertedExpr = strconv.FormatBool(!exists)
+        }
+        var inserted bool
+        err = tx.QueryRowContext(ctx,
+            `INSERT INTO products (id, name, description, price, currency, in_stock, image_url, stock, category_id)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0))
+             ON CONFLICT (id) DO UPDATE SET
+                 name = EXCLUDED.name, description = EXCLUDED.description, price = EXCLUDED.price,
+                 currency = EXCLUDED.currency, in_stock = EXCLUDED.in_stock, image_url = EXCLUDED.image_url,
+                 stock = coalesce(EXCLUDED.stock, products.stock),
+                 category_id = coalesce(EXCLUDED.category_id, products.category_id)
+             WHERE (products.name, products.description, products.price, products.currency, products.in_stock, products.image_url, products.stock, products.category_id)
+                 IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.description, EXCLUDED.price, EXCLUDED.currency, EXCLUDED.in_stock, EXCLUDED.image_url,
+                     coalesce(EXCLUDED.stock, products.stock), coalesce(EXCLUDED.category_id, products.category_id))
+             RETURNING `+insertedExpr,
+            p.ID, p.Name, p.Description, p.Price.Minor, p.Price.Currency, p.InStock, p.ImageURL, p.Stock, p.CategoryID).Scan(&inserted)
+        switch {
+        case errors.Is(err, sql.ErrNoRows):
+            res.Unchanged++
+        case err != nil:
+            return ImportResult{}, fmt.Errorf("ошибка сохранения товара %d: %w", p.ID, err)
+        case inserted:
+            res.Added++
+        default:
+            res.Updated++
+        }
+    }
+
+    if explicitIDs && d == DriverPostgres {
+        // Вставка с явным id не двигает последовательность: без этого
+        // следующий товар без id получил бы уже занятый номер. В SQLite
+        // новый id и так на единицу больше наибольшего.
+        _, err = tx.ExecContext(ctx,
+            `SELECT setval(pg_get_serial_sequence('products', 'id'), (SELECT max(id) FROM products))`)
+        if err != nil {
+            return ImportResult{}, fmt.Errorf("ошибка сдвига последовательности товаров: %w", err)
+        }
+    }
+
+    if err = tx.Commit(); err != nil {
+        return ImportResult{}, fmt.Errorf("ошибка фиксации импорта каталога: %w", err)
+    }
+    return res, nil
+}
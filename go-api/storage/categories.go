@@ -0,0 +1,75 @@
+package storage
+
+import (
+    "context"
+    "fmt"
+)
+
+// Category — категория каталога
+type Category struct {
+    ID   int64
+    Name string
+    // ParentID — родительская категория; 0 у корневых
+    ParentID int64
+    // Products — товары прямо в категории, без подкатегорий
+    Products int
+}
+
+// ListCategories возвращает все категории в порядке показа: по position,
+// затем по названию. Дерево не глубже двух уровней, поэтому собирается
+// на стороне вызывающего по ParentID.
+func (s *CatalogStore) ListCategories(ctx context.Context) ([]Category, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT c.id, c.name, COALESCE(c.parent_id, 0),
+                (SELECT count(*) FROM products p WHERE p.category_id = c.id)
+         FROM categories c
+         ORDER BY c.position, c.name, c.id`)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения категорий: %w", err)
+    }
+    defer rows.Close()
+
+    var out []Category
+    for rows.Next() {
+        var c Category
+        if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.Products); err != nil {
+            return nil, fmt.Errorf("ошибка чтения категории: %w", err)
+        }
+        out = append(out, c)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения категорий: %w", err)
+    }
+    return out, nil
+}
+
+// categoryProducts — товары категории и её подкатегорий
+const categoryProducts = `category_id IN (SELECT id FROM categories WHERE id = $1 OR parent_id = $1)`
+
+// ListProductsByCategory возвращает страницу товаров категории вместе с её
+// подкатегориями, упорядоченную по id
+func (s *CatalogStore) ListProductsByCategory(ctx context.Context, categoryID int64, limit, offset int) ([]Product, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+productColumns+` FROM products WHERE `+categoryProducts+` ORDER BY id LIMIT $2 OFFSET $3`,
+        categoryID, limit, offset)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения товаров категории %d: %w", categoryID, err)
+    }
+    return scanProducts(rows)
+}
+
+// CountProductsByCategory возвращает число товаров категории вместе с её подкатегориями
+func (s *CatalogStore) CountProductsByCategory(ctx context.Context, categoryID int64) (int, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var n int
+    err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM products WHERE `+categoryProducts, categoryID).Scan(&n)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка подсчёта товаров категории %d: %w", categoryID, err)
+    }
+    return n, nil
+}
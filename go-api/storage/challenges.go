@@ -0,0 +1,97 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// MemberChallenge — проверка нового участника группы: до ExpiresAt он
+// должен нажать кнопку в сообщении MessageID
+type MemberChallenge struct {
+    ChatID    int64
+    UserID    int64
+    MessageID int64
+    ExpiresAt time.Time
+}
+
+// MemberChallengeStore — незавершённые проверки участников в PostgreSQL:
+// переживают перезапуск, поэтому участник, не нажавший кнопку, будет
+// удалён и после падения процесса
+type MemberChallengeStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewMemberChallengeStore — фабрика хранилища проверок участников
+func NewMemberChallengeStore(db *sql.DB, queryTimeout time.Duration) *MemberChallengeStore {
+    return &MemberChallengeStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Create сохраняет проверку; повторный вход того же участника начинает её заново
+func (s *MemberChallengeStore) Create(ctx context.Context, c MemberChallenge) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO member_challenges (chat_id, user_id, message_id, expires_at) VALUES ($1, $2, $3, $4)
+         ON CONFLICT (chat_id, user_id) DO UPDATE SET message_id = EXCLUDED.message_id, expires_at = EXCLUDED.expires_at`,
+        c.ChatID, c.UserID, c.MessageID, c.ExpiresAt)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения проверки участника: %w", err)
+    }
+    return nil
+}
+
+// Resolve снимает проверку участника и возвращает её; ErrNotFound — проверки
+// нет (уже пройдена или время вышло)
+func (s *MemberChallengeStore) Resolve(ctx context.Context, chatID, userID int64) (MemberChallenge, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    c := MemberChallenge{ChatID: chatID, UserID: userID}
+    err := s.db.QueryRowContext(ctx,
+        `DELETE FROM member_challenges WHERE chat_id = $1 AND user_id = $2 RETURNING message_id, expires_at`,
+        chatID, userID).Scan(&c.MessageID, &c.ExpiresAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return MemberChallenge{}, ErrNotFound
+    }
+    if err != nil {
+        return MemberChallenge{}, fmt.Errorf("ошибка снятия проверки участника: %w", err)
+    }
+    return c, nil
+}
+
+// ClaimExpired забирает до limit проверок, время которых вышло к now.
+// Забранная проверка удаляется сразу, поэтому две реплики не удалят
+// одного участника дважды, а нажатие кнопки после этого уже не поможет.
+func (s *MemberChallengeStore) ClaimExpired(ctx context.Context, now time.Time, limit int) ([]MemberChallenge, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `DELETE FROM member_challenges WHERE (chat_id, user_id) IN (
+             SELECT chat_id, user_id FROM member_challenges
+             WHERE expires_at <= $1
+             ORDER BY expires_at
+             LIMIT $2`+dialectOf(s.db).skipLocked()+`
+         )
+         RETURNING chat_id, user_id, message_id, expires_at`,
+        now, limit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка выборки просроченных проверок: %w", err)
+    }
+    defer rows.Close()
+
+    var out []MemberChallenge
+    for rows.Next() {
+        var c MemberChallenge
+        if err := rows.Scan(&c.ChatID, &c.UserID, &c.MessageID, &c.ExpiresAt); err != nil {
+            return nil, fmt.Errorf("ошибка чтения проверки участника: %w", err)
+        }
+        out = append(out, c)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка выборки просроченных проверок: %w", err)
+    }
+    return out, nil
+}
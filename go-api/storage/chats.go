@@ -0,0 +1,131 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// ChatStore — список известных чатов для рассылок
+type ChatStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewChatStore — фабрика хранилища чатов
+func NewChatStore(db *sql.DB, queryTimeout time.Duration) *ChatStore {
+    return &ChatStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// ActiveChatIDs возвращает чаты, которые когда-либо писали боту, кроме
+// заблокировавших его. Чат снова считается активным, если написал после пометки.
+func (s *ChatStore) ActiveChatIDs(ctx context.Context) ([]int64, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT m.chat_id
+         FROM (SELECT chat_id, MAX(created_at) AS last_at FROM messages GROUP BY chat_id) m
+         LEFT JOIN inactive_chats i ON i.chat_id = m.chat_id
+         WHERE i.chat_id IS NULL OR m.last_at > i.marked_at
+         ORDER BY m.chat_id`)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения списка чатов: %w", err)
+    }
+    defer rows.Close()
+
+    var ids []int64
+    for rows.Next() {
+        var id int64
+        if err := rows.Scan(&id); err != nil {
+            return nil, fmt.Errorf("ошибка чтения списка чатов: %w", err)
+        }
+        ids = append(ids, id)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения списка чатов: %w", err)
+    }
+    return ids, nil
+}
+
+// MarkActive снимает пометку MarkInactive: покупатель разблокировал бота
+// или бота снова добавили в группу
+func (s *ChatStore) MarkActive(ctx context.Context, chatID int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    if _, err := s.db.ExecContext(ctx, `DELETE FROM inactive_chats WHERE chat_id = $1`, chatID); err != nil {
+        return fmt.Errorf("ошибка снятия пометки неактивного чата: %w", err)
+    }
+    return nil
+}
+
+// JoinGroup записывает группу, в которую добавили бота; повторное добавление
+// обновляет название и время
+func (s *ChatStore) JoinGroup(ctx context.Context, chatID int64, title string) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO bot_groups (chat_id, title) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET title = EXCLUDED.title, joined_at = now(), left_at = NULL`,
+        chatID, title)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения группы: %w", err)
+    }
+    return nil
+}
+
+// LeaveGroup отмечает, что бота удалили из группы; неизвестная группа — не ошибка
+func (s *ChatStore) LeaveGroup(ctx context.Context, chatID int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `UPDATE bot_groups SET left_at = now() WHERE chat_id = $1 AND left_at IS NULL`, chatID)
+    if err != nil {
+        return fmt.Errorf("ошибка отметки выхода из группы: %w", err)
+    }
+    return nil
+}
+
+// MarkInactive помечает чат заблокировавшим бота
+func (s *ChatStore) MarkInactive(ctx context.Context, chatID int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO inactive_chats (chat_id) VALUES ($1)
+         ON CONFLICT (chat_id) DO UPDATE SET marked_at = now()`,
+        chatID)
+    if err != nil {
+        return fmt.Errorf("ошибка пометки чата неактивным: %w", err)
+    }
+    return nil
+}
+
+// SetMemberVerification включает или выключает проверку новых участников
+// группы; группа, которой ещё нет в bot_groups, записывается
+func (s *ChatStore) SetMemberVerification(ctx context.Context, chatID int64, on bool) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO bot_groups (chat_id, verify_members) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET verify_members = EXCLUDED.verify_members`,
+        chatID, on)
+    if err != nil {
+        return fmt.Errorf("ошибка настройки проверки участников группы: %w", err)
+    }
+    return nil
+}
+
+// MemberVerification — включена ли в группе проверка новых участников
+func (s *ChatStore) MemberVerification(ctx context.Context, chatID int64) (bool, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var on bool
+    err := s.db.QueryRowContext(ctx,
+        `SELECT verify_members FROM bot_groups WHERE chat_id = $1`, chatID).Scan(&on)
+    if errors.Is(err, sql.ErrNoRows) {
+        return false, nil
+    }
+    if err != nil {
+        return false, fmt.Errorf("ошибка чтения настройки проверки участников: %w", err)
+    }
+    return on, nil
+}
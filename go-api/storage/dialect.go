@@ -0,0 +1,90 @@
+package storage
+
+import (
+    "database/sql"
+    "encoding/json"
+    "errors"
+
+    "github.com/lib/pq"
+    "modernc.org/sqlite"
+    sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Драйверы хранилища (STORAGE_DRIVER)
+const (
+    DriverPostgres = "postgres"
+    DriverSQLite   = "sqlite"
+)
+
+// dialect — диалект SQL базы, на которой работает хранилище. Запросы общие,
+// кроме мест, где у SQLite нет возможностей PostgreSQL: блокировок строк,
+// ILIKE и массивов в параметрах.
+type dialect string
+
+// dialectOf — диалект пула db: база, открытая OpenSQLite, — SQLite, любая другая — PostgreSQL
+func dialectOf(db *sql.DB) dialect {
+    if _, ok := db.Driver().(sqliteDriver); ok {
+        return DriverSQLite
+    }
+    return DriverPostgres
+}
+
+// lockRows — FOR UPDATE для SELECT в транзакции. В SQLite транзакция
+// записи (BEGIN IMMEDIATE) и так блокирует всю базу.
+func (d dialect) lockRows() string {
+    if d == DriverSQLite {
+        return ""
+    }
+    return " FOR UPDATE"
+}
+
+// skipLocked — FOR UPDATE SKIP LOCKED для очередей: запись, которую уже
+// забирает другой экземпляр, пропускается. В SQLite запрос записи выполняется
+// целиком под блокировкой базы, поэтому пропускать нечего.
+func (d dialect) skipLocked() string {
+    if d == DriverSQLite {
+        return ""
+    }
+    return " FOR UPDATE SKIP LOCKED"
+}
+
+// ilike — col ILIKE param; в SQLite LIKE без учёта регистра только для
+// латиницы, поэтому обе стороны приводятся к нижнему регистру (lower
+// в OpenSQLite понимает Юникод). Экранирование — как в escapeLike.
+func (d dialect) ilike(col, param string) string {
+    if d == DriverSQLite {
+        return `lower(` + col + `) LIKE lower(` + param + `) ESCAPE '\'`
+    }
+    return col + ` ILIKE ` + param
+}
+
+// inIDs — условие «col входит в список param»; сам список передаётся
+// значением ids
+func (d dialect) inIDs(col, param string) string {
+    if d == DriverSQLite {
+        return col + ` IN (SELECT value FROM json_each(` + param + `))`
+    }
+    return col + ` = ANY(` + param + `)`
+}
+
+// ids — значение параметра для inIDs
+func (d dialect) ids(ids []int64) any {
+    if d == DriverSQLite {
+        if ids == nil {
+            ids = []int64{}
+        }
+        data, _ := json.Marshal(ids)
+        return string(data)
+    }
+    return pq.Array(ids)
+}
+
+// isForeignKeyViolation — запись ссылается на несуществующую строку
+func isForeignKeyViolation(err error) bool {
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) {
+        return pqErr.Code == foreignKeyViolation
+    }
+    var sqErr *sqlite.Error
+    return errors.As(err, &sqErr) && sqErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
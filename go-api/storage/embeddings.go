@@ -0,0 +1,249 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "ai_seller/logging"
+
+    "github.com/lib/pq"
+)
+
+// EmbeddingDimensions — размерность столбца products.embedding
+const EmbeddingDimensions = 1536
+
+const (
+    // defaultReindexBatch — сколько товаров отправляется в OpenAI за один запрос,
+    // если размер партии не задан
+    defaultReindexBatch = 100
+    // reindexAttempts — сколько раз пробовать партию, прежде чем пропустить её
+    reindexAttempts = 3
+    // reindexRetryDelay — пауза перед повтором партии; удваивается с каждой попыткой
+    reindexRetryDelay = 5 * time.Second
+)
+
+// ErrSemanticDisabled — семантический поиск не включён через EnableSemanticSearch
+var ErrSemanticDisabled = errors.New("семантический поиск выключен")
+
+// Embedder — источник векторов для текстов (клиент OpenAI)
+type Embedder interface {
+    Embeddings(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// EnableSemanticSearch готовит схему под семантический поиск и включает его.
+// Требует расширение pgvector, поэтому выполняется не миграцией, а только
+// когда поиск включён в конфигурации.
+func (s *CatalogStore) EnableSemanticSearch(ctx context.Context, embedder Embedder) error {
+    stmts := []string{
+        `CREATE EXTENSION IF NOT EXISTS vector`,
+        `ALTER TABLE products ADD COLUMN IF NOT EXISTS embedding vector(` + strconv.Itoa(EmbeddingDimensions) + `)`,
+        `CREATE INDEX IF NOT EXISTS products_embedding_idx ON products USING hnsw (embedding vector_cosine_ops)`,
+        // Изменённое описание должно переиндексироваться, поэтому старый вектор сбрасываем
+        `CREATE OR REPLACE FUNCTION products_reset_embedding() RETURNS trigger AS $$
+         BEGIN
+             IF NEW.name IS DISTINCT FROM OLD.name OR NEW.description IS DISTINCT FROM OLD.description THEN
+                 NEW.embedding := NULL;
+             END IF;
+             RETURN NEW;
+         END $$ LANGUAGE plpgsql`,
+        `DROP TRIGGER IF EXISTS products_reset_embedding ON products`,
+        `CREATE TRIGGER products_reset_embedding BEFORE UPDATE ON products
+         FOR EACH ROW EXECUTE FUNCTION products_reset_embedding()`,
+    }
+    for _, stmt := range stmts {
+        if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+            return fmt.Errorf("ошибка подготовки семантического поиска (нужно расширение pgvector): %w", err)
+        }
+    }
+    s.embedder = embedder
+    return nil
+}
+
+// SemanticSearch возвращает k товаров, ближайших к запросу по косинусному
+// расстоянию эмбеддингов. Товары без эмбеддинга (до ReindexEmbeddings) не находятся.
+func (s *CatalogStore) SemanticSearch(ctx context.Context, query string, k int) ([]Product, error) {
+    if s.embedder == nil {
+        return nil, ErrSemanticDisabled
+    }
+    vectors, err := s.embedder.Embeddings(ctx, []string{strings.TrimSpace(query)})
+    if err != nil {
+        return nil, fmt.Errorf("ошибка расчёта эмбеддинга запроса: %w", err)
+    }
+
+    // Предел DB_TIMEOUT — только на сам запрос: эмбеддинг считает OpenAI
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+productColumns+` FROM products
+         WHERE embedding IS NOT NULL
+         ORDER BY embedding <=> $1::vector
+         LIMIT $2`,
+        vectorLiteral(vectors[0]), k)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка семантического поиска товаров: %w", err)
+    }
+    return scanProducts(rows)
+}
+
+// ReindexResult — итог ReindexEmbeddings
+type ReindexResult struct {
+    // Indexed — товары, получившие эмбеддинг
+    Indexed int
+    // Failed — товары из партий, которые не удалось проиндексировать
+    Failed int
+    // Elapsed — длительность индексации
+    Elapsed time.Duration
+}
+
+// ReindexEmbeddings считает эмбеддинги для товаров, у которых их нет:
+// разовое заполнение после включения поиска, затем — новые и изменённые товары.
+// Товары уходят в OpenAI партиями по batch штук (0 — по умолчанию), каждая
+// партия сохраняется сразу, поэтому прерванная индексация при следующем
+// запуске продолжается с товаров без эмбеддинга. Партия, не посчитанная за
+// reindexAttempts попыток, пропускается до следующего запуска, а остальные
+// индексируются дальше.
+func (s *CatalogStore) ReindexEmbeddings(ctx context.Context, batch int) (ReindexResult, error) {
+    if s.embedder == nil {
+        return ReindexResult{}, ErrSemanticDisabled
+    }
+    return reindex(ctx, s, s.embedder, batch, reindexRetryDelay)
+}
+
+// embeddingQueue — товары без эмбеддингов и место для посчитанных; в
+// CatalogStore — столбец products.embedding
+type embeddingQueue interface {
+    // pendingEmbeddings — до limit товаров без эмбеддинга с id больше after по порядку id
+    pendingEmbeddings(ctx context.Context, after int64, limit int) ([]Product, error)
+    saveEmbeddings(ctx context.Context, ids []int64, vectors [][]float32) error
+}
+
+// reindex — цикл ReindexEmbeddings над очередью q; retryDelay — пауза
+// перед первым повтором партии
+func reindex(ctx context.Context, q embeddingQueue, embedder Embedder, batch int, retryDelay time.Duration) (res ReindexResult, err error) {
+    if batch <= 0 {
+        batch = defaultReindexBatch
+    }
+
+    start := time.Now()
+    defer func() { res.Elapsed = time.Since(start) }()
+    // after — id последнего обработанного товара: пропущенная партия
+    // не должна выбираться снова в этом же запуске
+    var after int64
+    for {
+        products, err := q.pendingEmbeddings(ctx, after, batch)
+        if err != nil {
+            return res, err
+        }
+        if len(products) == 0 {
+            return res, nil
+        }
+        after = products[len(products)-1].ID
+
+        if err := indexBatch(ctx, q, embedder, products, retryDelay); err != nil {
+            if ctx.Err() != nil {
+                return res, err
+            }
+            res.Failed += len(products)
+            logging.Logger().Error("партия эмбеддингов пропущена",
+                "first_id", products[0].ID, "last_id", after, "err", err)
+            continue
+        }
+        res.Indexed += len(products)
+        logging.Logger().Info("партия эмбеддингов сохранена",
+            "indexed", res.Indexed, "per_second", perSecond(res.Indexed, time.Since(start)))
+    }
+}
+
+// indexBatch считает эмбеддинги партии товаров с повторами и сохраняет их разом
+func indexBatch(ctx context.Context, q embeddingQueue, embedder Embedder, products []Product, delay time.Duration) error {
+    texts := make([]string, len(products))
+    ids := make([]int64, len(products))
+    for i, p := range products {
+        texts[i] = embeddingText(p)
+        ids[i] = p.ID
+    }
+
+    var (
+        vectors [][]float32
+        err     error
+    )
+    for attempt := 1; ; attempt++ {
+        vectors, err = embedder.Embeddings(ctx, texts)
+        if err == nil && len(vectors) != len(texts) {
+            err = fmt.Errorf("получено %d эмбеддингов на %d текстов", len(vectors), len(texts))
+        }
+        if err == nil {
+            break
+        }
+        if attempt == reindexAttempts {
+            return fmt.Errorf("ошибка расчёта эмбеддингов товаров: %w", err)
+        }
+        // Клиент OpenAI уже повторил 429 и 5xx; пауза здесь — для лимитов,
+        // которые не успели сброситься за его повторы
+        logging.Logger().Warn("ошибка расчёта эмбеддингов, повторяем партию", "attempt", attempt, "delay", delay, "err", err)
+        select {
+        case <-time.After(delay):
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+        delay *= 2
+    }
+    return q.saveEmbeddings(ctx, ids, vectors)
+}
+
+func (s *CatalogStore) pendingEmbeddings(ctx context.Context, after int64, limit int) ([]Product, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT `+productColumns+` FROM products WHERE embedding IS NULL AND id > $1 ORDER BY id LIMIT $2`,
+        after, limit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения товаров без эмбеддингов: %w", err)
+    }
+    return scanProducts(rows)
+}
+
+func (s *CatalogStore) saveEmbeddings(ctx context.Context, ids []int64, vectors [][]float32) error {
+    literals := make([]string, len(vectors))
+    for i, v := range vectors {
+        literals[i] = vectorLiteral(v)
+    }
+    _, err := s.db.ExecContext(ctx,
+        `UPDATE products p SET embedding = v.embedding::vector
+         FROM unnest($1::bigint[], $2::text[]) AS v(id, embedding)
+         WHERE p.id = v.id`,
+        pq.Array(ids), pq.Array(literals))
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения эмбеддингов товаров %d–%d: %w", ids[0], ids[len(ids)-1], err)
+    }
+    return nil
+}
+
+// perSecond — скорость индексации в товарах в секунду, для логов
+func perSecond(n int, elapsed time.Duration) float64 {
+    if elapsed <= 0 {
+        return 0
+    }
+    return float64(n) / elapsed.Seconds()
+}
+
+// embeddingText — текст товара, по которому считается эмбеддинг
+func embeddingText(p Product) string {
+    return strings.TrimSpace(p.Name + ". " + p.Description)
+}
+
+// vectorLiteral — вектор в текстовом формате pgvector: [0.1,0.2,...]
+func vectorLiteral(v []float32) string {
+    var sb strings.Builder
+    sb.WriteByte('[')
+    for i, x := range v {
+        if i > 0 {
+            sb.WriteByte(',')
+        }
+        sb.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+    }
+    sb.WriteByte(']')
+    return sb.String()
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// memEmbeddings — products.embedding в памяти
+type memEmbeddings struct {
+    mu       sync.Mutex
+    products []Product
+    saved    map[int64][]float32
+}
+
+func newMemEmbeddings(n int) *memEmbeddings {
+    q := &memEmbeddings{saved: make(map[int64][]float32)}
+    for i := 1; i <= n; i++ {
+        q.products = append(q.products, Product{ID: int64(i), Name: fmt.Sprintf("товар %d", i)})
+    }
+    return q
+}
+
+func (q *memEmbeddings) pendingEmbeddings(ctx context.Context, after int64, limit int) ([]Product, error) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    var out []Product
+    for _, p := range q.products {
+        if _, done := q.saved[p.ID]; !done && p.ID > after && len(out) < limit {
+            out = append(out, p)
+        }
+    }
+    return out, nil
+}
+
+func (q *memEmbeddings) saveEmbeddings(ctx context.Context, ids []int64, vectors [][]float32) error {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    for i, id := range ids {
+        q.saved[id] = vectors[i]
+    }
+    return nil
+}
+
+// fakeEmbedder — эмбеддинги длиной 1; fail решает, упадёт ли запрос
+type fakeEmbedder struct {
+    mu    sync.Mutex
+    calls [][]string
+    fail  func(call int, inputs []string) error
+}
+
+func (e *fakeEmbedder) Embeddings(ctx context.Context, inputs []string) ([][]float32, error) {
+    e.mu.Lock()
+    e.calls = append(e.calls, inputs)
+    call := len(e.calls)
+    e.mu.Unlock()
+    if e.fail != nil {
+        if err := e.fail(call, inputs); err != nil {
+            return nil, err
+        }
+    }
+    out := make([][]float32, len(inputs))
+    for i := range inputs {
+        out[i] = []float32{float32(i)}
+    }
+    return out, nil
+}
+
+// sizes — размеры партий по запросам
+func (e *fakeEmbedder) sizes() []int {
+    var out []int
+    for _, c := range e.calls {
+        out = append(out, len(c))
+    }
+    return out
+}
+
+func TestReindexBatches(t *testing.T) {
+    q := newMemEmbeddings(7)
+    e := &fakeEmbedder{}
+
+    res, err := reindex(context.Background(), q, e, 3, time.Millisecond)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := e.sizes(); !slices.Equal(got, []int{3, 3, 1}) {
+        t.Fatalf("партии %v, нужно [3 3 1]", got)
+    }
+    if res.Indexed != 7 || res.Failed != 0 || len(q.saved) != 7 {
+        t.Fatalf("итог %+v, сохранено %d", res, len(q.saved))
+    }
+    if e.calls[0][0] != "товар 1." {
+        t.Fatalf("текст товара %q", e.calls[0][0])
+    }
+}
+
+// Партия, не посчитанная за reindexAttempts попыток, пропускается, а
+// остальные индексируются; следующий запуск берёт только её
+func TestReindexSkipsFailedBatch(t *testing.T) {
+    q := newMemEmbeddings(6)
+    broken := errors.New("503")
+    e := &fakeEmbedder{fail: func(call int, inputs []string) error {
+        if slices.Contains(inputs, "товар 3.") {
+            return broken
+        }
+        return nil
+    }}
+
+    res, err := reindex(context.Background(), q, e, 2, time.Millisecond)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if res.Indexed != 4 || res.Failed != 2 {
+        t.Fatalf("итог %+v, нужно 4 проиндексировано и 2 пропущено", res)
+    }
+    if got := e.sizes(); !slices.Equal(got, []int{2, 2, 2, 2, 2}) {
+        t.Fatalf("запросы %v: сломанная партия должна пробоваться %d раза, остальные — по разу", got, reindexAttempts)
+    }
+    if _, ok := q.saved[3]; ok {
+        t.Fatal("сохранён товар из сломанной партии")
+    }
+
+    e.calls, e.fail = nil, nil
+    res, err = reindex(context.Background(), q, e, 2, time.Millisecond)
+    if err != nil || res.Indexed != 2 || len(e.calls) != 1 || e.calls[0][0] != "товар 3." {
+        t.Fatalf("повторный запуск: %+v, %v, запросы %q", res, err, e.calls)
+    }
+}
+
+// Прерванная индексация продолжается с товаров без эмбеддинга: уже
+// сохранённые партии второй раз в OpenAI не уходят
+func TestReindexResumes(t *testing.T) {
+    q := newMemEmbeddings(10)
+    ctx, cancel := context.WithCancel(context.Background())
+    e := &fakeEmbedder{fail: func(call int, inputs []string) error {
+        if call == 3 {
+            cancel()
+            return ctx.Err()
+        }
+        return nil
+    }}
+
+    if _, err := reindex(ctx, q, e, 4, time.Millisecond); !errors.Is(err, context.Canceled) {
+        t.Fatalf("прерванная индексация: %v", err)
+    }
+    if len(q.saved) != 8 {
+        t.Fatalf("до прерывания сохранено %d товаров, нужно 8", len(q.saved))
+    }
+
+    first := len(e.calls)
+    e.fail = nil
+    res, err := reindex(context.Background(), q, e, 4, time.Millisecond)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resumed := e.calls[first:]
+    if res.Indexed != 2 || len(resumed) != 1 || strings.Join(resumed[0], "|") != "товар 9.|товар 10." {
+        t.Fatalf("после возобновления %+v, запросы %q", res, resumed)
+    }
+}
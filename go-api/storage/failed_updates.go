@@ -0,0 +1,113 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+    "unicode/utf8"
+)
+
+// MaxFailedPayload — предел сохраняемого JSON апдейта; длиннее обрезается
+// и для повтора уже не годится
+const MaxFailedPayload = 64 << 10
+
+// FailedUpdate — апдейт, обработка которого завершилась ошибкой
+type FailedUpdate struct {
+    ID       int64
+    UpdateID int64
+    ChatID   int64
+    Payload  []byte
+    // Truncated — payload обрезан по MaxFailedPayload
+    Truncated bool
+    Error     string
+    CreatedAt time.Time
+}
+
+// FailedUpdateStore — журнал необработанных апдейтов в PostgreSQL
+type FailedUpdateStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewFailedUpdateStore — фабрика журнала необработанных апдейтов
+func NewFailedUpdateStore(db *sql.DB, queryTimeout time.Duration) *FailedUpdateStore {
+    return &FailedUpdateStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Record сохраняет апдейт с ошибкой обработки
+func (s *FailedUpdateStore) Record(ctx context.Context, updateID, chatID int64, payload []byte, cause error) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    truncated := len(payload) > MaxFailedPayload
+    if truncated {
+        payload = truncateUTF8(payload, MaxFailedPayload)
+    }
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO failed_updates (update_id, chat_id, payload, truncated, error) VALUES ($1, $2, $3, $4, $5)`,
+        updateID, chatID, string(payload), truncated, cause.Error())
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения необработанного апдейта %d: %w", updateID, err)
+    }
+    return nil
+}
+
+// truncateUTF8 обрезает b до n байт, не разрезая символ UTF-8: колонка
+// payload текстовая, и PostgreSQL отвергает строку с половиной символа
+func truncateUTF8(b []byte, n int) []byte {
+    if len(b) <= n {
+        return b
+    }
+    for n > 0 && !utf8.RuneStart(b[n]) {
+        n--
+    }
+    return b[:n]
+}
+
+// ListFailed возвращает до limit самых старых необработанных апдейтов
+func (s *FailedUpdateStore) ListFailed(ctx context.Context, limit int) ([]FailedUpdate, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, update_id, chat_id, payload, truncated, error, created_at
+         FROM failed_updates ORDER BY id LIMIT $1`, limit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения необработанных апдейтов: %w", err)
+    }
+    defer rows.Close()
+
+    var failed []FailedUpdate
+    for rows.Next() {
+        var f FailedUpdate
+        var payload string
+        if err := rows.Scan(&f.ID, &f.UpdateID, &f.ChatID, &payload, &f.Truncated, &f.Error, &f.CreatedAt); err != nil {
+            return nil, fmt.Errorf("ошибка чтения необработанного апдейта: %w", err)
+        }
+        f.Payload = []byte(payload)
+        failed = append(failed, f)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения необработанных апдейтов: %w", err)
+    }
+    return failed, nil
+}
+
+// DeleteFailed убирает запись из журнала, например после успешного повтора
+func (s *FailedUpdateStore) DeleteFailed(ctx context.Context, id int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    if _, err := s.db.ExecContext(ctx, `DELETE FROM failed_updates WHERE id = $1`, id); err != nil {
+        return fmt.Errorf("ошибка удаления необработанного апдейта %d: %w", id, err)
+    }
+    return nil
+}
+
+// PruneFailed удаляет записи старше retention и возвращает их число
+func (s *FailedUpdateStore) PruneFailed(ctx context.Context, retention time.Duration) (int64, error) {
+    res, err := s.db.ExecContext(ctx,
+        `DELETE FROM failed_updates WHERE created_at < $1`, time.Now().Add(-retention))
+    if err != nil {
+        return 0, fmt.Errorf("ошибка очистки журнала необработанных апдейтов: %w", err)
+    }
+    return res.RowsAffected()
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+func TestTruncateUTF8KeepsWholeRunes(t *testing.T) {
+    payload := []byte(`{"text":"` + strings.Repeat("ж", 10) + `"}`)
+    for n := 0; n <= len(payload); n++ {
+        got := truncateUTF8(payload, n)
+        if len(got) > n {
+            t.Fatalf("n=%d: длина %d больше предела", n, len(got))
+        }
+        if !utf8.Valid(got) {
+            t.Fatalf("n=%d: обрезано посреди символа: %q", n, got)
+        }
+        if n-len(got) >= utf8.UTFMax {
+            t.Fatalf("n=%d: отрезано лишнее, осталось %d байт", n, len(got))
+        }
+    }
+    if got := truncateUTF8(payload, len(payload)+10); string(got) != string(payload) {
+        t.Fatalf("короткий payload изменён: %q", got)
+    }
+}
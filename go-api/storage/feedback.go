@@ -0,0 +1,64 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// Оценки ответа бота
+const (
+    RatingUp   = 1
+    RatingDown = -1
+)
+
+// FeedbackStats — сводка оценок ответов
+type FeedbackStats struct {
+    Up   int64
+    Down int64
+}
+
+// FeedbackStore — оценки ответов бота в PostgreSQL
+type FeedbackStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewFeedbackStore — фабрика хранилища оценок
+func NewFeedbackStore(db *sql.DB, queryTimeout time.Duration) *FeedbackStore {
+    return &FeedbackStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Record сохраняет оценку сообщения бота с вариантом промпта, закреплённым
+// за чатом; повторная оценка того же сообщения заменяет прежнюю
+func (s *FeedbackStore) Record(ctx context.Context, chatID, messageID int64, rating int) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    if rating != RatingUp && rating != RatingDown {
+        return fmt.Errorf("оценка должна быть %d или %d, получено %d", RatingUp, RatingDown, rating)
+    }
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO feedback (chat_id, message_id, rating, prompt_variant)
+         VALUES ($1, $2, $3, coalesce((SELECT prompt_variant FROM users WHERE chat_id = $1), ''))
+         ON CONFLICT (chat_id, message_id) DO UPDATE SET rating = EXCLUDED.rating, updated_at = now()`,
+        chatID, messageID, rating)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения оценки: %w", err)
+    }
+    return nil
+}
+
+// Stats считает положительные и отрицательные оценки за всё время
+func (s *FeedbackStore) Stats(ctx context.Context) (FeedbackStats, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var st FeedbackStats
+    err := s.db.QueryRowContext(ctx,
+        `SELECT count(*) FILTER (WHERE rating > 0), count(*) FILTER (WHERE rating < 0) FROM feedback`).
+        Scan(&st.Up, &st.Down)
+    if err != nil {
+        return FeedbackStats{}, fmt.Errorf("ошибка подсчёта оценок: %w", err)
+    }
+    return st, nil
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "ai_seller/apperr"
+    "ai_seller/breaker"
+    "ai_seller/money"
+
+    "github.com/lib/pq"
+    "modernc.org/sqlite"
+    sqlite3 "modernc.org/sqlite/lib"
+)
+
+// queryCanceled — SQLSTATE отменённого запроса (statement_timeout или отмена клиентом)
+const queryCanceled = "57014"
+
+// NewDBBreaker — предохранитель PostgreSQL: сбоем считаются только ошибки
+// связи с базой, а не «не найдено» или нарушение ограничений
+func NewDBBreaker(threshold int, cooldown time.Duration) *breaker.Breaker {
+    b := breaker.New("postgres", threshold, cooldown)
+    b.IsFailure = dbUnavailable
+    return b
+}
+
+// dbUnavailable — ошибка означает, что база не ответила или не может
+// обслуживать запросы (классы SQLSTATE 08 — соединение, 53 — ресурсы,
+// 57 — остановка сервера; в SQLite — занятая или недоступная на запись база,
+// ошибки диска). Прочие ответы сервера — сбой запроса, а не базы.
+// Истёкший таймаут запроса и отмена (в том числе query_canceled, которым
+// сервер отвечает на отмену по ctx) — тоже свойство запроса: несколько
+// медленных запросов не должны размыкать предохранитель для всех.
+func dbUnavailable(err error) bool {
+    if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrOutOfStock) ||
+        errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+        return false
+    }
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) {
+        if pqErr.Code == queryCanceled {
+            return false
+        }
+        switch pqErr.Code.Class() {
+        case "08", "53", "57":
+            return true
+        }
+        return false
+    }
+    var sqErr *sqlite.Error
+    if errors.As(err, &sqErr) {
+        // Младший байт — основной код, старшие уточняют его
+        switch sqErr.Code() & 0xff {
+        case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_NOMEM, sqlite3.SQLITE_READONLY,
+            sqlite3.SQLITE_IOERR, sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN:
+            return true
+        }
+        return false
+    }
+    return true
+}
+
+// guard выполняет вызов хранилища через предохранитель; отказ предохранителя
+// помечается как недоступность внешнего сервиса
+func guard[T any](b *breaker.Breaker, fn func() (T, error)) (T, error) {
+    out, err := breaker.Call(b, fn)
+    if errors.Is(err, breaker.ErrOpen) {
+        err = apperr.Upstream(err)
+    }
+    return out, err
+}
+
+func guardErr(b *breaker.Breaker, fn func() error) error {
+    _, err := guard(b, func() (struct{}, error) { return struct{}{}, fn() })
+    return err
+}
+
+// GuardedMessages — MessageStore за предохранителем PostgreSQL
+type GuardedMessages struct {
+    *MessageStore
+    Breaker *breaker.Breaker
+}
+
+func (g GuardedMessages) SaveMessage(ctx context.Context, chatID int64, role, text string) error {
+    return guardErr(g.Breaker, func() error { return g.MessageStore.SaveMessage(ctx, chatID, role, text) })
+}
+
+func (g GuardedMessages) SaveIncompleteMessage(ctx context.Context, chatID int64, role, text string) error {
+    return guardErr(g.Breaker, func() error { return g.MessageStore.SaveIncompleteMessage(ctx, chatID, role, text) })
+}
+
+func (g GuardedMessages) HasMessages(ctx context.Context, chatID int64) (bool, error) {
+    return guard(g.Breaker, func() (bool, error) { return g.MessageStore.HasMessages(ctx, chatID) })
+}
+
+func (g GuardedMessages) CountMessages(ctx context.Context, chatID int64) (int, error) {
+    return guard(g.Breaker, func() (int, error) { return g.MessageStore.CountMessages(ctx, chatID) })
+}
+
+func (g GuardedMessages) ArchiveHistory(ctx context.Context, chatID int64) error {
+    return guardErr(g.Breaker, func() error { return g.MessageStore.ArchiveHistory(ctx, chatID) })
+}
+
+func (g GuardedMessages) GetHistory(ctx context.Context, chatID int64, limit int) ([]Message, error) {
+    return guard(g.Breaker, func() ([]Message, error) { return g.MessageStore.GetHistory(ctx, chatID, limit) })
+}
+
+func (g GuardedMessages) Unsummarized(ctx context.Context, chatID, after int64, keep, limit int) ([]Message, error) {
+    return guard(g.Breaker, func() ([]Message, error) {
+        return g.MessageStore.Unsummarized(ctx, chatID, after, keep, limit)
+    })
+}
+
+// GuardedSummaries — SummaryStore за предохранителем PostgreSQL
+type GuardedSummaries struct {
+    *SummaryStore
+    Breaker *breaker.Breaker
+}
+
+func (g GuardedSummaries) GetSummary(ctx context.Context, chatID int64) (Summary, error) {
+    return guard(g.Breaker, func() (Summary, error) { return g.SummaryStore.GetSummary(ctx, chatID) })
+}
+
+func (g GuardedSummaries) SaveSummary(ctx context.Context, chatID int64, text string, upTo int64) error {
+    return guardErr(g.Breaker, func() error { return g.SummaryStore.SaveSummary(ctx, chatID, text, upTo) })
+}
+
+// GuardedUsers — UserStore за предохранителем PostgreSQL
+type GuardedUsers struct {
+    *UserStore
+    Breaker *breaker.Breaker
+}
+
+func (g GuardedUsers) Upsert(ctx context.Context, chatID int64, username, lang, name string) error {
+    return guardErr(g.Breaker, func() error { return g.UserStore.Upsert(ctx, chatID, username, lang, name) })
+}
+
+func (g GuardedUsers) SetBrief(ctx context.Context, chatID int64, brief bool) error {
+    return guardErr(g.Breaker, func() error { return g.UserStore.SetBrief(ctx, chatID, brief) })
+}
+
+func (g GuardedUsers) SetCurrency(ctx context.Context, chatID int64, currency string) error {
+    return guardErr(g.Breaker, func() error { return g.UserStore.SetCurrency(ctx, chatID, currency) })
+}
+
+func (g GuardedUsers) SetLang(ctx context.Context, chatID int64, lang string) error {
+    return guardErr(g.Breaker, func() error { return g.UserStore.SetLang(ctx, chatID, lang) })
+}
+
+func (g GuardedUsers) SetPromptVariant(ctx context.Context, chatID int64, variant string) error {
+    return guardErr(g.Breaker, func() error { return g.UserStore.SetPromptVariant(ctx, chatID, variant) })
+}
+
+func (g GuardedUsers) SetMenuHidden(ctx context.Context, chatID int64, hidden bool) error {
+    return guardErr(g.Breaker, func() error { return g.UserStore.SetMenuHidden(ctx, chatID, hidden) })
+}
+
+func (g GuardedUsers) GetUser(ctx context.Context, chatID int64) (User, error) {
+    return guard(g.Breaker, func() (User, error) { return g.UserStore.GetUser(ctx, chatID) })
+}
+
+// GuardedOrders — OrderStore за предохранителем PostgreSQL
+type GuardedOrders struct {
+    *OrderStore
+    Breaker *breaker.Breaker
+}
+
+func (g GuardedOrders) CreateOrder(ctx context.Context, chatID int64, items []OrderItem, total money.Money, idempotencyKey string, window time.Duration) (int64, error) {
+    return guard(g.Breaker, func() (int64, error) {
+        return g.OrderStore.CreateOrder(ctx, chatID, items, total, idempotencyKey, window)
+    })
+}
+
+func (g GuardedOrders) GetOrder(ctx context.Context, orderID, chatID int64) (Order, error) {
+    return guard(g.Breaker, func() (Order, error) { return g.OrderStore.GetOrder(ctx, orderID, chatID) })
+}
+
+func (g GuardedOrders) ChatOrders(ctx context.Context, chatID int64) ([]Order, error) {
+    return guard(g.Breaker, func() ([]Order, error) { return g.OrderStore.ChatOrders(ctx, chatID) })
+}
+
+func (g GuardedOrders) OrderState(ctx context.Context, orderID int64) (OrderState, error) {
+    return guard(g.Breaker, func() (OrderState, error) { return g.OrderStore.OrderState(ctx, orderID) })
+}
+
+func (g GuardedOrders) SetStatus(ctx context.Context, orderID int64, to OrderStatus, notice string) error {
+    return guardErr(g.Breaker, func() error { return g.OrderStore.SetStatus(ctx, orderID, to, notice) })
+}
+
+func (g GuardedOrders) ConfirmOrder(ctx context.Context, orderID int64) error {
+    return guardErr(g.Breaker, func() error { return g.OrderStore.ConfirmOrder(ctx, orderID) })
+}
+
+func (g GuardedOrders) LatestDraft(ctx context.Context, chatID int64, since time.Time) (Order, error) {
+    return guard(g.Breaker, func() (Order, error) { return g.OrderStore.LatestDraft(ctx, chatID, since) })
+}
+
+func (g GuardedOrders) DiscardDraft(ctx context.Context, orderID int64) error {
+    return guardErr(g.Breaker, func() error { return g.OrderStore.DiscardDraft(ctx, orderID) })
+}
+
+func (g GuardedOrders) OrdersBetween(ctx context.Context, from, to time.Time) ([]OrderSummary, error) {
+    return guard(g.Breaker, func() ([]OrderSummary, error) { return g.OrderStore.OrdersBetween(ctx, from, to) })
+}
+
+// GuardedFeedback — FeedbackStore за предохранителем PostgreSQL
+type GuardedFeedback struct {
+    *FeedbackStore
+    Breaker *breaker.Breaker
+}
+
+func (g GuardedFeedback) Record(ctx context.Context, chatID, messageID int64, rating int) error {
+    return guardErr(g.Breaker, func() error { return g.FeedbackStore.Record(ctx, chatID, messageID, rating) })
+}
+
+func (g GuardedFeedback) Stats(ctx context.Context) (FeedbackStats, error) {
+    return guard(g.Breaker, func() (FeedbackStats, error) { return g.FeedbackStore.Stats(ctx) })
+}
+
+// GuardedAttributions — AttributionStore за предохранителем PostgreSQL
+type GuardedAttributions struct {
+    *AttributionStore
+    Breaker *breaker.Breaker
+}
+
+func (g GuardedAttributions) Record(ctx context.Context, chatID int64, a Attribution) (bool, error) {
+    return guard(g.Breaker, func() (bool, error) { return g.AttributionStore.Record(ctx, chatID, a) })
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+
+    "github.com/lib/pq"
+)
+
+func TestDBUnavailable(t *testing.T) {
+    for _, tc := range []struct {
+        name string
+        err  error
+        want bool
+    }{
+        {"нет связи", errors.New("dial tcp: connection refused"), true},
+        {"соединение оборвано", &pq.Error{Code: "08006"}, true},
+        {"сервер останавливается", &pq.Error{Code: "57P01"}, true},
+        {"слишком много подключений", &pq.Error{Code: "53300"}, true},
+        {"не найдено", fmt.Errorf("заказ: %w", ErrNotFound), false},
+        {"нарушение ограничения", &pq.Error{Code: "23505"}, false},
+        {"отмена", context.Canceled, false},
+        {"таймаут запроса", fmt.Errorf("ошибка чтения: %w", context.DeadlineExceeded), false},
+        {"запрос отменён сервером", &pq.Error{Code: queryCanceled}, false},
+    } {
+        if got := dbUnavailable(tc.err); got != tc.want {
+            t.Errorf("%s: dbUnavailable = %v, нужно %v", tc.name, got, tc.want)
+        }
+    }
+}
+
+// Медленные запросы не размыкают предохранитель PostgreSQL
+func TestDBBreakerIgnoresTimeouts(t *testing.T) {
+    b := NewDBBreaker(2, 0)
+    for range 5 {
+        _ = b.Do(func() error { return context.DeadlineExceeded })
+    }
+    if err := b.Do(func() error { return nil }); err != nil {
+        t.Fatalf("после таймаутов предохранитель отклонил вызов: %v", err)
+    }
+}
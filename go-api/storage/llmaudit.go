@@ -0,0 +1,69 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "time"
+)
+
+// AuditMessage — сообщение запроса в журнале аудита
+type AuditMessage struct {
+    Role    string `json:"role"`
+    Content string `json:"content"`
+}
+
+// LLMAuditEntry — запись журнала запросов к модели
+type LLMAuditEntry struct {
+    // ChatID — чат, по которому шёл запрос; 0 — фоновая задача
+    ChatID           int64
+    TraceID          string
+    Model            string
+    Messages         []AuditMessage
+    Response         string
+    Error            string
+    PromptTokens     int64
+    CompletionTokens int64
+    Latency          time.Duration
+}
+
+// LLMAuditStore — журнал запросов к модели в PostgreSQL
+type LLMAuditStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewLLMAuditStore — фабрика журнала запросов к модели
+func NewLLMAuditStore(db *sql.DB, queryTimeout time.Duration) *LLMAuditStore {
+    return &LLMAuditStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Record сохраняет запись журнала; тексты записываются как есть —
+// личные данные скрывает вызывающий
+func (s *LLMAuditStore) Record(ctx context.Context, e LLMAuditEntry) error {
+    messages, err := json.Marshal(e.Messages)
+    if err != nil {
+        return fmt.Errorf("ошибка сериализации сообщений для аудита: %w", err)
+    }
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err = s.db.ExecContext(ctx,
+        `INSERT INTO llm_audit (chat_id, trace_id, model, messages, response, error, prompt_tokens, completion_tokens, latency_ms)
+         VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9)`,
+        e.ChatID, e.TraceID, e.Model, string(messages), e.Response, e.Error, e.PromptTokens, e.CompletionTokens, e.Latency.Milliseconds())
+    if err != nil {
+        return fmt.Errorf("ошибка записи журнала запросов к модели: %w", err)
+    }
+    return nil
+}
+
+// Prune удаляет записи старше retention и возвращает их число
+func (s *LLMAuditStore) Prune(ctx context.Context, retention time.Duration) (int64, error) {
+    res, err := s.db.ExecContext(ctx,
+        `DELETE FROM llm_audit WHERE created_at < $1`, time.Now().Add(-retention))
+    if err != nil {
+        return 0, fmt.Errorf("ошибка очистки журнала запросов к модели: %w", err)
+    }
+    return res.RowsAffected()
+}
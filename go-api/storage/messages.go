@@ -0,0 +1,174 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// Message — сохранённое сообщение диалога
+type Message struct {
+    ID        int64
+    Role      string
+    Content   string
+    CreatedAt time.Time
+    // Incomplete — ответ модели оборвался на середине потока
+    Incomplete bool
+}
+
+// MessageStore — хранилище истории сообщений в PostgreSQL
+type MessageStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewMessageStore — фабрика хранилища сообщений
+func NewMessageStore(db *sql.DB, queryTimeout time.Duration) *MessageStore {
+    return &MessageStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// SaveMessage сохраняет сообщение чата с указанной ролью (user/assistant)
+func (s *MessageStore) SaveMessage(ctx context.Context, chatID int64, role, text string) error {
+    return s.save(ctx, chatID, role, text, false)
+}
+
+// SaveIncompleteMessage сохраняет сообщение, помеченное незавершённым:
+// ответ модели, поток которого оборвался
+func (s *MessageStore) SaveIncompleteMessage(ctx context.Context, chatID int64, role, text string) error {
+    return s.save(ctx, chatID, role, text, true)
+}
+
+// save сохраняет сообщение с вариантом промпта, закреплённым за чатом
+func (s *MessageStore) save(ctx context.Context, chatID int64, role, text string, incomplete bool) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO messages (chat_id, role, content, incomplete, prompt_variant)
+         VALUES ($1, $2, $3, $4, coalesce((SELECT prompt_variant FROM users WHERE chat_id = $1), ''))`,
+        chatID, role, text, incomplete)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения сообщения: %w", err)
+    }
+    return nil
+}
+
+// HasMessages — писал ли чат когда-нибудь, включая архивную историю
+func (s *MessageStore) HasMessages(ctx context.Context, chatID int64) (bool, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var exists bool
+    err := s.db.QueryRowContext(ctx,
+        `SELECT EXISTS (SELECT 1 FROM messages WHERE chat_id = $1)`, chatID).Scan(&exists)
+    if err != nil {
+        return false, fmt.Errorf("ошибка проверки истории чата: %w", err)
+    }
+    return exists, nil
+}
+
+// CountMessages — сколько неархивных сообщений чата хранится, то есть
+// сколько истории доступно модели до свёртки в сводку
+func (s *MessageStore) CountMessages(ctx context.Context, chatID int64) (int, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var n int
+    err := s.db.QueryRowContext(ctx,
+        `SELECT count(*) FROM messages WHERE chat_id = $1 AND archived_at IS NULL`, chatID).Scan(&n)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка подсчёта сообщений чата: %w", err)
+    }
+    return n, nil
+}
+
+// ArchiveHistory убирает сообщения чата из контекста модели, не удаляя их.
+// Сводка архивной истории удаляется вместе с ней, в той же транзакции.
+func (s *MessageStore) ArchiveHistory(ctx context.Context, chatID int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, `DELETE FROM chat_summaries WHERE chat_id = $1`, chatID); err != nil {
+        return fmt.Errorf("ошибка удаления сводки истории: %w", err)
+    }
+    _, err = tx.ExecContext(ctx,
+        `UPDATE messages SET archived_at = now() WHERE chat_id = $1 AND archived_at IS NULL`, chatID)
+    if err != nil {
+        return fmt.Errorf("ошибка архивации истории: %w", err)
+    }
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("ошибка архивации истории: %w", err)
+    }
+    return nil
+}
+
+// GetHistory возвращает последние limit неархивных сообщений чата, от старых к новым
+func (s *MessageStore) GetHistory(ctx context.Context, chatID int64, limit int) ([]Message, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, role, content, created_at, incomplete FROM messages
+         WHERE chat_id = $1 AND archived_at IS NULL
+         ORDER BY created_at DESC, id DESC
+         LIMIT $2`,
+        chatID, limit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения истории: %w", err)
+    }
+    defer rows.Close()
+
+    var history []Message
+    for rows.Next() {
+        var m Message
+        if err := rows.Scan(&m.ID, &m.Role, &m.Content, &m.CreatedAt, &m.Incomplete); err != nil {
+            return nil, fmt.Errorf("ошибка чтения истории: %w", err)
+        }
+        history = append(history, m)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения истории: %w", err)
+    }
+
+    // Выбирали с конца, а модели нужен хронологический порядок
+    for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+        history[i], history[j] = history[j], history[i]
+    }
+    return history, nil
+}
+
+// Unsummarized возвращает до limit неархивных сообщений чата с id больше
+// after, кроме keep самых новых — их модель и так видит целиком.
+// Сообщения идут от старых к новым.
+func (s *MessageStore) Unsummarized(ctx context.Context, chatID, after int64, keep, limit int) ([]Message, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, role, content, created_at, incomplete FROM messages
+         WHERE chat_id = $1 AND archived_at IS NULL AND id > $2
+           AND id < (SELECT coalesce(min(id), 0) FROM (
+               SELECT id FROM messages WHERE chat_id = $1 AND archived_at IS NULL
+               ORDER BY id DESC LIMIT $3) recent)
+         ORDER BY id
+         LIMIT $4`,
+        chatID, after, keep, limit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения истории для сводки: %w", err)
+    }
+    defer rows.Close()
+
+    var messages []Message
+    for rows.Next() {
+        var m Message
+        if err := rows.Scan(&m.ID, &m.Role, &m.Content, &m.CreatedAt, &m.Incomplete); err != nil {
+            return nil, fmt.Errorf("ошибка чтения истории для сводки: %w", err)
+        }
+        messages = append(messages, m)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения истории для сводки: %w", err)
+    }
+    return messages, nil
+}
@@ -0,0 +1,531 @@
+package storage
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "ai_seller/money"
+)
+
+// OrderStatus — статус заказа; допустимые переходы хранятся в таблице
+// order_status_transitions
+type OrderStatus string
+
+const (
+    // OrderDraft — заказ создан, но оформление не завершено: остатки уже
+    // списаны, корзина могла быть не очищена. ConfirmOrder переводит его в OrderNew.
+    OrderDraft     OrderStatus = "draft"
+    OrderNew       OrderStatus = "new"
+    OrderPaid      OrderStatus = "paid"
+    OrderShipped   OrderStatus = "shipped"
+    OrderCancelled OrderStatus = "cancelled"
+)
+
+// ErrInvalidTransition — из текущего статуса заказа в запрошенный перейти нельзя
+var ErrInvalidTransition = errors.New("недопустимая смена статуса заказа")
+
+// ErrOutOfStock — товара не хватает на складе; подробности — в OutOfStockError
+var ErrOutOfStock = errors.New("товара нет в нужном количестве")
+
+// OutOfStockError — заказ не создан: этих товаров нет в нужном количестве
+// (закончились, сняты с продажи или удалены из каталога)
+type OutOfStockError struct {
+    ProductIDs []int64
+}
+
+func (e *OutOfStockError) Error() string {
+    return fmt.Sprintf("%s: товары %v", ErrOutOfStock, e.ProductIDs)
+}
+
+func (e *OutOfStockError) Unwrap() error {
+    return ErrOutOfStock
+}
+
+// OrderItem — позиция заказа; цена фиксируется на момент оформления
+type OrderItem struct {
+    ProductID int64
+    Qty       int
+    // Price — цена единицы; валюта у всех позиций та же, что у заказа
+    Price money.Money
+    // Name — название товара; заполняется при чтении заказа, пусто, если товар удалён
+    Name string
+}
+
+// Order — оформленный заказ
+type Order struct {
+    ID        int64
+    ChatID    int64
+    Total     money.Money
+    Status    OrderStatus
+    CreatedAt time.Time
+    Items     []OrderItem
+}
+
+// OrderSummary — заказ без позиций: для выгрузок и сводок
+type OrderSummary struct {
+    ID        int64
+    Total     money.Money
+    Status    OrderStatus
+    CreatedAt time.Time
+}
+
+// OrderStore — заказы в PostgreSQL
+type OrderStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewOrderStore — фабрика хранилища заказов
+func NewOrderStore(db *sql.DB, queryTimeout time.Duration) *OrderStore {
+    return &OrderStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// IdempotencyKey — ключ заказа из чата и состава корзины. Времени в ключе
+// нет: окно, в котором повтор считается тем же заказом, задаёт CreateOrder,
+// поэтому два нажатия с разницей в секунду не разойдутся по соседним окнам.
+func IdempotencyKey(chatID int64, items []OrderItem) string {
+    sorted := append([]OrderItem(nil), items...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+
+    h := sha256.New()
+    fmt.Fprintf(h, "%d", chatID)
+    for _, item := range sorted {
+        fmt.Fprintf(h, "|%d:%d:%d", item.ProductID, item.Qty, item.Price.Minor)
+    }
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// CreateOrder сохраняет заказ черновиком (OrderDraft) вместе с позициями
+// в одной транзакции и в ней же списывает остатки. Если какого-то товара не хватает, заказ не создаётся
+// и возвращается *OutOfStockError со всеми такими товарами.
+// Если неотменённый заказ с таким idempotencyKey создан не раньше window
+// назад (повторное нажатие /checkout), новый не создаётся — возвращается id существующего.
+// Проверка и вставка идут под блокировкой ключа, поэтому два одновременных
+// нажатия тоже дают один заказ.
+func (s *OrderStore) CreateOrder(ctx context.Context, chatID int64, items []OrderItem, total money.Money, idempotencyKey string, window time.Duration) (int64, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    return s.insertOrder(ctx, chatID, items, total, idempotencyKey, window)
+}
+
+// GetOrder возвращает заказ с позициями. Поиск ограничен чатом chatID:
+// чужой заказ неотличим от несуществующего — ErrNotFound.
+func (s *OrderStore) GetOrder(ctx context.Context, orderID, chatID int64) (Order, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    o := Order{ID: orderID, ChatID: chatID}
+    err := s.db.QueryRowContext(ctx,
+        `SELECT total, currency, status, created_at FROM orders WHERE id = $1 AND chat_id = $2`,
+        orderID, chatID).Scan(&o.Total.Minor, &o.Total.Currency, &o.Status, &o.CreatedAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return Order{}, ErrNotFound
+    }
+    if err != nil {
+        return Order{}, fmt.Errorf("ошибка чтения заказа %d: %w", orderID, err)
+    }
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT i.product_id, i.qty, i.price, COALESCE(p.name, '')
+         FROM order_items i LEFT JOIN products p ON p.id = i.product_id
+         WHERE i.order_id = $1
+         ORDER BY i.product_id`,
+        orderID)
+    if err != nil {
+        return Order{}, fmt.Errorf("ошибка чтения позиций заказа %d: %w", orderID, err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        item := OrderItem{Price: money.Money{Currency: o.Total.Currency}}
+        if err := rows.Scan(&item.ProductID, &item.Qty, &item.Price.Minor, &item.Name); err != nil {
+            return Order{}, fmt.Errorf("ошибка чтения позиции заказа: %w", err)
+        }
+        o.Items = append(o.Items, item)
+    }
+    if err := rows.Err(); err != nil {
+        return Order{}, fmt.Errorf("ошибка чтения позиций заказа %d: %w", orderID, err)
+    }
+    return o, nil
+}
+
+// ChatOrders возвращает все заказы чата с позициями, по порядку создания
+func (s *OrderStore) ChatOrders(ctx context.Context, chatID int64) ([]Order, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT o.id, o.total, o.currency, o.status, o.created_at, i.product_id, i.qty, i.price, COALESCE(p.name, '')
+         FROM orders o
+         JOIN order_items i ON i.order_id = o.id
+         LEFT JOIN products p ON p.id = i.product_id
+         WHERE o.chat_id = $1
+         ORDER BY o.id, i.product_id`,
+        chatID)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка выборки заказов чата: %w", err)
+    }
+    defer rows.Close()
+
+    var out []Order
+    for rows.Next() {
+        var (
+            o    = Order{ChatID: chatID}
+            item OrderItem
+        )
+        err := rows.Scan(&o.ID, &o.Total.Minor, &o.Total.Currency, &o.Status, &o.CreatedAt,
+            &item.ProductID, &item.Qty, &item.Price.Minor, &item.Name)
+        if err != nil {
+            return nil, fmt.Errorf("ошибка чтения заказа: %w", err)
+        }
+        item.Price.Currency = o.Total.Currency
+        // Строки одного заказа идут подряд: ORDER BY o.id
+        if n := len(out); n > 0 && out[n-1].ID == o.ID {
+            out[n-1].Items = append(out[n-1].Items, item)
+            continue
+        }
+        o.Items = []OrderItem{item}
+        out = append(out, o)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка выборки заказов чата: %w", err)
+    }
+    return out, nil
+}
+
+// OrderState — чат, текущий статус и сумма заказа: то, с чем сверяется
+// уведомление об оплате
+type OrderState struct {
+    ChatID int64
+    Status OrderStatus
+    Total  money.Money
+}
+
+// OrderState возвращает состояние заказа или ErrNotFound
+func (s *OrderStore) OrderState(ctx context.Context, orderID int64) (OrderState, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var st OrderState
+    err := s.db.QueryRowContext(ctx,
+        `SELECT chat_id, status, total, currency FROM orders WHERE id = $1`, orderID).
+        Scan(&st.ChatID, &st.Status, &st.Total.Minor, &st.Total.Currency)
+    if errors.Is(err, sql.ErrNoRows) {
+        return OrderState{}, ErrNotFound
+    }
+    if err != nil {
+        return OrderState{}, fmt.Errorf("ошибка чтения заказа %d: %w", orderID, err)
+    }
+    return st, nil
+}
+
+// SetStatus переводит заказ в статус to, если переход разрешён таблицей
+// order_status_transitions. Проверка и смена — один UPDATE, поэтому
+// параллельные смены статуса не проходят в обход правил.
+// Непустой notice ставится в outbox уведомлением покупателю в той же
+// транзакции: статус и сообщение либо сохраняются вместе, либо не
+// сохраняются вовсе, и падение процесса после смены статуса не теряет
+// уведомление. Ключ StatusNoticeKey не даёт поставить его дважды.
+func (s *OrderStore) SetStatus(ctx context.Context, orderID int64, to OrderStatus, notice string) (err error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer func() {
+        if err != nil {
+            tx.Rollback()
+        }
+    }()
+
+    var chatID int64
+    err = tx.QueryRowContext(ctx,
+        `UPDATE orders SET status = $2
+         WHERE id = $1 AND EXISTS (
+             SELECT 1 FROM order_status_transitions t
+             WHERE t.from_status = orders.status AND t.to_status = $2
+         )
+         RETURNING chat_id`,
+        orderID, string(to)).Scan(&chatID)
+    if errors.Is(err, sql.ErrNoRows) {
+        return transitionError(ctx, tx, orderID, to)
+    }
+    if err != nil {
+        return fmt.Errorf("ошибка смены статуса заказа %d: %w", orderID, err)
+    }
+
+    if notice != "" {
+        _, err = tx.ExecContext(ctx,
+            `INSERT INTO outbox (chat_id, text, dedupe_key) VALUES ($1, $2, $3)
+             ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING`,
+            chatID, notice, StatusNoticeKey(orderID, to))
+        if err != nil {
+            return fmt.Errorf("ошибка постановки уведомления о заказе %d: %w", orderID, err)
+        }
+    }
+
+    if err = tx.Commit(); err != nil {
+        return fmt.Errorf("ошибка фиксации статуса заказа %d: %w", orderID, err)
+    }
+    return nil
+}
+
+// ConfirmOrder завершает оформление: черновик становится новым заказом.
+// Уже подтверждённый заказ (повторное нажатие /checkout) не ошибка;
+// отменённый — ErrInvalidTransition, несуществующий — ErrNotFound.
+func (s *OrderStore) ConfirmOrder(ctx context.Context, orderID int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    res, err := s.db.ExecContext(ctx,
+        `UPDATE orders SET status = $2 WHERE id = $1 AND status = $3`,
+        orderID, string(OrderNew), string(OrderDraft))
+    if err != nil {
+        return fmt.Errorf("ошибка подтверждения заказа %d: %w", orderID, err)
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return fmt.Errorf("ошибка подтверждения заказа %d: %w", orderID, err)
+    }
+    if n > 0 {
+        return nil
+    }
+
+    var current OrderStatus
+    err = s.db.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1`, orderID).Scan(&current)
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrNotFound
+    }
+    if err != nil {
+        return fmt.Errorf("ошибка чтения статуса заказа %d: %w", orderID, err)
+    }
+    if current == OrderCancelled {
+        return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, current, OrderNew)
+    }
+    return nil
+}
+
+// LatestDraft возвращает последний черновик чата, созданный не раньше since,
+// с позициями; ErrNotFound — такого нет
+func (s *OrderStore) LatestDraft(ctx context.Context, chatID int64, since time.Time) (Order, error) {
+    var orderID int64
+    err := func() error {
+        ctx, cancel := s.withQueryTimeout(ctx)
+        defer cancel()
+        return s.db.QueryRowContext(ctx,
+            `SELECT id FROM orders
+             WHERE chat_id = $1 AND status = $2 AND created_at >= $3
+             ORDER BY created_at DESC, id DESC LIMIT 1`,
+            chatID, string(OrderDraft), since).Scan(&orderID)
+    }()
+    if errors.Is(err, sql.ErrNoRows) {
+        return Order{}, ErrNotFound
+    }
+    if err != nil {
+        return Order{}, fmt.Errorf("ошибка поиска черновика заказа: %w", err)
+    }
+    return s.GetOrder(ctx, orderID, chatID)
+}
+
+// DiscardDraft отменяет черновик и возвращает его позиции на склад одной
+// транзакцией; ErrNotFound — заказа нет или он уже не черновик
+func (s *OrderStore) DiscardDraft(ctx context.Context, orderID int64) (err error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer func() {
+        if err != nil {
+            tx.Rollback()
+        }
+    }()
+
+    res, err := tx.ExecContext(ctx,
+        `UPDATE orders SET status = $2 WHERE id = $1 AND status = $3`,
+        orderID, string(OrderCancelled), string(OrderDraft))
+    if err != nil {
+        return fmt.Errorf("ошибка отмены черновика заказа %d: %w", orderID, err)
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return fmt.Errorf("ошибка отмены черновика заказа %d: %w", orderID, err)
+    }
+    if n == 0 {
+        return ErrNotFound
+    }
+
+    // Товар, удалённый из каталога, вернуть некуда — UPDATE его просто не найдёт
+    _, err = tx.ExecContext(ctx,
+        `UPDATE products SET
+             stock = stock + i.qty,
+             in_stock = CASE WHEN products.stock IS NULL THEN products.in_stock ELSE true END
+         FROM order_items i
+         WHERE i.order_id = $1 AND products.id = i.product_id`,
+        orderID)
+    if err != nil {
+        return fmt.Errorf("ошибка возврата остатков заказа %d: %w", orderID, err)
+    }
+
+    if err = tx.Commit(); err != nil {
+        return fmt.Errorf("ошибка фиксации отмены черновика %d: %w", orderID, err)
+    }
+    return nil
+}
+
+// StatusNoticeKey — ключ дедупликации уведомления о переходе заказа в статус to
+func StatusNoticeKey(orderID int64, to OrderStatus) string {
+    return fmt.Sprintf("order:%d:%s", orderID, to)
+}
+
+// transitionError объясняет, почему UPDATE статуса не затронул заказ:
+// его нет (ErrNotFound) или переход запрещён (ErrInvalidTransition)
+func transitionError(ctx context.Context, tx *sql.Tx, orderID int64, to OrderStatus) error {
+    var current OrderStatus
+    err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1`, orderID).Scan(&current)
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrNotFound
+    }
+    if err != nil {
+        return fmt.Errorf("ошибка чтения статуса заказа %d: %w", orderID, err)
+    }
+    return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, current, to)
+}
+
+// recentOrderByKey — id неотменённого заказа с ключом идемпотентности,
+// созданного за последние window; 0 — такого нет
+func recentOrderByKey(ctx context.Context, tx *sql.Tx, idempotencyKey string, window time.Duration) (int64, error) {
+    var orderID int64
+    err := tx.QueryRowContext(ctx,
+        `SELECT id FROM orders
+         WHERE idempotency_key = $1 AND created_at > $2 AND status <> $3
+         ORDER BY created_at DESC LIMIT 1`,
+        idempotencyKey, time.Now().Add(-window), string(OrderCancelled)).Scan(&orderID)
+    if errors.Is(err, sql.ErrNoRows) {
+        return 0, nil
+    }
+    if err != nil {
+        return 0, fmt.Errorf("ошибка чтения существующего заказа: %w", err)
+    }
+    return orderID, nil
+}
+
+// insertOrder — транзакция создания заказа
+func (s *OrderStore) insertOrder(ctx context.Context, chatID int64, items []OrderItem, total money.Money, idempotencyKey string, window time.Duration) (orderID int64, err error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer func() {
+        if err != nil {
+            tx.Rollback()
+        }
+    }()
+
+    // Блокировка до конца транзакции: второе нажатие ждёт первое и видит
+    // уже созданный заказ. В SQLite транзакция и так одна на всю базу.
+    if dialectOf(s.db) == DriverPostgres {
+        if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, idempotencyKey); err != nil {
+            return 0, fmt.Errorf("ошибка блокировки ключа заказа: %w", err)
+        }
+    }
+    existing, err := recentOrderByKey(ctx, tx, idempotencyKey, window)
+    if err != nil {
+        return 0, err
+    }
+    if existing != 0 {
+        if err = tx.Commit(); err != nil {
+            return 0, fmt.Errorf("ошибка фиксации заказа: %w", err)
+        }
+        return existing, nil
+    }
+
+    err = tx.QueryRowContext(ctx,
+        `INSERT INTO orders (chat_id, total, currency, idempotency_key, status) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+        chatID, total.Minor, total.Currency, idempotencyKey, string(OrderDraft)).Scan(&orderID)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка создания заказа: %w", err)
+    }
+
+    for _, item := range items {
+        _, err = tx.ExecContext(ctx,
+            `INSERT INTO order_items (order_id, product_id, qty, price) VALUES ($1, $2, $3, $4)`,
+            orderID, item.ProductID, item.Qty, item.Price.Minor)
+        if err != nil {
+            return 0, fmt.Errorf("ошибка записи позиции заказа: %w", err)
+        }
+    }
+
+    if err = reserveStock(ctx, tx, items); err != nil {
+        return 0, err
+    }
+
+    if err = tx.Commit(); err != nil {
+        return 0, fmt.Errorf("ошибка фиксации заказа: %w", err)
+    }
+    return orderID, nil
+}
+
+// reserveStock списывает остатки позиций заказа. Условие stock >= qty
+// проверяется в самом UPDATE под блокировкой строки, поэтому два заказа
+// на последнюю единицу не пройдут оба: второй дождётся первого и не найдёт
+// остатка. Товары блокируются по возрастанию id, чтобы встречные заказы
+// не взаимоблокировались.
+func reserveStock(ctx context.Context, tx *sql.Tx, items []OrderItem) error {
+    sorted := append([]OrderItem(nil), items...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+
+    var missing []int64
+    for _, item := range sorted {
+        res, err := tx.ExecContext(ctx,
+            `UPDATE products SET
+                 stock = stock - $2,
+                 in_stock = CASE WHEN stock IS NULL THEN in_stock ELSE stock > $2 END
+             WHERE id = $1 AND in_stock AND (stock IS NULL OR stock >= $2)`,
+            item.ProductID, item.Qty)
+        if err != nil {
+            return fmt.Errorf("ошибка списания остатка товара %d: %w", item.ProductID, err)
+        }
+        n, err := res.RowsAffected()
+        if err != nil {
+            return fmt.Errorf("ошибка списания остатка товара %d: %w", item.ProductID, err)
+        }
+        if n == 0 {
+            missing = append(missing, item.ProductID)
+        }
+    }
+    if len(missing) > 0 {
+        return &OutOfStockError{ProductIDs: missing}
+    }
+    return nil
+}
+
+// OrdersBetween возвращает заказы, созданные в [from, to), по порядку
+// создания; незавершённые черновики не входят
+func (s *OrderStore) OrdersBetween(ctx context.Context, from, to time.Time) ([]OrderSummary, error) {
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT id, total, currency, status, created_at FROM orders
+         WHERE created_at >= $1 AND created_at < $2 AND status <> $3 ORDER BY id`,
+        from, to, string(OrderDraft))
+    if err != nil {
+        return nil, fmt.Errorf("ошибка выборки заказов за период: %w", err)
+    }
+    defer rows.Close()
+
+    var out []OrderSummary
+    for rows.Next() {
+        var o OrderSummary
+        if err := rows.Scan(&o.ID, &o.Total.Minor, &o.Total.Currency, &o.Status, &o.CreatedAt); err != nil {
+            return nil, fmt.Errorf("ошибка чтения заказа: %w", err)
+        }
+        out = append(out, o)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка выборки заказов за период: %w", err)
+    }
+    return out, nil
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+    "testing"
+
+    "ai_seller/money"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+    items := []OrderItem{{ProductID: 2, Qty: 1, Price: money.New(100, "RUB")}, {ProductID: 1, Qty: 3, Price: money.New(50, "RUB")}}
+    key := IdempotencyKey(42, items)
+
+    reordered := []OrderItem{items[1], items[0]}
+    if got := IdempotencyKey(42, reordered); got != key {
+        t.Fatal("порядок позиций меняет ключ")
+    }
+    if IdempotencyKey(43, items) == key {
+        t.Fatal("у другого чата тот же ключ")
+    }
+    changed := []OrderItem{items[0], {ProductID: 1, Qty: 4, Price: money.New(50, "RUB")}}
+    if IdempotencyKey(42, changed) == key {
+        t.Fatal("другое количество даёт тот же ключ")
+    }
+}
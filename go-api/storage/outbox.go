@@ -0,0 +1,148 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// OutboxMessage — ответ, ожидающий отправки в Telegram
+type OutboxMessage struct {
+    ID     int64
+    ChatID int64
+    Text   string
+    // WithFeedback — прикрепить к ответу кнопки оценки
+    WithFeedback bool
+    // ReplyTo — сообщение покупателя, которое ответ цитирует; 0 — без цитаты
+    ReplyTo int64
+    // Attempts — число попыток отправки, включая текущую
+    Attempts int
+    // SentParts — сколько частей длинного ответа уже доставлено
+    SentParts int
+}
+
+// OutboxStore — очередь исходящих ответов в PostgreSQL. Запись проходит
+// pending → sending → sent; при ошибке возвращается в pending с отсрочкой
+// или, если отправка невозможна, становится failed.
+type OutboxStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewOutboxStore — фабрика очереди исходящих ответов
+func NewOutboxStore(db *sql.DB, queryTimeout time.Duration) *OutboxStore {
+    return &OutboxStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Enqueue сохраняет ответ для отправки и возвращает id записи;
+// replyTo — сообщение, которое ответ цитирует (0 — без цитаты)
+func (s *OutboxStore) Enqueue(ctx context.Context, chatID int64, text string, withFeedback bool, replyTo int64) (int64, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var id int64
+    err := s.db.QueryRowContext(ctx,
+        `INSERT INTO outbox (chat_id, text, with_feedback, reply_to_message_id) VALUES ($1, $2, $3, $4) RETURNING id`,
+        chatID, text, withFeedback, replyTo).Scan(&id)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка сохранения ответа в очередь: %w", err)
+    }
+    return id, nil
+}
+
+// Claim забирает до limit ответов, которым пора уйти, и помечает их sending.
+// Записи, застрявшие в sending дольше stale (процесс упал во время
+// отправки), забираются снова: ответ может дойти дважды, но не потеряется.
+// SKIP LOCKED не даёт двум экземплярам забрать одну запись.
+func (s *OutboxStore) Claim(ctx context.Context, limit int, stale time.Duration) ([]OutboxMessage, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    now := time.Now()
+    rows, err := s.db.QueryContext(ctx,
+        `UPDATE outbox SET status = 'sending', claimed_at = $1, attempts = attempts + 1
+         WHERE id IN (
+             SELECT id FROM outbox
+             WHERE (status = 'pending' AND next_attempt_at <= $1)
+                OR (status = 'sending' AND claimed_at < $2)
+             ORDER BY id
+             LIMIT $3`+dialectOf(s.db).skipLocked()+`
+         )
+         RETURNING id, chat_id, text, with_feedback, reply_to_message_id, attempts, sent_parts`,
+        now, now.Add(-stale), limit)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка выборки очереди ответов: %w", err)
+    }
+    defer rows.Close()
+
+    var out []OutboxMessage
+    for rows.Next() {
+        var m OutboxMessage
+        if err := rows.Scan(&m.ID, &m.ChatID, &m.Text, &m.WithFeedback, &m.ReplyTo, &m.Attempts, &m.SentParts); err != nil {
+            return nil, fmt.Errorf("ошибка чтения ответа из очереди: %w", err)
+        }
+        out = append(out, m)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка выборки очереди ответов: %w", err)
+    }
+    return out, nil
+}
+
+// MarkPartSent запоминает, что доставлены первые parts частей ответа
+func (s *OutboxStore) MarkPartSent(ctx context.Context, id int64, parts int) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx, `UPDATE outbox SET sent_parts = $2 WHERE id = $1`, id, parts)
+    if err != nil {
+        return fmt.Errorf("ошибка отметки части ответа %d: %w", id, err)
+    }
+    return nil
+}
+
+// MarkSent отмечает ответ отправленным
+func (s *OutboxStore) MarkSent(ctx context.Context, id int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `UPDATE outbox SET status = 'sent', sent_at = $2 WHERE id = $1`, id, time.Now())
+    if err != nil {
+        return fmt.Errorf("ошибка отметки отправки ответа %d: %w", id, err)
+    }
+    return nil
+}
+
+// Retry возвращает ответ в очередь: следующая попытка не раньше чем через after
+func (s *OutboxStore) Retry(ctx context.Context, id int64, after time.Duration, cause error) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `UPDATE outbox SET status = 'pending', next_attempt_at = $2, last_error = $3 WHERE id = $1`,
+        id, time.Now().Add(after), cause.Error())
+    if err != nil {
+        return fmt.Errorf("ошибка отсрочки ответа %d: %w", id, err)
+    }
+    return nil
+}
+
+// Fail снимает ответ с отправки насовсем (бот заблокирован, попытки исчерпаны)
+func (s *OutboxStore) Fail(ctx context.Context, id int64, cause error) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `UPDATE outbox SET status = 'failed', last_error = $2 WHERE id = $1`, id, cause.Error())
+    if err != nil {
+        return fmt.Errorf("ошибка отметки неотправленного ответа %d: %w", id, err)
+    }
+    return nil
+}
+
+// PruneOutbox удаляет отправленные и неотправляемые записи старше retention
+// и возвращает их число
+func (s *OutboxStore) PruneOutbox(ctx context.Context, retention time.Duration) (int64, error) {
+    res, err := s.db.ExecContext(ctx,
+        `DELETE FROM outbox WHERE status IN ('sent', 'failed') AND created_at < $1`, time.Now().Add(-retention))
+    if err != nil {
+        return 0, fmt.Errorf("ошибка очистки очереди ответов: %w", err)
+    }
+    return res.RowsAffected()
+}
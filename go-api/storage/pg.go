@@ -1,32 +1,50 @@
 package storage
 
 import (
+    "context"
     "database/sql"
     "fmt"
-    "log"
     "time"
 
+    "ai_seller/logging"
+
     _ "github.com/lib/pq"
 )
 
-// ConnectPostgres устанавливает соединение с PostgreSQL
-func ConnectPostgres(dsn string) *sql.DB {
+// pingTimeout — сколько ждём ответа PostgreSQL при подключении
+const pingTimeout = 5 * time.Second
+
+// PoolOptions — настройки пула соединений с PostgreSQL
+type PoolOptions struct {
+    MaxOpenConns    int
+    MaxIdleConns    int
+    ConnMaxLifetime time.Duration
+}
+
+// Open открывает пул соединений с PostgreSQL и проверяет, что база отвечает.
+// Неверный DSN или недоступная база обнаруживаются сразу при старте.
+func Open(dsn string, opts PoolOptions) (*sql.DB, error) {
     db, err := sql.Open("postgres", dsn)
     if err != nil {
-        log.Fatalf("❌ Ошибка подключения к PostgreSQL: %v", err)
+        return nil, fmt.Errorf("ошибка подключения к PostgreSQL: %w", err)
     }
 
     // Настройка пула соединений
-    db.SetMaxOpenConns(10)
-    db.SetMaxIdleConns(5)
-    db.SetConnMaxLifetime(time.Hour)
+    db.SetMaxOpenConns(opts.MaxOpenConns)
+    db.SetMaxIdleConns(opts.MaxIdleConns)
+    db.SetConnMaxLifetime(opts.ConnMaxLifetime)
 
     // Проверка соединения
-    if err := db.Ping(); err != nil {
-        log.Fatalf("❌ PostgreSQL не отвечает: %v", err)
+    ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+    defer cancel()
+    if err := db.PingContext(ctx); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("PostgreSQL не отвечает: %w", err)
     }
 
-    fmt.Println("✅ Подключение к PostgreSQL успешно")
-    return db
+    logging.Logger().Info("подключение к PostgreSQL успешно",
+        "max_open_conns", opts.MaxOpenConns,
+        "max_idle_conns", opts.MaxIdleConns,
+        "conn_max_lifetime", opts.ConnMaxLifetime)
+    return db, nil
 }
-
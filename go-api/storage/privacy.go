@@ -0,0 +1,146 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+)
+
+// AnonymousChatID — chat_id обезличенных заказов: заказ остаётся для
+// отчётности, но больше не связан с покупателем
+const AnonymousChatID = 0
+
+// DataExport — всё, что магазин хранит о чате в PostgreSQL
+type DataExport struct {
+    // User — профиль; nil, если профиля нет
+    User *User
+    // Messages — вся история, включая архивированную /reset
+    Messages []Message
+    // Summary — сводка ранней части разговора; пусто, если её нет
+    Summary string
+    Orders  []OrderSummary
+}
+
+// eraseTables — таблицы, строки которых по chat_id удаляет Erase. Таблица
+// с chat_id, которой здесь нет, должна быть в keptTables с объяснением.
+var eraseTables = []string{
+    "messages", "chat_summaries", "users", "feedback", "failed_updates", "inactive_chats",
+    "outbox", "llm_audit", "attributions", "nudges", "bot_groups",
+}
+
+// keptTables — таблицы с chat_id, которые Erase не чистит по chat_id
+var keptTables = map[string]string{
+    "orders":            "обезличиваются: нужны для бухгалтерии",
+    "member_challenges": "удаляются и по группе, и по участнику",
+}
+
+// PrivacyStore — выгрузка и удаление данных покупателя по его запросу
+type PrivacyStore struct {
+    db *sql.DB
+}
+
+// NewPrivacyStore — фабрика хранилища для запросов о данных
+func NewPrivacyStore(db *sql.DB) *PrivacyStore {
+    return &PrivacyStore{db: db}
+}
+
+// Export собирает данные чата: профиль, историю сообщений, её сводку и заказы
+func (s *PrivacyStore) Export(ctx context.Context, chatID int64) (DataExport, error) {
+    var out DataExport
+
+    var u User
+    err := s.db.QueryRowContext(ctx,
+        `SELECT chat_id, username, name, lang FROM users WHERE chat_id = $1`, chatID).
+        Scan(&u.ChatID, &u.Username, &u.Name, &u.Lang)
+    switch {
+    case err == nil:
+        out.User = &u
+    case !errors.Is(err, sql.ErrNoRows):
+        return DataExport{}, fmt.Errorf("ошибка выгрузки профиля: %w", err)
+    }
+
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT role, content, created_at FROM messages WHERE chat_id = $1 ORDER BY created_at, id`, chatID)
+    if err != nil {
+        return DataExport{}, fmt.Errorf("ошибка выгрузки сообщений: %w", err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var m Message
+        if err := rows.Scan(&m.Role, &m.Content, &m.CreatedAt); err != nil {
+            return DataExport{}, fmt.Errorf("ошибка выгрузки сообщения: %w", err)
+        }
+        out.Messages = append(out.Messages, m)
+    }
+    if err := rows.Err(); err != nil {
+        return DataExport{}, fmt.Errorf("ошибка выгрузки сообщений: %w", err)
+    }
+
+    err = s.db.QueryRowContext(ctx, `SELECT summary FROM chat_summaries WHERE chat_id = $1`, chatID).Scan(&out.Summary)
+    if err != nil && !errors.Is(err, sql.ErrNoRows) {
+        return DataExport{}, fmt.Errorf("ошибка выгрузки сводки разговора: %w", err)
+    }
+
+    orders, err := s.db.QueryContext(ctx,
+        `SELECT id, total, currency, status, created_at FROM orders WHERE chat_id = $1 ORDER BY id`, chatID)
+    if err != nil {
+        return DataExport{}, fmt.Errorf("ошибка выгрузки заказов: %w", err)
+    }
+    defer orders.Close()
+    for orders.Next() {
+        var o OrderSummary
+        if err := orders.Scan(&o.ID, &o.Total.Minor, &o.Total.Currency, &o.Status, &o.CreatedAt); err != nil {
+            return DataExport{}, fmt.Errorf("ошибка выгрузки заказа: %w", err)
+        }
+        out.Orders = append(out.Orders, o)
+    }
+    if err := orders.Err(); err != nil {
+        return DataExport{}, fmt.Errorf("ошибка выгрузки заказов: %w", err)
+    }
+    return out, nil
+}
+
+// Erase удаляет данные чата в одной транзакции: историю и её сводку, профиль,
+// оценки, журналы необработанных апдейтов и запросов к модели, очередь
+// ответов, источник прихода, отметки о подсказках, пометки неактивности и
+// группы, проверки новых участников (и в группе chatID, и самого покупателя
+// в группах). Ссылки других покупателей на него как на пригласившего
+// обнуляются. Заказы нужны для бухгалтерии, поэтому не удаляются, а
+// обезличиваются (AnonymousChatID). Возвращает число обезличенных заказов.
+func (s *PrivacyStore) Erase(ctx context.Context, chatID int64) (orders int64, err error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+    }
+    defer func() {
+        if err != nil {
+            tx.Rollback()
+        }
+    }()
+
+    for _, table := range eraseTables {
+        if _, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE chat_id = $1`, chatID); err != nil {
+            return 0, fmt.Errorf("ошибка удаления данных из %s: %w", table, err)
+        }
+    }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM member_challenges WHERE chat_id = $1 OR user_id = $1`, chatID); err != nil {
+        return 0, fmt.Errorf("ошибка удаления данных из member_challenges: %w", err)
+    }
+    if _, err = tx.ExecContext(ctx, `UPDATE attributions SET referrer_id = NULL WHERE referrer_id = $1`, chatID); err != nil {
+        return 0, fmt.Errorf("ошибка удаления ссылок на пригласившего: %w", err)
+    }
+
+    res, err := tx.ExecContext(ctx, `UPDATE orders SET chat_id = $2 WHERE chat_id = $1`, chatID, AnonymousChatID)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка обезличивания заказов: %w", err)
+    }
+    if orders, err = res.RowsAffected(); err != nil {
+        return 0, fmt.Errorf("ошибка обезличивания заказов: %w", err)
+    }
+
+    if err = tx.Commit(); err != nil {
+        return 0, fmt.Errorf("ошибка фиксации удаления данных: %w", err)
+    }
+    return orders, nil
+}
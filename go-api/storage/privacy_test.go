@@ -0,0 +1,55 @@
+package storage
+
+import (
+    "os"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "testing"
+)
+
+// chatTables — таблицы миграций, где есть колонка chat_id
+func chatTables(t *testing.T) []string {
+    t.Helper()
+    files, err := filepath.Glob("../migrations/*.up.sql")
+    if err != nil || len(files) == 0 {
+        t.Fatalf("миграции не найдены: %v", err)
+    }
+    create := regexp.MustCompile(`(?is)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);`)
+    alter := regexp.MustCompile(`(?i)ALTER TABLE (\w+) ADD COLUMN (?:IF NOT EXISTS )?chat_id\b`)
+    column := regexp.MustCompile(`(?m)^\s*chat_id\s`)
+
+    var tables []string
+    for _, f := range files {
+        sql, err := os.ReadFile(f)
+        if err != nil {
+            t.Fatalf("%s: %v", f, err)
+        }
+        for _, m := range create.FindAllSubmatch(sql, -1) {
+            if column.Match(m[2]) {
+                tables = append(tables, string(m[1]))
+            }
+        }
+        for _, m := range alter.FindAllSubmatch(sql, -1) {
+            tables = append(tables, string(m[1]))
+        }
+    }
+    return tables
+}
+
+func TestEraseCoversChatTables(t *testing.T) {
+    tables := chatTables(t)
+    if !slices.Contains(tables, "messages") {
+        t.Fatalf("разбор миграций не нашёл messages: %v", tables)
+    }
+    for _, table := range tables {
+        if _, kept := keptTables[table]; !kept && !slices.Contains(eraseTables, table) {
+            t.Errorf("таблицу %s с chat_id не чистит Erase: добавьте её в eraseTables или keptTables", table)
+        }
+    }
+    for _, table := range eraseTables {
+        if !slices.Contains(tables, table) {
+            t.Errorf("в eraseTables таблица %s, которой нет в миграциях", table)
+        }
+    }
+}
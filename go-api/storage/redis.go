@@ -2,31 +2,80 @@ package storage
 
 import (
     "context"
-    "fmt"
-    "log"
     "time"
 
+    "ai_seller/logging"
+
     "github.com/redis/go-redis/v9"
 )
 
-// ConnectRedis создаёт клиент Redis и проверяет соединение
-func ConnectRedis(addr string) *redis.Client {
+// redisDialTimeout — короткий таймаут подключения: без Redis бот работает в
+// деградированном режиме, и каждая попытка достучаться не должна съедать время на ответ
+const redisDialTimeout = time.Second
+
+// blockingCommands — команды, которые сами ждут данных дольше обычного:
+// их срок задаёт аргумент BLOCK/timeout, а не REDIS_TIMEOUT
+var blockingCommands = map[string]bool{
+    "xread": true, "xreadgroup": true, "blpop": true, "brpop": true,
+    "blmove": true, "blmpop": true, "bzpopmin": true, "bzpopmax": true, "bzmpop": true,
+}
+
+// timeoutHook ограничивает каждую команду и конвейер сроком timeout через
+// контекст. Если у вызывающего дедлайн раньше, действует он: context.WithTimeout
+// не продлевает родительский срок, так что итоговый дедлайн — меньший из двух.
+type timeoutHook struct {
+    timeout time.Duration
+}
+
+func (h timeoutHook) DialHook(next redis.DialHook) redis.DialHook {
+    return next
+}
+
+func (h timeoutHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+    return func(ctx context.Context, cmd redis.Cmder) error {
+        if blockingCommands[cmd.Name()] {
+            return next(ctx, cmd)
+        }
+        ctx, cancel := context.WithTimeout(ctx, h.timeout)
+        defer cancel()
+        return next(ctx, cmd)
+    }
+}
+
+func (h timeoutHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+    return func(ctx context.Context, cmds []redis.Cmder) error {
+        ctx, cancel := context.WithTimeout(ctx, h.timeout)
+        defer cancel()
+        return next(ctx, cmds)
+    }
+}
+
+// ConnectRedis создаёт клиент Redis и проверяет соединение. Недоступный Redis
+// не мешает запуску: клиент переподключится сам, а до тех пор контекст
+// читается из PostgreSQL, кэши пропускаются. timeout — предел на одну
+// команду (REDIS_TIMEOUT), см. timeoutHook.
+func ConnectRedis(addr string, timeout time.Duration) *redis.Client {
     rdb := redis.NewClient(&redis.Options{
-        Addr:     addr,
-        Password: "", // Без пароля по умолчанию
-        DB:       0,  // БД по умолчанию
+        Addr:         addr,
+        Password:     "", // Без пароля по умолчанию
+        DB:           0,  // БД по умолчанию
+        DialTimeout:  redisDialTimeout,
+        ReadTimeout:  timeout,
+        WriteTimeout: timeout,
+        // Без этого go-redis игнорирует дедлайны контекста и timeoutHook не работает
+        ContextTimeoutEnabled: true,
     })
+    rdb.AddHook(timeoutHook{timeout: timeout})
 
     // Проверим соединение
-    ctx, cancel := context.WithTimeout(context.Background(), 
-2*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
     defer cancel()
 
     if err := rdb.Ping(ctx).Err(); err != nil {
-        log.Fatalf("❌ Redis недоступен: %v", err)
+        logging.Logger().Warn("Redis недоступен, работаем без него до восстановления", "addr", addr, "err", err)
+        return rdb
     }
 
-    fmt.Println("✅ Подключение к Redis успешно")
+    logging.Logger().Info("подключение к Redis успешно")
     return rdb
 }
-
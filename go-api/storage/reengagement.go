@@ -0,0 +1,85 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// ReengagementStore — выбор замолчавших покупателей и отметки отправленных
+// им подсказок в PostgreSQL
+type ReengagementStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewReengagementStore — фабрика хранилища подсказок повторного вовлечения
+func NewReengagementStore(db *sql.DB, queryTimeout time.Duration) *ReengagementStore {
+    return &ReengagementStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Candidates — до limit личных чатов из shoppers (смотревших товары или
+// менявших корзину за lookback), где покупатель последний раз писал от
+// lookback до idle назад. Не подходят чаты, заблокировавшие бота,
+// оформившие заказ за lookback и получившие подсказку за cooldown.
+// Сначала идут те, кто писал позже: им подсказка уместнее.
+func (s *ReengagementStore) Candidates(ctx context.Context, now time.Time, idle, lookback, cooldown time.Duration, limit int, shoppers []int64) ([]int64, error) {
+    if len(shoppers) == 0 {
+        return nil, nil
+    }
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    d := dialectOf(s.db)
+    rows, err := s.db.QueryContext(ctx,
+        `SELECT a.chat_id FROM (
+             SELECT chat_id, max(created_at) AS last_at FROM messages
+             WHERE role = 'user' AND `+d.inIDs("chat_id", "$5")+` AND chat_id > 0 AND archived_at IS NULL AND created_at >= $1
+             GROUP BY chat_id
+         ) a
+         WHERE a.last_at <= $2
+           AND NOT EXISTS (SELECT 1 FROM inactive_chats i WHERE i.chat_id = a.chat_id AND i.marked_at >= a.last_at)
+           AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.chat_id = a.chat_id AND o.created_at >= $1)
+           AND NOT EXISTS (SELECT 1 FROM nudges n WHERE n.chat_id = a.chat_id AND n.sent_at > $3)
+         ORDER BY a.last_at DESC
+         LIMIT $4`,
+        now.Add(-lookback), now.Add(-idle), now.Add(-cooldown), limit, d.ids(shoppers))
+    if err != nil {
+        return nil, fmt.Errorf("ошибка выбора чатов для подсказок: %w", err)
+    }
+    defer rows.Close()
+
+    var ids []int64
+    for rows.Next() {
+        var id int64
+        if err := rows.Scan(&id); err != nil {
+            return nil, fmt.Errorf("ошибка чтения чатов для подсказок: %w", err)
+        }
+        ids = append(ids, id)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения чатов для подсказок: %w", err)
+    }
+    return ids, nil
+}
+
+// ClaimNudge отмечает подсказку чату в момент now, если прошлая была
+// раньше чем за cooldown. true — отметку поставил этот вызов: две реплики
+// не напишут покупателю дважды.
+func (s *ReengagementStore) ClaimNudge(ctx context.Context, chatID int64, now time.Time, cooldown time.Duration) (bool, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    res, err := s.db.ExecContext(ctx,
+        `INSERT INTO nudges (chat_id, sent_at) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET sent_at = EXCLUDED.sent_at
+         WHERE nudges.sent_at <= $3`,
+        chatID, now, now.Add(-cooldown))
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки подсказки: %w", err)
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return false, fmt.Errorf("ошибка отметки подсказки: %w", err)
+    }
+    return n == 1, nil
+}
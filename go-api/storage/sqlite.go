@@ -0,0 +1,141 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "fmt"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "ai_seller/logging"
+
+    "modernc.org/sqlite"
+)
+
+// sqliteTimeFormat — формат времени в SQLite (_time_format=sqlite): всё
+// хранится в UTC, поэтому строки сравниваются в порядке времени
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// registerFunctions регистрирует в SQLite функции PostgreSQL, на которые
+// опираются запросы хранилищ; регистрация действует на все новые соединения
+var registerFunctions = sync.OnceFunc(func() {
+    sqlite.MustRegisterScalarFunction("now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+        return time.Now().UTC().Format(sqliteTimeFormat), nil
+    })
+    // Встроенная lower в SQLite меняет регистр только латиницы
+    sqlite.MustRegisterDeterministicScalarFunction("lower", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+        switch v := args[0].(type) {
+        case string:
+            return strings.ToLower(v), nil
+        case []byte:
+            return strings.ToLower(string(v)), nil
+        }
+        return args[0], nil
+    })
+    sqlite.MustRegisterDeterministicScalarFunction("similarity", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+        a, _ := args[0].(string)
+        b, _ := args[1].(string)
+        return Similarity(a, b), nil
+    })
+})
+
+// SQLiteDSN — DSN файла базы path для драйвера SQLite: внешние ключи
+// включены, время пишется в UTC, а транзакции сразу берут блокировку
+// записи — две транзакции не упрутся друг в друга посередине
+func SQLiteDSN(path string) string {
+    q := url.Values{}
+    q.Set("_time_format", "sqlite")
+    q.Set("_txlock", "immediate")
+    q.Add("_pragma", "foreign_keys(1)")
+    q.Add("_pragma", "busy_timeout(5000)")
+    q.Add("_pragma", "journal_mode(WAL)")
+    return "file:" + path + "?" + q.Encode()
+}
+
+// OpenSQLite открывает базу SQLite в файле path — для установки на одном
+// сервере без PostgreSQL. Читать соединения пула могут одновременно, а
+// пишет одно за раз: остальные ждут до busy_timeout.
+func OpenSQLite(path string, opts PoolOptions) (*sql.DB, error) {
+    registerFunctions()
+    db := sql.OpenDB(sqliteConnector{dsn: SQLiteDSN(path)})
+    db.SetMaxOpenConns(opts.MaxOpenConns)
+    db.SetMaxIdleConns(opts.MaxIdleConns)
+    db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
+    ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+    defer cancel()
+    if err := db.PingContext(ctx); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("ошибка открытия базы SQLite %s: %w", path, err)
+    }
+
+    logging.Logger().Info("база SQLite открыта", "path", path)
+    return db, nil
+}
+
+// sqliteConnector — соединения с базой SQLite через sqliteDriver
+type sqliteConnector struct {
+    dsn string
+}
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+    return sqliteDriver{}.Open(c.dsn)
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+    return sqliteDriver{}
+}
+
+// sqliteBase — драйвер modernc.org/sqlite, зарегистрированный как "sqlite":
+// функции registerFunctions есть только у его соединений
+var sqliteBase = func() driver.Driver {
+    db, _ := sql.Open("sqlite", "")
+    defer db.Close()
+    return db.Driver()
+}()
+
+// sqliteDriver — sqliteBase, переводящий время в параметрах в UTC; по нему
+// dialectOf узнаёт SQLite
+type sqliteDriver struct{}
+
+func (sqliteDriver) Open(dsn string) (driver.Conn, error) {
+    conn, err := sqliteBase.Open(dsn)
+    if err != nil {
+        return nil, err
+    }
+    return sqliteConn{conn.(sqliteDriverConn)}, nil
+}
+
+// sqliteDriverConn — возможности соединения modernc.org/sqlite, которые
+// sqliteConn передаёт database/sql как есть
+type sqliteDriverConn interface {
+    driver.Conn
+    driver.ConnBeginTx
+    driver.ConnPrepareContext
+    driver.ExecerContext
+    driver.QueryerContext
+    driver.Pinger
+    driver.SessionResetter
+    driver.Validator
+}
+
+type sqliteConn struct {
+    sqliteDriverConn
+}
+
+// CheckNamedValue переводит время в UTC: SQLite сравнивает время как
+// строки, и в другом поясе запись оказалась бы не на своём месте
+func (sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+    v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+    if err != nil {
+        return err
+    }
+    if t, ok := v.(time.Time); ok {
+        v = t.UTC()
+    }
+    nv.Value = v
+    return nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// Overview — сводка активности для /stats
+type Overview struct {
+    Users int64
+    // MessagesToday — сообщения покупателей и ответы бота с начала суток (UTC)
+    MessagesToday int64
+    // ActiveChats — чаты, писавшие боту за последние 24 часа
+    ActiveChats int64
+    // UserMessages24h и FailedUpdates24h — знаменатель и числитель доли ошибок
+    UserMessages24h  int64
+    FailedUpdates24h int64
+}
+
+// StatsStore — агрегаты для админской статистики
+type StatsStore struct {
+    db *sql.DB
+}
+
+// NewStatsStore — фабрика хранилища статистики
+func NewStatsStore(db *sql.DB) *StatsStore {
+    return &StatsStore{db: db}
+}
+
+// Overview считает сводку одним запросом; счёт по времени идёт по индексам
+// на created_at, поэтому стоимость зависит от объёма за сутки, а не за всё время.
+// Начало дня (по UTC) и сутки назад считаются здесь, а не в SQL, одинаково
+// для PostgreSQL и SQLite.
+func (s *StatsStore) Overview(ctx context.Context) (Overview, error) {
+    now := time.Now().UTC()
+    var o Overview
+    err := s.db.QueryRowContext(ctx, `
+        SELECT
+            (SELECT count(*) FROM users),
+            (SELECT count(*) FROM messages WHERE created_at >= $1),
+            (SELECT count(DISTINCT chat_id) FROM messages WHERE role = 'user' AND created_at >= $2),
+            (SELECT count(*) FROM messages WHERE role = 'user' AND created_at >= $2),
+            (SELECT count(*) FROM failed_updates WHERE created_at >= $2)`,
+        now.Truncate(24*time.Hour), now.Add(-24*time.Hour)).
+        Scan(&o.Users, &o.MessagesToday, &o.ActiveChats, &o.UserMessages24h, &o.FailedUpdates24h)
+    if err != nil {
+        return Overview{}, fmt.Errorf("ошибка подсчёта статистики: %w", err)
+    }
+    return o, nil
+}
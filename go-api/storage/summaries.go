@@ -0,0 +1,61 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Summary — сводка ранней части диалога
+type Summary struct {
+    Text string
+    // UpTo — id последнего сообщения, вошедшего в сводку
+    UpTo      int64
+    UpdatedAt time.Time
+}
+
+// SummaryStore — сводки диалогов в PostgreSQL
+type SummaryStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewSummaryStore — фабрика хранилища сводок
+func NewSummaryStore(db *sql.DB, queryTimeout time.Duration) *SummaryStore {
+    return &SummaryStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// GetSummary возвращает сводку чата или ErrNotFound
+func (s *SummaryStore) GetSummary(ctx context.Context, chatID int64) (Summary, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    var sum Summary
+    err := s.db.QueryRowContext(ctx,
+        `SELECT summary, up_to, updated_at FROM chat_summaries WHERE chat_id = $1`, chatID).
+        Scan(&sum.Text, &sum.UpTo, &sum.UpdatedAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return Summary{}, ErrNotFound
+    }
+    if err != nil {
+        return Summary{}, fmt.Errorf("ошибка чтения сводки чата %d: %w", chatID, err)
+    }
+    return sum, nil
+}
+
+// SaveSummary сохраняет сводку по сообщениям до upTo включительно. Сводка,
+// посчитанная параллельно по более старым сообщениям, новую не затирает.
+func (s *SummaryStore) SaveSummary(ctx context.Context, chatID int64, text string, upTo int64) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO chat_summaries (chat_id, summary, up_to) VALUES ($1, $2, $3)
+         ON CONFLICT (chat_id) DO UPDATE SET summary = EXCLUDED.summary, up_to = EXCLUDED.up_to, updated_at = now()
+         WHERE chat_summaries.up_to < EXCLUDED.up_to`,
+        chatID, text, upTo)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения сводки чата %d: %w", chatID, err)
+    }
+    return nil
+}
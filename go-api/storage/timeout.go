@@ -0,0 +1,29 @@
+package storage
+
+import (
+    "context"
+    "time"
+)
+
+// queryLimit — предел на один вызов хранилища PostgreSQL (DB_TIMEOUT),
+// переданный в конструктор хранилища; 0 — только дедлайн вызывающего
+type queryLimit struct {
+    timeout time.Duration
+}
+
+// withQueryTimeout ограничивает вызов хранилища сроком DB_TIMEOUT. Если у
+// вызывающего дедлайн раньше (например, UPDATE_TIMEOUT апдейта почти истёк),
+// действует он: context.WithTimeout не продлевает родительский срок, так что
+// итоговый дедлайн — меньший из двух.
+//
+// Массовые операции предел не используют: они идут в фоне или по команде
+// админа и по определению долгие. Это импорт каталога, переиндексация,
+// чистка журналов, обход всех чатов (ChatStore.ActiveChatIDs), заказы за
+// период (OrderStore.OrdersBetween), сводка StatsStore.Overview и выгрузка
+// и удаление данных покупателя в PrivacyStore.
+func (l queryLimit) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+    if l.timeout <= 0 {
+        return ctx, func() {}
+    }
+    return context.WithTimeout(ctx, l.timeout)
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+)
+
+var errStopQuery = errors.New("запрос записан")
+
+// deadlineConnector — поддельная база: каждый запрос записывает, сколько
+// времени оставалось до дедлайна его ctx (ok=false — дедлайна нет), и падает
+type deadlineConnector struct {
+    mu   sync.Mutex
+    seen []queryCtx
+}
+
+type queryCtx struct {
+    left time.Duration
+    ok   bool
+}
+
+func (c *deadlineConnector) record(ctx context.Context) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    deadline, ok := ctx.Deadline()
+    c.seen = append(c.seen, queryCtx{left: time.Until(deadline), ok: ok})
+    return errStopQuery
+}
+
+// last — дедлайн последнего запроса
+func (c *deadlineConnector) last(t *testing.T) queryCtx {
+    t.Helper()
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if len(c.seen) == 0 {
+        t.Fatal("запросов к базе не было")
+    }
+    return c.seen[len(c.seen)-1]
+}
+
+func (c *deadlineConnector) Connect(context.Context) (driver.Conn, error) {
+    return deadlineConn{c}, nil
+}
+func (c *deadlineConnector) Driver() driver.Driver { return nil }
+
+type deadlineConn struct{ c *deadlineConnector }
+
+func (deadlineConn) Prepare(string) (driver.Stmt, error) { return nil, errStopQuery }
+func (deadlineConn) Close() error                        { return nil }
+func (deadlineConn) Begin() (driver.Tx, error)           { return nil, errStopQuery }
+
+func (d deadlineConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+    return nil, d.c.record(ctx)
+}
+
+func (d deadlineConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+    return nil, d.c.record(ctx)
+}
+
+func (d deadlineConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+    return nil, d.c.record(ctx)
+}
+
+func deadlineDB(t *testing.T) (*sql.DB, *deadlineConnector) {
+    t.Helper()
+    c := &deadlineConnector{}
+    db := sql.OpenDB(c)
+    t.Cleanup(func() { db.Close() })
+    return db, c
+}
+
+// Предел у каждого хранилища свой: хранилища с разными DB_TIMEOUT над одним
+// пулом друг другу не мешают
+func TestQueryTimeoutIsPerStore(t *testing.T) {
+    db, c := deadlineDB(t)
+    ctx := context.Background()
+
+    _, _ = NewMessageStore(db, time.Minute).GetHistory(ctx, 1, 10)
+    if got := c.last(t); !got.ok || got.left < 50*time.Second || got.left > time.Minute {
+        t.Fatalf("хранилище с пределом минута: осталось %v (дедлайн %v)", got.left, got.ok)
+    }
+    _, _ = NewMessageStore(db, 0).GetHistory(ctx, 1, 10)
+    if got := c.last(t); got.ok {
+        t.Fatalf("хранилище без предела получило дедлайн через %v", got.left)
+    }
+
+    // Более близкий дедлайн вызывающего остаётся в силе
+    short, cancel := context.WithTimeout(ctx, time.Second)
+    defer cancel()
+    _, _ = NewMessageStore(db, time.Minute).GetHistory(short, 1, 10)
+    if got := c.last(t); !got.ok || got.left > time.Second {
+        t.Fatalf("дедлайн вызывающего продлён: осталось %v", got.left)
+    }
+}
+
+// Массовые операции не ограничены DB_TIMEOUT, даже если хранилище его знает
+func TestBulkOperationsSkipQueryTimeout(t *testing.T) {
+    db, c := deadlineDB(t)
+    ctx := context.Background()
+    const tight = time.Millisecond
+
+    for name, call := range map[string]func(){
+        "ActiveChatIDs": func() { _, _ = NewChatStore(db, tight).ActiveChatIDs(ctx) },
+        "OrdersBetween": func() { _, _ = NewOrderStore(db, tight).OrdersBetween(ctx, time.Time{}, time.Now()) },
+        "Overview":      func() { _, _ = NewStatsStore(db).Overview(ctx) },
+        "Export":        func() { _, _ = NewPrivacyStore(db).Export(ctx, 1) },
+        "Erase":         func() { _, _ = NewPrivacyStore(db).Erase(ctx, 1) },
+    } {
+        call()
+        if got := c.last(t); got.ok {
+            t.Errorf("%s: запрос получил дедлайн через %v", name, got.left)
+        }
+    }
+
+    // Обычный вызов того же хранилища предел соблюдает
+    _ = NewChatStore(db, tight).MarkActive(ctx, 1)
+    if got := c.last(t); !got.ok {
+        t.Fatal("MarkActive без дедлайна DB_TIMEOUT")
+    }
+}
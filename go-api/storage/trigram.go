@@ -0,0 +1,37 @@
+package storage
+
+import (
+    "strings"
+    "unicode"
+)
+
+// Similarity — доля общих триграмм слов a и b среди всех, как similarity()
+// из pg_trgm; этой функцией SQLite и memstore ищут товары с опечатками
+func Similarity(a, b string) float64 {
+    ta, tb := trigrams(a), trigrams(b)
+    common := 0
+    for t := range ta {
+        if tb[t] {
+            common++
+        }
+    }
+    union := len(ta) + len(tb) - common
+    if union == 0 {
+        return 0
+    }
+    return float64(common) / float64(union)
+}
+
+// trigrams — триграммы слов текста, как show_trgm: слово в нижнем регистре
+// с двумя пробелами в начале и одним в конце
+func trigrams(s string) map[string]bool {
+    out := make(map[string]bool)
+    words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+    for _, w := range words {
+        r := []rune("  " + w + " ")
+        for i := 0; i+3 <= len(r); i++ {
+            out[string(r[i:i+3])] = true
+        }
+    }
+    return out
+}
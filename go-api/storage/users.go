@@ -0,0 +1,149 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// User — профиль покупателя из Telegram
+type User struct {
+    ChatID   int64
+    Username string
+    Name     string
+    Lang     string
+    // Brief — покупатель просил отвечать кратко (/brief)
+    Brief bool
+    // Currency — валюта показа цен (/currency); пусто — валюта магазина
+    Currency string
+    // PreferredLang — язык, выбранный через /lang; пусто — Lang из Telegram
+    PreferredLang string
+    // PromptVariant — вариант системного промпта в A/B-тесте; пусто — не назначен
+    PromptVariant string
+    // MenuHidden — покупатель скрыл меню под полем ввода (/menu off)
+    MenuHidden bool
+}
+
+// Language — язык общения: выбранный через /lang, иначе язык интерфейса Telegram
+func (u User) Language() string {
+    if u.PreferredLang != "" {
+        return u.PreferredLang
+    }
+    return u.Lang
+}
+
+// UserStore — профили покупателей в PostgreSQL
+type UserStore struct {
+    db *sql.DB
+    queryLimit
+}
+
+// NewUserStore — фабрика хранилища профилей
+func NewUserStore(db *sql.DB, queryTimeout time.Duration) *UserStore {
+    return &UserStore{db: db, queryLimit: queryLimit{queryTimeout}}
+}
+
+// Upsert создаёт или обновляет профиль по данным последнего сообщения
+func (s *UserStore) Upsert(ctx context.Context, chatID int64, username, lang, name string) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO users (chat_id, username, lang, name) VALUES ($1, $2, $3, $4)
+         ON CONFLICT (chat_id) DO UPDATE
+         SET username = EXCLUDED.username, lang = EXCLUDED.lang, name = EXCLUDED.name, updated_at = now()
+         WHERE (users.username, users.lang, users.name) IS DISTINCT FROM (EXCLUDED.username, EXCLUDED.lang, EXCLUDED.name)`,
+        chatID, username, lang, name)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения профиля: %w", err)
+    }
+    return nil
+}
+
+// SetBrief включает или выключает краткий режим ответов чата
+func (s *UserStore) SetBrief(ctx context.Context, chatID int64, brief bool) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO users (chat_id, brief) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET brief = EXCLUDED.brief, updated_at = now()`,
+        chatID, brief)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения режима ответов: %w", err)
+    }
+    return nil
+}
+
+// SetCurrency сохраняет валюту показа цен чата; пустая строка возвращает валюту магазина
+func (s *UserStore) SetCurrency(ctx context.Context, chatID int64, currency string) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO users (chat_id, currency) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET currency = EXCLUDED.currency, updated_at = now()`,
+        chatID, currency)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения валюты: %w", err)
+    }
+    return nil
+}
+
+// SetLang сохраняет язык общения чата; пустая строка возвращает язык Telegram
+func (s *UserStore) SetLang(ctx context.Context, chatID int64, lang string) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO users (chat_id, preferred_lang) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET preferred_lang = EXCLUDED.preferred_lang, updated_at = now()`,
+        chatID, lang)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения языка: %w", err)
+    }
+    return nil
+}
+
+// SetPromptVariant закрепляет за чатом вариант системного промпта
+func (s *UserStore) SetPromptVariant(ctx context.Context, chatID int64, variant string) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO users (chat_id, prompt_variant) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET prompt_variant = EXCLUDED.prompt_variant, updated_at = now()`,
+        chatID, variant)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения варианта промпта: %w", err)
+    }
+    return nil
+}
+
+// SetMenuHidden запоминает, скрыл ли чат меню под полем ввода
+func (s *UserStore) SetMenuHidden(ctx context.Context, chatID int64, hidden bool) error {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    _, err := s.db.ExecContext(ctx,
+        `INSERT INTO users (chat_id, menu_hidden) VALUES ($1, $2)
+         ON CONFLICT (chat_id) DO UPDATE SET menu_hidden = EXCLUDED.menu_hidden, updated_at = now()`,
+        chatID, hidden)
+    if err != nil {
+        return fmt.Errorf("ошибка сохранения видимости меню: %w", err)
+    }
+    return nil
+}
+
+// GetUser возвращает профиль или ErrNotFound
+func (s *UserStore) GetUser(ctx context.Context, chatID int64) (User, error) {
+    ctx, cancel := s.withQueryTimeout(ctx)
+    defer cancel()
+    u := User{ChatID: chatID}
+    err := s.db.QueryRowContext(ctx,
+        `SELECT username, name, lang, brief, currency, preferred_lang, prompt_variant, menu_hidden FROM users WHERE chat_id = $1`, chatID).
+        Scan(&u.Username, &u.Name, &u.Lang, &u.Brief, &u.Currency, &u.PreferredLang, &u.PromptVariant, &u.MenuHidden)
+    if errors.Is(err, sql.ErrNoRows) {
+        return User{}, ErrNotFound
+    }
+    if err != nil {
+        return User{}, fmt.Errorf("ошибка чтения профиля %d: %w", chatID, err)
+    }
+    return u, nil
+}
@@ -0,0 +1,506 @@
+// Package storetest — общие сценарии для хранилищ обработчиков. Одни и те же
+// проверки гоняются на фейках memstore, на хранилищах cache поверх redistest
+// и на хранилищах storage с обоими драйверами: SQLite во временном файле и,
+// если задан TEST_POSTGRES_DSN, PostgreSQL — так фейки и драйверы не
+// расходятся друг с другом.
+package storetest
+
+import (
+    "context"
+    "errors"
+    "math/rand/v2"
+    "slices"
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+
+    "ai_seller/cache"
+    "ai_seller/handlers"
+    "ai_seller/money"
+    "ai_seller/storage"
+)
+
+// chatID — свой чат на каждый сценарий: в общей базе тесты не мешают друг другу
+func chatID() int64 {
+    return 1_000_000_000 + rand.Int64N(1_000_000_000)
+}
+
+// Messages — сценарии handlers.MessageStore
+func Messages(t *testing.T, newStore func(t *testing.T) handlers.MessageStore) {
+    ctx := context.Background()
+
+    t.Run("история по порядку и с пределом", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        for _, m := range []storage.Message{
+            {Role: "user", Content: "привет"},
+            {Role: "assistant", Content: "здравствуйте"},
+            {Role: "user", Content: "есть чай?"},
+        } {
+            if err := s.SaveMessage(ctx, chat, m.Role, m.Content); err != nil {
+                t.Fatalf("SaveMessage: %v", err)
+            }
+        }
+        history, err := s.GetHistory(ctx, chat, 2)
+        if err != nil {
+            t.Fatalf("GetHistory: %v", err)
+        }
+        if len(history) != 2 || history[0].Content != "здравствуйте" || history[1].Content != "есть чай?" {
+            t.Fatalf("получено %+v, ожидались два последних сообщения от старых к новым", history)
+        }
+        if n, _ := s.CountMessages(ctx, chat); n != 3 {
+            t.Fatalf("CountMessages: получено %d, ожидалось 3", n)
+        }
+    })
+
+    t.Run("незавершённый ответ помечен", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        if err := s.SaveIncompleteMessage(ctx, chat, "assistant", "обрыв"); err != nil {
+            t.Fatalf("SaveIncompleteMessage: %v", err)
+        }
+        history, _ := s.GetHistory(ctx, chat, 10)
+        if len(history) != 1 || !history[0].Incomplete {
+            t.Fatalf("получено %+v, ожидалось одно незавершённое сообщение", history)
+        }
+    })
+
+    t.Run("архив скрыт из контекста, но чат писал", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        if has, _ := s.HasMessages(ctx, chat); has {
+            t.Fatal("у нового чата есть история")
+        }
+        _ = s.SaveMessage(ctx, chat, "user", "старое")
+        if err := s.ArchiveHistory(ctx, chat); err != nil {
+            t.Fatalf("ArchiveHistory: %v", err)
+        }
+        history, _ := s.GetHistory(ctx, chat, 10)
+        n, _ := s.CountMessages(ctx, chat)
+        has, _ := s.HasMessages(ctx, chat)
+        if len(history) != 0 || n != 0 || !has {
+            t.Fatalf("после архивации: история %d, счётчик %d, писал %v", len(history), n, has)
+        }
+    })
+}
+
+// Users — сценарии handlers.UserStore
+func Users(t *testing.T, newStore func(t *testing.T) handlers.UserStore) {
+    ctx := context.Background()
+
+    t.Run("нет профиля — ErrNotFound", func(t *testing.T) {
+        if _, err := newStore(t).GetUser(ctx, chatID()); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("получено %v, ожидалось storage.ErrNotFound", err)
+        }
+    })
+
+    t.Run("настройки сохраняются", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        if err := s.Upsert(ctx, chat, "buyer", "ru", "Аня"); err != nil {
+            t.Fatalf("Upsert: %v", err)
+        }
+        for _, err := range []error{
+            s.SetBrief(ctx, chat, true),
+            s.SetCurrency(ctx, chat, "USD"),
+            s.SetLang(ctx, chat, "en"),
+            s.SetPromptVariant(ctx, chat, "b"),
+            s.SetMenuHidden(ctx, chat, true),
+        } {
+            if err != nil {
+                t.Fatalf("настройка профиля: %v", err)
+            }
+        }
+        u, err := s.GetUser(ctx, chat)
+        if err != nil {
+            t.Fatalf("GetUser: %v", err)
+        }
+        if !u.Brief || u.Currency != "USD" || u.PreferredLang != "en" || u.PromptVariant != "b" || !u.MenuHidden {
+            t.Fatalf("получено %+v", u)
+        }
+    })
+}
+
+// Carts — сценарии handlers.CartStore
+func Carts(t *testing.T, newStore func(t *testing.T) handlers.CartStore) {
+    ctx := context.Background()
+
+    t.Run("количества складываются, позиции по id", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        for _, add := range [][2]int64{{7, 1}, {3, 2}, {7, 2}} {
+            if err := s.AddItem(ctx, chat, add[0], int(add[1])); err != nil {
+                t.Fatalf("AddItem: %v", err)
+            }
+        }
+        cart, err := s.GetCart(ctx, chat)
+        if err != nil {
+            t.Fatalf("GetCart: %v", err)
+        }
+        want := []cache.CartItem{{ProductID: 3, Qty: 2}, {ProductID: 7, Qty: 3}}
+        if !slices.Equal(cart.Items, want) {
+            t.Fatalf("получено %v, ожидалось %v", cart.Items, want)
+        }
+        if err := s.RemoveItem(ctx, chat, 3); err != nil {
+            t.Fatalf("RemoveItem: %v", err)
+        }
+        cart, _ = s.GetCart(ctx, chat)
+        if !slices.Equal(cart.Items, []cache.CartItem{{ProductID: 7, Qty: 3}}) {
+            t.Fatalf("после удаления: %v", cart.Items)
+        }
+    })
+
+    t.Run("неположительное количество отклоняется", func(t *testing.T) {
+        if err := newStore(t).AddItem(ctx, chatID(), 1, 0); err == nil {
+            t.Fatal("AddItem принял количество 0")
+        }
+    })
+
+    t.Run("напоминание о корзине одно", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        _ = s.AddItem(ctx, chat, 1, 1)
+        idle, err := s.IdleCarts(ctx, 0)
+        if err != nil || !slices.Contains(idle, chat) {
+            t.Fatalf("IdleCarts: %v, %v — корзины чата нет", idle, err)
+        }
+        first, _ := s.ClaimReminder(ctx, chat)
+        second, _ := s.ClaimReminder(ctx, chat)
+        if !first || second {
+            t.Fatalf("ClaimReminder: получено %v, %v, ожидалось true, false", first, second)
+        }
+        if idle, _ := s.IdleCarts(ctx, 0); slices.Contains(idle, chat) {
+            t.Fatal("о напомненной корзине напомнят снова")
+        }
+    })
+
+    t.Run("очистка", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        _ = s.AddItem(ctx, chat, 1, 1)
+        if err := s.ClearCart(ctx, chat); err != nil {
+            t.Fatalf("ClearCart: %v", err)
+        }
+        if cart, _ := s.GetCart(ctx, chat); !cart.Empty() {
+            t.Fatalf("корзина не пуста: %v", cart.Items)
+        }
+        if ok, _ := s.ClaimReminder(ctx, chat); ok {
+            t.Fatal("напоминание об очищенной корзине")
+        }
+    })
+}
+
+// Orders — сценарии handlers.OrderStore. Товары с id 1 и 2 должны быть в
+// наличии: настоящий OrderStore списывает остатки.
+func Orders(t *testing.T, newStore func(t *testing.T) handlers.OrderStore) {
+    ctx := context.Background()
+    items := []storage.OrderItem{{ProductID: 2, Qty: 1}, {ProductID: 1, Qty: 2}}
+    total := money.New(30000, "RUB")
+
+    t.Run("ключ идемпотентности не создаёт второй заказ", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        key := storage.IdempotencyKey(chat, items)
+        first, err := s.CreateOrder(ctx, chat, items, total, key, time.Minute)
+        if err != nil {
+            t.Fatalf("CreateOrder: %v", err)
+        }
+        second, err := s.CreateOrder(ctx, chat, items, total, key, time.Minute)
+        if err != nil || second != first {
+            t.Fatalf("повтор: получен заказ %d (%v), ожидался %d", second, err, first)
+        }
+        orders, _ := s.ChatOrders(ctx, chat)
+        if len(orders) != 1 {
+            t.Fatalf("заказов: получено %d, ожидалось 1", len(orders))
+        }
+    })
+
+    t.Run("после окна тот же ключ даёт новый заказ", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        key := storage.IdempotencyKey(chat, items)
+        first, err := s.CreateOrder(ctx, chat, items, total, key, 200*time.Millisecond)
+        if err != nil {
+            t.Fatalf("CreateOrder: %v", err)
+        }
+        time.Sleep(300 * time.Millisecond)
+        second, err := s.CreateOrder(ctx, chat, items, total, key, 200*time.Millisecond)
+        if err != nil || second == first {
+            t.Fatalf("после окна: получен заказ %d (%v), ожидался новый", second, err)
+        }
+    })
+
+    t.Run("одновременные нажатия дают один заказ", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        key := storage.IdempotencyKey(chat, items)
+        ids := make([]int64, 5)
+        var wg sync.WaitGroup
+        for i := range ids {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                ids[i], _ = s.CreateOrder(ctx, chat, items, total, key, time.Minute)
+            }()
+        }
+        wg.Wait()
+        for _, id := range ids {
+            if id != ids[0] || id == 0 {
+                t.Fatalf("получены заказы %v, ожидался один", ids)
+            }
+        }
+    })
+
+    t.Run("чужой заказ не виден", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        id, err := s.CreateOrder(ctx, chat, items, total, storage.IdempotencyKey(chat, items), time.Minute)
+        if err != nil {
+            t.Fatalf("CreateOrder: %v", err)
+        }
+        if _, err := s.GetOrder(ctx, id, chat+1); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("получено %v, ожидалось storage.ErrNotFound", err)
+        }
+        o, err := s.GetOrder(ctx, id, chat)
+        if err != nil || o.Status != storage.OrderDraft || len(o.Items) != 2 || o.Items[0].ProductID != 1 {
+            t.Fatalf("GetOrder: %+v, %v", o, err)
+        }
+    })
+
+    t.Run("переходы статусов", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        id, _ := s.CreateOrder(ctx, chat, items, total, storage.IdempotencyKey(chat, items), time.Minute)
+        if err := s.SetStatus(ctx, id, storage.OrderPaid, ""); !errors.Is(err, storage.ErrInvalidTransition) {
+            t.Fatalf("draft → paid: получено %v, ожидалось storage.ErrInvalidTransition", err)
+        }
+        if err := s.ConfirmOrder(ctx, id); err != nil {
+            t.Fatalf("ConfirmOrder: %v", err)
+        }
+        if err := s.SetStatus(ctx, id, storage.OrderShipped, ""); !errors.Is(err, storage.ErrInvalidTransition) {
+            t.Fatalf("new → shipped: получено %v, ожидалось storage.ErrInvalidTransition", err)
+        }
+        if err := s.SetStatus(ctx, id, storage.OrderPaid, "оплачен"); err != nil {
+            t.Fatalf("new → paid: %v", err)
+        }
+        st, err := s.OrderState(ctx, id)
+        if err != nil || st.ChatID != chat || st.Status != storage.OrderPaid || st.Total != total {
+            t.Fatalf("OrderState: %+v, %v", st, err)
+        }
+        if _, err := s.OrderState(ctx, id+1_000_000); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("несуществующий заказ: получено %v", err)
+        }
+    })
+
+    t.Run("заказы за период", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        id, _ := s.CreateOrder(ctx, chat, items, total, storage.IdempotencyKey(chat, items), time.Minute)
+        now := time.Now()
+        if drafts, _ := s.OrdersBetween(ctx, now.Add(-time.Hour), now.Add(time.Hour)); containsOrder(drafts, id) {
+            t.Fatalf("OrdersBetween: черновик %d в периоде %v", id, drafts)
+        }
+        _ = s.ConfirmOrder(ctx, id)
+        in, _ := s.OrdersBetween(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+        out, _ := s.OrdersBetween(ctx, now.Add(time.Hour), now.Add(2*time.Hour))
+        if !containsOrder(in, id) || containsOrder(out, id) {
+            t.Fatalf("OrdersBetween: заказ %d в периоде %v, вне периода %v", id, in, out)
+        }
+    })
+
+    t.Run("подтверждённый заказ не черновик", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        id, _ := s.CreateOrder(ctx, chat, items, total, storage.IdempotencyKey(chat, items), time.Minute)
+        if d, err := s.LatestDraft(ctx, chat, time.Now().Add(-time.Hour)); err != nil || d.ID != id || len(d.Items) != 2 {
+            t.Fatalf("LatestDraft: %+v, %v", d, err)
+        }
+        if err := s.ConfirmOrder(ctx, id); err != nil {
+            t.Fatalf("ConfirmOrder: %v", err)
+        }
+        // Повторное нажатие /checkout подтверждает тот же заказ ещё раз
+        if err := s.ConfirmOrder(ctx, id); err != nil {
+            t.Fatalf("повторный ConfirmOrder: %v", err)
+        }
+        if st, _ := s.OrderState(ctx, id); st.Status != storage.OrderNew {
+            t.Fatalf("статус: получен %s, ожидался %s", st.Status, storage.OrderNew)
+        }
+        if _, err := s.LatestDraft(ctx, chat, time.Now().Add(-time.Hour)); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("LatestDraft: получено %v, ожидалось storage.ErrNotFound", err)
+        }
+        if err := s.DiscardDraft(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("DiscardDraft: получено %v, ожидалось storage.ErrNotFound", err)
+        }
+        if err := s.ConfirmOrder(ctx, id+1_000_000); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("несуществующий заказ: получено %v", err)
+        }
+    })
+
+    t.Run("отменённый черновик", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        key := storage.IdempotencyKey(chat, items)
+        id, _ := s.CreateOrder(ctx, chat, items, total, key, time.Minute)
+        if err := s.DiscardDraft(ctx, id); err != nil {
+            t.Fatalf("DiscardDraft: %v", err)
+        }
+        if err := s.DiscardDraft(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("повторный DiscardDraft: получено %v, ожидалось storage.ErrNotFound", err)
+        }
+        if st, _ := s.OrderState(ctx, id); st.Status != storage.OrderCancelled {
+            t.Fatalf("статус: получен %s, ожидался %s", st.Status, storage.OrderCancelled)
+        }
+        if err := s.ConfirmOrder(ctx, id); !errors.Is(err, storage.ErrInvalidTransition) {
+            t.Fatalf("ConfirmOrder: получено %v, ожидалось storage.ErrInvalidTransition", err)
+        }
+        // Товары вернулись в корзину — оформление с тем же ключом создаёт новый заказ
+        next, err := s.CreateOrder(ctx, chat, items, total, key, time.Minute)
+        if err != nil || next == id {
+            t.Fatalf("повторное оформление: получен заказ %d (%v), ожидался новый", next, err)
+        }
+    })
+
+    t.Run("старый черновик не находится", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        _, _ = s.CreateOrder(ctx, chat, items, total, storage.IdempotencyKey(chat, items), time.Minute)
+        if _, err := s.LatestDraft(ctx, chat, time.Now().Add(time.Minute)); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("получено %v, ожидалось storage.ErrNotFound", err)
+        }
+        if _, err := s.LatestDraft(ctx, chat+1, time.Now().Add(-time.Hour)); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("чужой чат: получено %v, ожидалось storage.ErrNotFound", err)
+        }
+    })
+}
+
+// Feedback — сценарии handlers.FeedbackStore; Stats сравнивается с
+// состоянием до сценария, чтобы в общей базе не мешали чужие оценки
+func Feedback(t *testing.T, newStore func(t *testing.T) handlers.FeedbackStore) {
+    ctx := context.Background()
+
+    t.Run("повторная оценка заменяет прежнюю", func(t *testing.T) {
+        s, chat := newStore(t), chatID()
+        before, _ := s.Stats(ctx)
+        _ = s.Record(ctx, chat, 10, storage.RatingUp)
+        _ = s.Record(ctx, chat, 10, storage.RatingDown)
+        _ = s.Record(ctx, chat, 11, storage.RatingUp)
+        after, err := s.Stats(ctx)
+        if err != nil {
+            t.Fatalf("Stats: %v", err)
+        }
+        if after.Up-before.Up != 1 || after.Down-before.Down != 1 {
+            t.Fatalf("прирост оценок: +%d/-%d, ожидалось +1/-1", after.Up-before.Up, after.Down-before.Down)
+        }
+    })
+
+    t.Run("оценка вне ±1 отклоняется", func(t *testing.T) {
+        if err := newStore(t).Record(ctx, chatID(), 1, 5); err == nil {
+            t.Fatal("Record принял оценку 5")
+        }
+    })
+}
+
+// Attributions — сценарии handlers.AttributionStore
+func Attributions(t *testing.T, newStore func(t *testing.T) handlers.AttributionStore) {
+    ctx := context.Background()
+    s, chat := newStore(t), chatID()
+    first, err := s.Record(ctx, chat, storage.Attribution{Kind: storage.AttributionCampaign, Code: "spring"})
+    if err != nil || !first {
+        t.Fatalf("первое касание: %v, %v", first, err)
+    }
+    again, err := s.Record(ctx, chat, storage.Attribution{Kind: storage.AttributionCampaign, Code: "summer"})
+    if err != nil || again {
+        t.Fatalf("второе касание записано: %v, %v", again, err)
+    }
+}
+
+// MemberChallenges — сценарии handlers.MemberChallengeStore
+func MemberChallenges(t *testing.T, newStore func(t *testing.T) handlers.MemberChallengeStore) {
+    ctx := context.Background()
+    now := time.Now().Truncate(time.Second)
+
+    t.Run("пройденная проверка снимается один раз", func(t *testing.T) {
+        s, chat := newStore(t), -chatID()
+        c := storage.MemberChallenge{ChatID: chat, UserID: 5, MessageID: 77, ExpiresAt: now.Add(time.Minute)}
+        if err := s.Create(ctx, c); err != nil {
+            t.Fatalf("Create: %v", err)
+        }
+        got, err := s.Resolve(ctx, chat, 5)
+        if err != nil || got.MessageID != 77 {
+            t.Fatalf("Resolve: %+v, %v", got, err)
+        }
+        if _, err := s.Resolve(ctx, chat, 5); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("повторный Resolve: получено %v, ожидалось storage.ErrNotFound", err)
+        }
+    })
+
+    t.Run("просроченные забираются один раз", func(t *testing.T) {
+        s, chat := newStore(t), -chatID()
+        _ = s.Create(ctx, storage.MemberChallenge{ChatID: chat, UserID: 1, ExpiresAt: now.Add(-time.Minute)})
+        _ = s.Create(ctx, storage.MemberChallenge{ChatID: chat, UserID: 2, ExpiresAt: now.Add(time.Hour)})
+        expired, err := s.ClaimExpired(ctx, now, 100)
+        if err != nil {
+            t.Fatalf("ClaimExpired: %v", err)
+        }
+        var mine []int64
+        for _, c := range expired {
+            if c.ChatID == chat {
+                mine = append(mine, c.UserID)
+            }
+        }
+        if len(mine) != 1 || mine[0] != 1 {
+            t.Fatalf("забраны участники %v, ожидался [1]", mine)
+        }
+        if _, err := s.Resolve(ctx, chat, 1); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("забранную проверку можно пройти: %v", err)
+        }
+        if _, err := s.Resolve(ctx, chat, 2); err != nil {
+            t.Fatalf("непросроченная проверка пропала: %v", err)
+        }
+    })
+}
+
+// Catalog — сценарии handlers.CatalogStore. Названия товаров — с номером
+// сценария, чтобы в общей базе поиск находил только свои.
+func Catalog(t *testing.T, newStore func(t *testing.T) handlers.CatalogStore) {
+    ctx := context.Background()
+
+    t.Run("импорт по названию без учёта регистра", func(t *testing.T) {
+        s, tag := newStore(t), strconv.FormatInt(chatID(), 10)
+        tea := storage.Product{Name: "Зелёный чай " + tag, Price: money.New(35000, "RUB"), InStock: true}
+        if res, err := s.ImportProducts(ctx, []storage.Product{tea}); err != nil || res.Added != 1 {
+            t.Fatalf("первый импорт: %+v, %v", res, err)
+        }
+        if res, err := s.ImportProducts(ctx, []storage.Product{tea}); err != nil || res.Unchanged != 1 {
+            t.Fatalf("тот же импорт: %+v, %v", res, err)
+        }
+        tea.Name, tea.Price = "ЗЕЛЁНЫЙ ЧАЙ "+tag, money.New(39000, "RUB")
+        if res, err := s.ImportProducts(ctx, []storage.Product{tea}); err != nil || res.Updated != 1 {
+            t.Fatalf("импорт с новой ценой: %+v, %v", res, err)
+        }
+        found, err := s.SearchProducts(ctx, "зелёный ЧАЙ "+tag)
+        if err != nil || len(found) != 1 || found[0].Price.Minor != 39000 {
+            t.Fatalf("SearchProducts: %+v, %v", found, err)
+        }
+    })
+
+    t.Run("нечёткий поиск находит опечатку", func(t *testing.T) {
+        s, tag := newStore(t), strconv.FormatInt(chatID(), 10)
+        _, _ = s.ImportProducts(ctx, []storage.Product{{Name: "Пуэр " + tag, Price: money.New(90000, "RUB"), InStock: true}})
+        found, err := s.FuzzySearchProducts(ctx, "пуер "+tag, 0.3)
+        if err != nil || len(found) != 1 || found[0].Name != "Пуэр "+tag {
+            t.Fatalf("FuzzySearchProducts: %+v, %v", found, err)
+        }
+    })
+
+    t.Run("остаток и категория", func(t *testing.T) {
+        s, tag := newStore(t), strconv.FormatInt(chatID(), 10)
+        _, _ = s.ImportProducts(ctx, []storage.Product{{Name: "Улун " + tag, Price: money.New(60000, "RUB"), InStock: true}})
+        found, _ := s.SearchProducts(ctx, "улун "+tag)
+        if len(found) != 1 {
+            t.Fatalf("не найден импортированный товар: %+v", found)
+        }
+        id := found[0].ID
+        p, err := s.SetStock(ctx, id, 0, 1)
+        if err != nil || p.Stock == nil || *p.Stock != 0 || p.InStock {
+            t.Fatalf("SetStock 0: %+v, %v", p, err)
+        }
+        if _, err := s.SetCategory(ctx, id, 999_999_999, 1); !errors.Is(err, storage.ErrNotFound) {
+            t.Fatalf("несуществующая категория: получено %v, ожидалось storage.ErrNotFound", err)
+        }
+    })
+}
+
+func containsOrder(orders []storage.OrderSummary, id int64) bool {
+    for _, o := range orders {
+        if o.ID == id {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,118 @@
+package storetest
+
+import (
+    "context"
+    "database/sql"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "ai_seller/cache"
+    "ai_seller/handlers"
+    "ai_seller/memstore"
+    "ai_seller/migrations"
+    "ai_seller/redistest"
+    "ai_seller/storage"
+)
+
+func TestMemstore(t *testing.T) {
+    t.Run("Messages", func(t *testing.T) {
+        Messages(t, func(*testing.T) handlers.MessageStore { return &memstore.Messages{} })
+    })
+    t.Run("Users", func(t *testing.T) {
+        Users(t, func(*testing.T) handlers.UserStore { return &memstore.Users{} })
+    })
+    t.Run("Carts", func(t *testing.T) {
+        Carts(t, func(*testing.T) handlers.CartStore { return &memstore.Carts{} })
+    })
+    t.Run("Orders", func(t *testing.T) {
+        Orders(t, func(*testing.T) handlers.OrderStore { return &memstore.Orders{} })
+    })
+    t.Run("Feedback", func(t *testing.T) {
+        Feedback(t, func(*testing.T) handlers.FeedbackStore { return &memstore.Feedback{} })
+    })
+    t.Run("Attributions", func(t *testing.T) {
+        Attributions(t, func(*testing.T) handlers.AttributionStore { return &memstore.Attributions{} })
+    })
+    t.Run("MemberChallenges", func(t *testing.T) {
+        MemberChallenges(t, func(*testing.T) handlers.MemberChallengeStore { return &memstore.MemberChallenges{} })
+    })
+    t.Run("Catalog", func(t *testing.T) {
+        Catalog(t, func(*testing.T) handlers.CatalogStore { return &memstore.Catalog{} })
+    })
+}
+
+func TestRedis(t *testing.T) {
+    Carts(t, func(t *testing.T) handlers.CartStore {
+        _, rdb := redistest.NewClient(t)
+        return cache.NewCartStore(rdb)
+    })
+}
+
+// TestPostgres гоняет сценарии на настоящей базе; без TEST_POSTGRES_DSN пропускается
+func TestPostgres(t *testing.T) {
+    dsn := os.Getenv("TEST_POSTGRES_DSN")
+    if dsn == "" {
+        t.Skip("TEST_POSTGRES_DSN не задан")
+    }
+    if err := migrations.RunMigrations(storage.DriverPostgres, dsn); err != nil {
+        t.Fatalf("миграции: %v", err)
+    }
+    db, err := storage.Open(dsn, storage.PoolOptions{MaxOpenConns: 4, MaxIdleConns: 4})
+    if err != nil {
+        t.Fatalf("подключение: %v", err)
+    }
+    t.Cleanup(func() { db.Close() })
+    runSQL(t, db)
+}
+
+// TestSQLite гоняет те же сценарии на SQLite в новом файле базы
+func TestSQLite(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "shop.db")
+    if err := migrations.RunMigrations(storage.DriverSQLite, path); err != nil {
+        t.Fatalf("миграции: %v", err)
+    }
+    db, err := storage.OpenSQLite(path, storage.PoolOptions{MaxOpenConns: 4, MaxIdleConns: 4})
+    if err != nil {
+        t.Fatalf("подключение: %v", err)
+    }
+    t.Cleanup(func() { db.Close() })
+    runSQL(t, db)
+}
+
+// runSQL — сценарии для хранилищ storage поверх db
+func runSQL(t *testing.T, db *sql.DB) {
+    seedProducts(t, db)
+
+    t.Run("Messages", func(t *testing.T) {
+        Messages(t, func(*testing.T) handlers.MessageStore { return storage.NewMessageStore(db, 0) })
+    })
+    t.Run("Users", func(t *testing.T) {
+        Users(t, func(*testing.T) handlers.UserStore { return storage.NewUserStore(db, 0) })
+    })
+    t.Run("Orders", func(t *testing.T) {
+        Orders(t, func(*testing.T) handlers.OrderStore { return storage.NewOrderStore(db, 0) })
+    })
+    t.Run("Feedback", func(t *testing.T) {
+        Feedback(t, func(*testing.T) handlers.FeedbackStore { return storage.NewFeedbackStore(db, 0) })
+    })
+    t.Run("Attributions", func(t *testing.T) {
+        Attributions(t, func(*testing.T) handlers.AttributionStore { return storage.NewAttributionStore(db, 0) })
+    })
+    t.Run("MemberChallenges", func(t *testing.T) {
+        MemberChallenges(t, func(*testing.T) handlers.MemberChallengeStore { return storage.NewMemberChallengeStore(db, 0) })
+    })
+    t.Run("Catalog", func(t *testing.T) {
+        Catalog(t, func(*testing.T) handlers.CatalogStore { return storage.NewCatalogStore(db, 0) })
+    })
+}
+
+// seedProducts заводит товары 1 и 2 без учёта остатков для сценариев Orders
+func seedProducts(t *testing.T, db *sql.DB) {
+    _, err := db.ExecContext(context.Background(),
+        `INSERT INTO products (id, name, price) VALUES (1, 'тест 1', 10000), (2, 'тест 2', 10000)
+         ON CONFLICT (id) DO UPDATE SET stock = NULL, in_stock = true`)
+    if err != nil {
+        t.Fatalf("товары для заказов: %v", err)
+    }
+}
@@ -0,0 +1,375 @@
+package telegram
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "time"
+)
+
+const apiBaseURL = "https://api.telegram.org"
+
+// Options — настройки клиента Telegram
+type Options struct {
+    // ParseMode — разметка сообщений по умолчанию (MarkdownV2, HTML или "" — простой текст)
+    ParseMode string
+}
+
+// Client — клиент Telegram Bot API
+type Client struct {
+    token      string
+    httpClient *http.Client
+    // pollClient — без общего таймаута: getUpdates держит соединение до timeout секунд
+    pollClient *http.Client
+    // fileClient — скачивание файлов: 20 МБ за 10 секунд на медленном канале не успеть
+    fileClient *http.Client
+    parseMode  string
+}
+
+// NewClient — фабрика клиента Telegram с токеном бота и настройками
+func NewClient(token string, opts Options) *Client {
+    return &Client{
+        token:      token,
+        httpClient: &http.Client{Timeout: 10 * time.Second},
+        pollClient: &http.Client{},
+        fileClient: &http.Client{Timeout: downloadTimeout},
+        parseMode:  opts.ParseMode,
+    }
+}
+
+// sendMessageRequest — тело запроса sendMessage
+type sendMessageRequest struct {
+    ChatID int64  `json:"chat_id"`
+    Text   string `json:"text"`
+    sendOptions
+}
+
+// SendMessage отправляет текстовое сообщение в чат и возвращает его
+// message_id для последующей правки. Текст длиннее лимита Telegram уходит
+// несколькими сообщениями по порядку; тогда возвращается id последнего —
+// к нему же прикрепляется клавиатура из WithReplyMarkup, а цитата из
+// WithReplyTo — к первому. Текст пишется обычным Markdown и переводится
+// в разметку режима (см. formatText).
+func (c *Client) SendMessage(chatID int64, text string, opts ...SendOption) (int64, error) {
+    o, err := applyOptions(sendOptions{ParseMode: c.parseMode}, opts)
+    if err != nil {
+        return 0, err
+    }
+
+    chunks := splitMessage(text, maxMessageLength)
+    var messageID int64
+    for i, chunk := range chunks {
+        req := sendMessageRequest{ChatID: chatID, Text: formatText(chunk, o.ParseMode), sendOptions: o}
+        if i < len(chunks)-1 {
+            req.ReplyMarkup = nil
+        }
+        if i > 0 {
+            req.ReplyToMessageID = 0
+        }
+        messageID, err = c.sendMessage(req, chunk)
+        if err != nil {
+            if len(chunks) == 1 {
+                return 0, err
+            }
+            return 0, fmt.Errorf("часть %d из %d: %w", i+1, len(chunks), err)
+        }
+    }
+    return messageID, nil
+}
+
+// sentMessage — то, что нужно из отправленного сообщения для его правки
+type sentMessage struct {
+    MessageID int64 `json:"message_id"`
+}
+
+// sendMessage отправляет одно сообщение. Если Telegram не смог разобрать
+// разметку, сообщение переотправляется исходным текстом plain без разметки —
+// пусть пользователь увидит лишние символы, чем не получит ответ вовсе.
+// Ответ на удалённое сообщение так же переотправляется без цитаты. Обе
+// беды могут случиться сразу, и Telegram сообщает о них по одной, поэтому
+// каждый отказ снимает свою причину, пока запрос не пройдёт или не упадёт
+// по другой.
+func (c *Client) sendMessage(req sendMessageRequest, plain string) (int64, error) {
+    var sent sentMessage
+    for {
+        err := c.do(context.Background(), "sendMessage", req, &sent)
+        switch {
+        case req.ParseMode != "" && isParseError(err):
+            req.ParseMode, req.Text = "", plain
+        case req.ReplyToMessageID != 0 && isReplyNotFound(err):
+            req.ReplyToMessageID = 0
+        default:
+            return sent.MessageID, err
+        }
+    }
+}
+
+// sendPhotoRequest — тело запроса sendPhoto
+type sendPhotoRequest struct {
+    ChatID  int64  `json:"chat_id"`
+    Photo   string `json:"photo"`
+    Caption string `json:"caption,omitempty"`
+    sendOptions
+}
+
+// SendPhoto отправляет фото по ссылке с подписью. Telegram скачивает фото сам
+// и отвечает 400, если ссылка недоступна. Подпись — простой текст, если
+// разметка не задана через WithParseMode.
+func (c *Client) SendPhoto(chatID int64, photoURL, caption string, opts ...SendOption) error {
+    o, err := applyOptions(sendOptions{}, opts)
+    if err != nil {
+        return err
+    }
+    return c.call("sendPhoto", sendPhotoRequest{ChatID: chatID, Photo: photoURL, Caption: formatText(caption, o.ParseMode), sendOptions: o})
+}
+
+// editMessageTextRequest — тело запроса editMessageText
+type editMessageTextRequest struct {
+    ChatID    int64  `json:"chat_id"`
+    MessageID int64  `json:"message_id"`
+    Text      string `json:"text"`
+    sendOptions
+}
+
+// EditMessageText заменяет текст и клавиатуру (WithReplyMarkup) ранее
+// отправленного сообщения. Текст — простой, если разметка не задана через
+// WithParseMode; при разметке Markdown переводится, как в SendMessage, и
+// при ошибке разбора правка повторяется простым текстом. Правка без
+// изменений ошибкой не считается.
+func (c *Client) EditMessageText(chatID, messageID int64, text string, opts ...SendOption) error {
+    o, err := applyOptions(sendOptions{}, opts)
+    if err != nil {
+        return err
+    }
+    // Правка не меняет, на что отвечает сообщение
+    o.ReplyToMessageID = 0
+    req := editMessageTextRequest{ChatID: chatID, MessageID: messageID, Text: formatText(text, o.ParseMode), sendOptions: o}
+    err = c.call("editMessageText", req)
+    if req.ParseMode != "" && isParseError(err) {
+        req.ParseMode, req.Text = "", text
+        err = c.call("editMessageText", req)
+    }
+    if isNotModified(err) {
+        return nil
+    }
+    return err
+}
+
+// deleteMessageRequest — тело запроса deleteMessage
+type deleteMessageRequest struct {
+    ChatID    int64 `json:"chat_id"`
+    MessageID int64 `json:"message_id"`
+}
+
+// DeleteMessage удаляет сообщение бота. Telegram разрешает это в течение 48 часов.
+func (c *Client) DeleteMessage(chatID, messageID int64) error {
+    return c.call("deleteMessage", deleteMessageRequest{ChatID: chatID, MessageID: messageID})
+}
+
+// sendChatActionRequest — тело запроса sendChatAction
+type sendChatActionRequest struct {
+    ChatID int64  `json:"chat_id"`
+    Action string `json:"action"`
+}
+
+// SendChatAction показывает в чате статус бота, например "typing".
+// Статус держится около 5 секунд или до следующего сообщения бота.
+func (c *Client) SendChatAction(chatID int64, action string) error {
+    return c.call("sendChatAction", sendChatActionRequest{ChatID: chatID, Action: action})
+}
+
+// answerCallbackQueryRequest — тело запроса answerCallbackQuery
+type answerCallbackQueryRequest struct {
+    CallbackQueryID string `json:"callback_query_id"`
+}
+
+// AnswerCallbackQuery подтверждает нажатие inline-кнопки, чтобы убрать индикатор загрузки
+func (c *Client) AnswerCallbackQuery(callbackID string) error {
+    return c.call("answerCallbackQuery", answerCallbackQueryRequest{CallbackQueryID: callbackID})
+}
+
+// setWebhookRequest — тело запроса setWebhook
+type setWebhookRequest struct {
+    URL            string   `json:"url"`
+    SecretToken    string   `json:"secret_token,omitempty"`
+    AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// allowedUpdates — типы апдейтов, которые обрабатывает бот
+var allowedUpdates = []string{"message", "callback_query", "my_chat_member"}
+
+// SetWebhook регистрирует адрес, на который Telegram будет слать апдейты.
+// secret приходит обратно в заголовке X-Telegram-Bot-Api-Secret-Token.
+func (c *Client) SetWebhook(url, secret string) error {
+    var ok bool
+    return c.do(context.Background(), "setWebhook", setWebhookRequest{
+        URL:            url,
+        SecretToken:    secret,
+        AllowedUpdates: allowedUpdates,
+    }, &ok)
+}
+
+// deleteWebhookRequest — тело запроса deleteWebhook
+type deleteWebhookRequest struct {
+    DropPendingUpdates bool `json:"drop_pending_updates"`
+}
+
+// DeleteWebhook снимает вебхук, чтобы получать апдейты через getUpdates.
+// Накопившиеся апдейты сохраняются.
+func (c *Client) DeleteWebhook() error {
+    var ok bool
+    return c.do(context.Background(), "deleteWebhook", deleteWebhookRequest{}, &ok)
+}
+
+// BotInfo — то, что нужно из ответа getMe
+type BotInfo struct {
+    ID       int64  `json:"id"`
+    Username string `json:"username"`
+}
+
+// GetMe возвращает ID и имя бота; заодно проверяет токен и доступность Bot API
+func (c *Client) GetMe(ctx context.Context) (BotInfo, error) {
+    var me BotInfo
+    if err := c.do(ctx, "getMe", struct{}{}, &me); err != nil {
+        return BotInfo{}, err
+    }
+    return me, nil
+}
+
+// apiResponse — общий конверт ответа Bot API
+type apiResponse struct {
+    OK          bool            `json:"ok"`
+    Result      json.RawMessage `json:"result"`
+    ErrorCode   int             `json:"error_code"`
+    Description string          `json:"description"`
+    Parameters  *struct {
+        RetryAfter      int   `json:"retry_after"`
+        MigrateToChatID int64 `json:"migrate_to_chat_id"`
+    } `json:"parameters"`
+}
+
+// APIError — отказ Bot API: ответ с кодом, отличным от 200, или "ok": false
+// (Telegram иногда отвечает так и с HTTP 200)
+type APIError struct {
+    Method string
+    // StatusCode — error_code из ответа, а если его нет — HTTP-статус
+    StatusCode  int
+    Description string
+    // RetryAfter — сколько подождать перед повтором (429 Too Many Requests)
+    RetryAfter time.Duration
+    // MigrateToChatID — новый id группы, ставшей супергруппой
+    MigrateToChatID int64
+}
+
+func (e *APIError) Error() string {
+    if e.RetryAfter > 0 {
+        return fmt.Sprintf("telegram %s вернул %d: %s (повтор через %s)", e.Method, e.StatusCode, e.Description, e.RetryAfter)
+    }
+    return fmt.Sprintf("telegram %s вернул %d: %s", e.Method, e.StatusCode, e.Description)
+}
+
+// newAPIError собирает ошибку из конверта ответа
+func newAPIError(method string, status int, envelope apiResponse) *APIError {
+    apiErr := &APIError{Method: method, StatusCode: status, Description: envelope.Description}
+    if envelope.ErrorCode != 0 {
+        apiErr.StatusCode = envelope.ErrorCode
+    }
+    if p := envelope.Parameters; p != nil {
+        apiErr.RetryAfter = time.Duration(p.RetryAfter) * time.Second
+        apiErr.MigrateToChatID = p.MigrateToChatID
+    }
+    return apiErr
+}
+
+// RetryAfter сообщает, сколько Telegram просит подождать перед повтором (429)
+func RetryAfter(err error) (time.Duration, bool) {
+    var apiErr *APIError
+    if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests && apiErr.RetryAfter > 0 {
+        return apiErr.RetryAfter, true
+    }
+    return 0, false
+}
+
+// IsForbidden сообщает, что Telegram отказал с 403 — обычно бот заблокирован пользователем
+func IsForbidden(err error) bool {
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// call выполняет метод Bot API без разбора результата
+func (c *Client) call(method string, payload interface{}) error {
+    return c.do(context.Background(), method, payload, nil)
+}
+
+// do выполняет POST-запрос к методу Bot API с JSON-телом и,
+// если out не nil, разбирает в него поле result ответа
+func (c *Client) do(ctx context.Context, method string, payload interface{}, out interface{}) error {
+    return c.doWith(ctx, c.httpClient, method, payload, out)
+}
+
+// doWith — то же, что do, через заданный HTTP-клиент
+func (c *Client) doWith(ctx context.Context, client *http.Client, method string, payload interface{}, out interface{}) error {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("ошибка сериализации запроса %s: %w", method, err)
+    }
+    return c.post(ctx, client, method, body, "application/json", out)
+}
+
+// post отправляет готовое тело запроса к методу Bot API и, если out не nil,
+// разбирает в него поле result ответа. Ошибкой считается и ответ с кодом,
+// отличным от 200, и "ok": false в теле. Запрос не повторяется: 429 и
+// сбои повторяет вызывающий код (см. RetryAfter), иначе попытки множатся.
+func (c *Client) post(ctx context.Context, client *http.Client, method string, body []byte, contentType string, out interface{}) error {
+    endpoint := fmt.Sprintf("%s/bot%s/%s", apiBaseURL, c.token, method)
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+    if err != nil {
+        return fmt.Errorf("ошибка создания запроса %s: %w", method, err)
+    }
+    req.Header.Set("Content-Type", contentType)
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("ошибка запроса %s: %w", method, stripURL(err))
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        var envelope apiResponse
+        if json.Unmarshal(respBody, &envelope) != nil || envelope.Description == "" {
+            envelope = apiResponse{Description: string(respBody)}
+        }
+        return newAPIError(method, resp.StatusCode, envelope)
+    }
+
+    var envelope apiResponse
+    if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+        return fmt.Errorf("ошибка разбора ответа %s: %w", method, err)
+    }
+    if !envelope.OK {
+        return newAPIError(method, resp.StatusCode, envelope)
+    }
+    if out == nil {
+        return nil
+    }
+    if err := json.Unmarshal(envelope.Result, out); err != nil {
+        return fmt.Errorf("ошибка разбора результата %s: %w", method, err)
+    }
+    return nil
+}
+
+// stripURL убирает из ошибки адрес запроса: он содержит токен бота,
+// а ошибки попадают в логи
+func stripURL(err error) error {
+    var urlErr *url.Error
+    if errors.As(err, &urlErr) {
+        return urlErr.Err
+    }
+    return err
+}
@@ -0,0 +1,274 @@
+package telegram
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "path"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// apiCall — запрос к фейковому Bot API
+type apiCall struct {
+    method string
+    body   map[string]any
+}
+
+// fakeAPI — Bot API в памяти вместо api.telegram.org: запоминает запросы и
+// отвечает respond; без respond — {"ok":true} с message_id 1
+type fakeAPI struct {
+    mu      sync.Mutex
+    calls   []apiCall
+    respond func(call apiCall) (status int, body string)
+}
+
+func (f *fakeAPI) RoundTrip(r *http.Request) (*http.Response, error) {
+    raw, _ := io.ReadAll(r.Body)
+    call := apiCall{method: path.Base(r.URL.Path)}
+    json.Unmarshal(raw, &call.body)
+
+    f.mu.Lock()
+    f.calls = append(f.calls, call)
+    respond := f.respond
+    f.mu.Unlock()
+
+    status, body := http.StatusOK, `{"ok":true,"result":{"message_id":1}}`
+    if respond != nil {
+        status, body = respond(call)
+    }
+    return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+}
+
+// Calls — запросы по порядку
+func (f *fakeAPI) Calls() []apiCall {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]apiCall(nil), f.calls...)
+}
+
+// newFakeClient — клиент с разметкой mode, который ходит в фейковый API
+func newFakeClient(mode string, respond func(apiCall) (int, string)) (*Client, *fakeAPI) {
+    api := &fakeAPI{respond: respond}
+    c := NewClient("123:test", Options{ParseMode: mode})
+    c.httpClient = &http.Client{Transport: api}
+    return c, api
+}
+
+// parseErrorOnce — первый запрос с разметкой Telegram не разбирает
+func parseErrorOnce(call apiCall) (int, string) {
+    if call.body["parse_mode"] != nil {
+        return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: can't parse entities: Character '.' is reserved"}`
+    }
+    return http.StatusOK, `{"ok":true,"result":{"message_id":1}}`
+}
+
+func TestSendMessageFormatsMarkdown(t *testing.T) {
+    c, api := newFakeClient(ParseModeMarkdownV2, nil)
+    if _, err := c.SendMessage(1, "Цена: **1 990 ₽**. Итого!"); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 1 || calls[0].body["text"] != `Цена: *1 990 ₽*\. Итого\!` || calls[0].body["parse_mode"] != ParseModeMarkdownV2 {
+        t.Fatalf("запросы: %+v", calls)
+    }
+}
+
+func TestSendMessageParseErrorFallsBackToPlain(t *testing.T) {
+    c, api := newFakeClient(ParseModeMarkdownV2, parseErrorOnce)
+    if _, err := c.SendMessage(1, "**Итого**: 5."); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 2 {
+        t.Fatalf("запросов: получено %d, ожидалось 2", len(calls))
+    }
+    // Простым текстом уходит исходный текст, без экранирования
+    if calls[1].body["parse_mode"] != nil || calls[1].body["text"] != "**Итого**: 5." {
+        t.Fatalf("повтор: %+v", calls[1].body)
+    }
+}
+
+func TestEditMessageTextParseErrorFallsBackToPlain(t *testing.T) {
+    c, api := newFakeClient("", parseErrorOnce)
+    if err := c.EditMessageText(1, 2, "a.b", WithParseMode(ParseModeHTML)); err != nil {
+        t.Fatalf("EditMessageText: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 2 || calls[1].body["text"] != "a.b" || calls[1].body["parse_mode"] != nil {
+        t.Fatalf("запросы: %+v", calls)
+    }
+}
+
+func TestSendMessagePlainByDefault(t *testing.T) {
+    c, api := newFakeClient("", nil)
+    if _, err := c.SendMessage(1, "**как есть**"); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    if got := api.Calls()[0].body["text"]; got != "**как есть**" {
+        t.Fatalf("текст без разметки изменён: %q", got)
+    }
+}
+
+// 429 клиент не повторяет сам: повторы решает вызывающий код
+func TestSendMessageDoesNotRetryTooManyRequests(t *testing.T) {
+    c, api := newFakeClient("", func(apiCall) (int, string) {
+        return http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":1}}`
+    })
+    _, err := c.SendMessage(1, "текст")
+    if wait, ok := RetryAfter(err); !ok || wait != time.Second {
+        t.Fatalf("ошибка %v, ожидался 429 с паузой 1s", err)
+    }
+    if n := len(api.Calls()); n != 1 {
+        t.Fatalf("запросов: получено %d, ожидался 1", n)
+    }
+}
+
+func TestSendMessageReplyToJSON(t *testing.T) {
+    c, api := newFakeClient("", nil)
+    long := strings.Repeat("а", maxMessageLength) + "\n\n" + "хвост"
+    if _, err := c.SendMessage(1, long, WithReplyTo(5)); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    if _, err := c.SendMessage(1, "без цитаты"); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 3 {
+        t.Fatalf("запросов: получено %d, ожидалось 3", len(calls))
+    }
+    // Цитата — только у первой части длинного ответа
+    if calls[0].body["reply_to_message_id"] != float64(5) {
+        t.Fatalf("первая часть: %+v", calls[0].body)
+    }
+    for _, call := range calls[1:] {
+        if _, ok := call.body["reply_to_message_id"]; ok {
+            t.Fatalf("лишняя цитата: %+v", call.body)
+        }
+    }
+}
+
+// replyNotFound — сообщение с цитатой Telegram отклоняет: оригинал удалён
+func replyNotFound(call apiCall) (int, string) {
+    if call.body["reply_to_message_id"] != nil {
+        return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: message to be replied not found"}`
+    }
+    return http.StatusOK, `{"ok":true,"result":{"message_id":1}}`
+}
+
+func TestSendMessageDeletedReplyFallsBack(t *testing.T) {
+    c, api := newFakeClient("", replyNotFound)
+    if _, err := c.SendMessage(1, "ответ", WithReplyTo(5)); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 2 || calls[1].body["reply_to_message_id"] != nil || calls[1].body["text"] != "ответ" {
+        t.Fatalf("запросы: %+v", calls)
+    }
+}
+
+// Разметка с ошибкой и удалённый оригинал сразу: Telegram называет причины
+// по одной, и каждая снимается своим повтором
+func TestSendMessageParseErrorAndDeletedReply(t *testing.T) {
+    c, api := newFakeClient(ParseModeMarkdownV2, func(call apiCall) (int, string) {
+        if call.body["parse_mode"] != nil {
+            return parseErrorOnce(call)
+        }
+        return replyNotFound(call)
+    })
+    if _, err := c.SendMessage(1, "**Итого**: 5.", WithReplyTo(5)); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 3 {
+        t.Fatalf("запросов: получено %d, ожидалось 3", len(calls))
+    }
+    last := calls[2].body
+    if last["parse_mode"] != nil || last["reply_to_message_id"] != nil || last["text"] != "**Итого**: 5." {
+        t.Fatalf("последний запрос: %+v", last)
+    }
+}
+
+func TestSendMessageReplyNotFoundBeforeParseError(t *testing.T) {
+    c, api := newFakeClient(ParseModeMarkdownV2, func(call apiCall) (int, string) {
+        if call.body["reply_to_message_id"] != nil {
+            return replyNotFound(call)
+        }
+        return parseErrorOnce(call)
+    })
+    if _, err := c.SendMessage(1, "**Итого**: 5.", WithReplyTo(5)); err != nil {
+        t.Fatalf("SendMessage: %v", err)
+    }
+    if n := len(api.Calls()); n != 3 {
+        t.Fatalf("запросов: получено %d, ожидалось 3", n)
+    }
+}
+
+func TestSendMessageReturnsMessageID(t *testing.T) {
+    c, _ := newFakeClient("", func(apiCall) (int, string) {
+        return http.StatusOK, `{"ok":true,"result":{"message_id":77}}`
+    })
+    id, err := c.SendMessage(1, "текст")
+    if err != nil || id != 77 {
+        t.Fatalf("SendMessage: id %d, %v", id, err)
+    }
+}
+
+func TestEditMessageText(t *testing.T) {
+    c, api := newFakeClient("", nil)
+    kb := NewInlineKeyboard().Row(CallbackButton("+1", "qty:5:+1"))
+    if err := c.EditMessageText(1, 77, "В корзине: 2 шт.", WithReplyMarkup(kb), WithReplyTo(3)); err != nil {
+        t.Fatalf("EditMessageText: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 1 || calls[0].method != "editMessageText" {
+        t.Fatalf("запросы: %+v", calls)
+    }
+    body := calls[0].body
+    if body["chat_id"] != float64(1) || body["message_id"] != float64(77) || body["text"] != "В корзине: 2 шт." {
+        t.Fatalf("тело запроса: %+v", body)
+    }
+    if _, ok := body["reply_markup"]; !ok {
+        t.Fatal("правка без клавиатуры")
+    }
+    if _, ok := body["reply_to_message_id"]; ok {
+        t.Fatal("правка меняет цитату")
+    }
+}
+
+// «message is not modified» — не ошибка: текст уже такой, какой нужен
+func TestEditMessageTextNotModified(t *testing.T) {
+    for desc, wantErr := range map[string]bool{
+        "Bad Request: message is not modified: specified new message content and reply markup are exactly the same": false,
+        "Bad Request: message to edit not found": true,
+    } {
+        c, _ := newFakeClient("", func(apiCall) (int, string) {
+            return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"` + desc + `"}`
+        })
+        if err := c.EditMessageText(1, 77, "текст"); (err != nil) != wantErr {
+            t.Fatalf("%q: ошибка %v", desc, err)
+        }
+    }
+}
+
+func TestDeleteMessage(t *testing.T) {
+    c, api := newFakeClient("", func(apiCall) (int, string) { return http.StatusOK, `{"ok":true,"result":true}` })
+    if err := c.DeleteMessage(1, 77); err != nil {
+        t.Fatalf("DeleteMessage: %v", err)
+    }
+    calls := api.Calls()
+    if len(calls) != 1 || calls[0].method != "deleteMessage" || calls[0].body["message_id"] != float64(77) {
+        t.Fatalf("запросы: %+v", calls)
+    }
+
+    c, _ = newFakeClient("", func(apiCall) (int, string) {
+        return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: message can't be deleted"}`
+    })
+    var apiErr *APIError
+    if err := c.DeleteMessage(1, 77); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+        t.Fatalf("ошибка удаления: %v", err)
+    }
+}
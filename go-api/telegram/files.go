@@ -0,0 +1,145 @@
+package telegram
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+const (
+    // maxDownloadSize — предел размера скачиваемого файла (Bot API отдаёт до 20 МБ)
+    maxDownloadSize = 20 << 20
+    // downloadTimeout — предел на одну попытку скачивания файла
+    downloadTimeout = 30 * time.Second
+)
+
+// ErrFileTooLarge — файл больше, чем Bot API разрешает скачать
+var ErrFileTooLarge = fmt.Errorf("файл больше %d МБ", maxDownloadSize>>20)
+
+// downloadStatusError — сервер файлов ответил кодом, отличным от 200
+type downloadStatusError struct {
+    status int
+}
+
+func (e *downloadStatusError) Error() string {
+    return fmt.Sprintf("скачивание файла вернуло %d", e.status)
+}
+
+// File — метаданные файла из getFile
+type File struct {
+    FileID   string `json:"file_id"`
+    FileSize int64  `json:"file_size"`
+    FilePath string `json:"file_path"`
+}
+
+// GetFile получает путь для скачивания файла по file_id
+func (c *Client) GetFile(ctx context.Context, fileID string) (File, error) {
+    var f File
+    err := c.do(ctx, "getFile", map[string]string{"file_id": fileID}, &f)
+    if err != nil {
+        return File{}, err
+    }
+    if f.FilePath == "" {
+        return File{}, fmt.Errorf("telegram getFile не вернул путь к файлу %s", fileID)
+    }
+    return f, nil
+}
+
+// DownloadFile получает путь файла через getFile и скачивает его. Временный
+// сбой скачивания (сеть, 5xx) повторяется один раз. Вместо
+// application/octet-stream, который Telegram отдаёт почти всегда, тип
+// определяется по содержимому.
+func (c *Client) DownloadFile(ctx context.Context, fileID string) ([]byte, string, error) {
+    f, err := c.GetFile(ctx, fileID)
+    if err != nil {
+        return nil, "", err
+    }
+    // Размер из getFile позволяет отказать, не скачивая файл
+    if f.FileSize > maxDownloadSize {
+        return nil, "", ErrFileTooLarge
+    }
+
+    data, contentType, err := c.download(ctx, f.FilePath)
+    if retryableDownload(err) && ctx.Err() == nil {
+        data, contentType, err = c.download(ctx, f.FilePath)
+    }
+    if err != nil {
+        return nil, "", err
+    }
+    if contentType == "" || contentType == "application/octet-stream" {
+        contentType = http.DetectContentType(data)
+    }
+    return data, contentType, nil
+}
+
+// retryableDownload — стоит ли повторить скачивание после ошибки err
+func retryableDownload(err error) bool {
+    if err == nil || errors.Is(err, ErrFileTooLarge) {
+        return false
+    }
+    var statusErr *downloadStatusError
+    if errors.As(err, &statusErr) {
+        return statusErr.status >= http.StatusInternalServerError || statusErr.status == http.StatusTooManyRequests
+    }
+    return true
+}
+
+// download скачивает файл по пути из getFile и возвращает его содержимое и Content-Type
+func (c *Client) download(ctx context.Context, filePath string) ([]byte, string, error) {
+    endpoint := fmt.Sprintf("%s/file/bot%s/%s", apiBaseURL, c.token, filePath)
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, "", fmt.Errorf("ошибка создания запроса на скачивание: %w", err)
+    }
+
+    resp, err := c.fileClient.Do(req)
+    if err != nil {
+        return nil, "", fmt.Errorf("ошибка скачивания файла: %w", stripURL(err))
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, "", &downloadStatusError{status: resp.StatusCode}
+    }
+
+    data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+    if err != nil {
+        return nil, "", fmt.Errorf("ошибка чтения файла: %w", err)
+    }
+    if len(data) > maxDownloadSize {
+        return nil, "", ErrFileTooLarge
+    }
+    return data, resp.Header.Get("Content-Type"), nil
+}
+
+// SendDocument отправляет файл data под именем filename с подписью caption
+// (простой текст). Файл загружается multipart-запросом, поэтому ссылка не нужна.
+func (c *Client) SendDocument(ctx context.Context, chatID int64, filename string, data []byte, caption string) error {
+    var body bytes.Buffer
+    w := multipart.NewWriter(&body)
+    if err := w.WriteField("chat_id", strconv.FormatInt(chatID, 10)); err != nil {
+        return fmt.Errorf("ошибка подготовки документа: %w", err)
+    }
+    if caption != "" {
+        if err := w.WriteField("caption", caption); err != nil {
+            return fmt.Errorf("ошибка подготовки документа: %w", err)
+        }
+    }
+    part, err := w.CreateFormFile("document", filename)
+    if err != nil {
+        return fmt.Errorf("ошибка подготовки документа: %w", err)
+    }
+    if _, err := part.Write(data); err != nil {
+        return fmt.Errorf("ошибка подготовки документа: %w", err)
+    }
+    if err := w.Close(); err != nil {
+        return fmt.Errorf("ошибка подготовки документа: %w", err)
+    }
+    return c.post(ctx, c.httpClient, "sendDocument", body.Bytes(), w.FormDataContentType(), nil)
+}
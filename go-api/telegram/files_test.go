@@ -0,0 +1,133 @@
+package telegram
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// jpeg — начало JPEG-файла, по которому http.DetectContentType узнаёт тип
+var jpeg = "\xff\xd8\xff\xe0\x00\x10JFIF\x00" + strings.Repeat("x", 100)
+
+// fileReply — ответ фейкового сервера файлов
+type fileReply struct {
+    status int
+    body   io.Reader
+}
+
+// fakeFiles — сервер файлов api.telegram.org/file: отвечает по порядку из
+// replies (последний повторяется) и запоминает пути запросов
+type fakeFiles struct {
+    mu      sync.Mutex
+    replies []fileReply
+    paths   []string
+}
+
+func (f *fakeFiles) RoundTrip(r *http.Request) (*http.Response, error) {
+    f.mu.Lock()
+    f.paths = append(f.paths, r.URL.Path)
+    reply := f.replies[min(len(f.paths), len(f.replies))-1]
+    f.mu.Unlock()
+    return &http.Response{
+        StatusCode: reply.status,
+        Body:       io.NopCloser(reply.body),
+        Header:     http.Header{"Content-Type": {"application/octet-stream"}},
+    }, nil
+}
+
+// endless — бесконечный поток байт
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) { return len(p), nil }
+
+// newFileClient — клиент, у которого getFile отвечает размером size
+// (0 — без размера), а скачивание идёт с files
+func newFileClient(size int64, files *fakeFiles) (*Client, *fakeAPI) {
+    c, api := newFakeClient("", func(call apiCall) (int, string) {
+        return http.StatusOK, fmt.Sprintf(`{"ok":true,"result":{"file_id":"f1","file_size":%d,"file_path":"photos/file_1.jpg"}}`, size)
+    })
+    c.fileClient = &http.Client{Transport: files}
+    return c, api
+}
+
+func TestDownloadFile(t *testing.T) {
+    files := &fakeFiles{replies: []fileReply{{http.StatusOK, strings.NewReader(jpeg)}}}
+    c, api := newFileClient(int64(len(jpeg)), files)
+
+    data, contentType, err := c.DownloadFile(context.Background(), "f1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(data) != jpeg || contentType != "image/jpeg" {
+        t.Fatalf("скачано %d байт типа %q", len(data), contentType)
+    }
+    if calls := api.Calls(); len(calls) != 1 || calls[0].method != "getFile" || calls[0].body["file_id"] != "f1" {
+        t.Fatalf("запросы к API %+v", calls)
+    }
+    if len(files.paths) != 1 || files.paths[0] != "/file/bot123:test/photos/file_1.jpg" {
+        t.Fatalf("скачивание по путям %q", files.paths)
+    }
+}
+
+// Временный сбой скачивания повторяется один раз, ответ 4xx — нет
+func TestDownloadFileRetry(t *testing.T) {
+    cases := []struct {
+        name      string
+        replies   []fileReply
+        downloads int
+        ok        bool
+    }{
+        {"502, потом успех", []fileReply{{http.StatusBadGateway, strings.NewReader("")}, {http.StatusOK, strings.NewReader(jpeg)}}, 2, true},
+        {"два сбоя подряд", []fileReply{{http.StatusServiceUnavailable, strings.NewReader("")}}, 2, false},
+        {"404 не повторяется", []fileReply{{http.StatusNotFound, strings.NewReader("")}}, 1, false},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            files := &fakeFiles{replies: tc.replies}
+            c, _ := newFileClient(0, files)
+            _, _, err := c.DownloadFile(context.Background(), "f1")
+            if (err == nil) != tc.ok {
+                t.Fatalf("ошибка %v, успех нужен: %v", err, tc.ok)
+            }
+            if len(files.paths) != tc.downloads {
+                t.Fatalf("скачиваний %d, нужно %d", len(files.paths), tc.downloads)
+            }
+        })
+    }
+}
+
+// Слишком большой файл отклоняется: по размеру из getFile — без скачивания,
+// без размера — на чтении, не дальше предела и без повтора
+func TestDownloadFileTooLarge(t *testing.T) {
+    files := &fakeFiles{replies: []fileReply{{http.StatusOK, strings.NewReader(jpeg)}}}
+    c, _ := newFileClient(maxDownloadSize+1, files)
+    if _, _, err := c.DownloadFile(context.Background(), "f1"); !errors.Is(err, ErrFileTooLarge) {
+        t.Fatalf("файл больше предела по getFile: %v", err)
+    }
+    if len(files.paths) != 0 {
+        t.Fatalf("файл больше предела скачивался: %q", files.paths)
+    }
+
+    files = &fakeFiles{replies: []fileReply{{http.StatusOK, io.LimitReader(endless{}, maxDownloadSize+1<<20)}}}
+    c, _ = newFileClient(0, files)
+    if _, _, err := c.DownloadFile(context.Background(), "f1"); !errors.Is(err, ErrFileTooLarge) {
+        t.Fatalf("файл больше предела без размера: %v", err)
+    }
+    if len(files.paths) != 1 {
+        t.Fatalf("слишком большой файл скачивался %d раз", len(files.paths))
+    }
+}
+
+func TestDownloadFileGetFileWithoutPath(t *testing.T) {
+    c, _ := newFakeClient("", func(apiCall) (int, string) {
+        return http.StatusOK, `{"ok":true,"result":{"file_id":"f1"}}`
+    })
+    if _, _, err := c.DownloadFile(context.Background(), "f1"); err == nil {
+        t.Fatal("getFile без file_path не дал ошибки")
+    }
+}
@@ -0,0 +1,148 @@
+package telegram
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+)
+
+// Режимы разметки текста сообщений (parse_mode); пустая строка — простой текст
+const (
+    ParseModeMarkdownV2 = "MarkdownV2"
+    ParseModeHTML       = "HTML"
+)
+
+// markdownV2Reserved — символы, которые в MarkdownV2 нужно экранировать вне разметки
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+
+// EscapeMarkdownV2 экранирует служебные символы MarkdownV2, чтобы
+// пользовательский текст (название товара, имя) вывелся как есть
+func EscapeMarkdownV2(s string) string {
+    var b strings.Builder
+    b.Grow(len(s))
+    for _, r := range s {
+        if strings.ContainsRune(markdownV2Reserved, r) {
+            b.WriteByte('\\')
+        }
+        b.WriteRune(r)
+    }
+    return b.String()
+}
+
+// sendOptions — необязательные поля sendMessage и sendPhoto
+type sendOptions struct {
+    ParseMode   string      `json:"parse_mode,omitempty"`
+    ReplyMarkup ReplyMarkup `json:"reply_markup,omitempty"`
+    // ReplyToMessageID — сообщение, на которое отвечаем (цитата в начале ответа)
+    ReplyToMessageID int64 `json:"reply_to_message_id,omitempty"`
+}
+
+// SendOption — настройка отдельного вызова SendMessage или SendPhoto
+type SendOption func(*sendOptions)
+
+// WithParseMode задаёт режим разметки сообщения вместо режима клиента по умолчанию
+func WithParseMode(mode string) SendOption {
+    return func(o *sendOptions) {
+        o.ParseMode = mode
+    }
+}
+
+// WithReplyMarkup прикрепляет к сообщению клавиатуру: inline-кнопки под
+// сообщением, меню под полем ввода (ReplyKeyboard) или команду убрать меню.
+// Правке сообщения (EditMessageText) подходит только InlineKeyboard.
+// Нулевой указатель на клавиатуру значит «без клавиатуры».
+func WithReplyMarkup(kb ReplyMarkup) SendOption {
+    return func(o *sendOptions) {
+        o.ReplyMarkup = markupOrNil(kb)
+    }
+}
+
+// markupOrNil сводит типизированный nil (*InlineKeyboard)(nil) к nil
+// интерфейса: иначе validate и omitempty принимают его за клавиатуру
+func markupOrNil(kb ReplyMarkup) ReplyMarkup {
+    switch k := kb.(type) {
+    case *InlineKeyboard:
+        if k == nil {
+            return nil
+        }
+    case *ReplyKeyboard:
+        if k == nil {
+            return nil
+        }
+    }
+    return kb
+}
+
+// WithReplyTo делает сообщение ответом на messageID: Telegram покажет его
+// цитату. Если исходное сообщение удалено, SendMessage отправляет без цитаты.
+func WithReplyTo(messageID int64) SendOption {
+    return func(o *sendOptions) {
+        o.ReplyToMessageID = messageID
+    }
+}
+
+// ReplyToOf — сообщение, которое цитирует вызов с опциями opts, или 0.
+// Нужен фейкам Telegram в тестах, чтобы проверить цитирование.
+func ReplyToOf(opts ...SendOption) int64 {
+    var o sendOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o.ReplyToMessageID
+}
+
+// ReplyMarkupOf — клавиатура вызова с опциями opts или nil.
+// Нужен фейкам Telegram в тестах, чтобы проверить кнопки.
+func ReplyMarkupOf(opts ...SendOption) ReplyMarkup {
+    var o sendOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o.ReplyMarkup
+}
+
+// ParseModeOf — разметка вызова с опциями opts у клиента с разметкой base.
+// Нужен фейкам Telegram в тестах, чтобы проверить, что текст уйдёт без разметки.
+func ParseModeOf(base string, opts ...SendOption) string {
+    o := sendOptions{ParseMode: base}
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o.ParseMode
+}
+
+// applyOptions применяет настройки вызова поверх base и проверяет клавиатуру
+func applyOptions(base sendOptions, opts []SendOption) (sendOptions, error) {
+    for _, opt := range opts {
+        opt(&base)
+    }
+    if base.ReplyMarkup != nil {
+        if err := base.ReplyMarkup.validate(); err != nil {
+            return sendOptions{}, err
+        }
+    }
+    return base, nil
+}
+
+// isNotModified — editMessageText с тем же текстом и клавиатурой: Telegram
+// отвечает 400, хотя сообщение уже в нужном виде
+func isNotModified(err error) bool {
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+        strings.Contains(apiErr.Description, "message is not modified")
+}
+
+// isReplyNotFound — сообщение, на которое отвечаем, уже удалено
+func isReplyNotFound(err error) bool {
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+        (strings.Contains(apiErr.Description, "message to be replied not found") ||
+            strings.Contains(apiErr.Description, "replied message not found"))
+}
+
+// isParseError — Telegram отклонил сообщение из-за ошибки в разметке
+func isParseError(err error) bool {
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+        strings.Contains(apiErr.Description, "can't parse entities")
+}
@@ -0,0 +1,41 @@
+package telegram
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+)
+
+func TestWithReplyMarkupTypedNil(t *testing.T) {
+    for name, kb := range map[string]ReplyMarkup{
+        "inline": (*InlineKeyboard)(nil),
+        "меню":   (*ReplyKeyboard)(nil),
+    } {
+        opts, err := applyOptions(sendOptions{}, []SendOption{WithReplyMarkup(kb)})
+        if err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+        if opts.ReplyMarkup != nil {
+            t.Fatalf("%s: клавиатура %#v, ожидалось без клавиатуры", name, opts.ReplyMarkup)
+        }
+        body, _ := json.Marshal(opts)
+        if strings.Contains(string(body), "reply_markup") {
+            t.Fatalf("%s: в запросе %s", name, body)
+        }
+    }
+}
+
+func TestApplyOptionsValidatesMarkup(t *testing.T) {
+    if _, err := applyOptions(sendOptions{}, []SendOption{WithReplyMarkup(NewReplyKeyboard())}); err == nil {
+        t.Fatal("меню без кнопок прошло проверку")
+    }
+    long := NewInlineKeyboard().Row(InlineKeyboardButton{Text: "x", CallbackData: strings.Repeat("a", MaxCallbackData+1)})
+    if _, err := applyOptions(sendOptions{}, []SendOption{WithReplyMarkup(long)}); err == nil {
+        t.Fatal("длинный callback_data прошёл проверку")
+    }
+    ok := NewReplyKeyboard().Row("Каталог")
+    opts, err := applyOptions(sendOptions{}, []SendOption{WithReplyMarkup(ok)})
+    if err != nil || opts.ReplyMarkup != ok {
+        t.Fatalf("получено %#v, %v", opts.ReplyMarkup, err)
+    }
+}
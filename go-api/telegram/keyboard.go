@@ -0,0 +1,144 @@
+package telegram
+
+import (
+    "errors"
+    "fmt"
+    "unicode/utf8"
+)
+
+// MaxCallbackData — предел длины callback_data кнопки в байтах
+const MaxCallbackData = 64
+
+// InlineKeyboardButton — кнопка под сообщением
+type InlineKeyboardButton struct {
+    Text         string `json:"text"`
+    CallbackData string `json:"callback_data,omitempty"`
+    URL          string `json:"url,omitempty"`
+}
+
+// CallbackButton — кнопка, нажатие которой приходит боту как callback_query с data
+func CallbackButton(text, data string) InlineKeyboardButton {
+    return InlineKeyboardButton{Text: text, CallbackData: data}
+}
+
+// URLButton — кнопка-ссылка
+func URLButton(text, url string) InlineKeyboardButton {
+    return InlineKeyboardButton{Text: text, URL: url}
+}
+
+// InlineKeyboard — reply_markup с inline-кнопками, собирается построчно:
+//
+//	kb := telegram.NewInlineKeyboard().Row(telegram.CallbackButton("В корзину", "add:42"))
+type InlineKeyboard struct {
+    Rows [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// NewInlineKeyboard — пустая клавиатура
+func NewInlineKeyboard() *InlineKeyboard {
+    return &InlineKeyboard{Rows: [][]InlineKeyboardButton{}}
+}
+
+// Row добавляет ряд кнопок
+func (k *InlineKeyboard) Row(buttons ...InlineKeyboardButton) *InlineKeyboard {
+    if len(buttons) > 0 {
+        k.Rows = append(k.Rows, buttons)
+    }
+    return k
+}
+
+// validate проверяет ограничения Telegram заранее: иначе сообщение
+// целиком отклоняется с невнятным BUTTON_DATA_INVALID
+func (k *InlineKeyboard) validate() error {
+    if k == nil {
+        return nil
+    }
+    for _, row := range k.Rows {
+        for _, b := range row {
+            if len(b.CallbackData) > MaxCallbackData {
+                return fmt.Errorf("callback_data кнопки %q длиннее %d байт", b.Text, MaxCallbackData)
+            }
+        }
+    }
+    return nil
+}
+
+// ReplyMarkup — клавиатура сообщения: *InlineKeyboard, *ReplyKeyboard
+// или ReplyKeyboardRemove
+type ReplyMarkup interface {
+    validate() error
+}
+
+// maxPlaceholder — предел длины подсказки в поле ввода, в символах
+const maxPlaceholder = 64
+
+// KeyboardButton — кнопка меню под полем ввода; нажатие приходит боту
+// обычным текстовым сообщением с текстом кнопки
+type KeyboardButton struct {
+    Text string `json:"text"`
+}
+
+// ReplyKeyboard — меню под полем ввода. По умолчанию меню постоянное
+// (не сворачивается после нажатия) и подогнано по высоте под кнопки:
+//
+//	kb := telegram.NewReplyKeyboard().Row("Каталог", "Корзина").Row("Помощь")
+type ReplyKeyboard struct {
+    Rows         [][]KeyboardButton `json:"keyboard"`
+    IsPersistent bool               `json:"is_persistent,omitempty"`
+    Resize       bool               `json:"resize_keyboard,omitempty"`
+    OneTime      bool               `json:"one_time_keyboard,omitempty"`
+    // Placeholder — подсказка в пустом поле ввода, пока меню открыто
+    Placeholder string `json:"input_field_placeholder,omitempty"`
+}
+
+// NewReplyKeyboard — пустое постоянное меню
+func NewReplyKeyboard() *ReplyKeyboard {
+    return &ReplyKeyboard{Rows: [][]KeyboardButton{}, IsPersistent: true, Resize: true}
+}
+
+// Row добавляет ряд кнопок с текстами texts
+func (k *ReplyKeyboard) Row(texts ...string) *ReplyKeyboard {
+    if len(texts) == 0 {
+        return k
+    }
+    row := make([]KeyboardButton, len(texts))
+    for i, t := range texts {
+        row[i] = KeyboardButton{Text: t}
+    }
+    k.Rows = append(k.Rows, row)
+    return k
+}
+
+// validate — Telegram отклоняет меню без кнопок и кнопки без текста
+func (k *ReplyKeyboard) validate() error {
+    if k == nil {
+        return nil
+    }
+    if len(k.Rows) == 0 {
+        return errors.New("в меню нет кнопок")
+    }
+    for _, row := range k.Rows {
+        for _, b := range row {
+            if b.Text == "" {
+                return errors.New("в меню есть кнопка без текста")
+            }
+        }
+    }
+    if utf8.RuneCountInString(k.Placeholder) > maxPlaceholder {
+        return fmt.Errorf("подсказка меню длиннее %d символов", maxPlaceholder)
+    }
+    return nil
+}
+
+// ReplyKeyboardRemove убирает меню под полем ввода
+type ReplyKeyboardRemove struct {
+    RemoveKeyboard bool `json:"remove_keyboard"`
+}
+
+// RemoveKeyboard — reply_markup, который прячет ранее показанное меню
+func RemoveKeyboard() ReplyKeyboardRemove {
+    return ReplyKeyboardRemove{RemoveKeyboard: true}
+}
+
+func (ReplyKeyboardRemove) validate() error {
+    return nil
+}
@@ -0,0 +1,205 @@
+package telegram
+
+import (
+    "html"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// markupStyle — как режим разметки записывает элементы Markdown
+type markupStyle struct {
+    text   func(string) string
+    code   func(string) string
+    pre    func(lang, code string) string
+    bold   func(string) string
+    italic func(string) string
+    link   func(label, url string) string
+}
+
+// escapeCodeV2 — внутри кода MarkdownV2 экранируются только ` и \
+func escapeCodeV2(s string) string {
+    return strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s)
+}
+
+var markdownV2Style = markupStyle{
+    text: EscapeMarkdownV2,
+    code: func(s string) string { return "`" + escapeCodeV2(s) + "`" },
+    pre: func(lang, code string) string {
+        return "```" + lang + "\n" + escapeCodeV2(code) + "```"
+    },
+    bold:   func(s string) string { return "*" + s + "*" },
+    italic: func(s string) string { return "_" + s + "_" },
+    link: func(label, url string) string {
+        return "[" + label + "](" + strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(url) + ")"
+    },
+}
+
+var htmlStyle = markupStyle{
+    text: html.EscapeString,
+    code: func(s string) string { return "<code>" + html.EscapeString(s) + "</code>" },
+    pre: func(lang, code string) string {
+        if lang == "" {
+            return "<pre>" + html.EscapeString(code) + "</pre>"
+        }
+        return `<pre><code class="language-` + html.EscapeString(lang) + `">` + html.EscapeString(code) + "</code></pre>"
+    },
+    bold:   func(s string) string { return "<b>" + s + "</b>" },
+    italic: func(s string) string { return "<i>" + s + "</i>" },
+    link: func(label, url string) string {
+        return `<a href="` + html.EscapeString(url) + `">` + label + "</a>"
+    },
+}
+
+// formatText переводит текст, написанный обычным Markdown (так отвечает
+// модель и так написаны фразы бота), в разметку режима mode: **жирный**,
+// *курсив* и _курсив_, `код`, блоки ``` и [ссылки](url) становятся
+// разметкой, заголовки «# …» — жирной строкой, а всё остальное
+// экранируется и выводится как есть. Незакрытая разметка остаётся текстом.
+// Пустой или неизвестный mode возвращает текст без изменений.
+func formatText(text, mode string) string {
+    switch mode {
+    case ParseModeMarkdownV2:
+        return formatLines(text, markdownV2Style)
+    case ParseModeHTML:
+        return formatLines(text, htmlStyle)
+    }
+    return text
+}
+
+// formatLines выделяет блоки кода и заголовки, остальное — formatInline
+func formatLines(text string, st markupStyle) string {
+    var b strings.Builder
+    for text != "" {
+        start := strings.Index(text, "```")
+        if start < 0 {
+            b.WriteString(formatHeadings(text, st))
+            break
+        }
+        end := strings.Index(text[start+3:], "```")
+        if end < 0 {
+            b.WriteString(formatHeadings(text, st))
+            break
+        }
+        b.WriteString(formatHeadings(text[:start], st))
+        body := text[start+3 : start+3+end]
+        lang, code, ok := strings.Cut(body, "\n")
+        if !ok || strings.ContainsAny(lang, " \t") {
+            lang, code = "", body
+        }
+        b.WriteString(st.pre(lang, code))
+        text = text[start+3+end+3:]
+    }
+    return b.String()
+}
+
+// formatHeadings делает строки «# Заголовок» жирными
+func formatHeadings(text string, st markupStyle) string {
+    lines := strings.Split(text, "\n")
+    for i, line := range lines {
+        if title, ok := heading(line); ok {
+            lines[i] = st.bold(formatInline(title, st, false))
+            continue
+        }
+        lines[i] = formatInline(line, st, true)
+    }
+    return strings.Join(lines, "\n")
+}
+
+// heading — текст заголовка Markdown от одной до шести «#» с пробелом
+func heading(line string) (string, bool) {
+    trimmed := strings.TrimLeft(line, "#")
+    level := len(line) - len(trimmed)
+    if level == 0 || level > 6 || !strings.HasPrefix(trimmed, " ") {
+        return "", false
+    }
+    return strings.TrimSpace(trimmed), true
+}
+
+// formatInline размечает строку; внутри жирного (bold = false) жирный не
+// вкладывается
+func formatInline(s string, st markupStyle, bold bool) string {
+    var b strings.Builder
+    for i := 0; i < len(s); {
+        rest := s[i:]
+        switch {
+        case rest[0] == '`':
+            if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+                b.WriteString(st.code(rest[1 : 1+end]))
+                i += end + 2
+                continue
+            }
+        case bold && strings.HasPrefix(rest, "**"):
+            if end := strings.Index(rest[2:], "**"); end > 0 {
+                b.WriteString(st.bold(formatInline(rest[2:2+end], st, false)))
+                i += end + 4
+                continue
+            }
+        case rest[0] == '*' || rest[0] == '_':
+            if end, ok := italicEnd(s, i); ok {
+                b.WriteString(st.italic(st.text(s[i+1 : end])))
+                i = end + 1
+                continue
+            }
+        case rest[0] == '[':
+            if label, url, n, ok := markdownLink(rest); ok {
+                b.WriteString(st.link(formatInline(label, st, bold), url))
+                i += n
+                continue
+            }
+        }
+        _, size := utf8.DecodeRuneInString(rest)
+        b.WriteString(st.text(rest[:size]))
+        i += size
+    }
+    return b.String()
+}
+
+// italicEnd — позиция закрывающего маркера курсива, открытого в s[i].
+// Маркер открывает курсив в начале слова перед непробельным символом и
+// закрывает в конце слова: snake_case и «2 * 3» остаются текстом.
+func italicEnd(s string, i int) (int, bool) {
+    marker := s[i]
+    if i > 0 && isWordByte(s, i-1) {
+        return 0, false
+    }
+    if i+1 >= len(s) || s[i+1] == ' ' || s[i+1] == marker {
+        return 0, false
+    }
+    for j := i + 2; j < len(s); j++ {
+        if s[j] != marker {
+            continue
+        }
+        if s[j-1] == ' ' || (j+1 < len(s) && isWordByte(s, j+1)) {
+            continue
+        }
+        return j, true
+    }
+    return 0, false
+}
+
+// isWordByte — в s[i] начинается или заканчивается буква либо цифра
+func isWordByte(s string, i int) bool {
+    r, _ := utf8.DecodeRuneInString(s[i:])
+    if r == utf8.RuneError {
+        r, _ = utf8.DecodeLastRuneInString(s[:i+1])
+    }
+    return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// markdownLink разбирает [текст](ссылка) в начале s; n — длина записи
+func markdownLink(s string) (label, url string, n int, ok bool) {
+    closeLabel := strings.Index(s, "](")
+    if closeLabel < 1 || strings.Contains(s[1:closeLabel], "[") {
+        return "", "", 0, false
+    }
+    closeURL := strings.IndexByte(s[closeLabel+2:], ')')
+    if closeURL < 1 {
+        return "", "", 0, false
+    }
+    url = s[closeLabel+2 : closeLabel+2+closeURL]
+    if strings.ContainsAny(url, " \n") {
+        return "", "", 0, false
+    }
+    return s[1:closeLabel], url, closeLabel + 3 + closeURL, true
+}
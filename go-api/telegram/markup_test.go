@@ -0,0 +1,41 @@
+package telegram
+
+import "testing"
+
+func TestFormatTextMarkdownV2(t *testing.T) {
+    for _, tc := range []struct{ in, want string }{
+        {"Привет. Как дела?", `Привет\. Как дела?`},
+        {"**Сенча** — 1 990 ₽", `*Сенча* — 1 990 ₽`},
+        {"*курсив* и _тоже_", `_курсив_ и _тоже_`},
+        {"file_name и 2 * 3 = 6", `file\_name и 2 \* 3 \= 6`},
+        {"команда `/cart` и `a\\b`", "команда `/cart` и `a\\\\b`"},
+        {"[магазин](https://shop.example/a_b) и [x]", `[магазин](https://shop.example/a_b) и \[x\]`},
+        {"## Доставка\n- курьер", "*Доставка*\n\\- курьер"},
+        {"```go\nx := 1\n```", "```go\nx := 1\n```"},
+        {"**не закрыт", `\*\*не закрыт`},
+        {"**жирный *и курсив***", `*жирный \*и курсив*\*`},
+    } {
+        if got := formatText(tc.in, ParseModeMarkdownV2); got != tc.want {
+            t.Errorf("formatText(%q):\nполучено  %q\nожидалось %q", tc.in, got, tc.want)
+        }
+    }
+}
+
+func TestFormatTextHTML(t *testing.T) {
+    for _, tc := range []struct{ in, want string }{
+        {"a < b & c", "a &lt; b &amp; c"},
+        {"**Сенча** и *курсив*", "<b>Сенча</b> и <i>курсив</i>"},
+        {"[сайт](https://x.example/?a=1&b=2)", `<a href="https://x.example/?a=1&amp;b=2">сайт</a>`},
+        {"```\n<tag>\n```", "<pre>&lt;tag&gt;\n</pre>"},
+    } {
+        if got := formatText(tc.in, ParseModeHTML); got != tc.want {
+            t.Errorf("formatText(%q):\nполучено  %q\nожидалось %q", tc.in, got, tc.want)
+        }
+    }
+}
+
+func TestFormatTextPlain(t *testing.T) {
+    if got := formatText("**как есть**.", ""); got != "**как есть**." {
+        t.Fatalf("получено %q", got)
+    }
+}
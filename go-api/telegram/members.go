@@ -0,0 +1,88 @@
+package telegram
+
+import (
+    "context"
+    "time"
+)
+
+// chatPermissions — что участнику можно делать в группе
+type chatPermissions struct {
+    CanSendMessages       bool `json:"can_send_messages"`
+    CanSendAudios         bool `json:"can_send_audios"`
+    CanSendDocuments      bool `json:"can_send_documents"`
+    CanSendPhotos         bool `json:"can_send_photos"`
+    CanSendVideos         bool `json:"can_send_videos"`
+    CanSendVideoNotes     bool `json:"can_send_video_notes"`
+    CanSendVoiceNotes     bool `json:"can_send_voice_notes"`
+    CanSendPolls          bool `json:"can_send_polls"`
+    CanSendOtherMessages  bool `json:"can_send_other_messages"`
+    CanAddWebPagePreviews bool `json:"can_add_web_page_previews"`
+}
+
+// allPermissions — все права отправки разом: false — участник только читает
+func allPermissions(allowed bool) chatPermissions {
+    return chatPermissions{
+        CanSendMessages: allowed, CanSendAudios: allowed, CanSendDocuments: allowed,
+        CanSendPhotos: allowed, CanSendVideos: allowed, CanSendVideoNotes: allowed,
+        CanSendVoiceNotes: allowed, CanSendPolls: allowed, CanSendOtherMessages: allowed,
+        CanAddWebPagePreviews: allowed,
+    }
+}
+
+// restrictChatMemberRequest — тело запроса restrictChatMember
+type restrictChatMemberRequest struct {
+    ChatID      int64           `json:"chat_id"`
+    UserID      int64           `json:"user_id"`
+    Permissions chatPermissions `json:"permissions"`
+    // UseIndependent — права передаются по отдельности, без вывода одних из других
+    UseIndependent bool `json:"use_independent_chat_permissions"`
+}
+
+// RestrictChatMember запрещает участнику группы писать (allowed=false) или
+// снимает ограничения (allowed=true). Бот должен быть администратором группы.
+func (c *Client) RestrictChatMember(chatID, userID int64, allowed bool) error {
+    return c.call("restrictChatMember", restrictChatMemberRequest{
+        ChatID:         chatID,
+        UserID:         userID,
+        Permissions:    allPermissions(allowed),
+        UseIndependent: true,
+    })
+}
+
+// banChatMemberRequest — тело запроса banChatMember
+type banChatMemberRequest struct {
+    ChatID    int64 `json:"chat_id"`
+    UserID    int64 `json:"user_id"`
+    UntilDate int64 `json:"until_date,omitempty"`
+}
+
+// BanChatMember удаляет участника из группы до until; нулевое until — навсегда.
+// Telegram считает вечным и срок меньше 30 секунд, поэтому для «выгнать, но
+// дать вернуться» нужен срок хотя бы в минуту.
+func (c *Client) BanChatMember(chatID, userID int64, until time.Time) error {
+    req := banChatMemberRequest{ChatID: chatID, UserID: userID}
+    if !until.IsZero() {
+        req.UntilDate = until.Unix()
+    }
+    return c.call("banChatMember", req)
+}
+
+// getChatMemberRequest — тело запроса getChatMember
+type getChatMemberRequest struct {
+    ChatID int64 `json:"chat_id"`
+    UserID int64 `json:"user_id"`
+}
+
+// chatMember — то, что нужно из ответа getChatMember
+type chatMember struct {
+    Status string `json:"status"`
+}
+
+// IsChatAdmin — участник userID владеет группой chatID или администрирует её
+func (c *Client) IsChatAdmin(chatID, userID int64) (bool, error) {
+    var m chatMember
+    if err := c.do(context.Background(), "getChatMember", getChatMemberRequest{ChatID: chatID, UserID: userID}, &m); err != nil {
+        return false, err
+    }
+    return m.Status == "creator" || m.Status == "administrator", nil
+}
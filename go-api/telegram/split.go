@@ -0,0 +1,82 @@
+package telegram
+
+import (
+    "strings"
+    "unicode/utf16"
+)
+
+// maxMessageLength — предел длины текста сообщения в Telegram.
+// Telegram считает длину в UTF-16 code units, а не в байтах или рунах.
+const maxMessageLength = 4096
+
+// SplitMessage — части, которыми SendMessage отправил бы text: каждая
+// уходит одним сообщением
+func SplitMessage(text string) []string {
+    return splitMessage(text, maxMessageLength)
+}
+
+// splitMessage делит текст на части не длиннее limit. Резать стараемся
+// по абзацам, затем по строкам, предложениям и пробелам — не посреди слова —
+// и не внутри блока кода ```.
+func splitMessage(text string, limit int) []string {
+    var chunks []string
+    for utf16Len(text) > limit {
+        cut := cutPoint(text, limit)
+        if chunk := strings.TrimRight(text[:cut], " \n"); chunk != "" {
+            chunks = append(chunks, chunk)
+        }
+        text = strings.TrimLeft(text[cut:], " \n")
+    }
+    if text != "" {
+        chunks = append(chunks, text)
+    }
+    return chunks
+}
+
+// cutPoint — байтовая позиция, по которой стоит разрезать text, чтобы первая
+// часть уложилась в limit
+func cutPoint(text string, limit int) int {
+    hard := prefixLen(text, limit)
+    prefix := text[:hard]
+
+    cut := -1
+    for _, sep := range []string{"\n\n", "\n", ". ", "! ", "? ", " "} {
+        if i := strings.LastIndex(prefix, sep); i > 0 {
+            cut = i + len(sep)
+            break
+        }
+    }
+    if cut <= 0 {
+        cut = hard
+    }
+
+    // Не оставляем блок кода открытым: режем перед его началом, если есть куда
+    if strings.Count(text[:cut], "```")%2 == 1 {
+        if i := strings.LastIndex(text[:cut], "```"); i > 0 {
+            cut = i
+        }
+    }
+    return cut
+}
+
+// prefixLen — длина в байтах самого длинного префикса text,
+// укладывающегося в limit UTF-16 code units
+func prefixLen(text string, limit int) int {
+    units := 0
+    for i, r := range text {
+        units += utf16.RuneLen(r)
+        if units > limit {
+            return i
+        }
+    }
+    return len(text)
+}
+
+// utf16Len — длина строки так, как её считает Telegram
+func utf16Len(s string) int {
+    n := 0
+    for _, r := range s {
+        n += utf16.RuneLen(r)
+    }
+    return n
+}
@@ -0,0 +1,132 @@
+package telegram
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+// checkChunks проверяет, что части укладываются в лимит Telegram и вместе
+// дают исходный текст без потерь, кроме пробелов на стыках
+func checkChunks(t *testing.T, text string, chunks []string) {
+    t.Helper()
+    for i, c := range chunks {
+        if n := utf16Len(c); n > maxMessageLength {
+            t.Fatalf("часть %d длиной %d больше %d", i+1, n, maxMessageLength)
+        }
+        if !utf8.ValidString(c) {
+            t.Fatalf("часть %d разрезана посреди символа", i+1)
+        }
+    }
+    if got, want := strings.Join(strings.Fields(strings.Join(chunks, "")), ""), strings.Join(strings.Fields(text), ""); got != want {
+        t.Fatal("части не складываются в исходный текст")
+    }
+}
+
+func TestSplitMessageParagraphs(t *testing.T) {
+    paragraph := strings.TrimSpace(strings.Repeat("Улун хорош. ", 84))
+    paragraphs := make([]string, 10)
+    for i := range paragraphs {
+        paragraphs[i] = paragraph
+    }
+    text := strings.Join(paragraphs, "\n\n")
+    if n := utf8.RuneCountInString(text); n < 10000 {
+        t.Fatalf("текст короче 10k: %d", n)
+    }
+
+    chunks := SplitMessage(text)
+    checkChunks(t, text, chunks)
+    // В часть влезают четыре абзаца; режется по их границам
+    want := []string{
+        strings.Join(paragraphs[:4], "\n\n"),
+        strings.Join(paragraphs[4:8], "\n\n"),
+        strings.Join(paragraphs[8:], "\n\n"),
+    }
+    if len(chunks) != len(want) {
+        t.Fatalf("частей %d, нужно %d", len(chunks), len(want))
+    }
+    for i := range want {
+        if chunks[i] != want[i] {
+            t.Fatalf("часть %d режет абзац: …%q", i+1, chunks[i][len(chunks[i])-20:])
+        }
+    }
+}
+
+func TestSplitMessageSentences(t *testing.T) {
+    var sb strings.Builder
+    for i := 0; sb.Len() < 20000; i++ {
+        fmt.Fprintf(&sb, "Предложение %d про зелёный чай. ", i)
+    }
+    text := strings.TrimSpace(sb.String())
+
+    chunks := SplitMessage(text)
+    checkChunks(t, text, chunks)
+    if len(chunks) < 2 {
+        t.Fatalf("частей %d", len(chunks))
+    }
+    for i, c := range chunks {
+        if !strings.HasPrefix(c, "Предложение ") || !strings.HasSuffix(c, "чай.") {
+            t.Fatalf("часть %d режет предложение: %q … %q", i+1, c[:30], c[len(c)-30:])
+        }
+    }
+}
+
+// Без пробелов текст режется ровно по лимиту, а суррогатные пары UTF-16 не
+// разрываются
+func TestSplitMessageHardCut(t *testing.T) {
+    word := strings.Repeat("a", 10000)
+    chunks := SplitMessage(word)
+    checkChunks(t, word, chunks)
+    if len(chunks) != 3 || len(chunks[0]) != maxMessageLength || len(chunks[2]) != 10000-2*maxMessageLength {
+        t.Fatalf("длины частей слова: %d", len(chunks))
+    }
+
+    emoji := strings.Repeat("🍵", 3000)
+    chunks = SplitMessage(emoji)
+    checkChunks(t, emoji, chunks)
+    if len(chunks) != 2 || utf8.RuneCountInString(chunks[0]) != maxMessageLength/2 {
+        t.Fatalf("эмодзи: %d частей, в первой %d", len(chunks), utf8.RuneCountInString(chunks[0]))
+    }
+}
+
+func TestSplitMessageKeepsCodeBlock(t *testing.T) {
+    prose := strings.TrimSpace(strings.Repeat("Описание чая. ", 280))
+    code := "```\n" + strings.TrimSpace(strings.Repeat("brew --temp 90\n", 30)) + "\n```"
+    text := prose + "\n" + code
+
+    chunks := SplitMessage(text)
+    checkChunks(t, text, chunks)
+    if len(chunks) != 2 || chunks[1] != code {
+        t.Fatalf("блок кода разрезан: %d частей, вторая %.40q", len(chunks), chunks[len(chunks)-1])
+    }
+}
+
+// Длинный ответ уходит несколькими сообщениями: цитата — у первого,
+// клавиатура — у последнего, id — последнего
+func TestSendMessageSplitsLongText(t *testing.T) {
+    var id int
+    c, api := newFakeClient("", func(apiCall) (int, string) {
+        id++
+        return http.StatusOK, fmt.Sprintf(`{"ok":true,"result":{"message_id":%d}}`, id)
+    })
+    text := strings.TrimSpace(strings.Repeat("Улун хорош. ", 850))
+    kb := NewInlineKeyboard().Row(CallbackButton("В корзину", "add:1"))
+
+    got, err := c.SendMessage(42, text, WithReplyTo(7), WithReplyMarkup(kb))
+    if err != nil {
+        t.Fatal(err)
+    }
+    calls := api.Calls()
+    if len(calls) != 3 || got != 3 {
+        t.Fatalf("отправлено %d сообщений, id %d", len(calls), got)
+    }
+    for i, call := range calls {
+        _, quoted := call.body["reply_to_message_id"]
+        _, keyboard := call.body["reply_markup"]
+        if quoted != (i == 0) || keyboard != (i == len(calls)-1) {
+            t.Fatalf("часть %d: цитата %v, клавиатура %v", i+1, quoted, keyboard)
+        }
+    }
+}
@@ -0,0 +1,30 @@
+package telegram
+
+import (
+    "context"
+    "encoding/json"
+    "time"
+)
+
+// getUpdatesRequest — тело запроса getUpdates
+type getUpdatesRequest struct {
+    Offset         int64    `json:"offset,omitempty"`
+    Timeout        int      `json:"timeout"`
+    AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// GetUpdates ждёт новые апдейты до timeout (long polling) и возвращает их
+// как есть: разбор — забота обработчика. offset — update_id последнего
+// обработанного апдейта плюс один; всё, что раньше, Telegram считает подтверждённым.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]json.RawMessage, error) {
+    ctx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+    defer cancel()
+
+    var updates []json.RawMessage
+    err := c.doWith(ctx, c.pollClient, "getUpdates", getUpdatesRequest{
+        Offset:         offset,
+        Timeout:        int(timeout / time.Second),
+        AllowedUpdates: allowedUpdates,
+    }, &updates)
+    return updates, err
+}